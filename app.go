@@ -0,0 +1,186 @@
+// Package golib -----------------------------
+// @file      : app.go
+// @author    : xiangtao
+// @contact   : xiangtao1994@gmail.com
+// @time      : 2025/9/14 18:20
+// Description: 应用生命周期，按顺序执行初始化、就绪、退出钩子
+// -------------------------------------------
+package golib
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/xiangtao94/golib/pkg/env"
+	"github.com/xiangtao94/golib/pkg/zlog"
+)
+
+const ctxKeyAppName = "__appName__"
+
+// App 应用生命周期：Bootstraps -> 按注册顺序执行 OnInit 钩子 -> 监听端口 -> 按注册顺序执行 OnReady 钩子
+// -> 收到 SIGINT/SIGTERM 或调用 Stop 后按 StartHttpServer 的流程优雅退出，逆序执行 OnShutdown 钩子
+type App struct {
+	conf       any
+	opts       []BootstrapOption
+	serverConf ServerConf
+	engine     *gin.Engine
+	initHooks  []hook
+	readyHooks []hook
+
+	listener net.Listener
+	stopCh   chan struct{}
+	stopOnce sync.Once
+}
+
+// NewApp conf 为已解析的应用配置，钩子中通过 Conf 读取；opts 在 Run 时传给 Bootstraps
+func NewApp(conf any, opts ...BootstrapOption) *App {
+	return &App{conf: conf, opts: opts, stopCh: make(chan struct{})}
+}
+
+func (a *App) Conf() any {
+	return a.conf
+}
+
+// Engine 返回 Run 传入的 engine，钩子中可用来注册路由
+func (a *App) Engine() *gin.Engine {
+	return a.engine
+}
+
+// SetServerConf 设置服务超时和优雅退出配置，不设置时使用 ServerConf 的默认值
+func (a *App) SetServerConf(conf ServerConf) *App {
+	a.serverConf = conf
+	return a
+}
+
+// OnInit 注册初始化钩子，监听端口前按注册顺序执行，任一钩子失败时执行已注册的退出钩子并不再启动服务
+func (a *App) OnInit(name string, fn func(ctx context.Context) error) *App {
+	a.initHooks = append(a.initHooks, hook{name: name, fn: fn})
+	return a
+}
+
+// OnReady 注册就绪钩子，监听端口后按注册顺序执行，失败时退出服务
+func (a *App) OnReady(name string, fn func(ctx context.Context) error) *App {
+	a.readyHooks = append(a.readyHooks, hook{name: name, fn: fn})
+	return a
+}
+
+// OnShutdown 同 golib.OnShutdown，与初始化钩子中注册的退出钩子一起逆序执行
+func (a *App) OnShutdown(name string, fn func(ctx context.Context) error, opts ...ShutdownOption) *App {
+	OnShutdown(name, fn, opts...)
+	return a
+}
+
+// Stop 触发优雅退出，与收到 SIGTERM 相同
+func (a *App) Stop() {
+	a.stopOnce.Do(func() {
+		close(a.stopCh)
+	})
+}
+
+// Run 启动应用并阻塞到退出完成，初始化钩子失败、监听失败或就绪钩子失败时返回错误
+// 钩子的 ctx 可通过 AppName 取到应用名，通过 LogContext 取到打印日志用的 gin.Context
+func (a *App) Run(engine *gin.Engine, port int) error {
+	a.engine = engine
+	Bootstraps(engine, a.opts...)
+	a.serverConf.checkConf()
+	ctx := newAppContext(env.GetAppName())
+
+	for _, h := range a.initHooks {
+		if err := runAppHook(ctx, "init", h); err != nil {
+			a.abortStartup(ctx)
+			return err
+		}
+	}
+
+	srv := newHTTPServer(engine, port, a.serverConf)
+	lis, err := net.Listen("tcp", srv.Addr)
+	if err != nil {
+		a.abortStartup(ctx)
+		return fmt.Errorf("listen: %w", err)
+	}
+	a.listener = lis
+	serveErr := make(chan error, 1)
+	go func() {
+		if err := srv.Serve(lis); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serveErr <- err
+		}
+	}()
+	log.Printf("Server is running on %s", lis.Addr())
+
+	var runErr error
+	for _, h := range a.readyHooks {
+		if runErr = runAppHook(ctx, "ready", h); runErr != nil {
+			break
+		}
+	}
+	if runErr == nil {
+		quit := make(chan os.Signal, 1)
+		signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+		defer signal.Stop(quit)
+		select {
+		case <-quit:
+		case <-a.stopCh:
+		case err := <-serveErr:
+			runErr = fmt.Errorf("serve: %w", err)
+		}
+	}
+	gracefulShutdown(ctx, a.serverConf.Shutdown, srv.Shutdown)
+	return runErr
+}
+
+// abortStartup 初始化或监听失败时逆序执行已注册的退出钩子，释放初始化钩子打开的资源
+func (a *App) abortStartup(ctx context.Context) {
+	shutdownCtx, cancel := context.WithTimeout(ctx, a.serverConf.Shutdown.Timeout)
+	defer cancel()
+	runShutdownHooks(shutdownCtx)
+	zlog.CloseLogger()
+}
+
+func runAppHook(ctx context.Context, stage string, h hook) error {
+	start := time.Now()
+	if err := runHook(ctx, h); err != nil {
+		zlog.Errorf(LogContext(ctx), "app %s %s hook %s failed: %v, cost: %v", AppName(ctx), stage, h.name, err, time.Since(start))
+		return fmt.Errorf("%s hook %s: %w", stage, h.name, err)
+	}
+	zlog.Infof(LogContext(ctx), "app %s %s hook %s done, cost: %v", AppName(ctx), stage, h.name, time.Since(start))
+	return nil
+}
+
+// newAppContext 构造钩子使用的 context，同一个应用的生命周期日志使用同一个请求ID
+func newAppContext(appName string) *gin.Context {
+	engine := gin.New()
+	// Deadline/Done 使用 Request.Context()
+	engine.ContextWithFallback = true
+	c := gin.CreateTestContextOnly(nil, engine)
+	c.Request, _ = http.NewRequest(http.MethodGet, "/", http.NoBody)
+	c.Set(ctxKeyAppName, appName)
+	return c
+}
+
+// LogContext 返回钩子 ctx 中的 gin.Context，用于 zlog 打印日志，非 App 钩子的 ctx 返回 nil
+func LogContext(ctx context.Context) *gin.Context {
+	if ctx == nil {
+		return nil
+	}
+	c, _ := ctx.Value(gin.ContextKey).(*gin.Context)
+	return c
+}
+
+// AppName 返回钩子 ctx 中的应用名
+func AppName(ctx context.Context) string {
+	if c := LogContext(ctx); c != nil {
+		return c.GetString(ctxKeyAppName)
+	}
+	return ""
+}
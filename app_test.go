@@ -0,0 +1,134 @@
+package golib
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xiangtao94/golib/pkg/env"
+)
+
+type appRecorder struct {
+	mu    sync.Mutex
+	order []string
+}
+
+func (r *appRecorder) hook(name string, err error) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		r.mu.Lock()
+		r.order = append(r.order, name)
+		r.mu.Unlock()
+		return err
+	}
+}
+
+func newTestApp(t *testing.T, conf any) *App {
+	resetShutdownHooks(t)
+	oldName := env.GetAppName()
+	t.Cleanup(func() {
+		env.SetAppName(oldName)
+		shuttingDown.Store(false)
+	})
+	return NewApp(conf, WithAppName("demo"))
+}
+
+func TestApp_HookOrder(t *testing.T) {
+	conf := &struct{ Port int }{Port: 8080}
+	app := newTestApp(t, conf)
+	rec := &appRecorder{}
+	engine := gin.New()
+	engine.GET("/ping", func(c *gin.Context) { c.String(http.StatusOK, "pong") })
+
+	app.OnShutdown("consumer", rec.hook("shutdown:consumer", nil))
+	app.OnInit("mysql", func(ctx context.Context) error {
+		assert.Equal(t, "demo", AppName(ctx))
+		assert.NotNil(t, LogContext(ctx))
+		assert.Same(t, conf, app.Conf())
+		assert.Same(t, engine, app.Engine())
+		// 初始化钩子中注册的退出钩子参与逆序执行
+		app.OnShutdown("mysql", rec.hook("shutdown:mysql", nil))
+		return rec.hook("init:mysql", nil)(ctx)
+	})
+	app.OnInit("redis", rec.hook("init:redis", nil))
+	app.OnReady("ping", func(ctx context.Context) error {
+		resp, err := http.Get("http://" + app.listener.Addr().String() + "/ping")
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		return rec.hook("ready:ping", nil)(ctx)
+	})
+	app.OnReady("stop", func(ctx context.Context) error {
+		app.Stop()
+		return rec.hook("ready:stop", nil)(ctx)
+	})
+
+	require.NoError(t, app.Run(engine, 0))
+	assert.Equal(t, []string{
+		"init:mysql", "init:redis", "ready:ping", "ready:stop", "shutdown:mysql", "shutdown:consumer",
+	}, rec.order)
+}
+
+func TestApp_InitFailure(t *testing.T) {
+	app := newTestApp(t, nil)
+	rec := &appRecorder{}
+	app.OnShutdown("consumer", rec.hook("shutdown:consumer", nil))
+	app.OnInit("redis", func(ctx context.Context) error {
+		app.OnShutdown("redis", rec.hook("shutdown:redis", nil))
+		return rec.hook("init:redis", nil)(ctx)
+	})
+	app.OnInit("mysql", rec.hook("init:mysql", errors.New("connection refused")))
+	app.OnInit("cache", rec.hook("init:cache", nil))
+	app.OnReady("ready", rec.hook("ready", nil))
+
+	err := app.Run(gin.New(), 0)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "init hook mysql")
+	// 初始化失败时不监听端口，不执行后续钩子，已注册的退出钩子逆序执行
+	assert.Nil(t, app.listener)
+	assert.Equal(t, []string{"init:redis", "init:mysql", "shutdown:redis", "shutdown:consumer"}, rec.order)
+
+	// panic 的钩子同样中止启动
+	app = newTestApp(t, nil)
+	app.OnInit("panic", func(ctx context.Context) error { panic("boom") })
+	assert.ErrorContains(t, app.Run(gin.New(), 0), "panic: boom")
+}
+
+func TestApp_ReadyFailure(t *testing.T) {
+	app := newTestApp(t, nil)
+	rec := &appRecorder{}
+	app.OnShutdown("consumer", rec.hook("shutdown:consumer", nil))
+	app.OnReady("register", rec.hook("ready:register", errors.New("registry unavailable")))
+
+	err := app.Run(gin.New(), 0)
+	assert.ErrorContains(t, err, "ready hook register")
+	// 已经监听端口，退出钩子照常执行
+	assert.Equal(t, []string{"ready:register", "shutdown:consumer"}, rec.order)
+}
+
+func TestApp_ShutdownTimeout(t *testing.T) {
+	app := newTestApp(t, nil)
+	rec := &appRecorder{}
+	app.OnShutdown("mysql", rec.hook("shutdown:mysql", nil))
+	app.OnShutdown("stuck", func(ctx context.Context) error {
+		assert.Equal(t, "demo", AppName(ctx))
+		time.Sleep(time.Second)
+		return nil
+	}, WithHookTimeout(50*time.Millisecond))
+	app.OnReady("stop", func(ctx context.Context) error {
+		app.Stop()
+		return nil
+	})
+
+	start := time.Now()
+	require.NoError(t, app.Run(gin.New(), 0))
+	// 超时的钩子不阻塞后续钩子
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+	assert.Equal(t, []string{"shutdown:mysql"}, rec.order)
+}
@@ -20,11 +20,10 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/prometheus/client_golang/prometheus"
 
+	"github.com/xiangtao94/golib/flow"
 	"github.com/xiangtao94/golib/pkg/env"
 	"github.com/xiangtao94/golib/pkg/middleware"
 	"github.com/xiangtao94/golib/pkg/zlog"
-
-	_ "net/http/pprof"
 )
 
 type BootstrapOption func(engine *gin.Engine)
@@ -57,7 +56,7 @@ func WithAccessLog(conf ...middleware.AccessLoggerConfig) BootstrapOption {
 	}
 }
 
-// 5. Recovery
+// 5. Recovery - handler 为 nil 时使用 middleware.DefaultRecoveryHandler 返回统一错误结构
 func WithRecovery(handler gin.RecoveryFunc) BootstrapOption {
 	return func(engine *gin.Engine) {
 		middleware.RegistryRecovery(engine, handler)
@@ -72,24 +71,110 @@ func WithPrometheus(cs ...prometheus.Collector) BootstrapOption {
 	}
 }
 
+// WithPrometheusConfig 与 WithPrometheus 相同，支持配置跳过路径与 endpoint 基数限制
+func WithPrometheusConfig(conf middleware.PromConfig, cs ...prometheus.Collector) BootstrapOption {
+	return func(engine *gin.Engine) {
+		middleware.RegistryMetricsWithConfig(engine, conf, cs...)
+	}
+}
+
+// WithLimits 请求体大小与超时限制，需放在 WithAccessLog 之前
+func WithLimits(conf middleware.LimitsConf) BootstrapOption {
+	return func(engine *gin.Engine) {
+		middleware.RegistryLimits(engine, conf)
+	}
+}
+
+// WithCORS 按配置处理跨域请求，需放在其他中间件之前，预检请求直接返回 204
+// 配置不合法（如 allowCredentials 与 "*" 同时使用）时 panic，在启动阶段暴露错误
+func WithCORS(conf middleware.CORSConf) BootstrapOption {
+	return func(engine *gin.Engine) {
+		if err := middleware.RegistryCORS(engine, conf); err != nil {
+			panic(err)
+		}
+	}
+}
+
 func Bootstraps(engine *gin.Engine, opts ...BootstrapOption) {
 	// 依次执行传入的可选项
 	for _, opt := range opts {
 		opt(engine)
 	}
-	// 统一添加pprof
-	engine.GET("/debug/pprof/*any", gin.WrapH(http.DefaultServeMux))
 }
 
-func StartHttpServer(engine *gin.Engine, port int) error {
+// ServerConf HTTP服务的超时和请求头大小限制，超时为0时使用默认值，小于0时不限制
+// 请求体大小限制使用 WithLimits
+type ServerConf struct {
+	// ReadTimeout 读取整个请求（含请求体）的超时时间，默认60s
+	ReadTimeout time.Duration `yaml:"readTimeout"`
+	// ReadHeaderTimeout 读取请求头的超时时间，防止慢速攻击，默认10s
+	ReadHeaderTimeout time.Duration `yaml:"readHeaderTimeout"`
+	// WriteTimeout 从读完请求头到写完响应的超时时间，默认60s；SSE 等长连接接口需调大或设为 -1
+	WriteTimeout time.Duration `yaml:"writeTimeout"`
+	// IdleTimeout keep-alive 连接的空闲超时时间，默认120s
+	IdleTimeout time.Duration `yaml:"idleTimeout"`
+	// MaxHeaderBytes 请求头最大字节数，默认1MB
+	MaxHeaderBytes int `yaml:"maxHeaderBytes"`
+	// Shutdown 优雅退出配置
+	Shutdown ShutdownConfig `yaml:"shutdown"`
+}
+
+func (conf *ServerConf) checkConf() {
+	conf.ReadTimeout = serverTimeout(conf.ReadTimeout, 60*time.Second)
+	conf.ReadHeaderTimeout = serverTimeout(conf.ReadHeaderTimeout, 10*time.Second)
+	conf.WriteTimeout = serverTimeout(conf.WriteTimeout, 60*time.Second)
+	conf.IdleTimeout = serverTimeout(conf.IdleTimeout, 120*time.Second)
+	if conf.MaxHeaderBytes <= 0 {
+		conf.MaxHeaderBytes = 1 << 20
+	}
+	conf.Shutdown.checkConf()
+}
+
+// serverTimeout 0 取默认值，小于0转为 http.Server 中表示不限制的0
+func serverTimeout(d, def time.Duration) time.Duration {
+	switch {
+	case d == 0:
+		return def
+	case d < 0:
+		return 0
+	default:
+		return d
+	}
+}
+
+func newHTTPServer(engine *gin.Engine, port int, conf ServerConf) *http.Server {
 	addr := fmt.Sprintf(":%d", port)
 	if strings.TrimSpace(addr) == "" || addr == ":" {
 		addr = ":8080"
 	}
-	srv := &http.Server{
-		Addr:    addr,
-		Handler: engine,
+	return &http.Server{
+		Addr:              addr,
+		Handler:           engine,
+		ReadTimeout:       conf.ReadTimeout,
+		ReadHeaderTimeout: conf.ReadHeaderTimeout,
+		WriteTimeout:      conf.WriteTimeout,
+		IdleTimeout:       conf.IdleTimeout,
+		MaxHeaderBytes:    conf.MaxHeaderBytes,
 	}
+}
+
+// StartHttpServer 启动HTTP服务并阻塞，收到 SIGINT/SIGTERM 后优雅退出：
+// 等待 DrainDelay -> 关闭HTTP服务 -> 等待 flow.Go 后台任务 -> 逆序执行 OnShutdown 钩子 -> 关闭日志
+// 服务超时使用 ServerConf 的默认值，需要调整时使用 StartHttpServerWithConf
+func StartHttpServer(engine *gin.Engine, port int, conf ...ShutdownConfig) error {
+	var serverConf ServerConf
+	if len(conf) > 0 {
+		serverConf.Shutdown = conf[0]
+	}
+	return StartHttpServerWithConf(engine, port, serverConf)
+}
+
+// StartHttpServerWithConf 与 StartHttpServer 相同，可配置服务超时和请求头大小
+func StartHttpServerWithConf(engine *gin.Engine, port int, conf ServerConf) error {
+	conf.checkConf()
+	shutdownConf := conf.Shutdown
+	srv := newHTTPServer(engine, port, conf)
+	addr := srv.Addr
 
 	// Initializing the server in a goroutine so that
 	// it won't block the graceful shutdown handling below
@@ -99,6 +184,43 @@ func StartHttpServer(engine *gin.Engine, port int) error {
 		}
 	}()
 	log.Printf("Server is running on %s", addr)
+	waitAndShutdown(shutdownConf, func(ctx context.Context) error {
+		return srv.Shutdown(ctx)
+	})
+	return nil
+}
+
+// Server 通过 StartServer 启动的服务，如 grpcx.Listen 返回的 gRPC 服务
+// Serve 阻塞直到服务关闭，正常关闭时返回 nil；Shutdown 优雅关闭，ctx 结束时强制关闭
+type Server interface {
+	Serve() error
+	Shutdown(ctx context.Context) error
+}
+
+// StartServer 启动服务并阻塞，退出流程与 StartHttpServer 相同
+//
+//	server := grpcx.NewServer(grpcx.ServerConf{})
+//	pb.RegisterUserServer(server, &userServer{})
+//	srv, err := grpcx.Listen(server, 9090)
+//	golib.StartServer(srv)
+func StartServer(srv Server, conf ...ShutdownConfig) error {
+	var shutdownConf ShutdownConfig
+	if len(conf) > 0 {
+		shutdownConf = conf[0]
+	}
+	shutdownConf.checkConf()
+	go func() {
+		if err := srv.Serve(); err != nil {
+			log.Fatalf("serve: %s\n", err)
+		}
+	}()
+	waitAndShutdown(shutdownConf, srv.Shutdown)
+	return nil
+}
+
+// waitAndShutdown 阻塞直到收到 SIGINT/SIGTERM，然后优雅退出：
+// 等待 DrainDelay -> stop 关闭服务 -> 等待 flow.Go 后台任务 -> 逆序执行 OnShutdown 钩子 -> 关闭日志
+func waitAndShutdown(shutdownConf ShutdownConfig, stop func(ctx context.Context) error) {
 	// Wait for interrupt signal to gracefully shutdown the server with
 	// a timeout of 5 seconds.
 	quit := make(chan os.Signal, 1)
@@ -107,16 +229,33 @@ func StartHttpServer(engine *gin.Engine, port int) error {
 	// kill -9 is syscall.SIGKILL but can't be catch, so don't need add it
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
+	gracefulShutdown(context.Background(), shutdownConf, stop)
+}
+
+// gracefulShutdown 退出流程，StartHttpServer、StartServer 和 App.Run 共用，base 为钩子 ctx 的父 context
+func gracefulShutdown(base context.Context, shutdownConf ShutdownConfig, stop func(ctx context.Context) error) {
 	log.Print("Shutting down server...")
+	shuttingDown.Store(true)
+
+	// 等待负载均衡摘除流量，期间继续处理请求
+	if shutdownConf.DrainDelay > 0 {
+		time.Sleep(shutdownConf.DrainDelay)
+	}
 
-	// The context is used to inform the server it has 5 seconds to finish
-	// the request it is currently handling
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	// The context is used to inform the server it has Timeout to finish
+	// the request it is currently handling and release resources
+	ctx, cancel := context.WithTimeout(base, shutdownConf.Timeout)
 	defer cancel()
-	if err := srv.Shutdown(ctx); err != nil {
-		zlog.Error(nil, "Server forced to shutdown: %v", err)
+	if err := stop(ctx); err != nil {
+		zlog.Errorf(LogContext(ctx), "Server forced to shutdown: %v", err)
 	}
+	// 后台任务可能依赖钩子中关闭的资源，先等待其结束
+	if err := flow.WaitTasks(ctx); err != nil {
+		zlog.Errorf(LogContext(ctx), "wait background tasks error: %v", err)
+	}
+	runShutdownHooks(ctx)
 
 	log.Print("Server exiting")
-	return nil
+	// 日志最后关闭，保证钩子日志落盘
+	zlog.CloseLogger()
 }
@@ -0,0 +1,52 @@
+package golib
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/xiangtao94/golib/pkg/middleware"
+)
+
+func TestWithCORS(t *testing.T) {
+	engine := gin.New()
+	Bootstraps(engine, WithCORS(middleware.CORSConf{AllowOrigins: []string{"https://*.example.com"}}))
+	engine.POST("/api", func(c *gin.Context) { c.String(http.StatusOK, "ok") })
+
+	req := httptest.NewRequest(http.MethodOptions, "/api", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, "https://app.example.com", w.Header().Get("Access-Control-Allow-Origin"))
+
+	assert.Panics(t, func() {
+		Bootstraps(gin.New(), WithCORS(middleware.CORSConf{AllowOrigins: []string{"*"}, AllowCredentials: true}))
+	})
+}
+
+func TestServerConf(t *testing.T) {
+	var conf ServerConf
+	conf.checkConf()
+	srv := newHTTPServer(gin.New(), 9090, conf)
+	assert.Equal(t, ":9090", srv.Addr)
+	assert.Equal(t, 60*time.Second, srv.ReadTimeout)
+	assert.Equal(t, 10*time.Second, srv.ReadHeaderTimeout)
+	assert.Equal(t, 60*time.Second, srv.WriteTimeout)
+	assert.Equal(t, 120*time.Second, srv.IdleTimeout)
+	assert.Equal(t, 1<<20, srv.MaxHeaderBytes)
+	assert.Equal(t, 5*time.Second, conf.Shutdown.Timeout)
+
+	// 小于0不限制
+	conf = ServerConf{WriteTimeout: -1, ReadTimeout: 5 * time.Second, MaxHeaderBytes: 4096}
+	conf.checkConf()
+	srv = newHTTPServer(gin.New(), 9090, conf)
+	assert.Zero(t, srv.WriteTimeout)
+	assert.Equal(t, 5*time.Second, srv.ReadTimeout)
+	assert.Equal(t, 4096, srv.MaxHeaderBytes)
+}
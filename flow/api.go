@@ -2,7 +2,11 @@ package flow
 
 import (
 	"encoding/json"
-	"fmt"
+	"slices"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
 	"github.com/xiangtao94/golib/pkg/errors"
 	"github.com/xiangtao94/golib/pkg/http"
 	"github.com/xiangtao94/golib/pkg/zlog"
@@ -29,6 +33,17 @@ type Api struct {
 	Layer
 	EncodeType string
 	Client     *http.ClientConf
+	// RetryBusinessCodes 需要重试的业务码，如上游限流、繁忙时返回的 10429、10503
+	// 仅对 ApiGetWithOpts、ApiPostWithOpts（包括 ApiGet、ApiPost）生效，重试时重新编码请求体
+	RetryBusinessCodes []int
+	// MaxBusinessRetries 业务码重试的最大次数，不含首次请求，默认2次
+	MaxBusinessRetries int
+	// BusinessRetryWait 首次重试等待时间，之后每次翻倍，默认200ms
+	BusinessRetryWait time.Duration
+	// BusinessRetryMaxWait 重试等待时间上限，默认2s
+	BusinessRetryMaxWait time.Duration
+	// OnBusinessError 上游返回非200业务码时回调，每次请求都会调用（包括被重试的），用于打点
+	OnBusinessError func(ctx *gin.Context, path string, res *ApiRes)
 }
 
 // api请求数据格式，默认json
@@ -78,11 +93,9 @@ func (entity *Api) ApiGetWithOpts(path string, reqOpts http.RequestOptions) (*Ap
 		return nil, errors.ErrorSystemError
 	}
 	reqOpts.Path = path
-	res, e := entity.Client.Get(entity.GetCtx(), reqOpts)
-	if e != nil {
-		return nil, e
-	}
-	return entity.handel(path, res)
+	return entity.invokeWithRetry(path, func() (*http.Result, error) {
+		return entity.Client.Get(entity.GetCtx(), reqOpts)
+	})
 }
 
 func (entity *Api) ApiDeleteWithOpts(path string, reqOpts http.RequestOptions) (*ApiRes, error) {
@@ -123,16 +136,73 @@ func (entity *Api) ApiPostWithOpts(path string, reqOpts http.RequestOptions) (*A
 		reqOpts.Encode = entity.GetEncodeType()
 	}
 	reqOpts.Path = path
-	res, err := entity.Client.Post(entity.GetCtx(), reqOpts)
-	if err != nil {
-		return nil, err
+	return entity.invokeWithRetry(path, func() (*http.Result, error) {
+		return entity.Client.Post(entity.GetCtx(), reqOpts)
+	})
+}
+
+// invokeWithRetry 执行请求，业务码在 RetryBusinessCodes 中时退避后重新请求，重试用尽后返回最后一次的结果
+func (entity *Api) invokeWithRetry(path string, do func() (*http.Result, error)) (*ApiRes, error) {
+	ctx := entity.GetCtx()
+	wait := entity.BusinessRetryWait
+	if wait <= 0 {
+		wait = 200 * time.Millisecond
+	}
+	maxWait := entity.BusinessRetryMaxWait
+	if maxWait <= 0 {
+		maxWait = 2 * time.Second
+	}
+	maxRetries := entity.MaxBusinessRetries
+	if maxRetries <= 0 {
+		maxRetries = 2
+	}
+	for attempt := 0; ; attempt++ {
+		res, err := do()
+		if err != nil {
+			return nil, err
+		}
+		apiRes, err := entity.handel(path, res)
+		if err != nil || apiRes.Code == 200 {
+			return apiRes, err
+		}
+		if entity.OnBusinessError != nil {
+			entity.OnBusinessError(ctx, path, apiRes)
+		}
+		if !slices.Contains(entity.RetryBusinessCodes, apiRes.Code) {
+			return apiRes, nil
+		}
+		if attempt >= maxRetries {
+			zlog.Warnf(ctx, "api business retry exhausted, path:%s, code:%d, attempts:%d", path, apiRes.Code, attempt+1)
+			return apiRes, nil
+		}
+		zlog.Warnf(ctx, "api business code retry, path:%s, code:%d, message:%s, attempt:%d, wait:%v",
+			path, apiRes.Code, apiRes.Message, attempt+1, wait)
+		if !sleepCtx(ctx, wait) {
+			return apiRes, nil
+		}
+		wait = min(wait*2, maxWait)
+	}
+}
+
+// sleepCtx 等待 d，请求被取消时提前返回 false
+func sleepCtx(ctx *gin.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	if ctx == nil || ctx.Request == nil {
+		<-timer.C
+		return true
+	}
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Request.Context().Done():
+		return false
 	}
-	return entity.handel(path, res)
 }
 
 func (entity *Api) handel(path string, res *http.Result) (*ApiRes, error) {
 	if res.HttpCode > 200 {
-		return nil, fmt.Errorf("api response status code: %d, message: %s", res.HttpCode, string(res.Response))
+		return nil, &http.HTTPStatusError{Code: res.HttpCode, Body: res.Response, Header: res.Header}
 	}
 	apiRes := &ApiRes{}
 	if len(res.Response) > 0 {
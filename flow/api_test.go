@@ -0,0 +1,97 @@
+package flow
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	http2 "github.com/xiangtao94/golib/pkg/http"
+)
+
+type retryApi struct {
+	Api
+}
+
+// newBusinessCodeServer 前 failures 次返回业务码 code，之后返回成功，记录每次收到的请求体
+func newBusinessCodeServer(t *testing.T, code int, failures int32) (*httptest.Server, *atomic.Int32, *[]string) {
+	var hits atomic.Int32
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, strings.TrimSpace(string(body)))
+		res := ApiRes{Code: 200, Message: "ok", Data: json.RawMessage(`{"name":"tom"}`)}
+		if hits.Add(1) <= failures {
+			res = ApiRes{Code: code, Message: "busy"}
+		}
+		_ = json.NewEncoder(w).Encode(res)
+	}))
+	t.Cleanup(server.Close)
+	return server, &hits, &bodies
+}
+
+func newRetryApi(server *httptest.Server) *retryApi {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+	api := Create(c, &retryApi{})
+	api.Client = &http2.ClientConf{Service: "upstream", Domain: server.URL, RetryTimes: -1}
+	api.RetryBusinessCodes = []int{10429, 10503}
+	api.BusinessRetryWait = time.Millisecond
+	return api
+}
+
+func TestApiBusinessCodeRetry(t *testing.T) {
+	server, hits, bodies := newBusinessCodeServer(t, 10503, 2)
+	api := newRetryApi(server)
+	var observed []int
+	api.OnBusinessError = func(_ *gin.Context, path string, res *ApiRes) {
+		assert.Equal(t, "/user", path)
+		observed = append(observed, res.Code)
+	}
+
+	res, err := api.ApiPost("/user", map[string]any{"id": 1})
+	require.NoError(t, err)
+	var out struct {
+		Name string `json:"name"`
+	}
+	require.NoError(t, api.DecodeApiResponse(&out, res, err))
+	assert.Equal(t, "tom", out.Name)
+	assert.Equal(t, int32(3), hits.Load())
+	assert.Equal(t, []int{10503, 10503}, observed)
+	// 每次重试都重新发送完整的请求体
+	assert.Equal(t, []string{`{"id":1}`, `{"id":1}`, `{"id":1}`}, *bodies)
+}
+
+func TestApiBusinessCodeRetryExhausted(t *testing.T) {
+	server, hits, _ := newBusinessCodeServer(t, 10429, 10)
+	api := newRetryApi(server)
+	api.MaxBusinessRetries = 1
+
+	res, err := api.ApiGet("/user", map[string]string{"id": "1"})
+	require.NoError(t, err)
+	assert.Equal(t, 10429, res.Code)
+	assert.Error(t, api.DecodeApiResponse(nil, res, err))
+	assert.Equal(t, int32(2), hits.Load())
+}
+
+func TestApiBusinessCodeNotRetryable(t *testing.T) {
+	server, hits, _ := newBusinessCodeServer(t, 10400, 1)
+	api := newRetryApi(server)
+	var observed []int
+	api.OnBusinessError = func(_ *gin.Context, _ string, res *ApiRes) { observed = append(observed, res.Code) }
+
+	res, err := api.ApiPost("/user", map[string]any{"id": 1})
+	require.NoError(t, err)
+	assert.Equal(t, 10400, res.Code)
+	assert.Error(t, api.DecodeApiResponse(nil, res, err))
+	assert.Equal(t, int32(1), hits.Load())
+	assert.Equal(t, []int{10400}, observed)
+}
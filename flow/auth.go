@@ -0,0 +1,13 @@
+package flow
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/xiangtao94/golib/pkg/middleware"
+)
+
+// GetAuthPrincipal 获取 middleware.RegistryAuth 写入的鉴权主体，未鉴权时返回 nil
+// Controller/Service 中可传入 entity.GetCtx()
+func GetAuthPrincipal(ctx *gin.Context) *middleware.AuthPrincipal {
+	return middleware.GetAuthPrincipal(ctx)
+}
@@ -0,0 +1,175 @@
+package flow
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+
+	"github.com/xiangtao94/golib/pkg/redis"
+	"github.com/xiangtao94/golib/pkg/zlog"
+)
+
+var DefaultRedisClient *redis.Redis
+
+func SetDefaultRedisClient(r *redis.Redis) {
+	DefaultRedisClient = r
+}
+
+func GetDefaultRedisClient() *redis.Redis {
+	return DefaultRedisClient
+}
+
+// ErrCacheUnavailable 关闭回源后 Redis 未配置或出错时返回
+var ErrCacheUnavailable = errors.New("cache unavailable")
+
+// 缓存层，cache-aside 模式读写 Redis，未命中时调用 loader 回源
+type ICache interface {
+	ILayer
+	GetRedis() *redis.Redis
+	SetRedis(r *redis.Redis)
+	SetNamespace(namespace string)
+	SetFallback(fallback bool)
+	Key(parts ...any) string
+	FullKey(key string) string
+	GetOrLoad(key string, ttl time.Duration, loader func() (any, error), out any) error
+	Delete(keys ...string) error
+}
+
+type Cache struct {
+	Layer
+	redis      *redis.Redis
+	namespace  string
+	noFallback bool
+}
+
+func (c *Cache) OnCreate() {
+	// hook if needed
+}
+
+// GetRedis 优先返回 SetRedis 设置的客户端, 否则 DefaultRedisClient
+func (c *Cache) GetRedis() *redis.Redis {
+	if c.redis != nil {
+		return c.redis
+	}
+	return DefaultRedisClient
+}
+
+func (c *Cache) SetRedis(r *redis.Redis) {
+	c.redis = r
+}
+
+// SetNamespace 设置缓存层的 key 命名空间，一般在 OnCreate 中设置
+func (c *Cache) SetNamespace(namespace string) {
+	c.namespace = namespace
+}
+
+// SetFallback 设置 Redis 出错时是否直接回源，默认回源；关闭后返回 ErrCacheUnavailable
+func (c *Cache) SetFallback(fallback bool) {
+	c.noFallback = !fallback
+}
+
+// Key 以 ":" 拼接 key 的各部分，如 Key("user", 1) 返回 "user:1"
+func (c *Cache) Key(parts ...any) string {
+	strs := make([]string, 0, len(parts))
+	for _, p := range parts {
+		strs = append(strs, fmt.Sprint(p))
+	}
+	return strings.Join(strs, ":")
+}
+
+// FullKey 返回实际写入 Redis 的 key：redis.GetKeyPrefix() + 命名空间 + key
+func (c *Cache) FullKey(key string) string {
+	if c.namespace == "" {
+		return redis.GetKeyPrefix() + key
+	}
+	return redis.GetKeyPrefix() + c.namespace + ":" + key
+}
+
+func (c *Cache) context() context.Context {
+	if ctx := c.GetCtx(); ctx != nil {
+		return ctx
+	}
+	return context.Background()
+}
+
+// GetOrLoad 读取 key 的缓存并 JSON 解码到 out，未命中时调用 loader 并将结果以 ttl 写入缓存
+// key 不含前缀，由 FullKey 补全；ttl <= 0 表示永不过期；loader 出错时不写缓存并原样返回错误
+// Redis 出错时默认直接回源且不写缓存，写缓存失败只记录日志
+func (c *Cache) GetOrLoad(key string, ttl time.Duration, loader func() (any, error), out any) error {
+	fullKey := c.FullKey(key)
+	r := c.GetRedis()
+	if r == nil {
+		if c.noFallback {
+			return fmt.Errorf("cache get %s: redis client not set: %w", fullKey, ErrCacheUnavailable)
+		}
+		zlog.Warnf(c.GetCtx(), "cache get %s: redis client not set, load directly", fullKey)
+		return c.load(nil, fullKey, ttl, loader, out)
+	}
+
+	data, err := r.Get(c.context(), fullKey).Bytes()
+	switch {
+	case err == nil:
+		if err = json.Unmarshal(data, out); err == nil {
+			return nil
+		}
+		zlog.Warnf(c.GetCtx(), "cache get %s: unmarshal error: %s, reload", fullKey, err.Error())
+	case errors.Is(err, goredis.Nil):
+	default:
+		if c.noFallback {
+			zlog.Errorf(c.GetCtx(), "cache get %s error: %s", fullKey, err.Error())
+			return fmt.Errorf("cache get %s: %w: %w", fullKey, ErrCacheUnavailable, err)
+		}
+		zlog.Warnf(c.GetCtx(), "cache get %s error: %s, load directly", fullKey, err.Error())
+		return c.load(nil, fullKey, ttl, loader, out)
+	}
+	return c.load(r, fullKey, ttl, loader, out)
+}
+
+// load 调用 loader 并解码到 out，r 不为空时写入缓存
+func (c *Cache) load(r *redis.Redis, fullKey string, ttl time.Duration, loader func() (any, error), out any) error {
+	v, err := loader()
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("cache marshal %s: %w", fullKey, err)
+	}
+	if r != nil {
+		if ttl < 0 {
+			ttl = 0
+		}
+		if err := r.Set(c.context(), fullKey, data, ttl).Err(); err != nil {
+			zlog.Warnf(c.GetCtx(), "cache set %s error: %s", fullKey, err.Error())
+		}
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("cache unmarshal %s: %w", fullKey, err)
+	}
+	return nil
+}
+
+// Delete 删除缓存，key 不含前缀；数据更新后调用，失败时返回错误以便调用方重试
+func (c *Cache) Delete(keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	r := c.GetRedis()
+	if r == nil {
+		return fmt.Errorf("cache delete: redis client not set: %w", ErrCacheUnavailable)
+	}
+	fullKeys := make([]string, 0, len(keys))
+	for _, key := range keys {
+		fullKeys = append(fullKeys, c.FullKey(key))
+	}
+	if err := r.Del(c.context(), fullKeys...).Err(); err != nil {
+		zlog.Errorf(c.GetCtx(), "cache delete %v error: %s", fullKeys, err.Error())
+		return fmt.Errorf("cache delete %v: %w", fullKeys, err)
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+package flow
+
+import (
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	goredis "github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xiangtao94/golib/pkg/redis"
+)
+
+type userInfo struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+type UserCache struct {
+	Cache
+}
+
+func (c *UserCache) OnCreate() {
+	c.SetNamespace("user")
+}
+
+func (c *UserCache) GetUser(id int, loader func() (any, error)) (*userInfo, error) {
+	var u userInfo
+	if err := c.GetOrLoad(c.Key("info", id), time.Minute, loader, &u); err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+func newTestRedis(t *testing.T) (*redis.Redis, *miniredis.Miniredis) {
+	mr := miniredis.RunT(t)
+	client := goredis.NewUniversalClient(&goredis.UniversalOptions{Addrs: []string{mr.Addr()}, MaxRetries: -1})
+	t.Cleanup(func() { _ = client.Close() })
+	return &redis.Redis{UniversalClient: client}, mr
+}
+
+func newTestCtx() *gin.Context {
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	return ctx
+}
+
+func TestCacheKey(t *testing.T) {
+	c := Create(newTestCtx(), &UserCache{})
+	assert.Equal(t, "info:1", c.Key("info", 1))
+	assert.Equal(t, redis.GetKeyPrefix()+"user:info:1", c.FullKey("info:1"))
+
+	c.SetNamespace("")
+	assert.Equal(t, redis.GetKeyPrefix()+"info:1", c.FullKey("info:1"))
+}
+
+func TestCacheGetOrLoad(t *testing.T) {
+	r, mr := newTestRedis(t)
+	SetDefaultRedisClient(r)
+	t.Cleanup(func() { SetDefaultRedisClient(nil) })
+	c := Create(newTestCtx(), &UserCache{})
+	assert.Same(t, r, c.GetRedis())
+
+	loads := 0
+	loader := func() (any, error) {
+		loads++
+		return userInfo{ID: 1, Name: "tom"}, nil
+	}
+	// 未命中回源并写缓存
+	u, err := c.GetUser(1, loader)
+	require.NoError(t, err)
+	assert.Equal(t, &userInfo{ID: 1, Name: "tom"}, u)
+	key := c.FullKey("info:1")
+	v, err := mr.Get(key)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"id":1,"name":"tom"}`, v)
+	assert.Equal(t, time.Minute, mr.TTL(key))
+
+	// 命中不回源
+	u, err = c.GetUser(1, loader)
+	require.NoError(t, err)
+	assert.Equal(t, "tom", u.Name)
+	assert.Equal(t, 1, loads)
+
+	// 缓存内容无法解码时重新回源
+	require.NoError(t, mr.Set(key, "not json"))
+	_, err = c.GetUser(1, loader)
+	require.NoError(t, err)
+	assert.Equal(t, 2, loads)
+
+	// 删除后重新回源
+	require.NoError(t, c.Delete(c.Key("info", 1)))
+	assert.False(t, mr.Exists(key))
+	_, err = c.GetUser(1, loader)
+	require.NoError(t, err)
+	assert.Equal(t, 3, loads)
+
+	// loader 出错不写缓存
+	boom := errors.New("boom")
+	_, err = c.GetUser(2, func() (any, error) { return nil, boom })
+	assert.ErrorIs(t, err, boom)
+	assert.False(t, mr.Exists(c.FullKey("info:2")))
+}
+
+func TestCacheRedisDown(t *testing.T) {
+	r, mr := newTestRedis(t)
+	mr.Close()
+	c := Create(newTestCtx(), &UserCache{})
+	c.SetRedis(r)
+
+	loads := 0
+	loader := func() (any, error) {
+		loads++
+		return userInfo{ID: 1, Name: "tom"}, nil
+	}
+	// 默认回源
+	u, err := c.GetUser(1, loader)
+	require.NoError(t, err)
+	assert.Equal(t, "tom", u.Name)
+	assert.Equal(t, 1, loads)
+	assert.Error(t, c.Delete("info:1"))
+
+	// 关闭回源后返回错误
+	c.SetFallback(false)
+	_, err = c.GetUser(1, loader)
+	assert.ErrorIs(t, err, ErrCacheUnavailable)
+	assert.Equal(t, 1, loads)
+}
+
+func TestCacheNoClient(t *testing.T) {
+	c := Create(newTestCtx(), &UserCache{})
+	require.Nil(t, c.GetRedis())
+	u, err := c.GetUser(1, func() (any, error) { return userInfo{ID: 1}, nil })
+	require.NoError(t, err)
+	assert.Equal(t, 1, u.ID)
+	assert.ErrorIs(t, c.Delete("info:1"), ErrCacheUnavailable)
+
+	c.SetFallback(false)
+	_, err = c.GetUser(1, func() (any, error) { return userInfo{ID: 1}, nil })
+	assert.ErrorIs(t, err, ErrCacheUnavailable)
+}
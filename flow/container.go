@@ -0,0 +1,76 @@
+package flow
+
+import (
+	"reflect"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+const ctxKeyLayerContainer = "__flowLayerContainer__"
+
+// layerContainer 请求内按类型缓存的实体
+type layerContainer struct {
+	mu      sync.Mutex
+	entries map[reflect.Type]*layerEntry
+}
+
+type layerEntry struct {
+	once  sync.Once
+	layer ILayer
+}
+
+// getContainer 返回当前请求的 container，不存在时创建
+// Use 和 Go 创建的上下文在进入业务逻辑前已创建，同一请求的协程并发调用 Get 只访问请求内的锁
+// 其他上下文（自定义 handler、测试）在首次 Get 时创建，需在启动协程前调用一次
+func getContainer(ctx *gin.Context) *layerContainer {
+	if v, ok := ctx.Get(ctxKeyLayerContainer); ok {
+		return v.(*layerContainer)
+	}
+	c := &layerContainer{entries: map[reflect.Type]*layerEntry{}}
+	ctx.Set(ctxKeyLayerContainer, c)
+	return c
+}
+
+func (c *layerContainer) entry(typ reflect.Type) *layerEntry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[typ]
+	if !ok {
+		e = &layerEntry{}
+		c.entries[typ] = e
+	}
+	return e
+}
+
+// Get 返回当前请求内 T 的实例，同一请求内多次调用返回同一个实例
+// 首次调用时按 Create 的顺序执行 SetCtx、SetEntity、OnCreate，每个请求每种类型的 OnCreate 只执行一次
+// 未通过 SetDB、SetRedis 指定客户端时，实体与 Create 创建的一样使用 DefaultDBClient、DefaultRedisClient 等默认客户端
+//
+//	userDao := flow.Get[UserDao](ctx)
+func Get[T any, PT interface {
+	*T
+	ILayer
+}](ctx *gin.Context) PT {
+	e := getContainer(ctx).entry(reflect.TypeFor[T]())
+	e.once.Do(func() {
+		e.layer = Create[PT](ctx, new(T))
+	})
+	return e.layer.(PT)
+}
+
+// Provide 指定当前请求内 Get 返回的实例，一般在测试中替换为 mock，之后的 Get 都返回该实例
+// 会设置实例的上下文和实体，但不执行 OnCreate，mock 的初始化由调用方完成
+func Provide[T any, PT interface {
+	*T
+	ILayer
+}](ctx *gin.Context, instance PT) {
+	instance.SetCtx(ctx)
+	instance.SetEntity(instance)
+	e := &layerEntry{layer: instance}
+	e.once.Do(func() {})
+	c := getContainer(ctx)
+	c.mu.Lock()
+	c.entries[reflect.TypeFor[T]()] = e
+	c.mu.Unlock()
+}
@@ -0,0 +1,134 @@
+package flow
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+var containerDaoCreated atomic.Int32
+
+type containerDao struct {
+	Dao
+	name string
+}
+
+func (d *containerDao) OnCreate() {
+	containerDaoCreated.Add(1)
+	d.SetTable("users")
+	d.name = "real"
+}
+
+type containerService struct {
+	Service
+}
+
+// Dao 通过 Get 获取依赖，不需要手动创建
+func (s *containerService) DaoName() string {
+	return Get[containerDao](s.GetCtx()).name
+}
+
+func newContainerCtx() *gin.Context {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	return c
+}
+
+func TestGet_SameInstancePerRequest(t *testing.T) {
+	containerDaoCreated.Store(0)
+	ctx := newContainerCtx()
+
+	a := Get[containerDao](ctx)
+	b := Get[containerDao](ctx)
+	assert.Same(t, a, b)
+	assert.EqualValues(t, 1, containerDaoCreated.Load())
+	assert.Same(t, ctx, a.GetCtx())
+	assert.Same(t, a, a.GetEntity())
+	assert.Equal(t, "users", a.GetTable())
+	assert.Equal(t, "real", Get[containerService](ctx).DaoName())
+
+	// 不同请求不共享实例，并发调用时 OnCreate 每个请求只执行一次
+	containerDaoCreated.Store(0)
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx := newContainerCtx()
+			// 与 Use 一样在启动协程前创建 container
+			getContainer(ctx)
+			var inner sync.WaitGroup
+			got := make([]*containerDao, 5)
+			for i := range got {
+				inner.Add(1)
+				go func() {
+					defer inner.Done()
+					got[i] = Get[containerDao](ctx)
+				}()
+			}
+			inner.Wait()
+			for _, d := range got {
+				assert.Same(t, got[0], d)
+			}
+			assert.NotSame(t, a, got[0])
+		}()
+	}
+	wg.Wait()
+	assert.EqualValues(t, 10, containerDaoCreated.Load())
+}
+
+func TestProvide(t *testing.T) {
+	containerDaoCreated.Store(0)
+	ctx := newContainerCtx()
+	mock := &containerDao{name: "mock"}
+	Provide(ctx, mock)
+
+	assert.Same(t, mock, Get[containerDao](ctx))
+	assert.Equal(t, "mock", Get[containerService](ctx).DaoName())
+	assert.Same(t, ctx, mock.GetCtx())
+	assert.Zero(t, containerDaoCreated.Load())
+
+	// 其他请求不受影响
+	assert.Equal(t, "real", Get[containerService](newContainerCtx()).DaoName())
+}
+
+type containerController struct {
+	Controller
+}
+
+func (c *containerController) Action(req *listUserReq) (any, error) {
+	// Use 已创建 container，协程中首次并发调用 Get 也只创建一个实例
+	var wg sync.WaitGroup
+	got := make([]*containerDao, 5)
+	for i := range got {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			got[i] = Get[containerDao](c.GetCtx())
+		}()
+	}
+	wg.Wait()
+	for _, d := range got {
+		if d != got[0] {
+			return nil, errors.New("different instances in one request")
+		}
+	}
+	return got[0].name, nil
+}
+
+func TestGet_ConcurrentInUse(t *testing.T) {
+	containerDaoCreated.Store(0)
+	engine := gin.New()
+	engine.GET("/users", Use[listUserReq](&containerController{}))
+	for range 10 {
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users", nil))
+		assert.Contains(t, w.Body.String(), `"data":"real"`)
+	}
+	assert.EqualValues(t, 10, containerDaoCreated.Load())
+}
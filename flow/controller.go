@@ -1,9 +1,11 @@
 package flow
 
 import (
+	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/gin-gonic/gin/binding"
 	"github.com/xiangtao94/golib/pkg/errors"
+	"github.com/xiangtao94/golib/pkg/orm"
 	"github.com/xiangtao94/golib/pkg/render"
 	"github.com/xiangtao94/golib/pkg/zlog"
 	"reflect"
@@ -54,6 +56,62 @@ func (c *Controller) RenderJsonSuccess(data any) {
 	render.RenderJsonSucc(c.GetCtx(), data)
 }
 
+// RenderPageSuccess 输出标准分页列表 {items, total, page, size, hasMore}
+// page 为 nil 时从绑定的请求中读取（请求需嵌入 orm.NormalPage），页码和每页大小按 orm.NormalPaginate 的默认值补齐
+// 在 Action 中调用后，Use 不会再输出 Action 的返回值
+func (c *Controller) RenderPageSuccess(items any, total int64, page *orm.NormalPage) {
+	if page == nil {
+		page = boundPage(c.GetCtx())
+	}
+	no, size := page.Normalize()
+	render.RenderPageSucc(c.GetCtx(), items, total, no, size)
+}
+
+const boundRequestKey = "__flowBoundRequest__"
+
+var normalPageType = reflect.TypeOf(orm.NormalPage{})
+
+// boundPage 取出绑定请求中嵌入的 orm.NormalPage，没有时返回空分页
+func boundPage(ctx *gin.Context) *orm.NormalPage {
+	req, ok := ctx.Get(boundRequestKey)
+	if !ok {
+		return &orm.NormalPage{}
+	}
+	v := reflect.ValueOf(req)
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return &orm.NormalPage{}
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return &orm.NormalPage{}
+	}
+	if v.Type() == normalPageType {
+		page := v.Interface().(orm.NormalPage)
+		return &page
+	}
+	sf, ok := v.Type().FieldByName(normalPageType.Name())
+	if !ok {
+		return &orm.NormalPage{}
+	}
+	f, err := v.FieldByIndexErr(sf.Index)
+	if err != nil {
+		return &orm.NormalPage{}
+	}
+	if f.Kind() == reflect.Ptr {
+		if f.IsNil() {
+			return &orm.NormalPage{}
+		}
+		f = f.Elem()
+	}
+	if f.Type() != normalPageType {
+		return &orm.NormalPage{}
+	}
+	page := f.Interface().(orm.NormalPage)
+	return &page
+}
+
 // clone Controller 实例（浅复制）
 // 这里改为用 reflect 创建新实例，避免指针类型判断复杂性
 func cloneController[T any](ctl IController[T]) IController[T] {
@@ -72,6 +130,8 @@ func cloneController[T any](ctl IController[T]) IController[T] {
 // Gin Handler
 func Use[T any](ctl IController[T]) func(ctx *gin.Context) {
 	return func(ctx *gin.Context) {
+		// 在进入业务逻辑前创建 container，业务协程中并发调用 Get 时无需再创建
+		getContainer(ctx)
 		newCtl := cloneController(ctl)
 		newCtl.SetCtx(ctx)
 		newCtl.SetEntity(newCtl)
@@ -89,19 +149,27 @@ func Use[T any](ctl IController[T]) func(ctx *gin.Context) {
 
 		if err != nil {
 			zlog.Errorf(newCtl.GetCtx(), "Controller %T param bind error: %v", newCtl, err)
-			newCtl.RenderJsonFail(errors.ErrorParamInvalid)
+			newCtl.RenderJsonFail(errors.FromValidation(err, &req))
 			return
 		}
 
+		ctx.Set(boundRequestKey, &req)
 		data, err := newCtl.Action(&req)
 		if err != nil {
-			zlog.Errorf(newCtl.GetCtx(), "Controller %T call action error: %v", newCtl, err)
+			// 带上 errors.WrapError 记录的错误码、底层错误和附加信息
+			zlog.ErrorLogger(newCtl.GetCtx(), fmt.Sprintf("Controller %T call action error: %v", newCtl, err), errors.LogFields(err)...)
 			newCtl.RenderJsonFail(err)
 			return
 		}
 
-		if newCtl.ShouldRender() {
-			newCtl.RenderJsonSuccess(data)
+		// Action 中已自行输出（如 RenderPageSuccess）时不再重复输出
+		if !newCtl.ShouldRender() || ctx.Writer.Written() {
+			return
+		}
+		if resp, ok := data.(*Response); ok && resp != nil {
+			resp.render(ctx)
+			return
 		}
+		newCtl.RenderJsonSuccess(data)
 	}
 }
@@ -0,0 +1,157 @@
+package flow
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xiangtao94/golib/pkg/orm"
+)
+
+type listUserReq struct {
+	orm.NormalPage
+	Name string `form:"name"`
+}
+
+type listUserController struct {
+	Controller
+}
+
+func (c *listUserController) Action(req *listUserReq) (any, error) {
+	c.RenderPageSuccess([]string{"a", "b"}, 5, nil)
+	return nil, nil
+}
+
+func servePage(t *testing.T, handler gin.HandlerFunc, target string) map[string]any {
+	engine := gin.New()
+	engine.GET("/users", handler)
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+	var body map[string]any
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	return body["data"].(map[string]any)
+}
+
+func TestController_RenderPageSuccess(t *testing.T) {
+	// 从绑定的请求中读取页码和每页大小
+	data := servePage(t, Use[listUserReq](&listUserController{}), "/users?No=2&Size=2")
+	assert.Equal(t, []any{"a", "b"}, data["items"])
+	assert.EqualValues(t, 2, data["page"])
+	assert.EqualValues(t, 2, data["size"])
+	assert.Equal(t, true, data["hasMore"])
+
+	// 未传分页参数时使用默认值
+	data = servePage(t, Use[listUserReq](&listUserController{}), "/users")
+	assert.EqualValues(t, 1, data["page"])
+	assert.EqualValues(t, 10, data["size"])
+	assert.Equal(t, false, data["hasMore"])
+}
+
+func TestController_RenderPageSuccessExplicitPage(t *testing.T) {
+	data := servePage(t, func(ctx *gin.Context) {
+		c := &Controller{}
+		c.SetCtx(ctx)
+		c.RenderPageSuccess(nil, 4, &orm.NormalPage{No: 2, Size: 2})
+	}, "/users")
+	assert.Equal(t, []any{}, data["items"])
+	assert.Equal(t, false, data["hasMore"])
+}
+
+func TestBoundPage(t *testing.T) {
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	assert.Equal(t, &orm.NormalPage{}, boundPage(ctx))
+
+	ctx.Set(boundRequestKey, &struct{ *orm.NormalPage }{})
+	assert.Equal(t, &orm.NormalPage{}, boundPage(ctx))
+	ctx.Set(boundRequestKey, &struct{ *orm.NormalPage }{&orm.NormalPage{No: 3}})
+	assert.Equal(t, 3, boundPage(ctx).No)
+	ctx.Set(boundRequestKey, &struct{ Name string }{})
+	assert.Equal(t, &orm.NormalPage{}, boundPage(ctx))
+}
+
+type createUserReq struct {
+	Name string `form:"name"`
+}
+
+type createUserController struct {
+	Controller
+}
+
+func (c *createUserController) Action(req *createUserReq) (any, error) {
+	switch req.Name {
+	case "":
+		return gin.H{"plain": true}, nil
+	case "redirect":
+		return Redirect(0, "/users/login"), nil
+	case "empty":
+		return &Response{Status: http.StatusNoContent}, nil
+	default:
+		return Created("/users/1", gin.H{"id": 1}), nil
+	}
+}
+
+func serveCreate(target string) *httptest.ResponseRecorder {
+	engine := gin.New()
+	engine.GET("/users", Use[createUserReq](&createUserController{}))
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+	return w
+}
+
+func TestUse_Response(t *testing.T) {
+	w := serveCreate("/users?name=tom")
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "/users/1", w.Header().Get("Location"))
+	var body map[string]any
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.EqualValues(t, 200, body["code"])
+	assert.Equal(t, map[string]any{"id": 1.0}, body["data"])
+
+	w = serveCreate("/users?name=redirect")
+	assert.Equal(t, http.StatusFound, w.Code)
+	assert.Equal(t, "/users/login", w.Header().Get("Location"))
+	assert.Empty(t, w.Body.String())
+
+	w = serveCreate("/users?name=empty")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Empty(t, w.Body.String())
+
+	// 普通返回值仍使用标准响应格式
+	w = serveCreate("/users")
+	assert.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, map[string]any{"plain": true}, body["data"])
+}
+
+type validateUserReq struct {
+	Age  int    `form:"age" binding:"gte=18"`
+	Name string `json:"user_name" form:"name" binding:"required"`
+}
+
+type validateUserController struct {
+	Controller
+}
+
+func (c *validateUserController) Action(req *validateUserReq) (any, error) {
+	return nil, nil
+}
+
+func TestUse_ValidationFieldName(t *testing.T) {
+	engine := gin.New()
+	engine.GET("/users", Use[validateUserReq](&validateUserController{}))
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users?age=10", nil))
+	var body map[string]any
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, "请求参数错误: age 必须大于等于 18; user_name 为必填项", body["message"])
+
+	// 不修改 gin 全局 validator，直接校验时仍是结构体字段名
+	err := binding.Validator.ValidateStruct(&validateUserReq{Age: 20})
+	assert.ErrorContains(t, err, "'validateUserReq.Name'")
+}
@@ -4,8 +4,10 @@ import (
 	"errors"
 	"fmt"
 	errors2 "github.com/xiangtao94/golib/pkg/errors"
+	"github.com/xiangtao94/golib/pkg/orm"
 	"github.com/xiangtao94/golib/pkg/zlog"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"gorm.io/gorm/schema"
 	"time"
 )
@@ -38,20 +40,23 @@ type Dao struct {
 	defaultDB    *gorm.DB
 	tableName    string
 	partitionNum int
+	tenantMode   TenantMode
 }
 
 func (d *Dao) OnCreate() {
 	// hook if needed
 }
 
+// getDBBase 使用请求的 context，客户端断开时正在执行的 SQL 随之取消
 func (d *Dao) getDBBase(db *gorm.DB) *gorm.DB {
 	if db == nil {
 		return nil
 	}
+	db = db.WithContext(orm.RequestContext(d.GetCtx()))
 	if d.tableName != "" {
-		return db.WithContext(d.GetCtx()).Table(d.tableName)
+		return db.Table(d.tableName)
 	}
-	return db.WithContext(d.GetCtx())
+	return db
 }
 
 // GetDB 优先返回 entity.db, 否则 defaultDB, 否则 DefaultDBClient
@@ -155,13 +160,30 @@ type CommonDao[T schema.Tabler] struct {
 	Dao
 }
 
+func (c *CommonDao[T]) tableName() string {
+	var t T
+	return t.TableName()
+}
+
+// db 按 SetTenantMode 返回租户的 DB，未设置时与 GetDB 相同
+func (c *CommonDao[T]) db() (*gorm.DB, error) {
+	if c.tenantMode == TenantNone {
+		return c.GetDB(), nil
+	}
+	return c.tenantDB(c.tableName())
+}
+
 func (c *CommonDao[T]) Insert(add *T) error {
 	if add == nil {
 		return nil
 	}
-	if err := c.GetDB().Create(add).Error; err != nil {
-		zlog.Error(c.GetCtx(), "CommonDao.Insert error: %v", err)
-		return errors2.ErrorSystemError
+	db, err := c.db()
+	if err != nil {
+		return err
+	}
+	if err = db.Create(add).Error; err != nil {
+		zlog.Errorf(c.GetCtx(), "CommonDao.Insert error: %v", err)
+		return errors2.WrapError(errors2.SYSTEM_ERROR, err, "op", "CommonDao.Insert", "table", c.tableName())
 	}
 	return nil
 }
@@ -170,9 +192,13 @@ func (c *CommonDao[T]) Update(update *T) error {
 	if update == nil {
 		return errors.New("update entity cannot be nil")
 	}
-	if err := c.GetDB().Save(update).Error; err != nil {
-		zlog.Error(c.GetCtx(), "CommonDao.Update error: %v", err)
-		return errors2.ErrorSystemError
+	db, err := c.db()
+	if err != nil {
+		return err
+	}
+	if err = db.Save(update).Error; err != nil {
+		zlog.Errorf(c.GetCtx(), "CommonDao.Update error: %v", err)
+		return errors2.WrapError(errors2.SYSTEM_ERROR, err, "op", "CommonDao.Update", "table", c.tableName())
 	}
 	return nil
 }
@@ -181,9 +207,13 @@ func (c *CommonDao[T]) Delete(delete *T) error {
 	if delete == nil {
 		return errors.New("delete entity cannot be nil")
 	}
-	if err := c.GetDB().Delete(delete).Error; err != nil {
-		zlog.Error(c.GetCtx(), "CommonDao.Delete error: %v", err)
-		return errors2.ErrorSystemError
+	db, err := c.db()
+	if err != nil {
+		return err
+	}
+	if err = db.Delete(delete).Error; err != nil {
+		zlog.Errorf(c.GetCtx(), "CommonDao.Delete error: %v", err)
+		return errors2.WrapError(errors2.SYSTEM_ERROR, err, "op", "CommonDao.Delete", "table", c.tableName())
 	}
 	return nil
 }
@@ -192,10 +222,36 @@ func (c *CommonDao[T]) BatchInsert(add []*T) error {
 	if len(add) == 0 {
 		return nil
 	}
+	db, err := c.db()
+	if err != nil {
+		return err
+	}
 	const batchSize = 2000
-	if err := c.GetDB().CreateInBatches(add, batchSize).Error; err != nil {
-		zlog.Error(c.GetCtx(), "CommonDao.BatchInsert error: %v", err)
-		return errors2.ErrorSystemError
+	if err = db.CreateInBatches(add, batchSize).Error; err != nil {
+		zlog.Errorf(c.GetCtx(), "CommonDao.BatchInsert error: %v", err)
+		return errors2.WrapError(errors2.SYSTEM_ERROR, err, "op", "CommonDao.BatchInsert", "table", c.tableName())
+	}
+	return nil
+}
+
+// BatchUpsert 批量插入，唯一键冲突时更新 updateColumns（MySQL 为 ON DUPLICATE KEY UPDATE），用于可重复执行的批量导入
+// updateColumns 为空时更新除主键和创建时间外的所有列
+func (c *CommonDao[T]) BatchUpsert(add []*T, updateColumns []string) error {
+	if len(add) == 0 {
+		return nil
+	}
+	onConflict := clause.OnConflict{UpdateAll: true}
+	if len(updateColumns) > 0 {
+		onConflict = clause.OnConflict{DoUpdates: clause.AssignmentColumns(updateColumns)}
+	}
+	db, err := c.db()
+	if err != nil {
+		return err
+	}
+	const batchSize = 2000
+	if err = db.Clauses(onConflict).CreateInBatches(add, batchSize).Error; err != nil {
+		zlog.Errorf(c.GetCtx(), "CommonDao.BatchUpsert error: %v", err)
+		return errors2.WrapError(errors2.SYSTEM_ERROR, err, "op", "CommonDao.BatchUpsert", "table", c.tableName())
 	}
 	return nil
 }
@@ -205,33 +261,121 @@ func (c *CommonDao[T]) UpdateById(id any, update map[string]interface{}) error {
 		return errors.New("update map cannot be nil")
 	}
 	update["updated_at"] = time.Now()
+	db, err := c.db()
+	if err != nil {
+		return err
+	}
 	var t T
-	db := c.GetDB().Model(&t)
-	if err := db.Where("id = ?", id).Updates(update).Error; err != nil {
-		zlog.Error(c.GetCtx(), "CommonDao.UpdateById error: %v", err)
-		return errors2.ErrorSystemError
+	if err = db.Model(&t).Where("id = ?", id).Updates(update).Error; err != nil {
+		zlog.Errorf(c.GetCtx(), "CommonDao.UpdateById error: %v", err)
+		return errors2.WrapError(errors2.SYSTEM_ERROR, err, "op", "CommonDao.UpdateById", "table", c.tableName())
+	}
+	return nil
+}
+
+// UpdateByIdVersioned 按主键和版本号更新并将版本号加一，T 需嵌入 orm.VersionedModel
+// 记录不存在、已删除或版本号不一致时返回 orm.ErrStaleObject，调用方可重新查询后重试
+func (c *CommonDao[T]) UpdateByIdVersioned(id any, version int, update map[string]interface{}) error {
+	if update == nil {
+		return errors.New("update map cannot be nil")
+	}
+	update["updated_at"] = time.Now()
+	update["version"] = gorm.Expr("version + 1")
+	db, err := c.db()
+	if err != nil {
+		return err
+	}
+	var t T
+	res := db.Model(&t).Where("id = ? AND version = ?", id, version).Updates(update)
+	if res.Error != nil {
+		zlog.Errorf(c.GetCtx(), "CommonDao.UpdateByIdVersioned error: %v", res.Error)
+		return errors2.WrapError(errors2.SYSTEM_ERROR, res.Error, "op", "CommonDao.UpdateByIdVersioned", "table", c.tableName())
+	}
+	if res.RowsAffected == 0 {
+		return orm.ErrStaleObject
 	}
 	return nil
 }
 
 func (c *CommonDao[T]) GetById(id any) (*T, error) {
+	db, err := c.db()
+	if err != nil {
+		return nil, err
+	}
 	var res T
-	err := c.GetDB().Where("id = ?", id).First(&res).Error
+	err = db.Where("id = ?", id).First(&res).Error
 	if errors.Is(err, gorm.ErrRecordNotFound) {
 		return nil, nil
 	}
 	if err != nil {
-		zlog.Error(c.GetCtx(), "CommonDao.GetById error: %v", err)
-		return nil, errors2.ErrorSystemError
+		zlog.Errorf(c.GetCtx(), "CommonDao.GetById error: %v", err)
+		return nil, errors2.WrapError(errors2.SYSTEM_ERROR, err, "op", "CommonDao.GetById", "table", c.tableName())
 	}
 	return &res, nil
 }
 
+// List 分页查询并返回总数，scopes 用于追加查询条件
+func (c *CommonDao[T]) List(page *orm.NormalPage, scopes ...func(*gorm.DB) *gorm.DB) ([]*T, int64, error) {
+	db, err := c.db()
+	if err != nil {
+		return nil, 0, err
+	}
+	var t T
+	var total int64
+	if err = db.Model(&t).Scopes(scopes...).Count(&total).Error; err != nil {
+		zlog.Errorf(c.GetCtx(), "CommonDao.List count error: %v", err)
+		return nil, 0, errors2.WrapError(errors2.SYSTEM_ERROR, err, "op", "CommonDao.List", "table", c.tableName())
+	}
+	var list []*T
+	if total == 0 {
+		return list, 0, nil
+	}
+	// Table 返回的 DB 不是新会话，每次查询重新获取
+	if db, err = c.db(); err != nil {
+		return nil, 0, err
+	}
+	if err = db.Scopes(scopes...).Scopes(orm.NormalPaginate(page)).Find(&list).Error; err != nil {
+		zlog.Errorf(c.GetCtx(), "CommonDao.List error: %v", err)
+		return nil, 0, errors2.WrapError(errors2.SYSTEM_ERROR, err, "op", "CommonDao.List", "table", c.tableName())
+	}
+	return list, total, nil
+}
+
+// ListByCursor 游标分页查询，keyColumns 为允许排序的列，最后一列需唯一（通常为 id），scopes 用于追加查询条件
+// 返回当前页和下一页游标，没有下一页时游标为空；游标或排序参数无效时返回 errors.ErrorParamInvalid
+func (c *CommonDao[T]) ListByCursor(page *orm.CursorPage, keyColumns []string, scopes ...func(*gorm.DB) *gorm.DB) ([]*T, string, error) {
+	db, err := c.db()
+	if err != nil {
+		return nil, "", err
+	}
+	var list []*T
+	db = db.Scopes(scopes...).Scopes(orm.CursorPaginate(page, keyColumns...))
+	if err = db.Find(&list).Error; err != nil {
+		if errors.Is(err, errors2.ErrorParamInvalid) {
+			return nil, "", err
+		}
+		zlog.Errorf(c.GetCtx(), "CommonDao.ListByCursor error: %v", err)
+		return nil, "", errors2.WrapError(errors2.SYSTEM_ERROR, err, "op", "CommonDao.ListByCursor", "table", c.tableName())
+	}
+	if db, err = c.db(); err != nil {
+		return nil, "", err
+	}
+	next, err := orm.NextCursor(db, page, list, keyColumns...)
+	if err != nil {
+		return nil, "", err
+	}
+	return list, next, nil
+}
+
 func (c *CommonDao[T]) DeleteById(id any) error {
+	db, err := c.db()
+	if err != nil {
+		return err
+	}
 	var t T
-	if err := c.GetDB().Where("id = ?", id).Delete(&t).Error; err != nil {
-		zlog.Error(c.GetCtx(), "CommonDao.DeleteById error: %v", err)
-		return errors2.ErrorSystemError
+	if err = db.Where("id = ?", id).Delete(&t).Error; err != nil {
+		zlog.Errorf(c.GetCtx(), "CommonDao.DeleteById error: %v", err)
+		return errors2.WrapError(errors2.SYSTEM_ERROR, err, "op", "CommonDao.DeleteById", "table", c.tableName())
 	}
 	return nil
 }
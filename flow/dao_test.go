@@ -0,0 +1,67 @@
+package flow
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/xiangtao94/golib/pkg/zlog"
+)
+
+type upsertUser struct {
+	ID        int64
+	Email     string
+	Name      string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (upsertUser) TableName() string { return "users" }
+
+func TestCommonDao_BatchUpsert(t *testing.T) {
+	db := newDryRunDB(t)
+	var sqls []string
+	require.NoError(t, db.Callback().Create().After("gorm:create").Register("test:capture", func(tx *gorm.DB) {
+		sqls = append(sqls, tx.Statement.SQL.String())
+	}))
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	dao := Create(c, &CommonDao[upsertUser]{})
+	dao.SetDB(db)
+
+	rows := []*upsertUser{{Email: "a@x.com", Name: "a"}, {Email: "b@x.com", Name: "b"}}
+	require.NoError(t, dao.BatchUpsert(rows, []string{"name"}))
+	require.Len(t, sqls, 1)
+	assert.Contains(t, sqls[0], "ON DUPLICATE KEY UPDATE `name`=VALUES(`name`)")
+	assert.NotContains(t, sqls[0], "`email`=VALUES")
+
+	// 不指定列时更新除主键和创建时间外的所有列
+	require.NoError(t, dao.BatchUpsert(rows, nil))
+	require.Len(t, sqls, 2)
+	assert.Contains(t, sqls[1], "ON DUPLICATE KEY UPDATE `updated_at`=?,`email`=VALUES(`email`),`name`=VALUES(`name`)")
+	assert.NotContains(t, sqls[1], "`created_at`=VALUES")
+
+	require.NoError(t, dao.BatchUpsert(nil, nil))
+	assert.Len(t, sqls, 2)
+}
+
+func TestDao_GetDBRequestContext(t *testing.T) {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	reqCtx, cancel := context.WithCancel(context.Background())
+	c.Request = httptest.NewRequest("GET", "/", nil).WithContext(reqCtx)
+	c.Set(zlog.ContextKeyRequestID, "req-1")
+	dao := Create(c, &CommonDao[upsertUser]{})
+	dao.SetDB(newDryRunDB(t))
+
+	// SQL 使用请求的 context，客户端断开时随之取消，同时保留 requestId
+	ctx := dao.GetDB().Statement.Context
+	assert.Equal(t, "req-1", ctx.Value(zlog.ContextKeyRequestID))
+	assert.NoError(t, ctx.Err())
+	cancel()
+	assert.ErrorIs(t, ctx.Err(), context.Canceled)
+}
@@ -1,8 +1,10 @@
 package flow
 
 import (
+	"fmt"
 	"github.com/gin-gonic/gin"
 	"reflect"
+	"strings"
 )
 
 type ILayer interface {
@@ -37,6 +39,23 @@ func (entity *Layer) OnCreate() {
 
 }
 
+// Go 在独立的后台上下文中异步执行 fn，复制 requestId 和日志字段并恢复 panic，见 flow.Go
+// 请求结束后 gin 会复用原上下文，fn 中需使用参数 ctx 而不是 GetCtx()
+func (entity *Layer) Go(fn func(ctx *gin.Context)) {
+	Go(entity.ctx, entity.taskName(), func(bgCtx *gin.Context) error {
+		fn(bgCtx)
+		return nil
+	})
+}
+
+// taskName 后台任务名，使用实体的类型名
+func (entity *Layer) taskName() string {
+	if entity.entity == nil {
+		return "Layer.Go"
+	}
+	return strings.TrimPrefix(fmt.Sprintf("%T", entity.entity), "*") + ".Go"
+}
+
 // 复制对象并带上新的上下文
 func CopyWithCtx[T ILayer](src T) T {
 	var v T
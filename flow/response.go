@@ -0,0 +1,49 @@
+package flow
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xiangtao94/golib/pkg/render"
+)
+
+// Response Action 返回 *Response 时，Use 按指定的HTTP状态码和响应头输出，Body 仍使用标准响应格式
+// 如创建资源返回 201 和 Location，重定向返回 302 和 Location
+type Response struct {
+	Status  int               // HTTP状态码，为0时为200
+	Headers map[string]string // 响应头
+	Body    any               // 响应的 data，为 nil 且状态码为 204、304 或重定向时不输出响应体
+}
+
+// Created 返回 201 和 Location 响应头
+func Created(location string, body any) *Response {
+	return &Response{Status: http.StatusCreated, Headers: map[string]string{"Location": location}, Body: body}
+}
+
+// Redirect 返回重定向响应，status 为0时为 302
+func Redirect(status int, location string) *Response {
+	if status == 0 {
+		status = http.StatusFound
+	}
+	return &Response{Status: status, Headers: map[string]string{"Location": location}}
+}
+
+func (r *Response) render(ctx *gin.Context) {
+	for k, v := range r.Headers {
+		ctx.Header(k, v)
+	}
+	status := r.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	if r.Body == nil && noBodyStatus(status) {
+		ctx.Status(status)
+		ctx.Writer.WriteHeaderNow()
+		return
+	}
+	render.RenderJsonSuccWithStatus(ctx, status, r.Body)
+}
+
+func noBodyStatus(status int) bool {
+	return status == http.StatusNoContent || (status >= http.StatusMultipleChoices && status < http.StatusBadRequest)
+}
@@ -1,6 +1,14 @@
 package flow
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+
+	errors2 "github.com/xiangtao94/golib/pkg/errors"
+	"github.com/xiangtao94/golib/pkg/orm"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
 
 // 业务分层，没有特殊逻辑
 type IService interface {
@@ -15,3 +23,91 @@ type Service struct {
 func (entity *Service) ServiceFunc() {
 	fmt.Print("this is service func\n")
 }
+
+// CommonService 标准资源的增删改查，委托给 CommonDao[T]，按需嵌入，自定义 Service 不受影响
+// 返回的错误均为 errors.Error，render 按请求语言返回错误信息
+//
+//	type UserService struct {
+//		flow.CommonService[model.User]
+//	}
+//	user, err := flow.Create(ctx, &UserService{}).GetById(id)
+type CommonService[T schema.Tabler] struct {
+	Layer
+	dao *CommonDao[T]
+}
+
+// SetDao 注入 Dao，如使用指定的数据库或表名，未注入时使用默认数据库
+func (s *CommonService[T]) SetDao(dao *CommonDao[T]) {
+	s.dao = dao
+}
+
+// GetDao 返回注入的 Dao，未注入时以当前上下文创建
+func (s *CommonService[T]) GetDao() *CommonDao[T] {
+	if s.dao == nil {
+		s.dao = Create(s.GetCtx(), &CommonDao[T]{})
+	}
+	return s.dao
+}
+
+func (s *CommonService[T]) Create(add *T) error {
+	if add == nil {
+		return errors2.ErrorParamInvalid
+	}
+	return serviceError(s.GetDao().Insert(add))
+}
+
+func (s *CommonService[T]) UpdateById(id any, update map[string]interface{}) error {
+	if id == nil || len(update) == 0 {
+		return errors2.ErrorParamInvalid
+	}
+	return serviceError(s.GetDao().UpdateById(id, update))
+}
+
+// GetById 记录不存在时返回 errors.ErrorRecordNotFound
+func (s *CommonService[T]) GetById(id any) (*T, error) {
+	if id == nil {
+		return nil, errors2.ErrorParamInvalid
+	}
+	res, err := s.GetDao().GetById(id)
+	if err != nil {
+		return nil, serviceError(err)
+	}
+	if res == nil {
+		return nil, errors2.ErrorRecordNotFound
+	}
+	return res, nil
+}
+
+func (s *CommonService[T]) DeleteById(id any) error {
+	if id == nil {
+		return errors2.ErrorParamInvalid
+	}
+	return serviceError(s.GetDao().DeleteById(id))
+}
+
+// List 分页查询并返回总数，scopes 用于追加查询条件
+func (s *CommonService[T]) List(page *orm.NormalPage, scopes ...func(*gorm.DB) *gorm.DB) ([]*T, int64, error) {
+	if page == nil {
+		page = &orm.NormalPage{}
+	}
+	list, total, err := s.GetDao().List(page, scopes...)
+	if err != nil {
+		return nil, 0, serviceError(err)
+	}
+	return list, total, nil
+}
+
+// serviceError 转为 errors.Error，已是 errors.Error 时原样返回
+func serviceError(err error) error {
+	if err == nil {
+		return nil
+	}
+	var e errors2.Error
+	if errors.As(err, &e) {
+		return err
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return errors2.ErrorRecordNotFound.Wrap(err)
+	}
+	return errors2.ErrorSystemError.Wrap(err)
+}
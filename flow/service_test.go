@@ -0,0 +1,67 @@
+package flow
+
+import (
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+
+	errors2 "github.com/xiangtao94/golib/pkg/errors"
+)
+
+type serviceUser struct {
+	ID   int64
+	Name string
+}
+
+func (serviceUser) TableName() string { return "users" }
+
+type userService struct {
+	CommonService[serviceUser]
+}
+
+func newDryRunDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(mysql.New(mysql.Config{DSN: "user:pass@tcp(127.0.0.1:1)/test", SkipInitializeWithVersion: true}),
+		&gorm.Config{DryRun: true, DisableAutomaticPing: true, SkipDefaultTransaction: true})
+	require.NoError(t, err)
+	return db
+}
+
+func TestCommonService(t *testing.T) {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	s := Create(c, &userService{})
+	assert.Same(t, c, s.GetDao().GetCtx())
+
+	dao := Create(c, &CommonDao[serviceUser]{})
+	dao.SetDB(newDryRunDB(t))
+	s.SetDao(dao)
+	assert.NoError(t, s.Create(&serviceUser{Name: "tom"}))
+	assert.NoError(t, s.UpdateById(1, map[string]interface{}{"name": "jerry"}))
+	assert.NoError(t, s.DeleteById(1))
+	list, total, err := s.List(nil)
+	assert.NoError(t, err)
+	assert.Zero(t, total)
+	assert.Empty(t, list)
+
+	// 参数错误
+	assert.ErrorIs(t, s.Create(nil), errors2.ErrorParamInvalid)
+	assert.ErrorIs(t, s.UpdateById(1, nil), errors2.ErrorParamInvalid)
+	_, err = s.GetById(nil)
+	assert.ErrorIs(t, err, errors2.ErrorParamInvalid)
+	assert.ErrorIs(t, s.DeleteById(nil), errors2.ErrorParamInvalid)
+}
+
+func TestServiceError(t *testing.T) {
+	assert.NoError(t, serviceError(nil))
+	assert.Equal(t, errors2.ErrorParamInvalid, serviceError(errors2.ErrorParamInvalid))
+	assert.ErrorIs(t, serviceError(gorm.ErrRecordNotFound), errors2.ErrorRecordNotFound)
+	boom := errors.New("boom")
+	err := serviceError(boom)
+	assert.ErrorIs(t, err, errors2.ErrorSystemError)
+	assert.ErrorIs(t, err, boom)
+}
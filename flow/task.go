@@ -0,0 +1,225 @@
+package flow
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	errors2 "github.com/xiangtao94/golib/pkg/errors"
+	"github.com/xiangtao94/golib/pkg/utils"
+	"github.com/xiangtao94/golib/pkg/zlog"
+)
+
+var (
+	taskEngine     *gin.Engine
+	taskEngineOnce sync.Once
+
+	taskKeysMu sync.RWMutex
+	taskKeys   = []string{zlog.ContextKeyUri}
+
+	// 进行中的后台任务数，服务退出时通过 WaitTasks 等待
+	// WaitTasks 超时返回后仍可能有新任务，不能使用 sync.WaitGroup
+	tasksMu   sync.Mutex
+	taskCount int
+	tasksIdle chan struct{}
+)
+
+func taskStarted() {
+	tasksMu.Lock()
+	if taskCount == 0 {
+		tasksIdle = make(chan struct{})
+	}
+	taskCount++
+	tasksMu.Unlock()
+}
+
+func taskFinished() {
+	tasksMu.Lock()
+	taskCount--
+	if taskCount == 0 {
+		close(tasksIdle)
+	}
+	tasksMu.Unlock()
+}
+
+// RegisterTaskContextKeys 追加 Go 复制到后台上下文的 key，requestId 和 zlog.AddField 的字段总会复制，需在服务启动时调用
+func RegisterTaskContextKeys(keys ...string) {
+	taskKeysMu.Lock()
+	taskKeys = append(taskKeys, keys...)
+	taskKeysMu.Unlock()
+}
+
+type taskOptions struct {
+	timeout time.Duration
+}
+
+// TaskOption Go 和 GoGroup 的可选项
+type TaskOption func(*taskOptions)
+
+// WithTaskTimeout 设置任务超时，超时后后台上下文的 Done() 关闭，fn 需自行响应
+func WithTaskTimeout(timeout time.Duration) TaskOption {
+	return func(o *taskOptions) {
+		o.timeout = timeout
+	}
+}
+
+// Go 在独立的上下文中异步执行 fn，请求结束后 gin 会复用 ctx，不能在协程中直接使用
+// 后台上下文复制 ctx 的 requestId、自定义日志字段和 RegisterTaskContextKeys 注册的 key，不受原请求取消的影响
+// fn 的 panic 会被恢复，出错时记录任务名和耗时
+func Go(ctx *gin.Context, name string, fn func(bgCtx *gin.Context) error, opts ...TaskOption) {
+	bgCtx, cancel := newTaskContext(ctx, opts)
+	taskStarted()
+	go func() {
+		defer taskFinished()
+		defer cancel()
+		_ = runTask(bgCtx, name, fn)
+	}()
+}
+
+// WaitTasks 等待所有进行中的后台任务结束，ctx 结束时返回 ctx.Err()，服务退出时自动调用
+func WaitTasks(ctx context.Context) error {
+	tasksMu.Lock()
+	if taskCount == 0 {
+		tasksMu.Unlock()
+		return nil
+	}
+	idle := tasksIdle
+	tasksMu.Unlock()
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// GoGroup 并发执行一组后台任务并汇总错误，用法同 sync.WaitGroup
+//
+//	g := flow.NewGoGroup(ctx, "load-profile")
+//	g.Go(func(bgCtx *gin.Context) error { ... })
+//	g.Go(func(bgCtx *gin.Context) error { ... })
+//	err := g.Wait()
+type GoGroup struct {
+	ctx  *gin.Context
+	name string
+	opts []TaskOption
+
+	wg   sync.WaitGroup
+	mu   sync.Mutex
+	n    int
+	errs []error
+}
+
+// NewGoGroup 创建任务组，每个任务使用独立的后台上下文，任务名为 name#序号
+func NewGoGroup(ctx *gin.Context, name string, opts ...TaskOption) *GoGroup {
+	return &GoGroup{ctx: ctx, name: name, opts: opts}
+}
+
+// Go 异步执行 fn，需在 Wait 之前调用
+func (g *GoGroup) Go(fn func(bgCtx *gin.Context) error) {
+	g.mu.Lock()
+	name := fmt.Sprintf("%s#%d", g.name, g.n)
+	g.n++
+	g.mu.Unlock()
+
+	bgCtx, cancel := newTaskContext(g.ctx, g.opts)
+	g.wg.Add(1)
+	taskStarted()
+	go func() {
+		defer taskFinished()
+		defer g.wg.Done()
+		defer cancel()
+		if err := runTask(bgCtx, name, fn); err != nil {
+			g.mu.Lock()
+			g.errs = append(g.errs, err)
+			g.mu.Unlock()
+		}
+	}()
+}
+
+// Wait 等待所有任务结束，有任务出错时返回 *utils.MultiError
+func (g *GoGroup) Wait() error {
+	g.wg.Wait()
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if len(g.errs) == 0 {
+		return nil
+	}
+	return &utils.MultiError{Errors: append([]error(nil), g.errs...)}
+}
+
+func getTaskEngine() *gin.Engine {
+	taskEngineOnce.Do(func() {
+		taskEngine = gin.New()
+		// 后台上下文的 Deadline/Done 使用 Request.Context()，用于超时控制
+		taskEngine.ContextWithFallback = true
+	})
+	return taskEngine
+}
+
+// newTaskContext 在调用方协程中完成复制，之后原 ctx 被修改或复用不影响后台上下文
+func newTaskContext(ctx *gin.Context, opts []TaskOption) (*gin.Context, context.CancelFunc) {
+	var o taskOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	taskCtx := context.Background()
+	if ctx != nil && ctx.Request != nil {
+		// 保留请求上下文中的值（如 OpenTelemetry 的 span），后台任务日志仍能通过上下文字段提取器取到 trace_id/span_id
+		taskCtx = context.WithoutCancel(ctx.Request.Context())
+	}
+	cancel := context.CancelFunc(func() {})
+	if o.timeout > 0 {
+		taskCtx, cancel = context.WithTimeout(taskCtx, o.timeout)
+	}
+
+	bgCtx := gin.CreateTestContextOnly(nil, getTaskEngine())
+	getContainer(bgCtx)
+	if ctx == nil {
+		bgCtx.Request, _ = http.NewRequestWithContext(taskCtx, http.MethodGet, "/", nil)
+		return bgCtx, cancel
+	}
+	if ctx.Request != nil {
+		bgCtx.Request = ctx.Request.Clone(taskCtx)
+		bgCtx.Request.Body = http.NoBody
+	} else {
+		bgCtx.Request, _ = http.NewRequestWithContext(taskCtx, http.MethodGet, "/", nil)
+	}
+	bgCtx.Set(zlog.ContextKeyRequestID, zlog.GetRequestID(ctx))
+	if fields := zlog.GetCustomerFields(ctx); len(fields) > 0 {
+		zlog.AddField(bgCtx, append([]zlog.Field(nil), fields...)...)
+	}
+	taskKeysMu.RLock()
+	defer taskKeysMu.RUnlock()
+	for _, key := range taskKeys {
+		if v, ok := ctx.Get(key); ok {
+			bgCtx.Set(key, v)
+		}
+	}
+	return bgCtx, cancel
+}
+
+// runTask 执行 fn 并恢复 panic，出错时记录任务名和耗时
+func runTask(bgCtx *gin.Context, name string, fn func(bgCtx *gin.Context) error) (err error) {
+	start := time.Now()
+	defer func() {
+		if r := recover(); r != nil {
+			zlog.Errorf(bgCtx, "background task %s panic: %v, cost: %v\n%s", name, r, time.Since(start), debug.Stack())
+			err = fmt.Errorf("task %s panic: %v", name, r)
+			return
+		}
+		if err != nil {
+			fields := append([]zlog.Field{zlog.String("task", name), zlog.Duration("cost", time.Since(start))}, errors2.LogFields(err)...)
+			zlog.ErrorLogger(bgCtx, fmt.Sprintf("background task %s error: %v", name, err), fields...)
+			err = fmt.Errorf("task %s: %w", name, err)
+			return
+		}
+		zlog.DebugLogger(bgCtx, fmt.Sprintf("background task %s done", name), zlog.String("task", name), zlog.Duration("cost", time.Since(start)))
+	}()
+	return fn(bgCtx)
+}
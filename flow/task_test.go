@@ -0,0 +1,172 @@
+package flow
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xiangtao94/golib/pkg/utils"
+	"github.com/xiangtao94/golib/pkg/zlog"
+)
+
+func TestGoCopiesContext(t *testing.T) {
+	RegisterTaskContextKeys("tenant")
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+
+	type result struct {
+		requestID string
+		tenant    string
+		other     string
+		fields    []zlog.Field
+		header    string
+	}
+	done := make(chan result, 1)
+	engine.GET("/", func(c *gin.Context) {
+		c.Set(zlog.ContextKeyRequestID, "req-1")
+		c.Set("tenant", "a")
+		c.Set("other", "x")
+		zlog.AddField(c, zlog.String("uid", "42"))
+		started := make(chan struct{})
+		Go(c, "copy", func(bgCtx *gin.Context) error {
+			<-started
+			done <- result{
+				requestID: zlog.GetRequestID(bgCtx),
+				tenant:    bgCtx.GetString("tenant"),
+				other:     bgCtx.GetString("other"),
+				fields:    zlog.GetCustomerFields(bgCtx),
+				header:    bgCtx.Request.Header.Get("X-Test"),
+			}
+			return nil
+		})
+		// 请求返回后修改原上下文不影响后台任务
+		c.Set("tenant", "b")
+		c.Request.Header.Set("X-Test", "changed")
+		zlog.AddField(c, zlog.String("late", "1"))
+		close(started)
+	})
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-Test", "origin")
+	engine.ServeHTTP(httptest.NewRecorder(), req)
+
+	res := <-done
+	assert.Equal(t, "req-1", res.requestID)
+	assert.Equal(t, "a", res.tenant)
+	assert.Empty(t, res.other)
+	assert.Equal(t, []zlog.Field{zlog.String("uid", "42")}, res.fields)
+	assert.Equal(t, "origin", res.header)
+	require.NoError(t, WaitTasks(context.Background()))
+}
+
+func TestGoDetachedFromRequest(t *testing.T) {
+	type traceKey struct{}
+	reqCtx, cancel := context.WithCancel(context.WithValue(context.Background(), traceKey{}, "t1"))
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodGet, "/", nil).WithContext(reqCtx)
+	cancel()
+
+	errCh := make(chan error, 1)
+	Go(c, "detached", func(bgCtx *gin.Context) error {
+		err := bgCtx.Err()
+		// 请求 context 中的值保留，取消不传递
+		if err == nil && bgCtx.Value(traceKey{}) != "t1" {
+			err = errors.New("request context value lost")
+		}
+		errCh <- err
+		return nil
+	})
+	assert.NoError(t, <-errCh)
+
+	// 超时后 Done 关闭
+	Go(c, "timeout", func(bgCtx *gin.Context) error {
+		<-bgCtx.Done()
+		errCh <- bgCtx.Err()
+		return bgCtx.Err()
+	}, WithTaskTimeout(10*time.Millisecond))
+	assert.ErrorIs(t, <-errCh, context.DeadlineExceeded)
+	require.NoError(t, WaitTasks(context.Background()))
+}
+
+func TestGoPanicRecovered(t *testing.T) {
+	Go(nil, "panic", func(bgCtx *gin.Context) error {
+		panic("boom")
+	})
+	require.NoError(t, WaitTasks(context.Background()))
+}
+
+func TestWaitTasksDeadline(t *testing.T) {
+	release := make(chan struct{})
+	Go(nil, "slow", func(bgCtx *gin.Context) error {
+		<-release
+		return nil
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, WaitTasks(ctx), context.DeadlineExceeded)
+	close(release)
+	require.NoError(t, WaitTasks(context.Background()))
+}
+
+func TestGoGroup(t *testing.T) {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Set(zlog.ContextKeyRequestID, "req-2")
+	boom := errors.New("boom")
+
+	g := NewGoGroup(c, "fanout")
+	ids := make(chan string, 3)
+	g.Go(func(bgCtx *gin.Context) error {
+		ids <- zlog.GetRequestID(bgCtx)
+		return nil
+	})
+	g.Go(func(bgCtx *gin.Context) error {
+		ids <- zlog.GetRequestID(bgCtx)
+		return boom
+	})
+	g.Go(func(bgCtx *gin.Context) error {
+		ids <- zlog.GetRequestID(bgCtx)
+		panic("bad")
+	})
+	err := g.Wait()
+	close(ids)
+	for id := range ids {
+		assert.Equal(t, "req-2", id)
+	}
+
+	var multi *utils.MultiError
+	require.ErrorAs(t, err, &multi)
+	assert.Len(t, multi.Errors, 2)
+	assert.ErrorIs(t, err, boom)
+	assert.Contains(t, err.Error(), "task fanout#1: boom")
+	assert.Contains(t, err.Error(), "task fanout#2 panic: bad")
+
+	assert.NoError(t, NewGoGroup(c, "empty").Wait())
+}
+
+type goService struct {
+	Service
+}
+
+func TestLayerGo(t *testing.T) {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Set(zlog.ContextKeyRequestID, "req-3")
+	s := Create(c, &goService{})
+	assert.Equal(t, "flow.goService.Go", s.taskName())
+
+	ids := make(chan string, 1)
+	s.Go(func(ctx *gin.Context) {
+		ids <- zlog.GetRequestID(ctx)
+	})
+	assert.Equal(t, "req-3", <-ids)
+	// panic 被恢复，不影响进程
+	s.Go(func(ctx *gin.Context) {
+		panic("boom")
+	})
+	require.NoError(t, WaitTasks(context.Background()))
+}
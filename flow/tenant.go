@@ -0,0 +1,101 @@
+package flow
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	errors2 "github.com/xiangtao94/golib/pkg/errors"
+	"github.com/xiangtao94/golib/pkg/zlog"
+)
+
+const ctxKeyTenantDB = "__tenantDbName__"
+
+// TenantMode 多租户的隔离方式
+type TenantMode int
+
+const (
+	// TenantNone 不区分租户，使用 GetDB
+	TenantNone TenantMode = iota
+	// TenantDatabase 每个租户一个库，按解析出的名称从 NamedDBClient 中选择
+	TenantDatabase
+	// TenantTablePrefix 所有租户在同一个库，表名为 "租户_表名"
+	TenantTablePrefix
+)
+
+var tenantDBResolver func(ctx *gin.Context) (string, error)
+
+// SetTenantDBResolver 设置从请求中解析租户的函数，如读取请求头或鉴权主体
+// TenantDatabase 模式下返回 NamedDBClient 中的名称，TenantTablePrefix 模式下返回表名前缀
+func SetTenantDBResolver(resolver func(ctx *gin.Context) (dbName string, err error)) {
+	tenantDBResolver = resolver
+}
+
+// SetTenantMode 设置 Dao 的租户隔离方式，设置后 CommonDao 的方法都使用租户的库或表，通常在 OnCreate 中调用
+func (d *Dao) SetTenantMode(mode TenantMode) {
+	d.tenantMode = mode
+}
+
+func (d *Dao) GetTenantMode() TenantMode {
+	return d.tenantMode
+}
+
+// GetTenantName 解析当前请求的租户，同一请求内只解析一次
+func (d *Dao) GetTenantName() (string, error) {
+	ctx := d.GetCtx()
+	if ctx != nil {
+		if name := ctx.GetString(ctxKeyTenantDB); name != "" {
+			return name, nil
+		}
+	}
+	if tenantDBResolver == nil {
+		return "", errors2.WrapError(errors2.SYSTEM_ERROR, errors.New("tenant db resolver is not set, call flow.SetTenantDBResolver"))
+	}
+	name, err := tenantDBResolver(ctx)
+	if err != nil {
+		return "", err
+	}
+	if name == "" {
+		return "", errors2.WrapError(errors2.TENANT_NOT_FOUND, errors.New("tenant is empty"))
+	}
+	if ctx != nil {
+		ctx.Set(ctxKeyTenantDB, name)
+	}
+	return name, nil
+}
+
+// GetTenantDB 返回当前请求租户的 DB；TenantTablePrefix 模式下为默认库，表名加上租户前缀
+// 租户没有对应的库时返回 errors.ErrorTenantNotFound，SetDB 绑定了 DB（如事务中）时直接使用绑定的 DB
+func (d *Dao) GetTenantDB() (*gorm.DB, error) {
+	return d.tenantDB("")
+}
+
+// tenantDB model 为未调用 SetTable 时 TenantTablePrefix 模式使用的表名
+func (d *Dao) tenantDB(model string) (*gorm.DB, error) {
+	name, err := d.GetTenantName()
+	if err != nil {
+		return nil, err
+	}
+	if d.tenantMode == TenantTablePrefix {
+		table := d.tableName
+		if table == "" {
+			table = model
+		}
+		db := d.GetDB()
+		if db == nil {
+			return nil, errors2.WrapError(errors2.SYSTEM_ERROR, errors.New("default db is not set"))
+		}
+		return db.Table(name + "_" + table), nil
+	}
+	if d.db != nil {
+		return d.getDBBase(d.db), nil
+	}
+	db, ok := NamedDBClient[name]
+	if !ok {
+		zlog.Warnf(d.GetCtx(), "tenant db %s is not configured", name)
+		return nil, errors2.WrapError(errors2.TENANT_NOT_FOUND, fmt.Errorf("tenant db %s is not configured", name), "tenantDB", name)
+	}
+	return d.getDBBase(db), nil
+}
@@ -0,0 +1,111 @@
+package flow
+
+import (
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	errors2 "github.com/xiangtao94/golib/pkg/errors"
+)
+
+type tenantUserDao struct {
+	CommonDao[serviceUser]
+}
+
+func (d *tenantUserDao) OnCreate() {
+	d.SetTenantMode(TenantDatabase)
+}
+
+// captureDB 记录在该 DB 上执行的写入 SQL
+func captureDB(t *testing.T) (*gorm.DB, *[]string) {
+	db := newDryRunDB(t)
+	var sqls []string
+	require.NoError(t, db.Callback().Create().After("gorm:create").Register("test:capture", func(tx *gorm.DB) {
+		sqls = append(sqls, tx.Statement.SQL.String())
+	}))
+	return db, &sqls
+}
+
+func setTenantGlobals(t *testing.T, defaultDB *gorm.DB, named map[string]*gorm.DB) {
+	oldDefault, oldNamed, oldResolver := DefaultDBClient, NamedDBClient, tenantDBResolver
+	t.Cleanup(func() {
+		DefaultDBClient, NamedDBClient, tenantDBResolver = oldDefault, oldNamed, oldResolver
+	})
+	SetDefaultDBClient(defaultDB)
+	SetNamedDBClient(named)
+	resolves := 0
+	SetTenantDBResolver(func(ctx *gin.Context) (string, error) {
+		resolves++
+		ctx.Set("resolves", resolves)
+		return ctx.GetHeader("X-Tenant"), nil
+	})
+}
+
+func tenantContext(tenant string) *gin.Context {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("POST", "/", nil)
+	if tenant != "" {
+		c.Request.Header.Set("X-Tenant", tenant)
+	}
+	return c
+}
+
+func TestDao_TenantDatabase(t *testing.T) {
+	db1, sqls1 := captureDB(t)
+	db2, sqls2 := captureDB(t)
+	setTenantGlobals(t, nil, map[string]*gorm.DB{"tenant_a": db1, "tenant_b": db2})
+
+	c := tenantContext("tenant_a")
+	require.NoError(t, Create(c, &tenantUserDao{}).Insert(&serviceUser{Name: "a"}))
+	// 同一请求内的其他 Dao 复用解析结果
+	require.NoError(t, Create(c, &tenantUserDao{}).Insert(&serviceUser{Name: "a2"}))
+	assert.Equal(t, 1, c.GetInt("resolves"))
+	require.NoError(t, Create(tenantContext("tenant_b"), &tenantUserDao{}).Insert(&serviceUser{Name: "b"}))
+
+	assert.Len(t, *sqls1, 2)
+	require.Len(t, *sqls2, 1)
+	assert.Contains(t, (*sqls2)[0], "INSERT INTO `users`")
+}
+
+func TestDao_TenantNotFound(t *testing.T) {
+	db1, sqls1 := captureDB(t)
+	setTenantGlobals(t, db1, map[string]*gorm.DB{"tenant_a": db1})
+
+	for _, tenant := range []string{"tenant_x", ""} {
+		dao := Create(tenantContext(tenant), &tenantUserDao{})
+		err := dao.Insert(&serviceUser{Name: "x"})
+		assert.ErrorIs(t, err, errors2.ErrorTenantNotFound, "tenant %q", tenant)
+		_, err = dao.GetTenantDB()
+		assert.ErrorIs(t, err, errors2.ErrorTenantNotFound)
+	}
+	assert.Empty(t, *sqls1)
+
+	// 解析失败时返回解析函数的错误
+	SetTenantDBResolver(func(*gin.Context) (string, error) { return "", errors2.ErrorUserNotLogin })
+	_, _, err := Create(tenantContext(""), &tenantUserDao{}).List(nil)
+	assert.True(t, errors.Is(err, errors2.ErrorUserNotLogin))
+}
+
+func TestDao_TenantTablePrefix(t *testing.T) {
+	db, sqls := captureDB(t)
+	setTenantGlobals(t, db, nil)
+
+	dao := Create(tenantContext("t1"), &CommonDao[serviceUser]{})
+	dao.SetTenantMode(TenantTablePrefix)
+	require.NoError(t, dao.Insert(&serviceUser{Name: "a"}))
+
+	// 与分表一起使用
+	dao.SetPartitionNum(4)
+	dao.SetTable("users")
+	dao.SetTable(dao.GetPartitionTable(6))
+	require.NoError(t, dao.Insert(&serviceUser{Name: "b"}))
+
+	require.Len(t, *sqls, 2)
+	assert.Contains(t, (*sqls)[0], "INSERT INTO `t1_users`")
+	assert.Contains(t, (*sqls)[1], "INSERT INTO `t1_users2`")
+}
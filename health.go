@@ -0,0 +1,201 @@
+// Package golib -----------------------------
+// @file      : health.go
+// @author    : xiangtao
+// @contact   : xiangtao1994@gmail.com
+// @time      : 2025/9/10 11:05
+// Description: 存活/就绪探针，/readyz 并行执行注册的依赖检查
+// -------------------------------------------
+package golib
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/xiangtao94/golib/pkg/zlog"
+)
+
+// HealthChecker 依赖检查函数，返回 nil 表示健康
+// 各组件在自己的包中提供检查，如 redis.Redis.HealthCheck、milvus.MilvusClient.HealthCheck、
+// elasticsearch.ElasticsearchClient.HealthCheck、orm.HealthChecker(db)，根包不依赖这些组件
+type HealthChecker func(ctx context.Context) error
+
+type HealthConf struct {
+	// LivenessPath 存活探针路径，默认 /healthz
+	LivenessPath string `yaml:"livenessPath"`
+	// ReadinessPath 就绪探针路径，默认 /readyz
+	ReadinessPath string `yaml:"readinessPath"`
+	// Timeout 单个检查的超时时间，默认3s
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+func (conf *HealthConf) checkConf() {
+	if conf.LivenessPath == "" {
+		conf.LivenessPath = "/healthz"
+	}
+	if conf.ReadinessPath == "" {
+		conf.ReadinessPath = "/readyz"
+	}
+	if conf.Timeout <= 0 {
+		conf.Timeout = 3 * time.Second
+	}
+}
+
+type healthCheckResult struct {
+	Name    string `json:"name"`
+	Status  string `json:"status"`
+	Latency string `json:"latency"`
+	Error   string `json:"error,omitempty"`
+}
+
+const (
+	healthStatusUp   = "up"
+	healthStatusDown = "down"
+)
+
+var (
+	healthMu       sync.RWMutex
+	healthCheckers = map[string]HealthChecker{}
+	// 收到退出信号后就绪探针立即返回503，配合 DrainDelay 摘除流量
+	shuttingDown atomic.Bool
+)
+
+// HealthCheck 具名的就绪检查，用于 WithHealthChecks
+type HealthCheck struct {
+	Name  string
+	Check HealthChecker
+}
+
+// RegisterHealthChecker 注册就绪检查，同名覆盖
+func RegisterHealthChecker(name string, fn HealthChecker) {
+	healthMu.Lock()
+	defer healthMu.Unlock()
+	healthCheckers[name] = fn
+}
+
+// WithHealthCheck 注册存活探针和就绪探针，探针请求不打印access日志
+func WithHealthCheck(conf ...HealthConf) BootstrapOption {
+	return func(engine *gin.Engine) {
+		var c HealthConf
+		if len(conf) > 0 {
+			c = conf[0]
+		}
+		c.checkConf()
+		registerHealthRoutes(engine, c)
+	}
+}
+
+// WithHealthChecks 注册就绪检查，以及存活探针 /livez 和就绪探针 /readyz，超时时间使用默认值
+// 可与 WithHealthCheck 同时使用，已注册的路径不会重复注册
+func WithHealthChecks(checks ...HealthCheck) BootstrapOption {
+	return func(engine *gin.Engine) {
+		for _, check := range checks {
+			RegisterHealthChecker(check.Name, check.Check)
+		}
+		c := HealthConf{LivenessPath: "/livez"}
+		c.checkConf()
+		registerHealthRoutes(engine, c)
+	}
+}
+
+// registerHealthRoutes 跳过 engine 上已存在的 GET 路由，重复注册时 gin 会 panic
+func registerHealthRoutes(engine *gin.Engine, c HealthConf) {
+	routes := map[string]bool{}
+	for _, r := range engine.Routes() {
+		if r.Method == http.MethodGet {
+			routes[r.Path] = true
+		}
+	}
+	if !routes[c.LivenessPath] {
+		engine.GET(c.LivenessPath, livenessHandler)
+	}
+	if !routes[c.ReadinessPath] {
+		engine.GET(c.ReadinessPath, readinessHandler(c.Timeout))
+	}
+}
+
+func livenessHandler(ctx *gin.Context) {
+	zlog.SetNoLogFlag(ctx)
+	ctx.JSON(http.StatusOK, gin.H{"status": healthStatusUp})
+}
+
+func readinessHandler(timeout time.Duration) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		zlog.SetNoLogFlag(ctx)
+		if shuttingDown.Load() {
+			ctx.JSON(http.StatusServiceUnavailable, gin.H{"status": healthStatusDown, "error": "shutting down"})
+			return
+		}
+		results := runHealthCheckers(ctx.Request.Context(), timeout)
+		status, code := healthStatusUp, http.StatusOK
+		for _, r := range results {
+			if r.Status != healthStatusUp {
+				status, code = healthStatusDown, http.StatusServiceUnavailable
+				break
+			}
+		}
+		ctx.JSON(code, gin.H{"status": status, "checks": results})
+	}
+}
+
+// runHealthCheckers 并行执行所有检查，每个检查单独超时
+func runHealthCheckers(ctx context.Context, timeout time.Duration) []healthCheckResult {
+	healthMu.RLock()
+	checkers := make(map[string]HealthChecker, len(healthCheckers))
+	for name, fn := range healthCheckers {
+		checkers[name] = fn
+	}
+	healthMu.RUnlock()
+
+	var (
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		results = make([]healthCheckResult, 0, len(checkers))
+	)
+	for name, fn := range checkers {
+		wg.Add(1)
+		go func(name string, fn HealthChecker) {
+			defer wg.Done()
+			start := time.Now()
+			err := runHealthChecker(ctx, fn, timeout)
+			r := healthCheckResult{Name: name, Status: healthStatusUp, Latency: time.Since(start).String()}
+			if err != nil {
+				r.Status = healthStatusDown
+				r.Error = err.Error()
+			}
+			mu.Lock()
+			results = append(results, r)
+			mu.Unlock()
+		}(name, fn)
+	}
+	wg.Wait()
+
+	sort.Slice(results, func(i, j int) bool { return results[i].Name < results[j].Name })
+	return results
+}
+
+func runHealthChecker(ctx context.Context, fn HealthChecker, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- fmt.Errorf("panic: %v", r)
+			}
+		}()
+		done <- fn(ctx)
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("timeout: %w", ctx.Err())
+	}
+}
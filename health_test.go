@@ -0,0 +1,131 @@
+package golib
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func resetHealthCheckers(t *testing.T) {
+	healthMu.Lock()
+	healthCheckers = map[string]HealthChecker{}
+	healthMu.Unlock()
+	t.Cleanup(func() {
+		healthMu.Lock()
+		healthCheckers = map[string]HealthChecker{}
+		healthMu.Unlock()
+		shuttingDown.Store(false)
+	})
+}
+
+func doHealthRequest(engine *gin.Engine, path string) (int, map[string]any) {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, path, nil)
+	engine.ServeHTTP(w, req)
+	var body map[string]any
+	_ = json.Unmarshal(w.Body.Bytes(), &body)
+	return w.Code, body
+}
+
+func TestHealthCheck(t *testing.T) {
+	resetHealthCheckers(t)
+
+	var redisDown atomic.Bool
+	RegisterHealthChecker("mysql", func(ctx context.Context) error { return nil })
+	RegisterHealthChecker("redis", func(ctx context.Context) error {
+		if redisDown.Load() {
+			return errors.New("connection refused")
+		}
+		return nil
+	})
+
+	engine := gin.New()
+	Bootstraps(engine, WithHealthCheck())
+
+	code, body := doHealthRequest(engine, "/healthz")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "up", body["status"])
+
+	code, body = doHealthRequest(engine, "/readyz")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Len(t, body["checks"], 2)
+
+	// 依赖故障时就绪探针返回503，存活探针不受影响
+	redisDown.Store(true)
+	code, body = doHealthRequest(engine, "/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "down", body["status"])
+	checks := body["checks"].([]any)
+	redisCheck := checks[1].(map[string]any)
+	assert.Equal(t, "redis", redisCheck["name"])
+	assert.Equal(t, "down", redisCheck["status"])
+	assert.Equal(t, "connection refused", redisCheck["error"])
+	code, _ = doHealthRequest(engine, "/healthz")
+	assert.Equal(t, http.StatusOK, code)
+
+	// 依赖恢复后就绪探针恢复200
+	redisDown.Store(false)
+	code, _ = doHealthRequest(engine, "/readyz")
+	assert.Equal(t, http.StatusOK, code)
+
+	// 退出过程中就绪探针返回503
+	shuttingDown.Store(true)
+	code, _ = doHealthRequest(engine, "/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+}
+
+func TestHealthCheckTimeout(t *testing.T) {
+	resetHealthCheckers(t)
+
+	RegisterHealthChecker("slow-a", func(ctx context.Context) error {
+		time.Sleep(time.Second)
+		return nil
+	})
+	RegisterHealthChecker("slow-b", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	engine := gin.New()
+	Bootstraps(engine, WithHealthCheck(HealthConf{ReadinessPath: "/ready", Timeout: 50 * time.Millisecond}))
+
+	// 检查并行执行，总耗时接近单个超时
+	start := time.Now()
+	code, _ := doHealthRequest(engine, "/ready")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+}
+
+func TestWithHealthChecks(t *testing.T) {
+	resetHealthCheckers(t)
+
+	engine := gin.New()
+	Bootstraps(engine, WithHealthChecks(
+		HealthCheck{Name: "mysql", Check: func(ctx context.Context) error { return nil }},
+		HealthCheck{Name: "milvus", Check: func(ctx context.Context) error { return errors.New("unavailable") }},
+	))
+
+	code, _ := doHealthRequest(engine, "/livez")
+	assert.Equal(t, http.StatusOK, code)
+	code, body := doHealthRequest(engine, "/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	checks := body["checks"].([]any)
+	assert.Len(t, checks, 2)
+	assert.Equal(t, "milvus", checks[0].(map[string]any)["name"])
+	assert.Equal(t, "down", checks[0].(map[string]any)["status"])
+
+	// 与 WithHealthCheck 一起使用时不重复注册 /readyz
+	assert.NotPanics(t, func() {
+		Bootstraps(engine, WithHealthCheck(), WithHealthChecks())
+	})
+	code, _ = doHealthRequest(engine, "/healthz")
+	assert.Equal(t, http.StatusOK, code)
+}
@@ -0,0 +1,279 @@
+// Package elasticsearch -----------------------------
+// @file      : alias.go
+// @author    : xiangtao
+// @contact   : xiangtao1994@gmail.com
+// @time      : 2025/9/14 16:20
+// Description: 索引别名管理与基于 reindex 的零停机重建索引
+// -------------------------------------------
+package elasticsearch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v8/typedapi/types"
+	"github.com/gin-gonic/gin"
+
+	"github.com/xiangtao94/golib/pkg/zlog"
+)
+
+const (
+	_defaultReindexPollInterval = 2 * time.Second
+	// _cancelTaskTimeout ctx 取消后用于取消 ES 任务的超时时间
+	_cancelTaskTimeout = 10 * time.Second
+)
+
+// ErrReindexFailed reindex 任务完成但存在失败的文档或任务本身出错
+var ErrReindexFailed = errors.New("elasticsearch reindex failed")
+
+// GetAliasIndices 返回别名指向的索引（按名称排序），别名不存在时返回空
+func (ec *ElasticsearchClient) GetAliasIndices(ctx *gin.Context, alias string) ([]string, error) {
+	ec.appendContext(ctx)
+	exists, err := ec.Client.Indices.ExistsAlias(alias).Do(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check alias %s: %w", alias, err)
+	}
+	if !exists {
+		return nil, nil
+	}
+	res, err := ec.Client.Indices.GetAlias().Name(alias).Do(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get alias %s: %w", alias, err)
+	}
+	indices := make([]string, 0, len(res))
+	for index := range res {
+		indices = append(indices, index)
+	}
+	sort.Strings(indices)
+	return indices, nil
+}
+
+// EnsureAlias 使别名只指向 index，别名已指向其他索引时在同一请求中移除，切换是原子的
+func (ec *ElasticsearchClient) EnsureAlias(ctx *gin.Context, alias, index string) error {
+	current, err := ec.GetAliasIndices(ctx, alias)
+	if err != nil {
+		return err
+	}
+	if len(current) == 1 && current[0] == index {
+		return nil
+	}
+	actions := []types.IndicesAction{addAlias(alias, index)}
+	for _, old := range current {
+		if old != index {
+			actions = append(actions, removeAlias(alias, old))
+		}
+	}
+	return ec.updateAliases(ctx, actions)
+}
+
+// SwapAlias 把别名从 fromIndex 切换到 toIndex，添加和移除在同一个 update aliases 请求中完成，读写不会看到中间状态
+func (ec *ElasticsearchClient) SwapAlias(ctx *gin.Context, alias, fromIndex, toIndex string) error {
+	return ec.updateAliases(ctx, []types.IndicesAction{addAlias(alias, toIndex), removeAlias(alias, fromIndex)})
+}
+
+func (ec *ElasticsearchClient) updateAliases(ctx *gin.Context, actions []types.IndicesAction) error {
+	ec.appendContext(ctx)
+	res, err := ec.Client.Indices.UpdateAliases().Actions(actions...).Do(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to update aliases: %w", err)
+	}
+	if !res.Acknowledged {
+		return errors.New("failed to update aliases: not acknowledged")
+	}
+	return nil
+}
+
+func addAlias(alias, index string) types.IndicesAction {
+	return types.IndicesAction{Add: &types.AddAction{Alias: &alias, Index: &index}}
+}
+
+func removeAlias(alias, index string) types.IndicesAction {
+	return types.IndicesAction{Remove: &types.RemoveAction{Alias: &alias, Index: &index}}
+}
+
+// ReindexOptions reindex 参数
+type ReindexOptions struct {
+	Query        *types.Query          // 只复制匹配的文档，为空复制全部
+	Script       *types.Script         // 复制时对文档做转换，为空原样复制
+	Slices       string                // 并行切片数，如 "auto"，为空不切片
+	PollInterval time.Duration         // 查询任务进度的间隔，默认2s
+	OnProgress   func(ReindexProgress) // 每次查询到任务进度时回调
+}
+
+// ReindexProgress reindex 任务的进度，任务完成时为最终结果
+type ReindexProgress struct {
+	Total            int64 `json:"total"`
+	Created          int64 `json:"created"`
+	Updated          int64 `json:"updated"`
+	Deleted          int64 `json:"deleted"`
+	Batches          int64 `json:"batches"`
+	VersionConflicts int64 `json:"version_conflicts"`
+	Noops            int64 `json:"noops"`
+}
+
+// ReindexResult reindex 任务的结果
+type ReindexResult struct {
+	ReindexProgress
+	Failures []string // 失败文档的原因，格式为 index/id: reason
+	Took     time.Duration
+}
+
+// reindexResponse 任务完成后 response 字段的内容
+type reindexResponse struct {
+	ReindexProgress
+	Took     int64                            `json:"took"`
+	Failures []types.BulkIndexByScrollFailure `json:"failures"`
+}
+
+// Reindex 把 sourceIndex 的文档复制到 destIndex，以异步任务提交后轮询任务 API 直到完成
+// 有失败的文档时返回结果和 ErrReindexFailed；ctx 被取消时（gin 需开启 ContextWithFallback）会取消 ES 上的任务
+func (ec *ElasticsearchClient) Reindex(ctx *gin.Context, sourceIndex, destIndex string, opts ReindexOptions) (*ReindexResult, error) {
+	ec.appendContext(ctx)
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = _defaultReindexPollInterval
+	}
+	req := ec.Client.Reindex().
+		Source(&types.ReindexSource{Index: []string{sourceIndex}, Query: opts.Query}).
+		Dest(&types.ReindexDestination{Index: destIndex}).
+		WaitForCompletion(false)
+	if opts.Script != nil {
+		req = req.Script(opts.Script)
+	}
+	if opts.Slices != "" {
+		req = req.Slices(opts.Slices)
+	}
+	res, err := req.Do(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start reindex %s -> %s: %w", sourceIndex, destIndex, err)
+	}
+	if res.Task == nil {
+		return nil, fmt.Errorf("failed to start reindex %s -> %s: no task id returned", sourceIndex, destIndex)
+	}
+	taskID := fmt.Sprint(res.Task)
+	zlog.Infof(ctx, "reindex %s -> %s started, task: %s", sourceIndex, destIndex, taskID)
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			ec.cancelTask(ctx, taskID)
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+		task, err := ec.Client.Tasks.Get(taskID).Do(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				ec.cancelTask(ctx, taskID)
+				return nil, ctx.Err()
+			}
+			return nil, fmt.Errorf("failed to get reindex task %s: %w", taskID, err)
+		}
+		if !task.Completed {
+			var progress ReindexProgress
+			if len(task.Task.Status) > 0 {
+				if err = json.Unmarshal(task.Task.Status, &progress); err != nil {
+					return nil, fmt.Errorf("failed to decode reindex task %s status: %w", taskID, err)
+				}
+			}
+			if opts.OnProgress != nil {
+				opts.OnProgress(progress)
+			}
+			timer.Reset(opts.PollInterval)
+			continue
+		}
+		return ec.reindexResult(ctx, taskID, task.Error, task.Response, opts.OnProgress)
+	}
+}
+
+func (ec *ElasticsearchClient) reindexResult(ctx *gin.Context, taskID string, taskErr *types.ErrorCause, raw json.RawMessage, onProgress func(ReindexProgress)) (*ReindexResult, error) {
+	if taskErr != nil {
+		reason := taskErr.Type
+		if taskErr.Reason != nil {
+			reason += ": " + *taskErr.Reason
+		}
+		zlog.Errorf(ctx, "reindex task %s failed: %s", taskID, reason)
+		return nil, fmt.Errorf("%w: task %s: %s", ErrReindexFailed, taskID, reason)
+	}
+	var resp reindexResponse
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, &resp); err != nil {
+			return nil, fmt.Errorf("failed to decode reindex task %s response: %w", taskID, err)
+		}
+	}
+	if onProgress != nil {
+		onProgress(resp.ReindexProgress)
+	}
+	result := &ReindexResult{ReindexProgress: resp.ReindexProgress, Took: time.Duration(resp.Took) * time.Millisecond}
+	for _, f := range resp.Failures {
+		reason := f.Cause.Type
+		if f.Cause.Reason != nil {
+			reason += ": " + *f.Cause.Reason
+		}
+		result.Failures = append(result.Failures, fmt.Sprintf("%s/%s: %s", f.Index, f.Id, reason))
+	}
+	if len(result.Failures) > 0 {
+		zlog.Errorf(ctx, "reindex task %s finished with %d failures, first: %s", taskID, len(result.Failures), result.Failures[0])
+		return result, fmt.Errorf("%w: task %s has %d failures", ErrReindexFailed, taskID, len(result.Failures))
+	}
+	zlog.Infof(ctx, "reindex task %s finished, total: %d, created: %d, updated: %d, took: %v",
+		taskID, result.Total, result.Created, result.Updated, result.Took)
+	return result, nil
+}
+
+// cancelTask ctx 已取消，使用独立的超时取消 ES 任务，失败只记录日志
+func (ec *ElasticsearchClient) cancelTask(ctx *gin.Context, taskID string) {
+	cancelCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), _cancelTaskTimeout)
+	defer cancel()
+	if _, err := ec.Client.Tasks.Cancel().TaskId(taskID).Do(cancelCtx); err != nil {
+		zlog.Errorf(ctx, "failed to cancel reindex task %s: %v", taskID, err)
+		return
+	}
+	zlog.Warnf(ctx, "reindex task %s cancelled", taskID)
+}
+
+// RebuildOptions RebuildIndex 参数
+type RebuildOptions struct {
+	Mapping   *types.TypeMapping   // 新索引的 mapping
+	Settings  *types.IndexSettings // 新索引的 settings，可为空
+	Reindex   ReindexOptions       // 从旧索引复制数据的参数
+	DeleteOld bool                 // 切换别名后删除旧索引
+}
+
+// RebuildIndex 零停机重建别名背后的索引：按 mapping 创建 alias_时间戳 的新索引，从别名当前指向的索引 reindex，
+// 再原子切换别名，返回新索引名。别名不存在时只创建索引并添加别名
+// reindex 失败时删除新索引，别名保持不变；切换期间写入旧索引的数据不会被复制，需要业务暂停写入或切换后补偿
+func (ec *ElasticsearchClient) RebuildIndex(ctx *gin.Context, alias string, opts RebuildOptions) (string, error) {
+	oldIndices, err := ec.GetAliasIndices(ctx, alias)
+	if err != nil {
+		return "", err
+	}
+	newIndex := fmt.Sprintf("%s_%s", alias, time.Now().Format("20060102150405"))
+	if err = ec.CreateIndex(ctx, newIndex, opts.Mapping, opts.Settings); err != nil {
+		return "", fmt.Errorf("failed to create index %s: %w", newIndex, err)
+	}
+	if len(oldIndices) > 0 {
+		if _, err = ec.Reindex(ctx, alias, newIndex, opts.Reindex); err != nil {
+			if delErr := ec.DeleteIndex(ctx, newIndex); delErr != nil {
+				zlog.Errorf(ctx, "failed to delete index %s after reindex failure: %v", newIndex, delErr)
+			}
+			return "", err
+		}
+	}
+	if err = ec.EnsureAlias(ctx, alias, newIndex); err != nil {
+		return "", err
+	}
+	zlog.Infof(ctx, "alias %s switched from %v to %s", alias, oldIndices, newIndex)
+	if opts.DeleteOld {
+		for _, old := range oldIndices {
+			if err = ec.DeleteIndex(ctx, old); err != nil {
+				return newIndex, fmt.Errorf("alias switched to %s but failed to delete old index %s: %w", newIndex, old, err)
+			}
+		}
+	}
+	return newIndex, nil
+}
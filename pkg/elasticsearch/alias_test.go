@@ -0,0 +1,303 @@
+package elasticsearch
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v8"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type stubCall struct {
+	method, path, query, body string
+}
+
+// stubTransport 按 "METHOD path" 返回预设响应，同一路由有多个响应时依次返回，最后一个重复使用
+type stubTransport struct {
+	mu        sync.Mutex
+	responses map[string][]stubResponse
+	calls     []stubCall
+}
+
+type stubResponse struct {
+	status int
+	body   string
+}
+
+func (s *stubTransport) on(method, path string, status int, body string) *stubTransport {
+	if s.responses == nil {
+		s.responses = map[string][]stubResponse{}
+	}
+	key := method + " " + path
+	s.responses[key] = append(s.responses[key], stubResponse{status: status, body: body})
+	return s
+}
+
+func (s *stubTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	var body []byte
+	if r.Body != nil {
+		body, _ = io.ReadAll(r.Body)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls = append(s.calls, stubCall{method: r.Method, path: r.URL.Path, query: r.URL.RawQuery, body: string(body)})
+	key := r.Method + " " + r.URL.Path
+	resp := stubResponse{status: http.StatusNotFound, body: `{"error":{"type":"not_found","reason":"no stub"},"status":404}`}
+	if rs := s.responses[key]; len(rs) > 0 {
+		resp = rs[0]
+		if len(rs) > 1 {
+			s.responses[key] = rs[1:]
+		}
+	}
+	header := http.Header{}
+	header.Set("Content-Type", "application/json")
+	header.Set("X-Elastic-Product", "Elasticsearch")
+	return &http.Response{
+		StatusCode: resp.status,
+		Header:     header,
+		Body:       io.NopCloser(strings.NewReader(resp.body)),
+		Request:    r,
+	}, nil
+}
+
+func (s *stubTransport) find(method, path string) []stubCall {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var calls []stubCall
+	for _, c := range s.calls {
+		if c.method == method && c.path == path {
+			calls = append(calls, c)
+		}
+	}
+	return calls
+}
+
+func newStubClient(t *testing.T, stub *stubTransport) *ElasticsearchClient {
+	client, err := elasticsearch.NewTypedClient(elasticsearch.Config{
+		Addresses: []string{"http://es.local:9200"},
+		Transport: stub,
+	})
+	require.NoError(t, err)
+	return &ElasticsearchClient{Client: client}
+}
+
+func newTestContext() *gin.Context {
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	ctx.Request = httptest.NewRequest(http.MethodPost, "/", nil)
+	return ctx
+}
+
+const ack = `{"acknowledged":true}`
+
+func TestSwapAlias(t *testing.T) {
+	stub := (&stubTransport{}).on(http.MethodPost, "/_aliases", http.StatusOK, ack)
+	ec := newStubClient(t, stub)
+
+	require.NoError(t, ec.SwapAlias(newTestContext(), "products", "products_v1", "products_v2"))
+	calls := stub.find(http.MethodPost, "/_aliases")
+	require.Len(t, calls, 1)
+	assert.JSONEq(t, `{"actions":[
+		{"add":{"alias":"products","index":"products_v2"}},
+		{"remove":{"alias":"products","index":"products_v1"}}
+	]}`, calls[0].body)
+}
+
+func TestEnsureAlias(t *testing.T) {
+	stub := (&stubTransport{}).
+		on(http.MethodHead, "/_alias/products", http.StatusOK, "").
+		on(http.MethodGet, "/_alias/products", http.StatusOK,
+			`{"products_v1":{"aliases":{"products":{}}},"products_v0":{"aliases":{"products":{}}}}`).
+		on(http.MethodPost, "/_aliases", http.StatusOK, ack)
+	ec := newStubClient(t, stub)
+	ctx := newTestContext()
+
+	indices, err := ec.GetAliasIndices(ctx, "products")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"products_v0", "products_v1"}, indices)
+
+	require.NoError(t, ec.EnsureAlias(ctx, "products", "products_v2"))
+	calls := stub.find(http.MethodPost, "/_aliases")
+	require.Len(t, calls, 1)
+	assert.JSONEq(t, `{"actions":[
+		{"add":{"alias":"products","index":"products_v2"}},
+		{"remove":{"alias":"products","index":"products_v0"}},
+		{"remove":{"alias":"products","index":"products_v1"}}
+	]}`, calls[0].body)
+}
+
+func TestEnsureAlias_Missing(t *testing.T) {
+	stub := (&stubTransport{}).
+		on(http.MethodHead, "/_alias/orders", http.StatusNotFound, "").
+		on(http.MethodPost, "/_aliases", http.StatusOK, ack)
+	ec := newStubClient(t, stub)
+	ctx := newTestContext()
+
+	indices, err := ec.GetAliasIndices(ctx, "orders")
+	require.NoError(t, err)
+	assert.Empty(t, indices)
+
+	require.NoError(t, ec.EnsureAlias(ctx, "orders", "orders_v1"))
+	calls := stub.find(http.MethodPost, "/_aliases")
+	require.Len(t, calls, 1)
+	assert.JSONEq(t, `{"actions":[{"add":{"alias":"orders","index":"orders_v1"}}]}`, calls[0].body)
+	assert.Empty(t, stub.find(http.MethodGet, "/_alias/orders"))
+}
+
+func TestReindex(t *testing.T) {
+	stub := (&stubTransport{}).
+		on(http.MethodPost, "/_reindex", http.StatusOK, `{"task":"node1:42"}`).
+		on(http.MethodGet, "/_tasks/node1:42", http.StatusOK,
+			`{"completed":false,"task":{"node":"node1","id":42,"status":{"total":10,"created":3}}}`).
+		on(http.MethodGet, "/_tasks/node1:42", http.StatusOK,
+			`{"completed":false,"task":{"node":"node1","id":42,"status":{"total":10,"created":7}}}`).
+		on(http.MethodGet, "/_tasks/node1:42", http.StatusOK,
+			`{"completed":true,"task":{"node":"node1","id":42},"response":{"took":1500,"total":10,"created":9,"updated":1,"failures":[]}}`)
+	ec := newStubClient(t, stub)
+
+	var progress []ReindexProgress
+	res, err := ec.Reindex(newTestContext(), "products_v1", "products_v2", ReindexOptions{
+		PollInterval: time.Millisecond,
+		OnProgress:   func(p ReindexProgress) { progress = append(progress, p) },
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(9), res.Created)
+	assert.Equal(t, int64(1), res.Updated)
+	assert.Equal(t, 1500*time.Millisecond, res.Took)
+	assert.Empty(t, res.Failures)
+	require.Len(t, progress, 3)
+	assert.Equal(t, int64(3), progress[0].Created)
+	assert.Equal(t, int64(7), progress[1].Created)
+	assert.Equal(t, int64(9), progress[2].Created)
+
+	calls := stub.find(http.MethodPost, "/_reindex")
+	require.Len(t, calls, 1)
+	assert.Contains(t, calls[0].query, "wait_for_completion=false")
+	assert.JSONEq(t, `{"source":{"index":["products_v1"]},"dest":{"index":"products_v2"}}`, calls[0].body)
+	assert.Len(t, stub.find(http.MethodGet, "/_tasks/node1:42"), 3)
+}
+
+func TestReindex_Failures(t *testing.T) {
+	stub := (&stubTransport{}).
+		on(http.MethodPost, "/_reindex", http.StatusOK, `{"task":"node1:7"}`).
+		on(http.MethodGet, "/_tasks/node1:7", http.StatusOK, `{"completed":true,"task":{"node":"node1","id":7},"response":{
+			"total":2,"created":1,"failures":[{"index":"products_v2","id":"doc-2","status":400,
+			"cause":{"type":"mapper_parsing_exception","reason":"failed to parse field [price]"}}]}}`)
+	ec := newStubClient(t, stub)
+
+	res, err := ec.Reindex(newTestContext(), "products_v1", "products_v2", ReindexOptions{PollInterval: time.Millisecond})
+	assert.ErrorIs(t, err, ErrReindexFailed)
+	require.NotNil(t, res)
+	assert.Equal(t, int64(1), res.Created)
+	assert.Equal(t, []string{"products_v2/doc-2: mapper_parsing_exception: failed to parse field [price]"}, res.Failures)
+
+	// 任务本身失败
+	stub = (&stubTransport{}).
+		on(http.MethodPost, "/_reindex", http.StatusOK, `{"task":"node1:8"}`).
+		on(http.MethodGet, "/_tasks/node1:8", http.StatusOK, `{"completed":true,"task":{"node":"node1","id":8},
+			"error":{"type":"index_not_found_exception","reason":"no such index [products_v1]"}}`)
+	ec = newStubClient(t, stub)
+	res, err = ec.Reindex(newTestContext(), "products_v1", "products_v2", ReindexOptions{PollInterval: time.Millisecond})
+	assert.ErrorIs(t, err, ErrReindexFailed)
+	assert.Contains(t, err.Error(), "no such index")
+	assert.Nil(t, res)
+}
+
+func TestReindex_CancelTask(t *testing.T) {
+	stub := (&stubTransport{}).
+		on(http.MethodPost, "/_reindex", http.StatusOK, `{"task":"node1:9"}`).
+		on(http.MethodGet, "/_tasks/node1:9", http.StatusOK, `{"completed":false,"task":{"node":"node1","id":9,"status":{"total":10}}}`).
+		on(http.MethodPost, "/_tasks/node1:9/_cancel", http.StatusOK, `{"nodes":{}}`)
+	ec := newStubClient(t, stub)
+
+	ctx, engine := gin.CreateTestContext(httptest.NewRecorder())
+	engine.ContextWithFallback = true
+	reqCtx, cancel := context.WithCancel(context.Background())
+	ctx.Request = httptest.NewRequest(http.MethodPost, "/", nil).WithContext(reqCtx)
+
+	_, err := ec.Reindex(ctx, "products_v1", "products_v2", ReindexOptions{
+		PollInterval: time.Millisecond,
+		OnProgress:   func(ReindexProgress) { cancel() },
+	})
+	assert.True(t, errors.Is(err, context.Canceled))
+	assert.Len(t, stub.find(http.MethodPost, "/_tasks/node1:9/_cancel"), 1)
+}
+
+func TestRebuildIndex(t *testing.T) {
+	stub := (&stubTransport{}).
+		on(http.MethodHead, "/_alias/products", http.StatusOK, "").
+		on(http.MethodGet, "/_alias/products", http.StatusOK, `{"products_v1":{"aliases":{"products":{}}}}`).
+		on(http.MethodPost, "/_reindex", http.StatusOK, `{"task":"node1:1"}`).
+		on(http.MethodGet, "/_tasks/node1:1", http.StatusOK, `{"completed":true,"task":{"node":"node1","id":1},"response":{"total":1,"created":1}}`).
+		on(http.MethodPost, "/_aliases", http.StatusOK, ack).
+		on(http.MethodDelete, "/products_v1", http.StatusOK, ack)
+	// 新索引名带时间戳，按前缀匹配
+	var created string
+	client, err := elasticsearch.NewTypedClient(elasticsearch.Config{
+		Addresses: []string{"http://es.local:9200"},
+		Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+			if r.Method == http.MethodPut && strings.HasPrefix(r.URL.Path, "/products_") {
+				created = strings.TrimPrefix(r.URL.Path, "/")
+				stub.on(http.MethodPut, r.URL.Path, http.StatusOK, `{"acknowledged":true,"shards_acknowledged":true,"index":"`+created+`"}`)
+			}
+			return stub.RoundTrip(r)
+		}),
+	})
+	require.NoError(t, err)
+	ec := &ElasticsearchClient{Client: client}
+
+	newIndex, err := ec.RebuildIndex(newTestContext(), "products", RebuildOptions{DeleteOld: true, Reindex: ReindexOptions{PollInterval: time.Millisecond}})
+	require.NoError(t, err)
+	assert.Equal(t, created, newIndex)
+
+	reindex := stub.find(http.MethodPost, "/_reindex")
+	require.Len(t, reindex, 1)
+	assert.JSONEq(t, `{"source":{"index":["products"]},"dest":{"index":"`+newIndex+`"}}`, reindex[0].body)
+	aliases := stub.find(http.MethodPost, "/_aliases")
+	require.Len(t, aliases, 1)
+	assert.JSONEq(t, `{"actions":[
+		{"add":{"alias":"products","index":"`+newIndex+`"}},
+		{"remove":{"alias":"products","index":"products_v1"}}
+	]}`, aliases[0].body)
+	assert.Len(t, stub.find(http.MethodDelete, "/products_v1"), 1)
+}
+
+func TestRebuildIndex_ReindexFailed(t *testing.T) {
+	stub := (&stubTransport{}).
+		on(http.MethodHead, "/_alias/products", http.StatusOK, "").
+		on(http.MethodGet, "/_alias/products", http.StatusOK, `{"products_v1":{"aliases":{"products":{}}}}`).
+		on(http.MethodPost, "/_reindex", http.StatusOK, `{"task":"node1:2"}`).
+		on(http.MethodGet, "/_tasks/node1:2", http.StatusOK, `{"completed":true,"task":{"node":"node1","id":2},
+			"error":{"type":"search_phase_execution_exception","reason":"all shards failed"}}`)
+	var created string
+	client, err := elasticsearch.NewTypedClient(elasticsearch.Config{
+		Addresses: []string{"http://es.local:9200"},
+		Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+			if strings.HasPrefix(r.URL.Path, "/products_2") {
+				created = r.URL.Path
+				stub.on(r.Method, r.URL.Path, http.StatusOK, `{"acknowledged":true,"index":"`+strings.TrimPrefix(created, "/")+`"}`)
+			}
+			return stub.RoundTrip(r)
+		}),
+	})
+	require.NoError(t, err)
+	ec := &ElasticsearchClient{Client: client}
+
+	_, err = ec.RebuildIndex(newTestContext(), "products", RebuildOptions{Reindex: ReindexOptions{PollInterval: time.Millisecond}})
+	assert.ErrorIs(t, err, ErrReindexFailed)
+	// 新索引被删除，别名没有切换
+	assert.Len(t, stub.find(http.MethodDelete, created), 1)
+	assert.Empty(t, stub.find(http.MethodPost, "/_aliases"))
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
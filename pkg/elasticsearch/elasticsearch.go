@@ -17,12 +17,15 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 
+	"github.com/xiangtao94/golib/pkg/utils"
 	"github.com/xiangtao94/golib/pkg/zlog"
 )
 
 const (
 	_defaultPrintRequestLen  = 512
 	_defaultPrintResponseLen = 10240
+	// bulkInsertBatchSize DocumentInsert 单次 bulk 请求的文档数
+	bulkInsertBatchSize = 3000
 )
 
 const (
@@ -81,6 +84,18 @@ func InitESClient(conf ElasticConf) (*ElasticsearchClient, error) {
 	}, nil
 }
 
+// HealthCheck 通过 ping 检查 elasticsearch 集群，可用于就绪探针（golib.RegisterHealthChecker）
+func (ec *ElasticsearchClient) HealthCheck(ctx context.Context) error {
+	ok, err := ec.Client.Ping().IsSuccess(ctx)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("elasticsearch ping failed")
+	}
+	return nil
+}
+
 // CheckIndex 判断索引是否存在
 func (ec *ElasticsearchClient) CheckIndex(ctx *gin.Context, indexName string) (bool, error) {
 	ec.appendContext(ctx)
@@ -118,33 +133,34 @@ func (ec *ElasticsearchClient) DeleteIndex(ctx *gin.Context, indexName string) e
 	return nil
 }
 
-// BulkInsert 批量插入数据，批量限制为 3000 条
+// BulkInsert 批量插入数据，每 3000 条发送一次 bulk 请求，某一批失败时停止，之前的批次已写入
 func (ec *ElasticsearchClient) DocumentInsert(ctx *gin.Context, indexName string, docs []any) (err error) {
 	ec.appendContext(ctx)
-	bulk := ec.Client.Bulk().Index(indexName)
-	for _, doc := range docs {
-		// 获取当前时间戳（秒级）
-		timestamp := time.Now().UnixMicro()
-		id := uuid.NewString()
-		// 将时间戳与文档内容连接
-		combined := fmt.Sprintf("%s%d", id, timestamp)
-		// 生成SHA256哈希
-		hash := sha256.Sum256([]byte(combined))
-		// Base64编码哈希值
-		uniqueID := base64.StdEncoding.EncodeToString(hash[:])
-		err = bulk.CreateOp(types.CreateOperation{Index_: &indexName, Id_: &uniqueID}, doc)
+	return utils.ForEachBatch(docs, bulkInsertBatchSize, func(batch []any) error {
+		bulk := ec.Client.Bulk().Index(indexName)
+		for _, doc := range batch {
+			// 获取当前时间戳（秒级）
+			timestamp := time.Now().UnixMicro()
+			id := uuid.NewString()
+			// 将时间戳与文档内容连接
+			combined := fmt.Sprintf("%s%d", id, timestamp)
+			// 生成SHA256哈希
+			hash := sha256.Sum256([]byte(combined))
+			// Base64编码哈希值
+			uniqueID := base64.StdEncoding.EncodeToString(hash[:])
+			if err := bulk.CreateOp(types.CreateOperation{Index_: &indexName, Id_: &uniqueID}, doc); err != nil {
+				return err
+			}
+		}
+		resp, err := bulk.Do(ctx)
 		if err != nil {
 			return err
 		}
-	}
-	resp, err := bulk.Do(ctx)
-	if err != nil {
-		return err
-	}
-	if resp.Errors {
-		return fmt.Errorf("elastic search error: %v", resp.Errors)
-	}
-	return nil
+		if resp.Errors {
+			return fmt.Errorf("elastic search error: %v", resp.Errors)
+		}
+		return nil
+	})
 }
 
 // BulkDelete 批量删除文档
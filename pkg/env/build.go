@@ -0,0 +1,66 @@
+package env
+
+import (
+	"sync"
+	"time"
+)
+
+// unknownBuildInfo 未设置构建信息时的取值
+const unknownBuildInfo = "unknown"
+
+// 构建信息，可通过 SetBuildInfo 设置，也可在编译时注入：
+//
+//	go build -ldflags "-X github.com/xiangtao94/golib/pkg/env.version=v1.2.3 -X github.com/xiangtao94/golib/pkg/env.gitSHA=$(git rev-parse --short HEAD)"
+var (
+	buildMu   sync.RWMutex
+	version   string
+	gitSHA    string
+	buildTime string
+
+	// 进程启动时间
+	startTime = time.Now()
+)
+
+// SetBuildInfo 设置构建版本、git 提交和构建时间，一般在 main 中用 -ldflags 注入的变量调用
+func SetBuildInfo(ver, sha, built string) {
+	buildMu.Lock()
+	defer buildMu.Unlock()
+	version, gitSHA, buildTime = ver, sha, built
+}
+
+// GetVersion 构建版本，未设置时为 unknown
+func GetVersion() string {
+	return buildInfoValue(&version)
+}
+
+// GetGitSHA 构建时的 git 提交，未设置时为 unknown
+func GetGitSHA() string {
+	return buildInfoValue(&gitSHA)
+}
+
+// GetBuildTime 构建时间，未设置时为 unknown
+func GetBuildTime() string {
+	return buildInfoValue(&buildTime)
+}
+
+// GetStartTime 进程启动时间
+func GetStartTime() time.Time {
+	return startTime
+}
+
+// GetRunMode 当前激活的环境，即环境变量 APP_ENV，未设置时为 unknown
+func GetRunMode() string {
+	if mode := GetEnv(APP_ENV, ""); mode != "" {
+		return mode
+	}
+	return unknownBuildInfo
+}
+
+func buildInfoValue(v *string) string {
+	buildMu.RLock()
+	defer buildMu.RUnlock()
+	if *v == "" {
+		return unknownBuildInfo
+	}
+	return *v
+}
@@ -59,7 +59,8 @@ func LoadConf(filename, subConf string, s interface{}) error {
 		return fmt.Errorf("failed to unmarshal config: %w", err)
 	}
 
-	return nil
+	// 替换 ${env:NAME}、${file:PATH} 形式的密钥引用
+	return ResolveSecrets(s)
 }
 
 // LoadConfWithDefaults 使用Viper读取配置，支持设置默认值
@@ -101,7 +102,8 @@ func LoadConfWithDefaults(filename, subConf string, defaults map[string]interfac
 		return fmt.Errorf("failed to unmarshal config: %w", err)
 	}
 
-	return nil
+	// 替换 ${env:NAME}、${file:PATH} 形式的密钥引用
+	return ResolveSecrets(s)
 }
 
 // bindEnvVars 手动绑定环境变量
@@ -147,5 +149,6 @@ func LoadConfFromViper(v *viper.Viper, s interface{}) error {
 		return fmt.Errorf("failed to unmarshal config: %w", err)
 	}
 
-	return nil
+	// 替换 ${env:NAME}、${file:PATH} 形式的密钥引用
+	return ResolveSecrets(s)
 }
@@ -18,6 +18,8 @@ const (
 
 const (
 	APP_NAME = "XT_APP_NAME"
+	// APP_ENV 分层配置默认激活的环境，如 dev、test、prod
+	APP_ENV = "APP_ENV"
 )
 
 var (
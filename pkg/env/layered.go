@@ -0,0 +1,135 @@
+package env
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+)
+
+// includesKey yaml中用于引入同目录其他配置文件的key
+const includesKey = "includes"
+
+// LayeredConfOptions 分层配置加载选项
+type LayeredConfOptions struct {
+	// Name 基础配置文件名（不包含扩展名），如 app 对应 app.yaml
+	Name string
+	// SubConf 子配置目录，相对于 GetConfDirPath()
+	SubConf string
+	// Profile 激活的环境，如 dev 对应 app-dev.yaml；为空时读取环境变量 APP_ENV
+	Profile string
+}
+
+// LoadLayeredConf 按以下顺序深度合并配置（后者覆盖前者），map逐层合并，标量和切片整体覆盖：
+//  1. 基础文件 includes 中列出的文件（按声明顺序，可嵌套引入）
+//  2. 基础文件 {Name}.yaml
+//  3. profile 文件 includes 中列出的文件
+//  4. profile 文件 {Name}-{Profile}.yaml（不存在时忽略）
+//  5. 环境变量（前缀为 GetAppName()，优先级最高）
+//
+// 循环引入会返回错误
+func LoadLayeredConf(opts LayeredConfOptions, s interface{}) error {
+	if opts.Name == "" {
+		return fmt.Errorf("layered config name is empty")
+	}
+	profile := opts.Profile
+	if profile == "" {
+		profile = GetEnv(APP_ENV, "")
+	}
+
+	v := viper.New()
+	v.SetEnvPrefix(GetAppName())
+	v.AutomaticEnv()
+	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+
+	dir := filepath.Join(GetConfDirPath(), opts.SubConf)
+	if err := mergeConfFile(v, filepath.Join(dir, opts.Name+".yaml"), nil); err != nil {
+		return err
+	}
+	if profile != "" {
+		profilePath := filepath.Join(dir, opts.Name+"-"+profile+".yaml")
+		if _, err := os.Stat(profilePath); err == nil {
+			if err := mergeConfFile(v, profilePath, nil); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := v.Unmarshal(s); err != nil {
+		return fmt.Errorf("failed to unmarshal config: %w", err)
+	}
+
+	// 替换 ${env:NAME}、${file:PATH} 形式的密钥引用
+	return ResolveSecrets(s)
+}
+
+// mergeConfFile 先合并 path 中 includes 引入的文件，再合并 path 自身
+// stack 为当前引入链，用于检测循环引入
+func mergeConfFile(v *viper.Viper, path string, stack []string) error {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return fmt.Errorf("failed to resolve config path %s: %w", path, err)
+	}
+	for i, p := range stack {
+		if p == absPath {
+			chain := append(stack[i:], absPath)
+			for j := range chain {
+				chain[j] = filepath.Base(chain[j])
+			}
+			return fmt.Errorf("cyclic config include: %s", strings.Join(chain, " -> "))
+		}
+	}
+	stack = append(stack[:len(stack):len(stack)], absPath)
+
+	content, err := os.ReadFile(absPath)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+	cfg := map[string]interface{}{}
+	if err := yaml.Unmarshal(content, &cfg); err != nil {
+		return fmt.Errorf("failed to parse config file %s: %w", filepath.Base(absPath), err)
+	}
+
+	includes, err := parseIncludes(cfg[includesKey])
+	if err != nil {
+		return fmt.Errorf("invalid includes in %s: %w", filepath.Base(absPath), err)
+	}
+	delete(cfg, includesKey)
+
+	for _, include := range includes {
+		if filepath.Ext(include) == "" {
+			include += ".yaml"
+		}
+		if !filepath.IsAbs(include) {
+			include = filepath.Join(filepath.Dir(absPath), include)
+		}
+		if err := mergeConfFile(v, include, stack); err != nil {
+			return err
+		}
+	}
+	return v.MergeConfigMap(cfg)
+}
+
+func parseIncludes(raw interface{}) ([]string, error) {
+	switch val := raw.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return []string{val}, nil
+	case []interface{}:
+		includes := make([]string, 0, len(val))
+		for _, item := range val {
+			name, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("include entry must be string, got %T", item)
+			}
+			includes = append(includes, name)
+		}
+		return includes, nil
+	default:
+		return nil, fmt.Errorf("includes must be a string or list, got %T", raw)
+	}
+}
@@ -0,0 +1,129 @@
+package env
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type layeredConfig struct {
+	Server struct {
+		Host string `mapstructure:"host"`
+		Port int    `mapstructure:"port"`
+	} `mapstructure:"server"`
+	Redis struct {
+		Addr string `mapstructure:"addr"`
+		Db   int    `mapstructure:"db"`
+	} `mapstructure:"redis"`
+	Mysql struct {
+		Addr string `mapstructure:"addr"`
+	} `mapstructure:"mysql"`
+	Tags []string `mapstructure:"tags"`
+}
+
+// setupConfDir 在临时目录下创建 conf 目录并写入配置文件，测试结束后恢复根目录
+func setupConfDir(t *testing.T, files map[string]string) {
+	t.Helper()
+	root := t.TempDir()
+	confDir := filepath.Join(root, "conf")
+	if err := os.MkdirAll(confDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(confDir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	old := rootPath
+	SetRootPath(root)
+	t.Cleanup(func() { rootPath = old })
+}
+
+func TestLoadLayeredConf(t *testing.T) {
+	SetAppName("layered")
+	setupConfDir(t, map[string]string{
+		"redis.yaml": "redis:\n  addr: redis-base:6379\n  db: 1\n",
+		"mysql.yaml": "mysql:\n  addr: mysql-base:3306\n",
+		"app.yaml": `includes:
+  - redis.yaml
+  - mysql
+server:
+  host: 0.0.0.0
+  port: 8080
+redis:
+  db: 2
+tags: [a, b, c]
+`,
+		"app-prod.yaml": `server:
+  port: 80
+redis:
+  addr: redis-prod:6379
+tags: [prod]
+`,
+	})
+
+	t.Run("BaseWithIncludes", func(t *testing.T) {
+		var conf layeredConfig
+		if err := LoadLayeredConf(LayeredConfOptions{Name: "app", Profile: "none"}, &conf); err != nil {
+			t.Fatalf("LoadLayeredConf failed: %v", err)
+		}
+		if conf.Redis.Addr != "redis-base:6379" || conf.Mysql.Addr != "mysql-base:3306" {
+			t.Errorf("includes not merged: %+v", conf)
+		}
+		// 基础文件覆盖 include 中的同名key
+		if conf.Redis.Db != 2 {
+			t.Errorf("Expected Redis.Db to be 2, got %d", conf.Redis.Db)
+		}
+		if len(conf.Tags) != 3 {
+			t.Errorf("Expected Tags to be [a b c], got %v", conf.Tags)
+		}
+	})
+
+	t.Run("ProfileOverlay", func(t *testing.T) {
+		t.Setenv(APP_ENV, "prod")
+		var conf layeredConfig
+		if err := LoadLayeredConf(LayeredConfOptions{Name: "app"}, &conf); err != nil {
+			t.Fatalf("LoadLayeredConf failed: %v", err)
+		}
+		// map 深度合并：未覆盖的字段保留
+		if conf.Server.Host != "0.0.0.0" || conf.Server.Port != 80 {
+			t.Errorf("unexpected server conf: %+v", conf.Server)
+		}
+		if conf.Redis.Addr != "redis-prod:6379" || conf.Redis.Db != 2 {
+			t.Errorf("unexpected redis conf: %+v", conf.Redis)
+		}
+		// 切片整体覆盖
+		if len(conf.Tags) != 1 || conf.Tags[0] != "prod" {
+			t.Errorf("Expected Tags to be [prod], got %v", conf.Tags)
+		}
+	})
+
+	t.Run("EnvironmentVariableOverride", func(t *testing.T) {
+		t.Setenv("LAYERED_SERVER_PORT", "9000")
+		var conf layeredConfig
+		if err := LoadLayeredConf(LayeredConfOptions{Name: "app", Profile: "prod"}, &conf); err != nil {
+			t.Fatalf("LoadLayeredConf failed: %v", err)
+		}
+		if conf.Server.Port != 9000 {
+			t.Errorf("Expected Server.Port to be 9000, got %d", conf.Server.Port)
+		}
+	})
+}
+
+func TestLoadLayeredConfCyclicInclude(t *testing.T) {
+	setupConfDir(t, map[string]string{
+		"app.yaml": "includes: [a.yaml]\n",
+		"a.yaml":   "includes: [b.yaml]\n",
+		"b.yaml":   "includes: [a.yaml]\n",
+	})
+
+	var conf layeredConfig
+	err := LoadLayeredConf(LayeredConfOptions{Name: "app"}, &conf)
+	if err == nil {
+		t.Fatal("Expected cyclic include error")
+	}
+	if !strings.Contains(err.Error(), "a.yaml -> b.yaml -> a.yaml") {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
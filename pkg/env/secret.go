@@ -0,0 +1,112 @@
+package env
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// secretRefPattern 匹配 ${env:NAME} 与 ${file:/path/to/secret}
+var secretRefPattern = regexp.MustCompile(`\$\{(env|file):([^}]+)\}`)
+
+// ResolveSecrets 递归遍历结构体、map、切片中的字符串，将 ${env:NAME}、${file:PATH} 替换为对应的值
+// 引用的环境变量未设置或文件不存在时返回错误，错误信息包含配置路径
+func ResolveSecrets(s interface{}) error {
+	v := reflect.ValueOf(s)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return fmt.Errorf("resolve secrets: expected non-nil pointer, got %T", s)
+	}
+	var errs []error
+	resolveValue(v.Elem(), "", &errs)
+	return errors.Join(errs...)
+}
+
+func resolveValue(v reflect.Value, path string, errs *[]error) {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return
+		}
+		elem := v.Elem()
+		if v.Kind() == reflect.Interface && elem.Kind() == reflect.String {
+			if resolved, ok := resolveString(elem.String(), path, errs); ok && v.CanSet() {
+				v.Set(reflect.ValueOf(resolved))
+			}
+			return
+		}
+		resolveValue(elem, path, errs)
+	case reflect.String:
+		if resolved, ok := resolveString(v.String(), path, errs); ok && v.CanSet() {
+			v.SetString(resolved)
+		}
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < v.NumField(); i++ {
+			if !t.Field(i).IsExported() {
+				continue
+			}
+			resolveValue(v.Field(i), joinConfPath(path, confFieldName(t.Field(i))), errs)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			resolveValue(v.Index(i), fmt.Sprintf("%s[%d]", path, i), errs)
+		}
+	case reflect.Map:
+		for _, key := range v.MapKeys() {
+			// map元素不可寻址，拷贝后处理再写回
+			elem := reflect.New(v.Type().Elem()).Elem()
+			elem.Set(v.MapIndex(key))
+			resolveValue(elem, joinConfPath(path, fmt.Sprint(key.Interface())), errs)
+			v.SetMapIndex(key, elem)
+		}
+	}
+}
+
+// resolveString 替换字符串中的引用，没有引用时返回 false
+func resolveString(s, path string, errs *[]error) (string, bool) {
+	if !strings.Contains(s, "${") {
+		return s, false
+	}
+	found := false
+	resolved := secretRefPattern.ReplaceAllStringFunc(s, func(ref string) string {
+		found = true
+		m := secretRefPattern.FindStringSubmatch(ref)
+		switch m[1] {
+		case "env":
+			val, ok := os.LookupEnv(m[2])
+			if !ok {
+				*errs = append(*errs, fmt.Errorf("config %s: env %s is not set", path, m[2]))
+			}
+			return val
+		default:
+			content, err := os.ReadFile(m[2])
+			if err != nil {
+				*errs = append(*errs, fmt.Errorf("config %s: read secret file %s error: %w", path, m[2], err))
+				return ""
+			}
+			return strings.TrimRight(string(content), "\r\n")
+		}
+	})
+	return resolved, found
+}
+
+// confFieldName 与 viper 反序列化保持一致，优先使用 mapstructure 标签
+func confFieldName(f reflect.StructField) string {
+	for _, tag := range []string{"mapstructure", "yaml", "json"} {
+		name := strings.Split(f.Tag.Get(tag), ",")[0]
+		if name != "" && name != "-" {
+			return name
+		}
+	}
+	return strings.ToLower(f.Name)
+}
+
+func joinConfPath(parent, name string) string {
+	if parent == "" {
+		return name
+	}
+	return parent + "." + name
+}
@@ -0,0 +1,144 @@
+package env
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type secretConfig struct {
+	Database struct {
+		Host     string `mapstructure:"host" validate:"required"`
+		Port     int    `mapstructure:"port" validate:"min=1,max=65535"`
+		Password string `mapstructure:"password" validate:"required"`
+	} `mapstructure:"database"`
+	Extra map[string]string `mapstructure:"extra"`
+	Hosts []string          `mapstructure:"hosts"`
+}
+
+func TestResolveSecrets(t *testing.T) {
+	secretFile := filepath.Join(t.TempDir(), "db_pass")
+	if err := os.WriteFile(secretFile, []byte("s3cret\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("SECRET_TEST_TOKEN", "token-123")
+	t.Setenv("SECRET_TEST_HOST", "db.internal")
+
+	t.Run("NestedSubstitution", func(t *testing.T) {
+		var conf secretConfig
+		conf.Database.Host = "${env:SECRET_TEST_HOST}"
+		conf.Database.Password = "${file:" + secretFile + "}"
+		conf.Extra = map[string]string{"token": "Bearer ${env:SECRET_TEST_TOKEN}"}
+		conf.Hosts = []string{"plain", "${env:SECRET_TEST_HOST}:3306"}
+
+		if err := ResolveSecrets(&conf); err != nil {
+			t.Fatalf("ResolveSecrets failed: %v", err)
+		}
+		if conf.Database.Host != "db.internal" {
+			t.Errorf("Expected Database.Host to be 'db.internal', got '%s'", conf.Database.Host)
+		}
+		if conf.Database.Password != "s3cret" {
+			t.Errorf("Expected Database.Password to be 's3cret', got '%s'", conf.Database.Password)
+		}
+		if conf.Extra["token"] != "Bearer token-123" {
+			t.Errorf("Expected Extra.token to be 'Bearer token-123', got '%s'", conf.Extra["token"])
+		}
+		if conf.Hosts[0] != "plain" || conf.Hosts[1] != "db.internal:3306" {
+			t.Errorf("unexpected Hosts: %v", conf.Hosts)
+		}
+	})
+
+	t.Run("InterfaceMap", func(t *testing.T) {
+		conf := map[string]interface{}{
+			"nested": map[string]interface{}{"token": "${env:SECRET_TEST_TOKEN}"},
+		}
+		if err := ResolveSecrets(&conf); err != nil {
+			t.Fatalf("ResolveSecrets failed: %v", err)
+		}
+		if got := conf["nested"].(map[string]interface{})["token"]; got != "token-123" {
+			t.Errorf("Expected nested.token to be 'token-123', got '%v'", got)
+		}
+	})
+
+	t.Run("MissingFile", func(t *testing.T) {
+		var conf secretConfig
+		conf.Database.Password = "${file:/nonexistent/db_pass}"
+		err := ResolveSecrets(&conf)
+		if err == nil {
+			t.Fatal("Expected error for missing secret file")
+		}
+		if !strings.Contains(err.Error(), "database.password") {
+			t.Errorf("error should name the config path, got: %v", err)
+		}
+	})
+
+	t.Run("LoadConf", func(t *testing.T) {
+		SetAppName("secret")
+		setupConfDir(t, map[string]string{
+			"db.yaml": "database:\n  host: localhost\n  port: 3306\n  password: ${file:" + secretFile + "}\n",
+		})
+		var conf secretConfig
+		if err := LoadConfValidated("db", "", &conf); err != nil {
+			t.Fatalf("LoadConfValidated failed: %v", err)
+		}
+		if conf.Database.Password != "s3cret" {
+			t.Errorf("Expected Database.Password to be 's3cret', got '%s'", conf.Database.Password)
+		}
+	})
+}
+
+func TestValidateConf(t *testing.T) {
+	var conf secretConfig
+	conf.Database.Port = 70000
+
+	err := ValidateConf(&conf)
+	if err == nil {
+		t.Fatal("Expected validation error")
+	}
+	for _, want := range []string{"database.host: required", "database.port: max=65535", "database.password: required"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error should contain %q, got: %v", want, err)
+		}
+	}
+
+	conf.Database.Host = "localhost"
+	conf.Database.Port = 3306
+	conf.Database.Password = "pass"
+	if err := ValidateConf(&conf); err != nil {
+		t.Errorf("ValidateConf failed: %v", err)
+	}
+}
+
+func TestLoadConfStrict(t *testing.T) {
+	SetAppName("strict")
+	setupConfDir(t, map[string]string{
+		"db.yaml": "database:\n  host: \"\"\n  port: 0\nredis:\n  addr: redis:6379\n",
+	})
+	t.Setenv("STRICT_DATABASE_NAME", "orders")
+
+	var conf struct {
+		Database struct {
+			Host string `mapstructure:"host"`
+			Port int    `mapstructure:"port"`
+			Name string `mapstructure:"name"`
+		} `mapstructure:"database"`
+		Redis struct {
+			Addr string `mapstructure:"addr"`
+		} `mapstructure:"redis"`
+	}
+	err := LoadConfStrict("db", "", &conf, []string{"database.host", "database.port", "database.name", "redis.addr", "mysql.addr"})
+	if err == nil {
+		t.Fatal("expected missing keys error")
+	}
+	if want := "config db missing required keys: database.host, mysql.addr"; err.Error() != want {
+		t.Errorf("got %q, want %q", err.Error(), want)
+	}
+
+	if err := LoadConfStrict("db", "", &conf, []string{"redis.addr", "database.name"}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if conf.Database.Name != "orders" {
+		t.Errorf("expected database.name from env, got %q", conf.Database.Name)
+	}
+}
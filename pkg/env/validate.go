@@ -0,0 +1,96 @@
+package env
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+)
+
+var confValidator = newConfValidator()
+
+func newConfValidator() *validator.Validate {
+	v := validator.New(validator.WithRequiredStructEnabled())
+	// 错误信息中使用配置文件中的key而不是结构体字段名
+	v.RegisterTagNameFunc(confFieldName)
+	return v
+}
+
+// ValidateConf 根据结构体的 validate 标签校验配置，所有不合法的key汇总在一个错误中返回
+func ValidateConf(s interface{}) error {
+	err := confValidator.Struct(s)
+	if err == nil {
+		return nil
+	}
+	var vErrs validator.ValidationErrors
+	if !errors.As(err, &vErrs) {
+		return fmt.Errorf("config validation error: %w", err)
+	}
+	msgs := make([]string, 0, len(vErrs))
+	for _, fe := range vErrs {
+		msg := confErrorPath(s, fe.Namespace()) + ": " + fe.Tag()
+		if fe.Param() != "" {
+			msg += "=" + fe.Param()
+		}
+		msgs = append(msgs, msg)
+	}
+	return fmt.Errorf("config validation failed: %s", strings.Join(msgs, "; "))
+}
+
+// confErrorPath 去掉命名空间中的根结构体名
+func confErrorPath(s interface{}, namespace string) string {
+	t := reflect.TypeOf(s)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return strings.TrimPrefix(namespace, t.Name()+".")
+}
+
+// LoadConfValidated 与 LoadConf 相同，加载后按 validate 标签校验配置
+func LoadConfValidated(filename, subConf string, s interface{}) error {
+	if err := LoadConf(filename, subConf, s); err != nil {
+		return err
+	}
+	return ValidateConf(s)
+}
+
+// LoadConfStrict 与 LoadConf 相同，加载后检查 required 中的key（点号分隔，如 database.host）
+// 在配置文件或环境变量中都有非空值，缺失的key汇总在一个错误中返回，避免服务带着空配置启动
+func LoadConfStrict(filename, subConf string, s interface{}, required []string) error {
+	v := NewViperInstance(filename, subConf, "yaml")
+	// 配置文件中没有的key，Unmarshal 不会读取环境变量，需显式绑定
+	for _, key := range required {
+		_ = v.BindEnv(key)
+	}
+	if err := LoadConfFromViper(v, s); err != nil {
+		return err
+	}
+	var missing []string
+	for _, key := range required {
+		if isEmptyConfValue(v.Get(key)) {
+			missing = append(missing, key)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("config %s missing required keys: %s", filename, strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// isEmptyConfValue nil、空白字符串、空切片和空map视为未配置，数值0和false视为已配置
+func isEmptyConfValue(val interface{}) bool {
+	if val == nil {
+		return true
+	}
+	if str, ok := val.(string); ok {
+		return strings.TrimSpace(str) == ""
+	}
+	rv := reflect.ValueOf(val)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Map, reflect.Array:
+		return rv.Len() == 0
+	}
+	return false
+}
@@ -0,0 +1,80 @@
+package env
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/viper"
+)
+
+// confMu 保护 LoadAndWatch 加载的配置结构体，热更新时持写锁替换
+var confMu sync.RWMutex
+
+// WatchConf 监听Viper实例对应的配置文件，文件变化后Viper会先重新读取配置，再调用 onChange
+// onChange 在 fsnotify 的 goroutine 中执行，需自行处理并发；同一实例只应调用一次
+func WatchConf(v *viper.Viper, onChange func(fsnotify.Event)) {
+	if onChange != nil {
+		v.OnConfigChange(onChange)
+	}
+	v.WatchConfig()
+}
+
+// LoadAndWatch 按 LoadConf 的规则加载配置，并在配置文件变化时重新反序列化到 s
+// 重新加载时先解析到新的实例，成功后在写锁内整体替换 s，再调用 onReload；解析失败则保留旧配置
+// 并发读取 s 时需使用 RLockConf 加读锁，或在 onReload 中把需要的值拷贝到自己的原子变量里
+// 注意：持有 s 内部的 map、切片、指针的引用在替换后不会更新
+func LoadAndWatch(filename, subConf string, s interface{}, onReload func()) error {
+	rv := reflect.ValueOf(s)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return fmt.Errorf("config target must be a non-nil pointer, got %T", s)
+	}
+
+	v := viper.New()
+	v.SetConfigName(filename)
+	v.SetConfigType("yaml")
+	v.AddConfigPath(filepath.Join(GetConfDirPath(), subConf))
+	v.SetEnvPrefix(GetAppName())
+	v.AutomaticEnv()
+	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+
+	confMu.Lock()
+	err := LoadConfFromViper(v, s)
+	confMu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	WatchConf(v, func(e fsnotify.Event) {
+		fresh := reflect.New(rv.Elem().Type())
+		if err := v.Unmarshal(fresh.Interface()); err != nil {
+			log.Printf("reload config %s error: %v", e.Name, err)
+			return
+		}
+		if err := ResolveSecrets(fresh.Interface()); err != nil {
+			log.Printf("reload config %s error: %v", e.Name, err)
+			return
+		}
+		confMu.Lock()
+		rv.Elem().Set(fresh.Elem())
+		confMu.Unlock()
+		if onReload != nil {
+			onReload()
+		}
+	})
+	return nil
+}
+
+// RLockConf 对 LoadAndWatch 管理的配置加读锁，返回解锁函数
+//
+//	unlock := env.RLockConf()
+//	enabled := conf.Feature.Enabled
+//	unlock()
+func RLockConf() (unlock func()) {
+	confMu.RLock()
+	return confMu.RUnlock
+}
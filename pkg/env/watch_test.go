@@ -0,0 +1,55 @@
+package env
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type watchConfig struct {
+	Feature struct {
+		Enabled bool `mapstructure:"enabled"`
+		Limit   int  `mapstructure:"limit"`
+	} `mapstructure:"feature"`
+}
+
+func TestLoadAndWatch(t *testing.T) {
+	SetAppName("watch")
+	setupConfDir(t, map[string]string{
+		"flags.yaml": "feature:\n  enabled: false\n  limit: 10\n",
+	})
+
+	var conf watchConfig
+	reloaded := make(chan struct{}, 4)
+	if err := LoadAndWatch("flags", "", &conf, func() { reloaded <- struct{}{} }); err != nil {
+		t.Fatal(err)
+	}
+	if conf.Feature.Enabled || conf.Feature.Limit != 10 {
+		t.Errorf("unexpected initial conf: %+v", conf)
+	}
+
+	path := filepath.Join(GetConfDirPath(), "flags.yaml")
+	if err := os.WriteFile(path, []byte("feature:\n  enabled: true\n  limit: 20\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-reloaded:
+	case <-time.After(5 * time.Second):
+		t.Fatal("config not reloaded")
+	}
+	unlock := RLockConf()
+	got := conf
+	unlock()
+	if !got.Feature.Enabled || got.Feature.Limit != 20 {
+		t.Errorf("unexpected reloaded conf: %+v", got)
+	}
+}
+
+func TestLoadAndWatchInvalidTarget(t *testing.T) {
+	var conf watchConfig
+	if err := LoadAndWatch("flags", "", conf, nil); err == nil {
+		t.Error("expected error for non-pointer target")
+	}
+}
@@ -2,6 +2,9 @@ package errors
 
 import (
 	"fmt"
+	"io"
+	"sort"
+
 	"github.com/gin-gonic/gin"
 	"github.com/xiangtao94/golib/pkg/env"
 )
@@ -10,21 +13,23 @@ import (
 type Error struct {
 	Code    int
 	Message map[string]string // 存储不同语言的消息
+	cause   error             // 底层错误，仅用于日志，不返回给客户端
+	fields  []any             // kv 形式的附加信息，仅用于日志
+	stack   []uintptr         // Wrap/WrapError 时的调用栈
 }
 
-// NewError 创建新的错误对象，并支持双语
+// NewError 创建新的错误对象，并支持多语言
 func NewError(code int, messages map[string]string) Error {
 	// 如果messages为空，则自动从ErrMsg获取
 	if messages == nil {
 		messages = make(map[string]string)
 	}
 
-	// 获取zh和en默认消息
-	if msg, ok := ErrMsg["zh"][code]; ok {
-		messages["zh"] = msg
-	}
-	if msg, ok := ErrMsg["en"][code]; ok {
-		messages["en"] = msg
+	// 获取所有已注册语言的默认消息
+	for lang, msgs := range ErrMsg {
+		if msg, ok := msgs[code]; ok {
+			messages[lang] = msg
+		}
 	}
 
 	return Error{
@@ -33,6 +38,28 @@ func NewError(code int, messages map[string]string) Error {
 	}
 }
 
+// RegisterMessages 注册或扩展某种语言的错误消息，需在 init 阶段调用
+// 已创建的错误（如 ErrorParamInvalid）在 GetMessage 时也会使用新注册的语言
+func RegisterMessages(lang string, msgs map[int]string) {
+	if ErrMsg[lang] == nil {
+		ErrMsg[lang] = make(map[int]string, len(msgs))
+	}
+	for code, msg := range msgs {
+		ErrMsg[lang][code] = msg
+	}
+}
+
+// WithMessage 返回覆盖指定语言消息的副本，不影响原错误
+func (err Error) WithMessage(lang, msg string) Error {
+	newMsg := make(map[string]string, len(err.Message)+1)
+	for key, val := range err.Message {
+		newMsg[key] = val
+	}
+	newMsg[lang] = msg
+	err.Message = newMsg
+	return err
+}
+
 func (err Error) Sprintf(v ...interface{}) Error {
 	newMsg := make(map[string]string, len(err.Message))
 	for key, val := range err.Message {
@@ -43,58 +70,140 @@ func (err Error) Sprintf(v ...interface{}) Error {
 }
 
 // GetMessage 获取指定语言的错误信息
+// 优先级：请求语言 -> 默认语言 -> 任意可用语言
 func (err Error) GetMessage(ctx *gin.Context) string {
-	lang := ctx.GetString(env.I18N_CONTEXT)
-	// 如果语言不存在，返回默认语言信息
-	if msg, exists := err.Message[lang]; exists {
-		return msg
-	} else {
-		return err.Message[env.GetLanguage()]
+	if ctx != nil {
+		if msg, ok := err.message(ctx.GetString(env.I18N_CONTEXT)); ok {
+			return msg
+		}
 	}
+	return err.Error()
 }
 
 // Error 方法默认返回当前设定语言的信息
 func (err Error) Error() string {
-	if msg, ok := err.Message[env.GetLanguage()]; ok {
+	if msg, ok := err.message(env.GetLanguage()); ok {
 		return msg
 	}
+	langs := make([]string, 0, len(err.Message))
+	for lang := range err.Message {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	for _, lang := range langs {
+		if msg := err.Message[lang]; msg != "" {
+			return msg
+		}
+	}
 	return "Unknown error"
 }
 
+// message 先取错误自身的消息，再取 RegisterMessages 注册的消息
+func (err Error) message(lang string) (string, bool) {
+	if lang == "" {
+		return "", false
+	}
+	if msg, ok := err.Message[lang]; ok {
+		return msg, true
+	}
+	msg, ok := ErrMsg[lang][err.Code]
+	return msg, ok
+}
+
+// Wrap 返回携带底层错误和调用栈的副本，错误码和多语言信息不变
+// 客户端仍只看到安全的错误信息，日志中可通过 %+v 或 errors.Unwrap 拿到原始错误
+func (err Error) Wrap(cause error) Error {
+	err.cause = cause
+	err.stack = callers()
+	return err
+}
+
+// Unwrap 返回底层错误
+func (err Error) Unwrap() error {
+	return err.cause
+}
+
+// Is 错误码相同即视为同一错误，支持 errors.Is(err, ErrorSystemError)
+func (err Error) Is(target error) bool {
+	t, ok := target.(Error)
+	return ok && t.Code == err.Code
+}
+
+// Format %+v 时追加附加信息、底层错误链和调用栈，供 render.StackLogger 打印
+func (err Error) Format(s fmt.State, verb rune) {
+	switch verb {
+	case 'v':
+		if s.Flag('+') {
+			io.WriteString(s, err.Error())
+			err.formatDetail(s)
+			return
+		}
+		fallthrough
+	case 's':
+		io.WriteString(s, err.Error())
+	case 'q':
+		fmt.Fprintf(s, "%q", err.Error())
+	}
+}
+
 // 定义错误码
 const (
-	SYSTEM_ERROR    = 1
-	PARAM_ERROR     = 2
-	USER_NOT_LOGIN  = 3
-	INVALID_REQUEST = 4
-	DEFAULT_ERROR   = 100
-	CUSTOM_ERROR    = 101
+	SYSTEM_ERROR      = 1
+	PARAM_ERROR       = 2
+	USER_NOT_LOGIN    = 3
+	INVALID_REQUEST   = 4
+	REQUEST_TOO_LARGE = 5
+	REQUEST_TIMEOUT   = 6
+	TOO_MANY_REQUESTS = 7
+	RECORD_NOT_FOUND  = 8
+	QUOTA_EXCEEDED    = 9
+	TENANT_NOT_FOUND  = 10
+	DEFAULT_ERROR     = 100
+	CUSTOM_ERROR      = 101
 )
 
 // 多语言错误消息
 var ErrMsg = map[string]map[int]string{
 	"zh": {
-		PARAM_ERROR:     "请求参数错误",
-		SYSTEM_ERROR:    "服务异常，请稍后重试",
-		USER_NOT_LOGIN:  "用户Session已失效，请重新登录",
-		INVALID_REQUEST: "请求无效，请稍后再试",
-		DEFAULT_ERROR:   "服务开小差了，请稍后再试",
+		PARAM_ERROR:       "请求参数错误",
+		SYSTEM_ERROR:      "服务异常，请稍后重试",
+		USER_NOT_LOGIN:    "用户Session已失效，请重新登录",
+		INVALID_REQUEST:   "请求无效，请稍后再试",
+		REQUEST_TOO_LARGE: "请求体过大",
+		REQUEST_TIMEOUT:   "请求超时，请稍后再试",
+		TOO_MANY_REQUESTS: "请求过于频繁，请稍后再试",
+		RECORD_NOT_FOUND:  "记录不存在",
+		QUOTA_EXCEEDED:    "调用次数已达上限",
+		TENANT_NOT_FOUND:  "租户不存在",
+		DEFAULT_ERROR:     "服务开小差了，请稍后再试",
 	},
 	"en": {
-		PARAM_ERROR:     "Request parameter error",
-		SYSTEM_ERROR:    "Service exception, please try again later",
-		USER_NOT_LOGIN:  "User session expired, please log in again",
-		INVALID_REQUEST: "Invalid request, please try again later",
-		DEFAULT_ERROR:   "The service is down, please try again later",
+		PARAM_ERROR:       "Request parameter error",
+		SYSTEM_ERROR:      "Service exception, please try again later",
+		USER_NOT_LOGIN:    "User session expired, please log in again",
+		INVALID_REQUEST:   "Invalid request, please try again later",
+		REQUEST_TOO_LARGE: "Request body too large",
+		REQUEST_TIMEOUT:   "Request timeout, please try again later",
+		TOO_MANY_REQUESTS: "Too many requests, please try again later",
+		RECORD_NOT_FOUND:  "Record not found",
+		QUOTA_EXCEEDED:    "Call quota exceeded",
+		TENANT_NOT_FOUND:  "Tenant not found",
+		DEFAULT_ERROR:     "The service is down, please try again later",
 	},
 }
 
 // 定义标准错误
 var (
-	ErrorParamInvalid   = NewError(PARAM_ERROR, nil)
-	ErrorSystemError    = NewError(SYSTEM_ERROR, nil)
-	ErrorUserNotLogin   = NewError(USER_NOT_LOGIN, nil)
-	ErrorInvalidRequest = NewError(INVALID_REQUEST, nil)
-	ErrorDefault        = NewError(DEFAULT_ERROR, nil)
-	ErrorCustomError    = NewError(CUSTOM_ERROR, map[string]string{"zh": "%s", "en": "%s"})
+	ErrorParamInvalid    = NewError(PARAM_ERROR, nil)
+	ErrorSystemError     = NewError(SYSTEM_ERROR, nil)
+	ErrorUserNotLogin    = NewError(USER_NOT_LOGIN, nil)
+	ErrorInvalidRequest  = NewError(INVALID_REQUEST, nil)
+	ErrorRequestTooLarge = NewError(REQUEST_TOO_LARGE, nil)
+	ErrorRequestTimeout  = NewError(REQUEST_TIMEOUT, nil)
+	ErrorTooManyRequests = NewError(TOO_MANY_REQUESTS, nil)
+	ErrorRecordNotFound  = NewError(RECORD_NOT_FOUND, nil)
+	ErrorQuotaExceeded   = NewError(QUOTA_EXCEEDED, nil)
+	ErrorTenantNotFound  = NewError(TENANT_NOT_FOUND, nil)
+	ErrorDefault         = NewError(DEFAULT_ERROR, nil)
+	ErrorCustomError     = NewError(CUSTOM_ERROR, map[string]string{"zh": "%s", "en": "%s"})
 )
@@ -0,0 +1,138 @@
+package errors
+
+import (
+	stderrors "errors"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/xiangtao94/golib/pkg/env"
+)
+
+func TestWrap(t *testing.T) {
+	cause := stderrors.New("dial tcp 127.0.0.1:3306: connection refused")
+	err := ErrorSystemError.Wrap(cause)
+
+	// 错误码和对客户端的信息不变
+	assert.Equal(t, SYSTEM_ERROR, err.Code)
+	assert.Equal(t, ErrorSystemError.Error(), err.Error())
+	assert.Equal(t, cause, err.Unwrap())
+	// 原始错误未被修改
+	assert.Nil(t, ErrorSystemError.Unwrap())
+
+	// %+v 打印底层错误，%v 只打印信息
+	assert.True(t, strings.Contains(fmt.Sprintf("%+v", err), "cause: "+cause.Error()))
+	assert.Equal(t, err.Error(), fmt.Sprintf("%v", err))
+	assert.Equal(t, ErrorSystemError.Error(), fmt.Sprintf("%+v", ErrorSystemError))
+}
+
+func TestErrorsIsAs(t *testing.T) {
+	cause := stderrors.New("record not found")
+	err := fmt.Errorf("query user: %w", ErrorParamInvalid.Wrap(cause))
+
+	assert.True(t, stderrors.Is(err, ErrorParamInvalid))
+	assert.True(t, stderrors.Is(err, cause))
+	assert.False(t, stderrors.Is(err, ErrorSystemError))
+
+	var e Error
+	assert.True(t, stderrors.As(err, &e))
+	assert.Equal(t, PARAM_ERROR, e.Code)
+	assert.Equal(t, cause, stderrors.Unwrap(e))
+}
+
+func TestRegisterMessages(t *testing.T) {
+	RegisterMessages("ja", map[int]string{PARAM_ERROR: "パラメータエラー", 9001: "ユーザーなし"})
+	t.Cleanup(func() { delete(ErrMsg, "ja") })
+
+	// 新建错误带上新注册的语言
+	err := NewError(9001, nil)
+	assert.Equal(t, "ユーザーなし", err.Message["ja"])
+
+	// 注册前创建的错误也能取到新语言
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	ctx.Set(env.I18N_CONTEXT, "ja")
+	assert.Equal(t, "パラメータエラー", ErrorParamInvalid.GetMessage(ctx))
+}
+
+func TestWithMessage(t *testing.T) {
+	err := ErrorParamInvalid.WithMessage("zh", "手机号格式错误")
+	assert.Equal(t, "手机号格式错误", err.Message["zh"])
+	assert.Equal(t, PARAM_ERROR, err.Code)
+	// 原错误不受影响
+	assert.Equal(t, ErrMsg["zh"][PARAM_ERROR], ErrorParamInvalid.Message["zh"])
+}
+
+func TestGetMessageFallback(t *testing.T) {
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	ctx.Set(env.I18N_CONTEXT, "fr")
+
+	// 请求语言不存在时回退到默认语言
+	err := NewError(9002, map[string]string{env.GetLanguage(): "default", "de": "deutsch"})
+	assert.Equal(t, "default", err.GetMessage(ctx))
+
+	// 默认语言也不存在时取任意可用语言
+	err = NewError(9002, map[string]string{"de": "deutsch"})
+	assert.Equal(t, "deutsch", err.GetMessage(ctx))
+
+	assert.Equal(t, "Unknown error", NewError(9002, nil).GetMessage(ctx))
+}
+
+func TestWrapError(t *testing.T) {
+	cause := stderrors.New("Error 1062: Duplicate entry")
+	err := WrapError(SYSTEM_ERROR, cause, "table", "user", "id", 42)
+
+	// 对客户端的错误码和信息与预定义错误一致
+	assert.Equal(t, SYSTEM_ERROR, err.Code)
+	assert.Equal(t, ErrorSystemError.Error(), err.Error())
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	ctx.Set(env.I18N_CONTEXT, "en")
+	assert.Equal(t, ErrMsg["en"][SYSTEM_ERROR], err.GetMessage(ctx))
+
+	// Unwrap 与 errors.Is
+	wrapped := fmt.Errorf("create user: %w", err)
+	assert.Equal(t, cause, stderrors.Unwrap(err))
+	assert.True(t, stderrors.Is(wrapped, ErrorSystemError))
+	assert.True(t, stderrors.Is(wrapped, cause))
+	assert.False(t, stderrors.Is(wrapped, ErrorParamInvalid))
+
+	// %+v 包含附加信息、底层错误和调用栈
+	detail := fmt.Sprintf("%+v", err)
+	assert.Contains(t, detail, "fields: table=user id=42")
+	assert.Contains(t, detail, "cause: "+cause.Error())
+	assert.Contains(t, detail, "errors.TestWrapError")
+	assert.Contains(t, fmt.Sprintf("%+v", ErrorParamInvalid.Wrap(cause)), "errors.TestWrapError")
+}
+
+func TestErrorFields(t *testing.T) {
+	cause := stderrors.New("record not found")
+	inner := ErrorParamInvalid.Wrap(cause).WithFields("uid", 7)
+	err := WrapError(DEFAULT_ERROR, inner, "op", "GetUser", "odd")
+
+	fields := map[string]any{}
+	for _, f := range err.Fields() {
+		if f.Interface != nil {
+			fields[f.Key] = f.Interface
+		} else if f.String != "" {
+			fields[f.Key] = f.String
+		} else {
+			fields[f.Key] = f.Integer
+		}
+	}
+	assert.Equal(t, int64(DEFAULT_ERROR), fields["errCode"])
+	assert.Equal(t, inner.Error(), fields["errCause"])
+	assert.Equal(t, "GetUser", fields["op"])
+	assert.Equal(t, "odd", fields["!BADKEY"])
+	// 错误链中其他 Error 的附加信息
+	assert.Equal(t, int64(7), fields["uid"])
+
+	assert.Len(t, LogFields(fmt.Errorf("wrap: %w", err)), len(err.Fields()))
+	assert.Len(t, LogFields(cause), 1)
+	assert.Nil(t, LogFields(nil))
+
+	// WithFields 不影响原错误
+	assert.Len(t, ErrorParamInvalid.Fields(), 1)
+}
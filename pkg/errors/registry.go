@@ -0,0 +1,186 @@
+package errors
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	// ModuleCommon 框架内置错误码所属模块，占用 1-999，业务模块使用 1000 以上的错误码
+	ModuleCommon = "common"
+	// ModuleUnregistered 未通过 Register 注册的错误码在指标中的模块名
+	ModuleUnregistered = "unregistered"
+)
+
+// BusinessErrorTotal 按模块和错误码统计通过 render 返回的业务错误，middleware.RegistryMetrics 会自动注册
+var BusinessErrorTotal = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "business_error_total",
+		Help: "Total number of business errors rendered to clients.",
+	}, []string{"module", "code"},
+)
+
+// CodeInfo 已注册错误码的信息，用于管理接口展示
+type CodeInfo struct {
+	Code     int               `json:"code"`
+	Module   string            `json:"module"`
+	Messages map[string]string `json:"messages"` // 语言 -> 错误信息
+}
+
+type codeRange struct {
+	module   string
+	min, max int
+}
+
+type codeOptions struct {
+	module string
+}
+
+// CodeOption Register 的可选项
+type CodeOption func(*codeOptions)
+
+// WithModule 指定错误码所属模块，错误码必须在该模块的范围内；不指定时按错误码所在范围确定模块
+func WithModule(module string) CodeOption {
+	return func(o *codeOptions) {
+		o.module = module
+	}
+}
+
+var (
+	registryMu sync.RWMutex
+	codeRanges []codeRange
+	codes      = map[int]CodeInfo{}
+)
+
+func init() {
+	RegisterCodeRange(ModuleCommon, 1, 999)
+	for _, err := range []Error{
+		ErrorSystemError, ErrorParamInvalid, ErrorUserNotLogin, ErrorInvalidRequest, ErrorRequestTooLarge,
+		ErrorRequestTimeout, ErrorTooManyRequests, ErrorRecordNotFound, ErrorQuotaExceeded, ErrorTenantNotFound,
+		ErrorDefault, ErrorCustomError,
+	} {
+		register(err.Code, ModuleCommon, err.Message)
+	}
+}
+
+// RegisterCodeRange 为模块分配错误码范围 [min, max]，需在 init 阶段调用
+// 范围不合法、与其他模块重叠或模块已分配过范围时 panic
+func RegisterCodeRange(module string, min, max int) {
+	if module == "" || min > max {
+		panic(fmt.Sprintf("errors: invalid code range %q [%d, %d]", module, min, max))
+	}
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	for _, r := range codeRanges {
+		if r.module == module {
+			panic(fmt.Sprintf("errors: module %q already has code range [%d, %d]", module, r.min, r.max))
+		}
+		if min <= r.max && r.min <= max {
+			panic(fmt.Sprintf("errors: code range %q [%d, %d] overlaps module %q [%d, %d]", module, min, max, r.module, r.min, r.max))
+		}
+	}
+	codeRanges = append(codeRanges, codeRange{module: module, min: min, max: max})
+}
+
+// Register 注册错误码及其多语言信息，返回对应的错误，需在 init 阶段调用
+// 错误码必须落在 RegisterCodeRange 分配的范围内，重复注册或超出范围时 panic
+// 注册的信息同时写入 ErrMsg，NewError(code, nil) 和 WrapError(code, ...) 可直接取到
+//
+//	var ErrUserNotFound = errors.Register(10001, map[string]string{"zh": "用户不存在", "en": "User not found"})
+func Register(code int, messages map[string]string, opts ...CodeOption) Error {
+	var o codeOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	registryMu.RLock()
+	module, ok := rangeModule(code)
+	registryMu.RUnlock()
+	if !ok {
+		panic(fmt.Sprintf("errors: code %d is not in any registered code range", code))
+	}
+	if o.module != "" && o.module != module {
+		panic(fmt.Sprintf("errors: code %d is out of range of module %q", code, o.module))
+	}
+	register(code, module, messages)
+	for lang, msg := range messages {
+		RegisterMessages(lang, map[int]string{code: msg})
+	}
+	return NewError(code, nil)
+}
+
+func register(code int, module string, messages map[string]string) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if info, ok := codes[code]; ok {
+		panic(fmt.Sprintf("errors: code %d already registered by module %q", code, info.Module))
+	}
+	msgs := make(map[string]string, len(messages))
+	for lang, msg := range messages {
+		msgs[lang] = msg
+	}
+	codes[code] = CodeInfo{Code: code, Module: module, Messages: msgs}
+}
+
+// rangeModule 需持有锁
+func rangeModule(code int) (string, bool) {
+	for _, r := range codeRanges {
+		if code >= r.min && code <= r.max {
+			return r.module, true
+		}
+	}
+	return "", false
+}
+
+// Lookup 返回已注册错误码的信息，包括之后通过 RegisterMessages 注册的语言
+func Lookup(code int) (CodeInfo, bool) {
+	registryMu.RLock()
+	info, ok := codes[code]
+	registryMu.RUnlock()
+	if !ok {
+		return CodeInfo{}, false
+	}
+	msgs := make(map[string]string, len(info.Messages))
+	for lang, msg := range info.Messages {
+		msgs[lang] = msg
+	}
+	for lang, byCode := range ErrMsg {
+		if msg, ok := byCode[code]; ok {
+			msgs[lang] = msg
+		}
+	}
+	info.Messages = msgs
+	return info, true
+}
+
+// Codes 按错误码升序返回所有已注册的错误码
+func Codes() []CodeInfo {
+	registryMu.RLock()
+	list := make([]int, 0, len(codes))
+	for code := range codes {
+		list = append(list, code)
+	}
+	registryMu.RUnlock()
+	sort.Ints(list)
+	infos := make([]CodeInfo, 0, len(list))
+	for _, code := range list {
+		if info, ok := Lookup(code); ok {
+			infos = append(infos, info)
+		}
+	}
+	return infos
+}
+
+// IncBusinessError 记录一次返回给客户端的业务错误，render 在返回错误时调用
+func IncBusinessError(code int) {
+	module := ModuleUnregistered
+	registryMu.RLock()
+	if info, ok := codes[code]; ok {
+		module = info.Module
+	}
+	registryMu.RUnlock()
+	BusinessErrorTotal.WithLabelValues(module, strconv.Itoa(code)).Inc()
+}
@@ -0,0 +1,53 @@
+package errors
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegister(t *testing.T) {
+	RegisterCodeRange("order", 20000, 20999)
+
+	err := Register(20001, map[string]string{"zh": "订单不存在", "en": "Order not found"})
+	assert.Equal(t, 20001, err.Code)
+	assert.Equal(t, "订单不存在", err.Message["zh"])
+	// 注册后 NewError/WrapError 可直接取到信息
+	assert.Equal(t, "Order not found", NewError(20001, nil).Message["en"])
+
+	info, ok := Lookup(20001)
+	assert.True(t, ok)
+	assert.Equal(t, "order", info.Module)
+	assert.Equal(t, map[string]string{"zh": "订单不存在", "en": "Order not found"}, info.Messages)
+
+	info, ok = Lookup(PARAM_ERROR)
+	assert.True(t, ok)
+	assert.Equal(t, ModuleCommon, info.Module)
+	assert.Equal(t, ErrMsg["en"][PARAM_ERROR], info.Messages["en"])
+	assert.Equal(t, ErrMsg["zh"][PARAM_ERROR], info.Messages["zh"])
+
+	_, ok = Lookup(20002)
+	assert.False(t, ok)
+
+	codes := Codes()
+	assert.Equal(t, SYSTEM_ERROR, codes[0].Code)
+	assert.Equal(t, 20001, codes[len(codes)-1].Code)
+
+	// 重复注册、超出范围、指定模块不匹配
+	assert.PanicsWithValue(t, `errors: code 20001 already registered by module "order"`, func() {
+		Register(20001, map[string]string{"zh": "重复"})
+	})
+	assert.Panics(t, func() { Register(PARAM_ERROR, nil) })
+	assert.Panics(t, func() { Register(30001, nil) })
+	assert.Panics(t, func() { Register(20002, nil, WithModule(ModuleCommon)) })
+	assert.NotPanics(t, func() { Register(20002, nil, WithModule("order")) })
+}
+
+func TestRegisterCodeRange(t *testing.T) {
+	RegisterCodeRange("payment", 21000, 21999)
+
+	assert.Panics(t, func() { RegisterCodeRange("payment", 22000, 22999) })
+	assert.Panics(t, func() { RegisterCodeRange("refund", 21500, 22500) })
+	assert.Panics(t, func() { RegisterCodeRange("common2", 999, 1000) })
+	assert.Panics(t, func() { RegisterCodeRange("bad", 2, 1) })
+}
@@ -0,0 +1,178 @@
+package errors
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// 参数校验失败的提示，参数依次为：字段名、规则参数、规则名
+// xxx_len 用于字符串、切片、map 的长度校验
+var validationMsg = map[string]map[string]string{
+	"zh": {
+		"required": "%[1]s 为必填项",
+		"min":      "%[1]s 不能小于 %[2]s",
+		"max":      "%[1]s 不能大于 %[2]s",
+		"len":      "%[1]s 必须等于 %[2]s",
+		"min_len":  "%[1]s 长度不能小于 %[2]s",
+		"max_len":  "%[1]s 长度不能大于 %[2]s",
+		"len_len":  "%[1]s 长度必须为 %[2]s",
+		"gt":       "%[1]s 必须大于 %[2]s",
+		"gte":      "%[1]s 必须大于等于 %[2]s",
+		"lt":       "%[1]s 必须小于 %[2]s",
+		"lte":      "%[1]s 必须小于等于 %[2]s",
+		"eq":       "%[1]s 必须等于 %[2]s",
+		"ne":       "%[1]s 不能等于 %[2]s",
+		"oneof":    "%[1]s 必须是 [%[2]s] 中的一个",
+		"email":    "%[1]s 必须是合法的邮箱",
+		"url":      "%[1]s 必须是合法的URL",
+		"uuid":     "%[1]s 必须是合法的UUID",
+		"numeric":  "%[1]s 必须是数字",
+		"type":     "%[1]s 类型错误，应为 %[2]s",
+		"default":  "%[1]s 校验失败（%[3]s）",
+	},
+	"en": {
+		"required": "%[1]s is required",
+		"min":      "%[1]s must be at least %[2]s",
+		"max":      "%[1]s must be at most %[2]s",
+		"len":      "%[1]s must be %[2]s",
+		"min_len":  "%[1]s must be at least %[2]s in length",
+		"max_len":  "%[1]s must be at most %[2]s in length",
+		"len_len":  "%[1]s must be %[2]s in length",
+		"gt":       "%[1]s must be greater than %[2]s",
+		"gte":      "%[1]s must be greater than or equal to %[2]s",
+		"lt":       "%[1]s must be less than %[2]s",
+		"lte":      "%[1]s must be less than or equal to %[2]s",
+		"eq":       "%[1]s must be equal to %[2]s",
+		"ne":       "%[1]s must not be equal to %[2]s",
+		"oneof":    "%[1]s must be one of [%[2]s]",
+		"email":    "%[1]s must be a valid email",
+		"url":      "%[1]s must be a valid URL",
+		"uuid":     "%[1]s must be a valid UUID",
+		"numeric":  "%[1]s must be numeric",
+		"type":     "%[1]s has wrong type, expected %[2]s",
+		"default":  "%[1]s failed on the '%[3]s' rule",
+	},
+}
+
+// FromValidation 将参数绑定/校验错误转换为 PARAM_ERROR，信息中列出每个不合法的字段
+// 支持 validator.ValidationErrors 与 JSON 类型错误，其他错误返回 ErrorParamInvalid；原始错误通过 Wrap 保留
+// 传入绑定的请求结构体时，字段名按其 json/form 标签输出，不依赖全局 validator 的 RegisterTagNameFunc
+func FromValidation(err error, req ...any) Error {
+	if err == nil {
+		return ErrorParamInvalid
+	}
+	var reqType reflect.Type
+	if len(req) > 0 && req[0] != nil {
+		reqType = reflect.TypeOf(req[0])
+	}
+	var vErrs validator.ValidationErrors
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &vErrs):
+		return newValidationError(func(lang string) []string {
+			msgs := make([]string, 0, len(vErrs))
+			for _, fe := range vErrs {
+				msgs = append(msgs, fieldErrorMessage(lang, fe, reqType))
+			}
+			return msgs
+		}).Wrap(err)
+	case errors.As(err, &typeErr):
+		return newValidationError(func(lang string) []string {
+			field := typeErr.Field
+			if field == "" {
+				field = "body"
+			}
+			return []string{fmt.Sprintf(validationMsg[lang]["type"], field, typeErr.Type.String())}
+		}).Wrap(err)
+	}
+	return ErrorParamInvalid.Wrap(err)
+}
+
+// newValidationError 为每种支持的语言拼接 "请求参数错误: a; b"
+func newValidationError(fields func(lang string) []string) Error {
+	messages := make(map[string]string, len(validationMsg))
+	for lang := range validationMsg {
+		base := ErrMsg[lang][PARAM_ERROR]
+		messages[lang] = base + ": " + strings.Join(fields(lang), "; ")
+	}
+	return Error{Code: PARAM_ERROR, Message: messages}
+}
+
+func fieldErrorMessage(lang string, fe validator.FieldError, reqType reflect.Type) string {
+	msgs := validationMsg[lang]
+	tag := fe.Tag()
+	if strings.HasPrefix(tag, "required") {
+		tag = "required"
+	}
+	key := tag
+	switch fe.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+		if _, ok := msgs[tag+"_len"]; ok {
+			key = tag + "_len"
+		}
+	}
+	format, ok := msgs[key]
+	if !ok {
+		format = msgs["default"]
+	}
+	return fmt.Sprintf(format, fieldPath(fe, reqType), fe.Param(), fe.Tag())
+}
+
+// fieldPath 去掉命名空间中的根结构体名，保留嵌套路径，如 user.name
+// reqType 不为空时沿 StructNamespace 逐级查找字段，使用 json/form 标签名
+func fieldPath(fe validator.FieldError, reqType reflect.Type) string {
+	if reqType == nil {
+		ns := fe.Namespace()
+		if i := strings.Index(ns, "."); i >= 0 {
+			return ns[i+1:]
+		}
+		return fe.Field()
+	}
+	parts := strings.Split(fe.StructNamespace(), ".")[1:]
+	names := make([]string, 0, len(parts))
+	typ := reqType
+	for _, part := range parts {
+		// 切片/map 元素的命名空间形如 Items[0]
+		name, index := part, ""
+		if i := strings.Index(part, "["); i >= 0 {
+			name, index = part[:i], part[i:]
+		}
+		for typ != nil && (typ.Kind() == reflect.Pointer || typ.Kind() == reflect.Slice ||
+			typ.Kind() == reflect.Array || typ.Kind() == reflect.Map) {
+			typ = typ.Elem()
+		}
+		if typ == nil || typ.Kind() != reflect.Struct {
+			names = append(names, part)
+			typ = nil
+			continue
+		}
+		field, ok := typ.FieldByName(name)
+		if !ok {
+			names = append(names, part)
+			typ = nil
+			continue
+		}
+		names = append(names, requestFieldName(field)+index)
+		typ = field.Type
+	}
+	return strings.Join(names, ".")
+}
+
+// requestFieldName 依次取 json、form 标签名，都没有时使用字段名
+func requestFieldName(field reflect.StructField) string {
+	for _, tag := range []string{"json", "form"} {
+		name, _, _ := strings.Cut(field.Tag.Get(tag), ",")
+		if name == "-" {
+			continue
+		}
+		if name != "" {
+			return name
+		}
+	}
+	return field.Name
+}
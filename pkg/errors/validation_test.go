@@ -0,0 +1,80 @@
+package errors
+
+import (
+	"encoding/json"
+	stderrors "errors"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/xiangtao94/golib/pkg/env"
+)
+
+type validationUser struct {
+	Name string `json:"name" validate:"required,min=2"`
+}
+
+type validationReq struct {
+	Age  int            `json:"age" validate:"gte=18"`
+	Role string         `json:"role" validate:"oneof=admin user"`
+	User validationUser `json:"user"`
+}
+
+func TestFromValidation(t *testing.T) {
+	v := validator.New()
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		return name
+	})
+	verr := v.Struct(validationReq{Age: 10, Role: "root", User: validationUser{Name: "a"}})
+	err := FromValidation(verr)
+
+	assert.Equal(t, PARAM_ERROR, err.Code)
+	assert.True(t, stderrors.Is(err, ErrorParamInvalid))
+	assert.Equal(t, verr, err.Unwrap())
+
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	ctx.Set(env.I18N_CONTEXT, "en")
+	assert.Equal(t, "Request parameter error: age must be greater than or equal to 18; role must be one of [admin user]; user.name must be at least 2 in length", err.GetMessage(ctx))
+	ctx.Set(env.I18N_CONTEXT, "zh")
+	assert.Equal(t, "请求参数错误: age 必须大于等于 18; role 必须是 [admin user] 中的一个; user.name 长度不能小于 2", err.GetMessage(ctx))
+}
+
+type validationItem struct {
+	SKU string `form:"sku" validate:"required"`
+}
+
+type validationBindReq struct {
+	Age   int              `json:"age" validate:"gte=18"`
+	Page  int              `form:"page" validate:"min=1"`
+	Owner *validationUser  `json:"owner"`
+	Items []validationItem `json:"items" validate:"dive"`
+}
+
+func TestFromValidationWithRequest(t *testing.T) {
+	// 未注册 TagNameFunc，字段名从请求结构体的标签中查找
+	req := validationBindReq{Age: 10, Page: 0, Owner: &validationUser{}, Items: []validationItem{{SKU: "a"}, {}}}
+	verr := validator.New().Struct(req)
+	assert.Equal(t, "Request parameter error: Age must be greater than or equal to 18; Page must be at least 1; Owner.Name is required; Items[1].SKU is required",
+		FromValidation(verr).Message["en"])
+	assert.Equal(t, "Request parameter error: age must be greater than or equal to 18; page must be at least 1; owner.name is required; items[1].sku is required",
+		FromValidation(verr, &req).Message["en"])
+}
+
+func TestFromValidationOtherErrors(t *testing.T) {
+	var req validationReq
+	jerr := json.Unmarshal([]byte(`{"age":"ten"}`), &req)
+	err := FromValidation(jerr)
+	assert.Equal(t, PARAM_ERROR, err.Code)
+	assert.Equal(t, "Request parameter error: age has wrong type, expected int", err.Message["en"])
+
+	cause := stderrors.New("EOF")
+	err = FromValidation(cause)
+	assert.Equal(t, ErrorParamInvalid.Message, err.Message)
+	assert.Equal(t, cause, err.Unwrap())
+}
@@ -0,0 +1,113 @@
+package errors
+
+import (
+	stderrors "errors"
+	"fmt"
+	"io"
+	"runtime"
+
+	"github.com/xiangtao94/golib/pkg/zlog"
+)
+
+const maxStackDepth = 32
+
+// WrapError 用 code 对应的错误包装底层错误，记录调用栈和 kv 形式的附加信息
+// 返回给客户端的错误码和信息与 NewError(code, nil) 相同，errors.Is 可与同错误码的预定义错误匹配
+// 自定义消息的错误（非 RegisterMessages 注册）使用 err.Wrap(cause).WithFields(kv...)
+//
+//	return errors.WrapError(errors.SYSTEM_ERROR, err, "table", "user", "id", id)
+func WrapError(code int, cause error, kv ...any) Error {
+	err := NewError(code, nil)
+	err.cause = cause
+	err.fields = kv
+	err.stack = callers()
+	return err
+}
+
+// WithFields 返回追加 kv 附加信息的副本，不影响原错误
+func (err Error) WithFields(kv ...any) Error {
+	fields := make([]any, 0, len(err.fields)+len(kv))
+	fields = append(fields, err.fields...)
+	err.fields = append(fields, kv...)
+	return err
+}
+
+// Fields 返回错误码、底层错误和附加信息，包括错误链中其他 Error 的附加信息，用于 zlog.ErrorLogger
+func (err Error) Fields() []zlog.Field {
+	fields := []zlog.Field{zlog.Int("errCode", err.Code)}
+	if err.cause != nil {
+		fields = append(fields, zlog.String("errCause", err.cause.Error()))
+	}
+	for e := err; ; {
+		fields = appendKV(fields, e.fields)
+		var inner Error
+		if !stderrors.As(e.cause, &inner) {
+			break
+		}
+		e = inner
+	}
+	return fields
+}
+
+// LogFields 错误链中有 Error 时返回其 Fields，否则只返回底层错误
+func LogFields(err error) []zlog.Field {
+	if err == nil {
+		return nil
+	}
+	var e Error
+	if stderrors.As(err, &e) {
+		return e.Fields()
+	}
+	return []zlog.Field{zlog.String("errCause", err.Error())}
+}
+
+// appendKV key 不是字符串时用 fmt.Sprint 转换，落单的 value 记为 !BADKEY
+func appendKV(fields []zlog.Field, kv []any) []zlog.Field {
+	for i := 0; i < len(kv); i += 2 {
+		if i+1 >= len(kv) {
+			fields = append(fields, zlog.Any("!BADKEY", kv[i]))
+			break
+		}
+		key, ok := kv[i].(string)
+		if !ok {
+			key = fmt.Sprint(kv[i])
+		}
+		fields = append(fields, zlog.Any(key, kv[i+1]))
+	}
+	return fields
+}
+
+// callers 跳过 runtime.Callers、callers 和 Wrap/WrapError 自身
+func callers() []uintptr {
+	pcs := make([]uintptr, maxStackDepth)
+	n := runtime.Callers(3, pcs)
+	return pcs[:n]
+}
+
+// formatDetail 依次输出附加信息、底层错误（递归 %+v 即错误链）和调用栈
+func (err Error) formatDetail(w io.Writer) {
+	if len(err.fields) > 0 {
+		io.WriteString(w, "\nfields:")
+		for i := 0; i < len(err.fields); i += 2 {
+			if i+1 >= len(err.fields) {
+				fmt.Fprintf(w, " !BADKEY=%v", err.fields[i])
+				break
+			}
+			fmt.Fprintf(w, " %v=%v", err.fields[i], err.fields[i+1])
+		}
+	}
+	if err.cause != nil {
+		fmt.Fprintf(w, "\ncause: %+v", err.cause)
+	}
+	if len(err.stack) > 0 {
+		io.WriteString(w, "\nstack:")
+		frames := runtime.CallersFrames(err.stack)
+		for {
+			frame, more := frames.Next()
+			fmt.Fprintf(w, "\n\t%s\n\t\t%s:%d", frame.Function, frame.File, frame.Line)
+			if !more {
+				break
+			}
+		}
+	}
+}
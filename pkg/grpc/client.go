@@ -0,0 +1,194 @@
+// Package grpc -----------------------------
+// @file      : client.go
+// @author    : xiangtao
+// @contact   : xiangtao1994@gmail.com
+// @time      : 2025/9/14 10:40
+// Description: gRPC 客户端连接，预置 keepalive、超时、重试和请求ID透传
+// -------------------------------------------
+package grpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/backoff"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/xiangtao94/golib/pkg/zlog"
+)
+
+// grpc-go 允许的最大尝试次数，超过时按5处理
+const maxRetryAttempts = 5
+
+// ClientConf gRPC 客户端配置，为0时使用默认值
+type ClientConf struct {
+	Service          string        `yaml:"service"`          // 下游服务名，用于日志和指标
+	Target           string        `yaml:"target"`           // 连接地址，如 dns:///user-service:9090
+	Timeout          time.Duration `yaml:"timeout"`          // 单次调用超时时间（含重试），ctx 未设置 deadline 时生效，默认5s
+	ConnectTimeout   time.Duration `yaml:"connectTimeout"`   // 建立连接的超时时间，默认3s
+	KeepaliveTime    time.Duration `yaml:"keepaliveTime"`    // 连接空闲多久后发送 ping，默认30s，不能小于服务端的 minPingInterval
+	KeepaliveTimeout time.Duration `yaml:"keepaliveTimeout"` // ping 超时时间，默认10s
+	RetryTimes       int           `yaml:"retryTimes"`       // 最大重试次数，默认2，小于0不重试，最多重试4次
+	RetryCodes       []string      `yaml:"retryCodes"`       // 可重试的状态码，默认 UNAVAILABLE
+	MaxRecvMsgSize   int           `yaml:"maxRecvMsgSize"`   // 最大接收消息字节数，默认4MB
+
+	Credentials credentials.TransportCredentials `json:"-"` // 传输凭证，为空时不使用 TLS
+	DialOptions []grpc.DialOption                `json:"-"` // 额外的连接选项
+}
+
+func (conf *ClientConf) checkConf() error {
+	if conf.Target == "" {
+		return errors.New("grpc client target is empty")
+	}
+	if conf.Service == "" {
+		conf.Service = conf.Target
+	}
+	if conf.Timeout <= 0 {
+		conf.Timeout = 5 * time.Second
+	}
+	if conf.ConnectTimeout <= 0 {
+		conf.ConnectTimeout = 3 * time.Second
+	}
+	if conf.KeepaliveTime <= 0 {
+		conf.KeepaliveTime = 30 * time.Second
+	}
+	if conf.KeepaliveTimeout <= 0 {
+		conf.KeepaliveTimeout = 10 * time.Second
+	}
+	if conf.RetryTimes == 0 {
+		conf.RetryTimes = 2
+	}
+	if len(conf.RetryCodes) == 0 {
+		conf.RetryCodes = []string{"UNAVAILABLE"}
+	}
+	if conf.Credentials == nil {
+		conf.Credentials = insecure.NewCredentials()
+	}
+	return nil
+}
+
+// NewConn 创建到 Target 的连接，连接在首次调用时建立
+// ctx 为 *gin.Context 或 GinContext 可取到时，自动把请求ID写入 outgoing metadata 的 request-id
+func NewConn(conf ClientConf) (*grpc.ClientConn, error) {
+	if err := conf.checkConf(); err != nil {
+		return nil, err
+	}
+	serviceConfig, err := conf.serviceConfig()
+	if err != nil {
+		return nil, err
+	}
+	ci := clientInterceptor{service: conf.Service, timeout: conf.Timeout}
+	dialOpts := []grpc.DialOption{
+		grpc.WithTransportCredentials(conf.Credentials),
+		grpc.WithKeepaliveParams(keepalive.ClientParameters{
+			Time:                conf.KeepaliveTime,
+			Timeout:             conf.KeepaliveTimeout,
+			PermitWithoutStream: true,
+		}),
+		grpc.WithConnectParams(grpc.ConnectParams{
+			Backoff:           backoff.DefaultConfig,
+			MinConnectTimeout: conf.ConnectTimeout,
+		}),
+		grpc.WithDefaultServiceConfig(serviceConfig),
+		grpc.WithChainUnaryInterceptor(ci.unary),
+		grpc.WithChainStreamInterceptor(ci.stream),
+	}
+	if conf.MaxRecvMsgSize > 0 {
+		dialOpts = append(dialOpts, grpc.WithDefaultCallOptions(grpc.MaxCallRecvMsgSize(conf.MaxRecvMsgSize)))
+	}
+	conn, err := grpc.NewClient(conf.Target, append(dialOpts, conf.DialOptions...)...)
+	if err != nil {
+		return nil, fmt.Errorf("grpc client %s init error: %w", conf.Service, err)
+	}
+	return conn, nil
+}
+
+// serviceConfig 所有方法使用相同的重试策略，退避从100ms开始，最长1s
+func (conf *ClientConf) serviceConfig() (string, error) {
+	methodConfig := map[string]any{
+		"name": []map[string]string{{}},
+	}
+	if conf.RetryTimes > 0 {
+		codes := make([]string, 0, len(conf.RetryCodes))
+		for _, code := range conf.RetryCodes {
+			codes = append(codes, strings.ToUpper(strings.TrimSpace(code)))
+		}
+		methodConfig["retryPolicy"] = map[string]any{
+			"maxAttempts":          min(conf.RetryTimes+1, maxRetryAttempts),
+			"initialBackoff":       "0.1s",
+			"maxBackoff":           "1s",
+			"backoffMultiplier":    2,
+			"retryableStatusCodes": codes,
+		}
+	}
+	b, err := json.Marshal(map[string]any{"methodConfig": []any{methodConfig}})
+	if err != nil {
+		return "", fmt.Errorf("grpc client %s service config error: %w", conf.Service, err)
+	}
+	return string(b), nil
+}
+
+type clientInterceptor struct {
+	service string
+	timeout time.Duration
+}
+
+func (ci clientInterceptor) unary(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	start := time.Now()
+	c := callerGinContext(ctx)
+	ctx = withRequestID(ctx, c)
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, ci.timeout)
+		defer cancel()
+	}
+	err := invoker(ctx, method, req, reply, cc, opts...)
+
+	code := status.Code(err)
+	clientHandled.WithLabelValues(ci.service, method, code.String()).Inc()
+	clientDuration.WithLabelValues(ci.service, method).Observe(time.Since(start).Seconds())
+	fields := []zlog.Field{
+		zlog.String("service", ci.service),
+		zlog.String("method", method),
+		zlog.String("target", cc.Target()),
+		zlog.String("code", code.String()),
+		zlog.String("cost", fmt.Sprintf("%v%s", zlog.GetRequestCost(start, time.Now()), "ms")),
+	}
+	logger := zlog.LoggerWithContext(grpcLogger(), c)
+	if err != nil {
+		logger.Error(err.Error(), fields...)
+	} else {
+		logger.Info("grpc invoke", fields...)
+	}
+	return err
+}
+
+// stream 流式调用的生命周期由调用方控制，只透传请求ID
+func (ci clientInterceptor) stream(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	return streamer(withRequestID(ctx, callerGinContext(ctx)), desc, cc, method, opts...)
+}
+
+// callerGinContext ctx 本身是 *gin.Context，或由 NewServer 的拦截器构造（gRPC 服务中再调用下游）
+func callerGinContext(ctx context.Context) *gin.Context {
+	if c, ok := ctx.(*gin.Context); ok {
+		return c
+	}
+	return GinContext(ctx)
+}
+
+func withRequestID(ctx context.Context, c *gin.Context) context.Context {
+	if c == nil {
+		return ctx
+	}
+	return metadata.AppendToOutgoingContext(ctx, MetadataRequestID, zlog.GetRequestID(c))
+}
@@ -0,0 +1,65 @@
+// Package grpc -----------------------------
+// @file      : metrics.go
+// @author    : xiangtao
+// @contact   : xiangtao1994@gmail.com
+// @time      : 2025/9/14 10:30
+// Description: gRPC 服务端和客户端的Prometheus指标
+// -------------------------------------------
+package grpc
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	serverHandled = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "grpc_server_handled_total",
+			Help: "Total number of RPCs completed on the server, regardless of success or failure.",
+		}, []string{"method", "code"},
+	)
+
+	serverDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name: "grpc_server_handling_seconds",
+			Help: "Latency of RPCs handled by the server in seconds.",
+		}, []string{"method"},
+	)
+
+	clientHandled = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "grpc_client_handled_total",
+			Help: "Total number of unary RPCs completed by the client, regardless of success or failure.",
+		}, []string{"service", "method", "code"},
+	)
+
+	clientDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name: "grpc_client_handling_seconds",
+			Help: "Latency of unary RPCs made by the client in seconds, including retries.",
+		}, []string{"service", "method"},
+	)
+
+	metricsCollector prometheus.Collector = grpcCollector{}
+)
+
+type grpcCollector struct{}
+
+func (grpcCollector) Describe(ch chan<- *prometheus.Desc) {
+	serverHandled.Describe(ch)
+	serverDuration.Describe(ch)
+	clientHandled.Describe(ch)
+	clientDuration.Describe(ch)
+}
+
+func (grpcCollector) Collect(ch chan<- prometheus.Metric) {
+	serverHandled.Collect(ch)
+	serverDuration.Collect(ch)
+	clientHandled.Collect(ch)
+	clientDuration.Collect(ch)
+}
+
+// MetricsCollector 返回 gRPC 指标，传给 middleware.RegistryMetrics 注册即可在 /metrics 中输出
+func MetricsCollector() prometheus.Collector {
+	return metricsCollector
+}
@@ -0,0 +1,69 @@
+// Package grpc -----------------------------
+// @file      : serve.go
+// @author    : xiangtao
+// @contact   : xiangtao1994@gmail.com
+// @time      : 2025/9/14 10:20
+// Description: 监听端口的 gRPC 服务，配合 golib.StartServer 启动和优雅退出
+// -------------------------------------------
+package grpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+
+	"google.golang.org/grpc"
+)
+
+// GracefulServer 已监听端口的 gRPC 服务，实现 golib.Server
+//
+//	server := grpcx.NewServer(grpcx.ServerConf{})
+//	pb.RegisterUserServer(server, &userServer{})
+//	srv, err := grpcx.Listen(server, 9090)
+//	golib.StartServer(srv)
+type GracefulServer struct {
+	server *grpc.Server
+	lis    net.Listener
+}
+
+// Listen 监听端口，端口被占用等错误在启动前返回
+func Listen(server *grpc.Server, port int) (*GracefulServer, error) {
+	lis, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	if err != nil {
+		return nil, fmt.Errorf("grpc listen: %w", err)
+	}
+	return &GracefulServer{server: server, lis: lis}, nil
+}
+
+// Addr 监听地址
+func (s *GracefulServer) Addr() net.Addr {
+	return s.lis.Addr()
+}
+
+// Serve 阻塞处理请求，Shutdown 后返回 nil
+func (s *GracefulServer) Serve() error {
+	log.Printf("gRPC server is running on %s", s.lis.Addr())
+	if err := s.server.Serve(s.lis); err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+		return fmt.Errorf("grpc serve: %w", err)
+	}
+	return nil
+}
+
+// Shutdown 先 GracefulStop 等待进行中的调用，GracefulStop 不支持超时，ctx 结束时强制关闭所有连接
+func (s *GracefulServer) Shutdown(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		s.server.GracefulStop()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		s.server.Stop()
+		<-done
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,198 @@
+// Package grpc -----------------------------
+// @file      : server.go
+// @author    : xiangtao
+// @contact   : xiangtao1994@gmail.com
+// @time      : 2025/9/14 10:20
+// Description: gRPC 服务端，预置 panic 恢复、访问日志和 Prometheus 指标拦截器
+// -------------------------------------------
+package grpc
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/xiangtao94/golib/pkg/zlog"
+)
+
+// MetadataRequestID 传递请求ID的 metadata key
+const MetadataRequestID = "request-id"
+
+// ServerConf gRPC 服务端配置，为0时使用默认值
+type ServerConf struct {
+	MaxRecvMsgSize    int           `yaml:"maxRecvMsgSize"`    // 最大接收消息字节数，默认4MB
+	MaxSendMsgSize    int           `yaml:"maxSendMsgSize"`    // 最大发送消息字节数，默认不限制
+	KeepaliveTime     time.Duration `yaml:"keepaliveTime"`     // 连接空闲多久后发送 ping，默认60s
+	KeepaliveTimeout  time.Duration `yaml:"keepaliveTimeout"`  // ping 超时时间，超时后关闭连接，默认20s
+	MinPingInterval   time.Duration `yaml:"minPingInterval"`   // 允许客户端 ping 的最小间隔，过于频繁的客户端会被断开，默认10s
+	MaxConnectionIdle time.Duration `yaml:"maxConnectionIdle"` // 连接无请求多久后关闭，默认不关闭
+	MaxConnectionAge  time.Duration `yaml:"maxConnectionAge"`  // 连接最长存活时间，用于扩容后重新均衡连接，默认不限制
+}
+
+func (conf *ServerConf) checkConf() {
+	if conf.KeepaliveTime <= 0 {
+		conf.KeepaliveTime = 60 * time.Second
+	}
+	if conf.KeepaliveTimeout <= 0 {
+		conf.KeepaliveTimeout = 20 * time.Second
+	}
+	if conf.MinPingInterval <= 0 {
+		conf.MinPingInterval = 10 * time.Second
+	}
+}
+
+// grpcLogger 访问日志使用的 logger，测试中替换
+var grpcLogger = func() *zap.Logger {
+	return zlog.NewLoggerWithSkip(1)
+}
+
+// NewServer 创建 gRPC 服务，拦截器顺序为 访问日志 -> 指标 -> panic 恢复 -> opts 中的拦截器 -> handler
+// handler 中通过 GinContext(ctx) 获取携带请求ID的 gin.Context，用于 zlog 日志和 http 客户端透传
+func NewServer(conf ServerConf, opts ...grpc.ServerOption) *grpc.Server {
+	conf.checkConf()
+	serverOpts := []grpc.ServerOption{
+		grpc.KeepaliveParams(keepalive.ServerParameters{
+			Time:              conf.KeepaliveTime,
+			Timeout:           conf.KeepaliveTimeout,
+			MaxConnectionIdle: conf.MaxConnectionIdle,
+			MaxConnectionAge:  conf.MaxConnectionAge,
+		}),
+		grpc.KeepaliveEnforcementPolicy(keepalive.EnforcementPolicy{
+			MinTime:             conf.MinPingInterval,
+			PermitWithoutStream: true,
+		}),
+		grpc.ChainUnaryInterceptor(unaryServerInterceptor),
+		grpc.ChainStreamInterceptor(streamServerInterceptor),
+	}
+	if conf.MaxRecvMsgSize > 0 {
+		serverOpts = append(serverOpts, grpc.MaxRecvMsgSize(conf.MaxRecvMsgSize))
+	}
+	if conf.MaxSendMsgSize > 0 {
+		serverOpts = append(serverOpts, grpc.MaxSendMsgSize(conf.MaxSendMsgSize))
+	}
+	return grpc.NewServer(append(serverOpts, opts...)...)
+}
+
+type ginContextKey struct{}
+
+// GinContext 返回服务端拦截器为本次调用构造的 gin.Context，非 NewServer 创建的服务调用时返回 nil
+func GinContext(ctx context.Context) *gin.Context {
+	c, _ := ctx.Value(ginContextKey{}).(*gin.Context)
+	return c
+}
+
+var (
+	callEngineOnce sync.Once
+	callEngine     *gin.Engine
+)
+
+func getCallEngine() *gin.Engine {
+	callEngineOnce.Do(func() {
+		callEngine = gin.New()
+		// Deadline/Done 使用 Request.Context()，即 gRPC 调用的上下文
+		callEngine.ContextWithFallback = true
+	})
+	return callEngine
+}
+
+// newCallContext 构造本次调用的 gin.Context，incoming metadata 作为请求头，
+// 上游的 request-id 按 zlog 的规则作为请求ID，没有时生成
+func newCallContext(ctx context.Context, fullMethod string) (context.Context, *gin.Context) {
+	c := gin.CreateTestContextOnly(nil, getCallEngine())
+	c.Request, _ = http.NewRequestWithContext(ctx, http.MethodPost, fullMethod, http.NoBody)
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		for key, values := range md {
+			if strings.HasPrefix(key, ":") || strings.HasSuffix(key, "-bin") {
+				continue
+			}
+			for _, v := range values {
+				c.Request.Header.Add(key, v)
+			}
+		}
+	}
+	c.Set(zlog.ContextKeyUri, fullMethod)
+	_ = zlog.GetRequestID(c)
+	ctx = context.WithValue(ctx, ginContextKey{}, c)
+	c.Request = c.Request.WithContext(ctx)
+	return ctx, c
+}
+
+func unaryServerInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+	start := time.Now()
+	ctx, c := newCallContext(ctx, info.FullMethod)
+	defer func() {
+		if r := recover(); r != nil {
+			err = recoverPanic(c, r)
+		}
+		finishServerCall(ctx, c, info.FullMethod, err, start)
+	}()
+	return handler(ctx, req)
+}
+
+type wrappedServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *wrappedServerStream) Context() context.Context {
+	return s.ctx
+}
+
+func streamServerInterceptor(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+	start := time.Now()
+	ctx, c := newCallContext(ss.Context(), info.FullMethod)
+	defer func() {
+		if r := recover(); r != nil {
+			err = recoverPanic(c, r)
+		}
+		finishServerCall(ctx, c, info.FullMethod, err, start)
+	}()
+	return handler(srv, &wrappedServerStream{ServerStream: ss, ctx: ctx})
+}
+
+// recoverPanic 记录堆栈，返回给调用方的错误不包含 panic 内容
+func recoverPanic(c *gin.Context, r any) error {
+	zlog.LoggerWithContext(grpcLogger(), c).Error("Panic Recovery",
+		zap.Any("error", r),
+		zap.String("stack", string(debug.Stack())),
+	)
+	return status.Error(codes.Internal, "internal error")
+}
+
+// finishServerCall 记录访问日志和指标
+func finishServerCall(ctx context.Context, c *gin.Context, fullMethod string, err error, start time.Time) {
+	code := status.Code(err)
+	serverHandled.WithLabelValues(fullMethod, code.String()).Inc()
+	serverDuration.WithLabelValues(fullMethod).Observe(time.Since(start).Seconds())
+
+	var peerAddr string
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		peerAddr = p.Addr.String()
+	}
+	fields := []zlog.Field{
+		zlog.String("method", fullMethod),
+		zlog.String("peer", peerAddr),
+		zlog.String("code", code.String()),
+		zlog.String("cost", fmt.Sprintf("%v%s", zlog.GetRequestCost(start, time.Now()), "ms")),
+	}
+	fields = append(fields, zlog.GetCustomerFields(c)...)
+	logger := zlog.LoggerWithRequestID(grpcLogger(), c)
+	if err != nil {
+		logger.Error(err.Error(), fields...)
+	} else {
+		logger.Info("grpc access", fields...)
+	}
+}
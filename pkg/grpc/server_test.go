@@ -0,0 +1,153 @@
+package grpc
+
+import (
+	"context"
+	"net"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	"github.com/xiangtao94/golib/pkg/zlog"
+)
+
+const (
+	echoMethod  = "/test.Echo/Say"
+	panicMethod = "/test.Echo/Panic"
+)
+
+// echoServiceDesc 手写的服务描述，Say 返回 handler 中看到的请求ID
+var echoServiceDesc = grpc.ServiceDesc{
+	ServiceName: "test.Echo",
+	HandlerType: (*any)(nil),
+	Methods: []grpc.MethodDesc{
+		{MethodName: "Say", Handler: echoHandler(func(ctx context.Context, in *wrapperspb.StringValue) (*wrapperspb.StringValue, error) {
+			c := GinContext(ctx)
+			zlog.LoggerWithContext(grpcLogger(), c).Info("say " + in.Value)
+			return wrapperspb.String(zlog.GetRequestID(c)), nil
+		}, echoMethod)},
+		{MethodName: "Panic", Handler: echoHandler(func(ctx context.Context, in *wrapperspb.StringValue) (*wrapperspb.StringValue, error) {
+			panic("boom")
+		}, panicMethod)},
+	},
+}
+
+func echoHandler(fn func(ctx context.Context, in *wrapperspb.StringValue) (*wrapperspb.StringValue, error), method string) func(srv any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+	return func(srv any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+		in := new(wrapperspb.StringValue)
+		if err := dec(in); err != nil {
+			return nil, err
+		}
+		handler := func(ctx context.Context, req any) (any, error) {
+			return fn(ctx, req.(*wrapperspb.StringValue))
+		}
+		return interceptor(ctx, in, &grpc.UnaryServerInfo{Server: srv, FullMethod: method}, handler)
+	}
+}
+
+func newTestConn(t *testing.T) (*grpc.ClientConn, *observer.ObservedLogs) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	old := grpcLogger
+	grpcLogger = func() *zap.Logger { return zap.New(core) }
+	t.Cleanup(func() { grpcLogger = old })
+
+	lis := bufconn.Listen(1 << 20)
+	server := NewServer(ServerConf{})
+	server.RegisterService(&echoServiceDesc, struct{}{})
+	go func() { _ = server.Serve(lis) }()
+	t.Cleanup(server.Stop)
+
+	conn, err := NewConn(ClientConf{
+		Service: "echo",
+		Target:  "passthrough:///bufnet",
+		DialOptions: []grpc.DialOption{grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		})},
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	return conn, logs
+}
+
+func TestRequestIDRoundTrip(t *testing.T) {
+	conn, logs := newTestConn(t)
+	before := testutil.ToFloat64(serverHandled.WithLabelValues(echoMethod, "OK"))
+	clientBefore := testutil.ToFloat64(clientHandled.WithLabelValues("echo", echoMethod, "OK"))
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Request-Id", "upstream-id")
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	ctx.Request = req
+
+	out := new(wrapperspb.StringValue)
+	require.NoError(t, conn.Invoke(ctx, echoMethod, wrapperspb.String("hi"), out))
+	assert.Equal(t, "upstream-id", out.Value)
+
+	handlerLogs := logs.FilterMessage("say hi").All()
+	require.Len(t, handlerLogs, 1)
+	assert.Equal(t, "upstream-id", handlerLogs[0].ContextMap()["requestId"])
+
+	access := logs.FilterMessage("grpc access").All()
+	require.Len(t, access, 1)
+	fields := access[0].ContextMap()
+	assert.Equal(t, "upstream-id", fields["requestId"])
+	assert.Equal(t, echoMethod, fields["method"])
+	assert.Equal(t, "OK", fields["code"])
+	assert.NotEmpty(t, fields["peer"])
+
+	invoke := logs.FilterMessage("grpc invoke").All()
+	require.Len(t, invoke, 1)
+	assert.Equal(t, "upstream-id", invoke[0].ContextMap()["requestId"])
+
+	assert.Equal(t, before+1, testutil.ToFloat64(serverHandled.WithLabelValues(echoMethod, "OK")))
+	assert.Equal(t, clientBefore+1, testutil.ToFloat64(clientHandled.WithLabelValues("echo", echoMethod, "OK")))
+}
+
+func TestRequestIDGenerated(t *testing.T) {
+	conn, _ := newTestConn(t)
+
+	// 非 gin.Context 调用时服务端生成请求ID
+	out := new(wrapperspb.StringValue)
+	require.NoError(t, conn.Invoke(context.Background(), echoMethod, wrapperspb.String("hi"), out))
+	assert.NotEmpty(t, out.Value)
+}
+
+func TestPanicRecovery(t *testing.T) {
+	conn, logs := newTestConn(t)
+	before := testutil.ToFloat64(serverHandled.WithLabelValues(panicMethod, "Internal"))
+
+	err := conn.Invoke(context.Background(), panicMethod, wrapperspb.String("hi"), new(wrapperspb.StringValue))
+	assert.Equal(t, codes.Internal, status.Code(err))
+	assert.NotContains(t, err.Error(), "boom")
+	require.Len(t, logs.FilterMessage("Panic Recovery").All(), 1)
+	assert.Equal(t, before+1, testutil.ToFloat64(serverHandled.WithLabelValues(panicMethod, "Internal")))
+}
+
+func TestServiceConfig(t *testing.T) {
+	conf := ClientConf{Target: "dns:///user:9090", RetryTimes: 10, RetryCodes: []string{"unavailable", "RESOURCE_EXHAUSTED"}}
+	require.NoError(t, conf.checkConf())
+	sc, err := conf.serviceConfig()
+	require.NoError(t, err)
+	assert.Contains(t, sc, `"maxAttempts":5`)
+	assert.Contains(t, sc, `"retryableStatusCodes":["UNAVAILABLE","RESOURCE_EXHAUSTED"]`)
+
+	conf = ClientConf{Target: "dns:///user:9090", RetryTimes: -1}
+	require.NoError(t, conf.checkConf())
+	sc, err = conf.serviceConfig()
+	require.NoError(t, err)
+	assert.NotContains(t, sc, "retryPolicy")
+
+	_, err = NewConn(ClientConf{})
+	assert.Error(t, err)
+}
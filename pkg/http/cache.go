@@ -0,0 +1,319 @@
+// Package http -----------------------------
+// @file      : cache.go
+// @author    : xiangtao
+// @contact   : xiangtao1994@gmail.com
+// @time      : 2025/9/14 17:00
+// Description: 幂等请求（GET/HEAD）的响应缓存，支持 redis 或进程内 LRU
+// -------------------------------------------
+package http
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	goredis "github.com/redis/go-redis/v9"
+
+	"github.com/xiangtao94/golib/pkg/redis"
+	"github.com/xiangtao94/golib/pkg/zlog"
+)
+
+// CacheConf 响应缓存配置，只缓存 2xx 的 GET/HEAD 响应
+// 默认缓存键包含 Path、QueryParams、Headers 和 Cookies；透传的请求头（如 Request-Id）不参与缓存键
+// 响应带 Cache-Control: no-store 时不缓存，带 max-age 时按 max-age 缓存；带 ETag 的响应过期后用 If-None-Match 校验，304 时沿用缓存
+type CacheConf struct {
+	TTL        time.Duration `yaml:"ttl"`        // 缓存时间，响应未指定 max-age 时使用，默认1分钟
+	StaleTTL   time.Duration `yaml:"staleTTL"`   // 带 ETag 的响应过期后继续保留的时间，用于 If-None-Match 校验，默认与 TTL 相同
+	MaxEntries int           `yaml:"maxEntries"` // 进程内 LRU 的最大条目数，默认1000，使用 redis 或自定义 Storage 时无效
+
+	Storage  CacheStorage                     `json:"-"` // 自定义缓存存储，优先于 Redis
+	Redis    *redis.Redis                     `json:"-"` // Storage 和 Redis 都为空时使用进程内 LRU
+	KeyFunc  func(opts RequestOptions) string `json:"-"` // 自定义缓存键，为空时使用默认规则，返回空字符串时不缓存
+	initOnce sync.Once
+}
+
+// CachedResponse 缓存的响应内容，ExpireAt 之后需要重新请求或校验 ETag
+type CachedResponse struct {
+	HttpCode int         `json:"code"`
+	Header   http.Header `json:"header"`
+	Body     []byte      `json:"body"`
+	ExpireAt time.Time   `json:"expireAt"`
+}
+
+// CacheStorage 响应缓存存储，ttl 为存储的保留时间，可能长于响应的 ExpireAt
+// 内置 NewLRUCacheStorage 和 NewRedisCacheStorage；读写失败时按未命中处理，只记录日志
+type CacheStorage interface {
+	Get(ctx context.Context, key string) (*CachedResponse, bool, error)
+	Set(ctx context.Context, key string, resp *CachedResponse, ttl time.Duration) error
+}
+
+func (conf *CacheConf) checkConf() {
+	if conf.TTL <= 0 {
+		conf.TTL = time.Minute
+	}
+	if conf.StaleTTL <= 0 {
+		conf.StaleTTL = conf.TTL
+	}
+	if conf.MaxEntries <= 0 {
+		conf.MaxEntries = 1000
+	}
+}
+
+func (conf *CacheConf) getStore() CacheStorage {
+	conf.initOnce.Do(func() {
+		conf.checkConf()
+		switch {
+		case conf.Storage != nil:
+		case conf.Redis != nil:
+			conf.Storage = NewRedisCacheStorage(conf.Redis)
+		default:
+			conf.Storage = NewLRUCacheStorage(conf.MaxEntries)
+		}
+	})
+	return conf.Storage
+}
+
+// cacheKey 返回请求的缓存键，不需要缓存时返回 false
+func (c *ClientConf) cacheKey(method string, opts RequestOptions) (string, bool) {
+	if c.Cache == nil || opts.NoCache {
+		return "", false
+	}
+	if method != http.MethodGet && method != http.MethodHead {
+		return "", false
+	}
+	var key string
+	if c.Cache.KeyFunc != nil {
+		key = c.Cache.KeyFunc(opts)
+	} else {
+		key = defaultCacheKey(opts)
+	}
+	if key == "" {
+		return "", false
+	}
+	return redis.GetKeyPrefix() + "httpcache:" + c.Service + ":" + method + ":" + key, true
+}
+
+// defaultCacheKey 对路径、查询参数、请求头和 Cookie 排序后取 sha1，避免键过长
+func defaultCacheKey(opts RequestOptions) string {
+	var b strings.Builder
+	b.WriteString(opts.Path)
+	writeSorted := func(sep string, m map[string]string) {
+		b.WriteString(sep)
+		for _, k := range slices.Sorted(maps.Keys(m)) {
+			b.WriteString(k)
+			b.WriteByte('=')
+			b.WriteString(m[k])
+			b.WriteByte('&')
+		}
+	}
+	writeSorted("?", opts.QueryParams)
+	writeSorted("#h:", opts.Headers)
+	writeSorted("#c:", opts.Cookies)
+	sum := sha1.Sum([]byte(b.String()))
+	return hex.EncodeToString(sum[:])
+}
+
+// doCached 带缓存执行请求：未过期时直接返回缓存；过期但有 ETag 时带上 If-None-Match 请求，304 时返回缓存并刷新过期时间
+func (c *ClientConf) doCached(ctx *gin.Context, key, method string, opts RequestOptions) (*Result, error) {
+	cached := c.getCached(ctx, key)
+	if cached != nil && time.Now().Before(cached.ExpireAt) {
+		res := cached.toResult(ctx)
+		c.recordCache(cacheResultHit)
+		c.logHttpInvoke(ctx, method, opts.Path, 0, res, nil, time.Now(), opts, zlog.String("cache", cacheResultHit))
+		return res, nil
+	}
+	etag := ""
+	if cached != nil {
+		etag = cached.Header.Get("ETag")
+	}
+	if etag != "" {
+		opts.Headers = maps.Clone(opts.Headers)
+		if opts.Headers == nil {
+			opts.Headers = make(map[string]string, 1)
+		}
+		opts.Headers["If-None-Match"] = etag
+	}
+
+	res, err := c.doRequest(ctx, method, opts)
+	if err == nil && etag != "" && res.HttpCode == http.StatusNotModified {
+		c.recordCache(cacheResultRevalidated)
+		c.setCached(ctx, key, cached, res.Header)
+		return cached.toResult(ctx), nil
+	}
+	c.recordCache(cacheResultMiss)
+	if err == nil && res.HttpCode >= http.StatusOK && res.HttpCode < http.StatusMultipleChoices {
+		c.setCached(ctx, key, &CachedResponse{HttpCode: res.HttpCode, Header: res.Header.Clone(), Body: slices.Clone(res.Response)}, res.Header)
+	}
+	return res, err
+}
+
+// getCached 读取缓存，读取失败按未命中处理
+func (c *ClientConf) getCached(ctx *gin.Context, key string) *CachedResponse {
+	cached, ok, err := c.Cache.getStore().Get(ctx, key)
+	if err != nil {
+		zlog.Warnf(ctx, "http cache get failed, key: %s, err: %v", key, err)
+		return nil
+	}
+	if !ok || cached == nil {
+		return nil
+	}
+	return cached
+}
+
+// setCached 按响应头的 Cache-Control 计算过期时间后写入，写入失败只记录日志
+func (c *ClientConf) setCached(ctx *gin.Context, key string, cached *CachedResponse, header http.Header) {
+	fresh, ok := freshnessTTL(header, c.Cache.TTL)
+	if !ok {
+		return
+	}
+	ttl := fresh
+	if cached.Header.Get("ETag") != "" {
+		ttl += c.Cache.StaleTTL
+	}
+	if ttl <= 0 {
+		return
+	}
+	stored := *cached
+	stored.ExpireAt = time.Now().Add(fresh)
+	if err := c.Cache.getStore().Set(ctx, key, &stored, ttl); err != nil {
+		zlog.Warnf(ctx, "http cache set failed, key: %s, err: %v", key, err)
+	}
+}
+
+// freshnessTTL 解析 Cache-Control：no-store 时不缓存；no-cache 时每次都需要校验；有 max-age 时按 max-age，否则为 defaultTTL
+func freshnessTTL(header http.Header, defaultTTL time.Duration) (time.Duration, bool) {
+	ttl := defaultTTL
+	for _, directive := range strings.Split(header.Get("Cache-Control"), ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(directive), "=")
+		switch strings.ToLower(name) {
+		case "no-store":
+			return 0, false
+		case "no-cache":
+			ttl = 0
+		case "max-age":
+			seconds, err := strconv.Atoi(strings.Trim(value, `"`))
+			if err == nil && seconds >= 0 && ttl > 0 {
+				ttl = time.Duration(seconds) * time.Second
+			}
+		}
+	}
+	return ttl, true
+}
+
+func (r *CachedResponse) toResult(ctx *gin.Context) *Result {
+	return &Result{
+		Ctx:      ctx,
+		HttpCode: r.HttpCode,
+		Response: slices.Clone(r.Body),
+		Header:   r.Header.Clone(),
+	}
+}
+
+type redisCacheStorage struct {
+	client *redis.Redis
+}
+
+// NewRedisCacheStorage 使用 redis 存储响应缓存，多实例共享
+func NewRedisCacheStorage(client *redis.Redis) CacheStorage {
+	return &redisCacheStorage{client: client}
+}
+
+func (r *redisCacheStorage) Get(ctx context.Context, key string) (*CachedResponse, bool, error) {
+	data, err := r.client.Get(ctx, key).Bytes()
+	if errors.Is(err, goredis.Nil) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	resp := new(CachedResponse)
+	if err = json.Unmarshal(data, resp); err != nil {
+		return nil, false, err
+	}
+	return resp, true, nil
+}
+
+func (r *redisCacheStorage) Set(ctx context.Context, key string, resp *CachedResponse, ttl time.Duration) error {
+	data, err := json.Marshal(resp)
+	if err != nil {
+		return err
+	}
+	return r.client.Set(ctx, key, data, ttl).Err()
+}
+
+type lruEntry struct {
+	key      string
+	resp     *CachedResponse
+	expireAt time.Time
+}
+
+// lruCacheStorage 进程内 LRU，超过容量时淘汰最久未访问的条目，过期条目在读取时删除
+type lruCacheStorage struct {
+	mu         sync.Mutex
+	maxEntries int
+	ll         *list.List
+	items      map[string]*list.Element
+}
+
+// NewLRUCacheStorage 进程内 LRU 存储，最多 maxEntries 条
+func NewLRUCacheStorage(maxEntries int) CacheStorage {
+	return newLRUCacheStorage(maxEntries)
+}
+
+func newLRUCacheStorage(maxEntries int) *lruCacheStorage {
+	if maxEntries <= 0 {
+		maxEntries = 1000
+	}
+	return &lruCacheStorage{
+		maxEntries: maxEntries,
+		ll:         list.New(),
+		items:      make(map[string]*list.Element),
+	}
+}
+
+func (l *lruCacheStorage) Get(_ context.Context, key string) (*CachedResponse, bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	elem, ok := l.items[key]
+	if !ok {
+		return nil, false, nil
+	}
+	entry := elem.Value.(*lruEntry)
+	if time.Now().After(entry.expireAt) {
+		l.ll.Remove(elem)
+		delete(l.items, key)
+		return nil, false, nil
+	}
+	l.ll.MoveToFront(elem)
+	return entry.resp, true, nil
+}
+
+func (l *lruCacheStorage) Set(_ context.Context, key string, resp *CachedResponse, ttl time.Duration) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	expireAt := time.Now().Add(ttl)
+	if elem, ok := l.items[key]; ok {
+		entry := elem.Value.(*lruEntry)
+		entry.resp, entry.expireAt = resp, expireAt
+		l.ll.MoveToFront(elem)
+		return nil
+	}
+	l.items[key] = l.ll.PushFront(&lruEntry{key: key, resp: resp, expireAt: expireAt})
+	for l.ll.Len() > l.maxEntries {
+		oldest := l.ll.Back()
+		l.ll.Remove(oldest)
+		delete(l.items, oldest.Value.(*lruEntry).key)
+	}
+	return nil
+}
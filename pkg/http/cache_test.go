@@ -0,0 +1,248 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newCacheServer(t *testing.T) (*httptest.Server, *atomic.Int32) {
+	hits := new(atomic.Int32)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := hits.Add(1)
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("X-Version", fmt.Sprint(n))
+		_, _ = fmt.Fprintf(w, "%s?%s #%d", r.URL.Path, r.URL.RawQuery, n)
+	}))
+	t.Cleanup(server.Close)
+	return server, hits
+}
+
+func TestClient_ResponseCache(t *testing.T) {
+	server, hits := newCacheServer(t)
+	client := &ClientConf{
+		Service:    "cache",
+		Domain:     server.URL,
+		RetryTimes: -1,
+		Cache:      &CacheConf{TTL: time.Minute},
+	}
+	ctx := newMetricsTestContext()
+
+	res, err := client.Get(ctx, RequestOptions{Path: "/item", QueryParams: map[string]string{"id": "1"}})
+	require.NoError(t, err)
+	assert.Equal(t, "/item?id=1 #1", string(res.Response))
+
+	// 命中缓存，状态码、响应头和响应体一致
+	res, err = client.Get(ctx, RequestOptions{Path: "/item", QueryParams: map[string]string{"id": "1"}})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, res.HttpCode)
+	assert.Equal(t, "/item?id=1 #1", string(res.Response))
+	assert.Equal(t, "1", res.Header.Get("X-Version"))
+	assert.EqualValues(t, 1, hits.Load())
+
+	// 修改返回的响应体不影响缓存
+	res.Response[0] = 'x'
+	res, _ = client.Get(ctx, RequestOptions{Path: "/item", QueryParams: map[string]string{"id": "1"}})
+	assert.Equal(t, "/item?id=1 #1", string(res.Response))
+
+	// 查询参数或请求头不同时不命中
+	res, _ = client.Get(ctx, RequestOptions{Path: "/item", QueryParams: map[string]string{"id": "2"}})
+	assert.Equal(t, "/item?id=2 #2", string(res.Response))
+	_, _ = client.Get(ctx, RequestOptions{Path: "/item", QueryParams: map[string]string{"id": "1"}, Headers: map[string]string{"Authorization": "u2"}})
+	assert.EqualValues(t, 3, hits.Load())
+
+	// NoCache 跳过缓存
+	res, _ = client.Get(ctx, RequestOptions{Path: "/item", QueryParams: map[string]string{"id": "1"}, NoCache: true})
+	assert.Equal(t, "/item?id=1 #4", string(res.Response))
+
+	// 非 2xx 与非幂等请求不缓存
+	for range 2 {
+		res, _ = client.Get(ctx, RequestOptions{Path: "/missing"})
+		assert.Equal(t, http.StatusNotFound, res.HttpCode)
+		_, _ = client.Post(ctx, RequestOptions{Path: "/item"})
+	}
+	assert.EqualValues(t, 8, hits.Load())
+}
+
+func TestClient_ResponseCacheKeyFuncAndTTL(t *testing.T) {
+	server, hits := newCacheServer(t)
+	client := &ClientConf{
+		Service:    "cache",
+		Domain:     server.URL,
+		RetryTimes: -1,
+		Cache: &CacheConf{
+			TTL: 50 * time.Millisecond,
+			// 忽略查询参数，/nocache 不缓存
+			KeyFunc: func(opts RequestOptions) string {
+				if opts.Path == "/nocache" {
+					return ""
+				}
+				return opts.Path
+			},
+		},
+	}
+	ctx := newMetricsTestContext()
+
+	_, _ = client.Get(ctx, RequestOptions{Path: "/item", QueryParams: map[string]string{"id": "1"}})
+	res, _ := client.Get(ctx, RequestOptions{Path: "/item", QueryParams: map[string]string{"id": "2"}})
+	assert.Equal(t, "/item?id=1 #1", string(res.Response))
+
+	// HEAD 与 GET 分开缓存
+	_, _ = client.Head(ctx, RequestOptions{Path: "/item"})
+	assert.EqualValues(t, 2, hits.Load())
+
+	_, _ = client.Get(ctx, RequestOptions{Path: "/nocache"})
+	_, _ = client.Get(ctx, RequestOptions{Path: "/nocache"})
+	assert.EqualValues(t, 4, hits.Load())
+
+	// 过期后重新请求
+	time.Sleep(80 * time.Millisecond)
+	res, _ = client.Get(ctx, RequestOptions{Path: "/item"})
+	assert.Equal(t, "/item? #5", string(res.Response))
+}
+
+func TestLRUCacheStorage_Evict(t *testing.T) {
+	ctx := context.Background()
+	cache := newLRUCacheStorage(2)
+	for _, key := range []string{"a", "b"} {
+		require.NoError(t, cache.Set(ctx, key, &CachedResponse{HttpCode: 200, Body: []byte(key)}, time.Minute))
+	}
+	// 访问 a 后 b 成为最久未访问的条目
+	_, ok, _ := cache.Get(ctx, "a")
+	assert.True(t, ok)
+	require.NoError(t, cache.Set(ctx, "c", &CachedResponse{HttpCode: 200}, time.Minute))
+
+	_, ok, _ = cache.Get(ctx, "b")
+	assert.False(t, ok)
+	for _, key := range []string{"a", "c"} {
+		_, ok, _ = cache.Get(ctx, key)
+		assert.True(t, ok, key)
+	}
+}
+
+func newETagServer(t *testing.T, cacheControl string) (*httptest.Server, *atomic.Int32, *atomic.Int32) {
+	hits, notModified := new(atomic.Int32), new(atomic.Int32)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := hits.Add(1)
+		if cacheControl != "" {
+			w.Header().Set("Cache-Control", cacheControl)
+		}
+		w.Header().Set("ETag", `"v1"`)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			notModified.Add(1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		_, _ = fmt.Fprintf(w, "body #%d", n)
+	}))
+	t.Cleanup(server.Close)
+	return server, hits, notModified
+}
+
+func TestClient_ResponseCacheETagRevalidation(t *testing.T) {
+	server, hits, notModified := newETagServer(t, "")
+	client := &ClientConf{
+		Service:    "cache_etag",
+		Domain:     server.URL,
+		RetryTimes: -1,
+		Cache:      &CacheConf{TTL: 50 * time.Millisecond},
+	}
+	ctx := newMetricsTestContext()
+	counter := func(result string) float64 {
+		return testutil.ToFloat64(clientCacheRequests.WithLabelValues("cache_etag", result))
+	}
+
+	res, err := client.Get(ctx, RequestOptions{Path: "/item"})
+	require.NoError(t, err)
+	assert.Equal(t, "body #1", string(res.Response))
+	res, _ = client.Get(ctx, RequestOptions{Path: "/item"})
+	assert.Equal(t, "body #1", string(res.Response))
+	assert.EqualValues(t, 1, hits.Load())
+
+	// 过期后带 If-None-Match 校验，304 时返回缓存的响应
+	time.Sleep(80 * time.Millisecond)
+	res, err = client.Get(ctx, RequestOptions{Path: "/item"})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, res.HttpCode)
+	assert.Equal(t, "body #1", string(res.Response))
+	assert.EqualValues(t, 2, hits.Load())
+	assert.EqualValues(t, 1, notModified.Load())
+
+	// 校验后刷新过期时间
+	res, _ = client.Get(ctx, RequestOptions{Path: "/item"})
+	assert.Equal(t, "body #1", string(res.Response))
+	assert.EqualValues(t, 2, hits.Load())
+
+	assert.Equal(t, 2.0, counter(cacheResultHit))
+	assert.Equal(t, 1.0, counter(cacheResultMiss))
+	assert.Equal(t, 1.0, counter(cacheResultRevalidated))
+}
+
+func TestClient_ResponseCacheControl(t *testing.T) {
+	ctx := newMetricsTestContext()
+
+	// no-store 不缓存
+	server, hits, _ := newETagServer(t, "no-store")
+	client := &ClientConf{Service: "cache", Domain: server.URL, RetryTimes: -1, Cache: &CacheConf{TTL: time.Minute}}
+	_, _ = client.Get(ctx, RequestOptions{Path: "/item"})
+	res, _ := client.Get(ctx, RequestOptions{Path: "/item"})
+	assert.Equal(t, "body #2", string(res.Response))
+	assert.EqualValues(t, 2, hits.Load())
+
+	// no-cache 每次都校验 ETag
+	server, hits, notModified := newETagServer(t, "no-cache")
+	client = &ClientConf{Service: "cache", Domain: server.URL, RetryTimes: -1, Cache: &CacheConf{TTL: time.Minute}}
+	_, _ = client.Get(ctx, RequestOptions{Path: "/item"})
+	res, _ = client.Get(ctx, RequestOptions{Path: "/item"})
+	assert.Equal(t, "body #1", string(res.Response))
+	assert.EqualValues(t, 2, hits.Load())
+	assert.EqualValues(t, 1, notModified.Load())
+
+	// max-age 优先于 TTL
+	server, hits, _ = newETagServer(t, "public, max-age=3600")
+	storage := NewLRUCacheStorage(10)
+	client = &ClientConf{Service: "cache", Domain: server.URL, RetryTimes: -1, Cache: &CacheConf{TTL: time.Millisecond, Storage: storage}}
+	_, _ = client.Get(ctx, RequestOptions{Path: "/item"})
+	time.Sleep(5 * time.Millisecond)
+	res, _ = client.Get(ctx, RequestOptions{Path: "/item"})
+	assert.Equal(t, "body #1", string(res.Response))
+	assert.EqualValues(t, 1, hits.Load())
+	key, _ := client.cacheKey(http.MethodGet, RequestOptions{Path: "/item"})
+	cached, ok, _ := storage.Get(ctx, key)
+	require.True(t, ok)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), cached.ExpireAt, time.Minute)
+}
+
+func TestFreshnessTTL(t *testing.T) {
+	cases := []struct {
+		cacheControl string
+		ttl          time.Duration
+		ok           bool
+	}{
+		{"", time.Minute, true},
+		{"max-age=30", 30 * time.Second, true},
+		{`private, max-age="10"`, 10 * time.Second, true},
+		{"max-age=abc", time.Minute, true},
+		{"no-cache", 0, true},
+		{"no-cache, max-age=30", 0, true},
+		{"No-Store", 0, false},
+	}
+	for _, tc := range cases {
+		header := http.Header{}
+		header.Set("Cache-Control", tc.cacheControl)
+		ttl, ok := freshnessTTL(header, time.Minute)
+		assert.Equal(t, tc.ttl, ttl, tc.cacheControl)
+		assert.Equal(t, tc.ok, ok, tc.cacheControl)
+	}
+}
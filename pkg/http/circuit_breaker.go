@@ -0,0 +1,192 @@
+// Package http -----------------------------
+// @file      : circuit_breaker.go
+// @author    : xiangtao
+// @contact   : xiangtao1994@gmail.com
+// @time      : 2025/9/13 17:20
+// Description: 按下游服务熔断，下游故障时快速失败
+// -------------------------------------------
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ErrCircuitOpen 熔断打开时请求直接返回该错误，可用 errors.Is 判断
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// CircuitBreaker 熔断配置，连续失败 FailureThreshold 次后打开，OpenDuration 后进入半开放行探测请求
+// 失败指网络错误、超时或 5xx 响应（重试后的最终结果），4xx 说明下游存活，不计为失败
+type CircuitBreaker struct {
+	FailureThreshold int           `yaml:"failureThreshold"` // 连续失败次数阈值，默认5
+	OpenDuration     time.Duration `yaml:"openDuration"`     // 打开状态持续时间，默认10s
+	HalfOpenMaxCalls int           `yaml:"halfOpenMaxCalls"` // 半开状态允许同时进行的探测请求数，默认1
+}
+
+// CircuitState 熔断状态，数值即 http_client_circuit_state 指标的取值
+type CircuitState int
+
+const (
+	CircuitClosed CircuitState = iota
+	CircuitHalfOpen
+	CircuitOpen
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitHalfOpen:
+		return "half_open"
+	case CircuitOpen:
+		return "open"
+	default:
+		return "unknown"
+	}
+}
+
+type circuitBreaker struct {
+	conf    CircuitBreaker
+	service string
+
+	mu       sync.Mutex
+	state    CircuitState
+	failures int
+	openedAt time.Time
+	probes   int
+	// generation 每次状态切换加一，丢弃切换前发出的请求的结果
+	generation uint64
+}
+
+func newCircuitBreaker(service string, conf CircuitBreaker) *circuitBreaker {
+	if conf.FailureThreshold <= 0 {
+		conf.FailureThreshold = 5
+	}
+	if conf.OpenDuration <= 0 {
+		conf.OpenDuration = 10 * time.Second
+	}
+	if conf.HalfOpenMaxCalls <= 0 {
+		conf.HalfOpenMaxCalls = 1
+	}
+	cb := &circuitBreaker{conf: conf, service: service}
+	clientCircuitState.WithLabelValues(service).Set(float64(CircuitClosed))
+	return cb
+}
+
+// allow 判断是否放行请求，返回当前代数，请求结束后传给 done/release
+func (cb *circuitBreaker) allow() (uint64, bool) {
+	if cb == nil {
+		return 0, true
+	}
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	if cb.state == CircuitOpen {
+		if time.Since(cb.openedAt) < cb.conf.OpenDuration {
+			return cb.generation, false
+		}
+		cb.setState(CircuitHalfOpen)
+	}
+	if cb.state == CircuitHalfOpen {
+		if cb.probes >= cb.conf.HalfOpenMaxCalls {
+			return cb.generation, false
+		}
+		cb.probes++
+	}
+	return cb.generation, true
+}
+
+// done 记录请求结果，status 为0表示未拿到响应
+func (cb *circuitBreaker) done(generation uint64, status int, err error) {
+	if cb == nil {
+		return
+	}
+	if status == 0 && (err == nil || errors.Is(err, context.Canceled)) {
+		// 调用方主动取消，不代表下游状态
+		cb.release(generation)
+		return
+	}
+	failed := status == 0 || status >= http.StatusInternalServerError
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	if generation != cb.generation {
+		return
+	}
+	switch cb.state {
+	case CircuitClosed:
+		if !failed {
+			cb.failures = 0
+			return
+		}
+		cb.failures++
+		if cb.failures >= cb.conf.FailureThreshold {
+			cb.setState(CircuitOpen)
+		}
+	case CircuitHalfOpen:
+		if failed {
+			cb.setState(CircuitOpen)
+		} else {
+			cb.setState(CircuitClosed)
+		}
+	}
+}
+
+// release 请求未发出，归还半开状态的探测名额
+func (cb *circuitBreaker) release(generation uint64) {
+	if cb == nil {
+		return
+	}
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	if generation == cb.generation && cb.state == CircuitHalfOpen && cb.probes > 0 {
+		cb.probes--
+	}
+}
+
+// setState 需持有锁
+func (cb *circuitBreaker) setState(state CircuitState) {
+	cb.state = state
+	cb.failures = 0
+	cb.probes = 0
+	cb.generation++
+	if state == CircuitOpen {
+		cb.openedAt = time.Now()
+	}
+	clientCircuitState.WithLabelValues(cb.service).Set(float64(state))
+}
+
+func (cb *circuitBreaker) currentState() CircuitState {
+	if cb == nil {
+		return CircuitClosed
+	}
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	if cb.state == CircuitOpen && time.Since(cb.openedAt) >= cb.conf.OpenDuration {
+		return CircuitHalfOpen
+	}
+	return cb.state
+}
+
+// State 返回熔断状态，未配置 CircuitBreaker 时始终为 CircuitClosed，可用于健康检查接口
+func (c *ClientConf) State() CircuitState {
+	return c.breaker.currentState()
+}
+
+// acquireCircuit 熔断打开时快速失败，并以 circuit_open 记录调用日志
+func (c *ClientConf) acquireCircuit(ctx *gin.Context, method string, opts RequestOptions) (uint64, error) {
+	if err := c.initClient(); err != nil {
+		return 0, err
+	}
+	generation, ok := c.breaker.allow()
+	if ok {
+		return generation, nil
+	}
+	err := fmt.Errorf("%w: %s", ErrCircuitOpen, c.Service)
+	c.logHttpInvoke(ctx, method, opts.Path, 0, nil, err, time.Now(), opts)
+	return 0, err
+}
@@ -0,0 +1,94 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	var hits atomic.Int32
+	var healthy atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		if healthy.Load() {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+	client := &ClientConf{
+		Service:    "breaker",
+		Domain:     server.URL,
+		Timeout:    time.Second,
+		RetryTimes: -1,
+		CircuitBreaker: &CircuitBreaker{
+			FailureThreshold: 3,
+			OpenDuration:     100 * time.Millisecond,
+		},
+	}
+	ctx := newMetricsTestContext()
+
+	// 连续失败达到阈值后打开
+	for i := 0; i < 3; i++ {
+		res, err := client.Get(ctx, RequestOptions{Path: "/"})
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusServiceUnavailable, res.HttpCode)
+	}
+	assert.Equal(t, CircuitOpen, client.State())
+	assert.Equal(t, float64(CircuitOpen), testutil.ToFloat64(clientCircuitState.WithLabelValues("breaker")))
+
+	// 打开期间直接失败，不访问下游
+	start := time.Now()
+	_, err := client.Get(ctx, RequestOptions{Path: "/"})
+	assert.True(t, errors.Is(err, ErrCircuitOpen))
+	_, err = client.GetStream(ctx, RequestOptions{Path: "/"}, func(data []byte) error { return nil })
+	assert.True(t, errors.Is(err, ErrCircuitOpen))
+	assert.Less(t, time.Since(start), 50*time.Millisecond)
+	assert.Equal(t, int32(3), hits.Load())
+
+	// OpenDuration 后探测成功则关闭
+	time.Sleep(150 * time.Millisecond)
+	assert.Equal(t, CircuitHalfOpen, client.State())
+	healthy.Store(true)
+	res, err := client.Get(ctx, RequestOptions{Path: "/"})
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, res.HttpCode)
+	assert.Equal(t, CircuitClosed, client.State())
+	assert.Equal(t, int32(4), hits.Load())
+}
+
+func TestCircuitBreakerHalfOpenFailure(t *testing.T) {
+	cb := newCircuitBreaker("breaker-half-open", CircuitBreaker{FailureThreshold: 1, OpenDuration: time.Millisecond, HalfOpenMaxCalls: 1})
+	gen, ok := cb.allow()
+	assert.True(t, ok)
+	cb.done(gen, 0, errors.New("connection refused"))
+	assert.Equal(t, CircuitOpen, cb.currentState())
+
+	time.Sleep(2 * time.Millisecond)
+	probe, ok := cb.allow()
+	assert.True(t, ok)
+	// 半开状态只放行 HalfOpenMaxCalls 个探测请求
+	_, ok = cb.allow()
+	assert.False(t, ok)
+	// 探测失败重新打开
+	cb.done(probe, http.StatusBadGateway, nil)
+	assert.Equal(t, CircuitOpen, cb.state)
+}
+
+func TestCircuitBreakerIgnoresClientErrors(t *testing.T) {
+	cb := newCircuitBreaker("breaker-4xx", CircuitBreaker{FailureThreshold: 2})
+	for i := 0; i < 3; i++ {
+		gen, ok := cb.allow()
+		assert.True(t, ok)
+		cb.done(gen, http.StatusNotFound, nil)
+	}
+	assert.Equal(t, CircuitClosed, cb.currentState())
+}
@@ -3,11 +3,16 @@ package http
 import (
 	"bufio"
 	"context"
+	"crypto/tls"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"maps"
+	"mime/multipart"
 	"net/http"
 	"net/url"
+	"os"
 	"slices"
 	"strings"
 	"sync"
@@ -38,28 +43,44 @@ type ClientConf struct {
 	ConnectTimeout   time.Duration            `yaml:"connectTimeout"`   // 连接超时时间
 	MaxReqBodyLen    int                      `yaml:"maxReqBodyLen"`    // request body 最大长度展示，0表示采用默认的10240，-1表示不打印
 	MaxRespBodyLen   int                      `yaml:"maxRespBodyLen"`   // response body 最大长度展示，0表示采用默认的10240，-1表示不打印。指定长度的时候需注意，返回的json可能被截断
-	HttpStat         bool                     `yaml:"httpStat"`         // http 分析，默认关闭
+	HttpStat         bool                     `yaml:"httpStat"`         // http 分析，默认关闭；开启后统计连接复用，见 PoolStats
+	MinReuseRatio    float64                  `yaml:"minReuseRatio"`    // 开启 HttpStat 时连接复用率低于该值打印 debug 日志，默认0.5
 	RetryTimes       int                      `yaml:"retryTimes"`       // 最大重试次数
 	RetryWaitTime    time.Duration            `yaml:"retryWaitTime"`    // 重试等待间隔
 	RetryMaxWaitTime time.Duration            `yaml:"retryMaxWaitTime"` // 最大重试等待
 	Proxy            string                   `yaml:"proxy"`
 	RetryPolicy      resty.RetryConditionFunc // 自定义重试条件
+	CircuitBreaker   *CircuitBreaker          `yaml:"circuitBreaker"`   // 熔断配置，为空不启用
+	FailOnHTTPError  bool                     `yaml:"failOnHTTPError"`  // >=400 的响应以 HTTPStatusError 返回
+	HedgingPolicy    *HedgingPolicy           `yaml:"hedgingPolicy"`    // 对冲请求配置，为空不启用
+	LoadBalancerType string                   `yaml:"loadBalancerType"` // 多域名负载均衡方式：roundRobin（默认）、weightedRoundRobin、leastConn
+	Weights          map[string]int           `yaml:"weights"`          // 域名权重，未配置的域名权重为1
+	Ejection         *EjectionPolicy          `yaml:"ejection"`         // 故障摘除配置，为空不启用
+	PropagateHeaders []string                 `yaml:"propagateHeaders"` // 从上游请求透传给下游的请求头，默认只透传 Request-Id
+	Cache            *CacheConf               `yaml:"cache"`            // GET/HEAD 响应缓存配置，为空不启用
+	TLS              *TLSConf                 `yaml:"tls"`              // 自定义 CA、双向 TLS 配置，为空使用默认配置
+
+	OnBeforeRequest []BeforeRequestHook `json:"-"` // 请求发出前依次调用，如签名、添加全局请求头，返回错误时不发出请求
+	OnAfterResponse []AfterResponseHook `json:"-"` // 收到响应后依次调用，返回错误时本次调用返回该错误
+	// 每次调用结束后（包括失败、熔断和命中缓存）以完整的请求体和响应体调用，不受 MaxReqBodyLen/MaxRespBodyLen 截断，用于审计
+	// 请求失败时 status 和 respBody 取自 HTTPStatusError，没有响应时 status 为0；流式请求没有 respBody
+	AuditHook func(ctx *gin.Context, method, url string, reqBody, respBody []byte, status int) `json:"-"`
 
 	Transport    http.RoundTripper  `json:"-"` // 可选的自定义 Transport
 	LoadBalancer resty.LoadBalancer `json:"-"`
 
 	HTTPClient *resty.Client `json:"-"`
 	once       sync.Once
+	breaker    *circuitBreaker
+	pool       *poolStats
+	initErr    error
 }
 
 func (c *ClientConf) selectBaseURL() (string, error) {
 	if len(c.Domains) == 0 {
 		return c.Domain, nil
 	}
-	if c.LoadBalancer != nil {
-		return c.LoadBalancer.Next()
-	}
-	return c.HTTPClient.LoadBalancer().Next()
+	return c.loadBalancer().Next()
 }
 
 // RequestOptions 是单个请求可选参数
@@ -68,10 +89,36 @@ type RequestOptions struct {
 	Encode       string              // EncodeJson EncodeForm EncodeRaw EncodeRawByte EncodeFile
 	RequestBody  any                 // body 数据
 	RequestFiles map[string][]string // EncodeFile 模式下的表单数据 key是表单字段名，value是多个本地文件路径
-	QueryParams  map[string]string   // 查询参数
-	Headers      map[string]string   // 自定义请求头
-	Cookies      map[string]string   // 自定义 Cookie (键值对)
-	Timeout      time.Duration       // 单次请求超时时间（若为零则使用客户端配置）
+	// EncodeFile 模式下通过 io.Reader 上传的文件，key是表单字段名，可与 RequestFiles 同时使用
+	RequestFileReaders map[string][]FileReader
+	QueryParams        map[string]string // 查询参数
+	Headers            map[string]string // 自定义请求头
+	Cookies            map[string]string // 自定义 Cookie (键值对)
+	Timeout            time.Duration     // 单次请求超时时间（若为零则使用客户端配置）
+	NoCache            bool              // 跳过响应缓存，既不读取也不写入
+}
+
+// FileReader 通过 io.Reader 上传的文件，请求体以流的方式发送，不会整体读入内存（配置了 OnBeforeRequest 时除外）
+// Reader 实现 io.Closer 时请求结束后关闭；实现 io.Seeker 时重试前回到开头，否则重试会发送不完整的内容
+type FileReader struct {
+	FileName    string    // 文件名
+	ContentType string    // 该部分的 Content-Type，为空时按前512字节探测
+	Reader      io.Reader // 文件内容
+	Size        int64     // 文件大小，可选，仅用于日志
+}
+
+// FileReaderFromHeader 打开 gin 收到的上传文件（ctx.FormFile），用于直接转发给下游，不落盘
+func FileReaderFromHeader(fh *multipart.FileHeader) (FileReader, error) {
+	f, err := fh.Open()
+	if err != nil {
+		return FileReader{}, fmt.Errorf("failed to open upload file %s: %w", fh.Filename, err)
+	}
+	return FileReader{
+		FileName:    fh.Filename,
+		ContentType: fh.Header.Get("Content-Type"),
+		Reader:      f,
+		Size:        fh.Size,
+	}, nil
 }
 
 type Result struct {
@@ -115,6 +162,9 @@ func (c *ClientConf) initClient() error {
 		if c.MaxRespBodyLen == 0 {
 			c.MaxRespBodyLen = 10240
 		}
+		if c.PropagateHeaders == nil {
+			c.PropagateHeaders = []string{"Request-Id"}
+		}
 
 		client := resty.New()
 		client.SetTimeout(c.Timeout)
@@ -136,21 +186,40 @@ func (c *ClientConf) initClient() error {
 		if c.Proxy != "" {
 			client.SetProxy(c.Proxy)
 		}
-		if len(c.Domains) > 0 {
-			var rr *resty.RoundRobin
-			rr, err = resty.NewRoundRobin(c.Domains...)
+		if c.TLS != nil {
+			var base, tlsConfig *tls.Config
+			if base, err = transportTLSConfig(c.Transport); err != nil {
+				return
+			}
+			if tlsConfig, err = c.TLS.tlsConfig(base); err != nil {
+				return
+			}
+			client.SetTLSClientConfig(tlsConfig)
+		}
+		// 包装后不再是 *http.Transport，需在 SetProxy 之后
+		if c.HttpStat {
+			c.pool = newPoolStats(c.Service, c.MinReuseRatio)
+			client.SetTransport(c.pool.wrap(c.Transport))
+		}
+		if len(c.Domains) > 0 && c.LoadBalancer == nil {
+			var lb resty.LoadBalancer
+			lb, err = c.newLoadBalancer()
 			if err != nil {
 				return
 			}
-			client.SetLoadBalancer(rr)
+			client.SetLoadBalancer(lb)
 		}
 		client.SetLogger(GetHttpLogger().Sugar())
 		c.HTTPClient = client
+		if c.CircuitBreaker != nil {
+			c.breaker = newCircuitBreaker(c.Service, *c.CircuitBreaker)
+		}
 	})
 	if err != nil {
-		return fmt.Errorf("http client init error: %v", err)
+		c.initErr = fmt.Errorf("http client init error: %v", err)
 	}
-	return nil
+	// 初始化失败后每次调用都返回同一个错误
+	return c.initErr
 }
 
 func GetHttpLogger() *zap.Logger {
@@ -197,8 +266,41 @@ func (c *ClientConf) Delete(ctx *gin.Context, opts RequestOptions) (*Result, err
 	return c.do(ctx, http.MethodDelete, opts)
 }
 
-// do 执行通用请求方法
-func (c *ClientConf) do(ctx *gin.Context, method string, opts RequestOptions) (res *Result, err error) {
+// do 执行通用请求方法，配置了 Cache 时先查缓存
+func (c *ClientConf) do(ctx *gin.Context, method string, opts RequestOptions) (*Result, error) {
+	if key, ok := c.cacheKey(method, opts); ok {
+		return c.doCached(ctx, key, method, opts)
+	}
+	return c.doRequest(ctx, method, opts)
+}
+
+// doRequest 向下游发出请求
+func (c *ClientConf) doRequest(ctx *gin.Context, method string, opts RequestOptions) (res *Result, err error) {
+	var timeoutCtx context.Context
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		timeoutCtx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	} else {
+		timeoutCtx = ctx
+	}
+	// 熔断打开时不再构造请求
+	generation, err := c.acquireCircuit(ctx, method, opts)
+	if err != nil {
+		return nil, err
+	}
+	if c.shouldHedge(method, opts) {
+		return c.doHedged(ctx, timeoutCtx, generation, method, opts)
+	}
+	_, res, err = c.send(ctx, timeoutCtx, generation, method, opts, false)
+	return res, err
+}
+
+// DoRaw 发出请求并返回原始的 *resty.Response，用于读取 Result 之外的信息，
+// 如 resp.Request.TraceInfo() 中的 DNS、建连耗时，resp.ReceivedAt()，resp.RawResponse.TLS
+// 请求开启 trace，经过熔断、指标、日志和 AfterResponse，不使用响应缓存和对冲请求
+// 开启 FailOnHTTPError 时 >=400 的响应同时返回 resp 和 HTTPStatusError
+func (c *ClientConf) DoRaw(ctx *gin.Context, method string, opts RequestOptions) (*resty.Response, error) {
 	var timeoutCtx context.Context
 	if opts.Timeout > 0 {
 		var cancel context.CancelFunc
@@ -207,54 +309,91 @@ func (c *ClientConf) do(ctx *gin.Context, method string, opts RequestOptions) (r
 	} else {
 		timeoutCtx = ctx
 	}
-	// 记录开始时间
-	req, err := c.buildRequest(ctx, method, opts)
+	generation, err := c.acquireCircuit(ctx, method, opts)
 	if err != nil {
 		return nil, err
 	}
-	req.WithContext(timeoutCtx)
+	resp, _, err := c.send(ctx, timeoutCtx, generation, method, opts, true)
+	return resp, err
+}
+
+// send 构造并发送一次请求，trace 为 true 时开启 resty 的 trace
+func (c *ClientConf) send(ctx *gin.Context, timeoutCtx context.Context, generation uint64, method string, opts RequestOptions, trace bool) (resp *resty.Response, res *Result, err error) {
+	req, baseURL, err := c.buildRequest(ctx, method, opts)
+	if err != nil {
+		c.breaker.release(generation)
+		return nil, nil, err
+	}
+	req.SetContext(timeoutCtx)
+	if trace {
+		req.EnableTrace()
+	}
 
 	start := time.Now()
+	var status int
 	defer func() { // 不能省略这个闭包函数， 否则req和err传入不进去
-		c.logHttpInvoke(ctx, req, res, err, start, opts)
+		c.breaker.done(generation, status, err)
+		c.recordMetrics(method, status, req.Attempt, start)
+		c.logHttpInvoke(ctx, method, req.URL, req.Attempt, res, err, start, opts)
 	}()
 	// 执行请求
-	resp, err := req.Send()
+	resp, err = req.Send()
+	if resp != nil {
+		status = resp.StatusCode()
+	}
+	c.feedback(baseURL, status, err)
 	if err != nil {
-		return nil, err
+		err = classifyError(err)
+		return nil, nil, err
 	}
-	res = &Result{
-		Ctx: ctx,
+	if res, err = c.toResult(ctx, resp); err != nil {
+		return resp, nil, err
 	}
-	if resp != nil {
-		res.HttpCode = resp.StatusCode()
-		res.Response = resp.Bytes()
-		res.Header = resp.Header()
+	if err = c.runAfterResponse(ctx, req, res); err != nil {
+		return resp, nil, err
+	}
+	return resp, res, nil
+}
+
+// toResult 转换响应，开启 FailOnHTTPError 时 >=400 的响应返回 HTTPStatusError
+func (c *ClientConf) toResult(ctx *gin.Context, resp *resty.Response) (*Result, error) {
+	if c.FailOnHTTPError && resp.StatusCode() >= http.StatusBadRequest {
+		return nil, &HTTPStatusError{Code: resp.StatusCode(), Body: resp.Bytes(), Header: resp.Header()}
 	}
-	return res, nil
+	return &Result{
+		Ctx:      ctx,
+		HttpCode: resp.StatusCode(),
+		Response: resp.Bytes(),
+		Header:   resp.Header(),
+	}, nil
 }
 
-func (c *ClientConf) logHttpInvoke(ctx *gin.Context, req *resty.Request, res *Result, err error, start time.Time, opts RequestOptions) {
+func (c *ClientConf) logHttpInvoke(ctx *gin.Context, method, requestUrl string, attempts int, res *Result, err error, start time.Time, opts RequestOptions, extra ...zap.Field) {
 	msg := "http invoke"
-	if err != nil {
+	if errors.Is(err, ErrCircuitOpen) {
+		msg = "circuit_open"
+	} else if err != nil {
 		msg = err.Error()
 	}
 	var status int
-	var respBodyStr string
+	var respBody []byte
 	if res != nil {
 		status = res.HttpCode
-		respBodyStr = string(res.Response)
+		respBody = res.Response
 	}
+	reqBodyStr := c.getReqBodyStr(opts)
+	c.audit(ctx, method, requestUrl, reqBodyStr, respBody, status, err)
 	fields := []zap.Field{
 		zlog.String("service", c.Service),
-		zlog.String("method", req.Method),
-		zlog.String("requestUrl", req.URL),
-		zlog.Int("attempts", req.Attempt),
+		zlog.String("method", method),
+		zlog.String("requestUrl", requestUrl),
+		zlog.Int("attempts", attempts),
 		zlog.Int("status", status),
-		zlog.String("request", truncateString(c.getReqBodyStr(opts), c.MaxReqBodyLen)),
-		zlog.String("response", truncateString(respBodyStr, c.MaxRespBodyLen)),
+		zlog.String("request", truncateString(reqBodyStr, c.MaxReqBodyLen)),
+		zlog.String("response", truncateString(string(respBody), c.MaxRespBodyLen)),
 		zlog.String("cost", fmt.Sprintf("%v%s", zlog.GetRequestCost(start, time.Now()), "ms")),
 	}
+	fields = append(fields, extra...)
 	logger := zlog.LoggerWithContext(GetHttpLogger(), ctx)
 	if err != nil {
 		logger.Error(msg, fields...)
@@ -263,6 +402,18 @@ func (c *ClientConf) logHttpInvoke(ctx *gin.Context, req *resty.Request, res *Re
 	}
 }
 
+// audit 调用 AuditHook，失败时从 HTTPStatusError 中取状态码和响应体
+func (c *ClientConf) audit(ctx *gin.Context, method, requestUrl, reqBody string, respBody []byte, status int, err error) {
+	if c.AuditHook == nil {
+		return
+	}
+	var statusErr *HTTPStatusError
+	if respBody == nil && errors.As(err, &statusErr) {
+		status, respBody = statusErr.Code, statusErr.Body
+	}
+	c.AuditHook(ctx, method, requestUrl, []byte(reqBody), respBody, status)
+}
+
 func (c *ClientConf) doStream(ctx *gin.Context, method string, opts RequestOptions, f func(data []byte) error) (res *Result, err error) {
 	var timeoutCtx context.Context
 	if opts.Timeout > 0 {
@@ -272,23 +423,42 @@ func (c *ClientConf) doStream(ctx *gin.Context, method string, opts RequestOptio
 	} else {
 		timeoutCtx = ctx
 	}
-	req, err := c.buildRequest(ctx, method, opts)
+	// 熔断打开时不再构造请求
+	generation, err := c.acquireCircuit(ctx, method, opts)
+	if err != nil {
+		return nil, err
+	}
+	// 流式响应边读边回调，无法在多个请求间择优，不对冲
+	req, baseURL, err := c.buildRequest(ctx, method, opts)
 	if err != nil {
+		c.breaker.release(generation)
 		return nil, err
 	}
-	req.WithContext(timeoutCtx)
+	req.SetContext(timeoutCtx)
 	start := time.Now()
+	var status int
+	var sendErr error
 	defer func() { // 不能省略这个闭包函数， 否则req和err传入不进去
-		c.logHttpInvoke(ctx, req, res, err, start, opts)
+		c.feedback(baseURL, status, sendErr) // 流读完后再回调，业务回调的错误不计为域名失败
+		c.breaker.done(generation, status, err)
+		c.recordMetrics(method, status, req.Attempt, start)
+		c.logHttpInvoke(ctx, method, req.URL, req.Attempt, res, err, start, opts)
 	}()
 	// 通过自定义执行方式以获取 response.RawBody()
 	resp, err := req.SetDoNotParseResponse(true).Send()
+	if resp != nil {
+		status = resp.StatusCode()
+	}
+	sendErr = err
 	if err != nil {
+		err = classifyError(err)
 		return nil, err
 	}
 	if resp.IsError() {
+		body, _ := io.ReadAll(resp.Body)
 		_ = resp.Body.Close()
-		return nil, fmt.Errorf("http response code %v, error: %s", resp.StatusCode(), resp.String())
+		err = &HTTPStatusError{Code: status, Body: body, Header: resp.Header()}
+		return nil, err
 	}
 	scanner := bufio.NewScanner(resp.Body)
 	scanner.Buffer(make([]byte, slices.Min([]int{4096, defaultSseMaxBufSize})), defaultSseMaxBufSize)
@@ -301,6 +471,7 @@ func (c *ClientConf) doStream(ctx *gin.Context, method string, opts RequestOptio
 		}
 	}
 	if err = scanner.Err(); err != nil {
+		err = classifyError(err)
 		return nil, err
 	}
 	_ = resp.Body.Close()
@@ -308,6 +479,9 @@ func (c *ClientConf) doStream(ctx *gin.Context, method string, opts RequestOptio
 		Ctx:      ctx,
 		HttpCode: resp.StatusCode(),
 	}
+	if err = c.runAfterResponse(ctx, req, res); err != nil {
+		return nil, err
+	}
 	return
 }
 func (c *ClientConf) doRequestSetBody(req *resty.Request, opts RequestOptions) error {
@@ -343,23 +517,45 @@ func (c *ClientConf) doRequestSetBody(req *resty.Request, opts RequestOptions) e
 				req.SetFile(field, path)
 			}
 		}
+		for field, readers := range opts.RequestFileReaders {
+			for _, fr := range readers {
+				req.SetMultipartFields(&resty.MultipartField{
+					Name:        field,
+					FileName:    fr.FileName,
+					ContentType: fr.ContentType,
+					Reader:      fr.Reader,
+					FileSize:    fr.Size,
+				})
+			}
+		}
 	default:
 		req.SetBody(opts.RequestBody)
 	}
 	return nil
 }
 
-func (c *ClientConf) buildRequest(ctx *gin.Context, method string, opts RequestOptions) (*resty.Request, error) {
+// buildRequest 返回请求和选中的域名，请求结束后需通过 feedback 回调负载均衡
+func (c *ClientConf) buildRequest(ctx *gin.Context, method string, opts RequestOptions) (*resty.Request, string, error) {
 	err := c.initClient()
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 	// 构造完整 URL
-	urlStr, err := c.selectBaseURL()
+	baseURL, err := c.selectBaseURL()
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
-	urlStr = strings.TrimRight(urlStr, "/") + opts.Path
+	req, err := c.newRequest(ctx, method, baseURL, opts)
+	if err != nil {
+		c.releaseBaseURL(baseURL)
+		return nil, "", err
+	}
+	return req, baseURL, nil
+}
+
+// newRequest 基于指定的域名构造请求
+func (c *ClientConf) newRequest(ctx *gin.Context, method, baseURL string, opts RequestOptions) (*resty.Request, error) {
+	urlStr := strings.TrimRight(baseURL, "/") + opts.Path
 	req := c.HTTPClient.R() // 设置请求上下文
 	req.URL = urlStr
 	req.Method = method
@@ -367,23 +563,48 @@ func (c *ClientConf) buildRequest(ctx *gin.Context, method string, opts RequestO
 	if len(opts.QueryParams) > 0 {
 		req.SetQueryParams(opts.QueryParams)
 	}
-	// 处理 Headers
+	// 处理 Headers，显式指定的请求头优先于透传的请求头
+	c.propagateHeaders(ctx, req)
 	for k, v := range opts.Headers {
 		req.SetHeader(k, v)
 	}
-	req.Header.Set("Request-Id", zlog.GetRequestID(ctx))
 	// 处理 Cookies
 	for name, val := range opts.Cookies {
 		cookie := &http.Cookie{Name: name, Value: val}
 		req.SetCookie(cookie)
 	}
-	err = c.doRequestSetBody(req, opts)
+	var err error
+	if len(c.OnBeforeRequest) > 0 {
+		err = setBodyBytes(req, opts)
+	} else {
+		err = c.doRequestSetBody(req, opts)
+	}
 	if err != nil {
 		return nil, err
 	}
+	if err = c.runBeforeRequest(ctx, req); err != nil {
+		return nil, err
+	}
 	return req, nil
 }
 
+// propagateHeaders 把上游请求中 PropagateHeaders 的值透传给下游
+// Request-Id 取 zlog.GetRequestID，上游没有时使用本次请求生成的ID，保证整条调用链ID一致
+func (c *ClientConf) propagateHeaders(ctx *gin.Context, req *resty.Request) {
+	for _, name := range c.PropagateHeaders {
+		if http.CanonicalHeaderKey(name) == "Request-Id" {
+			req.Header.Set(name, zlog.GetRequestID(ctx))
+			continue
+		}
+		if ctx == nil || ctx.Request == nil {
+			continue
+		}
+		if v := ctx.Request.Header.Get(name); v != "" {
+			req.Header.Set(name, v)
+		}
+	}
+}
+
 func (c *ClientConf) getReqBodyStr(opts RequestOptions) string {
 	// 处理请求体
 	var reqBodyStr string
@@ -410,8 +631,7 @@ func (c *ClientConf) getReqBodyStr(opts RequestOptions) string {
 			reqBodyStr = string(b)
 		}
 	case EncodeFile:
-		// 无法完整记录请求体字符串，只能简单提示
-		reqBodyStr = "[multipart form data with files]"
+		reqBodyStr = describeMultipart(opts)
 	default:
 		if opts.RequestBody != nil {
 			// 记录请求体内容（JSON 序列化）
@@ -422,6 +642,42 @@ func (c *ClientConf) getReqBodyStr(opts RequestOptions) string {
 	return reqBodyStr
 }
 
+// describeMultipart 记录表单字段和文件的名称、大小，不读取文件内容
+// 如 [multipart] description=头像; avatar=a.png(image/png, 2048B); docs=/path/doc.pdf(1024B)
+func describeMultipart(opts RequestOptions) string {
+	parts := []string{"[multipart]"}
+	if opts.RequestBody != nil {
+		values, _ := getFormRequestData(opts.RequestBody)
+		if len(values) > 0 {
+			parts = append(parts, values.Encode())
+		}
+	}
+	for _, field := range slices.Sorted(maps.Keys(opts.RequestFiles)) {
+		for _, path := range opts.RequestFiles[field] {
+			size := "unknown size"
+			if fi, err := os.Stat(path); err == nil {
+				size = fmt.Sprintf("%dB", fi.Size())
+			}
+			parts = append(parts, fmt.Sprintf("%s=%s(%s)", field, path, size))
+		}
+	}
+	for _, field := range slices.Sorted(maps.Keys(opts.RequestFileReaders)) {
+		for _, fr := range opts.RequestFileReaders[field] {
+			info := make([]string, 0, 2)
+			if fr.ContentType != "" {
+				info = append(info, fr.ContentType)
+			}
+			if fr.Size > 0 {
+				info = append(info, fmt.Sprintf("%dB", fr.Size))
+			} else {
+				info = append(info, "unknown size")
+			}
+			parts = append(parts, fmt.Sprintf("%s=%s(%s)", field, fr.FileName, strings.Join(info, ", ")))
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
 func getFormRequestData(requestBody any) (url.Values, error) {
 	v := url.Values{}
 
@@ -101,6 +101,105 @@ func TestClient_Post_Echo(t *testing.T) {
 	assert.Equal(t, 200, resp.HttpCode)
 }
 
+func TestClient_DoRaw(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(mockHandler))
+	defer server.Close()
+
+	client := &ClientConf{
+		Service:         "test",
+		Domain:          server.URL,
+		RetryTimes:      -1,
+		FailOnHTTPError: true,
+	}
+	ctx, _ := gin.CreateTestContext(nil)
+	ctx.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+
+	resp, err := client.DoRaw(ctx, http.MethodGet, RequestOptions{Path: "/ok"})
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode())
+	assert.Equal(t, `{"msg":"success"}`, resp.String())
+	assert.False(t, resp.ReceivedAt().IsZero())
+	// 开启了 trace，可以读取建连等耗时
+	trace := resp.Request.TraceInfo()
+	assert.Positive(t, trace.TotalTime)
+	assert.Equal(t, server.Listener.Addr().String(), trace.RemoteAddr)
+
+	// FailOnHTTPError 时同时返回响应和 HTTPStatusError
+	resp, err = client.DoRaw(ctx, http.MethodGet, RequestOptions{Path: "/missing"})
+	var statusErr *HTTPStatusError
+	assert.ErrorAs(t, err, &statusErr)
+	assert.Equal(t, http.StatusNotFound, statusErr.Code)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode())
+}
+
+func TestClient_RequestIdPropagation(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(r.Header.Get("Request-Id")))
+	}))
+	defer server.Close()
+
+	client := &ClientConf{
+		Service: "test",
+		Domain:  server.URL,
+		Timeout: 2 * time.Second,
+	}
+
+	// 上游传入的 Request-Id 原样透传给下游
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Request-Id", "upstream-id")
+	ctx, _ := gin.CreateTestContext(nil)
+	ctx.Request = req
+
+	resp, err := client.Get(ctx, RequestOptions{Path: "/"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "upstream-id", string(resp.Response))
+	assert.Equal(t, "upstream-id", zlog.GetRequestID(ctx))
+}
+
+func TestClient_PropagateHeaders(t *testing.T) {
+	var got http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	newCtx := func() *gin.Context {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Request-Id", "upstream-id")
+		req.Header.Set("Trace-Id", "trace-1")
+		req.Header.Set("Authorization", "Bearer secret")
+		ctx, _ := gin.CreateTestContext(nil)
+		ctx.Request = req
+		return ctx
+	}
+
+	// 默认只透传 Request-Id
+	client := &ClientConf{Service: "test", Domain: server.URL}
+	_, err := client.Get(newCtx(), RequestOptions{Path: "/"})
+	assert.NoError(t, err)
+	assert.Equal(t, "upstream-id", got.Get("Request-Id"))
+	assert.Empty(t, got.Get("Trace-Id"))
+	assert.Empty(t, got.Get("Authorization"))
+
+	// 配置的请求头上游没有时不设置，显式指定的请求头优先
+	client = &ClientConf{Service: "test", Domain: server.URL, PropagateHeaders: []string{"request-id", "Trace-Id", "Span-Id"}}
+	_, err = client.Get(newCtx(), RequestOptions{Path: "/", Headers: map[string]string{"Trace-Id": "override"}})
+	assert.NoError(t, err)
+	assert.Equal(t, "upstream-id", got.Get("Request-Id"))
+	assert.Equal(t, "override", got.Get("Trace-Id"))
+	_, ok := got["Span-Id"]
+	assert.False(t, ok)
+
+	// 空列表不透传
+	client = &ClientConf{Service: "test", Domain: server.URL, PropagateHeaders: []string{}}
+	_, err = client.Get(newCtx(), RequestOptions{Path: "/"})
+	assert.NoError(t, err)
+	assert.Empty(t, got.Get("Request-Id"))
+}
+
 func TestClient_Timeout(t *testing.T) {
 	// 模拟一个超时服务
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
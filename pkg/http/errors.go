@@ -0,0 +1,53 @@
+// Package http -----------------------------
+// @file      : errors.go
+// @author    : xiangtao
+// @contact   : xiangtao1994@gmail.com
+// @time      : 2025/9/13 17:50
+// Description: 请求失败的错误分类，便于调用方判断是否重试
+// -------------------------------------------
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+)
+
+var (
+	ErrTimeout = errors.New("http request timeout")
+	ErrConnect = errors.New("http connect failed")
+	ErrDNS     = errors.New("http dns lookup failed")
+)
+
+// HTTPStatusError 非2xx响应，开启 ClientConf.FailOnHTTPError 后 >=400 的响应以该错误返回，流式请求始终如此
+type HTTPStatusError struct {
+	Code   int
+	Body   []byte
+	Header http.Header
+}
+
+func (e *HTTPStatusError) Error() string {
+	return fmt.Sprintf("http response code %d, error: %s", e.Code, string(e.Body))
+}
+
+// classifyError 把底层错误包装为 ErrDNS、ErrTimeout、ErrConnect，原始错误仍可通过 errors.Is/As 获取
+func classifyError(err error) error {
+	if err == nil || errors.Is(err, ErrCircuitOpen) {
+		return err
+	}
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return fmt.Errorf("%w: %w", ErrDNS, err)
+	}
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return fmt.Errorf("%w: %w", ErrTimeout, err)
+	}
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return fmt.Errorf("%w: %w", ErrConnect, err)
+	}
+	return err
+}
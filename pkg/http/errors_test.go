@@ -0,0 +1,83 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClientErrorTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(500 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	client := &ClientConf{Service: "err-timeout", Domain: server.URL, Timeout: 100 * time.Millisecond, RetryTimes: -1}
+
+	_, err := client.Get(newMetricsTestContext(), RequestOptions{Path: "/"})
+	assert.True(t, errors.Is(err, ErrTimeout), "%v", err)
+
+	// 单次请求超时
+	client = &ClientConf{Service: "err-timeout", Domain: server.URL, Timeout: 3 * time.Second, RetryTimes: -1}
+	start := time.Now()
+	_, err = client.Get(newMetricsTestContext(), RequestOptions{Path: "/", Timeout: 50 * time.Millisecond})
+	assert.Less(t, time.Since(start), 400*time.Millisecond)
+	assert.True(t, errors.Is(err, ErrTimeout), "%v", err)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded), "%v", err)
+}
+
+func TestClientErrorConnect(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(mockHandler))
+	server.Close()
+	client := &ClientConf{Service: "err-connect", Domain: server.URL, Timeout: time.Second, RetryTimes: -1}
+
+	_, err := client.Get(newMetricsTestContext(), RequestOptions{Path: "/ok"})
+	assert.True(t, errors.Is(err, ErrConnect), "%v", err)
+	assert.False(t, errors.Is(err, ErrTimeout))
+	var opErr *net.OpError
+	assert.True(t, errors.As(err, &opErr))
+}
+
+func TestClientErrorDNS(t *testing.T) {
+	client := &ClientConf{Service: "err-dns", Domain: "http://golib-test.invalid", Timeout: 2 * time.Second, RetryTimes: -1}
+
+	_, err := client.Get(newMetricsTestContext(), RequestOptions{Path: "/"})
+	assert.True(t, errors.Is(err, ErrDNS), "%v", err)
+}
+
+func TestClientErrorHTTPStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Reason", "quota")
+		w.WriteHeader(http.StatusTooManyRequests)
+		_, _ = w.Write([]byte(`{"msg":"slow down"}`))
+	}))
+	defer server.Close()
+
+	// 默认不把非2xx当作错误
+	client := &ClientConf{Service: "err-status", Domain: server.URL, Timeout: time.Second, RetryTimes: -1}
+	res, err := client.Get(newMetricsTestContext(), RequestOptions{Path: "/"})
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusTooManyRequests, res.HttpCode)
+
+	client = &ClientConf{Service: "err-status", Domain: server.URL, Timeout: time.Second, RetryTimes: -1, FailOnHTTPError: true}
+	res, err = client.Get(newMetricsTestContext(), RequestOptions{Path: "/"})
+	assert.Nil(t, res)
+	var statusErr *HTTPStatusError
+	if assert.True(t, errors.As(err, &statusErr)) {
+		assert.Equal(t, http.StatusTooManyRequests, statusErr.Code)
+		assert.Equal(t, `{"msg":"slow down"}`, string(statusErr.Body))
+		assert.Equal(t, "quota", statusErr.Header.Get("X-Reason"))
+	}
+
+	// 流式请求始终返回 HTTPStatusError
+	_, err = client.GetStream(newMetricsTestContext(), RequestOptions{Path: "/"}, func(data []byte) error { return nil })
+	if assert.True(t, errors.As(err, &statusErr)) {
+		assert.Equal(t, `{"msg":"slow down"}`, string(statusErr.Body))
+	}
+}
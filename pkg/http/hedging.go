@@ -0,0 +1,166 @@
+// Package http -----------------------------
+// @file      : hedging.go
+// @author    : xiangtao
+// @contact   : xiangtao1994@gmail.com
+// @time      : 2025/9/13 18:30
+// Description: 对冲请求，首个请求超过 Delay 未返回时向其他域名发出备份请求，取最先返回的结果
+// -------------------------------------------
+package http
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"resty.dev/v3"
+
+	"github.com/xiangtao94/golib/pkg/zlog"
+)
+
+// HedgingPolicy 对冲配置，适用于对尾延迟敏感的只读调用
+type HedgingPolicy struct {
+	Delay          time.Duration `yaml:"delay"`          // 请求超过该时间未返回则发出对冲请求，为0不启用
+	MaxHedges      int           `yaml:"maxHedges"`      // 最多额外发出的请求数，默认1
+	OnlyIdempotent *bool         `yaml:"onlyIdempotent"` // 只对 GET/HEAD 对冲，为空时默认 true
+}
+
+type hedgeResult struct {
+	index int
+	req   *resty.Request
+	resp  *resty.Response
+	err   error
+}
+
+// shouldHedge 请求体为 io.Reader 或上传文件时无法重放，不对冲
+func (c *ClientConf) shouldHedge(method string, opts RequestOptions) bool {
+	p := c.HedgingPolicy
+	if p == nil || p.Delay <= 0 {
+		return false
+	}
+	if (p.OnlyIdempotent == nil || *p.OnlyIdempotent) && method != http.MethodGet && method != http.MethodHead {
+		return false
+	}
+	if strings.ToLower(opts.Encode) == EncodeFile {
+		return false
+	}
+	_, isReader := opts.RequestBody.(io.Reader)
+	return !isReader
+}
+
+// selectHedgeBaseURL 通过负载均衡选择一个未使用过的域名，域名都用过时复用
+func (c *ClientConf) selectHedgeBaseURL(used []string) (string, error) {
+	var baseURL string
+	for i := 0; i < max(len(c.Domains), 1); i++ {
+		u, err := c.selectBaseURL()
+		if err != nil {
+			return "", err
+		}
+		if baseURL != "" {
+			c.releaseBaseURL(baseURL)
+		}
+		baseURL = u
+		if !slices.Contains(used, u) {
+			break
+		}
+	}
+	return baseURL, nil
+}
+
+// doHedged 发出首个请求，每隔 Delay 未返回则再发出一个对冲请求，取第一个成功的结果并取消其余请求
+// 所有请求都失败时返回最后一个错误
+func (c *ClientConf) doHedged(ctx *gin.Context, parent context.Context, generation uint64, method string, opts RequestOptions) (res *Result, err error) {
+	maxHedges := c.HedgingPolicy.MaxHedges
+	if maxHedges <= 0 {
+		maxHedges = 1
+	}
+	hedgeCtx, cancel := context.WithCancel(parent)
+	defer cancel() // 取消未胜出的请求
+
+	results := make(chan hedgeResult, maxHedges+1)
+	var used []string
+	send := func(index int) error {
+		baseURL, err := c.selectHedgeBaseURL(used)
+		if err != nil {
+			return err
+		}
+		used = append(used, baseURL)
+		req, err := c.newRequest(ctx, method, baseURL, opts)
+		if err != nil {
+			c.releaseBaseURL(baseURL)
+			return err
+		}
+		req.SetContext(hedgeCtx)
+		go func() {
+			resp, err := req.Send()
+			status := 0
+			if resp != nil {
+				status = resp.StatusCode()
+			}
+			c.feedback(baseURL, status, err)
+			results <- hedgeResult{index: index, req: req, resp: resp, err: err}
+		}()
+		return nil
+	}
+
+	if err = send(0); err != nil {
+		c.breaker.release(generation)
+		return nil, err
+	}
+
+	start := time.Now()
+	var winner hedgeResult
+	var status int
+	hedges := 0
+	defer func() {
+		c.breaker.done(generation, status, err)
+		c.recordMetrics(method, status, winner.req.Attempt, start)
+		for i := 1; i <= hedges; i++ {
+			clientHedges.WithLabelValues(c.Service, strconv.FormatBool(winner.index == i)).Inc()
+		}
+		c.logHttpInvoke(ctx, method, winner.req.URL, winner.req.Attempt, res, err, start, opts,
+			zlog.Int("hedges", hedges), zlog.Int("hedgeWinner", winner.index))
+	}()
+
+	timer := time.NewTimer(c.HedgingPolicy.Delay)
+	defer timer.Stop()
+	pending := 1
+	for {
+		select {
+		case <-timer.C:
+			if send(hedges+1) != nil {
+				continue
+			}
+			hedges++
+			pending++
+			if hedges < maxHedges {
+				timer.Reset(c.HedgingPolicy.Delay)
+			}
+			continue
+		case winner = <-results:
+			pending--
+		}
+		if winner.err == nil || pending == 0 {
+			break
+		}
+	}
+
+	if winner.resp != nil {
+		status = winner.resp.StatusCode()
+	}
+	if winner.err != nil {
+		err = classifyError(winner.err)
+		return nil, err
+	}
+	if res, err = c.toResult(ctx, winner.resp); err != nil {
+		return nil, err
+	}
+	if err = c.runAfterResponse(ctx, winner.req, res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
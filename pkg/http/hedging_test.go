@@ -0,0 +1,122 @@
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+// newHedgingServers 返回一个慢服务和一个快服务，慢服务的请求被取消时关闭 cancelled
+func newHedgingServers(t *testing.T) (slow, fast *httptest.Server, slowHits, fastHits *atomic.Int32, cancelled chan struct{}) {
+	slowHits, fastHits = new(atomic.Int32), new(atomic.Int32)
+	cancelled = make(chan struct{}, 1)
+	slow = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		slowHits.Add(1)
+		select {
+		case <-time.After(2 * time.Second):
+			_, _ = w.Write([]byte("slow"))
+		case <-r.Context().Done():
+			cancelled <- struct{}{}
+		}
+	}))
+	fast = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fastHits.Add(1)
+		_, _ = w.Write([]byte("fast"))
+	}))
+	t.Cleanup(func() {
+		slow.Close()
+		fast.Close()
+	})
+	return
+}
+
+func TestHedgingFastWins(t *testing.T) {
+	slow, fast, slowHits, fastHits, cancelled := newHedgingServers(t)
+	client := &ClientConf{
+		Service:       "hedge",
+		Domains:       []string{slow.URL, fast.URL},
+		Timeout:       3 * time.Second,
+		RetryTimes:    -1,
+		HedgingPolicy: &HedgingPolicy{Delay: 50 * time.Millisecond},
+	}
+
+	start := time.Now()
+	res, err := client.Get(newMetricsTestContext(), RequestOptions{Path: "/"})
+	assert.NoError(t, err)
+	assert.Equal(t, "fast", string(res.Response))
+	assert.Less(t, time.Since(start), time.Second)
+	assert.Equal(t, int32(1), slowHits.Load())
+	assert.Equal(t, int32(1), fastHits.Load())
+
+	// 慢请求被取消
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Fatal("slow request not cancelled")
+	}
+	assert.Equal(t, float64(1), testutil.ToFloat64(clientHedges.WithLabelValues("hedge", "true")))
+}
+
+func TestHedgingSkipsPost(t *testing.T) {
+	slow, fast, _, fastHits, _ := newHedgingServers(t)
+	client := &ClientConf{
+		Service:       "hedge-post",
+		Domains:       []string{slow.URL, fast.URL},
+		Timeout:       100 * time.Millisecond,
+		RetryTimes:    -1,
+		HedgingPolicy: &HedgingPolicy{Delay: 10 * time.Millisecond},
+	}
+
+	_, err := client.Post(newMetricsTestContext(), RequestOptions{Path: "/", Encode: EncodeJson, RequestBody: map[string]string{"a": "b"}})
+	assert.ErrorIs(t, err, ErrTimeout)
+	assert.Equal(t, int32(0), fastHits.Load())
+}
+
+func TestHedgingSkipsStream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for i := 0; i < 3; i++ {
+			_, _ = fmt.Fprintf(w, "data: %d\n", i)
+			w.(http.Flusher).Flush()
+			time.Sleep(20 * time.Millisecond)
+		}
+	}))
+	t.Cleanup(server.Close)
+	client := &ClientConf{
+		Service:       "hedge-stream",
+		Domain:        server.URL,
+		Timeout:       time.Second,
+		RetryTimes:    -1,
+		HedgingPolicy: &HedgingPolicy{Delay: 10 * time.Millisecond},
+	}
+
+	// 流式请求不走对冲，每行数据都回调
+	var chunks []string
+	_, err := client.GetStream(newMetricsTestContext(), RequestOptions{Path: "/"}, func(data []byte) error {
+		chunks = append(chunks, string(data))
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"data: 0", "data: 1", "data: 2"}, chunks)
+}
+
+func TestShouldHedge(t *testing.T) {
+	allowAll := false
+	client := &ClientConf{HedgingPolicy: &HedgingPolicy{Delay: time.Millisecond, OnlyIdempotent: &allowAll}}
+	assert.True(t, client.shouldHedge(http.MethodPost, RequestOptions{Encode: EncodeJson, RequestBody: map[string]string{}}))
+	// 无法重放的请求体不对冲
+	assert.False(t, client.shouldHedge(http.MethodPost, RequestOptions{Encode: EncodeFile}))
+	assert.False(t, client.shouldHedge(http.MethodPost, RequestOptions{RequestBody: http.NoBody}))
+
+	client.HedgingPolicy = &HedgingPolicy{Delay: time.Millisecond}
+	assert.True(t, client.shouldHedge(http.MethodHead, RequestOptions{}))
+	assert.False(t, client.shouldHedge(http.MethodPut, RequestOptions{}))
+	client.HedgingPolicy = nil
+	assert.False(t, client.shouldHedge(http.MethodGet, RequestOptions{}))
+}
@@ -0,0 +1,242 @@
+// Package http -----------------------------
+// @file      : hooks.go
+// @author    : xiangtao
+// @contact   : xiangtao1994@gmail.com
+// @time      : 2025/9/14 15:00
+// Description: 客户端级别的请求/响应钩子，用于签名、全局请求头等
+// -------------------------------------------
+package http
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"resty.dev/v3"
+)
+
+// BeforeRequestHook 请求发出前调用，返回错误时不发出请求
+// 配置了 BeforeRequestHook 时请求体会提前序列化，req.Body 为最终发送的 []byte（没有请求体时为 nil）
+type BeforeRequestHook func(ctx *gin.Context, req *resty.Request) error
+
+// AfterResponseHook 收到响应后调用，返回错误时本次调用返回该错误
+type AfterResponseHook func(ctx *gin.Context, req *resty.Request, res *Result) error
+
+// runBeforeRequest 依次执行 OnBeforeRequest，在打印日志前执行，修改的请求头会体现在日志和下游请求中
+func (c *ClientConf) runBeforeRequest(ctx *gin.Context, req *resty.Request) error {
+	for _, hook := range c.OnBeforeRequest {
+		if err := hook(ctx, req); err != nil {
+			return fmt.Errorf("http before request hook: %w", err)
+		}
+	}
+	return nil
+}
+
+// runAfterResponse 依次执行 OnAfterResponse
+func (c *ClientConf) runAfterResponse(ctx *gin.Context, req *resty.Request, res *Result) error {
+	for _, hook := range c.OnAfterResponse {
+		if err := hook(ctx, req, res); err != nil {
+			return fmt.Errorf("http after response hook: %w", err)
+		}
+	}
+	return nil
+}
+
+// setBodyBytes 按 Encode 把请求体序列化为 []byte，使钩子看到的请求体与实际发送的一致
+// 表单和文件上传也在这里编码，未显式指定 Content-Type 时按编码方式设置
+func setBodyBytes(req *resty.Request, opts RequestOptions) error {
+	var body []byte
+	var contentType string
+	switch strings.ToLower(opts.Encode) {
+	case EncodeForm:
+		if opts.RequestBody != nil {
+			values, err := getFormRequestData(opts.RequestBody)
+			if err != nil {
+				return fmt.Errorf("failed to marshal form body: %v", err)
+			}
+			body, contentType = []byte(values.Encode()), "application/x-www-form-urlencoded"
+		}
+	case EncodeFile:
+		var err error
+		body, contentType, err = multipartBody(opts)
+		if err != nil {
+			return err
+		}
+	default:
+		switch v := opts.RequestBody.(type) {
+		case nil:
+		case []byte:
+			body = v
+		case string:
+			body = []byte(v)
+		case io.Reader:
+			b, err := io.ReadAll(v)
+			if err != nil {
+				return fmt.Errorf("failed to read request body: %v", err)
+			}
+			body = b
+		default:
+			b, err := json.Marshal(v)
+			if err != nil {
+				return fmt.Errorf("failed to marshal json body: %v", err)
+			}
+			body, contentType = b, "application/json"
+		}
+	}
+	if contentType != "" && req.Header.Get("Content-Type") == "" {
+		req.SetHeader("Content-Type", contentType)
+	}
+	req.SetBody(body)
+	return nil
+}
+
+// multipartBody 编码 EncodeFile 的表单字段和文件，文件会整体读入内存
+func multipartBody(opts RequestOptions) ([]byte, string, error) {
+	buf := &bytes.Buffer{}
+	mw := multipart.NewWriter(buf)
+	if opts.RequestBody != nil {
+		values, err := getFormRequestData(opts.RequestBody)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to marshal form body: %v", err)
+		}
+		for key, vs := range values {
+			for _, v := range vs {
+				if err = mw.WriteField(key, v); err != nil {
+					return nil, "", err
+				}
+			}
+		}
+	}
+	for field, paths := range opts.RequestFiles {
+		for _, path := range paths {
+			if err := writeFilePart(mw, field, path); err != nil {
+				return nil, "", err
+			}
+		}
+	}
+	for field, readers := range opts.RequestFileReaders {
+		for _, fr := range readers {
+			if err := writeReaderPart(mw, field, fr); err != nil {
+				return nil, "", err
+			}
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return nil, "", err
+	}
+	return buf.Bytes(), mw.FormDataContentType(), nil
+}
+
+func writeFilePart(mw *multipart.Writer, field, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open upload file: %v", err)
+	}
+	defer f.Close()
+	part, err := mw.CreateFormFile(field, filepath.Base(path))
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(part, f)
+	return err
+}
+
+// writeReaderPart ContentType 为空时与 resty 一致，按前512字节探测
+func writeReaderPart(mw *multipart.Writer, field string, fr FileReader) error {
+	if c, ok := fr.Reader.(io.Closer); ok {
+		defer c.Close()
+	}
+	data, err := io.ReadAll(fr.Reader)
+	if err != nil {
+		return fmt.Errorf("failed to read upload file %s: %v", fr.FileName, err)
+	}
+	contentType := fr.ContentType
+	if contentType == "" {
+		contentType = http.DetectContentType(data)
+	}
+	h := make(textproto.MIMEHeader)
+	h.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`,
+		quoteEscaper.Replace(field), quoteEscaper.Replace(fr.FileName)))
+	h.Set("Content-Type", contentType)
+	part, err := mw.CreatePart(h)
+	if err != nil {
+		return err
+	}
+	_, err = part.Write(data)
+	return err
+}
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+// HMACSignerConf HMAC-SHA256 签名配置，签名串为 method|path|timestamp|hex(sha256(body))
+type HMACSignerConf struct {
+	KeyID           string `yaml:"keyId"`           // 密钥ID，不为空时写入 KeyIDHeader
+	Secret          string `yaml:"secret"`          // 签名密钥
+	SignatureHeader string `yaml:"signatureHeader"` // 签名请求头，默认 X-Signature
+	TimestampHeader string `yaml:"timestampHeader"` // 时间戳（Unix秒）请求头，默认 X-Timestamp
+	DigestHeader    string `yaml:"digestHeader"`    // 请求体摘要请求头，默认 X-Content-Sha256
+	KeyIDHeader     string `yaml:"keyIdHeader"`     // 密钥ID请求头，默认 X-Key-Id
+}
+
+func (conf *HMACSignerConf) checkConf() {
+	if conf.SignatureHeader == "" {
+		conf.SignatureHeader = "X-Signature"
+	}
+	if conf.TimestampHeader == "" {
+		conf.TimestampHeader = "X-Timestamp"
+	}
+	if conf.DigestHeader == "" {
+		conf.DigestHeader = "X-Content-Sha256"
+	}
+	if conf.KeyIDHeader == "" {
+		conf.KeyIDHeader = "X-Key-Id"
+	}
+}
+
+// Sign 计算签名，path 为不含查询参数的 URL 路径，digest 为请求体 sha256 的十六进制，服务端校验时使用相同的方法
+func (conf HMACSignerConf) Sign(method, path, timestamp, digest string) string {
+	mac := hmac.New(sha256.New, []byte(conf.Secret))
+	mac.Write([]byte(strings.Join([]string{strings.ToUpper(method), path, timestamp, digest}, "|")))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// NewHMACSigner 返回对请求签名的 BeforeRequestHook，放在 OnBeforeRequest 的最后，避免签名后请求再被修改
+// 重试时复用首次的签名，服务端校验时间戳的有效期需大于客户端超时时间
+func NewHMACSigner(conf HMACSignerConf) BeforeRequestHook {
+	conf.checkConf()
+	return func(ctx *gin.Context, req *resty.Request) error {
+		if conf.Secret == "" {
+			return errors.New("hmac signer secret is empty")
+		}
+		u, err := url.Parse(req.URL)
+		if err != nil {
+			return fmt.Errorf("hmac signer parse url: %w", err)
+		}
+		body, _ := req.Body.([]byte)
+		sum := sha256.Sum256(body)
+		digest := hex.EncodeToString(sum[:])
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		req.SetHeader(conf.TimestampHeader, timestamp)
+		req.SetHeader(conf.DigestHeader, digest)
+		if conf.KeyID != "" {
+			req.SetHeader(conf.KeyIDHeader, conf.KeyID)
+		}
+		req.SetHeader(conf.SignatureHeader, conf.Sign(req.Method, u.EscapedPath(), timestamp, digest))
+		return nil
+	}
+}
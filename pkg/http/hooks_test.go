@@ -0,0 +1,193 @@
+package http
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"resty.dev/v3"
+)
+
+// verifySignature 模拟合作方按相同算法校验签名
+func verifySignature(conf HMACSignerConf, r *http.Request) (string, bool) {
+	body, _ := io.ReadAll(r.Body)
+	sum := sha256.Sum256(body)
+	digest := hex.EncodeToString(sum[:])
+	if r.Header.Get("X-Content-Sha256") != digest {
+		return string(body), false
+	}
+	want := conf.Sign(r.Method, r.URL.EscapedPath(), r.Header.Get("X-Timestamp"), digest)
+	return string(body), r.Header.Get("X-Signature") == want && r.Header.Get("X-Key-Id") == conf.KeyID
+}
+
+func TestClient_HMACSigner(t *testing.T) {
+	conf := HMACSignerConf{KeyID: "partner", Secret: "s3cret"}
+	var received atomic.Value
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := verifySignature(conf, r)
+		received.Store(body)
+		if !ok {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	file := filepath.Join(t.TempDir(), "a.txt")
+	require.NoError(t, os.WriteFile(file, []byte("file content"), 0o644))
+
+	cases := []struct {
+		name     string
+		opts     RequestOptions
+		contains string
+	}{
+		{"json", RequestOptions{Path: "/sign", Encode: EncodeJson, RequestBody: map[string]any{"a": 1}}, `{"a":1}`},
+		{"form", RequestOptions{Path: "/sign", Encode: EncodeForm, RequestBody: map[string]string{"k": "v w"}}, "k=v+w"},
+		{"file", RequestOptions{Path: "/sign", Encode: EncodeFile, RequestBody: map[string]string{"k": "v"},
+			RequestFiles: map[string][]string{"file": {file}}}, "file content"},
+		{"raw", RequestOptions{Path: "/sign/%E4%B8%AD", Encode: EncodeRaw, RequestBody: "raw body"}, "raw body"},
+		{"empty", RequestOptions{Path: "/sign"}, ""},
+	}
+	client := &ClientConf{
+		Service:         "partner",
+		Domain:          server.URL,
+		RetryTimes:      -1,
+		OnBeforeRequest: []BeforeRequestHook{NewHMACSigner(conf)},
+	}
+	ctx, _ := gin.CreateTestContext(nil)
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			res, err := client.Post(ctx, tc.opts)
+			require.NoError(t, err)
+			assert.Equal(t, http.StatusOK, res.HttpCode)
+			assert.True(t, strings.Contains(received.Load().(string), tc.contains))
+		})
+	}
+
+	// 签名密钥不一致时校验失败
+	client.OnBeforeRequest = []BeforeRequestHook{NewHMACSigner(HMACSignerConf{KeyID: "partner", Secret: "wrong"})}
+	res, err := client.Post(ctx, RequestOptions{Path: "/sign", Encode: EncodeJson, RequestBody: map[string]any{"a": 1}})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusUnauthorized, res.HttpCode)
+}
+
+func TestClient_BeforeRequestHookError(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+	}))
+	defer server.Close()
+
+	errDenied := errors.New("denied")
+	var tenant string
+	client := &ClientConf{
+		Service: "test",
+		Domain:  server.URL,
+		OnBeforeRequest: []BeforeRequestHook{
+			func(ctx *gin.Context, req *resty.Request) error {
+				req.SetHeader("X-Tenant", "t1")
+				return nil
+			},
+			func(ctx *gin.Context, req *resty.Request) error {
+				tenant = req.Header.Get("X-Tenant")
+				return errDenied
+			},
+		},
+	}
+	ctx, _ := gin.CreateTestContext(nil)
+	_, err := client.Get(ctx, RequestOptions{Path: "/ok"})
+	assert.ErrorIs(t, err, errDenied)
+	assert.Equal(t, "t1", tenant)
+	assert.Zero(t, calls.Load())
+}
+
+func TestClient_AfterResponseHook(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(mockHandler))
+	defer server.Close()
+
+	errInvalid := errors.New("invalid response")
+	var seen int
+	client := &ClientConf{
+		Service: "test",
+		Domain:  server.URL,
+		OnAfterResponse: []AfterResponseHook{func(ctx *gin.Context, req *resty.Request, res *Result) error {
+			seen = res.HttpCode
+			if !strings.Contains(string(res.Response), "success") {
+				return errInvalid
+			}
+			return nil
+		}},
+	}
+	ctx, _ := gin.CreateTestContext(nil)
+	res, err := client.Get(ctx, RequestOptions{Path: "/ok"})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, res.HttpCode)
+
+	res, err = client.Get(ctx, RequestOptions{Path: "/missing"})
+	assert.ErrorIs(t, err, errInvalid)
+	assert.Nil(t, res)
+	assert.Equal(t, http.StatusNotFound, seen)
+}
+
+type auditRecord struct {
+	method, url       string
+	reqBody, respBody string
+	status            int
+}
+
+func TestClient_AuditHook(t *testing.T) {
+	longBody := strings.Repeat("x", 100)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = io.WriteString(w, `{"error":"bad"}`)
+			return
+		}
+		_, _ = io.WriteString(w, longBody)
+	}))
+	var records []auditRecord
+	client := &ClientConf{
+		Service:         "audit",
+		Domain:          server.URL,
+		RetryTimes:      -1,
+		MaxReqBodyLen:   5,
+		MaxRespBodyLen:  -1,
+		FailOnHTTPError: true,
+		AuditHook: func(ctx *gin.Context, method, url string, reqBody, respBody []byte, status int) {
+			records = append(records, auditRecord{method, url, string(reqBody), string(respBody), status})
+		},
+	}
+	ctx := newMetricsTestContext()
+
+	// 不受日志截断长度限制
+	_, err := client.Post(ctx, RequestOptions{Path: "/ok", Encode: EncodeRaw, RequestBody: "full request body"})
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, auditRecord{http.MethodPost, server.URL + "/ok", "full request body", longBody, http.StatusOK}, records[0])
+
+	// 失败时同样调用，状态码和响应体取自 HTTPStatusError
+	_, err = client.Post(ctx, RequestOptions{Path: "/fail", Encode: EncodeJson, RequestBody: map[string]int{"a": 1}})
+	require.Error(t, err)
+	require.Len(t, records, 2)
+	assert.Equal(t, auditRecord{http.MethodPost, server.URL + "/fail", `{"a":1}`, `{"error":"bad"}`, http.StatusBadRequest}, records[1])
+
+	// 请求未发出时状态码为0
+	server.Close()
+	_, err = client.Get(ctx, RequestOptions{Path: "/ok"})
+	require.Error(t, err)
+	require.Len(t, records, 3)
+	assert.Equal(t, 0, records[2].status)
+	assert.Empty(t, records[2].respBody)
+}
@@ -0,0 +1,334 @@
+// Package http -----------------------------
+// @file      : load_balancer.go
+// @author    : xiangtao
+// @contact   : xiangtao1994@gmail.com
+// @time      : 2025/9/13 21:10
+// Description: 多域名加权轮询和最少连接数负载均衡，支持摘除连续失败的域名
+// -------------------------------------------
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+
+	"resty.dev/v3"
+
+	"github.com/xiangtao94/golib/pkg/zlog"
+)
+
+// ClientConf.LoadBalancerType 的取值
+const (
+	LoadBalancerRoundRobin         = "roundRobin"         // 轮询，默认
+	LoadBalancerWeightedRoundRobin = "weightedRoundRobin" // 按 Weights 加权轮询
+	LoadBalancerLeastConn          = "leastConn"          // 进行中请求数最少的域名优先
+)
+
+// EjectionPolicy 故障摘除配置，域名连续失败 MaxFailures 次后摘除 Duration
+// 到期后放行一个探测请求，成功则恢复，失败则继续摘除 Duration
+// 失败指连接失败、超时或 5xx 响应；所有域名都被摘除时忽略摘除状态，避免整体不可用
+type EjectionPolicy struct {
+	MaxFailures int           `yaml:"maxFailures"` // 连续失败次数，默认5
+	Duration    time.Duration `yaml:"duration"`    // 摘除时长，默认30s
+}
+
+// BalancerOption 负载均衡的可选项
+type BalancerOption func(*balancer)
+
+// WithEjection 开启故障摘除
+func WithEjection(policy EjectionPolicy) BalancerOption {
+	return func(b *balancer) {
+		if policy.MaxFailures <= 0 {
+			policy.MaxFailures = 5
+		}
+		if policy.Duration <= 0 {
+			policy.Duration = 30 * time.Second
+		}
+		b.ejection = &policy
+	}
+}
+
+type upstream struct {
+	baseURL      string
+	weight       int
+	current      int // 平滑加权轮询的当前权重
+	active       int // 进行中的请求数
+	failures     int // 连续失败次数
+	ejected      bool
+	ejectedUntil time.Time
+	probing      bool // 摘除到期后已放行探测请求，结果返回前不再选择
+}
+
+// available 未摘除，或摘除到期且没有进行中的探测请求
+func (h *upstream) available(now time.Time) bool {
+	return !h.ejected || (!h.probing && !now.Before(h.ejectedUntil))
+}
+
+// balancer 维护域名状态，Next 选中时增加进行中请求数，Feedback 时减少并统计失败
+type balancer struct {
+	service  string // 日志中的服务名
+	mu       sync.Mutex
+	hosts    []*upstream
+	ejection *EjectionPolicy
+	now      func() time.Time
+	pick     func(hosts []*upstream) *upstream
+}
+
+func newBalancer(weights map[string]int, opts []BalancerOption) (*balancer, error) {
+	if len(weights) == 0 {
+		return nil, errors.New("load balancer: no domains")
+	}
+	b := &balancer{now: time.Now}
+	for baseURL, weight := range weights {
+		if weight <= 0 {
+			return nil, fmt.Errorf("load balancer: invalid weight %d for %s", weight, baseURL)
+		}
+		u, err := url.Parse(baseURL)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return nil, fmt.Errorf("load balancer: invalid domain %q", baseURL)
+		}
+		b.hosts = append(b.hosts, &upstream{baseURL: baseURL, weight: weight})
+	}
+	// map 无序，排序保证选择顺序稳定
+	sort.Slice(b.hosts, func(i, j int) bool { return b.hosts[i].baseURL < b.hosts[j].baseURL })
+	for _, opt := range opts {
+		opt(b)
+	}
+	return b, nil
+}
+
+func (b *balancer) Next() (string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := b.now()
+	hosts := make([]*upstream, 0, len(b.hosts))
+	for _, h := range b.hosts {
+		if h.available(now) {
+			hosts = append(hosts, h)
+		}
+	}
+	if len(hosts) == 0 {
+		hosts = b.hosts
+	}
+	h := b.pick(hosts)
+	h.active++
+	if h.ejected {
+		h.probing = true
+	}
+	return h.baseURL, nil
+}
+
+// Feedback 请求结束时调用，BaseURL 不属于该负载均衡时忽略
+func (b *balancer) Feedback(f *resty.RequestFeedback) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	h := b.find(f.BaseURL)
+	if h == nil {
+		return
+	}
+	if h.active > 0 {
+		h.active--
+	}
+	h.probing = false
+	if f.Success {
+		h.failures = 0
+		if h.ejected {
+			h.ejected = false
+			zlog.Infof(nil, "http load balancer %s: domain %s recovered", b.service, h.baseURL)
+		}
+		return
+	}
+	if b.ejection == nil {
+		return
+	}
+	if h.ejected {
+		// 探测失败继续摘除
+		h.ejectedUntil = b.now().Add(b.ejection.Duration)
+		zlog.Warnf(nil, "http load balancer %s: domain %s probe failed, eject for %v", b.service, h.baseURL, b.ejection.Duration)
+		return
+	}
+	h.failures++
+	if h.failures >= b.ejection.MaxFailures {
+		h.failures = 0
+		h.ejected = true
+		h.ejectedUntil = b.now().Add(b.ejection.Duration)
+		zlog.Warnf(nil, "http load balancer %s: domain %s ejected for %v after %d consecutive failures", b.service, h.baseURL, b.ejection.Duration, b.ejection.MaxFailures)
+	}
+}
+
+// release 选中后未发出请求，只减少进行中请求数
+func (b *balancer) release(baseURL string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	h := b.find(baseURL)
+	if h == nil {
+		return
+	}
+	if h.active > 0 {
+		h.active--
+	}
+	h.probing = false
+}
+
+func (b *balancer) Close() error {
+	return nil
+}
+
+func (b *balancer) find(baseURL string) *upstream {
+	for _, h := range b.hosts {
+		if h.baseURL == baseURL {
+			return h
+		}
+	}
+	return nil
+}
+
+// WeightedRoundRobin 平滑加权轮询，权重 5:1 的两个域名按 5:1 的比例交错选择
+type WeightedRoundRobin struct {
+	*balancer
+}
+
+var _ resty.LoadBalancer = (*WeightedRoundRobin)(nil)
+
+// NewWeightedRoundRobin 创建加权轮询负载均衡，weights 为域名到权重的映射，权重需大于0
+func NewWeightedRoundRobin(weights map[string]int, opts ...BalancerOption) (*WeightedRoundRobin, error) {
+	b, err := newBalancer(weights, opts)
+	if err != nil {
+		return nil, err
+	}
+	b.pick = pickWeighted
+	return &WeightedRoundRobin{balancer: b}, nil
+}
+
+func pickWeighted(hosts []*upstream) *upstream {
+	var best *upstream
+	total := 0
+	for _, h := range hosts {
+		h.current += h.weight
+		total += h.weight
+		if best == nil || h.current > best.current {
+			best = h
+		}
+	}
+	best.current -= total
+	return best
+}
+
+// LeastConn 选择进行中请求数与权重之比最小的域名，适用于下游处理耗时差异较大的场景
+type LeastConn struct {
+	*balancer
+	next int
+}
+
+var _ resty.LoadBalancer = (*LeastConn)(nil)
+
+// NewLeastConn 创建最少连接数负载均衡，weights 为域名到权重的映射，权重需大于0
+func NewLeastConn(weights map[string]int, opts ...BalancerOption) (*LeastConn, error) {
+	b, err := newBalancer(weights, opts)
+	if err != nil {
+		return nil, err
+	}
+	lc := &LeastConn{balancer: b}
+	b.pick = lc.pick
+	return lc, nil
+}
+
+// pick 比较 active/weight，相同时从上次选中的下一个开始轮询，避免空闲时总选第一个
+func (lc *LeastConn) pick(hosts []*upstream) *upstream {
+	var best *upstream
+	for i := range hosts {
+		h := hosts[(lc.next+i)%len(hosts)]
+		if best == nil || h.active*best.weight < best.active*h.weight {
+			best = h
+		}
+	}
+	lc.next++
+	return best
+}
+
+// newLoadBalancer 按 LoadBalancerType 创建负载均衡，未配置类型且不摘除时使用 resty 的轮询
+func (c *ClientConf) newLoadBalancer() (resty.LoadBalancer, error) {
+	var opts []BalancerOption
+	if c.Ejection != nil {
+		opts = append(opts, WithEjection(*c.Ejection))
+	}
+	weights := make(map[string]int, len(c.Domains))
+	for _, domain := range c.Domains {
+		weights[domain] = 1
+		if w, ok := c.Weights[domain]; ok {
+			weights[domain] = w
+		}
+	}
+	var b *balancer
+	switch c.LoadBalancerType {
+	case "", LoadBalancerRoundRobin:
+		if c.Ejection == nil {
+			return resty.NewRoundRobin(c.Domains...)
+		}
+		// 权重相同的加权轮询即轮询
+		for domain := range weights {
+			weights[domain] = 1
+		}
+		wrr, err := NewWeightedRoundRobin(weights, opts...)
+		if err != nil {
+			return nil, err
+		}
+		b = wrr.balancer
+	case LoadBalancerWeightedRoundRobin:
+		wrr, err := NewWeightedRoundRobin(weights, opts...)
+		if err != nil {
+			return nil, err
+		}
+		b = wrr.balancer
+	case LoadBalancerLeastConn:
+		lc, err := NewLeastConn(weights, opts...)
+		if err != nil {
+			return nil, err
+		}
+		b = lc.balancer
+	default:
+		return nil, fmt.Errorf("unknown load balancer type %q", c.LoadBalancerType)
+	}
+	b.service = c.Service
+	return b, nil
+}
+
+func (c *ClientConf) loadBalancer() resty.LoadBalancer {
+	if c.LoadBalancer != nil {
+		return c.LoadBalancer
+	}
+	if c.HTTPClient == nil {
+		return nil
+	}
+	return c.HTTPClient.LoadBalancer()
+}
+
+// feedback 请求结束后通知负载均衡，用于最少连接数统计和故障摘除
+// 请求使用完整 URL，resty 自身的 Feedback 不带域名，因此由这里回调
+// 调用方取消（如对冲请求落选）不计为失败
+func (c *ClientConf) feedback(baseURL string, status int, err error) {
+	if len(c.Domains) == 0 {
+		return
+	}
+	lb := c.loadBalancer()
+	if lb == nil {
+		return
+	}
+	success := err == nil || errors.Is(err, context.Canceled)
+	if status >= http.StatusInternalServerError && status != http.StatusNotImplemented {
+		success = false
+	}
+	lb.Feedback(&resty.RequestFeedback{BaseURL: baseURL, Success: success})
+}
+
+// releaseBaseURL 选中的域名未使用时释放
+func (c *ClientConf) releaseBaseURL(baseURL string) {
+	if r, ok := c.loadBalancer().(interface{ release(string) }); ok {
+		r.release(baseURL)
+	}
+}
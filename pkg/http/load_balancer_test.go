@@ -0,0 +1,179 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"resty.dev/v3"
+)
+
+func nextN(t *testing.T, lb resty.LoadBalancer, n int) []string {
+	res := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		u, err := lb.Next()
+		require.NoError(t, err)
+		res = append(res, u)
+		lb.Feedback(&resty.RequestFeedback{BaseURL: u, Success: true})
+	}
+	return res
+}
+
+func TestWeightedRoundRobin(t *testing.T) {
+	const a, b = "http://a.example.com", "http://b.example.com"
+	wrr, err := NewWeightedRoundRobin(map[string]int{a: 3, b: 1})
+	require.NoError(t, err)
+	// 平滑加权轮询：按比例交错而不是连续选择
+	assert.Equal(t, []string{a, a, b, a, a, a, b, a}, nextN(t, wrr, 8))
+
+	_, err = NewWeightedRoundRobin(nil)
+	assert.Error(t, err)
+	_, err = NewWeightedRoundRobin(map[string]int{a: 0})
+	assert.Error(t, err)
+	_, err = NewWeightedRoundRobin(map[string]int{"a.example.com": 1})
+	assert.Error(t, err)
+}
+
+func TestLeastConn(t *testing.T) {
+	const a, b = "http://a.example.com", "http://b.example.com"
+	lc, err := NewLeastConn(map[string]int{a: 1, b: 1})
+	require.NoError(t, err)
+
+	first, _ := lc.Next()
+	second, _ := lc.Next()
+	assert.ElementsMatch(t, []string{a, b}, []string{first, second})
+	// 都有一个进行中的请求，first 结束后 first 最少
+	lc.Feedback(&resty.RequestFeedback{BaseURL: first, Success: true})
+	u, _ := lc.Next()
+	assert.Equal(t, first, u)
+
+	// 权重 2:1 时 a 可承担两倍的进行中请求
+	lc, err = NewLeastConn(map[string]int{a: 2, b: 1})
+	require.NoError(t, err)
+	var picks []string
+	for i := 0; i < 3; i++ {
+		u, _ := lc.Next()
+		picks = append(picks, u)
+	}
+	assert.ElementsMatch(t, []string{a, a, b}, picks)
+
+	// 选中后未使用时释放进行中请求数
+	lc.release(a)
+	lc.release(a)
+	u, _ = lc.Next()
+	assert.Equal(t, a, u)
+}
+
+func TestBalancerEjection(t *testing.T) {
+	const a, b = "http://a.example.com", "http://b.example.com"
+	wrr, err := NewWeightedRoundRobin(map[string]int{a: 1, b: 1}, WithEjection(EjectionPolicy{MaxFailures: 2, Duration: time.Minute}))
+	require.NoError(t, err)
+	now := time.Now()
+	wrr.now = func() time.Time { return now }
+
+	fail := func(u string) {
+		wrr.Feedback(&resty.RequestFeedback{BaseURL: u, Success: false})
+	}
+	fail(a)
+	wrr.Feedback(&resty.RequestFeedback{BaseURL: a, Success: true}) // 成功后重新计数
+	fail(a)
+	assert.Contains(t, nextN(t, wrr, 4), a)
+
+	fail(a)
+	fail(a)
+	assert.Equal(t, []string{b, b, b, b}, nextN(t, wrr, 4))
+
+	// 到期后放行一个探测请求，结果返回前不再选择
+	now = now.Add(time.Minute)
+	var probe string
+	for probe != a {
+		probe, _ = wrr.Next()
+		if probe != a {
+			wrr.Feedback(&resty.RequestFeedback{BaseURL: probe, Success: true})
+		}
+	}
+	assert.Equal(t, []string{b, b}, nextN(t, wrr, 2))
+
+	// 探测失败继续摘除
+	fail(a)
+	now = now.Add(30 * time.Second)
+	assert.Equal(t, []string{b, b}, nextN(t, wrr, 2))
+
+	// 探测成功后恢复
+	now = now.Add(30 * time.Second)
+	assert.Contains(t, nextN(t, wrr, 2), a)
+	assert.ElementsMatch(t, []string{a, b}, nextN(t, wrr, 2))
+
+	// 全部摘除时忽略摘除状态
+	fail(a)
+	fail(a)
+	fail(b)
+	fail(b)
+	u, err := wrr.Next()
+	assert.NoError(t, err)
+	assert.NotEmpty(t, u)
+
+	// 不属于该负载均衡的域名忽略
+	wrr.Feedback(&resty.RequestFeedback{Success: false})
+}
+
+func TestClientLoadBalancerType(t *testing.T) {
+	var healthyHits, brokenHits atomic.Int32
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		healthyHits.Add(1)
+		_, _ = w.Write([]byte("ok"))
+	}))
+	broken := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		brokenHits.Add(1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	t.Cleanup(func() {
+		healthy.Close()
+		broken.Close()
+	})
+
+	client := &ClientConf{
+		Service:          "lb",
+		Domains:          []string{healthy.URL, broken.URL},
+		RetryTimes:       -1,
+		LoadBalancerType: LoadBalancerLeastConn,
+		Ejection:         &EjectionPolicy{MaxFailures: 1, Duration: time.Minute},
+	}
+	for i := 0; i < 10; i++ {
+		_, _ = client.Get(newMetricsTestContext(), RequestOptions{Path: "/"})
+	}
+	// broken 第一次失败后被摘除
+	assert.Equal(t, int32(1), brokenHits.Load())
+	assert.Equal(t, int32(9), healthyHits.Load())
+
+	bad := &ClientConf{Domains: []string{healthy.URL}, LoadBalancerType: "random"}
+	_, err := bad.Get(newMetricsTestContext(), RequestOptions{Path: "/"})
+	assert.ErrorContains(t, err, "unknown load balancer type")
+}
+
+func TestClientWeights(t *testing.T) {
+	var aHits, bHits atomic.Int32
+	a := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { aHits.Add(1) }))
+	b := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { bHits.Add(1) }))
+	t.Cleanup(func() {
+		a.Close()
+		b.Close()
+	})
+	client := &ClientConf{
+		Service:          "lb-weighted",
+		Domains:          []string{a.URL, b.URL},
+		RetryTimes:       -1,
+		LoadBalancerType: LoadBalancerWeightedRoundRobin,
+		Weights:          map[string]int{a.URL: 4},
+	}
+	for i := 0; i < 10; i++ {
+		_, err := client.Get(newMetricsTestContext(), RequestOptions{Path: "/"})
+		require.NoError(t, err)
+	}
+	assert.Equal(t, int32(8), aHits.Load())
+	assert.Equal(t, int32(2), bHits.Load())
+}
@@ -0,0 +1,135 @@
+// Package http -----------------------------
+// @file      : metrics.go
+// @author    : xiangtao
+// @contact   : xiangtao1994@gmail.com
+// @time      : 2025/9/13 16:30
+// Description: 出站HTTP请求的Prometheus指标
+// -------------------------------------------
+package http
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// 状态码按类别记录，避免标签基数膨胀
+const statusClassErr = "err"
+
+// 响应缓存的结果
+const (
+	cacheResultHit         = "hit"
+	cacheResultMiss        = "miss"
+	cacheResultRevalidated = "revalidated"
+)
+
+var (
+	clientReqCount = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "http_client_requests_total",
+			Help: "Total number of outbound HTTP requests.",
+		}, []string{"service", "method", "status"},
+	)
+
+	clientReqDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name: "http_client_request_duration_seconds",
+			Help: "Outbound HTTP request latencies in seconds, including retries.",
+		}, []string{"service", "method"},
+	)
+
+	clientRetries = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "http_client_retries_total",
+			Help: "Total number of outbound HTTP request retries.",
+		}, []string{"service"},
+	)
+
+	clientCircuitState = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "http_client_circuit_state",
+			Help: "Circuit breaker state of outbound HTTP clients (0 closed, 1 half-open, 2 open).",
+		}, []string{"service"},
+	)
+
+	clientHedges = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "http_client_hedges_total",
+			Help: "Total number of hedged outbound HTTP requests, labeled by whether the hedge won.",
+		}, []string{"service", "won"},
+	)
+
+	clientCacheRequests = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "http_client_cache_requests_total",
+			Help: "Total number of cacheable outbound HTTP requests, labeled by cache result (hit, miss, revalidated).",
+		}, []string{"service", "result"},
+	)
+
+	clientConnReuse = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "http_client_conn_reuse_total",
+			Help: "Total number of outbound HTTP requests that reused a pooled connection, only counted when HttpStat is enabled.",
+		}, []string{"service"},
+	)
+
+	clientDials = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "http_client_dial_total",
+			Help: "Total number of outbound HTTP requests that dialed a new connection, only counted when HttpStat is enabled.",
+		}, []string{"service"},
+	)
+
+	metricsCollector prometheus.Collector = clientCollector{}
+)
+
+type clientCollector struct{}
+
+func (clientCollector) Describe(ch chan<- *prometheus.Desc) {
+	clientReqCount.Describe(ch)
+	clientReqDuration.Describe(ch)
+	clientRetries.Describe(ch)
+	clientCircuitState.Describe(ch)
+	clientHedges.Describe(ch)
+	clientCacheRequests.Describe(ch)
+	clientConnReuse.Describe(ch)
+	clientDials.Describe(ch)
+}
+
+func (clientCollector) Collect(ch chan<- prometheus.Metric) {
+	clientReqCount.Collect(ch)
+	clientReqDuration.Collect(ch)
+	clientRetries.Collect(ch)
+	clientCircuitState.Collect(ch)
+	clientHedges.Collect(ch)
+	clientCacheRequests.Collect(ch)
+	clientConnReuse.Collect(ch)
+	clientDials.Collect(ch)
+}
+
+// MetricsCollector 返回出站请求指标，传给 middleware.RegistryMetrics 注册即可在 /metrics 中输出
+func MetricsCollector() prometheus.Collector {
+	return metricsCollector
+}
+
+// recordMetrics 记录一次调用，status 为0表示未拿到响应
+func (c *ClientConf) recordMetrics(method string, status int, attempts int, start time.Time) {
+	clientReqCount.WithLabelValues(c.Service, method, statusClass(status)).Inc()
+	clientReqDuration.WithLabelValues(c.Service, method).Observe(time.Since(start).Seconds())
+	if attempts > 1 {
+		clientRetries.WithLabelValues(c.Service).Add(float64(attempts - 1))
+	}
+}
+
+func statusClass(status int) string {
+	if status < 100 || status > 599 {
+		return statusClassErr
+	}
+	return strconv.Itoa(status/100) + "xx"
+}
+
+// recordCache 记录一次可缓存请求的缓存结果
+func (c *ClientConf) recordCache(result string) {
+	clientCacheRequests.WithLabelValues(c.Service, result).Inc()
+}
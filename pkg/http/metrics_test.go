@@ -0,0 +1,93 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func newMetricsTestContext() *gin.Context {
+	ctx, _ := gin.CreateTestContext(nil)
+	ctx.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+	return ctx
+}
+
+func TestClientMetrics(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	assert.NoError(t, registry.Register(MetricsCollector()))
+
+	server := httptest.NewServer(http.HandlerFunc(mockHandler))
+	defer server.Close()
+	client := &ClientConf{Service: "metrics-ok", Domain: server.URL, Timeout: 2 * time.Second}
+
+	ctx := newMetricsTestContext()
+	_, err := client.Get(ctx, RequestOptions{Path: "/ok"})
+	assert.NoError(t, err)
+	_, err = client.Get(ctx, RequestOptions{Path: "/ok"})
+	assert.NoError(t, err)
+	_, err = client.Get(ctx, RequestOptions{Path: "/missing"})
+	assert.NoError(t, err)
+
+	assert.Equal(t, float64(2), testutil.ToFloat64(clientReqCount.WithLabelValues("metrics-ok", http.MethodGet, "2xx")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(clientReqCount.WithLabelValues("metrics-ok", http.MethodGet, "4xx")))
+
+	// 抓取注册表，确认指标已暴露
+	body := scrapeMetrics(registry)
+	assert.Contains(t, body, `http_client_requests_total{method="GET",service="metrics-ok",status="2xx"} 2`)
+	assert.Contains(t, body, `http_client_requests_total{method="GET",service="metrics-ok",status="4xx"} 1`)
+	assert.Contains(t, body, `http_client_request_duration_seconds_count{method="GET",service="metrics-ok"} 3`)
+}
+
+func TestClientMetricsRetry(t *testing.T) {
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+	client := &ClientConf{
+		Service:          "metrics-retry",
+		Domain:           server.URL,
+		Timeout:          2 * time.Second,
+		RetryTimes:       2,
+		RetryWaitTime:    time.Millisecond,
+		RetryMaxWaitTime: 5 * time.Millisecond,
+	}
+
+	_, _ = client.Get(newMetricsTestContext(), RequestOptions{Path: "/"})
+
+	assert.Equal(t, int32(3), hits.Load())
+	assert.Equal(t, float64(2), testutil.ToFloat64(clientRetries.WithLabelValues("metrics-retry")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(clientReqCount.WithLabelValues("metrics-retry", http.MethodGet, "5xx")))
+}
+
+func TestClientMetricsError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(mockHandler))
+	server.Close()
+	client := &ClientConf{Service: "metrics-err", Domain: server.URL, Timeout: time.Second, RetryTimes: -1}
+
+	_, err := client.Get(newMetricsTestContext(), RequestOptions{Path: "/ok"})
+	assert.Error(t, err)
+	assert.Equal(t, float64(1), testutil.ToFloat64(clientReqCount.WithLabelValues("metrics-err", http.MethodGet, "err")))
+}
+
+func TestStatusClass(t *testing.T) {
+	assert.Equal(t, "2xx", statusClass(204))
+	assert.Equal(t, "3xx", statusClass(302))
+	assert.Equal(t, "5xx", statusClass(503))
+	assert.Equal(t, "err", statusClass(0))
+}
+
+func scrapeMetrics(registry *prometheus.Registry) string {
+	w := httptest.NewRecorder()
+	promhttp.HandlerFor(registry, promhttp.HandlerOpts{}).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	return w.Body.String()
+}
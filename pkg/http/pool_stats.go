@@ -0,0 +1,157 @@
+// Package http -----------------------------
+// @file      : pool_stats.go
+// @author    : xiangtao
+// @contact   : xiangtao1994@gmail.com
+// @time      : 2025/9/14 17:50
+// Description: 连接池统计，开启 HttpStat 后通过 httptrace 统计连接复用与新建
+// -------------------------------------------
+package http
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/xiangtao94/golib/pkg/zlog"
+)
+
+// 复用率低于 MinReuseRatio 时，最多每隔 poolStatLogInterval 打印一次 debug 日志
+var poolStatLogInterval = time.Minute
+
+// 默认的最低连接复用率
+const defaultMinReuseRatio = 0.5
+
+// PoolStats 连接池统计，Reused、Dialed 为累计值
+type PoolStats struct {
+	Reused   int64 // 复用已有连接的请求数
+	Dialed   int64 // 新建连接的请求数
+	InFlight int64 // 正在进行的请求数
+	Open     int64 // 当前打开的连接数，使用自定义 Transport 时不统计，为0
+	Idle     int64 // 空闲连接数，按 Open - InFlight 估算（HTTP/1.1 一个请求占用一个连接）
+}
+
+// poolStats 由 statTransport 在每次请求时更新
+type poolStats struct {
+	service       string
+	minReuseRatio float64
+	countOpen     bool
+
+	reused   atomic.Int64
+	dialed   atomic.Int64
+	inFlight atomic.Int64
+	open     atomic.Int64
+
+	mu         sync.Mutex
+	lastLog    time.Time
+	lastReused int64
+	lastDialed int64
+}
+
+func newPoolStats(service string, minReuseRatio float64) *poolStats {
+	if minReuseRatio <= 0 {
+		minReuseRatio = defaultMinReuseRatio
+	}
+	return &poolStats{service: service, minReuseRatio: minReuseRatio, lastLog: time.Now()}
+}
+
+// PoolStats 返回连接池统计，未开启 HttpStat 时返回零值
+func (c *ClientConf) PoolStats() PoolStats {
+	p := c.pool
+	if p == nil {
+		return PoolStats{}
+	}
+	s := PoolStats{
+		Reused:   p.reused.Load(),
+		Dialed:   p.dialed.Load(),
+		InFlight: p.inFlight.Load(),
+		Open:     p.open.Load(),
+	}
+	if p.countOpen {
+		s.Idle = max(s.Open-s.InFlight, 0)
+	}
+	return s
+}
+
+// wrap 包装 Transport；默认 Transport 同时统计打开的连接数
+func (p *poolStats) wrap(rt http.RoundTripper) http.RoundTripper {
+	if t, ok := rt.(*http.Transport); ok && t.DialContext == nil && t.DialTLSContext == nil {
+		dialer := &net.Dialer{}
+		t.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			conn, err := dialer.DialContext(ctx, network, addr)
+			if err != nil {
+				return nil, err
+			}
+			p.open.Add(1)
+			return &countedConn{Conn: conn, pool: p}, nil
+		}
+		p.countOpen = true
+	}
+	return &statTransport{next: rt, pool: p}
+}
+
+// countedConn 关闭时减少打开的连接数
+type countedConn struct {
+	net.Conn
+	pool   *poolStats
+	closed atomic.Bool
+}
+
+func (c *countedConn) Close() error {
+	if c.closed.CompareAndSwap(false, true) {
+		c.pool.open.Add(-1)
+	}
+	return c.Conn.Close()
+}
+
+type statTransport struct {
+	next http.RoundTripper
+	pool *poolStats
+}
+
+func (t *statTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	p := t.pool
+	p.inFlight.Add(1)
+	defer p.inFlight.Add(-1)
+	// 与 resty 的 EnableTrace 同时使用时，httptrace 会依次调用两者的回调
+	trace := &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			if info.Reused {
+				p.reused.Add(1)
+				clientConnReuse.WithLabelValues(p.service).Inc()
+			} else {
+				p.dialed.Add(1)
+				clientDials.WithLabelValues(p.service).Inc()
+			}
+		},
+	}
+	resp, err := t.next.RoundTrip(req.WithContext(httptrace.WithClientTrace(req.Context(), trace)))
+	p.logLowReuse()
+	return resp, err
+}
+
+// logLowReuse 统计上次检查以来的复用率，低于阈值时打印 debug 日志，通常说明 MaxIdleConnsPerHost 过小或下游关闭了 keep-alive
+func (p *poolStats) logLowReuse() {
+	p.mu.Lock()
+	if time.Since(p.lastLog) < poolStatLogInterval {
+		p.mu.Unlock()
+		return
+	}
+	reused, dialed := p.reused.Load(), p.dialed.Load()
+	deltaReused, deltaDialed := reused-p.lastReused, dialed-p.lastDialed
+	p.lastLog, p.lastReused, p.lastDialed = time.Now(), reused, dialed
+	p.mu.Unlock()
+
+	total := deltaReused + deltaDialed
+	if total == 0 {
+		return
+	}
+	ratio := float64(deltaReused) / float64(total)
+	if ratio < p.minReuseRatio {
+		zlog.Debugf(nil, "http client %s connection reuse ratio %.2f below %.2f, reused: %d, dialed: %d, open: %d, inFlight: %d",
+			p.service, ratio, p.minReuseRatio, deltaReused, deltaDialed, p.open.Load(), p.inFlight.Load())
+	}
+}
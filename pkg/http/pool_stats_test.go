@@ -0,0 +1,67 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_PoolStats(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(mockHandler))
+	defer server.Close()
+	client := &ClientConf{Service: "pool-keepalive", Domain: server.URL, RetryTimes: -1, HttpStat: true}
+	defer client.Close()
+	ctx := newMetricsTestContext()
+
+	_, err := client.Get(ctx, RequestOptions{Path: "/ok"})
+	require.NoError(t, err)
+	assert.Equal(t, PoolStats{Dialed: 1, Open: 1, Idle: 1}, client.PoolStats())
+
+	// keep-alive 时第二次请求复用连接
+	_, err = client.Get(ctx, RequestOptions{Path: "/ok"})
+	require.NoError(t, err)
+	assert.Equal(t, PoolStats{Reused: 1, Dialed: 1, Open: 1, Idle: 1}, client.PoolStats())
+	assert.Equal(t, float64(1), testutil.ToFloat64(clientConnReuse.WithLabelValues("pool-keepalive")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(clientDials.WithLabelValues("pool-keepalive")))
+}
+
+func TestClient_PoolStatsKeepAliveDisabled(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(mockHandler))
+	defer server.Close()
+	client := &ClientConf{
+		Service:    "pool-no-keepalive",
+		Domain:     server.URL,
+		RetryTimes: -1,
+		HttpStat:   true,
+		Transport:  &http.Transport{DisableKeepAlives: true},
+	}
+	ctx := newMetricsTestContext()
+
+	interval := poolStatLogInterval
+	poolStatLogInterval = 0
+	defer func() { poolStatLogInterval = interval }()
+	for range 2 {
+		_, err := client.Get(ctx, RequestOptions{Path: "/ok"})
+		require.NoError(t, err)
+	}
+	stats := client.PoolStats()
+	assert.Equal(t, int64(0), stats.Reused)
+	assert.Equal(t, int64(2), stats.Dialed)
+	assert.Equal(t, float64(2), testutil.ToFloat64(clientDials.WithLabelValues("pool-no-keepalive")))
+	// 复用率检查已处理到最新的计数
+	assert.Equal(t, int64(2), client.pool.lastDialed)
+}
+
+func TestClient_PoolStatsDisabled(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(mockHandler))
+	defer server.Close()
+	client := &ClientConf{Service: "pool-disabled", Domain: server.URL, RetryTimes: -1}
+
+	_, err := client.Get(newMetricsTestContext(), RequestOptions{Path: "/ok"})
+	require.NoError(t, err)
+	assert.Equal(t, PoolStats{}, client.PoolStats())
+}
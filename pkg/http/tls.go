@@ -0,0 +1,65 @@
+// Package http -----------------------------
+// @file      : tls.go
+// @author    : xiangtao
+// @contact   : xiangtao1994@gmail.com
+// @time      : 2025/9/14 18:00
+// Description: 客户端 TLS 配置，支持自定义 CA 和双向 TLS（客户端证书）
+// -------------------------------------------
+package http
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+)
+
+// TLSConf 客户端 TLS 配置，文件均为 PEM 格式
+type TLSConf struct {
+	CertFile           string `yaml:"certFile"`           // 客户端证书，双向 TLS 时与 KeyFile 同时配置
+	KeyFile            string `yaml:"keyFile"`            // 客户端私钥
+	CAFile             string `yaml:"caFile"`             // 校验服务端证书的 CA，为空时使用系统根证书
+	InsecureSkipVerify bool   `yaml:"insecureSkipVerify"` // 不校验服务端证书，仅用于测试环境
+}
+
+// tlsConfig 在 base 的基础上加载证书，base 为自定义 Transport 原有的配置
+func (t *TLSConf) tlsConfig(base *tls.Config) (*tls.Config, error) {
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12}
+	if base != nil {
+		cfg = base.Clone()
+	}
+	if (t.CertFile == "") != (t.KeyFile == "") {
+		return nil, errors.New("tls certFile and keyFile must be set together")
+	}
+	if t.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(t.CertFile, t.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("load tls client certificate: %w", err)
+		}
+		cfg.Certificates = append(cfg.Certificates, cert)
+	}
+	if t.CAFile != "" {
+		pem, err := os.ReadFile(t.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("read tls ca file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificate found in tls ca file %s", t.CAFile)
+		}
+		cfg.RootCAs = pool
+	}
+	cfg.InsecureSkipVerify = t.InsecureSkipVerify
+	return cfg, nil
+}
+
+// transportTLSConfig 返回 Transport 原有的 TLS 配置，只支持 *http.Transport
+func transportTLSConfig(rt http.RoundTripper) (*tls.Config, error) {
+	t, ok := rt.(*http.Transport)
+	if !ok {
+		return nil, fmt.Errorf("tls conf requires *http.Transport, got %T", rt)
+	}
+	return t.TLSClientConfig, nil
+}
@@ -0,0 +1,139 @@
+package http
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	der  []byte
+}
+
+// newTestCert 生成证书，parent 为空时生成自签名的 CA
+func newTestCert(t *testing.T, parent *testCert, cn string, serial int64) *testCert {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	signer, signerKey := tmpl, key
+	if parent == nil {
+		tmpl.IsCA, tmpl.BasicConstraintsValid = true, true
+	} else {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, signer, &key.PublicKey, signerKey)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return &testCert{cert: cert, key: key, der: der}
+}
+
+// writeFiles 写入 PEM 格式的证书和私钥，返回文件路径
+func (c *testCert) writeFiles(t *testing.T, name string) (string, string) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, name+".crt"), filepath.Join(dir, name+".key")
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.der}), 0o600))
+	keyDER, err := x509.MarshalECPrivateKey(c.key)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600))
+	return certFile, keyFile
+}
+
+func (c *testCert) tlsCertificate() tls.Certificate {
+	return tls.Certificate{Certificate: [][]byte{c.der}, PrivateKey: c.key}
+}
+
+// newMTLSServer 要求客户端提供 ca 签发的证书
+func newMTLSServer(t *testing.T, ca *testCert) *httptest.Server {
+	serverCert := newTestCert(t, ca, "server", 2)
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.cert)
+	server := httptest.NewUnstartedServer(http.HandlerFunc(mockHandler))
+	server.TLS = &tls.Config{
+		Certificates: []tls.Certificate{serverCert.tlsCertificate()},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    pool,
+	}
+	server.StartTLS()
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestClient_MutualTLS(t *testing.T) {
+	ca := newTestCert(t, nil, "ca", 1)
+	server := newMTLSServer(t, ca)
+	caFile, _ := ca.writeFiles(t, "ca")
+	certFile, keyFile := newTestCert(t, ca, "client", 3).writeFiles(t, "client")
+	ctx := newMetricsTestContext()
+
+	client := &ClientConf{Service: "mtls", Domain: server.URL, RetryTimes: -1,
+		TLS: &TLSConf{CertFile: certFile, KeyFile: keyFile, CAFile: caFile}}
+	res, err := client.Get(ctx, RequestOptions{Path: "/ok"})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, res.HttpCode)
+
+	// 没有客户端证书时服务端拒绝握手
+	noCert := &ClientConf{Service: "mtls", Domain: server.URL, RetryTimes: -1, TLS: &TLSConf{CAFile: caFile}}
+	_, err = noCert.Get(ctx, RequestOptions{Path: "/ok"})
+	assert.Error(t, err)
+
+	// 不信任服务端证书
+	noCA := &ClientConf{Service: "mtls", Domain: server.URL, RetryTimes: -1, TLS: &TLSConf{CertFile: certFile, KeyFile: keyFile}}
+	_, err = noCA.Get(ctx, RequestOptions{Path: "/ok"})
+	assert.Error(t, err)
+
+	insecure := &ClientConf{Service: "mtls", Domain: server.URL, RetryTimes: -1,
+		TLS: &TLSConf{CertFile: certFile, KeyFile: keyFile, InsecureSkipVerify: true}}
+	_, err = insecure.Get(ctx, RequestOptions{Path: "/ok"})
+	assert.NoError(t, err)
+}
+
+func TestClient_TLSInvalidConf(t *testing.T) {
+	ca := newTestCert(t, nil, "ca", 1)
+	caFile, keyFile := ca.writeFiles(t, "ca")
+	ctx := newMetricsTestContext()
+
+	cases := map[string]*ClientConf{
+		"missing key":      {TLS: &TLSConf{CertFile: caFile}},
+		"missing file":     {TLS: &TLSConf{CAFile: filepath.Join(t.TempDir(), "none.pem")}},
+		"invalid ca":       {TLS: &TLSConf{CAFile: keyFile}},
+		"custom transport": {TLS: &TLSConf{InsecureSkipVerify: true}, Transport: roundTripFunc(nil)},
+	}
+	for name, client := range cases {
+		client.Domain = "https://127.0.0.1:1"
+		_, err := client.Get(ctx, RequestOptions{Path: "/ok"})
+		assert.Error(t, err, name)
+		// 初始化失败后再次调用返回同样的错误
+		_, err2 := client.Get(ctx, RequestOptions{Path: "/ok"})
+		assert.Equal(t, err, err2, name)
+	}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
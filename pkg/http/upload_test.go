@@ -0,0 +1,208 @@
+package http
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type uploadedPart struct {
+	fileName    string
+	contentType string
+	data        string
+}
+
+// readParts 读取上游收到的 multipart 请求，表单字段的 fileName 为空
+func readParts(t *testing.T, r *http.Request) map[string][]uploadedPart {
+	mr, err := r.MultipartReader()
+	require.NoError(t, err)
+	parts := map[string][]uploadedPart{}
+	for {
+		p, err := mr.NextPart()
+		if errors.Is(err, io.EOF) {
+			return parts
+		}
+		require.NoError(t, err)
+		data, err := io.ReadAll(p)
+		require.NoError(t, err)
+		parts[p.FormName()] = append(parts[p.FormName()], uploadedPart{
+			fileName:    p.FileName(),
+			contentType: p.Header.Get("Content-Type"),
+			data:        string(data),
+		})
+	}
+}
+
+// newInboundUpload 构造带上传文件的入站请求
+func newInboundUpload(t *testing.T) *gin.Context {
+	body := &bytes.Buffer{}
+	mw := multipart.NewWriter(body)
+	h := make(textproto.MIMEHeader)
+	h.Set("Content-Disposition", `form-data; name="avatar"; filename="me.png"`)
+	h.Set("Content-Type", "image/png")
+	part, err := mw.CreatePart(h)
+	require.NoError(t, err)
+	_, _ = part.Write([]byte("\x89PNG fake image"))
+	require.NoError(t, mw.Close())
+
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	ctx.Request = httptest.NewRequest(http.MethodPost, "/profile", body)
+	ctx.Request.Header.Set("Content-Type", mw.FormDataContentType())
+	return ctx
+}
+
+func TestClient_UploadFileReaders(t *testing.T) {
+	var received map[string][]uploadedPart
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = readParts(t, r)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	ctx := newInboundUpload(t)
+	fh, err := ctx.FormFile("avatar")
+	require.NoError(t, err)
+	avatar, err := FileReaderFromHeader(fh)
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "doc.txt")
+	require.NoError(t, os.WriteFile(path, []byte("from disk"), 0o644))
+
+	opts := RequestOptions{
+		Path:         "/upload",
+		Encode:       EncodeFile,
+		RequestBody:  map[string]string{"uid": "42"},
+		RequestFiles: map[string][]string{"doc": {path}},
+		RequestFileReaders: map[string][]FileReader{
+			"avatar": {avatar},
+			"meta":   {{FileName: "meta.json", ContentType: "application/json", Reader: strings.NewReader(`{"a":1}`)}},
+		},
+	}
+	client := &ClientConf{Service: "upload", Domain: server.URL}
+	res, err := client.Post(ctx, opts)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, res.HttpCode)
+
+	assert.Equal(t, []uploadedPart{{fileName: "me.png", contentType: "image/png", data: "\x89PNG fake image"}}, received["avatar"])
+	assert.Equal(t, []uploadedPart{{fileName: "meta.json", contentType: "application/json", data: `{"a":1}`}}, received["meta"])
+	require.Len(t, received["doc"], 1)
+	assert.Equal(t, "doc.txt", received["doc"][0].fileName)
+	assert.Equal(t, "from disk", received["doc"][0].data)
+	assert.Equal(t, "42", received["uid"][0].data)
+
+	// 日志只记录名称和大小
+	desc := describeMultipart(opts)
+	assert.Contains(t, desc, "uid=42")
+	assert.Contains(t, desc, "avatar=me.png(image/png, 15B)")
+	assert.Contains(t, desc, "meta=meta.json(application/json, unknown size)")
+	assert.Contains(t, desc, "doc="+path+"(9B)")
+}
+
+func TestClient_UploadFileReadersWithHooks(t *testing.T) {
+	var received map[string][]uploadedPart
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = readParts(t, r)
+	}))
+	defer server.Close()
+
+	client := &ClientConf{Service: "upload", Domain: server.URL,
+		OnBeforeRequest: []BeforeRequestHook{NewHMACSigner(HMACSignerConf{Secret: "s"})}}
+	ctx, _ := gin.CreateTestContext(nil)
+	_, err := client.Post(ctx, RequestOptions{Path: "/upload", Encode: EncodeFile, RequestFileReaders: map[string][]FileReader{
+		"meta": {{FileName: "meta.json", ContentType: "application/json", Reader: strings.NewReader(`{"a":1}`)}},
+	}})
+	require.NoError(t, err)
+	assert.Equal(t, []uploadedPart{{fileName: "meta.json", contentType: "application/json", data: `{"a":1}`}}, received["meta"])
+}
+
+// gatedReader 产生 total 字节，发送 gate 字节后等待上游开始接收，客户端整体缓冲请求体时会超时失败
+type gatedReader struct {
+	total, gate, sent int
+	started           <-chan struct{}
+}
+
+func (r *gatedReader) Read(p []byte) (int, error) {
+	if r.sent >= r.total {
+		return 0, io.EOF
+	}
+	if r.sent >= r.gate && r.started != nil {
+		select {
+		case <-r.started:
+			r.started = nil
+		case <-time.After(5 * time.Second):
+			return 0, errors.New("upstream did not receive data before the body was fully read")
+		}
+	}
+	n := min(len(p), r.total-r.sent)
+	for i := range p[:n] {
+		p[i] = 'x'
+	}
+	r.sent += n
+	return n, nil
+}
+
+func TestClient_UploadLargeReaderStreams(t *testing.T) {
+	const total = 32 << 20
+	started := make(chan struct{})
+	var size int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mr, err := r.MultipartReader()
+		require.NoError(t, err)
+		p, err := mr.NextPart()
+		require.NoError(t, err)
+		buf := make([]byte, 1)
+		_, err = io.ReadFull(p, buf)
+		require.NoError(t, err)
+		close(started)
+		n, _ := io.Copy(io.Discard, p)
+		size = n + 1
+	}))
+	defer server.Close()
+
+	client := &ClientConf{Service: "upload", Domain: server.URL, Timeout: 30 * time.Second, RetryTimes: -1}
+	ctx, _ := gin.CreateTestContext(nil)
+	res, err := client.Post(ctx, RequestOptions{Path: "/upload", Encode: EncodeFile, RequestFileReaders: map[string][]FileReader{
+		"blob": {{FileName: "blob.bin", ContentType: "application/octet-stream",
+			Reader: &gatedReader{total: total, gate: 1 << 20, started: started}}},
+	}})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, res.HttpCode)
+	assert.Equal(t, int64(total), size)
+}
+
+func TestClient_UploadPipedStream(t *testing.T) {
+	var received map[string][]uploadedPart
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = readParts(t, r)
+	}))
+	defer server.Close()
+
+	// 边生成边上传，数据不落盘也不在内存中完整保存
+	pr, pw := io.Pipe()
+	go func() {
+		for i := 0; i < 3; i++ {
+			_, _ = pw.Write([]byte("chunk;"))
+		}
+		_ = pw.Close()
+	}()
+	client := &ClientConf{Service: "upload", Domain: server.URL, RetryTimes: -1}
+	ctx, _ := gin.CreateTestContext(nil)
+	_, err := client.Post(ctx, RequestOptions{Path: "/upload", Encode: EncodeFile, RequestFileReaders: map[string][]FileReader{
+		"export": {{FileName: "export.csv", ContentType: "text/csv", Reader: pr}},
+	}})
+	require.NoError(t, err)
+	assert.Equal(t, []uploadedPart{{fileName: "export.csv", contentType: "text/csv", data: "chunk;chunk;chunk;"}}, received["export"])
+}
@@ -0,0 +1,93 @@
+// Package mcp -----------------------------
+// @file      : func_tool.go
+// @author    : xiangtao
+// @contact   : xiangtao1994@gmail.com
+// @time      : 2025/9/13 19:40
+// Description: 把普通函数注册为工具，处理函数中可直接使用 gin.Context
+// -------------------------------------------
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"github.com/xiangtao94/golib/pkg/zlog"
+)
+
+// AddToolFromFunc 把普通函数注册为工具，fn 收到当前请求的 gin.Context 和原始调用参数
+// inputSchema 支持 json.RawMessage、[]byte、string（JSON Schema 文本）、mcp.ToolInputSchema、map[string]any，为 nil 时不限制参数
+// 需要根据结构体生成 Schema 时使用 AddTypedTool
+// fn 返回 *mcp.CallToolResult 时原样返回，string 作为文本返回，其他值序列化为 JSON 文本；返回的错误作为工具错误结果返回给客户端
+func (h *Handler) AddToolFromFunc(name, description string, inputSchema any, fn func(ctx *gin.Context, args json.RawMessage) (any, error)) error {
+	tool, err := newFuncTool(name, description, inputSchema)
+	if err != nil {
+		return err
+	}
+	h.AddTool(tool, func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		c := requestGinContext(ctx)
+		args := json.RawMessage("{}")
+		if raw := req.GetRawArguments(); raw != nil {
+			data, err := json.Marshal(raw)
+			if err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("invalid arguments: %v", err)), nil
+			}
+			args = data
+		}
+		out, err := fn(c, args)
+		if err != nil {
+			zlog.Errorf(c, "mcp tool %s call error: %v", name, err)
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		if result, ok := out.(*mcp.CallToolResult); ok {
+			return result, nil
+		}
+		return textToolResult(out)
+	})
+	return nil
+}
+
+func newFuncTool(name, description string, inputSchema any) (mcp.Tool, error) {
+	switch schema := inputSchema.(type) {
+	case nil:
+		return mcp.NewTool(name, mcp.WithDescription(description)), nil
+	case mcp.ToolInputSchema:
+		tool := mcp.NewTool(name, mcp.WithDescription(description))
+		tool.InputSchema = schema
+		return tool, nil
+	case json.RawMessage:
+		return newRawSchemaTool(name, description, schema)
+	case []byte:
+		return newRawSchemaTool(name, description, schema)
+	case string:
+		return newRawSchemaTool(name, description, []byte(schema))
+	case map[string]any:
+		data, err := json.Marshal(schema)
+		if err != nil {
+			return mcp.Tool{}, fmt.Errorf("mcp tool %s marshal input schema error: %w", name, err)
+		}
+		return mcp.NewToolWithRawSchema(name, description, data), nil
+	default:
+		return mcp.Tool{}, fmt.Errorf("mcp tool %s unsupported input schema type %T", name, inputSchema)
+	}
+}
+
+func newRawSchemaTool(name, description string, schema []byte) (mcp.Tool, error) {
+	if !json.Valid(schema) {
+		return mcp.Tool{}, fmt.Errorf("mcp tool %s input schema is not valid JSON", name)
+	}
+	return mcp.NewToolWithRawSchema(name, description, json.RawMessage(schema)), nil
+}
+
+// requestGinContext 非 HTTP 传输（如 stdio）调用时没有 gin.Context，构造一个携带 ctx 的空 gin.Context
+func requestGinContext(ctx context.Context) *gin.Context {
+	if c, ok := GinContext(ctx); ok {
+		return c
+	}
+	req, _ := http.NewRequestWithContext(ctx, http.MethodPost, "/", http.NoBody)
+	return &gin.Context{Request: req}
+}
@@ -18,6 +18,8 @@ type Handler struct {
 	ServerOpts         []server.ServerOption
 	StreamableHTTPOpts []server.StreamableHTTPOption
 	BaseURL            string
+	EnableSSE          bool               // 同时注册旧版 SSE 传输的 BasePath/sse、BasePath/message 路由
+	SSEOpts            []server.SSEOption // SSE 服务器选项
 }
 
 // MCPHandlerOption 是配置MCPHandler的函数选项
@@ -28,6 +30,7 @@ func NewHandler(name, version string, opts ...MCPHandlerOption) *Handler {
 		BasePath:           "/mcp",
 		ServerOpts:         []server.ServerOption{},
 		StreamableHTTPOpts: []server.StreamableHTTPOption{},
+		SSEOpts:            []server.SSEOption{},
 	}
 	for _, opt := range opts {
 		opt(h)
@@ -70,6 +73,22 @@ func WitStreamableHTTPOptions(opts ...server.StreamableHTTPOption) MCPHandlerOpt
 	}
 }
 
+// WithSSE 启用 SSE 传输，兼容只支持 SSE 的客户端
+func WithSSE(opts ...server.SSEOption) MCPHandlerOption {
+	return func(h *Handler) {
+		h.EnableSSE = true
+		h.SSEOpts = append(h.SSEOpts, opts...)
+	}
+}
+
+// WithToolLogging 通过zlog记录每次工具调用的工具名、参数（超过1024字符截断）、结果大小和耗时
+// 通过 RegisterRoutes 注册时日志带上请求的 requestId
+func WithToolLogging() MCPHandlerOption {
+	return func(h *Handler) {
+		h.ServerOpts = append(h.ServerOpts, server.WithToolHandlerMiddleware(toolLogging))
+	}
+}
+
 // AddTool 向MCP服务器添加工具
 func (h *Handler) AddTool(tool mcp.Tool, handler server.ToolHandlerFunc) {
 	h.server.AddTool(tool, handler)
@@ -95,6 +114,48 @@ func (h *Handler) DeleteSessionTools(sessionID string, names ...string) error {
 	return h.server.DeleteSessionTools(sessionID, names...)
 }
 
+// AddResource 向MCP服务器添加资源
+// 资源、资源模板、提示词列表变化时，若声明了 listChanged 能力（server.WithResourceCapabilities、server.WithPromptCapabilities），
+// 底层服务器会通过 SendNotificationToAllClients 通知所有已初始化的会话
+func (h *Handler) AddResource(resource mcp.Resource, handler server.ResourceHandlerFunc) {
+	h.server.AddResource(resource, handler)
+}
+
+// AddResources 向MCP服务器添加多个资源
+func (h *Handler) AddResources(resources ...server.ServerResource) {
+	h.server.AddResources(resources...)
+}
+
+// DeleteResources 按URI删除资源
+func (h *Handler) DeleteResources(uris ...string) {
+	h.server.DeleteResources(uris...)
+}
+
+// AddResourceTemplate 向MCP服务器添加资源模板
+func (h *Handler) AddResourceTemplate(template mcp.ResourceTemplate, handler server.ResourceTemplateHandlerFunc) {
+	h.server.AddResourceTemplate(template, handler)
+}
+
+// AddResourceTemplates 向MCP服务器添加多个资源模板
+func (h *Handler) AddResourceTemplates(templates ...server.ServerResourceTemplate) {
+	h.server.AddResourceTemplates(templates...)
+}
+
+// AddPrompt 向MCP服务器添加提示词
+func (h *Handler) AddPrompt(prompt mcp.Prompt, handler server.PromptHandlerFunc) {
+	h.server.AddPrompt(prompt, handler)
+}
+
+// AddPrompts 向MCP服务器添加多个提示词
+func (h *Handler) AddPrompts(prompts ...server.ServerPrompt) {
+	h.server.AddPrompts(prompts...)
+}
+
+// DeletePrompts 按名称删除提示词
+func (h *Handler) DeletePrompts(names ...string) {
+	h.server.DeletePrompts(names...)
+}
+
 // SendNotificationToAllClients 向所有客户端发送通知
 func (h *Handler) SendNotificationToAllClients(method string, params map[string]any) {
 	h.server.SendNotificationToAllClients(method, params)
@@ -0,0 +1,138 @@
+package mcp
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/xiangtao94/golib/pkg/zlog"
+)
+
+type rpcResponse struct {
+	Result json.RawMessage `json:"result"`
+	Error  *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+func callMCP(t *testing.T, engine *gin.Engine, sessionID, body string) (rpcResponse, http.Header) {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/mcp", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json, text/event-stream")
+	req.Header.Set("Request-Id", "rid-mcp")
+	if sessionID != "" {
+		req.Header.Set("Mcp-Session-Id", sessionID)
+	}
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var resp rpcResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp), w.Body.String())
+	require.Nil(t, resp.Error)
+	return resp, w.Header()
+}
+
+func TestRegisterRoutesToolFlow(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	core, logs := observer.New(zapcore.InfoLevel)
+	old := toolLogger
+	toolLogger = func() *zap.Logger { return zap.New(core) }
+	t.Cleanup(func() { toolLogger = old })
+
+	h := NewHandler("test", "1.0.0", WithToolLogging())
+	err := h.AddToolFromFunc("greet", "greet someone", `{"type":"object","properties":{"name":{"type":"string"}},"required":["name"]}`,
+		func(c *gin.Context, args json.RawMessage) (any, error) {
+			var in struct {
+				Name string `json:"name"`
+			}
+			if err := json.Unmarshal(args, &in); err != nil {
+				return nil, err
+			}
+			if in.Name == "" {
+				return nil, errors.New("name is required")
+			}
+			return map[string]string{"greeting": "hello " + in.Name, "requestId": zlog.GetRequestID(c)}, nil
+		})
+	require.NoError(t, err)
+	engine := gin.New()
+	h.RegisterRoutes(engine)
+
+	_, header := callMCP(t, engine, "", `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-03-26","capabilities":{},"clientInfo":{"name":"test","version":"1.0.0"}}}`)
+	sessionID := header.Get("Mcp-Session-Id")
+	require.NotEmpty(t, sessionID)
+
+	resp, _ := callMCP(t, engine, sessionID, `{"jsonrpc":"2.0","id":2,"method":"tools/list"}`)
+	var list struct {
+		Tools []struct {
+			Name        string          `json:"name"`
+			InputSchema json.RawMessage `json:"inputSchema"`
+		} `json:"tools"`
+	}
+	require.NoError(t, json.Unmarshal(resp.Result, &list))
+	require.Len(t, list.Tools, 1)
+	assert.Equal(t, "greet", list.Tools[0].Name)
+	assert.Contains(t, string(list.Tools[0].InputSchema), `"required":["name"]`)
+
+	resp, _ = callMCP(t, engine, sessionID, `{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"greet","arguments":{"name":"gopher"}}}`)
+	var result struct {
+		IsError bool `json:"isError"`
+		Content []struct {
+			Text string `json:"text"`
+		} `json:"content"`
+	}
+	require.NoError(t, json.Unmarshal(resp.Result, &result))
+	assert.False(t, result.IsError)
+	require.Len(t, result.Content, 1)
+	assert.JSONEq(t, `{"greeting":"hello gopher","requestId":"rid-mcp"}`, result.Content[0].Text)
+
+	// 工具日志带上请求的 requestId、工具名和耗时
+	entries := logs.FilterMessage("mcp tool call").AllUntimed()
+	require.Len(t, entries, 1)
+	fields := entries[0].ContextMap()
+	assert.Equal(t, "rid-mcp", fields["requestId"])
+	assert.Equal(t, "greet", fields["tool"])
+	assert.Contains(t, fields, "cost")
+
+	resp, _ = callMCP(t, engine, sessionID, `{"jsonrpc":"2.0","id":4,"method":"tools/call","params":{"name":"greet","arguments":{}}}`)
+	require.NoError(t, json.Unmarshal(resp.Result, &result))
+	assert.True(t, result.IsError)
+	assert.Equal(t, "name is required", result.Content[0].Text)
+}
+
+func TestAddToolFromFuncSchema(t *testing.T) {
+	h := NewHandler("test", "1.0.0")
+	fn := func(c *gin.Context, args json.RawMessage) (any, error) { return "ok", nil }
+	assert.NoError(t, h.AddToolFromFunc("no_schema", "", nil, fn))
+	assert.NoError(t, h.AddToolFromFunc("map_schema", "", map[string]any{"type": "object"}, fn))
+	assert.Error(t, h.AddToolFromFunc("bad_json", "", `{"type":`, fn))
+	assert.Error(t, h.AddToolFromFunc("bad_type", "", 123, fn))
+}
+
+func TestRegisterRoutesSSE(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	h := NewHandler("test", "1.0.0", WithSSE())
+	engine := gin.New()
+	h.RegisterRoutes(engine)
+	server := httptest.NewServer(engine)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/mcp/sse")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+	buf := make([]byte, 256)
+	n, _ := resp.Body.Read(buf)
+	assert.Contains(t, string(buf[:n]), "event: endpoint")
+	assert.Contains(t, string(buf[:n]), "/mcp/message?sessionId=")
+}
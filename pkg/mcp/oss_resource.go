@@ -0,0 +1,113 @@
+// Package mcp -----------------------------
+// @file      : oss_resource.go
+// @author    : xiangtao
+// @contact   : xiangtao1994@gmail.com
+// @time      : 2025/9/13 20:10
+// Description: 把对象存储中的对象作为MCP资源提供，URI 格式为 oss://bucket/object
+// -------------------------------------------
+package mcp
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"path"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+
+	"github.com/xiangtao94/golib/pkg/oss"
+	"github.com/xiangtao94/golib/pkg/zlog"
+)
+
+const (
+	objectURIScheme = "oss://"
+	// maxObjectResourceSize 单个资源读取上限，避免把大文件整体读入内存返回给模型
+	maxObjectResourceSize = 10 << 20
+)
+
+// ObjectURI 返回对象在MCP资源中的URI
+func ObjectURI(bucket, object string) string {
+	return objectURIScheme + bucket + "/" + object
+}
+
+// AddObjectResource 把对象存储中的单个对象注册为资源，URI 为 oss://bucket/object，资源名为对象路径
+func (h *Handler) AddObjectResource(storage oss.ObjectStorage, bucket, object, description string) {
+	opts := []mcp.ResourceOption{mcp.WithResourceDescription(description)}
+	if mimeType := mime.TypeByExtension(path.Ext(object)); mimeType != "" {
+		opts = append(opts, mcp.WithMIMEType(mimeType))
+	}
+	h.AddResource(mcp.NewResource(ObjectURI(bucket, object), object, opts...), objectResourceHandler(storage, bucket))
+}
+
+// AddObjectResourceTemplate 注册资源模板 oss://bucket/{+object}，客户端可按对象路径读取 bucket 下的任意对象
+// 模板不会出现在 resources/list 中，需要列出时使用 AddObjectResource 逐个注册
+func (h *Handler) AddObjectResourceTemplate(storage oss.ObjectStorage, bucket, description string) {
+	template := mcp.NewResourceTemplate(objectURIScheme+bucket+"/{+object}", bucket, mcp.WithTemplateDescription(description))
+	h.AddResourceTemplate(template, server.ResourceTemplateHandlerFunc(objectResourceHandler(storage, bucket)))
+}
+
+// objectResourceHandler 读取 bucket 下的对象，文本类型以文本返回，其他类型以 base64 返回
+func objectResourceHandler(storage oss.ObjectStorage, bucket string) server.ResourceHandlerFunc {
+	prefix := ObjectURI(bucket, "")
+	return func(ctx context.Context, req mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+		uri := req.Params.URI
+		object := strings.TrimPrefix(uri, prefix)
+		if object == uri || object == "" {
+			return nil, fmt.Errorf("resource %s is not an object of bucket %s", uri, bucket)
+		}
+		c := requestGinContext(ctx)
+		reader, info, err := storage.DownloadFile(c, bucket, object)
+		if err != nil {
+			zlog.Errorf(c, "mcp read resource %s error: %v", uri, err)
+			return nil, fmt.Errorf("read resource %s error: %w", uri, err)
+		}
+		defer reader.Close()
+		data, err := io.ReadAll(io.LimitReader(reader, maxObjectResourceSize+1))
+		if err != nil {
+			return nil, fmt.Errorf("read resource %s error: %w", uri, err)
+		}
+		if len(data) > maxObjectResourceSize {
+			return nil, fmt.Errorf("resource %s exceeds %d bytes", uri, maxObjectResourceSize)
+		}
+
+		mimeType := ""
+		if info != nil {
+			mimeType = info.ContentType
+		}
+		if mimeType == "" || mimeType == "application/octet-stream" {
+			if t := mime.TypeByExtension(path.Ext(object)); t != "" {
+				mimeType = t
+			}
+		}
+		if isTextMIMEType(mimeType) {
+			return []mcp.ResourceContents{mcp.TextResourceContents{URI: uri, MIMEType: mimeType, Text: string(data)}}, nil
+		}
+		if mimeType == "" {
+			mimeType = "application/octet-stream"
+		}
+		return []mcp.ResourceContents{mcp.BlobResourceContents{
+			URI:      uri,
+			MIMEType: mimeType,
+			Blob:     base64.StdEncoding.EncodeToString(data),
+		}}, nil
+	}
+}
+
+func isTextMIMEType(mimeType string) bool {
+	mediaType, _, err := mime.ParseMediaType(mimeType)
+	if err != nil {
+		return false
+	}
+	if strings.HasPrefix(mediaType, "text/") {
+		return true
+	}
+	switch mediaType {
+	case "application/json", "application/xml", "application/yaml", "application/x-yaml", "application/javascript":
+		return true
+	}
+	return strings.HasSuffix(mediaType, "+json") || strings.HasSuffix(mediaType, "+xml")
+}
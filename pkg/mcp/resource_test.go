@@ -0,0 +1,142 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xiangtao94/golib/pkg/oss"
+)
+
+// fakeStorage 只实现 DownloadFile，其余方法调用会 panic
+type fakeStorage struct {
+	oss.ObjectStorage
+	objects map[string]fakeObject
+}
+
+type fakeObject struct {
+	data        []byte
+	contentType string
+}
+
+func (f *fakeStorage) DownloadFile(_ *gin.Context, bucket, object string) (io.ReadCloser, *oss.DownloadInfo, error) {
+	obj, ok := f.objects[bucket+"/"+object]
+	if !ok {
+		return nil, nil, errors.New("object not found")
+	}
+	return io.NopCloser(bytes.NewReader(obj.data)), &oss.DownloadInfo{
+		ObjectName:  object,
+		Size:        int64(len(obj.data)),
+		ContentType: obj.contentType,
+	}, nil
+}
+
+func newInProcessClient(t *testing.T, h *Handler) *client.Client {
+	t.Helper()
+	c, err := client.NewInProcessClient(h.GetServer())
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = c.Close() })
+	ctx := context.Background()
+	require.NoError(t, c.Start(ctx))
+	req := mcp.InitializeRequest{}
+	req.Params.ProtocolVersion = mcp.LATEST_PROTOCOL_VERSION
+	req.Params.ClientInfo = mcp.Implementation{Name: "test", Version: "1.0.0"}
+	_, err = c.Initialize(ctx, req)
+	require.NoError(t, err)
+	return c
+}
+
+func TestObjectResources(t *testing.T) {
+	storage := &fakeStorage{objects: map[string]fakeObject{
+		"docs/guide/intro.md": {data: []byte("# intro"), contentType: "text/markdown"},
+		"docs/logo.png":       {data: []byte{0x89, 'P', 'N', 'G'}, contentType: "image/png"},
+		"docs/conf.json":      {data: []byte(`{"a":1}`), contentType: "application/octet-stream"},
+	}}
+	h := NewHandler("test", "1.0.0")
+	h.AddObjectResource(storage, "docs", "guide/intro.md", "使用说明")
+	h.AddObjectResourceTemplate(storage, "docs", "文档库")
+	c := newInProcessClient(t, h)
+	ctx := context.Background()
+
+	list, err := c.ListResources(ctx, mcp.ListResourcesRequest{})
+	require.NoError(t, err)
+	require.Len(t, list.Resources, 1)
+	assert.Equal(t, "oss://docs/guide/intro.md", list.Resources[0].URI)
+	assert.Equal(t, "guide/intro.md", list.Resources[0].Name)
+	assert.Equal(t, "使用说明", list.Resources[0].Description)
+
+	templates, err := c.ListResourceTemplates(ctx, mcp.ListResourceTemplatesRequest{})
+	require.NoError(t, err)
+	require.Len(t, templates.ResourceTemplates, 1)
+	assert.Equal(t, "oss://docs/{+object}", templates.ResourceTemplates[0].URITemplate.Raw())
+
+	read := func(uri string) mcp.ResourceContents {
+		req := mcp.ReadResourceRequest{}
+		req.Params.URI = uri
+		res, err := c.ReadResource(ctx, req)
+		require.NoError(t, err)
+		require.Len(t, res.Contents, 1)
+		return res.Contents[0]
+	}
+
+	text, ok := read("oss://docs/guide/intro.md").(mcp.TextResourceContents)
+	require.True(t, ok)
+	assert.Equal(t, "# intro", text.Text)
+	assert.Equal(t, "text/markdown", text.MIMEType)
+
+	// 通过模板读取，content type 为 octet-stream 时按扩展名识别
+	text, ok = read("oss://docs/conf.json").(mcp.TextResourceContents)
+	require.True(t, ok)
+	assert.Equal(t, `{"a":1}`, text.Text)
+	assert.Equal(t, "application/json", text.MIMEType)
+
+	blob, ok := read("oss://docs/logo.png").(mcp.BlobResourceContents)
+	require.True(t, ok)
+	assert.Equal(t, "image/png", blob.MIMEType)
+	assert.Equal(t, base64.StdEncoding.EncodeToString([]byte{0x89, 'P', 'N', 'G'}), blob.Blob)
+
+	req := mcp.ReadResourceRequest{}
+	req.Params.URI = "oss://docs/missing.txt"
+	_, err = c.ReadResource(ctx, req)
+	assert.Error(t, err)
+
+	h.DeleteResources("oss://docs/guide/intro.md")
+	list, err = c.ListResources(ctx, mcp.ListResourcesRequest{})
+	require.NoError(t, err)
+	assert.Empty(t, list.Resources)
+}
+
+func TestPrompts(t *testing.T) {
+	h := NewHandler("test", "1.0.0")
+	h.AddPrompt(mcp.NewPrompt("review", mcp.WithPromptDescription("代码评审"), mcp.WithArgument("lang", mcp.RequiredArgument())),
+		func(ctx context.Context, req mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+			return mcp.NewGetPromptResult("代码评审", []mcp.PromptMessage{
+				mcp.NewPromptMessage(mcp.RoleUser, mcp.NewTextContent("review this "+req.Params.Arguments["lang"]+" code")),
+			}), nil
+		})
+	c := newInProcessClient(t, h)
+	ctx := context.Background()
+
+	req := mcp.GetPromptRequest{}
+	req.Params.Name = "review"
+	req.Params.Arguments = map[string]string{"lang": "go"}
+	res, err := c.GetPrompt(ctx, req)
+	require.NoError(t, err)
+	require.Len(t, res.Messages, 1)
+	content, ok := res.Messages[0].Content.(mcp.TextContent)
+	require.True(t, ok)
+	assert.Equal(t, "review this go code", content.Text)
+
+	h.DeletePrompts("review")
+	_, err = c.GetPrompt(ctx, req)
+	assert.Error(t, err)
+}
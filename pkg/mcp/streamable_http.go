@@ -7,7 +7,9 @@
 package mcp
 
 import (
+	"context"
 	"fmt"
+	"net/http"
 	"slices"
 
 	"github.com/gin-gonic/gin"
@@ -15,24 +17,76 @@ import (
 	"github.com/xiangtao94/golib/pkg/zlog"
 )
 
+type ginContextKey struct{}
+
 // Register 注册MCP路由到Gin引擎
+// Deprecated: 使用 RegisterRoutes，支持为MCP路由添加中间件
 func (h *Handler) Register(r *gin.Engine) {
-	// 创建SSE服务器选项
+	h.RegisterRoutes(r)
+}
+
+// RegisterRoutes 在 BasePath 下注册 streamable HTTP 路由（POST/GET/DELETE），启用 SSE 时同时注册 BasePath/sse、BasePath/message
+// middlewares 只作用于MCP路由，如鉴权、access日志；工具处理函数中通过 GinContext 获取当前请求的 *gin.Context
+func (h *Handler) RegisterRoutes(engine *gin.Engine, middlewares ...gin.HandlerFunc) {
 	shOpts := slices.Clone(h.StreamableHTTPOpts)
+	shOpts = append(shOpts,
+		server.WithEndpointPath(h.BasePath),
+		server.WithLogger(newLogger()),
+		server.WithHTTPContextFunc(h.httpContext),
+	)
+	streamServer := server.NewStreamableHTTPServer(h.server, shOpts...)
+
+	serve := func(c *gin.Context) {
+		c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), ginContextKey{}, c))
+		streamServer.ServeHTTP(c.Writer, c.Request)
+	}
+	group := engine.Group(h.BasePath, middlewares...)
+	group.POST("", serve)
+	group.GET("", serve)
+	group.DELETE("", serve)
+
+	if h.EnableSSE {
+		h.registerSSERoutes(group)
+	}
+}
+
+// registerSSERoutes SSE 传输在 message 请求返回后异步执行工具，注入的是 gin.Context 的副本
+func (h *Handler) registerSSERoutes(group *gin.RouterGroup) {
+	sseOpts := slices.Clone(h.SSEOpts)
+	sseOpts = append(sseOpts,
+		server.WithStaticBasePath(h.BasePath),
+		server.WithSSEContextFunc(h.httpContext),
+	)
+	if h.BaseURL != "" {
+		sseOpts = append(sseOpts, server.WithBaseURL(h.BaseURL))
+	}
+	sseServer := server.NewSSEServer(h.server, sseOpts...)
+
+	wrap := func(handler http.Handler) gin.HandlerFunc {
+		return func(c *gin.Context) {
+			c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), ginContextKey{}, c.Copy()))
+			handler.ServeHTTP(c.Writer, c.Request)
+		}
+	}
+	group.GET("/sse", wrap(sseServer.SSEHandler()))
+	group.POST("/message", wrap(sseServer.MessageHandler()))
+}
+
+// httpContext 把 gin.Context 带入MCP请求上下文，再执行 WithContextFunc 设置的函数
+func (h *Handler) httpContext(ctx context.Context, r *http.Request) context.Context {
+	if c, ok := r.Context().Value(ginContextKey{}).(*gin.Context); ok {
+		ctx = context.WithValue(ctx, ginContextKey{}, c)
+	}
+	if h.ContextFn != nil {
+		ctx = h.ContextFn(ctx, r)
+	}
+	return ctx
+}
 
-	shOpts = append(shOpts, server.WithEndpointPath(h.BasePath))
-	shOpts = append(shOpts, server.WithLogger(newLogger()))
-	sseServer := server.NewStreamableHTTPServer(h.server, shOpts...)
-
-	r.POST(h.BasePath, func(c *gin.Context) {
-		sseServer.ServeHTTP(c.Writer, c.Request)
-	})
-	r.GET(h.BasePath, func(c *gin.Context) {
-		sseServer.ServeHTTP(c.Writer, c.Request)
-	})
-	r.DELETE(h.BasePath, func(c *gin.Context) {
-		sseServer.ServeHTTP(c.Writer, c.Request)
-	})
+// GinContext 从工具处理函数的ctx中获取当前请求的 *gin.Context，仅在请求处理期间有效
+func GinContext(ctx context.Context) (*gin.Context, bool) {
+	c, ok := ctx.Value(ginContextKey{}).(*gin.Context)
+	return c, ok
 }
 
 type mcpLogger struct {
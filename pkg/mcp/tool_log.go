@@ -0,0 +1,68 @@
+// Package mcp -----------------------------
+// @file      : tool_log.go
+// @author    : xiangtao
+// @contact   : xiangtao1994@gmail.com
+// @time      : 2025/9/13 17:20
+// Description: 工具调用日志
+// -------------------------------------------
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"go.uber.org/zap"
+
+	"github.com/xiangtao94/golib/pkg/zlog"
+)
+
+// 日志中参数的最大长度
+const _maxToolArgsLen = 1024
+
+var toolLogger = func() *zap.Logger {
+	return zlog.NewLoggerWithSkip(1)
+}
+
+// toolLogging 记录工具名、参数、结果大小和耗时，requestId 取自 RegisterRoutes 注入的 gin.Context
+func toolLogging(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, req mcp.CallToolRequest) (result *mcp.CallToolResult, err error) {
+		start := time.Now()
+		defer func() {
+			logToolCall(ctx, req, result, err, start)
+		}()
+		return next(ctx, req)
+	}
+}
+
+func logToolCall(ctx context.Context, req mcp.CallToolRequest, result *mcp.CallToolResult, err error, start time.Time) {
+	c, _ := GinContext(ctx)
+	args, _ := json.Marshal(req.GetRawArguments())
+	argsStr := string(args)
+	if len(argsStr) > _maxToolArgsLen {
+		argsStr = argsStr[:_maxToolArgsLen] + "...(truncated)"
+	}
+	var resultSize int
+	var isError bool
+	if result != nil {
+		data, _ := json.Marshal(result)
+		resultSize = len(data)
+		isError = result.IsError
+	}
+	fields := []zap.Field{
+		zlog.String("tool", req.Params.Name),
+		zlog.String("arguments", argsStr),
+		zlog.Int("resultSize", resultSize),
+		zlog.Bool("isError", isError),
+		zlog.String("cost", fmt.Sprintf("%v%s", zlog.GetRequestCost(start, time.Now()), "ms")),
+	}
+	logger := zlog.LoggerWithContext(toolLogger(), c)
+	if err != nil {
+		logger.Error(err.Error(), fields...)
+		return
+	}
+	logger.Info("mcp tool call", fields...)
+}
@@ -0,0 +1,69 @@
+// Package mcp -----------------------------
+// @file      : typed_tool.go
+// @author    : xiangtao
+// @contact   : xiangtao1994@gmail.com
+// @time      : 2025/9/13 15:10
+// Description: 基于结构体的工具注册，自动生成 JSON Schema
+// -------------------------------------------
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/mark3labs/mcp-go/mcp"
+
+	"github.com/xiangtao94/golib/pkg/zlog"
+)
+
+// AddTypedTool 注册类型化工具：根据 In 生成输入 JSON Schema（支持 json、jsonschema 标签），调用参数自动反序列化到 In
+// Out 为结构体时同时生成输出 Schema 并以结构化结果返回，否则以文本返回；fn 返回的错误作为工具错误结果返回给客户端
+// Go 方法不支持类型参数，因此以函数形式提供
+func AddTypedTool[In any, Out any](h *Handler, name, description string, fn func(ctx context.Context, in In) (Out, error), opts ...mcp.ToolOption) {
+	structured := isStructType[Out]()
+	toolOpts := []mcp.ToolOption{mcp.WithDescription(description), mcp.WithInputSchema[In]()}
+	if structured {
+		toolOpts = append(toolOpts, mcp.WithOutputSchema[Out]())
+	}
+	toolOpts = append(toolOpts, opts...)
+
+	h.AddTool(mcp.NewTool(name, toolOpts...), func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		c, _ := GinContext(ctx)
+		var in In
+		if err := req.BindArguments(&in); err != nil {
+			zlog.Warnf(c, "mcp tool %s bind arguments error: %v", name, err)
+			return mcp.NewToolResultError(fmt.Sprintf("invalid arguments: %v", err)), nil
+		}
+		out, err := fn(ctx, in)
+		if err != nil {
+			zlog.Errorf(c, "mcp tool %s call error: %v", name, err)
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		if structured {
+			return mcp.NewToolResultStructuredOnly(out), nil
+		}
+		return textToolResult(out)
+	})
+}
+
+// isStructType MCP 要求输出 Schema 为 object，只有结构体（或其指针）才生成
+func isStructType[T any]() bool {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Struct
+}
+
+func textToolResult(out any) (*mcp.CallToolResult, error) {
+	if s, ok := out.(string); ok {
+		return mcp.NewToolResultText(s), nil
+	}
+	data, err := json.Marshal(out)
+	if err != nil {
+		return nil, fmt.Errorf("marshal tool result error: %w", err)
+	}
+	return mcp.NewToolResultText(string(data)), nil
+}
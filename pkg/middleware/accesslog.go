@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io"
 	"mime"
+	"net/url"
 	"slices"
 	"strings"
 	"time"
@@ -17,6 +18,9 @@ import (
 	"github.com/xiangtao94/golib/pkg/zlog"
 )
 
+// 打印 access 日志，测试中替换
+var accessInfo = zlog.AccessInfo
+
 const (
 	_defaultPrintRequestLen  = 10240
 	_defaultPrintResponseLen = 10240
@@ -25,22 +29,39 @@ const (
 type customRespWriter struct {
 	gin.ResponseWriter
 	body *bytes.Buffer
+	ctx  *gin.Context
 }
 
 func (w customRespWriter) WriteString(s string) (int, error) {
-	if w.body != nil {
-		w.body.WriteString(s)
-	}
+	w.capture([]byte(s))
 	return w.ResponseWriter.WriteString(s)
 }
 
 func (w customRespWriter) Write(b []byte) (int, error) {
-	if w.body != nil {
-		w.body.Write(b)
-	}
+	w.capture(b)
 	return w.ResponseWriter.Write(b)
 }
 
+// capture 缓存响应体用于打印，按 zlog.SetAccessLogOptions 设置的配置停止或限制缓存
+func (w customRespWriter) capture(b []byte) {
+	if w.body == nil {
+		return
+	}
+	if opts, ok := zlog.GetAccessLogOptions(w.ctx); ok {
+		if opts.Skip || opts.MaxRespBodyLen == -1 {
+			return
+		}
+		if opts.MaxRespBodyLen > 0 {
+			remain := opts.MaxRespBodyLen - w.body.Len()
+			if remain <= 0 {
+				return
+			}
+			b = b[:min(len(b), remain)]
+		}
+	}
+	w.body.Write(b)
+}
+
 // access日志打印
 type AccessLoggerConfig struct {
 	SkipPaths    []string `yaml:"skipPaths"`
@@ -50,8 +71,25 @@ type AccessLoggerConfig struct {
 	MaxReqBodyLen int `yaml:"maxReqBodyLen"`
 	// response body 最大长度展示，0表示采用默认的10240，-1表示不打印。指定长度的时候需注意，返回的json可能被截断
 	MaxRespBodyLen int `yaml:"maxRespBodyLen"`
+	// 脱敏的字段，值替换为***：不含点号的字段名匹配任意层级的同名key，如 password；含点号的按完整路径匹配，如 user.token
+	// 对JSON请求/响应体、表单和查询参数生效，不区分大小写
+	RedactFields []string `yaml:"redactFields"`
+	// 脱敏的请求头，包含 Cookie 时 cookie 的值同样脱敏
+	RedactHeaders []string `yaml:"redactHeaders"`
 	// 自定义Skip功能
 	Skip func(ctx *gin.Context) bool
+
+	// SampleRate 成功且未超过 SlowThreshold 的请求的采样率，取值 0-1，为空时全部打印
+	// 失败的请求（HTTP状态码>=400、ctx.Errors 不为空、响应头 code 不是200即业务错误）总是打印
+	SampleRate *float64 `yaml:"sampleRate"`
+	// SampleByRequestID 按请求ID哈希决定是否采样，同一请求ID的结果固定，便于排查时复现
+	SampleByRequestID bool `yaml:"sampleByRequestId"`
+	// AlwaysLogStatus 总是打印的HTTP状态码，如 [201, 302]
+	AlwaysLogStatus []int `yaml:"alwaysLogStatus"`
+	// AlwaysLog 自定义总是打印的条件，在请求处理完成后调用
+	AlwaysLog func(ctx *gin.Context) bool
+	// SlowThreshold 耗时超过该值的请求总是打印，并增加字段 slow=true，0表示不启用
+	SlowThreshold time.Duration `yaml:"slowThreshold"`
 }
 
 // DefaultAccessLoggerConfig 返回默认的Access日志配置
@@ -110,17 +148,20 @@ func AccessLog(conf AccessLoggerConfig) gin.HandlerFunc {
 		maxRespBodyLen = _defaultPrintResponseLen
 	}
 
+	redact := newRedactor(conf.RedactFields, conf.RedactHeaders)
+	sampler := newAccessSampler(conf)
+
 	return func(c *gin.Context) {
 		// Start timer
 		start := time.Now()
 		path := c.Request.URL.Path
 
 		// body writer
-		blw := &customRespWriter{body: bytes.NewBufferString(""), ResponseWriter: c.Writer}
+		blw := &customRespWriter{body: bytes.NewBufferString(""), ResponseWriter: c.Writer, ctx: c}
 		c.Writer = blw
 
 		// 请求参数，涉及到回写，要在处理业务逻辑之前
-		reqParam := getReqBody(c, maxReqBodyLen)
+		reqParam := getReqBody(c, maxReqBodyLen, redact)
 
 		c.Set(zlog.ContextKeyUri, path)
 		_ = zlog.GetRequestID(c)
@@ -137,6 +178,26 @@ func AccessLog(conf AccessLoggerConfig) gin.HandlerFunc {
 			return
 		}
 
+		// handler 通过 zlog.SetAccessLogOptions 设置的单请求配置
+		respBodyLen := maxRespBodyLen
+		if opts, ok := zlog.GetAccessLogOptions(c); ok {
+			if opts.Skip {
+				return
+			}
+			reqParam = truncateReqParam(reqParam, opts.MaxReqBodyLen)
+			if opts.MaxRespBodyLen != 0 {
+				respBodyLen = opts.MaxRespBodyLen
+			}
+		}
+
+		// 采样在请求完成后决定，此时状态码和耗时已知
+		end := time.Now()
+		slow := conf.SlowThreshold > 0 && end.Sub(start) >= conf.SlowThreshold
+		if !slow && !sampler.keep(c) {
+			accessLogSampledOut.Inc()
+			return
+		}
+
 		// 固定notice
 		commonFields := []zlog.Field{
 			zlog.String("method", c.Request.Method),
@@ -146,10 +207,10 @@ func AccessLog(conf AccessLoggerConfig) gin.HandlerFunc {
 			zlog.String("requestParam", reqParam),
 		}
 		if len(conf.PrintHeaders) > 0 {
-			commonFields = append(commonFields, zlog.String("requestHeader", getHeader(c, conf.PrintHeaders)))
+			commonFields = append(commonFields, zlog.String("requestHeader", getHeader(c, conf.PrintHeaders, redact)))
 		}
 		if conf.PrintCookie {
-			commonFields = append(commonFields, zlog.String("cookie", getCookie(c)))
+			commonFields = append(commonFields, zlog.String("cookie", getCookie(c, redact.matchHeader("Cookie"))))
 		}
 		contentType := c.Writer.Header().Get("Content-Type")
 		mediaType, _, err := mime.ParseMediaType(contentType)
@@ -157,32 +218,45 @@ func AccessLog(conf AccessLoggerConfig) gin.HandlerFunc {
 			mediaType = ""
 		}
 		var response any
-		if blw.body != nil && maxRespBodyLen != -1 {
+		if blw.body != nil && respBodyLen != -1 {
 			if strings.Contains(mediaType, "application/json") {
 				response = json.RawMessage{}
-				_ = json.Unmarshal(blw.body.Bytes(), &response)
+				_ = json.Unmarshal(redact.redactJSON(blw.body.Bytes()), &response)
 			} else if strings.Contains(mediaType, "text/event-stream") {
 				response = blw.body.String()
 			}
 		}
 		commonFields = append(commonFields, zlog.Any("responseBody", response), zlog.Int("bodySize", c.Writer.Size()))
-		commonFields = append(commonFields, AppendCostTime(start, time.Now())...)
+		commonFields = append(commonFields, AppendCostTime(start, end)...)
+		if slow {
+			commonFields = append(commonFields, zlog.Bool("slow", true))
+		}
 		// 新的notice添加方式
 		customerFields := zlog.GetCustomerFields(c)
 		commonFields = append(commonFields, customerFields...)
-		zlog.AccessInfo(c, commonFields...)
+		accessInfo(c, commonFields...)
 	}
 }
 
+// truncateReqParam 按单请求配置截断已读取的请求参数，maxLen 为0时不变，-1时清空
+func truncateReqParam(reqParam string, maxLen int) string {
+	switch {
+	case maxLen == -1:
+		return ""
+	case maxLen > 0 && len(reqParam) > maxLen:
+		return reqParam[:maxLen]
+	}
+	return reqParam
+}
+
 // 请求参数
-func getReqBody(c *gin.Context, maxReqBodyLen int) (reqBody string) {
+func getReqBody(c *gin.Context, maxReqBodyLen int, redact *redactor) (reqBody string) {
 	// 不打印参数
 	if maxReqBodyLen == -1 {
 		return reqBody
 	}
 	if c.Request.Method == "GET" || c.Request.Method == "DELETE" {
-		allParams := c.Request.URL.Query()
-		reqBody = allParams.Encode()
+		reqBody = redact.redactValues(c.Request.URL.Query())
 		return reqBody
 	}
 	// body中的参数
@@ -190,13 +264,15 @@ func getReqBody(c *gin.Context, maxReqBodyLen int) (reqBody string) {
 		requestBody, err := c.GetRawData()
 		if err != nil {
 			zlog.WarnLogger(c, "get http request body error: "+err.Error())
+			c.Request.Body = replayBody(requestBody, err)
+			return reqBody
 		}
 		c.Request.Body = io.NopCloser(bytes.NewBuffer(requestBody))
 
 		if _, err := c.MultipartForm(); err != nil {
 			zlog.WarnLogger(c, "parse http request form body error: "+err.Error())
 		}
-		reqBody = c.Request.PostForm.Encode()
+		reqBody = redact.redactValues(c.Request.PostForm)
 		c.Request.Body = io.NopCloser(bytes.NewBuffer(requestBody))
 	} else if c.Request.Body != nil {
 		requestBody, err := c.GetRawData()
@@ -204,7 +280,10 @@ func getReqBody(c *gin.Context, maxReqBodyLen int) (reqBody string) {
 			zlog.WarnLogger(c, "get http request body error: "+err.Error())
 		}
 		reqBody = *(*string)(unsafe.Pointer(&requestBody))
-		c.Request.Body = io.NopCloser(bytes.NewBuffer(requestBody))
+		c.Request.Body = replayBody(requestBody, err)
+		if redact != nil {
+			reqBody = redactBody(c.ContentType(), requestBody, redact)
+		}
 	}
 	// 截断参数
 	if len(reqBody) > maxReqBodyLen {
@@ -213,18 +292,56 @@ func getReqBody(c *gin.Context, maxReqBodyLen int) (reqBody string) {
 	return reqBody
 }
 
-func getCookie(ctx *gin.Context) string {
+// redactBody 按 Content-Type 对JSON和表单请求体脱敏，其他类型原样返回
+func redactBody(contentType string, body []byte, redact *redactor) string {
+	switch contentType {
+	case binding.MIMEJSON:
+		return string(redact.redactJSON(body))
+	case binding.MIMEPOSTForm:
+		if values, err := url.ParseQuery(string(body)); err == nil {
+			return redact.redactValues(values)
+		}
+	}
+	return string(body)
+}
+
+// replayBody 回写已读取的请求体；读取出错（如超过 MaxBytesReader 限制）时，业务读取到末尾会拿到同样的错误
+func replayBody(data []byte, err error) io.ReadCloser {
+	if err == nil {
+		return io.NopCloser(bytes.NewBuffer(data))
+	}
+	return io.NopCloser(io.MultiReader(bytes.NewReader(data), errReader{err: err}))
+}
+
+type errReader struct {
+	err error
+}
+
+func (r errReader) Read([]byte) (int, error) {
+	return 0, r.err
+}
+
+func getCookie(ctx *gin.Context, redacted bool) string {
 	cStr := ""
 	for _, c := range ctx.Request.Cookies() {
-		cStr += fmt.Sprintf("%s=%s&", c.Name, c.Value)
+		value := c.Value
+		if redacted {
+			value = redactedValue
+		}
+		cStr += fmt.Sprintf("%s=%s&", c.Name, value)
 	}
 	return strings.TrimRight(cStr, "&")
 }
 
-func getHeader(ctx *gin.Context, headers []string) string {
+func getHeader(ctx *gin.Context, headers []string, redact *redactor) string {
 	cStr := ""
 	for k, v := range ctx.Request.Header {
-		if slices.Contains(headers, k) {
+		if !slices.Contains(headers, k) {
+			continue
+		}
+		if redact.matchHeader(k) {
+			cStr += fmt.Sprintf("%s=%s&", k, redactedValue)
+		} else {
 			cStr += fmt.Sprintf("%s=%s&", k, v)
 		}
 	}
@@ -0,0 +1,70 @@
+// Package middleware -----------------------------
+// @file      : accesslog_sample.go
+// @author    : xiangtao
+// @contact   : xiangtao1994@gmail.com
+// @time      : 2025/9/14 16:10
+// Description: access 日志采样，失败和慢请求总是打印，其余按比例采样
+// -------------------------------------------
+package middleware
+
+import (
+	"hash/fnv"
+	"math"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/xiangtao94/golib/pkg/zlog"
+)
+
+type accessSampler struct {
+	rate        float64
+	byRequestID bool
+	status      map[int]struct{}
+	always      func(ctx *gin.Context) bool
+}
+
+func newAccessSampler(conf AccessLoggerConfig) *accessSampler {
+	s := &accessSampler{rate: 1, byRequestID: conf.SampleByRequestID, always: conf.AlwaysLog}
+	if conf.SampleRate != nil {
+		s.rate = min(max(*conf.SampleRate, 0), 1)
+	}
+	s.status = make(map[int]struct{}, len(conf.AlwaysLogStatus))
+	for _, code := range conf.AlwaysLogStatus {
+		s.status[code] = struct{}{}
+	}
+	return s
+}
+
+// keep 请求完成后调用，返回是否打印
+func (s *accessSampler) keep(c *gin.Context) bool {
+	if s.rate >= 1 || failed(c) {
+		return true
+	}
+	if _, ok := s.status[c.Writer.Status()]; ok {
+		return true
+	}
+	if s.always != nil && s.always(c) {
+		return true
+	}
+	if s.rate <= 0 {
+		return false
+	}
+	if s.byRequestID {
+		h := fnv.New64a()
+		_, _ = h.Write([]byte(zlog.GetRequestID(c)))
+		return float64(h.Sum64())/math.MaxUint64 < s.rate
+	}
+	return rand.Float64() < s.rate
+}
+
+// failed HTTP状态码>=400、处理过程中记录了错误，或 render.RenderJsonFail 写入的业务码不是200
+func failed(c *gin.Context) bool {
+	if c.Writer.Status() >= http.StatusBadRequest || len(c.Errors) > 0 {
+		return true
+	}
+	code := c.Writer.Header().Get("code")
+	return code != "" && code != strconv.Itoa(http.StatusOK)
+}
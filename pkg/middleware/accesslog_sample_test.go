@@ -0,0 +1,126 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+// captureAccessLog 记录打印的 uri 和 slow 字段
+func captureAccessLog(t *testing.T) map[string]bool {
+	logged := map[string]bool{}
+	old := accessInfo
+	accessInfo = func(ctx *gin.Context, fields ...zap.Field) {
+		slow := false
+		for _, f := range fields {
+			if f.Key == "slow" {
+				slow = true
+			}
+		}
+		logged[ctx.Request.URL.Path] = slow
+	}
+	t.Cleanup(func() { accessInfo = old })
+	return logged
+}
+
+func newSampleEngine(conf AccessLoggerConfig) *gin.Engine {
+	engine := gin.New()
+	engine.Use(AccessLog(conf))
+	engine.GET("/ok", func(c *gin.Context) { c.String(http.StatusOK, "ok") })
+	engine.GET("/created", func(c *gin.Context) { c.String(http.StatusCreated, "created") })
+	engine.GET("/error", func(c *gin.Context) { c.String(http.StatusInternalServerError, "error") })
+	engine.GET("/biz", func(c *gin.Context) {
+		// 与 render.RenderJsonFail 一致，业务错误HTTP状态码为200，响应头 code 为业务码
+		c.Header("code", "4001")
+		c.String(http.StatusOK, "{}")
+	})
+	engine.GET("/slow", func(c *gin.Context) {
+		time.Sleep(30 * time.Millisecond)
+		c.String(http.StatusOK, "slow")
+	})
+	return engine
+}
+
+func serve(engine *gin.Engine, path, requestID string) {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	if requestID != "" {
+		req.Header.Set("Request-Id", requestID)
+	}
+	engine.ServeHTTP(httptest.NewRecorder(), req)
+}
+
+func TestAccessLogSampling(t *testing.T) {
+	zero, one := 0.0, 1.0
+	paths := []string{"/ok", "/created", "/error", "/biz", "/slow"}
+
+	t.Run("rate 0", func(t *testing.T) {
+		logged := captureAccessLog(t)
+		before := testutil.ToFloat64(accessLogSampledOut)
+		engine := newSampleEngine(AccessLoggerConfig{SampleRate: &zero, SlowThreshold: 20 * time.Millisecond})
+		for _, p := range paths {
+			serve(engine, p, "")
+		}
+		assert.Equal(t, map[string]bool{"/error": false, "/biz": false, "/slow": true}, logged)
+		assert.Equal(t, 2.0, testutil.ToFloat64(accessLogSampledOut)-before)
+	})
+
+	t.Run("rate 1", func(t *testing.T) {
+		logged := captureAccessLog(t)
+		before := testutil.ToFloat64(accessLogSampledOut)
+		engine := newSampleEngine(AccessLoggerConfig{SampleRate: &one})
+		for _, p := range paths {
+			serve(engine, p, "")
+		}
+		assert.Len(t, logged, len(paths))
+		assert.Zero(t, testutil.ToFloat64(accessLogSampledOut)-before)
+	})
+
+	t.Run("always log", func(t *testing.T) {
+		logged := captureAccessLog(t)
+		before := testutil.ToFloat64(accessLogSampledOut)
+		engine := newSampleEngine(AccessLoggerConfig{SampleRate: &zero, AlwaysLogStatus: []int{http.StatusCreated},
+			AlwaysLog: func(ctx *gin.Context) bool { return ctx.Request.URL.Path == "/slow" }})
+		for _, p := range paths {
+			serve(engine, p, "")
+		}
+		assert.Equal(t, map[string]bool{"/created": false, "/error": false, "/biz": false, "/slow": false}, logged)
+		assert.Equal(t, 1.0, testutil.ToFloat64(accessLogSampledOut)-before)
+	})
+}
+
+func TestAccessLogSampleByRequestID(t *testing.T) {
+	half := 0.5
+	logged := captureAccessLog(t)
+	before := testutil.ToFloat64(accessLogSampledOut)
+	engine := newSampleEngine(AccessLoggerConfig{SampleRate: &half, SampleByRequestID: true})
+
+	decisions := map[string]bool{}
+	for i := 0; i < 200; i++ {
+		rid := fmt.Sprintf("rid-%d", i)
+		serve(engine, "/ok", rid)
+		decisions[rid] = len(logged) > 0
+		clear(logged)
+	}
+	kept := 0
+	for _, keep := range decisions {
+		if keep {
+			kept++
+		}
+	}
+	assert.InDelta(t, 100, kept, 30)
+	assert.Equal(t, float64(200-kept), testutil.ToFloat64(accessLogSampledOut)-before)
+
+	// 同一请求ID的采样结果固定
+	for rid, keep := range decisions {
+		serve(engine, "/ok", rid)
+		assert.Equal(t, keep, len(logged) > 0, rid)
+		clear(logged)
+	}
+}
@@ -0,0 +1,80 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/xiangtao94/golib/pkg/zlog"
+)
+
+// captureAccessFields 按 uri 记录打印的字段
+func captureAccessFields(t *testing.T) map[string]map[string]zap.Field {
+	logged := map[string]map[string]zap.Field{}
+	old := accessInfo
+	accessInfo = func(ctx *gin.Context, fields ...zap.Field) {
+		m := map[string]zap.Field{}
+		for _, f := range fields {
+			m[f.Key] = f
+		}
+		logged[ctx.Request.URL.Path] = m
+	}
+	t.Cleanup(func() { accessInfo = old })
+	return logged
+}
+
+func TestAccessLogOptions(t *testing.T) {
+	logged := captureAccessFields(t)
+	var bufferedDownload int
+	engine := gin.New()
+	engine.Use(AccessLog(DefaultAccessLoggerConfig()))
+	engine.POST("/default", func(c *gin.Context) {
+		c.Header("Content-Type", "text/event-stream")
+		c.String(http.StatusOK, "data: hello world\n\n")
+	})
+	engine.POST("/download", func(c *gin.Context) {
+		zlog.SetAccessLogOptions(c, zlog.AccessLogOptions{MaxReqBodyLen: -1, MaxRespBodyLen: -1})
+		c.Data(http.StatusOK, "application/octet-stream", make([]byte, 1<<20))
+		bufferedDownload = c.Writer.(*customRespWriter).body.Len()
+	})
+	engine.POST("/stream", func(c *gin.Context) {
+		zlog.SetAccessLogOptions(c, zlog.AccessLogOptions{MaxReqBodyLen: 5, MaxRespBodyLen: 10})
+		c.Header("Content-Type", "text/event-stream")
+		c.String(http.StatusOK, "data: hello world\n\n")
+	})
+	engine.POST("/skip", func(c *gin.Context) {
+		zlog.SetAccessLogOptions(c, zlog.AccessLogOptions{Skip: true})
+		c.String(http.StatusOK, "ok")
+	})
+
+	for _, path := range []string{"/default", "/download", "/stream", "/skip"} {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(`{"name":"golib"}`))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+	}
+
+	require.Contains(t, logged, "/default")
+	assert.Equal(t, `{"name":"golib"}`, logged["/default"]["requestParam"].String)
+	assert.Equal(t, "data: hello world\n\n", logged["/default"]["responseBody"].String)
+
+	// 下载不缓存响应体，也不打印请求参数
+	require.Contains(t, logged, "/download")
+	assert.Zero(t, bufferedDownload)
+	assert.Empty(t, logged["/download"]["requestParam"].String)
+	assert.Nil(t, logged["/download"]["responseBody"].Interface)
+	assert.Equal(t, int64(1<<20), logged["/download"]["bodySize"].Integer)
+
+	require.Contains(t, logged, "/stream")
+	assert.Equal(t, `{"nam`, logged["/stream"]["requestParam"].String)
+	assert.Equal(t, "data: hell", logged["/stream"]["responseBody"].String)
+
+	assert.NotContains(t, logged, "/skip")
+}
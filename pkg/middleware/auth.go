@@ -0,0 +1,163 @@
+// Package middleware -----------------------------
+// @file      : auth.go
+// @author    : xiangtao
+// @contact   : xiangtao1994@gmail.com
+// @time      : 2025/9/12 16:40
+// Description: API Key / JWT 鉴权中间件
+// -------------------------------------------
+package middleware
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	errors2 "github.com/xiangtao94/golib/pkg/errors"
+	"github.com/xiangtao94/golib/pkg/render"
+	"github.com/xiangtao94/golib/pkg/zlog"
+)
+
+const (
+	AuthModeAPIKey = "apikey"
+	AuthModeJWT    = "jwt"
+
+	// ContextKeyAuthPrincipal 鉴权通过后 *AuthPrincipal 在 gin.Context 中的key
+	ContextKeyAuthPrincipal = "_auth_principal"
+)
+
+// AuthPrincipal 鉴权主体
+type AuthPrincipal struct {
+	// Mode apikey 或 jwt
+	Mode string
+	// Subject apikey 模式为 key 的名称，jwt 模式为 sub
+	Subject string
+	// Claims jwt 的全部声明，apikey 模式为空
+	Claims map[string]any
+}
+
+type APIKeyConf struct {
+	// Header 携带 key 的请求头，默认 X-API-Key
+	Header string `yaml:"header"`
+	// Keys 名称 -> key，名称作为 AuthPrincipal.Subject
+	Keys map[string]string `yaml:"keys"`
+}
+
+type AuthConf struct {
+	// Mode apikey 或 jwt
+	Mode      string     `yaml:"mode"`
+	APIKey    APIKeyConf `yaml:"apiKey"`
+	JWT       JWTConf    `yaml:"jwt"`
+	SkipPaths []string   `yaml:"skipPaths"`
+	// Validator 签名校验通过后的自定义校验，如检查角色、黑名单
+	Validator func(ctx *gin.Context, principal *AuthPrincipal) error `yaml:"-"`
+}
+
+// RegistryAuth 在 engine 或路由组上注册鉴权中间件，配置不合法时返回错误
+// 鉴权失败统一通过 render.RenderJsonFail 返回 ErrorUserNotLogin
+func RegistryAuth(r gin.IRoutes, conf AuthConf) error {
+	handler, err := Auth(conf)
+	if err != nil {
+		return err
+	}
+	r.Use(handler)
+	return nil
+}
+
+// Auth 根据配置生成鉴权中间件
+func Auth(conf AuthConf) (gin.HandlerFunc, error) {
+	var authenticate func(ctx *gin.Context) (*AuthPrincipal, error)
+	switch conf.Mode {
+	case AuthModeAPIKey:
+		if len(conf.APIKey.Keys) == 0 {
+			return nil, fmt.Errorf("auth: apiKey.keys is empty")
+		}
+		if conf.APIKey.Header == "" {
+			conf.APIKey.Header = "X-API-Key"
+		}
+		authenticate = apiKeyAuthenticator(conf.APIKey)
+	case AuthModeJWT:
+		verifier, err := newJWTVerifier(conf.JWT)
+		if err != nil {
+			return nil, fmt.Errorf("auth: %w", err)
+		}
+		authenticate = jwtAuthenticator(verifier)
+	default:
+		return nil, fmt.Errorf("auth: unsupported mode %q", conf.Mode)
+	}
+
+	skip := make(map[string]struct{}, len(conf.SkipPaths))
+	for _, path := range conf.SkipPaths {
+		skip[path] = struct{}{}
+	}
+
+	return func(ctx *gin.Context) {
+		if _, ok := skip[ctx.Request.URL.Path]; ok {
+			ctx.Next()
+			return
+		}
+		principal, err := authenticate(ctx)
+		if err == nil && conf.Validator != nil {
+			err = conf.Validator(ctx, principal)
+		}
+		if err != nil {
+			zlog.Warnf(ctx, "auth failed, mode: %s, uri: %s, error: %v", conf.Mode, ctx.Request.URL.Path, err)
+			render.RenderJsonFail(ctx, errors2.ErrorUserNotLogin)
+			ctx.Abort()
+			return
+		}
+		ctx.Set(ContextKeyAuthPrincipal, principal)
+		ctx.Next()
+	}, nil
+}
+
+// GetAuthPrincipal 获取鉴权主体，未经过鉴权时返回 nil
+func GetAuthPrincipal(ctx *gin.Context) *AuthPrincipal {
+	if ctx == nil {
+		return nil
+	}
+	if v, ok := ctx.Get(ContextKeyAuthPrincipal); ok {
+		p, _ := v.(*AuthPrincipal)
+		return p
+	}
+	return nil
+}
+
+func apiKeyAuthenticator(conf APIKeyConf) func(ctx *gin.Context) (*AuthPrincipal, error) {
+	return func(ctx *gin.Context) (*AuthPrincipal, error) {
+		key := ctx.GetHeader(conf.Header)
+		if key == "" {
+			return nil, errors.New("api key missing")
+		}
+		// 遍历全部 key，避免提前返回导致的时序差异
+		subject := ""
+		for name, k := range conf.Keys {
+			if subtle.ConstantTimeCompare([]byte(key), []byte(k)) == 1 {
+				subject = name
+			}
+		}
+		if subject == "" {
+			return nil, errors.New("api key invalid")
+		}
+		return &AuthPrincipal{Mode: AuthModeAPIKey, Subject: subject}, nil
+	}
+}
+
+func jwtAuthenticator(verifier *jwtVerifier) func(ctx *gin.Context) (*AuthPrincipal, error) {
+	return func(ctx *gin.Context) (*AuthPrincipal, error) {
+		header := ctx.GetHeader("Authorization")
+		token, ok := strings.CutPrefix(header, "Bearer ")
+		if !ok || token == "" {
+			return nil, errors.New("bearer token missing")
+		}
+		claims, err := verifier.verify(token, time.Now())
+		if err != nil {
+			return nil, err
+		}
+		sub, _ := claims["sub"].(string)
+		return &AuthPrincipal{Mode: AuthModeJWT, Subject: sub, Claims: claims}, nil
+	}
+}
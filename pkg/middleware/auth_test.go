@@ -0,0 +1,164 @@
+package middleware
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	errors2 "github.com/xiangtao94/golib/pkg/errors"
+)
+
+const testJWTSecret = "test-secret"
+
+func signHS256(t *testing.T, secret string, claims map[string]any) string {
+	header, _ := json.Marshal(map[string]string{"alg": JWTAlgHS256, "typ": "JWT"})
+	payload, err := json.Marshal(claims)
+	assert.NoError(t, err)
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(signed))
+	return signed + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func newAuthTestEngine(t *testing.T, conf AuthConf) *gin.Engine {
+	engine := gin.New()
+	assert.NoError(t, RegistryAuth(engine, conf))
+	engine.GET("/me", func(c *gin.Context) {
+		p := GetAuthPrincipal(c)
+		c.JSON(http.StatusOK, gin.H{"subject": p.Subject, "claims": p.Claims})
+	})
+	engine.GET("/public", func(c *gin.Context) { c.String(http.StatusOK, "ok") })
+	return engine
+}
+
+func doAuthRequest(engine *gin.Engine, path string, headers map[string]string) (int, map[string]any) {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, path, nil)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	engine.ServeHTTP(w, req)
+	body := map[string]any{}
+	_ = json.Unmarshal(w.Body.Bytes(), &body)
+	return w.Code, body
+}
+
+func jwtTestConf() AuthConf {
+	return AuthConf{
+		Mode:      AuthModeJWT,
+		JWT:       JWTConf{Algorithm: JWTAlgHS256, Secret: testJWTSecret, Issuer: "golib", Audience: "api", ClockSkew: 5 * time.Second},
+		SkipPaths: []string{"/public"},
+	}
+}
+
+func TestAuthJWTValid(t *testing.T) {
+	engine := newAuthTestEngine(t, jwtTestConf())
+	token := signHS256(t, testJWTSecret, map[string]any{
+		"sub": "user-1", "iss": "golib", "aud": []string{"api", "web"}, "role": "admin",
+		"exp": time.Now().Add(time.Hour).Unix(),
+	})
+
+	code, body := doAuthRequest(engine, "/me", map[string]string{"Authorization": "Bearer " + token})
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "user-1", body["subject"])
+	assert.Equal(t, "admin", body["claims"].(map[string]any)["role"])
+
+	// 跳过路径无需鉴权
+	code, _ = doAuthRequest(engine, "/public", nil)
+	assert.Equal(t, http.StatusOK, code)
+}
+
+func TestAuthJWTRejected(t *testing.T) {
+	engine := newAuthTestEngine(t, jwtTestConf())
+	valid := map[string]any{"sub": "user-1", "iss": "golib", "aud": "api", "exp": time.Now().Add(time.Hour).Unix()}
+
+	cases := map[string]string{
+		"missing":         "",
+		"expired":         signHS256(t, testJWTSecret, map[string]any{"sub": "user-1", "iss": "golib", "aud": "api", "exp": time.Now().Add(-time.Minute).Unix()}),
+		"wrong signature": signHS256(t, "other-secret", valid),
+		"wrong issuer":    signHS256(t, testJWTSecret, map[string]any{"sub": "user-1", "iss": "evil", "aud": "api"}),
+		"malformed":       "not-a-jwt",
+		"non-numeric exp": signHS256(t, testJWTSecret, map[string]any{"sub": "user-1", "iss": "golib", "aud": "api", "exp": "never"}),
+		"non-numeric nbf": signHS256(t, testJWTSecret, map[string]any{"sub": "user-1", "iss": "golib", "aud": "api", "nbf": nil}),
+	}
+	for name, token := range cases {
+		t.Run(name, func(t *testing.T) {
+			code, body := doAuthRequest(engine, "/me", map[string]string{"Authorization": "Bearer " + token})
+			// 通过 RenderJsonFail 返回统一格式
+			assert.Equal(t, http.StatusOK, code)
+			assert.Equal(t, float64(errors2.USER_NOT_LOGIN), body["code"])
+		})
+	}
+}
+
+func TestJWTNonNumericTimeClaim(t *testing.T) {
+	v, err := newJWTVerifier(jwtTestConf().JWT)
+	require.NoError(t, err)
+	for _, claims := range []map[string]any{
+		{"iss": "golib", "aud": "api", "exp": "never"},
+		{"iss": "golib", "aud": "api", "exp": true},
+		{"iss": "golib", "aud": "api", "nbf": "soon"},
+	} {
+		_, err := v.verify(signHS256(t, testJWTSecret, claims), time.Now())
+		assert.ErrorIs(t, err, ErrTokenMalformed, claims)
+	}
+}
+
+func TestAuthJWTClockSkew(t *testing.T) {
+	engine := newAuthTestEngine(t, jwtTestConf())
+	token := signHS256(t, testJWTSecret, map[string]any{"iss": "golib", "aud": "api", "exp": time.Now().Add(-2 * time.Second).Unix()})
+
+	_, body := doAuthRequest(engine, "/me", map[string]string{"Authorization": "Bearer " + token})
+	assert.Nil(t, body["code"])
+}
+
+func TestAuthJWTRS256(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	der, _ := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	pub := string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+
+	engine := newAuthTestEngine(t, AuthConf{Mode: AuthModeJWT, JWT: JWTConf{Algorithm: JWTAlgRS256, PublicKey: pub}})
+
+	// HS256 token 使用公钥作为密钥的算法混淆攻击会被拒绝
+	forged := signHS256(t, pub, map[string]any{"sub": "attacker"})
+	_, body := doAuthRequest(engine, "/me", map[string]string{"Authorization": "Bearer " + forged})
+	assert.Equal(t, float64(errors2.USER_NOT_LOGIN), body["code"])
+}
+
+func TestAuthAPIKey(t *testing.T) {
+	engine := newAuthTestEngine(t, AuthConf{
+		Mode:   AuthModeAPIKey,
+		APIKey: APIKeyConf{Keys: map[string]string{"billing": "key-123"}},
+		Validator: func(ctx *gin.Context, p *AuthPrincipal) error {
+			return nil
+		},
+	})
+
+	code, body := doAuthRequest(engine, "/me", map[string]string{"X-API-Key": "key-123"})
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "billing", body["subject"])
+
+	_, body = doAuthRequest(engine, "/me", map[string]string{"X-API-Key": "wrong"})
+	assert.Equal(t, float64(errors2.USER_NOT_LOGIN), body["code"])
+}
+
+func TestAuthInvalidConf(t *testing.T) {
+	_, err := Auth(AuthConf{Mode: AuthModeJWT, JWT: JWTConf{Algorithm: "none"}})
+	assert.Error(t, err)
+	_, err = Auth(AuthConf{Mode: AuthModeAPIKey})
+	assert.Error(t, err)
+}
@@ -0,0 +1,190 @@
+// Package middleware -----------------------------
+// @file      : cors_config.go
+// @author    : xiangtao
+// @contact   : xiangtao1994@gmail.com
+// @time      : 2025/9/12 10:40
+// Description: 可配置的CORS中间件，支持子域名通配、预检缓存与凭证校验
+// -------------------------------------------
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CORSConf struct {
+	// AllowOrigins 允许的源，支持精确匹配、"*" 以及子域名通配（https://*.example.com）
+	AllowOrigins []string `yaml:"allowOrigins"`
+	// AllowMethods 允许的方法，默认 GET, POST, PUT, PATCH, DELETE, HEAD, OPTIONS
+	AllowMethods []string `yaml:"allowMethods"`
+	// AllowHeaders 允许的请求头，默认 Origin, Content-Type, Accept, Authorization, X-Requested-With
+	AllowHeaders []string `yaml:"allowHeaders"`
+	// ExposeHeaders 允许浏览器读取的响应头
+	ExposeHeaders []string `yaml:"exposeHeaders"`
+	// AllowCredentials 是否允许携带cookie，不能与 "*" 同时使用
+	AllowCredentials bool `yaml:"allowCredentials"`
+	// MaxAge 预检结果缓存时间，默认12h
+	MaxAge time.Duration `yaml:"maxAge"`
+	// AllowOriginFunc 自定义源校验，AllowOrigins 未匹配时调用
+	AllowOriginFunc func(origin string) bool `yaml:"-"`
+}
+
+func DefaultCORSConf() CORSConf {
+	return CORSConf{
+		AllowMethods: []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete, http.MethodHead, http.MethodOptions},
+		AllowHeaders: []string{"Origin", "Content-Type", "Accept", "Authorization", "X-Requested-With"},
+		MaxAge:       12 * time.Hour,
+	}
+}
+
+func mergeWithDefaultCORS(userConf CORSConf) CORSConf {
+	defaultConf := DefaultCORSConf()
+	if len(userConf.AllowMethods) == 0 {
+		userConf.AllowMethods = defaultConf.AllowMethods
+	}
+	if len(userConf.AllowHeaders) == 0 {
+		userConf.AllowHeaders = defaultConf.AllowHeaders
+	}
+	if userConf.MaxAge <= 0 {
+		userConf.MaxAge = defaultConf.MaxAge
+	}
+	return userConf
+}
+
+func (conf CORSConf) validate() error {
+	if len(conf.AllowOrigins) == 0 && conf.AllowOriginFunc == nil {
+		return fmt.Errorf("cors: allowOrigins or AllowOriginFunc is required")
+	}
+	for _, origin := range conf.AllowOrigins {
+		if origin == "*" && conf.AllowCredentials {
+			return fmt.Errorf("cors: allowCredentials can not be used with allowOrigins \"*\"")
+		}
+		if strings.Count(origin, "*") > 1 || (origin != "*" && strings.Contains(origin, "*") && !strings.Contains(origin, "://*.")) {
+			return fmt.Errorf("cors: invalid origin pattern %q", origin)
+		}
+	}
+	return nil
+}
+
+// RegistryCORS 在 engine 上全局注册CORS中间件，需在其他中间件之前注册
+// 配置不合法（如 allowCredentials 与 "*" 同时使用）时返回错误
+func RegistryCORS(engine *gin.Engine, conf CORSConf) error {
+	handler, err := CORS(conf)
+	if err != nil {
+		return err
+	}
+	engine.Use(handler)
+	return nil
+}
+
+// CORS 根据配置生成CORS中间件，可用于路由组或单个路由
+// 路由组上使用时，需为该组注册 OPTIONS 路由才能响应预检请求
+func CORS(conf CORSConf) (gin.HandlerFunc, error) {
+	if err := conf.validate(); err != nil {
+		return nil, err
+	}
+	conf = mergeWithDefaultCORS(conf)
+	matcher := newOriginMatcher(conf.AllowOrigins)
+	allowMethods := strings.Join(conf.AllowMethods, ", ")
+	allowHeaders := strings.Join(conf.AllowHeaders, ", ")
+	exposeHeaders := strings.Join(conf.ExposeHeaders, ", ")
+	maxAge := strconv.Itoa(int(conf.MaxAge.Seconds()))
+
+	return func(c *gin.Context) {
+		origin := c.Request.Header.Get("Origin")
+		preflight := c.Request.Method == http.MethodOptions && c.Request.Header.Get("Access-Control-Request-Method") != ""
+		if !matcher.any {
+			// 响应随Origin变化，避免缓存串用
+			c.Writer.Header().Add("Vary", "Origin")
+		}
+		if preflight {
+			c.Writer.Header().Add("Vary", "Access-Control-Request-Method")
+			c.Writer.Header().Add("Vary", "Access-Control-Request-Headers")
+		}
+		if origin == "" {
+			c.Next()
+			return
+		}
+
+		allowed := matcher.match(origin) || (conf.AllowOriginFunc != nil && conf.AllowOriginFunc(origin))
+		if !allowed {
+			// 不回显不允许的源；非预检请求不带CORS头，由浏览器拦截
+			if preflight {
+				c.AbortWithStatus(http.StatusForbidden)
+				return
+			}
+			c.Next()
+			return
+		}
+
+		if matcher.any && !conf.AllowCredentials {
+			c.Header("Access-Control-Allow-Origin", "*")
+		} else {
+			c.Header("Access-Control-Allow-Origin", origin)
+		}
+		if conf.AllowCredentials {
+			c.Header("Access-Control-Allow-Credentials", "true")
+		}
+		if preflight {
+			c.Header("Access-Control-Allow-Methods", allowMethods)
+			c.Header("Access-Control-Allow-Headers", allowHeaders)
+			c.Header("Access-Control-Max-Age", maxAge)
+			c.AbortWithStatus(http.StatusNoContent)
+			return
+		}
+		if exposeHeaders != "" {
+			c.Header("Access-Control-Expose-Headers", exposeHeaders)
+		}
+		c.Next()
+	}, nil
+}
+
+type originMatcher struct {
+	any      bool
+	exact    map[string]struct{}
+	wildcard []wildcardOrigin
+}
+
+// wildcardOrigin https://*.example.com 拆分为 https:// 与 .example.com
+type wildcardOrigin struct {
+	prefix string
+	suffix string
+}
+
+func newOriginMatcher(origins []string) *originMatcher {
+	m := &originMatcher{exact: map[string]struct{}{}}
+	for _, origin := range origins {
+		origin = strings.ToLower(strings.TrimSpace(origin))
+		switch {
+		case origin == "*":
+			m.any = true
+		case strings.Contains(origin, "*"):
+			i := strings.Index(origin, "*")
+			m.wildcard = append(m.wildcard, wildcardOrigin{prefix: origin[:i], suffix: origin[i+1:]})
+		default:
+			m.exact[origin] = struct{}{}
+		}
+	}
+	return m
+}
+
+func (m *originMatcher) match(origin string) bool {
+	if m.any {
+		return true
+	}
+	origin = strings.ToLower(origin)
+	if _, ok := m.exact[origin]; ok {
+		return true
+	}
+	for _, w := range m.wildcard {
+		if len(origin) > len(w.prefix)+len(w.suffix) && strings.HasPrefix(origin, w.prefix) && strings.HasSuffix(origin, w.suffix) {
+			return true
+		}
+	}
+	return false
+}
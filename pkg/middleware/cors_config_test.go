@@ -0,0 +1,99 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func newCORSTestEngine(t *testing.T, conf CORSConf) *gin.Engine {
+	engine := gin.New()
+	assert.NoError(t, RegistryCORS(engine, conf))
+	engine.GET("/api", func(c *gin.Context) { c.String(http.StatusOK, "ok") })
+	return engine
+}
+
+func doCORSRequest(engine *gin.Engine, method, origin string, headers map[string]string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(method, "/api", nil)
+	if origin != "" {
+		req.Header.Set("Origin", origin)
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	engine.ServeHTTP(w, req)
+	return w
+}
+
+func TestCORSPreflight(t *testing.T) {
+	engine := newCORSTestEngine(t, CORSConf{
+		AllowOrigins:     []string{"https://app.example.com", "https://*.example.org"},
+		AllowCredentials: true,
+	})
+
+	w := doCORSRequest(engine, http.MethodOptions, "https://a.example.org", map[string]string{
+		"Access-Control-Request-Method":  "POST",
+		"Access-Control-Request-Headers": "Content-Type",
+	})
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, "https://a.example.org", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "true", w.Header().Get("Access-Control-Allow-Credentials"))
+	assert.Contains(t, w.Header().Get("Access-Control-Allow-Methods"), "POST")
+	assert.Equal(t, "43200", w.Header().Get("Access-Control-Max-Age"))
+	assert.Equal(t, []string{"Origin", "Access-Control-Request-Method", "Access-Control-Request-Headers"}, w.Header().Values("Vary"))
+
+	w = doCORSRequest(engine, http.MethodGet, "https://app.example.com", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "https://app.example.com", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, []string{"Origin"}, w.Header().Values("Vary"))
+}
+
+func TestCORSRejectOrigin(t *testing.T) {
+	engine := newCORSTestEngine(t, CORSConf{
+		AllowOrigins: []string{"https://*.example.org"},
+	})
+
+	w := doCORSRequest(engine, http.MethodOptions, "https://example.org.evil.com", map[string]string{
+		"Access-Control-Request-Method": "GET",
+	})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+
+	// 非预检请求不回显源，由浏览器拦截
+	w = doCORSRequest(engine, http.MethodGet, "https://evil.com", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, []string{"Origin"}, w.Header().Values("Vary"))
+}
+
+func TestCORSAllowOriginFunc(t *testing.T) {
+	engine := newCORSTestEngine(t, CORSConf{
+		AllowOriginFunc: func(origin string) bool { return origin == "http://localhost:3000" },
+	})
+
+	w := doCORSRequest(engine, http.MethodGet, "http://localhost:3000", nil)
+	assert.Equal(t, "http://localhost:3000", w.Header().Get("Access-Control-Allow-Origin"))
+}
+
+func TestCORSWildcard(t *testing.T) {
+	engine := newCORSTestEngine(t, CORSConf{AllowOrigins: []string{"*"}})
+
+	w := doCORSRequest(engine, http.MethodGet, "https://any.com", nil)
+	assert.Equal(t, "*", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Empty(t, w.Header().Values("Vary"))
+}
+
+func TestCORSInvalidConf(t *testing.T) {
+	err := RegistryCORS(gin.New(), CORSConf{AllowOrigins: []string{"*"}, AllowCredentials: true})
+	assert.Error(t, err)
+
+	_, err = CORS(CORSConf{AllowOrigins: []string{"https://*.*.example.com"}})
+	assert.Error(t, err)
+
+	_, err = CORS(CORSConf{})
+	assert.Error(t, err)
+}
@@ -0,0 +1,329 @@
+// Package middleware -----------------------------
+// @file      : limits.go
+// @author    : xiangtao
+// @contact   : xiangtao1994@gmail.com
+// @time      : 2025/9/13 10:20
+// Description: 请求体大小限制与处理超时
+// -------------------------------------------
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	errors2 "github.com/xiangtao94/golib/pkg/errors"
+	"github.com/xiangtao94/golib/pkg/render"
+	"github.com/xiangtao94/golib/pkg/zlog"
+)
+
+const _defaultMaxBodySize = 10 << 20
+
+type LimitsConf struct {
+	// MaxBodySize 请求体最大字节数，默认10MB，-1 不限制
+	MaxBodySize int64 `yaml:"maxBodySize"`
+	// Timeout 处理超时时间，0 不限制
+	Timeout time.Duration `yaml:"timeout"`
+	// RouteTimeouts 按路由（gin FullPath，如 /api/user/:id）单独设置超时，优先于 Timeout，<= 0 表示该路由不限制
+	RouteTimeouts map[string]time.Duration `yaml:"routeTimeouts"`
+	// ExemptPaths 不受限制的路径（请求路径或 FullPath），如 SSE 推送、文件上传
+	ExemptPaths []string `yaml:"exemptPaths"`
+	// Exempt 自定义豁免规则，返回 true 时不受限制
+	Exempt func(ctx *gin.Context) bool `yaml:"-"`
+}
+
+func DefaultLimitsConf() LimitsConf {
+	return LimitsConf{
+		MaxBodySize: _defaultMaxBodySize,
+	}
+}
+
+func mergeWithDefaultLimits(userConf LimitsConf) LimitsConf {
+	defaultConf := DefaultLimitsConf()
+	if userConf.MaxBodySize == 0 {
+		userConf.MaxBodySize = defaultConf.MaxBodySize
+	}
+	return userConf
+}
+
+// RegistryLimits 在 engine 上全局注册请求体大小与超时限制
+// 需在 AccessLog 之前注册，否则 AccessLog 会先读取完整的请求体
+func RegistryLimits(engine *gin.Engine, conf LimitsConf) {
+	engine.Use(Limits(conf))
+}
+
+// Limits 根据配置生成限制中间件
+// 请求体超限返回413，处理超时返回504，均通过 render.RenderJsonFailWithStatus 输出
+func Limits(conf LimitsConf) gin.HandlerFunc {
+	conf = mergeWithDefaultLimits(conf)
+	exempt := make(map[string]struct{}, len(conf.ExemptPaths))
+	for _, path := range conf.ExemptPaths {
+		exempt[path] = struct{}{}
+	}
+
+	return func(c *gin.Context) {
+		if _, ok := exempt[c.Request.URL.Path]; ok {
+			c.Next()
+			return
+		}
+		if _, ok := exempt[c.FullPath()]; ok {
+			c.Next()
+			return
+		}
+		if conf.Exempt != nil && conf.Exempt(c) {
+			c.Next()
+			return
+		}
+
+		var body *limitedBody
+		var lw *limitedWriter
+		if conf.MaxBodySize > 0 && c.Request.Body != nil && c.Request.Body != http.NoBody {
+			// Content-Length 已超限时直接拒绝，不进入业务逻辑
+			if c.Request.ContentLength > conf.MaxBodySize {
+				abortTooLarge(c, conf.MaxBodySize)
+				return
+			}
+			body = &limitedBody{ReadCloser: http.MaxBytesReader(c.Writer, c.Request.Body, conf.MaxBodySize)}
+			c.Request.Body = body
+			lw = &limitedWriter{ResponseWriter: c.Writer, body: body}
+			c.Writer = lw
+		}
+
+		timeout := conf.Timeout
+		if t, ok := conf.RouteTimeouts[c.FullPath()]; ok {
+			timeout = t
+		}
+		if timeout > 0 {
+			nextWithTimeout(c, timeout)
+		} else {
+			c.Next()
+		}
+
+		// chunked 请求读取时才超限，业务输出（通常是 bind 失败的参数错误）已被丢弃，统一返回413
+		if lw != nil {
+			c.Writer = lw.ResponseWriter
+			if lw.dropped || (!c.Writer.Written() && body.exceeded.Load()) {
+				abortTooLarge(c, conf.MaxBodySize)
+			}
+		}
+	}
+}
+
+func abortTooLarge(c *gin.Context, limit int64) {
+	zlog.Warnf(c, "request body too large, limit: %d, uri: %s", limit, c.Request.URL.Path)
+	render.RenderJsonFailWithStatus(c, http.StatusRequestEntityTooLarge, errors2.ErrorRequestTooLarge)
+	c.Abort()
+}
+
+// limitedBody 记录请求体是否超限
+type limitedBody struct {
+	io.ReadCloser
+	exceeded atomic.Bool
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	var maxErr *http.MaxBytesError
+	if errors.As(err, &maxErr) {
+		b.exceeded.Store(true)
+	}
+	return n, err
+}
+
+// limitedWriter 在响应真正输出前检查请求体是否超限，超限时丢弃业务响应，由 Limits 返回413
+// 超时模式下处理函数写入缓冲区，缓冲的响应写回时同样经过这里
+type limitedWriter struct {
+	gin.ResponseWriter
+	body    *limitedBody
+	dropped bool
+}
+
+func (w *limitedWriter) drop() bool {
+	if !w.dropped && !w.ResponseWriter.Written() && w.body.exceeded.Load() {
+		w.dropped = true
+		// 业务设置的响应头（如 Content-Length）不再适用
+		header := w.ResponseWriter.Header()
+		for k := range header {
+			delete(header, k)
+		}
+	}
+	return w.dropped
+}
+
+func (w *limitedWriter) WriteHeaderNow() {
+	if !w.drop() {
+		w.ResponseWriter.WriteHeaderNow()
+	}
+}
+
+func (w *limitedWriter) Write(b []byte) (int, error) {
+	if w.drop() {
+		return len(b), nil
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *limitedWriter) WriteString(s string) (int, error) {
+	if w.drop() {
+		return len(s), nil
+	}
+	return w.ResponseWriter.WriteString(s)
+}
+
+func (w *limitedWriter) Flush() {
+	if !w.drop() {
+		w.ResponseWriter.Flush()
+	}
+}
+
+// nextWithTimeout 在独立协程中执行后续处理函数，响应先写入缓冲区
+// 超时后立即返回504，处理函数之后的写入被丢弃；等处理函数退出后才返回，避免并发访问 gin.Context
+func nextWithTimeout(c *gin.Context, timeout time.Duration) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+	defer cancel()
+	c.Request = c.Request.WithContext(ctx)
+
+	w := c.Writer
+	tw := newTimeoutWriter(w)
+	c.Writer = tw
+
+	done := make(chan struct{})
+	var p any
+	go func() {
+		defer close(done)
+		defer func() {
+			p = recover()
+		}()
+		c.Next()
+	}()
+
+	timedOut := false
+	select {
+	case <-done:
+	case <-ctx.Done():
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			timedOut = true
+			tw.timeout()
+			zlog.Warnf(c, "request timeout after %s, uri: %s", timeout, c.Request.URL.Path)
+			writeTimeout(c.Copy(), w)
+		}
+		<-done
+	}
+
+	c.Writer = w
+	if timedOut {
+		if p != nil {
+			zlog.Errorf(c, "handler panic after timeout: %v", p)
+		}
+		c.Abort()
+		return
+	}
+	if p != nil {
+		panic(p)
+	}
+	tw.writeTo(w)
+}
+
+// writeTimeout 使用 Context 副本渲染504，带 Content-Length 保证客户端无需等待处理函数退出
+func writeTimeout(cp *gin.Context, w gin.ResponseWriter) {
+	rw := newTimeoutWriter(w)
+	cp.Writer = rw
+	render.RenderJsonFailWithStatus(cp, http.StatusGatewayTimeout, errors2.ErrorRequestTimeout)
+	rw.Header().Set("Content-Length", strconv.Itoa(rw.body.Len()))
+	rw.writeTo(w)
+	w.Flush()
+}
+
+// timeoutWriter 缓存状态码、响应头和响应体，处理完成后一次性写入原始 writer，
+// 保证超时与正常响应只有一方调用 WriteHeader
+type timeoutWriter struct {
+	gin.ResponseWriter
+	header   http.Header
+	body     bytes.Buffer
+	status   int
+	written  bool
+	timedOut atomic.Bool
+}
+
+func newTimeoutWriter(w gin.ResponseWriter) *timeoutWriter {
+	return &timeoutWriter{
+		ResponseWriter: w,
+		header:         w.Header().Clone(),
+		status:         http.StatusOK,
+	}
+}
+
+func (w *timeoutWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *timeoutWriter) WriteHeader(code int) {
+	if code > 0 && !w.written {
+		w.status = code
+	}
+}
+
+func (w *timeoutWriter) WriteHeaderNow() {
+	w.written = true
+}
+
+func (w *timeoutWriter) Write(b []byte) (int, error) {
+	if w.timedOut.Load() {
+		return 0, http.ErrHandlerTimeout
+	}
+	w.written = true
+	return w.body.Write(b)
+}
+
+func (w *timeoutWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func (w *timeoutWriter) Status() int {
+	if w.timedOut.Load() {
+		return http.StatusGatewayTimeout
+	}
+	return w.status
+}
+
+func (w *timeoutWriter) Size() int {
+	if !w.written {
+		return -1
+	}
+	return w.body.Len()
+}
+
+func (w *timeoutWriter) Written() bool {
+	return w.written
+}
+
+// Flush 缓冲模式下不下发数据，流式接口需加入豁免
+func (w *timeoutWriter) Flush() {
+	w.WriteHeaderNow()
+}
+
+func (w *timeoutWriter) timeout() {
+	w.timedOut.Store(true)
+}
+
+func (w *timeoutWriter) writeTo(dst gin.ResponseWriter) {
+	header := dst.Header()
+	for k := range header {
+		delete(header, k)
+	}
+	for k, v := range w.header {
+		header[k] = v
+	}
+	dst.WriteHeader(w.status)
+	if w.written {
+		dst.WriteHeaderNow()
+		_, _ = dst.Write(w.body.Bytes())
+	}
+}
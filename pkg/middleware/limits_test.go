@@ -0,0 +1,178 @@
+package middleware
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	errors2 "github.com/xiangtao94/golib/pkg/errors"
+)
+
+func newLimitsTestEngine(conf LimitsConf) (*gin.Engine, *bool) {
+	engine := gin.New()
+	RegistryLimits(engine, conf)
+	RegistryAccessLog(engine)
+	called := false
+	engine.POST("/upload", func(c *gin.Context) {
+		called = true
+		data, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			return
+		}
+		c.String(http.StatusOK, "%d", len(data))
+	})
+	engine.POST("/bind", func(c *gin.Context) {
+		called = true
+		var req map[string]any
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, req)
+	})
+	engine.GET("/slow", func(c *gin.Context) {
+		called = true
+		select {
+		case <-c.Request.Context().Done():
+			c.String(http.StatusOK, "late")
+		case <-time.After(time.Second):
+			c.String(http.StatusOK, "done")
+		}
+	})
+	engine.GET("/fast", func(c *gin.Context) {
+		called = true
+		c.Header("X-Handler", "fast")
+		c.String(http.StatusCreated, "fast")
+	})
+	return engine, &called
+}
+
+func decodeLimitsCode(t *testing.T, w *httptest.ResponseRecorder) int {
+	var resp struct {
+		Code int `json:"code"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	return resp.Code
+}
+
+func TestLimitsBodyTooLarge(t *testing.T) {
+	engine, called := newLimitsTestEngine(LimitsConf{MaxBodySize: 8})
+
+	// Content-Length 超限，不进入处理函数
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/upload", strings.NewReader("0123456789"))
+	engine.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	assert.Equal(t, errors2.REQUEST_TOO_LARGE, decodeLimitsCode(t, w))
+	assert.False(t, *called)
+
+	// 未知长度的请求体读取时超限
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodPost, "/upload", io.NopCloser(strings.NewReader("0123456789")))
+	req.ContentLength = -1
+	engine.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	assert.Equal(t, errors2.REQUEST_TOO_LARGE, decodeLimitsCode(t, w))
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodPost, "/upload", strings.NewReader("01234567"))
+	engine.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "8", w.Body.String())
+}
+
+func TestLimitsChunkedBodyTooLarge(t *testing.T) {
+	for name, conf := range map[string]LimitsConf{
+		"no timeout": {MaxBodySize: 8},
+		"timeout":    {MaxBodySize: 8, Timeout: time.Second},
+	} {
+		t.Run(name, func(t *testing.T) {
+			engine, called := newLimitsTestEngine(conf)
+
+			// bind 失败后业务输出了参数错误，仍返回413
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(http.MethodPost, "/bind", io.NopCloser(strings.NewReader(`{"name":"0123456789"}`)))
+			req.ContentLength = -1
+			req.Header.Set("Content-Type", "application/json")
+			engine.ServeHTTP(w, req)
+			assert.True(t, *called)
+			assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+			assert.Equal(t, errors2.REQUEST_TOO_LARGE, decodeLimitsCode(t, w))
+
+			// 未超限的 chunked 请求正常处理
+			w = httptest.NewRecorder()
+			req, _ = http.NewRequest(http.MethodPost, "/bind", io.NopCloser(strings.NewReader(`{"a":1}`)))
+			req.ContentLength = -1
+			req.Header.Set("Content-Type", "application/json")
+			engine.ServeHTTP(w, req)
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.JSONEq(t, `{"a":1}`, w.Body.String())
+		})
+	}
+}
+
+func TestLimitsTimeout(t *testing.T) {
+	engine, _ := newLimitsTestEngine(LimitsConf{Timeout: 50 * time.Millisecond})
+
+	start := time.Now()
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/slow", nil)
+	engine.ServeHTTP(w, req)
+	assert.Less(t, time.Since(start), time.Second)
+	assert.Equal(t, http.StatusGatewayTimeout, w.Code)
+	// 处理函数超时后的写入被丢弃，只有一次响应
+	assert.Equal(t, errors2.REQUEST_TIMEOUT, decodeLimitsCode(t, w))
+	assert.NotContains(t, w.Body.String(), "late")
+
+	// 未超时的响应原样输出
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodGet, "/fast", nil)
+	engine.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "fast", w.Header().Get("X-Handler"))
+	assert.Equal(t, "fast", w.Body.String())
+}
+
+func TestLimitsRouteTimeout(t *testing.T) {
+	engine, _ := newLimitsTestEngine(LimitsConf{
+		Timeout:       time.Second,
+		RouteTimeouts: map[string]time.Duration{"/slow": 20 * time.Millisecond},
+	})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/slow", nil)
+	engine.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusGatewayTimeout, w.Code)
+}
+
+func TestLimitsExempt(t *testing.T) {
+	engine, called := newLimitsTestEngine(LimitsConf{
+		MaxBodySize: 8,
+		Timeout:     20 * time.Millisecond,
+		ExemptPaths: []string{"/upload"},
+		Exempt: func(ctx *gin.Context) bool {
+			return ctx.GetHeader("Accept") == "text/event-stream"
+		},
+	})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/upload", strings.NewReader("0123456789"))
+	engine.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "10", w.Body.String())
+	assert.True(t, *called)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodGet, "/slow", nil)
+	req.Header.Set("Accept", "text/event-stream")
+	engine.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "done", w.Body.String())
+}
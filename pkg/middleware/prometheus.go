@@ -7,9 +7,11 @@ import (
 	"github.com/prometheus/client_golang/prometheus/collectors"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/xiangtao94/golib/pkg/env"
+	errors2 "github.com/xiangtao94/golib/pkg/errors"
 	"github.com/xiangtao94/golib/pkg/orm"
 	"github.com/xiangtao94/golib/pkg/zlog"
 	"net/http"
+	"sync"
 	"time"
 )
 
@@ -49,22 +51,107 @@ var (
 			Help:      "HTTP response sizes in bytes.",
 		}, labels,
 	)
+
+	// accessLogSampledOut 被采样丢弃的 access 日志数，与打印的条数相加为总请求数
+	accessLogSampledOut = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "access_log_sampled_out_total",
+			Help:      "Total number of access log entries dropped by sampling.",
+		},
+	)
+
+	// quotaRequests 调用配额的检查结果，result 为 allowed、rejected、observed、error
+	quotaRequests = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "quota_requests_total",
+			Help:      "Total number of requests checked against call quotas.",
+		}, []string{"name", "result"},
+	)
+)
+
+var buildInfoDesc = prometheus.NewDesc(
+	namespace+"_app_build_info",
+	"Build information of the running binary, value is always 1.",
+	[]string{"version", "git_sha"}, nil,
+)
+
+// buildInfoCollector 采集时读取 env 的构建信息，注册后调用 env.SetBuildInfo 也能生效
+type buildInfoCollector struct{}
+
+func (buildInfoCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- buildInfoDesc
+}
+
+func (buildInfoCollector) Collect(ch chan<- prometheus.Metric) {
+	ch <- prometheus.MustNewConstMetric(buildInfoDesc, prometheus.GaugeValue, 1, env.GetVersion(), env.GetGitSHA())
+}
+
+const (
+	// 未匹配路由（404）的 endpoint 标签
+	unmatchedEndpoint = "unmatched"
+	// 超过 MaxEndpoints 后新出现的 endpoint 统一使用的标签
+	overflowEndpoint = "other"
 )
 
+type PromConfig struct {
+	// SkipPaths 不统计的路径，默认 /metrics
+	SkipPaths []string `yaml:"skipPaths"`
+	// MaxEndpoints endpoint 标签的最大取值个数，超出后记为 other，0表示不限制
+	MaxEndpoints int `yaml:"maxEndpoints"`
+}
+
+func DefaultPromConfig() PromConfig {
+	return PromConfig{
+		SkipPaths:    []string{"/metrics"},
+		MaxEndpoints: 0,
+	}
+}
+
+func mergeWithDefaultProm(userConf PromConfig) PromConfig {
+	defaultConf := DefaultPromConfig()
+	if userConf.SkipPaths == nil {
+		userConf.SkipPaths = defaultConf.SkipPaths
+	}
+	if userConf.MaxEndpoints < 0 {
+		userConf.MaxEndpoints = defaultConf.MaxEndpoints
+	}
+	return userConf
+}
+
+// packageCollectors 各组件包初始化后暴露的指标，未初始化的为 nil
+func packageCollectors() []prometheus.Collector {
+	return []prometheus.Collector{
+		orm.MysqlPromCollector,
+		errors2.BusinessErrorTotal,
+	}
+}
+
 func RegistryMetrics(engine *gin.Engine, cs ...prometheus.Collector) {
+	RegistryMetricsWithConfig(engine, PromConfig{}, cs...)
+}
+
+// RegistryMetricsWithConfig 注册 /metrics 与请求指标中间件，自动注册已初始化组件的指标
+func RegistryMetricsWithConfig(engine *gin.Engine, conf PromConfig, cs ...prometheus.Collector) {
 	runtimeMetricsRegister := prometheus.NewRegistry()
 	runtimeMetricsRegister.MustRegister(collectors.NewGoCollector(),
 		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
 		reqCount,
 		reqDuration,
 		reqSizeBytes,
-		respSizeBytes)
-	if orm.MysqlPromCollector != nil {
-		runtimeMetricsRegister.MustRegister(orm.MysqlPromCollector)
+		respSizeBytes,
+		accessLogSampledOut,
+		quotaRequests,
+		buildInfoCollector{})
+	for _, c := range packageCollectors() {
+		if c != nil {
+			runtimeMetricsRegister.MustRegister(c)
+		}
 	}
 	// 自定义监控指标
 	runtimeMetricsRegister.MustRegister(cs...)
-	engine.Use(PromMiddleware(env.AppName))
+	engine.Use(PromMiddlewareWithConfig(env.AppName, conf))
 	engine.GET("/metrics", func(ctx *gin.Context) {
 		// 避免metrics打点输出过多无用日志
 		zlog.SetNoLogFlag(ctx)
@@ -76,14 +163,30 @@ func RegistryMetrics(engine *gin.Engine, cs ...prometheus.Collector) {
 }
 
 func PromMiddleware(appName string) gin.HandlerFunc {
+	return PromMiddlewareWithConfig(appName, PromConfig{})
+}
+
+// PromMiddlewareWithConfig endpoint 标签使用路由模板（如 /users/:id）而不是原始路径，避免标签基数膨胀
+func PromMiddlewareWithConfig(appName string, conf PromConfig) gin.HandlerFunc {
+	conf = mergeWithDefaultProm(conf)
+	skip := make(map[string]struct{}, len(conf.SkipPaths))
+	for _, path := range conf.SkipPaths {
+		skip[path] = struct{}{}
+	}
+	guard := newEndpointGuard(conf.MaxEndpoints)
+
 	return func(c *gin.Context) {
-		if c.Request.URL.Path == "/metrics" {
+		if _, ok := skip[c.Request.URL.Path]; ok {
 			return
 		}
 		start := time.Now()
 		c.Next()
 		status := fmt.Sprintf("%d", c.Writer.Status())
-		endpoint := c.Request.URL.Path
+		endpoint := c.FullPath()
+		if endpoint == "" {
+			endpoint = unmatchedEndpoint
+		}
+		endpoint = guard.label(endpoint)
 		method := c.Request.Method
 		lvs := []string{appName, status, endpoint, method}
 		// no response content will return -1
@@ -98,6 +201,39 @@ func PromMiddleware(appName string) gin.HandlerFunc {
 	}
 }
 
+// endpointGuard 限制 endpoint 标签的取值个数
+type endpointGuard struct {
+	max  int
+	mu   sync.RWMutex
+	seen map[string]struct{}
+}
+
+func newEndpointGuard(max int) *endpointGuard {
+	return &endpointGuard{max: max, seen: map[string]struct{}{}}
+}
+
+func (g *endpointGuard) label(endpoint string) string {
+	if g.max <= 0 {
+		return endpoint
+	}
+	g.mu.RLock()
+	_, ok := g.seen[endpoint]
+	g.mu.RUnlock()
+	if ok {
+		return endpoint
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if _, ok := g.seen[endpoint]; ok {
+		return endpoint
+	}
+	if len(g.seen) >= g.max {
+		return overflowEndpoint
+	}
+	g.seen[endpoint] = struct{}{}
+	return endpoint
+}
+
 func getRequestCostInSeconds(start, end time.Time) float64 {
 	seconds := end.Sub(start).Seconds()
 	return seconds
@@ -0,0 +1,93 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/xiangtao94/golib/pkg/env"
+	"github.com/xiangtao94/golib/pkg/zlog"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+	zlog.InitLog(zlog.LogConfig{})
+}
+
+func newPromTestEngine(conf PromConfig) *gin.Engine {
+	reqCount.Reset()
+	reqDuration.Reset()
+	reqSizeBytes.Reset()
+	respSizeBytes.Reset()
+
+	engine := gin.New()
+	engine.Use(PromMiddlewareWithConfig("test", conf))
+	ok := func(c *gin.Context) { c.String(http.StatusOK, "ok") }
+	engine.GET("/users/:id", ok)
+	engine.GET("/orders/:id", ok)
+	engine.GET("/items/:id", ok)
+	engine.GET("/healthz", ok)
+	return engine
+}
+
+func doPromRequest(engine *gin.Engine, path string) {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, path, nil)
+	engine.ServeHTTP(w, req)
+}
+
+func TestPromMiddlewareEndpointLabel(t *testing.T) {
+	engine := newPromTestEngine(PromConfig{})
+
+	doPromRequest(engine, "/users/1")
+	doPromRequest(engine, "/users/2")
+	doPromRequest(engine, "/not-found/123")
+
+	// 不同id只产生一个路由模板的序列
+	assert.Equal(t, 2, testutil.CollectAndCount(reqCount))
+	assert.Equal(t, float64(2), testutil.ToFloat64(reqCount.WithLabelValues("test", "200", "/users/:id", "GET")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(reqCount.WithLabelValues("test", "404", "unmatched", "GET")))
+}
+
+func TestPromMiddlewareSkipPaths(t *testing.T) {
+	engine := newPromTestEngine(PromConfig{SkipPaths: []string{"/healthz"}})
+
+	doPromRequest(engine, "/healthz")
+	doPromRequest(engine, "/users/1")
+
+	assert.Equal(t, 1, testutil.CollectAndCount(reqCount))
+}
+
+func TestPromMiddlewareMaxEndpoints(t *testing.T) {
+	engine := newPromTestEngine(PromConfig{MaxEndpoints: 2})
+
+	doPromRequest(engine, "/users/1")
+	doPromRequest(engine, "/orders/1")
+	doPromRequest(engine, "/items/1")
+	doPromRequest(engine, "/users/2")
+
+	assert.Equal(t, float64(2), testutil.ToFloat64(reqCount.WithLabelValues("test", "200", "/users/:id", "GET")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(reqCount.WithLabelValues("test", "200", "other", "GET")))
+	assert.Equal(t, 3, testutil.CollectAndCount(reqCount))
+}
+
+func TestBuildInfoMetric(t *testing.T) {
+	t.Cleanup(func() { env.SetBuildInfo("", "", "") })
+	engine := gin.New()
+	RegistryMetrics(engine)
+	scrape := func() string {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/metrics", nil)
+		engine.ServeHTTP(w, req)
+		return w.Body.String()
+	}
+
+	// 未设置时为 unknown，之后设置的构建信息在下次采集时生效
+	assert.Contains(t, scrape(), `monitor_app_build_info{git_sha="unknown",version="unknown"} 1`)
+	env.SetBuildInfo("v1.2.3", "abc1234", "")
+	assert.Contains(t, scrape(), `monitor_app_build_info{git_sha="abc1234",version="v1.2.3"} 1`)
+}
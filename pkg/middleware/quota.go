@@ -0,0 +1,315 @@
+// Package middleware -----------------------------
+// @file      : quota.go
+// @author    : xiangtao
+// @contact   : xiangtao1994@gmail.com
+// @time      : 2025/9/14 17:40
+// Description: 按调用方统计的调用配额（如每个 API Key 每天1万次），计数保存在Redis
+// -------------------------------------------
+package middleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	goredis "github.com/redis/go-redis/v9"
+
+	errors2 "github.com/xiangtao94/golib/pkg/errors"
+	"github.com/xiangtao94/golib/pkg/redis"
+	"github.com/xiangtao94/golib/pkg/render"
+	"github.com/xiangtao94/golib/pkg/zlog"
+)
+
+// 配额窗口
+const (
+	QuotaWindowHour  = "hour"
+	QuotaWindowDay   = "day"
+	QuotaWindowMonth = "month"
+)
+
+// 配额检查结果，用于 monitor_quota_requests_total 的 result 标签
+const (
+	quotaResultAllowed  = "allowed"
+	quotaResultRejected = "rejected"
+	quotaResultObserved = "observed" // 超限但只观察不拒绝
+	quotaResultError    = "error"
+)
+
+// QuotaRule 一个窗口的配额，窗口按自然时间划分，如 day 为所在时区的0点到次日0点
+type QuotaRule struct {
+	Limit    int64  `yaml:"limit"`    // 窗口内允许的调用次数
+	Window   string `yaml:"window"`   // hour、day（默认）、month
+	Timezone string `yaml:"timezone"` // 划分窗口的时区，如 Asia/Shanghai，默认本地时区
+
+	loc *time.Location
+}
+
+// QuotaConf 调用配额配置，与限流不同，配额按自然窗口累计，窗口结束后清零
+type QuotaConf struct {
+	Name   string      `yaml:"name"`   // 配额名称，区分不同路由组的配额，默认 default
+	Rules  []QuotaRule `yaml:"rules"`  // 可同时配置多个窗口，如每天1万次且每小时1千次，任一超限即拒绝
+	Header string      `yaml:"header"` // 从请求头取调用方，未配置时优先取鉴权主体的 Subject，其次 X-API-Key
+	Claim  string      `yaml:"claim"`  // 从鉴权主体的声明中取调用方，优先于 Header
+	// 只记录日志和指标，不拒绝请求，用于上线初期观察
+	ObserveOnly bool `yaml:"observeOnly"`
+	// Redis 不可用时是否拒绝请求，默认放行并记录warn日志
+	FailClosed bool   `yaml:"failClosed"`
+	KeyPrefix  string `yaml:"keyPrefix"` // 计数key前缀，默认 quota:，会再带上 redis.GetKeyPrefix()
+	// 存储计数的Redis
+	Redis *redis.Redis `yaml:"-"`
+	// 自定义调用方，优先于 Claim 和 Header；返回空字符串时不统计
+	KeyFunc func(c *gin.Context) string `yaml:"-"`
+}
+
+// QuotaStatus 调用方在一个窗口内的用量
+type QuotaStatus struct {
+	Window    string    `json:"window"`
+	Limit     int64     `json:"limit"`
+	Used      int64     `json:"used"`
+	Remaining int64     `json:"remaining"`
+	Reset     time.Time `json:"reset"` // 窗口结束时间，之后重新计数
+}
+
+// Quota 调用配额中间件，Handler 统计并检查配额，QuotaUsage 查询用量
+type Quota struct {
+	conf  QuotaConf
+	store quotaStore
+	now   func() time.Time
+}
+
+// quotaStore 配额计数存储，incr 对每个key加1并在 expireAt 过期，返回加1后的值
+type quotaStore interface {
+	incr(ctx context.Context, keys []string, expireAt []time.Time) ([]int64, error)
+	get(ctx context.Context, keys []string) ([]int64, error)
+}
+
+// RegistryQuota 在 engine 或路由组上注册调用配额，返回的 *Quota 可用于查询用量
+func RegistryQuota(r gin.IRoutes, conf QuotaConf) (*Quota, error) {
+	q, err := NewQuota(conf)
+	if err != nil {
+		return nil, err
+	}
+	r.Use(q.Handler())
+	return q, nil
+}
+
+// NewQuota 根据配置创建调用配额，配置不合法时返回错误
+func NewQuota(conf QuotaConf) (*Quota, error) {
+	if conf.Redis == nil {
+		return nil, fmt.Errorf("quota conf: redis is required")
+	}
+	if len(conf.Rules) == 0 {
+		return nil, fmt.Errorf("quota conf: rules is empty")
+	}
+	rules := make([]QuotaRule, len(conf.Rules))
+	for i, rule := range conf.Rules {
+		if rule.Limit <= 0 {
+			return nil, fmt.Errorf("quota conf: limit must be positive")
+		}
+		switch rule.Window {
+		case "":
+			rule.Window = QuotaWindowDay
+		case QuotaWindowHour, QuotaWindowDay, QuotaWindowMonth:
+		default:
+			return nil, fmt.Errorf("quota conf: unsupported window %q", rule.Window)
+		}
+		rule.loc = time.Local
+		if rule.Timezone != "" {
+			loc, err := time.LoadLocation(rule.Timezone)
+			if err != nil {
+				return nil, fmt.Errorf("quota conf: invalid timezone %q: %w", rule.Timezone, err)
+			}
+			rule.loc = loc
+		}
+		rules[i] = rule
+	}
+	conf.Rules = rules
+	if conf.Name == "" {
+		conf.Name = "default"
+	}
+	if conf.KeyPrefix == "" {
+		conf.KeyPrefix = "quota:"
+	}
+	if conf.KeyFunc == nil {
+		conf.KeyFunc = quotaKeyFunc(conf.Claim, conf.Header)
+	}
+	return &Quota{conf: conf, store: &redisQuotaStore{client: conf.Redis}, now: time.Now}, nil
+}
+
+// quotaKeyFunc 默认调用方：配置了 Claim 时取鉴权主体的声明，否则取请求头，都未配置时优先取鉴权主体的 Subject
+func quotaKeyFunc(claim, header string) func(c *gin.Context) string {
+	return func(c *gin.Context) string {
+		principal := GetAuthPrincipal(c)
+		if claim != "" {
+			if principal == nil || principal.Claims[claim] == nil {
+				return ""
+			}
+			return fmt.Sprint(principal.Claims[claim])
+		}
+		if header != "" {
+			return c.GetHeader(header)
+		}
+		if principal != nil && principal.Subject != "" {
+			return principal.Subject
+		}
+		return c.GetHeader("X-API-Key")
+	}
+}
+
+// window 返回 t 所在窗口的开始和结束时间
+func (r QuotaRule) window(t time.Time) (time.Time, time.Time) {
+	t = t.In(r.loc)
+	y, m, d := t.Date()
+	switch r.Window {
+	case QuotaWindowHour:
+		start := time.Date(y, m, d, t.Hour(), 0, 0, 0, r.loc)
+		return start, start.Add(time.Hour)
+	case QuotaWindowMonth:
+		return time.Date(y, m, 1, 0, 0, 0, 0, r.loc), time.Date(y, m+1, 1, 0, 0, 0, 0, r.loc)
+	default:
+		return time.Date(y, m, d, 0, 0, 0, 0, r.loc), time.Date(y, m, d+1, 0, 0, 0, 0, r.loc)
+	}
+}
+
+// keys 计数key带上窗口开始时间，窗口切换后自然使用新的key
+// 调用方用 {} 包裹作为 hash tag，集群模式下同一调用方各窗口的key在同一个slot，MULTI 和 MGET 不会 CROSSSLOT
+func (q *Quota) keys(key string, now time.Time) ([]string, []time.Time) {
+	keys := make([]string, len(q.conf.Rules))
+	resets := make([]time.Time, len(q.conf.Rules))
+	for i, rule := range q.conf.Rules {
+		start, end := rule.window(now)
+		keys[i] = redis.GetKeyPrefix() + q.conf.KeyPrefix + q.conf.Name + ":" + rule.Window + ":" +
+			start.Format("2006010215") + ":{" + key + "}"
+		resets[i] = end
+	}
+	return keys, resets
+}
+
+func (q *Quota) statuses(counts []int64, resets []time.Time) []QuotaStatus {
+	statuses := make([]QuotaStatus, len(q.conf.Rules))
+	for i, rule := range q.conf.Rules {
+		statuses[i] = QuotaStatus{
+			Window:    rule.Window,
+			Limit:     rule.Limit,
+			Used:      counts[i],
+			Remaining: max(rule.Limit-counts[i], 0),
+			Reset:     resets[i],
+		}
+	}
+	return statuses
+}
+
+// QuotaUsage 查询调用方当前窗口的用量，不计数，供运营、客服工具使用
+func (q *Quota) QuotaUsage(ctx context.Context, key string) ([]QuotaStatus, error) {
+	keys, resets := q.keys(key, q.now())
+	counts, err := q.store.get(ctx, keys)
+	if err != nil {
+		return nil, fmt.Errorf("get quota usage: %w", err)
+	}
+	return q.statuses(counts, resets), nil
+}
+
+// Handler 统计调用次数，超限时返回429和 ErrorQuotaExceeded；超限后的请求也会计数
+// 响应头带剩余最少的窗口的 X-Quota-Limit、X-Quota-Remaining、X-Quota-Reset（窗口结束的unix时间戳）
+func (q *Quota) Handler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := q.conf.KeyFunc(c)
+		if key == "" {
+			c.Next()
+			return
+		}
+		keys, resets := q.keys(key, q.now())
+		counts, err := q.store.incr(c, keys, resets)
+		if err != nil {
+			q.record(quotaResultError)
+			zlog.Warnf(c, "quota redis error, name: %s, key: %s, err: %v", q.conf.Name, key, err)
+			if q.conf.FailClosed && !q.conf.ObserveOnly {
+				render.RenderJsonFailWithStatus(c, http.StatusTooManyRequests, errors2.ErrorTooManyRequests)
+				c.Abort()
+				return
+			}
+			c.Next()
+			return
+		}
+
+		statuses := q.statuses(counts, resets)
+		tightest, exceeded := statuses[0], false
+		for i, status := range statuses {
+			if status.Remaining < tightest.Remaining {
+				tightest = status
+			}
+			if counts[i] > status.Limit {
+				exceeded = true
+			}
+		}
+		c.Header("X-Quota-Limit", strconv.FormatInt(tightest.Limit, 10))
+		c.Header("X-Quota-Remaining", strconv.FormatInt(tightest.Remaining, 10))
+		c.Header("X-Quota-Reset", strconv.FormatInt(tightest.Reset.Unix(), 10))
+		if !exceeded {
+			q.record(quotaResultAllowed)
+			c.Next()
+			return
+		}
+		if q.conf.ObserveOnly {
+			q.record(quotaResultObserved)
+			zlog.Warnf(c, "quota exceeded (observe only), name: %s, key: %s", q.conf.Name, key)
+			c.Next()
+			return
+		}
+		q.record(quotaResultRejected)
+		zlog.Warnf(c, "quota exceeded, name: %s, key: %s", q.conf.Name, key)
+		render.RenderJsonFailWithStatus(c, http.StatusTooManyRequests, errors2.ErrorQuotaExceeded)
+		c.Abort()
+	}
+}
+
+func (q *Quota) record(result string) {
+	quotaRequests.WithLabelValues(q.conf.Name, result).Inc()
+}
+
+type redisQuotaStore struct {
+	client *redis.Redis
+}
+
+func (s *redisQuotaStore) incr(ctx context.Context, keys []string, expireAt []time.Time) ([]int64, error) {
+	cmds := make([]*goredis.IntCmd, len(keys))
+	_, err := s.client.TxPipelined(ctx, func(pipe goredis.Pipeliner) error {
+		for i, key := range keys {
+			cmds[i] = pipe.Incr(ctx, key)
+			pipe.ExpireAt(ctx, key, expireAt[i])
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	counts := make([]int64, len(keys))
+	for i, cmd := range cmds {
+		counts[i] = cmd.Val()
+	}
+	return counts, nil
+}
+
+func (s *redisQuotaStore) get(ctx context.Context, keys []string) ([]int64, error) {
+	values, err := s.client.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+	counts := make([]int64, len(keys))
+	for i, v := range values {
+		str, ok := v.(string)
+		if !ok {
+			continue
+		}
+		n, err := strconv.ParseInt(str, 10, 64)
+		if err != nil {
+			return nil, errors.New("invalid quota counter " + keys[i])
+		}
+		counts[i] = n
+	}
+	return counts, nil
+}
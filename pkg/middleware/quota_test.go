@@ -0,0 +1,282 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	goredis "github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	errors2 "github.com/xiangtao94/golib/pkg/errors"
+	"github.com/xiangtao94/golib/pkg/redis"
+)
+
+// quotaClock Quota 与 miniredis 共用的时钟，advance 同时推进两者，EXPIREAT 设置的key按窗口结束时间过期
+type quotaClock struct {
+	now time.Time
+	mr  *miniredis.Miniredis
+}
+
+func (c *quotaClock) advance(d time.Duration) {
+	c.now = c.now.Add(d)
+	c.mr.FastForward(d)
+	c.mr.SetTime(c.now)
+}
+
+func newTestQuota(t *testing.T, conf QuotaConf, now time.Time) (*Quota, *gin.Engine, *quotaClock) {
+	rdb, mr := newTestRedis(t)
+	mr.SetTime(now)
+	clock := &quotaClock{now: now, mr: mr}
+	conf.Redis = rdb
+	q, err := NewQuota(conf)
+	require.NoError(t, err)
+	q.now = func() time.Time { return clock.now }
+	engine := gin.New()
+	engine.Use(q.Handler())
+	engine.GET("/api", func(c *gin.Context) { c.String(http.StatusOK, "ok") })
+	return q, engine, clock
+}
+
+func quotaRequest(engine *gin.Engine, key string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/api", nil)
+	if key != "" {
+		req.Header.Set("X-API-Key", key)
+	}
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+	return w
+}
+
+func TestQuotaInvalidConf(t *testing.T) {
+	rdb, _ := newTestRedis(t)
+	_, err := NewQuota(QuotaConf{Rules: []QuotaRule{{Limit: 1}}})
+	assert.Error(t, err)
+	_, err = NewQuota(QuotaConf{Redis: rdb})
+	assert.Error(t, err)
+	_, err = NewQuota(QuotaConf{Redis: rdb, Rules: []QuotaRule{{Limit: 1, Window: "week"}}})
+	assert.Error(t, err)
+	_, err = NewQuota(QuotaConf{Redis: rdb, Rules: []QuotaRule{{Limit: 1, Timezone: "Nowhere/City"}}})
+	assert.Error(t, err)
+}
+
+func TestQuotaExhaustion(t *testing.T) {
+	loc, _ := time.LoadLocation("Asia/Shanghai")
+	now := time.Date(2025, 9, 14, 10, 0, 0, 0, loc)
+	_, engine, _ := newTestQuota(t, QuotaConf{Rules: []QuotaRule{{Limit: 2, Timezone: "Asia/Shanghai"}}}, now)
+
+	for i := 1; i <= 2; i++ {
+		w := quotaRequest(engine, "k1")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "2", w.Header().Get("X-Quota-Limit"))
+		assert.Equal(t, strconv.Itoa(2-i), w.Header().Get("X-Quota-Remaining"))
+		assert.Equal(t, strconv.FormatInt(time.Date(2025, 9, 15, 0, 0, 0, 0, loc).Unix(), 10), w.Header().Get("X-Quota-Reset"))
+	}
+	w := quotaRequest(engine, "k1")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, errors2.QUOTA_EXCEEDED, decodeLimitsCode(t, w))
+	assert.Equal(t, "0", w.Header().Get("X-Quota-Remaining"))
+
+	// 其他调用方不受影响，没有调用方的请求不统计
+	assert.Equal(t, http.StatusOK, quotaRequest(engine, "k2").Code)
+	for range 3 {
+		assert.Equal(t, http.StatusOK, quotaRequest(engine, "").Code)
+	}
+}
+
+func TestQuotaRolloverAtMidnight(t *testing.T) {
+	loc, _ := time.LoadLocation("Asia/Shanghai")
+	// 上海时间23:59，UTC 为15:59，按上海时区的0点切换窗口
+	now := time.Date(2025, 9, 14, 23, 59, 0, 0, loc).UTC()
+	q, engine, clock := newTestQuota(t, QuotaConf{Rules: []QuotaRule{{Limit: 1, Timezone: "Asia/Shanghai"}}}, now)
+
+	assert.Equal(t, http.StatusOK, quotaRequest(engine, "k1").Code)
+	assert.Equal(t, http.StatusTooManyRequests, quotaRequest(engine, "k1").Code)
+	keys, _ := q.keys("k1", now)
+	assert.Equal(t, time.Minute, clock.mr.TTL(keys[0]))
+
+	// 到上海时间0点旧窗口的计数过期
+	clock.advance(time.Minute)
+	assert.False(t, clock.mr.Exists(keys[0]))
+	w := quotaRequest(engine, "k1")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, strconv.FormatInt(time.Date(2025, 9, 16, 0, 0, 0, 0, loc).Unix(), 10), w.Header().Get("X-Quota-Reset"))
+}
+
+func TestQuotaMultipleWindows(t *testing.T) {
+	now := time.Date(2025, 9, 14, 10, 30, 0, 0, time.UTC)
+	_, engine, clock := newTestQuota(t, QuotaConf{Rules: []QuotaRule{
+		{Limit: 10, Window: QuotaWindowDay, Timezone: "UTC"},
+		{Limit: 2, Window: QuotaWindowHour, Timezone: "UTC"},
+	}}, now)
+
+	w := quotaRequest(engine, "k1")
+	assert.Equal(t, "2", w.Header().Get("X-Quota-Limit"))
+	assert.Equal(t, "1", w.Header().Get("X-Quota-Remaining"))
+	quotaRequest(engine, "k1")
+	assert.Equal(t, http.StatusTooManyRequests, quotaRequest(engine, "k1").Code)
+
+	// 下一个小时按小时的配额恢复，按天的配额继续累计
+	clock.advance(time.Hour)
+	w = quotaRequest(engine, "k1")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "1", w.Header().Get("X-Quota-Remaining"))
+}
+
+func TestQuotaObserveOnly(t *testing.T) {
+	_, engine, _ := newTestQuota(t, QuotaConf{Name: "observe", ObserveOnly: true, Rules: []QuotaRule{{Limit: 1}}}, time.Now())
+	observed := testutil.ToFloat64(quotaRequests.WithLabelValues("observe", quotaResultObserved))
+
+	assert.Equal(t, http.StatusOK, quotaRequest(engine, "k1").Code)
+	w := quotaRequest(engine, "k1")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "0", w.Header().Get("X-Quota-Remaining"))
+	// 超限后的请求仍然计数
+	w = quotaRequest(engine, "k1")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, observed+2, testutil.ToFloat64(quotaRequests.WithLabelValues("observe", quotaResultObserved)))
+}
+
+func TestQuotaRedisUnavailable(t *testing.T) {
+	for _, failClosed := range []bool{false, true} {
+		engine := gin.New()
+		rdb, mr := newTestRedis(t)
+		mr.Close()
+		_, err := RegistryQuota(engine, QuotaConf{Redis: rdb, FailClosed: failClosed, Rules: []QuotaRule{{Limit: 1}}})
+		assert.NoError(t, err)
+		engine.GET("/api", func(c *gin.Context) { c.String(http.StatusOK, "ok") })
+
+		w := quotaRequest(engine, "k1")
+		if failClosed {
+			assert.Equal(t, http.StatusTooManyRequests, w.Code)
+			assert.Equal(t, errors2.TOO_MANY_REQUESTS, decodeLimitsCode(t, w))
+		} else {
+			assert.Equal(t, http.StatusOK, w.Code)
+		}
+	}
+}
+
+func TestQuotaUsage(t *testing.T) {
+	now := time.Date(2025, 9, 14, 10, 0, 0, 0, time.UTC)
+	q, engine, _ := newTestQuota(t, QuotaConf{Rules: []QuotaRule{{Limit: 5, Window: QuotaWindowMonth, Timezone: "UTC"}}}, now)
+	quotaRequest(engine, "k1")
+	quotaRequest(engine, "k1")
+
+	usage, err := q.QuotaUsage(context.Background(), "k1")
+	assert.NoError(t, err)
+	assert.Equal(t, []QuotaStatus{{
+		Window:    QuotaWindowMonth,
+		Limit:     5,
+		Used:      2,
+		Remaining: 3,
+		Reset:     time.Date(2025, 10, 1, 0, 0, 0, 0, time.UTC),
+	}}, usage)
+
+	// 查询不计数
+	usage, err = q.QuotaUsage(context.Background(), "k1")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), usage[0].Used)
+}
+
+func TestQuotaKeyFromClaim(t *testing.T) {
+	rdb, _ := newTestRedis(t)
+	q, err := NewQuota(QuotaConf{Redis: rdb, Claim: "tenant", Rules: []QuotaRule{{Limit: 1}}})
+	assert.NoError(t, err)
+	engine := gin.New()
+	engine.Use(func(c *gin.Context) {
+		c.Set(ContextKeyAuthPrincipal, &AuthPrincipal{Mode: "jwt", Subject: c.GetHeader("X-User"), Claims: map[string]any{"tenant": "t1"}})
+	}, q.Handler())
+	engine.GET("/api", func(c *gin.Context) { c.String(http.StatusOK, "ok") })
+
+	for i, user := range []string{"u1", "u2"} {
+		req := httptest.NewRequest(http.MethodGet, "/api", nil)
+		req.Header.Set("X-User", user)
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+		// 同一租户的不同用户共享配额
+		if i == 0 {
+			assert.Equal(t, http.StatusOK, w.Code)
+		} else {
+			assert.Equal(t, http.StatusTooManyRequests, w.Code)
+		}
+	}
+}
+
+func TestRedisQuotaStoreExpiry(t *testing.T) {
+	now := time.Date(2025, 9, 14, 10, 59, 0, 0, time.UTC)
+	rdb, mr := newTestRedis(t)
+	mr.SetTime(now)
+	store := &redisQuotaStore{client: rdb}
+	ctx := context.Background()
+	keys := []string{"quota:hour:{k1}", "quota:day:{k1}"}
+	expireAt := []time.Time{now.Truncate(time.Hour).Add(time.Hour), time.Date(2025, 9, 15, 0, 0, 0, 0, time.UTC)}
+
+	counts, err := store.incr(ctx, keys, expireAt)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{1, 1}, counts)
+	counts, err = store.incr(ctx, keys, expireAt)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{2, 2}, counts)
+	assert.Equal(t, time.Minute, mr.TTL(keys[0]))
+	assert.Equal(t, 13*time.Hour+time.Minute, mr.TTL(keys[1]))
+
+	counts, err = store.get(ctx, append(keys, "quota:none:{k1}"))
+	require.NoError(t, err)
+	assert.Equal(t, []int64{2, 2, 0}, counts)
+
+	// 到窗口结束时间后按小时的计数过期，按天的继续累计
+	mr.FastForward(time.Minute)
+	mr.SetTime(expireAt[0])
+	counts, err = store.get(ctx, keys)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{0, 2}, counts)
+	counts, err = store.incr(ctx, keys, []time.Time{expireAt[0].Add(time.Hour), expireAt[1]})
+	require.NoError(t, err)
+	assert.Equal(t, []int64{1, 3}, counts)
+
+	require.NoError(t, mr.Set("quota:bad:{k1}", "x"))
+	_, err = store.get(ctx, []string{"quota:bad:{k1}"})
+	assert.ErrorContains(t, err, "invalid quota counter")
+}
+
+func TestQuotaClusterSameSlot(t *testing.T) {
+	nodes := []*miniredis.Miniredis{miniredis.RunT(t), miniredis.RunT(t)}
+	client := goredis.NewClusterClient(&goredis.ClusterOptions{
+		ClusterSlots: func(ctx context.Context) ([]goredis.ClusterSlot, error) {
+			return []goredis.ClusterSlot{
+				{Start: 0, End: 8191, Nodes: []goredis.ClusterNode{{Addr: nodes[0].Addr()}}},
+				{Start: 8192, End: 16383, Nodes: []goredis.ClusterNode{{Addr: nodes[1].Addr()}}},
+			}, nil
+		},
+	})
+	t.Cleanup(func() { _ = client.Close() })
+	q, err := NewQuota(QuotaConf{Redis: &redis.Redis{UniversalClient: client}, Rules: []QuotaRule{
+		{Limit: 10, Window: QuotaWindowDay},
+		{Limit: 5, Window: QuotaWindowHour},
+		{Limit: 100, Window: QuotaWindowMonth},
+	}})
+	require.NoError(t, err)
+	engine := gin.New()
+	engine.Use(q.Handler())
+	engine.GET("/api", func(c *gin.Context) { c.String(http.StatusOK, "ok") })
+
+	for _, caller := range []string{"k1", "k2", "k3"} {
+		for range 2 {
+			assert.Equal(t, http.StatusOK, quotaRequest(engine, caller).Code)
+		}
+		// 同一调用方所有窗口的key在同一个节点上，MGET 能读到全部计数
+		usage, err := q.QuotaUsage(context.Background(), caller)
+		require.NoError(t, err)
+		for _, status := range usage {
+			assert.Equal(t, int64(2), status.Used, caller+" "+status.Window)
+		}
+	}
+	assert.Equal(t, 9, len(nodes[0].Keys())+len(nodes[1].Keys()))
+}
@@ -0,0 +1,124 @@
+// Package middleware -----------------------------
+// @file      : rate_limit_redis.go
+// @author    : xiangtao
+// @contact   : xiangtao1994@gmail.com
+// @time      : 2025/9/13 20:10
+// Description: 基于Redis滑动窗口的分布式限流
+// -------------------------------------------
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	goredis "github.com/redis/go-redis/v9"
+
+	errors2 "github.com/xiangtao94/golib/pkg/errors"
+	"github.com/xiangtao94/golib/pkg/redis"
+	"github.com/xiangtao94/golib/pkg/render"
+	"github.com/xiangtao94/golib/pkg/zlog"
+)
+
+// 滑动窗口：清理窗口外的记录后计数，未超限则记录本次请求
+// 返回 {是否放行, 窗口内请求数, 最早一条记录的时间(ms)}
+var slidingWindowScript = goredis.NewScript(`
+local key = KEYS[1]
+local now = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local limit = tonumber(ARGV[3])
+redis.call("zremrangebyscore", key, 0, now - window)
+local count = redis.call("zcard", key)
+local allowed = 0
+if count < limit then
+	redis.call("zadd", key, now, ARGV[4])
+	count = count + 1
+	allowed = 1
+end
+redis.call("pexpire", key, window)
+local oldest = redis.call("zrange", key, 0, 0, "withscores")
+return {allowed, count, tonumber(oldest[2] or now)}`)
+
+// RateLimitConfig 分布式限流配置，同一个key在 Window 内最多 Limit 次请求
+type RateLimitConfig struct {
+	Limit     int           `yaml:"limit"`     // 窗口内允许的请求数
+	Window    time.Duration `yaml:"window"`    // 滑动窗口大小，默认1分钟
+	KeyPrefix string        `yaml:"keyPrefix"` // 限流key前缀，默认 ratelimit:，会再带上 redis.GetKeyPrefix()
+	// Redis 不可用时是否拒绝请求，默认放行并记录warn日志
+	FailClosed bool `yaml:"failClosed"`
+	// 存储限流计数的Redis
+	Redis *redis.Redis `yaml:"-"`
+	// 限流维度，默认客户端IP；返回空字符串时不限流
+	KeyFunc func(c *gin.Context) string `yaml:"-"`
+}
+
+// RegistryRateLimit 为全部路由注册分布式限流
+func RegistryRateLimit(engine *gin.Engine, conf RateLimitConfig) error {
+	handler, err := RateLimit(conf)
+	if err != nil {
+		return err
+	}
+	engine.Use(handler)
+	return nil
+}
+
+// RateLimit 基于Redis滑动窗口的限流中间件，超限时返回429并设置 Retry-After
+// 响应头带 X-RateLimit-Limit、X-RateLimit-Remaining，多实例部署共享同一个计数
+func RateLimit(conf RateLimitConfig) (gin.HandlerFunc, error) {
+	if conf.Redis == nil {
+		return nil, fmt.Errorf("rate limit conf: redis is required")
+	}
+	if conf.Limit <= 0 {
+		return nil, fmt.Errorf("rate limit conf: limit must be positive")
+	}
+	if conf.Window <= 0 {
+		conf.Window = time.Minute
+	}
+	if conf.KeyPrefix == "" {
+		conf.KeyPrefix = "ratelimit:"
+	}
+	if conf.KeyFunc == nil {
+		conf.KeyFunc = func(c *gin.Context) string {
+			return c.ClientIP()
+		}
+	}
+	limit := strconv.Itoa(conf.Limit)
+	window := conf.Window.Milliseconds()
+
+	return func(c *gin.Context) {
+		key := conf.KeyFunc(c)
+		if key == "" {
+			c.Next()
+			return
+		}
+		now := time.Now().UnixMilli()
+		// 同一毫秒内的请求需要不同的member
+		member := strconv.FormatInt(now, 10) + ":" + zlog.GetRequestID(c) + ":" + strconv.FormatInt(time.Now().UnixNano(), 36)
+		res, err := slidingWindowScript.Run(c, conf.Redis, []string{redis.GetKeyPrefix() + conf.KeyPrefix + key},
+			now, window, conf.Limit, member).Int64Slice()
+		if err != nil || len(res) != 3 {
+			zlog.Warnf(c, "rate limit redis error, key: %s, err: %v", key, err)
+			if conf.FailClosed {
+				render.RenderJsonFailWithStatus(c, http.StatusTooManyRequests, errors2.ErrorTooManyRequests)
+				c.Abort()
+				return
+			}
+			c.Next()
+			return
+		}
+
+		c.Header("X-RateLimit-Limit", limit)
+		c.Header("X-RateLimit-Remaining", strconv.FormatInt(max(int64(conf.Limit)-res[1], 0), 10))
+		if res[0] == 1 {
+			c.Next()
+			return
+		}
+		// 最早的记录滑出窗口后才能再次请求
+		retryAfter := time.Duration(res[2]+window-now) * time.Millisecond
+		c.Header("Retry-After", strconv.Itoa(max(int((retryAfter+time.Second-1)/time.Second), 1)))
+		render.RenderJsonFailWithStatus(c, http.StatusTooManyRequests, errors2.ErrorTooManyRequests)
+		c.Abort()
+	}, nil
+}
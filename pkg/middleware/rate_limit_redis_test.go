@@ -0,0 +1,108 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	goredis "github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+
+	errors2 "github.com/xiangtao94/golib/pkg/errors"
+	"github.com/xiangtao94/golib/pkg/redis"
+)
+
+// newTestRedis 返回连接 miniredis 的客户端，关闭 miniredis 即可模拟 Redis 不可用
+func newTestRedis(t *testing.T) (*redis.Redis, *miniredis.Miniredis) {
+	mr := miniredis.RunT(t)
+	client := goredis.NewUniversalClient(&goredis.UniversalOptions{Addrs: []string{mr.Addr()}, MaxRetries: -1})
+	t.Cleanup(func() { _ = client.Close() })
+	return &redis.Redis{UniversalClient: client}, mr
+}
+
+func TestRateLimitInvalidConf(t *testing.T) {
+	_, err := RateLimit(RateLimitConfig{Limit: 10})
+	assert.Error(t, err)
+	_, err = RateLimit(RateLimitConfig{Redis: &redis.Redis{}})
+	assert.Error(t, err)
+}
+
+func TestRateLimitRedisUnavailable(t *testing.T) {
+	rdb, mr := newTestRedis(t)
+	mr.Close()
+	for _, failClosed := range []bool{false, true} {
+		handler, err := RateLimit(RateLimitConfig{Limit: 1, Redis: rdb, FailClosed: failClosed})
+		assert.NoError(t, err)
+		engine := gin.New()
+		engine.Use(handler)
+		engine.GET("/api", func(c *gin.Context) { c.String(http.StatusOK, "ok") })
+
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api", nil))
+		if failClosed {
+			assert.Equal(t, http.StatusTooManyRequests, w.Code)
+			assert.Equal(t, errors2.TOO_MANY_REQUESTS, decodeLimitsCode(t, w))
+		} else {
+			assert.Equal(t, http.StatusOK, w.Code)
+		}
+	}
+}
+
+func TestRateLimitEmptyKeySkipped(t *testing.T) {
+	rdb, mr := newTestRedis(t)
+	mr.Close()
+	handler, err := RateLimit(RateLimitConfig{Limit: 1, Redis: rdb, FailClosed: true,
+		KeyFunc: func(c *gin.Context) string { return c.GetHeader("X-API-Key") }})
+	assert.NoError(t, err)
+	engine := gin.New()
+	engine.Use(handler)
+	engine.GET("/api", func(c *gin.Context) { c.String(http.StatusOK, "ok") })
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestRateLimitSlidingWindow(t *testing.T) {
+	rdb, mr := newTestRedis(t)
+	handler, err := RateLimit(RateLimitConfig{Limit: 2, Window: 200 * time.Millisecond, Redis: rdb})
+	assert.NoError(t, err)
+	engine := gin.New()
+	engine.Use(handler)
+	engine.GET("/api", func(c *gin.Context) { c.String(http.StatusOK, "ok") })
+	request := func(ip string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api", nil)
+		req.RemoteAddr = ip + ":1234"
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+		return w
+	}
+
+	for i, remaining := range []string{"1", "0"} {
+		w := request("10.0.0.1")
+		assert.Equal(t, http.StatusOK, w.Code, i)
+		assert.Equal(t, "2", w.Header().Get("X-RateLimit-Limit"))
+		assert.Equal(t, remaining, w.Header().Get("X-RateLimit-Remaining"))
+	}
+	w := request("10.0.0.1")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, errors2.TOO_MANY_REQUESTS, decodeLimitsCode(t, w))
+	assert.Equal(t, "0", w.Header().Get("X-RateLimit-Remaining"))
+	assert.Equal(t, "1", w.Header().Get("Retry-After"))
+	// 不同的key单独计数
+	assert.Equal(t, http.StatusOK, request("10.0.0.2").Code)
+	key := redis.GetKeyPrefix() + "ratelimit:10.0.0.1"
+	members, err := mr.ZMembers(key)
+	assert.NoError(t, err)
+	assert.Len(t, members, 2)
+	assert.Equal(t, 200*time.Millisecond, mr.TTL(key))
+
+	// 最早的请求滑出窗口后恢复
+	time.Sleep(250 * time.Millisecond)
+	w = request("10.0.0.1")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "1", w.Header().Get("X-RateLimit-Remaining"))
+}
@@ -2,24 +2,36 @@ package middleware
 
 import (
 	"errors"
-	"github.com/xiangtao94/golib/pkg/zlog"
-	"go.uber.org/zap"
 	"net"
-	"net/http"
 	"os"
 	"runtime/debug"
 	"strings"
 
 	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	errors2 "github.com/xiangtao94/golib/pkg/errors"
+	"github.com/xiangtao94/golib/pkg/render"
+	"github.com/xiangtao94/golib/pkg/zlog"
 )
 
+// RegistryRecovery 注册panic恢复中间件，handle 为 nil 时使用 DefaultRecoveryHandler
 func RegistryRecovery(engine *gin.Engine, handle gin.RecoveryFunc) {
 	if handle == nil {
-		handle = func(c *gin.Context, err interface{}) {
-			c.AbortWithStatus(http.StatusInternalServerError)
-		}
+		handle = DefaultRecoveryHandler
+	}
+	engine.Use(CustomRecoveryWithZap(zlog.NewLoggerWithSkip(1), handle))
+}
+
+// DefaultRecoveryHandler 以统一的错误结构返回 ErrorSystemError，堆栈已由 CustomRecoveryWithZap 记录
+// 响应已经开始写出时无法再改写，只中断后续处理
+func DefaultRecoveryHandler(c *gin.Context, _ any) {
+	if c.Writer.Written() {
+		c.Abort()
+		return
 	}
-	//engine.Use(CustomRecoveryWithZap(zlog.NewLoggerWithSkip(1), handle))
+	render.RenderJsonFail(c, errors2.ErrorSystemError)
+	c.Abort()
 }
 
 func CustomRecoveryWithZap(logger *zap.Logger, handle gin.RecoveryFunc) gin.HandlerFunc {
@@ -45,9 +57,8 @@ func CustomRecoveryWithZap(logger *zap.Logger, handle gin.RecoveryFunc) gin.Hand
 					c.Abort()
 					return
 				}
-				logger = zlog.LoggerWithContext(logger, c)
 				// 正常 panic 情况
-				logger.Error("Panic Recovery",
+				zlog.LoggerWithContext(logger, c).Error("Panic Recovery",
 					zap.Any("error", err),
 					zap.Any("stack", string(debug.Stack())),
 				)
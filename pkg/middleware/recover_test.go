@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	errors2 "github.com/xiangtao94/golib/pkg/errors"
+)
+
+func TestRegistryRecoveryDefault(t *testing.T) {
+	engine := gin.New()
+	RegistryRecovery(engine, nil)
+	engine.GET("/panic", func(c *gin.Context) {
+		panic("boom")
+	})
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/panic", nil))
+	// 与 render.RenderJsonFail 一致，HTTP状态码由 RegisterStatusMapper 决定，默认200
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Type"), "application/json")
+	assert.Equal(t, errors2.ErrorSystemError.Code, decodeLimitsCode(t, w))
+}
+
+func TestRecoveryLogsRequestID(t *testing.T) {
+	core, logs := observer.New(zapcore.ErrorLevel)
+	engine := gin.New()
+	engine.Use(CustomRecoveryWithZap(zap.New(core), DefaultRecoveryHandler))
+	engine.GET("/panic", func(c *gin.Context) {
+		panic("boom")
+	})
+	engine.GET("/written", func(c *gin.Context) {
+		c.String(http.StatusOK, "partial")
+		panic("boom")
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/panic", nil)
+	req.Header.Set("Request-Id", "rid-panic")
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+	assert.Equal(t, errors2.ErrorSystemError.Code, decodeLimitsCode(t, w))
+
+	entries := logs.FilterMessage("Panic Recovery").All()
+	if assert.Len(t, entries, 1) {
+		fields := entries[0].ContextMap()
+		assert.Equal(t, "rid-panic", fields["requestId"])
+		assert.Equal(t, "boom", fields["error"])
+		assert.Contains(t, fields["stack"], "recover_test.go")
+	}
+
+	// 响应已写出时不再追加错误结构
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/written", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "partial", w.Body.String())
+}
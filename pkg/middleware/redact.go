@@ -0,0 +1,122 @@
+// Package middleware -----------------------------
+// @file      : redact.go
+// @author    : xiangtao
+// @contact   : xiangtao1994@gmail.com
+// @time      : 2025/9/13 19:10
+// Description: access日志脱敏，替换请求/响应中的敏感字段和请求头
+// -------------------------------------------
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+const redactedValue = "***"
+
+// redactor 字段和请求头名称均不区分大小写
+type redactor struct {
+	fields  []string
+	headers []string
+}
+
+func newRedactor(fields, headers []string) *redactor {
+	if len(fields) == 0 && len(headers) == 0 {
+		return nil
+	}
+	r := &redactor{}
+	for _, f := range fields {
+		r.fields = append(r.fields, strings.ToLower(f))
+	}
+	for _, h := range headers {
+		r.headers = append(r.headers, strings.ToLower(h))
+	}
+	return r
+}
+
+// matchField 不含点号的字段名匹配任意层级的同名key，含点号的路径需完整匹配（数组不占路径层级）
+func (r *redactor) matchField(path, key string) bool {
+	path, key = strings.ToLower(path), strings.ToLower(key)
+	for _, f := range r.fields {
+		if f == path || (!strings.Contains(f, ".") && f == key) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *redactor) matchHeader(name string) bool {
+	return r != nil && slices.Contains(r.headers, strings.ToLower(name))
+}
+
+// redactJSON 解析失败时原样返回，由调用方决定是否打印
+func (r *redactor) redactJSON(data []byte) []byte {
+	if r == nil || len(r.fields) == 0 || len(data) == 0 {
+		return data
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var v any
+	if err := decoder.Decode(&v); err != nil {
+		return data
+	}
+	redacted, err := json.Marshal(r.redactValue("", v))
+	if err != nil {
+		return data
+	}
+	return redacted
+}
+
+func (r *redactor) redactValue(path string, v any) any {
+	switch val := v.(type) {
+	case map[string]any:
+		for k, item := range val {
+			p := k
+			if path != "" {
+				p = path + "." + k
+			}
+			if r.matchField(p, k) {
+				val[k] = redactedValue
+				continue
+			}
+			val[k] = r.redactValue(p, item)
+		}
+	case []any:
+		for i, item := range val {
+			val[i] = r.redactValue(path, item)
+		}
+	}
+	return v
+}
+
+// redactValues 对表单、查询参数脱敏并编码，*** 不做转义方便阅读
+func (r *redactor) redactValues(values url.Values) string {
+	if r == nil || len(r.fields) == 0 {
+		return values.Encode()
+	}
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	var buf strings.Builder
+	for _, k := range keys {
+		redacted := r.matchField(k, k)
+		for _, v := range values[k] {
+			if buf.Len() > 0 {
+				buf.WriteByte('&')
+			}
+			buf.WriteString(url.QueryEscape(k))
+			buf.WriteByte('=')
+			if redacted {
+				buf.WriteString(redactedValue)
+			} else {
+				buf.WriteString(url.QueryEscape(v))
+			}
+		}
+	}
+	return buf.String()
+}
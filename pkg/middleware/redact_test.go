@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func newRedactTestContext(method, target, contentType, body string) *gin.Context {
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	ctx.Request = httptest.NewRequest(method, target, strings.NewReader(body))
+	if contentType != "" {
+		ctx.Request.Header.Set("Content-Type", contentType)
+	}
+	return ctx
+}
+
+func TestRedactJSON(t *testing.T) {
+	r := newRedactor([]string{"password", "user.Token"}, nil)
+	got := r.redactJSON([]byte(`{"name":"a","Password":"p1","user":{"token":"t","password":"p2"},"items":[{"password":"p3","id":12345678901234567890}],"token":"keep"}`))
+	assert.JSONEq(t, `{"name":"a","Password":"***","user":{"token":"***","password":"***"},"items":[{"password":"***","id":12345678901234567890}],"token":"keep"}`, string(got))
+
+	// 非JSON原样返回
+	assert.Equal(t, "not json", string(r.redactJSON([]byte("not json"))))
+}
+
+func TestGetReqBodyRedact(t *testing.T) {
+	r := newRedactor([]string{"password", "token"}, nil)
+
+	ctx := newRedactTestContext(http.MethodPost, "/login", "application/json", `{"username":"admin","password":"secret"}`)
+	assert.JSONEq(t, `{"username":"admin","password":"***"}`, getReqBody(ctx, 1024, r))
+	// 业务读取到的仍是原始请求体
+	data, _ := io.ReadAll(ctx.Request.Body)
+	assert.Equal(t, `{"username":"admin","password":"secret"}`, string(data))
+
+	ctx = newRedactTestContext(http.MethodPost, "/login", "application/x-www-form-urlencoded", "username=admin&password=secret")
+	assert.Equal(t, "password=***&username=admin", getReqBody(ctx, 1024, r))
+
+	ctx = newRedactTestContext(http.MethodGet, "/callback?token=abc&state=1", "", "")
+	assert.Equal(t, "state=1&token=***", getReqBody(ctx, 1024, r))
+
+	// 未配置脱敏时保持原样
+	ctx = newRedactTestContext(http.MethodPost, "/login", "application/json", `{"password":"secret"}`)
+	assert.Equal(t, `{"password":"secret"}`, getReqBody(ctx, 1024, newRedactor(nil, nil)))
+}
+
+func TestRedactHeaders(t *testing.T) {
+	r := newRedactor(nil, []string{"authorization", "Cookie"})
+	ctx := newRedactTestContext(http.MethodGet, "/", "", "")
+	ctx.Request.Header.Set("Authorization", "Bearer token")
+	ctx.Request.Header.Set("X-Trace", "t1")
+	ctx.Request.AddCookie(&http.Cookie{Name: "session", Value: "s1"})
+
+	assert.Equal(t, "Authorization=***", getHeader(ctx, []string{"Authorization"}, r))
+	assert.Equal(t, "X-Trace=[t1]", getHeader(ctx, []string{"X-Trace"}, r))
+	assert.Equal(t, "session=***", getCookie(ctx, r.matchHeader("Cookie")))
+	assert.Equal(t, "session=s1", getCookie(ctx, false))
+}
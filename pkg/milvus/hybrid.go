@@ -0,0 +1,128 @@
+// Package milvus -----------------------------
+// @file      : hybrid.go
+// @author    : xiangtao
+// @contact   : xiangtao1994@gmail.com
+// @time      : 2025/9/14 15:40
+// Description: 混合搜索，多路向量搜索（可带标量过滤）后按 RRF 或加权融合排序
+// -------------------------------------------
+package milvus
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/milvus-io/milvus-sdk-go/v2/client"
+	"github.com/milvus-io/milvus-sdk-go/v2/entity"
+
+	"github.com/xiangtao94/golib/pkg/zlog"
+)
+
+// AnnRequest 混合搜索中的一路向量搜索
+type AnnRequest struct {
+	FieldName   string             // 向量字段
+	Vector      []float32          // 查询向量
+	MetricType  entity.MetricType  // 与该字段索引的度量类型一致
+	Expr        string             // 标量过滤表达式，为空不过滤
+	Limit       int                // 该路召回的候选数，默认与 topK 相同
+	SearchParam entity.SearchParam // 搜索参数，为空时使用索引的默认参数
+}
+
+// RerankStrategy 多路结果的融合方式，使用 RRFRerank 或 WeightedRerank 创建
+type RerankStrategy struct {
+	k       float64
+	weights []float64
+}
+
+// RRFRerank 按排名倒数融合（score = Σ 1/(k+rank)），不依赖各路分数的量纲，k 为0时使用60
+func RRFRerank(k float64) RerankStrategy {
+	if k <= 0 {
+		k = 60
+	}
+	return RerankStrategy{k: k}
+}
+
+// WeightedRerank 按权重对各路归一化后的分数加权求和，权重顺序与 annReqs 一致，取值 [0, 1]
+func WeightedRerank(weights ...float64) RerankStrategy {
+	return RerankStrategy{weights: weights}
+}
+
+func (r RerankStrategy) reranker(reqs int) (client.Reranker, error) {
+	if r.weights == nil {
+		if r.k <= 0 {
+			return nil, errors.New("rerank strategy is empty, use RRFRerank or WeightedRerank")
+		}
+		return client.NewRRFReranker().WithK(r.k), nil
+	}
+	if len(r.weights) != reqs {
+		return nil, fmt.Errorf("weighted rerank has %d weights, want %d", len(r.weights), reqs)
+	}
+	for _, w := range r.weights {
+		if w < 0 || w > 1 {
+			return nil, fmt.Errorf("weighted rerank weight %v out of range [0, 1]", w)
+		}
+	}
+	return client.NewWeightedReranker(r.weights), nil
+}
+
+// HybridSearch 对多个向量字段分别搜索后按 reranker 融合，返回前 topK 个结果
+// 适用于多模态检索（如文本向量 + 图片向量）或稠密向量 + 稀疏向量的混合召回
+func (mc *MilvusClient) HybridSearch(ctx *gin.Context, collectionName string, annReqs []AnnRequest, reranker RerankStrategy, topK int, outputFields []string) ([]SearchResult, error) {
+	start := time.Now()
+	subRequests, rr, err := buildHybridRequests(annReqs, reranker, topK)
+	if err != nil {
+		zlog.Errorf(ctx, "invalid hybrid search on collection %s: %v", collectionName, err)
+		return nil, fmt.Errorf("invalid hybrid search: %w", err)
+	}
+	var searchResult []client.SearchResult
+	err = mc.do(ctx, func(c client.Client) (err error) {
+		searchResult, err = c.HybridSearch(ctx, collectionName, nil, topK, outputFields, rr, subRequests)
+		return err
+	})
+	if err != nil {
+		zlog.Errorf(ctx, "failed to hybrid search in collection %s: %v", collectionName, err)
+		return nil, fmt.Errorf("failed to hybrid search: %w", err)
+	}
+
+	var results []SearchResult
+	if len(searchResult) > 0 {
+		results = toSearchResults(searchResult[0])
+	}
+	zlog.Infof(ctx, "hybrid searched %d fields in collection %s, topK: %d, results: %d, cost: %v",
+		len(annReqs), collectionName, topK, len(results), time.Since(start))
+	return results, nil
+}
+
+func buildHybridRequests(annReqs []AnnRequest, reranker RerankStrategy, topK int) ([]*client.ANNSearchRequest, client.Reranker, error) {
+	if len(annReqs) == 0 {
+		return nil, nil, errors.New("annReqs is empty")
+	}
+	if topK <= 0 {
+		return nil, nil, fmt.Errorf("topK %d must be positive", topK)
+	}
+	rr, err := reranker.reranker(len(annReqs))
+	if err != nil {
+		return nil, nil, err
+	}
+	subRequests := make([]*client.ANNSearchRequest, 0, len(annReqs))
+	for i, req := range annReqs {
+		if req.FieldName == "" || len(req.Vector) == 0 || req.MetricType == "" {
+			return nil, nil, fmt.Errorf("annReqs[%d] requires field name, vector and metric type", i)
+		}
+		sp := req.SearchParam
+		if sp == nil {
+			// Flat 的搜索参数为空，由服务端按索引类型取默认值
+			if sp, err = entity.NewIndexFlatSearchParam(); err != nil {
+				return nil, nil, err
+			}
+		}
+		limit := req.Limit
+		if limit <= 0 {
+			limit = topK
+		}
+		subRequests = append(subRequests, client.NewANNSearchRequest(req.FieldName, req.MetricType, req.Expr,
+			[]entity.Vector{entity.FloatVector(req.Vector)}, sp, limit))
+	}
+	return subRequests, rr, nil
+}
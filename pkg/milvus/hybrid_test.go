@@ -0,0 +1,88 @@
+package milvus
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/milvus-io/milvus-sdk-go/v2/client"
+	"github.com/milvus-io/milvus-sdk-go/v2/entity"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// hybridClient 记录 HybridSearch 的参数并返回固定结果
+type hybridClient struct {
+	client.Client
+	limit       int
+	subRequests int
+	rankParams  map[string]string
+}
+
+func (f *hybridClient) HybridSearch(ctx context.Context, collName string, partitions []string, limit int, outputFields []string, reranker client.Reranker, subRequests []*client.ANNSearchRequest, opts ...client.SearchQueryOptionFunc) ([]client.SearchResult, error) {
+	f.limit = limit
+	f.subRequests = len(subRequests)
+	f.rankParams = map[string]string{}
+	for _, kv := range reranker.GetParams() {
+		f.rankParams[kv.Key] = kv.Value
+	}
+	return []client.SearchResult{{
+		ResultCount: 2,
+		IDs:         entity.NewColumnInt64("id", []int64{7, 3}),
+		Scores:      []float32{0.03, 0.02},
+		Fields:      []entity.Column{entity.NewColumnVarChar("title", []string{"a", "b"})},
+	}}, nil
+}
+
+func TestHybridSearch(t *testing.T) {
+	fake := &hybridClient{}
+	mc := &MilvusClient{client: fake}
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	reqs := []AnnRequest{
+		{FieldName: "text_vector", Vector: []float32{0.1, 0.2}, MetricType: entity.COSINE, Expr: "lang == 'zh'"},
+		{FieldName: "image_vector", Vector: []float32{0.3, 0.4}, MetricType: entity.L2, Limit: 50},
+	}
+
+	results, err := mc.HybridSearch(ctx, "docs", reqs, RRFRerank(0), 10, []string{"title"})
+	require.NoError(t, err)
+	assert.Equal(t, 10, fake.limit)
+	assert.Equal(t, 2, fake.subRequests)
+	assert.Equal(t, "rrf", fake.rankParams["strategy"])
+	assert.JSONEq(t, `{"k":60}`, fake.rankParams["params"])
+	require.Len(t, results, 2)
+	assert.Equal(t, int64(7), results[0].ID)
+	assert.Equal(t, "a", results[0].Fields["title"])
+
+	_, err = mc.HybridSearch(ctx, "docs", reqs, WeightedRerank(0.7, 0.3), 5, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "weighted", fake.rankParams["strategy"])
+	assert.JSONEq(t, `{"weights":[0.7,0.3]}`, fake.rankParams["params"])
+}
+
+func TestHybridSearch_Invalid(t *testing.T) {
+	fake := &hybridClient{}
+	mc := &MilvusClient{client: fake}
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	req := AnnRequest{FieldName: "vector", Vector: []float32{0.1}, MetricType: entity.IP}
+
+	cases := map[string]struct {
+		reqs     []AnnRequest
+		reranker RerankStrategy
+		topK     int
+	}{
+		"empty requests":   {nil, RRFRerank(60), 10},
+		"zero topK":        {[]AnnRequest{req}, RRFRerank(60), 0},
+		"empty strategy":   {[]AnnRequest{req}, RerankStrategy{}, 10},
+		"weights mismatch": {[]AnnRequest{req}, WeightedRerank(0.5, 0.5), 10},
+		"weight range":     {[]AnnRequest{req}, WeightedRerank(1.5), 10},
+		"missing vector":   {[]AnnRequest{{FieldName: "vector", MetricType: entity.IP}}, RRFRerank(60), 10},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := mc.HybridSearch(ctx, "docs", tc.reqs, tc.reranker, tc.topK, nil)
+			assert.Error(t, err)
+		})
+	}
+	assert.Zero(t, fake.subRequests)
+}
@@ -0,0 +1,336 @@
+// Package milvus -----------------------------
+// @file      : iterator.go
+// @author    : xiangtao
+// @contact   : xiangtao1994@gmail.com
+// @time      : 2025/9/14 17:30
+// Description: 按主键分批查询与导出，避免一次性加载大集合
+// -------------------------------------------
+package milvus
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/milvus-io/milvus-sdk-go/v2/client"
+	"github.com/milvus-io/milvus-sdk-go/v2/entity"
+
+	"github.com/xiangtao94/golib/pkg/zlog"
+)
+
+// 导出格式
+const (
+	ExportJSONL = "jsonl"
+	ExportCSV   = "csv"
+)
+
+// iteratorLogEvery 每读取多少批打印一次进度日志
+var iteratorLogEvery = 10
+
+// QueryIterator 按主键升序分批查询，每批在上一批最后一个主键之后继续，不使用 offset，导出大集合时内存只保留一批数据
+// 每次 Next 都经过自动重连，不依赖 SDK 绑定单个连接的 QueryIterator
+type QueryIterator struct {
+	mc             *MilvusClient
+	ctx            *gin.Context
+	reqCtx         context.Context // 请求的 context，客户端断开或调用方取消时停止
+	collectionName string
+	expr           string
+	outputFields   []string
+	batchSize      int
+	pkField        *entity.Field
+
+	fields  []string // 第一批结果的字段顺序，CSV 表头使用
+	lastPK  any
+	done    bool
+	batches int
+	rows    int
+	start   time.Time
+}
+
+// QueryIterator 创建分批查询的迭代器，主键只支持 Int64 和 VarChar，结果中总是包含主键字段
+// ctx.Request 的 context 取消时，Next 在批次之间返回 context 的错误
+func (mc *MilvusClient) QueryIterator(ctx *gin.Context, collectionName, expr string, outputFields []string, batchSize int) (*QueryIterator, error) {
+	if batchSize <= 0 {
+		return nil, fmt.Errorf("invalid batch size %d", batchSize)
+	}
+	coll, err := mc.DescribeCollection(ctx, collectionName)
+	if err != nil {
+		return nil, err
+	}
+	var pkField *entity.Field
+	if coll.Schema != nil {
+		pkField = coll.Schema.PKField()
+	}
+	if pkField == nil {
+		return nil, fmt.Errorf("collection %s has no primary key field", collectionName)
+	}
+	if pkField.DataType != entity.FieldTypeInt64 && pkField.DataType != entity.FieldTypeVarChar {
+		return nil, fmt.Errorf("unsupported primary key type %s of collection %s", pkField.DataType.Name(), collectionName)
+	}
+	fields := slices.Clone(outputFields)
+	if len(fields) > 0 && !slices.Contains(fields, pkField.Name) {
+		fields = append([]string{pkField.Name}, fields...)
+	}
+	reqCtx := context.Background()
+	if ctx != nil && ctx.Request != nil {
+		reqCtx = ctx.Request.Context()
+	}
+	return &QueryIterator{
+		mc:             mc,
+		ctx:            ctx,
+		reqCtx:         reqCtx,
+		collectionName: collectionName,
+		expr:           strings.TrimSpace(expr),
+		outputFields:   fields,
+		batchSize:      batchSize,
+		pkField:        pkField,
+		start:          time.Now(),
+	}, nil
+}
+
+// Next 返回下一批数据，每行为字段名到值的映射；没有更多数据时返回 io.EOF，ctx 取消时返回 ctx 的错误
+func (it *QueryIterator) Next() ([]map[string]any, error) {
+	if it.done {
+		return nil, io.EOF
+	}
+	if err := it.reqCtx.Err(); err != nil {
+		return nil, err
+	}
+
+	var result client.ResultSet
+	err := it.mc.do(it.ctx, func(c client.Client) (err error) {
+		result, err = c.Query(it.reqCtx, it.collectionName, nil, it.batchExpr(), it.outputFields, client.WithLimit(int64(it.batchSize)))
+		return err
+	})
+	if err != nil {
+		zlog.Errorf(it.ctx, "failed to query batch from collection %s after %d rows: %v", it.collectionName, it.rows, err)
+		return nil, fmt.Errorf("failed to query batch: %w", err)
+	}
+
+	n := result.Len()
+	if n < it.batchSize {
+		it.done = true
+	}
+	if n == 0 {
+		it.logProgress("query iterator finished")
+		return nil, io.EOF
+	}
+	rows, err := it.toRows(result, n)
+	if err != nil {
+		return nil, err
+	}
+	it.batches++
+	it.rows += n
+	if it.done {
+		it.logProgress("query iterator finished")
+	} else if it.batches%iteratorLogEvery == 0 {
+		it.logProgress("query iterator progress")
+	}
+	return rows, nil
+}
+
+// Close 结束迭代，之后 Next 返回 io.EOF
+func (it *QueryIterator) Close() {
+	it.done = true
+}
+
+// batchExpr 在用户表达式上追加主键条件
+func (it *QueryIterator) batchExpr() string {
+	if it.lastPK == nil {
+		return it.expr
+	}
+	var cond string
+	if it.pkField.DataType == entity.FieldTypeInt64 {
+		cond = fmt.Sprintf("%s > %d", it.pkField.Name, it.lastPK)
+	} else {
+		cond = fmt.Sprintf("%s > %s", it.pkField.Name, strconv.Quote(it.lastPK.(string)))
+	}
+	if it.expr == "" {
+		return cond
+	}
+	return fmt.Sprintf("(%s) and %s", it.expr, cond)
+}
+
+func (it *QueryIterator) toRows(result client.ResultSet, n int) ([]map[string]any, error) {
+	pkColumn := result.GetColumn(it.pkField.Name)
+	if pkColumn == nil || pkColumn.Len() != n {
+		return nil, fmt.Errorf("query result of collection %s missing primary key %s", it.collectionName, it.pkField.Name)
+	}
+	lastPK, err := maxPK(pkColumn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read primary key: %w", err)
+	}
+	if it.fields == nil {
+		for _, col := range result {
+			it.fields = append(it.fields, col.Name())
+		}
+	}
+
+	rows := make([]map[string]any, n)
+	for i := range rows {
+		row := make(map[string]any, len(result))
+		for _, col := range result {
+			if value, err := col.Get(i); err == nil {
+				row[col.Name()] = exportValue(col, value)
+			}
+		}
+		rows[i] = row
+	}
+	it.lastPK = lastPK
+	return rows, nil
+}
+
+// maxPK 取本批最大的主键作为下一批的起点，不依赖结果的返回顺序
+func maxPK(col entity.Column) (any, error) {
+	switch col.Type() {
+	case entity.FieldTypeInt64:
+		var last int64
+		for i := range col.Len() {
+			v, err := col.GetAsInt64(i)
+			if err != nil {
+				return nil, err
+			}
+			if i == 0 || v > last {
+				last = v
+			}
+		}
+		return last, nil
+	case entity.FieldTypeVarChar:
+		var last string
+		for i := range col.Len() {
+			v, err := col.GetAsString(i)
+			if err != nil {
+				return nil, err
+			}
+			if i == 0 || v > last {
+				last = v
+			}
+		}
+		return last, nil
+	}
+	return nil, fmt.Errorf("unsupported primary key type %s", col.Type().Name())
+}
+
+func (it *QueryIterator) logProgress(msg string) {
+	zlog.Infof(it.ctx, "%s, collection: %s, batches: %d, rows: %d, cost: %v",
+		msg, it.collectionName, it.batches, it.rows, time.Since(it.start))
+}
+
+// exportValue JSON 字段输出原始 JSON，字节类型的向量输出为数组
+func exportValue(col entity.Column, value any) any {
+	b, ok := value.([]byte)
+	if !ok {
+		return value
+	}
+	if col.Type() == entity.FieldTypeJSON {
+		return json.RawMessage(b)
+	}
+	ints := make([]int, len(b))
+	for i, v := range b {
+		ints[i] = int(v)
+	}
+	return ints
+}
+
+// ExportToWriter 分批查询并写入 w，format 为 ExportJSONL（每行一个 JSON 对象）或 ExportCSV（第一行为表头）
+// 向量和数组字段输出为 JSON 数组；ctx 取消时在批次之间停止，已写入的数据保持完整，返回已写入的行数和 ctx 的错误
+func (mc *MilvusClient) ExportToWriter(ctx *gin.Context, collectionName, expr string, outputFields []string, batchSize int, w io.Writer, format string) (int64, error) {
+	var write func(it *QueryIterator, rows []map[string]any) error
+	var flush func() error
+	switch format {
+	case ExportJSONL:
+		bw := bufio.NewWriter(w)
+		enc := json.NewEncoder(bw)
+		write = func(_ *QueryIterator, rows []map[string]any) error {
+			for _, row := range rows {
+				if err := enc.Encode(row); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		flush = bw.Flush
+	case ExportCSV:
+		cw := csv.NewWriter(w)
+		header := false
+		write = func(it *QueryIterator, rows []map[string]any) error {
+			if !header {
+				header = true
+				if err := cw.Write(it.fields); err != nil {
+					return err
+				}
+			}
+			record := make([]string, len(it.fields))
+			for _, row := range rows {
+				for i, field := range it.fields {
+					record[i] = csvValue(row[field])
+				}
+				if err := cw.Write(record); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		flush = func() error {
+			cw.Flush()
+			return cw.Error()
+		}
+	default:
+		return 0, fmt.Errorf("unsupported export format %q", format)
+	}
+
+	it, err := mc.QueryIterator(ctx, collectionName, expr, outputFields, batchSize)
+	if err != nil {
+		return 0, err
+	}
+	defer it.Close()
+
+	var total int64
+	for {
+		rows, err := it.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err == nil {
+			err = write(it, rows)
+		}
+		if err != nil {
+			if flushErr := flush(); flushErr != nil {
+				zlog.Warnf(ctx, "failed to flush export of collection %s: %v", collectionName, flushErr)
+			}
+			zlog.Warnf(ctx, "export collection %s stopped after %d rows: %v", collectionName, total, err)
+			return total, err
+		}
+		total += int64(len(rows))
+	}
+	if err = flush(); err != nil {
+		return total, fmt.Errorf("failed to flush export: %w", err)
+	}
+	zlog.Infof(ctx, "exported %d rows from collection %s as %s, cost: %v", total, collectionName, format, time.Since(it.start))
+	return total, nil
+}
+
+func csvValue(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case json.RawMessage:
+		return string(v)
+	}
+	b, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(b)
+}
@@ -0,0 +1,173 @@
+package milvus
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/milvus-io/milvus-sdk-go/v2/client"
+	"github.com/milvus-io/milvus-sdk-go/v2/entity"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// pagedClient 按顺序返回固定的分页结果，记录每次查询的表达式
+type pagedClient struct {
+	client.Client
+	pages   []client.ResultSet
+	exprs   []string
+	limits  []int64
+	onQuery func(n int)
+}
+
+func (f *pagedClient) DescribeCollection(ctx context.Context, collName string) (*entity.Collection, error) {
+	return &entity.Collection{Name: collName, Schema: NewSchema(collName).PrimaryInt64("id", false).
+		FloatVector("vector", 2).Varchar("title", 64).Build()}, nil
+}
+
+func (f *pagedClient) Query(ctx context.Context, collectionName string, partitionNames []string, expr string, outputFields []string, opts ...client.SearchQueryOptionFunc) (client.ResultSet, error) {
+	opt := &client.SearchQueryOption{}
+	for _, o := range opts {
+		o(opt)
+	}
+	f.exprs = append(f.exprs, expr)
+	f.limits = append(f.limits, opt.Limit)
+	n := len(f.exprs)
+	if f.onQuery != nil {
+		f.onQuery(n)
+	}
+	if n > len(f.pages) {
+		return client.ResultSet{}, nil
+	}
+	return f.pages[n-1], nil
+}
+
+func docsPage(ids ...int64) client.ResultSet {
+	vectors := make([][]float32, len(ids))
+	titles := make([]string, len(ids))
+	for i, id := range ids {
+		vectors[i] = []float32{float32(id), 0.5}
+		titles[i] = "doc," + string(rune('a'+id-1))
+	}
+	return client.ResultSet{
+		entity.NewColumnInt64("id", ids),
+		entity.NewColumnFloatVector("vector", 2, vectors),
+		entity.NewColumnVarChar("title", titles),
+	}
+}
+
+func newIteratorCtx() (*gin.Context, context.CancelFunc) {
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	reqCtx, cancel := context.WithCancel(context.Background())
+	ctx.Request = httptest.NewRequest(http.MethodGet, "/", nil).WithContext(reqCtx)
+	return ctx, cancel
+}
+
+func TestQueryIterator(t *testing.T) {
+	fake := &pagedClient{pages: []client.ResultSet{docsPage(1, 2, 3), docsPage(4, 5, 6), docsPage(7)}}
+	mc := &MilvusClient{client: fake}
+	ctx, cancel := newIteratorCtx()
+	defer cancel()
+
+	it, err := mc.QueryIterator(ctx, "docs", "lang == 'zh'", []string{"title"}, 3)
+	require.NoError(t, err)
+	var ids []int64
+	for {
+		rows, err := it.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		for _, row := range rows {
+			ids = append(ids, row["id"].(int64))
+		}
+	}
+	assert.Equal(t, []int64{1, 2, 3, 4, 5, 6, 7}, ids)
+	// 最后一批不足 batchSize 时不再查询
+	assert.Equal(t, []string{"lang == 'zh'", "(lang == 'zh') and id > 3", "(lang == 'zh') and id > 6"}, fake.exprs)
+	assert.Equal(t, []int64{3, 3, 3}, fake.limits)
+	_, err = it.Next()
+	assert.Equal(t, io.EOF, err)
+
+	// 数据量恰好是 batchSize 的整数倍时，最后一次查询为空
+	fake = &pagedClient{pages: []client.ResultSet{docsPage(1, 2), docsPage(3, 4)}}
+	mc = &MilvusClient{client: fake}
+	it, err = mc.QueryIterator(ctx, "docs", "", nil, 2)
+	require.NoError(t, err)
+	for range 2 {
+		rows, err := it.Next()
+		require.NoError(t, err)
+		assert.Len(t, rows, 2)
+	}
+	_, err = it.Next()
+	assert.Equal(t, io.EOF, err)
+	assert.Equal(t, []string{"", "id > 2", "id > 4"}, fake.exprs)
+
+	_, err = mc.QueryIterator(ctx, "docs", "", nil, 0)
+	assert.Error(t, err)
+}
+
+func TestExportToWriter_JSONL(t *testing.T) {
+	fake := &pagedClient{pages: []client.ResultSet{docsPage(1, 2, 3), docsPage(4, 5, 6), docsPage(7)}}
+	mc := &MilvusClient{client: fake}
+	ctx, cancel := newIteratorCtx()
+	defer cancel()
+
+	var buf bytes.Buffer
+	n, err := mc.ExportToWriter(ctx, "docs", "", []string{"vector", "title"}, 3, &buf, ExportJSONL)
+	require.NoError(t, err)
+	assert.EqualValues(t, 7, n)
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	require.Len(t, lines, 7)
+	for i, line := range lines {
+		var row map[string]any
+		require.NoError(t, json.Unmarshal([]byte(line), &row), line)
+		assert.EqualValues(t, i+1, row["id"])
+		assert.Equal(t, []any{float64(i + 1), 0.5}, row["vector"])
+	}
+}
+
+func TestExportToWriter_CSV(t *testing.T) {
+	fake := &pagedClient{pages: []client.ResultSet{docsPage(1, 2)}}
+	mc := &MilvusClient{client: fake}
+	ctx, cancel := newIteratorCtx()
+	defer cancel()
+
+	var buf bytes.Buffer
+	n, err := mc.ExportToWriter(ctx, "docs", "", nil, 3, &buf, ExportCSV)
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, n)
+	assert.Equal(t, "id,vector,title\n1,\"[1,0.5]\",\"doc,a\"\n2,\"[2,0.5]\",\"doc,b\"\n", buf.String())
+
+	_, err = mc.ExportToWriter(ctx, "docs", "", nil, 3, &buf, "xml")
+	assert.ErrorContains(t, err, "unsupported export format")
+}
+
+func TestExportToWriter_Canceled(t *testing.T) {
+	ctx, cancel := newIteratorCtx()
+	// 第一批返回后请求取消
+	fake := &pagedClient{
+		pages: []client.ResultSet{docsPage(1, 2, 3), docsPage(4, 5, 6), docsPage(7)},
+		onQuery: func(n int) {
+			if n == 1 {
+				cancel()
+			}
+		},
+	}
+	mc := &MilvusClient{client: fake}
+
+	var buf bytes.Buffer
+	n, err := mc.ExportToWriter(ctx, "docs", "", nil, 3, &buf, ExportJSONL)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.EqualValues(t, 3, n)
+	assert.Len(t, fake.exprs, 1)
+	// 已写入的批次完整输出
+	assert.Equal(t, 3, strings.Count(buf.String(), "\n"))
+	assert.True(t, strings.HasSuffix(buf.String(), "}\n"))
+}
@@ -10,12 +10,15 @@ package milvus
 import (
 	"context"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/milvus-io/milvus-sdk-go/v2/client"
 	"github.com/milvus-io/milvus-sdk-go/v2/entity"
 	"github.com/xiangtao94/golib/pkg/zlog"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/keepalive"
 )
 
 // MilvusConf Milvus配置
@@ -25,12 +28,58 @@ type MilvusConf struct {
 	Username string `yaml:"username"` // 用户名（可选）
 	Password string `yaml:"password"` // 密码（可选）
 	Database string `yaml:"database"` // 数据库名（可选）
+
+	ConnectTimeout   time.Duration `yaml:"connectTimeout"`   // 建立连接的超时时间，默认5s
+	KeepaliveTime    time.Duration `yaml:"keepaliveTime"`    // 连接空闲时发送 keepalive 探测的间隔，默认5s
+	KeepaliveTimeout time.Duration `yaml:"keepaliveTimeout"` // keepalive 探测的超时时间，默认10s
 }
 
-// MilvusClient Milvus客户端封装
+func (conf *MilvusConf) checkConf() {
+	if conf.ConnectTimeout <= 0 {
+		conf.ConnectTimeout = 5 * time.Second
+	}
+	if conf.KeepaliveTime <= 0 {
+		conf.KeepaliveTime = 5 * time.Second
+	}
+	if conf.KeepaliveTimeout <= 0 {
+		conf.KeepaliveTimeout = 10 * time.Second
+	}
+}
+
+func (conf *MilvusConf) address() string {
+	return fmt.Sprintf("%s:%s", conf.Host, conf.Port)
+}
+
+func (conf *MilvusConf) clientConfig() client.Config {
+	connectParam := client.Config{
+		Address: conf.address(),
+		// 在 SDK 默认选项之后追加，覆盖默认的 keepalive 参数
+		DialOptions: append(append([]grpc.DialOption{}, client.DefaultGrpcOpts...),
+			grpc.WithKeepaliveParams(keepalive.ClientParameters{
+				Time:                conf.KeepaliveTime,
+				Timeout:             conf.KeepaliveTimeout,
+				PermitWithoutStream: true,
+			})),
+	}
+
+	if conf.Username != "" {
+		connectParam.Username = conf.Username
+		connectParam.Password = conf.Password
+	}
+
+	if conf.Database != "" {
+		connectParam.DBName = conf.Database
+	}
+	return connectParam
+}
+
+// MilvusClient Milvus客户端封装，连接不可用时自动重建客户端，只读请求重试一次
 type MilvusClient struct {
-	client client.Client
-	config MilvusConf
+	mu        sync.RWMutex
+	client    client.Client
+	refs      *clientRefs
+	config    MilvusConf
+	newClient func(ctx context.Context, config client.Config) (client.Client, error)
 }
 
 // SearchResult 搜索结果
@@ -58,28 +107,20 @@ type IndexInfo struct {
 
 // NewMilvusClient 创建Milvus客户端
 func NewMilvusClient(config MilvusConf) (*MilvusClient, error) {
-	connectParam := client.Config{
-		Address: fmt.Sprintf("%s:%s", config.Host, config.Port),
-	}
-
-	if config.Username != "" {
-		connectParam.Username = config.Username
-		connectParam.Password = config.Password
-	}
-
-	if config.Database != "" {
-		connectParam.DBName = config.Database
-	}
-
-	c, err := client.NewClient(context.Background(), connectParam)
+	config.checkConf()
+	mc := &MilvusClient{
+		config:    config,
+		newClient: client.NewClient,
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), config.ConnectTimeout)
+	defer cancel()
+	c, err := mc.newClient(ctx, config.clientConfig())
 	if err != nil {
 		return nil, fmt.Errorf("failed to create milvus client: %w", err)
 	}
-
-	return &MilvusClient{
-		client: c,
-		config: config,
-	}, nil
+	mc.client = c
+	mc.refs = &clientRefs{}
+	return mc, nil
 }
 
 // CreateCollection 创建集合
@@ -87,7 +128,11 @@ func (mc *MilvusClient) CreateCollection(ctx *gin.Context, collectionName string
 	start := time.Now()
 
 	// 检查集合是否已存在
-	exists, err := mc.client.HasCollection(ctx, collectionName)
+	var exists bool
+	err := mc.do(ctx, func(c client.Client) (err error) {
+		exists, err = c.HasCollection(ctx, collectionName)
+		return err
+	})
 	if err != nil {
 		zlog.Errorf(ctx, "failed to check collection exists %s: %v", collectionName, err)
 		return fmt.Errorf("failed to check collection exists: %w", err)
@@ -119,7 +164,9 @@ func (mc *MilvusClient) CreateCollection(ctx *gin.Context, collectionName string
 		},
 	}
 
-	err = mc.client.CreateCollection(ctx, schema, 1)
+	err = mc.doWrite(ctx, func(c client.Client) error {
+		return c.CreateCollection(ctx, schema, 1)
+	})
 	if err != nil {
 		zlog.Errorf(ctx, "failed to create collection %s: %v", collectionName, err)
 		return fmt.Errorf("failed to create collection: %w", err)
@@ -135,7 +182,11 @@ func (mc *MilvusClient) CreateCollectionWithSchema(ctx *gin.Context, schema *ent
 	start := time.Now()
 
 	// 检查集合是否已存在
-	exists, err := mc.client.HasCollection(ctx, schema.CollectionName)
+	var exists bool
+	err := mc.do(ctx, func(c client.Client) (err error) {
+		exists, err = c.HasCollection(ctx, schema.CollectionName)
+		return err
+	})
 	if err != nil {
 		zlog.Errorf(ctx, "failed to check collection exists %s: %v", schema.CollectionName, err)
 		return fmt.Errorf("failed to check collection exists: %w", err)
@@ -146,7 +197,9 @@ func (mc *MilvusClient) CreateCollectionWithSchema(ctx *gin.Context, schema *ent
 		return nil
 	}
 
-	err = mc.client.CreateCollection(ctx, schema, shardsNum)
+	err = mc.doWrite(ctx, func(c client.Client) error {
+		return c.CreateCollection(ctx, schema, shardsNum)
+	})
 	if err != nil {
 		zlog.Errorf(ctx, "failed to create collection %s: %v", schema.CollectionName, err)
 		return fmt.Errorf("failed to create collection: %w", err)
@@ -161,7 +214,9 @@ func (mc *MilvusClient) CreateCollectionWithSchema(ctx *gin.Context, schema *ent
 func (mc *MilvusClient) DropCollection(ctx *gin.Context, collectionName string) error {
 	start := time.Now()
 
-	err := mc.client.DropCollection(ctx, collectionName)
+	err := mc.doWrite(ctx, func(c client.Client) error {
+		return c.DropCollection(ctx, collectionName)
+	})
 	if err != nil {
 		zlog.Errorf(ctx, "failed to drop collection %s: %v", collectionName, err)
 		return fmt.Errorf("failed to drop collection: %w", err)
@@ -182,7 +237,11 @@ func (mc *MilvusClient) InsertVectors(ctx *gin.Context, collectionName string, v
 	// 添加额外字段
 	columns = append(columns, extraFields...)
 
-	result, err := mc.client.Insert(ctx, collectionName, "", columns...)
+	var result entity.Column
+	err := mc.doWrite(ctx, func(c client.Client) (err error) {
+		result, err = c.Insert(ctx, collectionName, "", columns...)
+		return err
+	})
 	if err != nil {
 		zlog.Errorf(ctx, "failed to insert vectors to collection %s: %v", collectionName, err)
 		return nil, fmt.Errorf("failed to insert vectors: %w", err)
@@ -202,22 +261,66 @@ func (mc *MilvusClient) SearchVectors(ctx *gin.Context, collectionName string, q
 		zlog.Errorf(ctx, "failed to create search param: %v", err)
 		return nil, fmt.Errorf("failed to create search param: %w", err)
 	}
-	vectors := make([]entity.Vector, len(queryVectors))
+	results, err := mc.search(ctx, collectionName, queryVectors, topK, searchParam, outputFields)
+	if err != nil {
+		return nil, err
+	}
+
+	zlog.Infof(ctx, "searched %d query vectors in collection %s, topK: %d, cost: %v",
+		len(queryVectors), collectionName, topK, time.Since(start))
+	return results, nil
+}
+
+// rangeSearchLimit 范围搜索每个查询向量最多返回的结果数，为 Milvus topK 的上限
+var rangeSearchLimit = 16384
+
+// RangeSearch 范围搜索，返回与查询向量距离在 [rangeFilter, radius) 内的所有向量（最多 rangeSearchLimit 个），
+// 适用于近似去重等候选数量不固定的场景；度量类型与 SearchVectors 一致为 L2，rangeFilter 为0时包含完全相同的向量
+func (mc *MilvusClient) RangeSearch(ctx *gin.Context, collectionName string, queryVectors [][]float32, radius, rangeFilter float32, outputFields []string) ([][]SearchResult, error) {
+	if radius <= 0 || rangeFilter < 0 || rangeFilter >= radius {
+		return nil, fmt.Errorf("invalid range search radius %v and range filter %v, want 0 <= rangeFilter < radius", radius, rangeFilter)
+	}
+	start := time.Now()
+
+	searchParam, err := entity.NewIndexIvfFlatSearchParam(1024)
+	if err != nil {
+		zlog.Errorf(ctx, "failed to create search param: %v", err)
+		return nil, fmt.Errorf("failed to create search param: %w", err)
+	}
+	searchParam.AddRadius(float64(radius))
+	searchParam.AddRangeFilter(float64(rangeFilter))
+	results, err := mc.search(ctx, collectionName, queryVectors, rangeSearchLimit, searchParam, outputFields)
+	if err != nil {
+		return nil, err
+	}
+
+	zlog.Infof(ctx, "range searched %d query vectors in collection %s, radius: %v, rangeFilter: %v, cost: %v",
+		len(queryVectors), collectionName, radius, rangeFilter, time.Since(start))
+	return results, nil
+}
+
+// search 在 vector 字段上按 L2 搜索，结果与 queryVectors 一一对应
+func (mc *MilvusClient) search(ctx *gin.Context, collectionName string, queryVectors [][]float32, topK int, searchParam entity.SearchParam, outputFields []string) ([][]SearchResult, error) {
+	vectors := make([]entity.Vector, 0, len(queryVectors))
 	for _, vector := range queryVectors {
 		vectors = append(vectors, entity.FloatVector(vector))
 	}
-	searchResult, err := mc.client.Search(
-		ctx,
-		collectionName,
-		nil, // 分区名，nil表示搜索所有分区
-		"",  // 表达式过滤条件
-		outputFields,
-		vectors,
-		"vector",
-		entity.L2,
-		topK,
-		searchParam,
-	)
+	var searchResult []client.SearchResult
+	err := mc.do(ctx, func(c client.Client) (err error) {
+		searchResult, err = c.Search(
+			ctx,
+			collectionName,
+			nil, // 分区名，nil表示搜索所有分区
+			"",  // 表达式过滤条件
+			outputFields,
+			vectors,
+			"vector",
+			entity.L2,
+			topK,
+			searchParam,
+		)
+		return err
+	})
 	if err != nil {
 		zlog.Errorf(ctx, "failed to search vectors in collection %s: %v", collectionName, err)
 		return nil, fmt.Errorf("failed to search vectors: %w", err)
@@ -226,33 +329,38 @@ func (mc *MilvusClient) SearchVectors(ctx *gin.Context, collectionName string, q
 	// 转换搜索结果
 	results := make([][]SearchResult, len(queryVectors))
 	for i, result := range searchResult {
-		results[i] = make([]SearchResult, result.ResultCount)
-		for j := 0; j < result.ResultCount; j++ {
-			searchRes := SearchResult{
-				Score:  result.Scores[j],
-				Fields: make(map[string]interface{}),
-			}
+		if i < len(results) {
+			results[i] = toSearchResults(result)
+		}
+	}
+	return results, nil
+}
 
-			// 获取ID
-			if result.IDs != nil {
-				id, _ := result.IDs.Get(j)
-				searchRes.ID = id
-			}
+// toSearchResults 转换单个查询向量的搜索结果
+func toSearchResults(result client.SearchResult) []SearchResult {
+	results := make([]SearchResult, result.ResultCount)
+	for j := 0; j < result.ResultCount; j++ {
+		searchRes := SearchResult{
+			Score:  result.Scores[j],
+			Fields: make(map[string]interface{}),
+		}
 
-			// 获取其他字段
-			for _, field := range result.Fields {
-				if value, err := field.Get(j); err == nil {
-					searchRes.Fields[field.Name()] = value
-				}
-			}
+		// 获取ID
+		if result.IDs != nil {
+			id, _ := result.IDs.Get(j)
+			searchRes.ID = id
+		}
 
-			results[i][j] = searchRes
+		// 获取其他字段
+		for _, field := range result.Fields {
+			if value, err := field.Get(j); err == nil {
+				searchRes.Fields[field.Name()] = value
+			}
 		}
-	}
 
-	zlog.Infof(ctx, "searched %d query vectors in collection %s, topK: %d, cost: %v",
-		len(queryVectors), collectionName, topK, time.Since(start))
-	return results, nil
+		results[j] = searchRes
+	}
+	return results
 }
 
 // CreateIndex 创建索引
@@ -265,7 +373,9 @@ func (mc *MilvusClient) CreateIndex(ctx *gin.Context, collectionName, fieldName
 		return fmt.Errorf("failed to create index config: %w", err)
 	}
 
-	err = mc.client.CreateIndex(ctx, collectionName, fieldName, idx, false)
+	err = mc.doWrite(ctx, func(c client.Client) error {
+		return c.CreateIndex(ctx, collectionName, fieldName, idx, false)
+	})
 	if err != nil {
 		zlog.Errorf(ctx, "failed to create index on %s.%s: %v", collectionName, fieldName, err)
 		return fmt.Errorf("failed to create index: %w", err)
@@ -276,18 +386,36 @@ func (mc *MilvusClient) CreateIndex(ctx *gin.Context, collectionName, fieldName
 	return nil
 }
 
-// LoadCollection 加载集合到内存
+// LoadCollection 加载集合到内存，使用服务端默认的副本数和资源组
 func (mc *MilvusClient) LoadCollection(ctx *gin.Context, collectionName string, async bool) error {
+	return mc.LoadCollectionWithOptions(ctx, collectionName, 0, nil, async)
+}
+
+// LoadCollectionWithOptions 按副本数和资源组加载集合，查询请求在多个副本间分摊，单个 QueryNode 故障时仍可查询
+// replicaNumber <= 0 时使用服务端默认值（1），resourceGroups 为空时使用默认资源组；每个副本都完整加载一份数据，
+// 内存占用约为单副本的 replicaNumber 倍，且副本数不能超过资源组内的 QueryNode 数
+// 集合已加载时修改副本数需先 ReleaseCollection；async 为 true 时立即返回，通过 GetLoadingProgress 查询进度
+func (mc *MilvusClient) LoadCollectionWithOptions(ctx *gin.Context, collectionName string, replicaNumber int, resourceGroups []string, async bool) error {
 	start := time.Now()
 
-	err := mc.client.LoadCollection(ctx, collectionName, async)
+	var opts []client.LoadCollectionOption
+	if replicaNumber > 0 {
+		opts = append(opts, client.WithReplicaNumber(int32(replicaNumber)))
+	}
+	if len(resourceGroups) > 0 {
+		opts = append(opts, client.WithResourceGroups(resourceGroups))
+	}
+	err := mc.doWrite(ctx, func(c client.Client) error {
+		return c.LoadCollection(ctx, collectionName, async, opts...)
+	})
 	if err != nil {
-		zlog.Errorf(ctx, "failed to load collection %s: %v", collectionName, err)
+		zlog.Errorf(ctx, "failed to load collection %s, replicas: %d, resource groups: %v: %v",
+			collectionName, replicaNumber, resourceGroups, err)
 		return fmt.Errorf("failed to load collection: %w", err)
 	}
 
-	zlog.Infof(ctx, "collection %s loaded successfully, async: %v, cost: %v",
-		collectionName, async, time.Since(start))
+	zlog.Infof(ctx, "collection %s loaded successfully, replicas: %d, resource groups: %v, async: %v, cost: %v",
+		collectionName, replicaNumber, resourceGroups, async, time.Since(start))
 	return nil
 }
 
@@ -295,7 +423,9 @@ func (mc *MilvusClient) LoadCollection(ctx *gin.Context, collectionName string,
 func (mc *MilvusClient) ReleaseCollection(ctx *gin.Context, collectionName string) error {
 	start := time.Now()
 
-	err := mc.client.ReleaseCollection(ctx, collectionName)
+	err := mc.doWrite(ctx, func(c client.Client) error {
+		return c.ReleaseCollection(ctx, collectionName)
+	})
 	if err != nil {
 		zlog.Errorf(ctx, "failed to release collection %s: %v", collectionName, err)
 		return fmt.Errorf("failed to release collection: %w", err)
@@ -309,7 +439,11 @@ func (mc *MilvusClient) ReleaseCollection(ctx *gin.Context, collectionName strin
 func (mc *MilvusClient) GetCollectionStatistics(ctx *gin.Context, collectionName string) (map[string]string, error) {
 	start := time.Now()
 
-	stats, err := mc.client.GetCollectionStatistics(ctx, collectionName)
+	var stats map[string]string
+	err := mc.do(ctx, func(c client.Client) (err error) {
+		stats, err = c.GetCollectionStatistics(ctx, collectionName)
+		return err
+	})
 	if err != nil {
 		zlog.Errorf(ctx, "failed to get collection statistics %s: %v", collectionName, err)
 		return nil, fmt.Errorf("failed to get collection statistics: %w", err)
@@ -326,7 +460,9 @@ func (mc *MilvusClient) DeleteByIds(ctx *gin.Context, collectionName string, ids
 	// 构建删除表达式
 	expr := fmt.Sprintf("id in %v", ids)
 
-	err := mc.client.Delete(ctx, collectionName, "", expr)
+	err := mc.doWrite(ctx, func(c client.Client) error {
+		return c.Delete(ctx, collectionName, "", expr)
+	})
 	if err != nil {
 		zlog.Errorf(ctx, "failed to delete data from collection %s: %v", collectionName, err)
 		return fmt.Errorf("failed to delete data: %w", err)
@@ -341,7 +477,9 @@ func (mc *MilvusClient) DeleteByIds(ctx *gin.Context, collectionName string, ids
 func (mc *MilvusClient) DeleteByExpr(ctx *gin.Context, collectionName string, expr string) error {
 	start := time.Now()
 
-	err := mc.client.Delete(ctx, collectionName, "", expr)
+	err := mc.doWrite(ctx, func(c client.Client) error {
+		return c.Delete(ctx, collectionName, "", expr)
+	})
 	if err != nil {
 		zlog.Errorf(ctx, "failed to delete data from collection %s with expr %s: %v", collectionName, expr, err)
 		return fmt.Errorf("failed to delete data: %w", err)
@@ -356,7 +494,11 @@ func (mc *MilvusClient) DeleteByExpr(ctx *gin.Context, collectionName string, ex
 func (mc *MilvusClient) ListCollections(ctx *gin.Context) ([]*entity.Collection, error) {
 	start := time.Now()
 
-	collections, err := mc.client.ListCollections(ctx)
+	var collections []*entity.Collection
+	err := mc.do(ctx, func(c client.Client) (err error) {
+		collections, err = c.ListCollections(ctx)
+		return err
+	})
 	if err != nil {
 		zlog.Errorf(ctx, "failed to list collections: %v", err)
 		return nil, fmt.Errorf("failed to list collections: %w", err)
@@ -370,7 +512,11 @@ func (mc *MilvusClient) ListCollections(ctx *gin.Context) ([]*entity.Collection,
 func (mc *MilvusClient) DescribeCollection(ctx *gin.Context, collectionName string) (*entity.Collection, error) {
 	start := time.Now()
 
-	collection, err := mc.client.DescribeCollection(ctx, collectionName)
+	var collection *entity.Collection
+	err := mc.do(ctx, func(c client.Client) (err error) {
+		collection, err = c.DescribeCollection(ctx, collectionName)
+		return err
+	})
 	if err != nil {
 		zlog.Errorf(ctx, "failed to describe collection %s: %v", collectionName, err)
 		return nil, fmt.Errorf("failed to describe collection: %w", err)
@@ -384,7 +530,9 @@ func (mc *MilvusClient) DescribeCollection(ctx *gin.Context, collectionName stri
 func (mc *MilvusClient) Flush(ctx *gin.Context, collectionName string) error {
 	start := time.Now()
 
-	err := mc.client.Flush(ctx, collectionName, false)
+	err := mc.doWrite(ctx, func(c client.Client) error {
+		return c.Flush(ctx, collectionName, false)
+	})
 	if err != nil {
 		zlog.Errorf(ctx, "failed to flush collections %v: %v", collectionName, err)
 		return fmt.Errorf("failed to flush collections: %w", err)
@@ -398,7 +546,11 @@ func (mc *MilvusClient) Flush(ctx *gin.Context, collectionName string) error {
 func (mc *MilvusClient) GetLoadingProgress(ctx *gin.Context, collectionName string) (int64, error) {
 	start := time.Now()
 
-	progress, err := mc.client.GetLoadingProgress(ctx, collectionName, nil)
+	var progress int64
+	err := mc.do(ctx, func(c client.Client) (err error) {
+		progress, err = c.GetLoadingProgress(ctx, collectionName, nil)
+		return err
+	})
 	if err != nil {
 		zlog.Errorf(ctx, "failed to get loading progress for collection %s: %v", collectionName, err)
 		return 0, fmt.Errorf("failed to get loading progress: %w", err)
@@ -413,7 +565,11 @@ func (mc *MilvusClient) GetLoadingProgress(ctx *gin.Context, collectionName stri
 func (mc *MilvusClient) Query(ctx *gin.Context, collectionName string, expr string, outputFields []string) ([]entity.Column, error) {
 	start := time.Now()
 
-	result, err := mc.client.Query(ctx, collectionName, nil, expr, outputFields)
+	var result client.ResultSet
+	err := mc.do(ctx, func(c client.Client) (err error) {
+		result, err = c.Query(ctx, collectionName, nil, expr, outputFields)
+		return err
+	})
 	if err != nil {
 		zlog.Errorf(ctx, "failed to query data from collection %s: %v", collectionName, err)
 		return nil, fmt.Errorf("failed to query data: %w", err)
@@ -426,12 +582,21 @@ func (mc *MilvusClient) Query(ctx *gin.Context, collectionName string, expr stri
 
 // Close 关闭客户端连接
 func (mc *MilvusClient) Close() error {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
 	if mc.client != nil {
 		return mc.client.Close()
 	}
 	return nil
 }
 
+// CheckHealth 检查 Milvus 服务状态，不健康时返回原因
+//
+// Deprecated: 使用 HealthCheck
+func (mc *MilvusClient) CheckHealth(ctx context.Context) error {
+	return mc.HealthCheck(ctx)
+}
+
 // CreateDefaultIndex 创建默认的IVF_FLAT索引
 func (mc *MilvusClient) CreateDefaultIndex(ctx *gin.Context, collectionName string) error {
 	params := map[string]string{
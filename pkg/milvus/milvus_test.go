@@ -0,0 +1,71 @@
+package milvus
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/milvus-io/milvus-sdk-go/v2/client"
+	"github.com/milvus-io/milvus-sdk-go/v2/entity"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// searchClient 记录 Search 的参数，每个查询向量返回一个结果
+type searchClient struct {
+	client.Client
+	vectors []entity.Vector
+	topK    int
+	params  map[string]any
+}
+
+func (f *searchClient) Search(ctx context.Context, collName string, partitions []string, expr string, outputFields []string, vectors []entity.Vector, vectorField string, metricType entity.MetricType, topK int, sp entity.SearchParam, opts ...client.SearchQueryOptionFunc) ([]client.SearchResult, error) {
+	f.vectors = vectors
+	f.topK = topK
+	f.params = sp.Params()
+	results := make([]client.SearchResult, len(vectors))
+	for i := range vectors {
+		results[i] = client.SearchResult{
+			ResultCount: 1,
+			IDs:         entity.NewColumnInt64("id", []int64{int64(i + 1)}),
+			Scores:      []float32{0.1},
+		}
+	}
+	return results, nil
+}
+
+func TestRangeSearch(t *testing.T) {
+	fake := &searchClient{}
+	mc := &MilvusClient{client: fake}
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	results, err := mc.RangeSearch(ctx, "docs", [][]float32{{0.1, 0.2}, {0.3, 0.4}}, 0.5, 0, []string{"title"})
+	require.NoError(t, err)
+	assert.Equal(t, rangeSearchLimit, fake.topK)
+	assert.Equal(t, float64(0.5), fake.params["radius"])
+	assert.Equal(t, float64(0), fake.params["range_filter"])
+	require.Len(t, fake.vectors, 2)
+	require.Len(t, results, 2)
+	assert.Equal(t, int64(2), results[1][0].ID)
+
+	for _, c := range [][2]float32{{0, 0}, {0.5, 0.5}, {0.5, -0.1}} {
+		_, err = mc.RangeSearch(ctx, "docs", [][]float32{{0.1}}, c[0], c[1], nil)
+		assert.Error(t, err, "radius %v, rangeFilter %v", c[0], c[1])
+	}
+}
+
+func TestSearchVectors(t *testing.T) {
+	fake := &searchClient{}
+	mc := &MilvusClient{client: fake}
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	results, err := mc.SearchVectors(ctx, "docs", [][]float32{{0.1, 0.2}, {0.3, 0.4}}, 10, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 10, fake.topK)
+	assert.NotContains(t, fake.params, "radius")
+	// 只发送查询向量，没有多余的空向量
+	assert.Equal(t, []entity.Vector{entity.FloatVector{0.1, 0.2}, entity.FloatVector{0.3, 0.4}}, fake.vectors)
+	require.Len(t, results, 2)
+	assert.Equal(t, int64(1), results[0][0].ID)
+}
@@ -0,0 +1,162 @@
+// Package milvus -----------------------------
+// @file      : reconnect.go
+// @author    : xiangtao
+// @contact   : xiangtao1994@gmail.com
+// @time      : 2025/9/13 22:40
+// Description: 健康检查和连接不可用时的自动重连
+// -------------------------------------------
+package milvus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/gin-gonic/gin"
+	"github.com/milvus-io/milvus-sdk-go/v2/client"
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/xiangtao94/golib/pkg/zlog"
+)
+
+// ReconnectTotal 按地址统计自动重连次数，通过 golib.WithPrometheus(milvus.ReconnectTotal) 注册
+var ReconnectTotal = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "milvus_reconnect_total",
+		Help: "Total number of milvus client reconnections.",
+	}, []string{"address"},
+)
+
+// reconnectLogf 重连成功的日志，测试中替换
+var reconnectLogf = zlog.Warnf
+
+// HealthCheck 检查 Milvus 服务状态，不健康时返回原因，可用于就绪探针（golib.RegisterHealthChecker）
+// 服务端不支持 CheckHealth 时使用 GetVersion 检查连通性
+func (mc *MilvusClient) HealthCheck(ctx context.Context) error {
+	return mc.do(ctx, func(c client.Client) error {
+		state, err := c.CheckHealth(ctx)
+		if status.Code(err) == codes.Unimplemented {
+			if _, err = c.GetVersion(ctx); err != nil {
+				return fmt.Errorf("milvus get version error: %w", err)
+			}
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("milvus check health error: %w", err)
+		}
+		if !state.IsHealthy {
+			return fmt.Errorf("milvus unhealthy: %v", state.Reasons)
+		}
+		return nil
+	})
+}
+
+// do 执行只读请求，连接不可用（如 Milvus proxy 重启）时重建客户端并重试一次
+func (mc *MilvusClient) do(ctx context.Context, fn func(c client.Client) error) error {
+	return mc.call(ctx, true, fn)
+}
+
+// doWrite 执行写请求（插入、删除、建集合、建索引等），连接不可用时同样重建客户端，
+// 但只在请求未发出（ErrClientNotReady）时重试：已到达服务端的写入重放会重复执行，如 auto-ID 的数据被插入两次
+func (mc *MilvusClient) doWrite(ctx context.Context, fn func(c client.Client) error) error {
+	return mc.call(ctx, false, fn)
+}
+
+func (mc *MilvusClient) call(ctx context.Context, idempotent bool, fn func(c client.Client) error) error {
+	c, refs := mc.acquire()
+	err := fn(c)
+	refs.release(c)
+	if err == nil || !isConnectionError(err) {
+		return err
+	}
+	if reconnectErr := mc.reconnect(ctx, c, err); reconnectErr != nil {
+		return fmt.Errorf("%w (reconnect failed: %v)", err, reconnectErr)
+	}
+	if !idempotent && !errors.Is(err, client.ErrClientNotReady) {
+		return err
+	}
+	c, refs = mc.acquire()
+	defer refs.release(c)
+	return fn(c)
+}
+
+// clientRefs 客户端进行中的调用数，重连后旧客户端在最后一个调用结束时才关闭，不中断其他协程的请求
+type clientRefs struct {
+	inflight atomic.Int64
+	retired  atomic.Bool
+	once     sync.Once
+}
+
+// acquire 返回当前客户端并增加其调用数，调用结束后需调用 release
+func (mc *MilvusClient) acquire() (client.Client, *clientRefs) {
+	mc.mu.RLock()
+	c, refs := mc.client, mc.refs
+	if refs != nil {
+		refs.inflight.Add(1)
+	}
+	mc.mu.RUnlock()
+	if refs != nil {
+		return c, refs
+	}
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	if mc.refs == nil {
+		mc.refs = &clientRefs{}
+	}
+	mc.refs.inflight.Add(1)
+	return mc.client, mc.refs
+}
+
+func (r *clientRefs) release(c client.Client) {
+	if r.inflight.Add(-1) == 0 && r.retired.Load() {
+		r.close(c)
+	}
+}
+
+// retire 客户端被替换，没有进行中的调用时立即关闭，否则由最后一个 release 关闭
+func (r *clientRefs) retire(c client.Client) {
+	r.retired.Store(true)
+	if r.inflight.Load() == 0 {
+		r.close(c)
+	}
+}
+
+func (r *clientRefs) close(c client.Client) {
+	r.once.Do(func() {
+		if c != nil {
+			_ = c.Close()
+		}
+	})
+}
+
+// reconnect 使用保存的配置重建客户端，stale 已被其他请求替换时直接返回
+func (mc *MilvusClient) reconnect(ctx context.Context, stale client.Client, cause error) error {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	if mc.client != stale {
+		return nil
+	}
+	ginCtx, _ := ctx.(*gin.Context)
+	connCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), mc.config.ConnectTimeout)
+	defer cancel()
+	c, err := mc.newClient(connCtx, mc.config.clientConfig())
+	if err != nil {
+		zlog.Errorf(ginCtx, "milvus %s reconnect failed: %v", mc.config.address(), err)
+		return err
+	}
+	mc.refs.retire(stale)
+	mc.client = c
+	mc.refs = &clientRefs{}
+	ReconnectTotal.WithLabelValues(mc.config.address()).Inc()
+	reconnectLogf(ginCtx, "milvus %s reconnected after error: %v", mc.config.address(), cause)
+	return nil
+}
+
+// isConnectionError 连接断开或不可用，业务错误（如集合不存在）不重连
+func isConnectionError(err error) bool {
+	return errors.Is(err, client.ErrClientNotReady) || status.Code(err) == codes.Unavailable
+}
@@ -0,0 +1,210 @@
+package milvus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/milvus-io/milvus-sdk-go/v2/client"
+	"github.com/milvus-io/milvus-sdk-go/v2/entity"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// fakeClient 只实现用到的方法，unavailable 次数内返回 Unavailable
+type fakeClient struct {
+	client.Client
+	unavailable int
+	calls       int
+	closed      atomic.Bool
+	health      func() (*entity.MilvusState, error)
+	// insertErr 不为空时 Insert 返回该错误，inserts 记录到达的插入次数
+	insertErr error
+	inserts   int
+	// block 不为空时 HasCollection 阻塞到 block 关闭，模拟进行中的请求
+	block chan struct{}
+}
+
+func (f *fakeClient) Insert(ctx context.Context, collName string, partitionName string, columns ...entity.Column) (entity.Column, error) {
+	f.inserts++
+	if f.insertErr != nil {
+		return nil, f.insertErr
+	}
+	return entity.NewColumnInt64("id", []int64{1}), nil
+}
+
+func (f *fakeClient) HasCollection(ctx context.Context, collName string) (bool, error) {
+	if f.block != nil {
+		<-f.block
+		return true, nil
+	}
+	f.calls++
+	if f.unavailable > 0 {
+		f.unavailable--
+		return false, status.Error(codes.Unavailable, "connection refused")
+	}
+	return collName == "exists", nil
+}
+
+func (f *fakeClient) CheckHealth(ctx context.Context) (*entity.MilvusState, error) {
+	return f.health()
+}
+
+func (f *fakeClient) GetVersion(ctx context.Context) (string, error) {
+	return "v2.2.0", nil
+}
+
+func (f *fakeClient) Close() error {
+	f.closed.Store(true)
+	return nil
+}
+
+func newFakeMilvusClient(t *testing.T, first *fakeClient, next func() (client.Client, error)) (*MilvusClient, *[]string) {
+	var logs []string
+	origin := reconnectLogf
+	reconnectLogf = func(ctx *gin.Context, format string, args ...interface{}) {
+		logs = append(logs, fmt.Sprintf(format, args...))
+	}
+	t.Cleanup(func() { reconnectLogf = origin })
+
+	conf := MilvusConf{Host: "milvus", Port: "19530"}
+	conf.checkConf()
+	return &MilvusClient{
+		client: first,
+		refs:   &clientRefs{},
+		config: conf,
+		newClient: func(ctx context.Context, config client.Config) (client.Client, error) {
+			return next()
+		},
+	}, &logs
+}
+
+func TestReconnectOnUnavailable(t *testing.T) {
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	broken := &fakeClient{unavailable: 1}
+	healthy := &fakeClient{}
+	var dials atomic.Int32
+	mc, logs := newFakeMilvusClient(t, broken, func() (client.Client, error) {
+		dials.Add(1)
+		return healthy, nil
+	})
+	before := testutil.ToFloat64(ReconnectTotal.WithLabelValues("milvus:19530"))
+
+	// 第一次 Unavailable 后透明重连并重试一次
+	require.NoError(t, mc.CreateCollection(ctx, "exists", 8, ""))
+	assert.Equal(t, 1, broken.calls)
+	assert.True(t, broken.closed.Load())
+	assert.Equal(t, 1, healthy.calls)
+	assert.Equal(t, int32(1), dials.Load())
+	assert.Equal(t, before+1, testutil.ToFloat64(ReconnectTotal.WithLabelValues("milvus:19530")))
+	require.Len(t, *logs, 1)
+	assert.Contains(t, (*logs)[0], "milvus milvus:19530 reconnected")
+
+	// 非连接错误不重连
+	healthy.health = func() (*entity.MilvusState, error) { return nil, errors.New("bad request") }
+	assert.ErrorContains(t, mc.HealthCheck(ctx), "bad request")
+	assert.Equal(t, int32(1), dials.Load())
+
+	// 重试仍失败时返回错误，只重试一次
+	healthy.unavailable = 2
+	assert.Error(t, mc.CreateCollection(ctx, "exists", 8, ""))
+	assert.Equal(t, int32(2), dials.Load())
+}
+
+func TestReconnectWriteNotReplayed(t *testing.T) {
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	broken := &fakeClient{insertErr: status.Error(codes.Unavailable, "connection reset")}
+	healthy := &fakeClient{}
+	mc, _ := newFakeMilvusClient(t, broken, func() (client.Client, error) {
+		return healthy, nil
+	})
+
+	// Unavailable 时插入可能已到达服务端，只重连不重放
+	_, err := mc.InsertVectors(ctx, "docs", [][]float32{{1, 2}})
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	assert.Equal(t, 1, broken.inserts)
+	assert.Zero(t, healthy.inserts)
+	assert.True(t, broken.closed.Load())
+
+	// 之后的写请求使用新连接
+	_, err = mc.InsertVectors(ctx, "docs", [][]float32{{1, 2}})
+	require.NoError(t, err)
+	assert.Equal(t, 1, healthy.inserts)
+
+	// client not ready 时请求未发出，重连后重试
+	notReady := &fakeClient{insertErr: client.ErrClientNotReady}
+	retried := &fakeClient{}
+	mc, _ = newFakeMilvusClient(t, notReady, func() (client.Client, error) {
+		return retried, nil
+	})
+	_, err = mc.InsertVectors(ctx, "docs", [][]float32{{1, 2}})
+	require.NoError(t, err)
+	assert.Equal(t, 1, retried.inserts)
+}
+
+func TestReconnectKeepsInflightCalls(t *testing.T) {
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	stale := &fakeClient{
+		block:  make(chan struct{}),
+		health: func() (*entity.MilvusState, error) { return nil, status.Error(codes.Unavailable, "connection refused") },
+	}
+	fresh := &fakeClient{health: func() (*entity.MilvusState, error) { return &entity.MilvusState{IsHealthy: true}, nil }}
+	mc, _ := newFakeMilvusClient(t, stale, func() (client.Client, error) {
+		return fresh, nil
+	})
+
+	// 一个请求在旧连接上进行中
+	done := make(chan error)
+	go func() {
+		done <- mc.CreateCollection(ctx, "exists", 8, "")
+	}()
+	require.Eventually(t, func() bool {
+		mc.mu.RLock()
+		defer mc.mu.RUnlock()
+		return mc.refs.inflight.Load() == 1
+	}, time.Second, time.Millisecond)
+
+	// 另一个请求触发重连，旧连接在进行中的请求结束后才关闭
+	require.NoError(t, mc.HealthCheck(ctx))
+	assert.False(t, stale.closed.Load())
+	close(stale.block)
+	require.NoError(t, <-done)
+	assert.True(t, stale.closed.Load())
+	assert.False(t, fresh.closed.Load())
+}
+
+func TestReconnectFailed(t *testing.T) {
+	mc, logs := newFakeMilvusClient(t, &fakeClient{unavailable: 1}, func() (client.Client, error) {
+		return nil, errors.New("dial timeout")
+	})
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	err := mc.CreateCollection(ctx, "exists", 8, "")
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	assert.ErrorContains(t, err, "reconnect failed: dial timeout")
+	assert.Empty(t, *logs)
+}
+
+func TestHealthCheck(t *testing.T) {
+	fc := &fakeClient{}
+	mc, _ := newFakeMilvusClient(t, fc, nil)
+
+	fc.health = func() (*entity.MilvusState, error) { return &entity.MilvusState{IsHealthy: true}, nil }
+	assert.NoError(t, mc.HealthCheck(context.Background()))
+
+	fc.health = func() (*entity.MilvusState, error) {
+		return &entity.MilvusState{IsHealthy: false, Reasons: []string{"querynode down"}}, nil
+	}
+	assert.ErrorContains(t, mc.HealthCheck(context.Background()), "querynode down")
+
+	// 旧版本不支持 CheckHealth 时使用 GetVersion
+	fc.health = func() (*entity.MilvusState, error) { return nil, status.Error(codes.Unimplemented, "unknown method") }
+	assert.NoError(t, mc.HealthCheck(context.Background()))
+}
@@ -0,0 +1,262 @@
+// Package milvus -----------------------------
+// @file      : schema.go
+// @author    : xiangtao
+// @contact   : xiangtao1994@gmail.com
+// @time      : 2025/9/13 23:00
+// Description: 集合 schema 构建和 EnsureCollection 自动迁移
+// -------------------------------------------
+package milvus
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/milvus-io/milvus-sdk-go/v2/client"
+	"github.com/milvus-io/milvus-sdk-go/v2/entity"
+
+	"github.com/xiangtao94/golib/pkg/zlog"
+)
+
+// ErrSchemaIncompatible 已有集合的 schema 与期望不兼容（如向量维度、字段类型变化、缺少字段），需人工迁移
+var ErrSchemaIncompatible = errors.New("milvus: collection schema incompatible")
+
+// SchemaBuilder 链式构建集合 schema
+//
+//	schema := milvus.NewSchema("docs").PrimaryVarchar("id", 64).FloatVector("vector", 768).
+//		Varchar("title", 512).JSON("meta").EnableDynamicField().Build()
+type SchemaBuilder struct {
+	schema *entity.Schema
+}
+
+// NewSchema 创建集合 name 的 schema
+func NewSchema(name string) *SchemaBuilder {
+	return &SchemaBuilder{schema: entity.NewSchema().WithName(name)}
+}
+
+func (b *SchemaBuilder) Description(desc string) *SchemaBuilder {
+	b.schema.WithDescription(desc)
+	return b
+}
+
+// PrimaryInt64 int64 主键，autoID 为 true 时由 Milvus 生成
+func (b *SchemaBuilder) PrimaryInt64(name string, autoID bool) *SchemaBuilder {
+	return b.field(entity.NewField().WithName(name).WithDataType(entity.FieldTypeInt64).
+		WithIsPrimaryKey(true).WithIsAutoID(autoID))
+}
+
+// PrimaryVarchar 字符串主键，由调用方指定
+func (b *SchemaBuilder) PrimaryVarchar(name string, maxLen int64) *SchemaBuilder {
+	return b.field(entity.NewField().WithName(name).WithDataType(entity.FieldTypeVarChar).
+		WithIsPrimaryKey(true).WithMaxLength(maxLen))
+}
+
+func (b *SchemaBuilder) FloatVector(name string, dim int64) *SchemaBuilder {
+	return b.field(entity.NewField().WithName(name).WithDataType(entity.FieldTypeFloatVector).WithDim(dim))
+}
+
+func (b *SchemaBuilder) Varchar(name string, maxLen int64) *SchemaBuilder {
+	return b.field(entity.NewField().WithName(name).WithDataType(entity.FieldTypeVarChar).WithMaxLength(maxLen))
+}
+
+func (b *SchemaBuilder) Bool(name string) *SchemaBuilder {
+	return b.field(entity.NewField().WithName(name).WithDataType(entity.FieldTypeBool))
+}
+
+func (b *SchemaBuilder) Int32(name string) *SchemaBuilder {
+	return b.field(entity.NewField().WithName(name).WithDataType(entity.FieldTypeInt32))
+}
+
+func (b *SchemaBuilder) Int64(name string) *SchemaBuilder {
+	return b.field(entity.NewField().WithName(name).WithDataType(entity.FieldTypeInt64))
+}
+
+func (b *SchemaBuilder) Float(name string) *SchemaBuilder {
+	return b.field(entity.NewField().WithName(name).WithDataType(entity.FieldTypeFloat))
+}
+
+func (b *SchemaBuilder) Double(name string) *SchemaBuilder {
+	return b.field(entity.NewField().WithName(name).WithDataType(entity.FieldTypeDouble))
+}
+
+func (b *SchemaBuilder) JSON(name string) *SchemaBuilder {
+	return b.field(entity.NewField().WithName(name).WithDataType(entity.FieldTypeJSON))
+}
+
+// EnableDynamicField 开启动态字段，未在 schema 中声明的字段写入 $meta
+func (b *SchemaBuilder) EnableDynamicField() *SchemaBuilder {
+	b.schema.WithDynamicFieldEnabled(true)
+	return b
+}
+
+func (b *SchemaBuilder) field(f *entity.Field) *SchemaBuilder {
+	b.schema.WithField(f)
+	return b
+}
+
+func (b *SchemaBuilder) Build() *entity.Schema {
+	return b.schema
+}
+
+// IndexOption 向量字段的索引配置
+type IndexOption struct {
+	FieldName  string
+	IndexType  entity.IndexType
+	MetricType entity.MetricType
+	Params     map[string]string
+}
+
+// HNSWIndex HNSW 索引，M 为每个节点的最大连接数，efConstruction 为构建时的候选集大小
+func HNSWIndex(fieldName string, metricType entity.MetricType, M, efConstruction int) IndexOption {
+	return IndexOption{FieldName: fieldName, IndexType: entity.HNSW, MetricType: metricType, Params: map[string]string{
+		"M":              strconv.Itoa(M),
+		"efConstruction": strconv.Itoa(efConstruction),
+	}}
+}
+
+// IVFFlatIndex IVF_FLAT 索引，nlist 为聚类中心数
+func IVFFlatIndex(fieldName string, metricType entity.MetricType, nlist int) IndexOption {
+	return IndexOption{FieldName: fieldName, IndexType: entity.IvfFlat, MetricType: metricType, Params: map[string]string{
+		"nlist": strconv.Itoa(nlist),
+	}}
+}
+
+// DiskANNIndex DISKANN 磁盘索引，适用于内存放不下的大规模数据
+func DiskANNIndex(fieldName string, metricType entity.MetricType) IndexOption {
+	return IndexOption{FieldName: fieldName, IndexType: entity.DISKANN, MetricType: metricType}
+}
+
+// EnsureOptions EnsureCollection 的配置
+type EnsureOptions struct {
+	ShardsNum int32         // 分片数，默认1
+	Indexes   []IndexOption // 需要创建的索引，已存在的索引不重复创建
+	SkipLoad  bool          // 不加载集合到内存
+}
+
+// EnsureCollection 集合不存在时按 schema 创建，已存在时校验 schema 是否兼容，之后创建缺失的索引并加载集合
+// 向量维度、字段类型、主键变化或缺少字段时返回 ErrSchemaIncompatible，不会继续写入不兼容的集合
+// 当前 SDK 不支持为已有集合添加字段，新增字段需人工迁移，或开启动态字段后写入 $meta
+func (mc *MilvusClient) EnsureCollection(ctx *gin.Context, schema *entity.Schema, opts EnsureOptions) error {
+	start := time.Now()
+	name := schema.CollectionName
+
+	var exists bool
+	err := mc.do(ctx, func(c client.Client) (err error) {
+		exists, err = c.HasCollection(ctx, name)
+		return err
+	})
+	if err != nil {
+		zlog.Errorf(ctx, "failed to check collection exists %s: %v", name, err)
+		return fmt.Errorf("failed to check collection exists: %w", err)
+	}
+
+	if exists {
+		var coll *entity.Collection
+		err = mc.do(ctx, func(c client.Client) (err error) {
+			coll, err = c.DescribeCollection(ctx, name)
+			return err
+		})
+		if err != nil {
+			zlog.Errorf(ctx, "failed to describe collection %s: %v", name, err)
+			return fmt.Errorf("failed to describe collection: %w", err)
+		}
+		if diffs := diffSchema(schema, coll.Schema); len(diffs) > 0 {
+			zlog.Errorf(ctx, "collection %s schema incompatible: %s", name, strings.Join(diffs, "; "))
+			return fmt.Errorf("%w: collection %s: %s", ErrSchemaIncompatible, name, strings.Join(diffs, "; "))
+		}
+	} else {
+		shardsNum := opts.ShardsNum
+		if shardsNum <= 0 {
+			shardsNum = 1
+		}
+		err = mc.doWrite(ctx, func(c client.Client) error {
+			return c.CreateCollection(ctx, schema, shardsNum)
+		})
+		if err != nil {
+			zlog.Errorf(ctx, "failed to create collection %s: %v", name, err)
+			return fmt.Errorf("failed to create collection: %w", err)
+		}
+		zlog.Infof(ctx, "collection %s created, shards: %d", name, shardsNum)
+	}
+
+	for _, opt := range opts.Indexes {
+		if err = mc.ensureIndex(ctx, name, opt); err != nil {
+			return err
+		}
+	}
+
+	if !opts.SkipLoad {
+		err = mc.doWrite(ctx, func(c client.Client) error {
+			return c.LoadCollection(ctx, name, false)
+		})
+		if err != nil {
+			zlog.Errorf(ctx, "failed to load collection %s: %v", name, err)
+			return fmt.Errorf("failed to load collection: %w", err)
+		}
+	}
+
+	zlog.Infof(ctx, "collection %s ensured, existed: %v, cost: %v", name, exists, time.Since(start))
+	return nil
+}
+
+// ensureIndex 字段上没有索引时创建，DescribeIndex 出错视为没有索引
+func (mc *MilvusClient) ensureIndex(ctx *gin.Context, collectionName string, opt IndexOption) error {
+	var indexes []entity.Index
+	err := mc.do(ctx, func(c client.Client) (err error) {
+		indexes, err = c.DescribeIndex(ctx, collectionName, opt.FieldName)
+		return err
+	})
+	if err == nil && len(indexes) > 0 {
+		return nil
+	}
+	// NewIndexByType 会写入 metric_type，复制一份避免修改调用方的 Params
+	params := make(map[string]string, len(opt.Params)+1)
+	for k, v := range opt.Params {
+		params[k] = v
+	}
+	idx, _ := NewIndexByType(opt.IndexType, opt.MetricType, params)
+	err = mc.doWrite(ctx, func(c client.Client) error {
+		return c.CreateIndex(ctx, collectionName, opt.FieldName, idx, false)
+	})
+	if err != nil {
+		zlog.Errorf(ctx, "failed to create index on %s.%s: %v", collectionName, opt.FieldName, err)
+		return fmt.Errorf("failed to create index: %w", err)
+	}
+	zlog.Infof(ctx, "index created on %s.%s, type: %s, metric: %s", collectionName, opt.FieldName, opt.IndexType, opt.MetricType)
+	return nil
+}
+
+// diffSchema 返回 want 与已有集合 schema 的不兼容项，已有集合多出的字段不影响
+func diffSchema(want, got *entity.Schema) []string {
+	existing := make(map[string]*entity.Field, len(got.Fields))
+	for _, f := range got.Fields {
+		existing[f.Name] = f
+	}
+	var diffs []string
+	for _, f := range want.Fields {
+		g, ok := existing[f.Name]
+		if !ok {
+			diffs = append(diffs, fmt.Sprintf("field %s missing", f.Name))
+			continue
+		}
+		if g.DataType != f.DataType {
+			diffs = append(diffs, fmt.Sprintf("field %s type %s, want %s", f.Name, g.DataType.Name(), f.DataType.Name()))
+			continue
+		}
+		if g.PrimaryKey != f.PrimaryKey {
+			diffs = append(diffs, fmt.Sprintf("field %s primary key %v, want %v", f.Name, g.PrimaryKey, f.PrimaryKey))
+		}
+		for _, key := range []string{entity.TypeParamDim, entity.TypeParamMaxLength} {
+			if w, ok := f.TypeParams[key]; ok && g.TypeParams[key] != w {
+				diffs = append(diffs, fmt.Sprintf("field %s %s %s, want %s", f.Name, key, g.TypeParams[key], w))
+			}
+		}
+	}
+	if want.EnableDynamicField && !got.EnableDynamicField {
+		diffs = append(diffs, "dynamic field disabled")
+	}
+	return diffs
+}
@@ -0,0 +1,135 @@
+package milvus
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/milvus-io/milvus-proto/go-api/v2/milvuspb"
+	"github.com/milvus-io/milvus-sdk-go/v2/client"
+	"github.com/milvus-io/milvus-sdk-go/v2/entity"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// schemaClient 在内存中记录集合和索引
+type schemaClient struct {
+	client.Client
+	collections map[string]*entity.Schema
+	indexes     map[string]entity.Index
+	calls       []string
+	loadReq     *milvuspb.LoadCollectionRequest
+}
+
+func newSchemaClient() *schemaClient {
+	return &schemaClient{collections: map[string]*entity.Schema{}, indexes: map[string]entity.Index{}}
+}
+
+func (f *schemaClient) HasCollection(ctx context.Context, collName string) (bool, error) {
+	_, ok := f.collections[collName]
+	return ok, nil
+}
+
+func (f *schemaClient) DescribeCollection(ctx context.Context, collName string) (*entity.Collection, error) {
+	return &entity.Collection{Name: collName, Schema: f.collections[collName]}, nil
+}
+
+func (f *schemaClient) CreateCollection(ctx context.Context, schema *entity.Schema, shardsNum int32, opts ...client.CreateCollectionOption) error {
+	f.calls = append(f.calls, "CreateCollection")
+	f.collections[schema.CollectionName] = schema
+	return nil
+}
+
+func (f *schemaClient) DescribeIndex(ctx context.Context, collName string, fieldName string, opts ...client.IndexOption) ([]entity.Index, error) {
+	if idx, ok := f.indexes[collName+"."+fieldName]; ok {
+		return []entity.Index{idx}, nil
+	}
+	return nil, nil
+}
+
+func (f *schemaClient) CreateIndex(ctx context.Context, collName string, fieldName string, idx entity.Index, async bool, opts ...client.IndexOption) error {
+	f.calls = append(f.calls, "CreateIndex")
+	f.indexes[collName+"."+fieldName] = idx
+	return nil
+}
+
+func (f *schemaClient) LoadCollection(ctx context.Context, collName string, async bool, opts ...client.LoadCollectionOption) error {
+	f.calls = append(f.calls, "LoadCollection")
+	f.loadReq = &milvuspb.LoadCollectionRequest{CollectionName: collName}
+	for _, opt := range opts {
+		opt(f.loadReq)
+	}
+	return nil
+}
+
+func docsSchema(dim int64) *entity.Schema {
+	return NewSchema("docs").PrimaryVarchar("id", 64).FloatVector("vector", dim).
+		Varchar("title", 512).JSON("meta").EnableDynamicField().Build()
+}
+
+func TestSchemaBuilder(t *testing.T) {
+	schema := docsSchema(768)
+	assert.Equal(t, "docs", schema.CollectionName)
+	assert.True(t, schema.EnableDynamicField)
+	require.Len(t, schema.Fields, 4)
+	assert.True(t, schema.Fields[0].PrimaryKey)
+	assert.Equal(t, "64", schema.Fields[0].TypeParams[entity.TypeParamMaxLength])
+	assert.Equal(t, entity.FieldTypeFloatVector, schema.Fields[1].DataType)
+	assert.Equal(t, "768", schema.Fields[1].TypeParams[entity.TypeParamDim])
+	assert.Equal(t, entity.FieldTypeJSON, schema.Fields[3].DataType)
+}
+
+func TestEnsureCollection(t *testing.T) {
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	fake := newSchemaClient()
+	mc, _ := newFakeMilvusClient(t, nil, nil)
+	mc.client = fake
+	opts := EnsureOptions{Indexes: []IndexOption{HNSWIndex("vector", entity.COSINE, 16, 200)}}
+
+	// 不存在时创建集合、索引并加载
+	require.NoError(t, mc.EnsureCollection(ctx, docsSchema(768), opts))
+	assert.Equal(t, []string{"CreateCollection", "CreateIndex", "LoadCollection"}, fake.calls)
+	idx := fake.indexes["docs.vector"]
+	require.NotNil(t, idx)
+	assert.Equal(t, entity.HNSW, idx.IndexType())
+	assert.Equal(t, "COSINE", idx.Params()["metric_type"])
+	assert.Equal(t, "16", idx.Params()["M"])
+	assert.Len(t, opts.Indexes[0].Params, 2, "caller params should not be modified")
+
+	// schema 相同时只加载
+	fake.calls = nil
+	require.NoError(t, mc.EnsureCollection(ctx, docsSchema(768), opts))
+	assert.Equal(t, []string{"LoadCollection"}, fake.calls)
+
+	// 维度变化
+	fake.calls = nil
+	err := mc.EnsureCollection(ctx, docsSchema(1024), opts)
+	assert.ErrorIs(t, err, ErrSchemaIncompatible)
+	assert.ErrorContains(t, err, "field vector dim 768, want 1024")
+	assert.Empty(t, fake.calls)
+
+	// 缺少字段、类型变化
+	err = mc.EnsureCollection(ctx, NewSchema("docs").PrimaryVarchar("id", 64).FloatVector("vector", 768).
+		Int64("title").Bool("published").Build(), EnsureOptions{SkipLoad: true})
+	assert.ErrorIs(t, err, ErrSchemaIncompatible)
+	assert.ErrorContains(t, err, "field title type VarChar, want Int64")
+	assert.ErrorContains(t, err, "field published missing")
+}
+
+func TestLoadCollectionWithOptions(t *testing.T) {
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	fake := newSchemaClient()
+	mc, _ := newFakeMilvusClient(t, nil, nil)
+	mc.client = fake
+
+	require.NoError(t, mc.LoadCollectionWithOptions(ctx, "docs", 2, []string{"rg1", "rg2"}, true))
+	assert.Equal(t, "docs", fake.loadReq.CollectionName)
+	assert.EqualValues(t, 2, fake.loadReq.ReplicaNumber)
+	assert.Equal(t, []string{"rg1", "rg2"}, fake.loadReq.ResourceGroups)
+
+	// 不指定时使用服务端默认值
+	require.NoError(t, mc.LoadCollection(ctx, "docs", false))
+	assert.Zero(t, fake.loadReq.ReplicaNumber)
+	assert.Empty(t, fake.loadReq.ResourceGroups)
+}
@@ -0,0 +1,228 @@
+// Package orm -----------------------------
+// @file      : cursor.go
+// @author    : xiangtao
+// @contact   : xiangtao1994@gmail.com
+// @time      : 2025/9/13 22:10
+// Description: 游标（keyset）分页，深分页时不使用 OFFSET
+// -------------------------------------------
+package orm
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+
+	errors2 "github.com/xiangtao94/golib/pkg/errors"
+)
+
+// CursorPage 游标分页参数，按上一页最后一行的排序列取值定位下一页，耗时不随页数增加
+type CursorPage struct {
+	Cursor  string `json:"cursor"`  // 上一页返回的 nextCursor，首页为空
+	Size    int    `json:"size"`    // 每页大小，默认10，最大100
+	OrderBy string `json:"orderBy"` // 排序规则，如 "created_at desc"，列需在 keyColumns 中，默认按 keyColumns 升序
+}
+
+type cursorColumn struct {
+	name string
+	desc bool
+}
+
+func (page *CursorPage) size() int {
+	switch {
+	case page.Size > 100:
+		return 100
+	case page.Size <= 0:
+		return 10
+	}
+	return page.Size
+}
+
+// columns 解析 OrderBy，列需在 keyColumns 中且排序方向一致
+// keyColumns 的最后一列需唯一（通常为 id），OrderBy 未包含时按相同方向追加，保证排序稳定
+func (page *CursorPage) columns(keyColumns []string) ([]cursorColumn, error) {
+	if len(keyColumns) == 0 {
+		return nil, errors.New("orm: CursorPaginate requires at least one key column")
+	}
+	if strings.TrimSpace(page.OrderBy) == "" {
+		cols := make([]cursorColumn, 0, len(keyColumns))
+		for _, name := range keyColumns {
+			cols = append(cols, cursorColumn{name: name})
+		}
+		return cols, nil
+	}
+	var cols []cursorColumn
+	for _, item := range strings.Split(page.OrderBy, ",") {
+		parts := strings.Fields(item)
+		if len(parts) == 0 || len(parts) > 2 {
+			return nil, errors2.ErrorParamInvalid.Wrap(fmt.Errorf("invalid orderBy %q", page.OrderBy))
+		}
+		col := cursorColumn{name: parts[0]}
+		if len(parts) == 2 {
+			switch strings.ToLower(parts[1]) {
+			case "asc":
+			case "desc":
+				col.desc = true
+			default:
+				return nil, errors2.ErrorParamInvalid.Wrap(fmt.Errorf("invalid orderBy %q", page.OrderBy))
+			}
+		}
+		if !slices.Contains(keyColumns, col.name) {
+			return nil, errors2.ErrorParamInvalid.Wrap(fmt.Errorf("orderBy column %q is not allowed", col.name))
+		}
+		if len(cols) > 0 && cols[0].desc != col.desc {
+			return nil, errors2.ErrorParamInvalid.Wrap(fmt.Errorf("orderBy %q mixes asc and desc", page.OrderBy))
+		}
+		cols = append(cols, col)
+	}
+	unique := keyColumns[len(keyColumns)-1]
+	if !containsColumn(cols, unique) {
+		cols = append(cols, cursorColumn{name: unique, desc: cols[0].desc})
+	}
+	return cols, nil
+}
+
+// CursorPaginate 游标分页，keyColumns 为允许排序的列，最后一列需唯一（通常为 id）
+// 使用 col > ? 或 (a, b) > (?, ?) 定位，降序时为 <，游标无效时查询返回 errors.ErrorParamInvalid
+//
+//	db.Scopes(orm.CursorPaginate(page, "created_at", "id")).Find(&users)
+//	next, err := orm.NextCursor(db, page, users, "created_at", "id")
+func CursorPaginate(page *CursorPage, keyColumns ...string) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		cols, err := page.columns(keyColumns)
+		if err != nil {
+			_ = db.AddError(err)
+			return db
+		}
+		orderBy := clause.OrderBy{}
+		for _, col := range cols {
+			orderBy.Columns = append(orderBy.Columns, clause.OrderByColumn{Column: clause.Column{Name: col.name}, Desc: col.desc})
+		}
+		db = db.Order(orderBy).Limit(page.size())
+		if page.Cursor == "" {
+			return db
+		}
+		model := db.Statement.Model
+		if model == nil {
+			model = db.Statement.Dest
+		}
+		fields, err := cursorFields(db, model, cols)
+		if err != nil {
+			_ = db.AddError(err)
+			return db
+		}
+		values, err := decodeCursor(page.Cursor, fields)
+		if err != nil {
+			_ = db.AddError(err)
+			return db
+		}
+		return db.Where(keysetCondition(cols, values))
+	}
+}
+
+// NextCursor 根据查询结果 items（切片或切片指针）的最后一行生成下一页游标，参数与 CursorPaginate 相同
+// 结果不足一页时没有下一页，返回空字符串
+func NextCursor(db *gorm.DB, page *CursorPage, items any, keyColumns ...string) (string, error) {
+	cols, err := page.columns(keyColumns)
+	if err != nil {
+		return "", err
+	}
+	rows := reflect.Indirect(reflect.ValueOf(items))
+	if rows.Kind() != reflect.Slice {
+		return "", fmt.Errorf("orm: NextCursor expects a slice, got %T", items)
+	}
+	if rows.Len() == 0 || rows.Len() < page.size() {
+		return "", nil
+	}
+	fields, err := cursorFields(db, items, cols)
+	if err != nil {
+		return "", err
+	}
+	last := reflect.Indirect(rows.Index(rows.Len() - 1))
+	values := make([]any, 0, len(fields))
+	for _, field := range fields {
+		v, _ := field.ValueOf(db.Statement.Context, last)
+		values = append(values, v)
+	}
+	b, err := json.Marshal(values)
+	if err != nil {
+		return "", fmt.Errorf("orm: encode cursor: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func cursorFields(db *gorm.DB, model any, cols []cursorColumn) ([]*schema.Field, error) {
+	if model == nil {
+		return nil, errors.New("orm: CursorPaginate requires a model or destination")
+	}
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(model); err != nil {
+		return nil, err
+	}
+	fields := make([]*schema.Field, 0, len(cols))
+	for _, col := range cols {
+		field := stmt.Schema.LookUpField(col.name)
+		if field == nil {
+			return nil, fmt.Errorf("orm: unknown column %q of %s", col.name, stmt.Schema.Name)
+		}
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+// decodeCursor 按字段类型解码游标，被篡改或与排序列不匹配时返回 errors.ErrorParamInvalid
+func decodeCursor(cursor string, fields []*schema.Field) ([]any, error) {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, errors2.ErrorParamInvalid.Wrap(fmt.Errorf("invalid cursor: %w", err))
+	}
+	var raws []json.RawMessage
+	if err = json.Unmarshal(b, &raws); err != nil {
+		return nil, errors2.ErrorParamInvalid.Wrap(fmt.Errorf("invalid cursor: %w", err))
+	}
+	if len(raws) != len(fields) {
+		return nil, errors2.ErrorParamInvalid.Wrap(fmt.Errorf("invalid cursor: want %d values, got %d", len(fields), len(raws)))
+	}
+	values := make([]any, 0, len(fields))
+	for i, field := range fields {
+		v := reflect.New(field.FieldType)
+		if err = json.Unmarshal(raws[i], v.Interface()); err != nil {
+			return nil, errors2.ErrorParamInvalid.Wrap(fmt.Errorf("invalid cursor value for %s: %w", field.DBName, err))
+		}
+		values = append(values, v.Elem().Interface())
+	}
+	return values, nil
+}
+
+func keysetCondition(cols []cursorColumn, values []any) clause.Expression {
+	op := ">"
+	if cols[0].desc {
+		op = "<"
+	}
+	if len(cols) == 1 {
+		return clause.Expr{SQL: "? " + op + " ?", Vars: []any{clause.Column{Name: cols[0].name}, values[0]}}
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(cols)), ", ")
+	vars := make([]any, 0, len(cols)*2)
+	for _, col := range cols {
+		vars = append(vars, clause.Column{Name: col.name})
+	}
+	vars = append(vars, values...)
+	return clause.Expr{SQL: "(" + placeholders + ") " + op + " (" + placeholders + ")", Vars: vars}
+}
+
+func containsColumn(cols []cursorColumn, name string) bool {
+	for _, col := range cols {
+		if col.name == name {
+			return true
+		}
+	}
+	return false
+}
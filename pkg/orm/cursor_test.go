@@ -0,0 +1,143 @@
+package orm
+
+import (
+	"database/sql/driver"
+	"encoding/base64"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	errors2 "github.com/xiangtao94/golib/pkg/errors"
+)
+
+type item struct {
+	ID    int64
+	Score int
+}
+
+// keysetTable 模拟按 (score, id) 游标查询的表，分数有重复
+func keysetTable(n int, desc bool) func(query string, args []driver.Value) *fakeRows {
+	data := make([]item, 0, n)
+	for i := 1; i <= n; i++ {
+		data = append(data, item{ID: int64(i), Score: i % 4})
+	}
+	less := func(a, b item) bool {
+		if a.Score != b.Score {
+			return a.Score < b.Score
+		}
+		return a.ID < b.ID
+	}
+	return func(query string, args []driver.Value) *fakeRows {
+		sorted := append([]item(nil), data...)
+		sort.Slice(sorted, func(i, j int) bool {
+			if desc {
+				return less(sorted[j], sorted[i])
+			}
+			return less(sorted[i], sorted[j])
+		})
+		limit := int(args[len(args)-1].(int64))
+		res := &fakeRows{columns: []string{"id", "score"}}
+		for _, it := range sorted {
+			if len(args) == 3 {
+				after := item{Score: int(args[0].(int64)), ID: args[1].(int64)}
+				if desc && !less(it, after) || !desc && !less(after, it) {
+					continue
+				}
+			}
+			if len(res.rows) == limit {
+				break
+			}
+			res.rows = append(res.rows, []driver.Value{it.ID, int64(it.Score)})
+		}
+		return res
+	}
+}
+
+func TestCursorPaginateStitching(t *testing.T) {
+	for _, desc := range []bool{false, true} {
+		conn := &fakeConn{query: keysetTable(25, desc)}
+		db := newFakeDB(t, conn)
+		page := &CursorPage{Size: 10}
+		if desc {
+			page.OrderBy = "score desc"
+		}
+
+		var all []item
+		var sizes []int
+		for {
+			var items []item
+			require.NoError(t, db.Scopes(CursorPaginate(page, "score", "id")).Find(&items).Error)
+			next, err := NextCursor(db, page, items, "score", "id")
+			require.NoError(t, err)
+			all = append(all, items...)
+			sizes = append(sizes, len(items))
+			if next == "" {
+				break
+			}
+			page.Cursor = next
+		}
+		assert.Equal(t, []int{10, 10, 5}, sizes)
+		require.Len(t, all, 25)
+		seen := map[int64]bool{}
+		for i, it := range all {
+			seen[it.ID] = true
+			if i > 0 {
+				prev := all[i-1]
+				ordered := prev.Score < it.Score || prev.Score == it.Score && prev.ID < it.ID
+				assert.Equal(t, !desc, ordered, "desc=%v %v before %v", desc, prev, it)
+			}
+		}
+		assert.Len(t, seen, 25)
+		if desc {
+			assert.Contains(t, conn.lastSQL(), "(`score`, `id`) < (?, ?) ORDER BY `score` DESC,`id` DESC")
+		} else {
+			assert.Contains(t, conn.lastSQL(), "(`score`, `id`) > (?, ?) ORDER BY `score`,`id`")
+		}
+	}
+}
+
+func TestCursorPaginateSQL(t *testing.T) {
+	conn := &fakeConn{}
+	db := newFakeDB(t, conn).Session(&gorm.Session{DryRun: true})
+	cursor, err := NextCursor(db, &CursorPage{Size: 1}, []item{{ID: 7}}, "id")
+	require.NoError(t, err)
+
+	stmt := db.Scopes(CursorPaginate(&CursorPage{Cursor: cursor, Size: 20}, "id")).Find(&[]item{}).Statement
+	assert.Equal(t, "SELECT * FROM `items` WHERE `id` > ? ORDER BY `id` LIMIT ?", stmt.SQL.String())
+	assert.Equal(t, []any{int64(7), 20}, stmt.Vars)
+
+	// 空结果和不足一页时没有下一页
+	next, err := NextCursor(db, &CursorPage{}, []item{}, "id")
+	require.NoError(t, err)
+	assert.Empty(t, next)
+	next, err = NextCursor(db, &CursorPage{Size: 2}, &[]*item{{ID: 1}}, "id")
+	require.NoError(t, err)
+	assert.Empty(t, next)
+}
+
+func TestCursorPaginateInvalid(t *testing.T) {
+	db := newFakeDB(t, &fakeConn{}).Session(&gorm.Session{DryRun: true})
+	encode := func(s string) string { return base64.RawURLEncoding.EncodeToString([]byte(s)) }
+	pages := map[string]*CursorPage{
+		"not base64":    {Cursor: "!!!"},
+		"not array":     {Cursor: encode(`{"id":1}`)},
+		"wrong count":   {Cursor: encode(`[1,2]`)},
+		"wrong type":    {Cursor: encode(`["abc"]`)},
+		"column":        {OrderBy: "name desc"},
+		"direction":     {OrderBy: "id sideways"},
+		"mixed":         {OrderBy: "score desc, id asc"},
+		"empty orderBy": {OrderBy: "score,,id"},
+	}
+	for name, page := range pages {
+		keys := []string{"id"}
+		if strings.Contains(page.OrderBy, "score") {
+			keys = []string{"score", "id"}
+		}
+		err := db.Scopes(CursorPaginate(page, keys...)).Find(&[]item{}).Error
+		assert.ErrorIs(t, err, errors2.ErrorParamInvalid, name)
+	}
+}
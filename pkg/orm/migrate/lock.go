@@ -0,0 +1,91 @@
+// Package migrate -----------------------------
+// @file      : lock.go
+// @author    : xiangtao
+// @contact   : xiangtao1994@gmail.com
+// @time      : 2025/9/14 14:30
+// Description: 迁移锁，多实例同时启动时只有一个实例执行迁移
+// -------------------------------------------
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/xiangtao94/golib/pkg/zlog"
+)
+
+// 表锁的重试间隔
+var lockRetryInterval = 200 * time.Millisecond
+
+// lock 获取迁移锁，返回释放函数
+func (m *Migrator) lock(ctx context.Context, db *gorm.DB) (func(), error) {
+	if db.Dialector.Name() == "mysql" {
+		return m.mysqlLock(ctx, db)
+	}
+	return m.tableLock(ctx, db)
+}
+
+// mysqlLock GET_LOCK 与连接绑定，需要固定一个连接直到释放，连接断开时锁自动释放
+func (m *Migrator) mysqlLock(ctx context.Context, db *gorm.DB) (func(), error) {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, fmt.Errorf("migrate: get sql db: %w", err)
+	}
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("migrate: get conn: %w", err)
+	}
+	var got sql.NullInt64
+	err = conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, ?)", m.opts.LockName, int(m.opts.LockTimeout.Seconds())).Scan(&got)
+	if err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("migrate: get lock %s: %w", m.opts.LockName, err)
+	}
+	if !got.Valid || got.Int64 != 1 {
+		_ = conn.Close()
+		return nil, fmt.Errorf("%w: %s", ErrLocked, m.opts.LockName)
+	}
+	return func() {
+		var released sql.NullInt64
+		if err := conn.QueryRowContext(context.Background(), "SELECT RELEASE_LOCK(?)", m.opts.LockName).Scan(&released); err != nil {
+			zlog.Errorf(nil, "migrate: release lock %s error: %v", m.opts.LockName, err)
+		}
+		_ = conn.Close()
+	}, nil
+}
+
+// tableLock 其他数据库在 TableName_lock 表中插入一行作为锁，主键冲突说明锁被占用
+// 持有锁的实例异常退出时需要手动删除该行
+func (m *Migrator) tableLock(ctx context.Context, db *gorm.DB) (func(), error) {
+	table := clause.Table{Name: m.opts.TableName + "_lock"}
+	err := db.Exec("CREATE TABLE IF NOT EXISTS ? (name VARCHAR(255) NOT NULL PRIMARY KEY, locked_at TIMESTAMP NOT NULL)", table).Error
+	if err != nil {
+		return nil, fmt.Errorf("migrate: create table %s: %w", table.Name, err)
+	}
+	deadline := time.Now().Add(m.opts.LockTimeout)
+	for {
+		err = db.Exec("INSERT INTO ? (name, locked_at) VALUES (?, ?)", table, m.opts.LockName, time.Now()).Error
+		if err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("%w: %s: %v", ErrLocked, m.opts.LockName, err)
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(lockRetryInterval):
+		}
+	}
+	return func() {
+		err := m.db.Exec("DELETE FROM ? WHERE name = ?", table, m.opts.LockName).Error
+		if err != nil {
+			zlog.Errorf(nil, "migrate: release lock %s error: %v", m.opts.LockName, err)
+		}
+	}, nil
+}
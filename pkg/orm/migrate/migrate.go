@@ -0,0 +1,355 @@
+// Package migrate -----------------------------
+// @file      : migrate.go
+// @author    : xiangtao
+// @contact   : xiangtao1994@gmail.com
+// @time      : 2025/9/14 14:10
+// Description: 数据库迁移，按注册顺序执行并记录到 schema_migrations 表
+// -------------------------------------------
+package migrate
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/xiangtao94/golib/pkg/zlog"
+)
+
+var (
+	// ErrChecksumMismatch 已执行的迁移内容被修改，需要新增迁移而不是修改已执行的迁移
+	ErrChecksumMismatch = errors.New("migrate: checksum of applied migration changed")
+	// ErrIrreversible 迁移没有提供 down，无法回滚
+	ErrIrreversible = errors.New("migrate: migration is irreversible")
+	// ErrLocked 等待 LockTimeout 后仍未获取到迁移锁，其他实例正在执行迁移
+	ErrLocked = errors.New("migrate: lock is held by another instance")
+)
+
+// Migration 一次迁移，Up/Down 在事务中执行
+type Migration struct {
+	ID       string
+	Up       func(tx *gorm.DB) error
+	Down     func(tx *gorm.DB) error // 为空时不能回滚
+	checksum string
+}
+
+type MigrationOption func(*Migration)
+
+// WithChecksum 使用 content 计算校验和，函数迁移默认只以 ID 计算，
+// 传入迁移涉及的 SQL 或版本号后，修改已执行的迁移会在启动时报错
+func WithChecksum(content string) MigrationOption {
+	return func(m *Migration) {
+		m.checksum = checksum(content)
+	}
+}
+
+// NewMigration 创建迁移，用于 Options.Migrations
+func NewMigration(id string, up, down func(tx *gorm.DB) error, opts ...MigrationOption) *Migration {
+	m := &Migration{ID: id, Up: up, Down: down, checksum: checksum(id)}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+// NewSQLMigration 创建 SQL 迁移，校验和由 up、down 计算，down 为空时不能回滚
+func NewSQLMigration(id, up, down string) *Migration {
+	m := NewMigration(id, func(tx *gorm.DB) error {
+		return tx.Exec(up).Error
+	}, nil, WithChecksum(up+"\n--\n"+down))
+	if down != "" {
+		m.Down = func(tx *gorm.DB) error {
+			return tx.Exec(down).Error
+		}
+	}
+	return m
+}
+
+var (
+	registryMu sync.Mutex
+	registry   []*Migration
+)
+
+// Register 注册迁移，按注册顺序执行，通常在各模块的 init 中调用，ID 重复时 panic
+//
+//	migrate.Register("20250914_create_users", func(tx *gorm.DB) error {
+//		return tx.AutoMigrate(&User{})
+//	}, func(tx *gorm.DB) error {
+//		return tx.Migrator().DropTable(&User{})
+//	})
+func Register(id string, up, down func(tx *gorm.DB) error, opts ...MigrationOption) {
+	add(NewMigration(id, up, down, opts...))
+}
+
+// RegisterSQL 注册 SQL 迁移
+func RegisterSQL(id, up, down string) {
+	add(NewSQLMigration(id, up, down))
+}
+
+func add(m *Migration) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	for _, r := range registry {
+		if r.ID == m.ID {
+			panic(fmt.Sprintf("migrate: duplicate migration id %s", m.ID))
+		}
+	}
+	registry = append(registry, m)
+}
+
+// Registered 返回已注册的迁移
+func Registered() []*Migration {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	return append([]*Migration(nil), registry...)
+}
+
+// Options 迁移配置
+type Options struct {
+	// TableName 记录已执行迁移的表，默认 schema_migrations
+	TableName string
+	// LockName 迁移锁名称，MySQL 使用 GET_LOCK，其他数据库使用 TableName_lock 表中的一行，默认 golib_migrate
+	LockName string
+	// LockTimeout 等待迁移锁的时间，默认30s
+	LockTimeout time.Duration
+	// Migrations 要执行的迁移，为空时使用 Register 注册的迁移
+	Migrations []*Migration
+}
+
+func (opts *Options) checkConf() {
+	if opts.TableName == "" {
+		opts.TableName = "schema_migrations"
+	}
+	if opts.LockName == "" {
+		opts.LockName = "golib_migrate"
+	}
+	if opts.LockTimeout <= 0 {
+		opts.LockTimeout = 30 * time.Second
+	}
+	if opts.Migrations == nil {
+		opts.Migrations = Registered()
+	}
+}
+
+// MigrationStatus 迁移状态
+type MigrationStatus struct {
+	ID              string
+	Applied         bool
+	AppliedAt       time.Time
+	ChecksumChanged bool // 已执行后内容被修改
+	Unknown         bool // 已执行但当前代码中没有注册，如回滚到旧版本代码
+}
+
+// schemaMigration schema_migrations 表的一行
+type schemaMigration struct {
+	ID        string    `gorm:"column:id"`
+	Checksum  string    `gorm:"column:checksum"`
+	AppliedAt time.Time `gorm:"column:applied_at"`
+}
+
+// Migrator 执行迁移，多个实例同时执行时通过迁移锁串行
+type Migrator struct {
+	db   *gorm.DB
+	opts Options
+}
+
+// New 创建 Migrator，db 为 orm.InitMysqlClient 返回的客户端
+func New(db *gorm.DB, opts Options) *Migrator {
+	opts.checkConf()
+	return &Migrator{db: db, opts: opts}
+}
+
+// RunMigrations 执行所有待执行的迁移，适合在服务启动时调用
+func RunMigrations(db *gorm.DB, opts Options) error {
+	_, err := New(db, opts).Up(context.Background())
+	return err
+}
+
+// WithMigrations 启动时执行待执行的数据库迁移，失败时 panic，多实例同时启动时只有一个实例执行
+// 返回值可直接作为 golib.BootstrapOption 使用：golib.Bootstraps(engine, migrate.WithMigrations(db))
+func WithMigrations(db *gorm.DB, opts ...Options) func(engine *gin.Engine) {
+	return func(engine *gin.Engine) {
+		var o Options
+		if len(opts) > 0 {
+			o = opts[0]
+		}
+		if err := RunMigrations(db, o); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// Up 按注册顺序执行待执行的迁移，返回本次执行的迁移ID
+// 已执行迁移的校验和变化时返回 ErrChecksumMismatch，不执行任何迁移
+func (m *Migrator) Up(ctx context.Context) (applied []string, err error) {
+	err = m.withLock(ctx, func(db *gorm.DB) error {
+		records, err := m.applied(db)
+		if err != nil {
+			return err
+		}
+		if err = m.verify(records); err != nil {
+			return err
+		}
+		for _, mig := range m.opts.Migrations {
+			if _, ok := records[mig.ID]; ok {
+				continue
+			}
+			start := time.Now()
+			err = db.Transaction(func(tx *gorm.DB) error {
+				if err := mig.Up(tx); err != nil {
+					return err
+				}
+				record := schemaMigration{ID: mig.ID, Checksum: mig.checksum, AppliedAt: time.Now()}
+				return tx.Table(m.opts.TableName).Create(&record).Error
+			})
+			if err != nil {
+				zlog.Errorf(nil, "migration %s failed: %v", mig.ID, err)
+				return fmt.Errorf("migrate: apply %s: %w", mig.ID, err)
+			}
+			zlog.Infof(nil, "migration %s applied, cost: %v", mig.ID, time.Since(start))
+			applied = append(applied, mig.ID)
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Down 按注册的逆序回滚最近执行的 n 个迁移，返回回滚的迁移ID
+func (m *Migrator) Down(ctx context.Context, n int) (reverted []string, err error) {
+	err = m.withLock(ctx, func(db *gorm.DB) error {
+		records, err := m.applied(db)
+		if err != nil {
+			return err
+		}
+		if err = m.verify(records); err != nil {
+			return err
+		}
+		for i := len(m.opts.Migrations) - 1; i >= 0 && len(reverted) < n; i-- {
+			mig := m.opts.Migrations[i]
+			if _, ok := records[mig.ID]; !ok {
+				continue
+			}
+			if mig.Down == nil {
+				return fmt.Errorf("%w: %s", ErrIrreversible, mig.ID)
+			}
+			start := time.Now()
+			err = db.Transaction(func(tx *gorm.DB) error {
+				if err := mig.Down(tx); err != nil {
+					return err
+				}
+				return tx.Table(m.opts.TableName).Where("id = ?", mig.ID).Delete(&schemaMigration{}).Error
+			})
+			if err != nil {
+				zlog.Errorf(nil, "migration %s revert failed: %v", mig.ID, err)
+				return fmt.Errorf("migrate: revert %s: %w", mig.ID, err)
+			}
+			zlog.Infof(nil, "migration %s reverted, cost: %v", mig.ID, time.Since(start))
+			reverted = append(reverted, mig.ID)
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// Status 返回所有迁移的状态，注册的迁移按注册顺序在前，未注册但已执行的在后
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	db := m.db.WithContext(ctx)
+	if err := m.createTable(db); err != nil {
+		return nil, err
+	}
+	records, err := m.applied(db)
+	if err != nil {
+		return nil, err
+	}
+	statuses := make([]MigrationStatus, 0, len(m.opts.Migrations))
+	known := make(map[string]bool, len(m.opts.Migrations))
+	for _, mig := range m.opts.Migrations {
+		known[mig.ID] = true
+		s := MigrationStatus{ID: mig.ID}
+		if r, ok := records[mig.ID]; ok {
+			s.Applied = true
+			s.AppliedAt = r.AppliedAt
+			s.ChecksumChanged = r.Checksum != mig.checksum
+		}
+		statuses = append(statuses, s)
+	}
+	for _, r := range sortedRecords(records) {
+		if !known[r.ID] {
+			statuses = append(statuses, MigrationStatus{ID: r.ID, Applied: true, AppliedAt: r.AppliedAt, Unknown: true})
+		}
+	}
+	return statuses, nil
+}
+
+// withLock 建表后获取迁移锁再执行 fn
+func (m *Migrator) withLock(ctx context.Context, fn func(db *gorm.DB) error) error {
+	db := m.db.WithContext(ctx)
+	if err := m.createTable(db); err != nil {
+		return err
+	}
+	unlock, err := m.lock(ctx, db)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	return fn(db)
+}
+
+func (m *Migrator) createTable(db *gorm.DB) error {
+	err := db.Exec("CREATE TABLE IF NOT EXISTS ? (id VARCHAR(255) NOT NULL PRIMARY KEY, checksum VARCHAR(64) NOT NULL, applied_at TIMESTAMP NOT NULL)",
+		clause.Table{Name: m.opts.TableName}).Error
+	if err != nil {
+		return fmt.Errorf("migrate: create table %s: %w", m.opts.TableName, err)
+	}
+	return nil
+}
+
+func (m *Migrator) applied(db *gorm.DB) (map[string]schemaMigration, error) {
+	var rows []schemaMigration
+	if err := db.Table(m.opts.TableName).Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("migrate: load applied migrations: %w", err)
+	}
+	records := make(map[string]schemaMigration, len(rows))
+	for _, r := range rows {
+		records[r.ID] = r
+	}
+	return records, nil
+}
+
+// verify 检查已执行迁移的校验和，未注册的迁移（如新版本实例已执行）不影响
+func (m *Migrator) verify(records map[string]schemaMigration) error {
+	for _, mig := range m.opts.Migrations {
+		if r, ok := records[mig.ID]; ok && r.Checksum != mig.checksum {
+			return fmt.Errorf("%w: %s", ErrChecksumMismatch, mig.ID)
+		}
+	}
+	return nil
+}
+
+// sortedRecords 按执行时间排序
+func sortedRecords(records map[string]schemaMigration) []schemaMigration {
+	list := make([]schemaMigration, 0, len(records))
+	for _, r := range records {
+		list = append(list, r)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if !list[i].AppliedAt.Equal(list[j].AppliedAt) {
+			return list[i].AppliedAt.Before(list[j].AppliedAt)
+		}
+		return list[i].ID < list[j].ID
+	})
+	return list
+}
+
+func checksum(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,283 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// fakeConn 内存中模拟 schema_migrations 表和业务表，事务回滚时恢复到 Begin 时的状态
+type fakeConn struct {
+	mu       sync.Mutex
+	records  []fakeRecord
+	tables   map[string]bool
+	snapshot *fakeConn
+	lockFree bool // GET_LOCK 是否成功
+	released int
+}
+
+type fakeRecord struct {
+	id, checksum string
+	appliedAt    time.Time
+}
+
+func newFakeConn() *fakeConn {
+	return &fakeConn{tables: map[string]bool{}, lockFree: true}
+}
+
+func (c *fakeConn) Prepare(string) (driver.Stmt, error) { return nil, driver.ErrSkip }
+func (c *fakeConn) Close() error                        { return nil }
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	tables := make(map[string]bool, len(c.tables))
+	for k, v := range c.tables {
+		tables[k] = v
+	}
+	c.snapshot = &fakeConn{records: append([]fakeRecord(nil), c.records...), tables: tables}
+	return c, nil
+}
+
+func (c *fakeConn) Commit() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.snapshot = nil
+	return nil
+}
+
+func (c *fakeConn) Rollback() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.records, c.tables, c.snapshot = c.snapshot.records, c.snapshot.tables, nil
+	return nil
+}
+
+func (c *fakeConn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	switch {
+	case strings.HasPrefix(query, "CREATE TABLE IF NOT EXISTS"):
+	case strings.HasPrefix(query, "INSERT INTO `schema_migrations`"):
+		c.records = append(c.records, fakeRecord{
+			id:        args[0].Value.(string),
+			checksum:  args[1].Value.(string),
+			appliedAt: args[2].Value.(time.Time),
+		})
+	case strings.HasPrefix(query, "DELETE FROM `schema_migrations`"):
+		for i, r := range c.records {
+			if r.id == args[0].Value {
+				c.records = append(c.records[:i], c.records[i+1:]...)
+				break
+			}
+		}
+	case strings.HasPrefix(query, "CREATE TABLE "):
+		c.tables[strings.TrimPrefix(query, "CREATE TABLE ")] = true
+	case strings.HasPrefix(query, "DROP TABLE "):
+		delete(c.tables, strings.TrimPrefix(query, "DROP TABLE "))
+	default:
+		return nil, errors.New("syntax error: " + query)
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (c *fakeConn) QueryContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Rows, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	switch {
+	case strings.HasPrefix(query, "SELECT GET_LOCK"):
+		if c.lockFree {
+			return &fakeRows{columns: []string{"lock"}, rows: [][]driver.Value{{int64(1)}}}, nil
+		}
+		return &fakeRows{columns: []string{"lock"}, rows: [][]driver.Value{{int64(0)}}}, nil
+	case strings.HasPrefix(query, "SELECT RELEASE_LOCK"):
+		c.released++
+		return &fakeRows{columns: []string{"lock"}, rows: [][]driver.Value{{int64(1)}}}, nil
+	case strings.HasPrefix(query, "SELECT * FROM `schema_migrations`"):
+		rows := &fakeRows{columns: []string{"id", "checksum", "applied_at"}}
+		for _, r := range c.records {
+			rows.rows = append(rows.rows, []driver.Value{r.id, r.checksum, r.appliedAt})
+		}
+		return rows, nil
+	}
+	return nil, errors.New("unexpected query: " + query)
+}
+
+type fakeRows struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string { return r.columns }
+func (r *fakeRows) Close() error      { return nil }
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
+
+var (
+	registerOnce sync.Once
+	fakeConns    sync.Map
+)
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(name string) (driver.Conn, error) {
+	conn, _ := fakeConns.Load(name)
+	return conn.(*fakeConn), nil
+}
+
+func newFakeDB(t *testing.T, conn *fakeConn) *gorm.DB {
+	registerOnce.Do(func() {
+		sql.Register("migrate-fake", &fakeDriver{})
+	})
+	fakeConns.Store(t.Name(), conn)
+	sqlDB, err := sql.Open("migrate-fake", t.Name())
+	require.NoError(t, err)
+	db, err := gorm.Open(mysql.New(mysql.Config{Conn: sqlDB, SkipInitializeWithVersion: true}),
+		&gorm.Config{Logger: logger.Discard})
+	require.NoError(t, err)
+	return db
+}
+
+func testMigrations() []*Migration {
+	return []*Migration{
+		NewSQLMigration("001_users", "CREATE TABLE users", "DROP TABLE users"),
+		NewMigration("002_orders", func(tx *gorm.DB) error {
+			return tx.Exec("CREATE TABLE orders").Error
+		}, func(tx *gorm.DB) error {
+			return tx.Exec("DROP TABLE orders").Error
+		}),
+	}
+}
+
+func TestMigrator_UpDown(t *testing.T) {
+	conn := newFakeConn()
+	m := New(newFakeDB(t, conn), Options{Migrations: testMigrations()})
+	ctx := context.Background()
+
+	applied, err := m.Up(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"001_users", "002_orders"}, applied)
+	assert.Equal(t, map[string]bool{"users": true, "orders": true}, conn.tables)
+	assert.Equal(t, 1, conn.released)
+
+	// 再次执行没有待执行的迁移
+	applied, err = m.Up(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, applied)
+
+	statuses, err := m.Status(ctx)
+	require.NoError(t, err)
+	require.Len(t, statuses, 2)
+	assert.True(t, statuses[0].Applied)
+	assert.False(t, statuses[0].AppliedAt.IsZero())
+	assert.False(t, statuses[1].ChecksumChanged)
+
+	reverted, err := m.Down(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"002_orders"}, reverted)
+	assert.Equal(t, map[string]bool{"users": true}, conn.tables)
+
+	statuses, err = m.Status(ctx)
+	require.NoError(t, err)
+	assert.True(t, statuses[0].Applied)
+	assert.False(t, statuses[1].Applied)
+
+	reverted, err = m.Down(ctx, 5)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"001_users"}, reverted)
+	assert.Empty(t, conn.tables)
+	assert.Empty(t, conn.records)
+}
+
+func TestMigrator_FailedMigrationRollback(t *testing.T) {
+	conn := newFakeConn()
+	migrations := append(testMigrations(), NewSQLMigration("003_broken", "ALTER TABLE", ""))
+	m := New(newFakeDB(t, conn), Options{Migrations: migrations})
+
+	applied, err := m.Up(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "003_broken")
+	assert.Equal(t, []string{"001_users", "002_orders"}, applied)
+	require.Len(t, conn.records, 2)
+	assert.Equal(t, 1, conn.released)
+}
+
+func TestMigrator_ChecksumMismatch(t *testing.T) {
+	conn := newFakeConn()
+	db := newFakeDB(t, conn)
+	_, err := New(db, Options{Migrations: testMigrations()}).Up(context.Background())
+	require.NoError(t, err)
+
+	// 修改已执行的迁移
+	changed := testMigrations()
+	changed[0] = NewSQLMigration("001_users", "CREATE TABLE users_v2", "DROP TABLE users_v2")
+	changed = append(changed, NewSQLMigration("003_items", "CREATE TABLE items", "DROP TABLE items"))
+	m := New(db, Options{Migrations: changed})
+
+	_, err = m.Up(context.Background())
+	assert.ErrorIs(t, err, ErrChecksumMismatch)
+	assert.False(t, conn.tables["items"])
+
+	statuses, err := m.Status(context.Background())
+	require.NoError(t, err)
+	assert.True(t, statuses[0].ChecksumChanged)
+}
+
+func TestMigrator_UnknownAndIrreversible(t *testing.T) {
+	conn := newFakeConn()
+	db := newFakeDB(t, conn)
+	migrations := append(testMigrations(), NewSQLMigration("003_items", "CREATE TABLE items", ""))
+	_, err := New(db, Options{Migrations: migrations}).Up(context.Background())
+	require.NoError(t, err)
+
+	_, err = New(db, Options{Migrations: migrations}).Down(context.Background(), 1)
+	assert.ErrorIs(t, err, ErrIrreversible)
+
+	// 旧版本代码看到新版本执行的迁移时正常启动
+	old := New(db, Options{Migrations: testMigrations()})
+	applied, err := old.Up(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, applied)
+	statuses, err := old.Status(context.Background())
+	require.NoError(t, err)
+	require.Len(t, statuses, 3)
+	assert.Equal(t, "003_items", statuses[2].ID)
+	assert.True(t, statuses[2].Unknown)
+}
+
+func TestMigrator_Locked(t *testing.T) {
+	conn := newFakeConn()
+	conn.lockFree = false
+	_, err := New(newFakeDB(t, conn), Options{Migrations: testMigrations()}).Up(context.Background())
+	assert.ErrorIs(t, err, ErrLocked)
+	assert.Empty(t, conn.tables)
+}
+
+func TestWithMigrations(t *testing.T) {
+	conn := newFakeConn()
+	db := newFakeDB(t, conn)
+	WithMigrations(db, Options{Migrations: testMigrations()})(gin.New())
+	assert.Equal(t, map[string]bool{"users": true, "orders": true}, conn.tables)
+
+	broken := append(testMigrations(), NewSQLMigration("003_broken", "ALTER TABLE", ""))
+	assert.Panics(t, func() { WithMigrations(db, Options{Migrations: broken})(gin.New()) })
+}
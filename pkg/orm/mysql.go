@@ -37,22 +37,27 @@ type Option struct {
 
 var MysqlPromCollector prometheus.Collector
 
+// Normalize 返回实际使用的页码和每页大小：页码默认 1，每页大小默认 10、最大 100
+func (page *NormalPage) Normalize() (no, size int) {
+	no = 1
+	if page.No > 0 {
+		no = page.No
+	}
+
+	size = page.Size
+	switch {
+	case size > 100:
+		size = 100
+	case size <= 0:
+		size = 10
+	}
+	return no, size
+}
+
 // 分页示例
 func NormalPaginate(page *NormalPage) func(db *gorm.DB) *gorm.DB {
 	return func(db *gorm.DB) *gorm.DB {
-		pageNo := 1
-		if page.No > 0 {
-			pageNo = page.No
-		}
-
-		pageSize := page.Size
-		switch {
-		case pageSize > 100:
-			pageSize = 100
-		case pageSize <= 0:
-			pageSize = 10
-		}
-
+		pageNo, pageSize := page.Normalize()
 		offset := (pageNo - 1) * pageSize
 		orderBy := "id asc"
 		if len(page.OrderBy) > 0 {
@@ -75,6 +80,8 @@ type MysqlConf struct {
 	ConnTimeOut     time.Duration `yaml:"connTimeOut"`
 	WriteTimeOut    time.Duration `yaml:"writeTimeOut"`
 	ReadTimeOut     time.Duration `yaml:"readTimeOut"`
+	// QueryTimeout 单条 SQL 的执行超时，超时返回 ErrQueryTimeout，0表示不限制
+	QueryTimeout time.Duration `yaml:"queryTimeout"`
 }
 
 func (conf *MysqlConf) checkConf() {
@@ -128,6 +135,13 @@ func InitMysqlClient(conf MysqlConf) (client *gorm.DB, err error) {
 	if err != nil {
 		return client, err
 	}
+	// VersionedModel 的乐观锁
+	if err = client.Use(OptimisticLock{}); err != nil {
+		return client, err
+	}
+	if err = client.Use(QueryTimeout{Timeout: conf.QueryTimeout}); err != nil {
+		return client, err
+	}
 
 	sqlDB, err := client.DB()
 	if err != nil {
@@ -203,13 +217,26 @@ func (l *ormLogger) Trace(ctx context.Context, begin time.Time, fc func() (strin
 
 func (l *ormLogger) AppendCustomField(ctx context.Context) []zlog.Field {
 	var requestID string
-	if c, ok := ctx.(*gin.Context); ok && c != nil {
+	var ctxFields []zlog.Field
+	if c := ginContext(ctx); c != nil {
 		requestID = zlog.GetRequestID(c)
+		ctxFields = zlog.GetContextFields(c)
 	}
 	fields := []zlog.Field{
 		zlog.String("requestId", requestID),
 	}
-	return fields
+	return append(fields, ctxFields...)
+}
+
+// HealthChecker 通过 PingContext 检查 mysql 连接，可用于就绪探针（golib.RegisterHealthChecker）
+func HealthChecker(client *gorm.DB) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		sqlDB, err := client.DB()
+		if err != nil {
+			return err
+		}
+		return sqlDB.PingContext(ctx)
+	}
 }
 
 // TransactionManager 事务管理器
@@ -222,7 +249,7 @@ type TransactionManager struct {
 func NewTransactionManager(ctx *gin.Context, client *gorm.DB) *TransactionManager {
 	return &TransactionManager{
 		ctx: ctx,
-		db:  client.WithContext(ctx),
+		db:  client.WithContext(RequestContext(ctx)),
 	}
 }
 
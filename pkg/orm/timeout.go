@@ -0,0 +1,140 @@
+// Package orm -----------------------------
+// @file      : timeout.go
+// @author    : xiangtao
+// @contact   : xiangtao1994@gmail.com
+// @time      : 2025/9/14 17:20
+// Description: 单条 SQL 超时控制，请求取消时中断正在执行的 SQL
+// -------------------------------------------
+package orm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"github.com/xiangtao94/golib/pkg/zlog"
+)
+
+// ErrQueryTimeout 单条 SQL 执行超过 QueryTimeout，errors.Is 同时匹配 context.DeadlineExceeded
+var ErrQueryTimeout = errors.New("orm: query timeout")
+
+const queryTimeoutKey = "orm:query_timeout"
+
+// RequestContext 返回传给 gorm 的 context：派生自 ctx.Request.Context()，客户端断开时取消，驱动随之中断正在执行的 SQL
+// 同时可以通过 Value 取到 gin.Context 中的 requestId 等值，SQL 日志照常输出
+func RequestContext(ctx *gin.Context) context.Context {
+	if ctx == nil {
+		return context.Background()
+	}
+	if ctx.Request == nil {
+		return ctx
+	}
+	return &requestContext{Context: ctx.Request.Context(), gin: ctx}
+}
+
+type requestContext struct {
+	context.Context
+	gin *gin.Context
+}
+
+func (c *requestContext) Value(key any) any {
+	if key == gin.ContextKey {
+		return c.gin
+	}
+	if v := c.Context.Value(key); v != nil {
+		return v
+	}
+	return c.gin.Value(key)
+}
+
+// ginContext 取出 context 中的 gin.Context，*gin.Context 和 RequestContext 及其派生的 context 都可以取到
+func ginContext(ctx context.Context) *gin.Context {
+	if ctx == nil {
+		return nil
+	}
+	c, _ := ctx.Value(gin.ContextKey).(*gin.Context)
+	return c
+}
+
+// QueryTimeout 单条 SQL 超时插件，db.Use(orm.QueryTimeout{Timeout: 3 * time.Second}) 注册，InitMysqlClient 按 MysqlConf.QueryTimeout 注册
+// 每条 SQL 的 context 在调用方 context 的基础上加上超时，超时返回 ErrQueryTimeout 并打印 WARN 日志
+// 事务中按单条 SQL 计算超时，不限制整个事务的时长；Rows/Row/Scan 的超时包含读取结果的时间
+type QueryTimeout struct {
+	Timeout time.Duration
+}
+
+func (QueryTimeout) Name() string {
+	return "orm:query_timeout"
+}
+
+type queryTimeoutState struct {
+	parent context.Context
+	cancel context.CancelFunc
+}
+
+func (p QueryTimeout) Initialize(db *gorm.DB) error {
+	if p.Timeout <= 0 {
+		return nil
+	}
+	cb := db.Callback()
+	registers := []func() error{
+		func() error { return cb.Create().Before("*").Register("orm:timeout_before_create", p.before) },
+		func() error { return cb.Create().After("*").Register("orm:timeout_after_create", p.after) },
+		func() error { return cb.Query().Before("*").Register("orm:timeout_before_query", p.before) },
+		func() error { return cb.Query().After("*").Register("orm:timeout_after_query", p.after) },
+		func() error { return cb.Update().Before("*").Register("orm:timeout_before_update", p.before) },
+		func() error { return cb.Update().After("*").Register("orm:timeout_after_update", p.after) },
+		func() error { return cb.Delete().Before("*").Register("orm:timeout_before_delete", p.before) },
+		func() error { return cb.Delete().After("*").Register("orm:timeout_after_delete", p.after) },
+		func() error { return cb.Raw().Before("*").Register("orm:timeout_before_raw", p.before) },
+		func() error { return cb.Raw().After("*").Register("orm:timeout_after_raw", p.after) },
+		func() error { return cb.Row().Before("*").Register("orm:timeout_before_row", p.before) },
+		// 返回的 rows 还未读取，不能取消 context，超时后由定时器释放
+		func() error { return cb.Row().After("*").Register("orm:timeout_after_row", p.afterRow) },
+	}
+	for _, register := range registers {
+		if err := register(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p QueryTimeout) before(db *gorm.DB) {
+	stmt := db.Statement
+	if stmt.Context == nil {
+		stmt.Context = context.Background()
+	}
+	ctx, cancel := context.WithTimeout(stmt.Context, p.Timeout)
+	db.InstanceSet(queryTimeoutKey, &queryTimeoutState{parent: stmt.Context, cancel: cancel})
+	stmt.Context = ctx
+}
+
+func (p QueryTimeout) after(db *gorm.DB) {
+	if state := p.finish(db); state != nil {
+		state.cancel()
+	}
+}
+
+func (p QueryTimeout) afterRow(db *gorm.DB) {
+	p.finish(db)
+}
+
+// finish 转换超时错误，并恢复调用方的 context，复用同一个 *gorm.DB 执行下一条 SQL 时重新计时
+func (p QueryTimeout) finish(db *gorm.DB) *queryTimeoutState {
+	v, ok := db.InstanceGet(queryTimeoutKey)
+	if !ok {
+		return nil
+	}
+	state := v.(*queryTimeoutState)
+	db.Statement.Context = state.parent
+	if errors.Is(db.Error, context.DeadlineExceeded) && !errors.Is(db.Error, ErrQueryTimeout) {
+		zlog.Warnf(ginContext(state.parent), "sql exceeded query timeout %v, sql: %s", p.Timeout, db.Statement.SQL.String())
+		db.Error = fmt.Errorf("%w: %w", ErrQueryTimeout, db.Error)
+	}
+	return state
+}
@@ -0,0 +1,101 @@
+package orm
+
+import (
+	"context"
+	"database/sql/driver"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/xiangtao94/golib/pkg/zlog"
+)
+
+func newTimeoutDB(t *testing.T, delay, timeout time.Duration) (*gorm.DB, *fakeConn) {
+	conn := &fakeConn{
+		delay: delay,
+		exec:  func(string, []driver.Value) int64 { return 1 },
+		query: func(string, []driver.Value) *fakeRows { return countResult(3) },
+	}
+	db := newFakeDB(t, conn)
+	require.NoError(t, db.Use(QueryTimeout{Timeout: timeout}))
+	return db, conn
+}
+
+func TestQueryTimeout(t *testing.T) {
+	db, conn := newTimeoutDB(t, time.Second, 30*time.Millisecond)
+
+	start := time.Now()
+	var count int64
+	err := db.Model(&account{}).Count(&count).Error
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+	assert.ErrorIs(t, err, ErrQueryTimeout)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	err = db.Exec("UPDATE accounts SET name = ?", "a").Error
+	assert.ErrorIs(t, err, ErrQueryTimeout)
+	var n int64
+	err = db.Raw("SELECT count(*) FROM accounts").Scan(&n).Error
+	assert.ErrorIs(t, err, ErrQueryTimeout)
+
+	// 未超时时正常返回，执行完后恢复调用方的 context，复用同一个 *gorm.DB 重新计时
+	conn.delay = 10 * time.Millisecond
+	tx := db.Session(&gorm.Session{}).Model(&account{})
+	for range 3 {
+		require.NoError(t, tx.Count(&count).Error)
+		assert.EqualValues(t, 3, count)
+	}
+}
+
+func TestQueryTimeout_ParentCanceled(t *testing.T) {
+	db, _ := newTimeoutDB(t, time.Second, time.Minute)
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+
+	start := time.Now()
+	var count int64
+	err := db.WithContext(ctx).Model(&account{}).Count(&count).Error
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.NotErrorIs(t, err, ErrQueryTimeout)
+}
+
+func TestQueryTimeout_PerStatementInTransaction(t *testing.T) {
+	// 每条 SQL 20ms，整个事务超过单条超时也能成功
+	db, _ := newTimeoutDB(t, 20*time.Millisecond, 50*time.Millisecond)
+	err := db.Transaction(func(tx *gorm.DB) error {
+		for range 4 {
+			if err := tx.Exec("UPDATE accounts SET name = ?", "a").Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+}
+
+func TestRequestContext(t *testing.T) {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	reqCtx, cancel := context.WithCancel(context.Background())
+	c.Request = httptest.NewRequest("GET", "/", nil).WithContext(reqCtx)
+	c.Set(zlog.ContextKeyRequestID, "req-1")
+
+	ctx := RequestContext(c)
+	assert.Same(t, c, ginContext(ctx))
+	assert.Equal(t, "req-1", ctx.Value(zlog.ContextKeyRequestID))
+	// 派生的 context 也能取到 gin.Context，SQL 日志带上 requestId
+	timeoutCtx, stop := context.WithTimeout(ctx, time.Minute)
+	defer stop()
+	assert.Same(t, c, ginContext(timeoutCtx))
+
+	// 请求取消时随之取消
+	cancel()
+	assert.ErrorIs(t, timeoutCtx.Err(), context.Canceled)
+
+	assert.NotNil(t, RequestContext(nil))
+	assert.Nil(t, ginContext(context.Background()))
+}
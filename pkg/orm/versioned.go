@@ -0,0 +1,191 @@
+// Package orm -----------------------------
+// @file      : versioned.go
+// @author    : xiangtao
+// @contact   : xiangtao1994@gmail.com
+// @time      : 2025/9/13 21:40
+// Description: 乐观锁和忽略软删除记录的唯一性校验
+// -------------------------------------------
+package orm
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// ErrStaleObject 乐观锁更新行数为0，记录已被其他请求修改或已删除，可重新查询后重试或提示冲突
+var ErrStaleObject = errors.New("orm: stale object, record has been modified or deleted")
+
+// VersionedModel 带乐观锁版本号的 CrudModel，需注册 OptimisticLock 插件（InitMysqlClient 已注册）
+// 通过 Save 或 Model(&obj).Updates 更新查询出的对象时，自动追加 version = 当前版本号 条件并将版本号加一
+// 更新行数为0时返回 ErrStaleObject，对象的版本号保持不变；版本号为0（未查询出的对象、批量更新）时不加锁
+type VersionedModel struct {
+	CrudModel
+	Version int `json:"version" gorm:"not null;default:1;comment:乐观锁版本号"`
+}
+
+func (VersionedModel) optimisticLock() {}
+
+type versioned interface {
+	optimisticLock()
+}
+
+var versionedType = reflect.TypeOf((*versioned)(nil)).Elem()
+
+const (
+	versionFieldName = "Version"
+	versionLockKey   = "orm:version_lock"
+)
+
+// OptimisticLock 乐观锁插件，db.Use(orm.OptimisticLock{}) 注册
+type OptimisticLock struct{}
+
+func (OptimisticLock) Name() string {
+	return "orm:optimistic_lock"
+}
+
+func (OptimisticLock) Initialize(db *gorm.DB) error {
+	if err := db.Callback().Update().After("gorm:before_update").Before("gorm:update").
+		Register("orm:before_versioned_update", beforeVersionedUpdate); err != nil {
+		return err
+	}
+	return db.Callback().Update().After("gorm:update").Before("gorm:after_update").
+		Register("orm:after_versioned_update", afterVersionedUpdate)
+}
+
+func beforeVersionedUpdate(db *gorm.DB) {
+	stmt := db.Statement
+	if db.Error != nil || stmt.Schema == nil || stmt.ReflectValue.Kind() != reflect.Struct ||
+		!reflect.PointerTo(stmt.Schema.ModelType).Implements(versionedType) {
+		return
+	}
+	field := stmt.Schema.LookUpField(versionFieldName)
+	if field == nil {
+		return
+	}
+	v, isZero := field.ValueOf(stmt.Context, stmt.ReflectValue)
+	version, ok := v.(int)
+	if isZero || !ok {
+		return
+	}
+	stmt.AddClause(clause.Where{Exprs: []clause.Expression{
+		clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: field.DBName}, Value: version},
+	}})
+	// Select 指定了更新列时补上版本号
+	if len(stmt.Selects) > 0 && !selected(stmt.Selects, field) {
+		stmt.Selects = append(stmt.Selects, field.DBName)
+	}
+	stmt.SetColumn(field.Name, version+1, true)
+	db.InstanceSet(versionLockKey, version)
+}
+
+func afterVersionedUpdate(db *gorm.DB) {
+	v, ok := db.InstanceGet(versionLockKey)
+	if !ok {
+		return
+	}
+	if db.Error == nil && !db.DryRun && db.RowsAffected == 0 {
+		db.AddError(ErrStaleObject)
+	}
+	if db.Error != nil {
+		// 更新失败时恢复版本号，调用方可重新查询后重试
+		field := db.Statement.Schema.LookUpField(versionFieldName)
+		_ = field.Set(db.Statement.Context, db.Statement.ReflectValue, v)
+	}
+}
+
+func selected(selects []string, field *schema.Field) bool {
+	for _, s := range selects {
+		if s == "*" || s == field.Name || s == field.DBName {
+			return true
+		}
+	}
+	return false
+}
+
+// UniqueNotDeleted 检查 model 在 cols 上的取值在未删除的记录中是否唯一，用于写入前的存在性校验
+// cols 为字段名或列名，model 主键不为零时排除自身，可用于更新前的校验
+// 并发写入时仍需唯一索引兜底，见 CreateUniqueNotDeletedIndex
+func UniqueNotDeleted(db *gorm.DB, model any, cols ...string) (bool, error) {
+	if len(cols) == 0 {
+		return false, errors.New("orm: UniqueNotDeleted requires at least one column")
+	}
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(model); err != nil {
+		return false, err
+	}
+	ctx := db.Statement.Context
+	value := reflect.Indirect(reflect.ValueOf(model))
+	// 使用新的零值对象，只保留软删除条件，不带入 model 自身的主键条件
+	tx := db.Model(reflect.New(stmt.Schema.ModelType).Interface())
+	for _, col := range cols {
+		field := stmt.Schema.LookUpField(col)
+		if field == nil {
+			return false, fmt.Errorf("orm: unknown column %q of %s", col, stmt.Schema.Name)
+		}
+		v, _ := field.ValueOf(ctx, value)
+		tx = tx.Where(clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: field.DBName}, Value: v})
+	}
+	for _, field := range stmt.Schema.PrimaryFields {
+		if v, isZero := field.ValueOf(ctx, value); !isZero {
+			tx = tx.Where(clause.Neq{Column: clause.Column{Table: clause.CurrentTable, Name: field.DBName}, Value: v})
+		}
+	}
+	var count int64
+	if err := tx.Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count == 0, nil
+}
+
+// NotDeletedColumn CreateUniqueNotDeletedIndex 添加的虚拟列，未删除为1，已删除为 NULL
+const NotDeletedColumn = "not_deleted"
+
+// UniqueNotDeletedIndexSQL 返回只约束未删除记录的唯一索引的 MySQL 语句
+// 唯一索引直接包含 deleted_at 无效：未删除记录的 deleted_at 都为 NULL，NULL 互不相等，重复数据仍可写入
+// 因此添加虚拟列 not_deleted，唯一索引建在 (cols..., not_deleted) 上，已删除记录为 NULL 不参与约束
+func UniqueNotDeletedIndexSQL(table, index string, cols ...string) []string {
+	quoted := make([]string, 0, len(cols)+1)
+	for _, col := range append(cols, NotDeletedColumn) {
+		quoted = append(quoted, "`"+col+"`")
+	}
+	return []string{
+		fmt.Sprintf("ALTER TABLE `%s` ADD COLUMN `%s` TINYINT GENERATED ALWAYS AS (IF(`deleted_at` IS NULL, 1, NULL)) VIRTUAL", table, NotDeletedColumn),
+		fmt.Sprintf("CREATE UNIQUE INDEX `%s` ON `%s` (%s)", index, table, strings.Join(quoted, ", ")),
+	}
+}
+
+// CreateUniqueNotDeletedIndex 在 model 对应的表上创建 UniqueNotDeletedIndexSQL 的唯一索引，在 AutoMigrate 之后调用
+// cols 为字段名或列名，虚拟列或索引已存在时跳过
+func CreateUniqueNotDeletedIndex(db *gorm.DB, model any, index string, cols ...string) error {
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(model); err != nil {
+		return err
+	}
+	dbNames := make([]string, 0, len(cols))
+	for _, col := range cols {
+		field := stmt.Schema.LookUpField(col)
+		if field == nil {
+			return fmt.Errorf("orm: unknown column %q of %s", col, stmt.Schema.Name)
+		}
+		dbNames = append(dbNames, field.DBName)
+	}
+	sqls := UniqueNotDeletedIndexSQL(stmt.Schema.Table, index, dbNames...)
+	migrator := db.Migrator()
+	if !migrator.HasColumn(model, NotDeletedColumn) {
+		if err := db.Exec(sqls[0]).Error; err != nil {
+			return fmt.Errorf("add column %s: %w", NotDeletedColumn, err)
+		}
+	}
+	if !migrator.HasIndex(model, index) {
+		if err := db.Exec(sqls[1]).Error; err != nil {
+			return fmt.Errorf("create index %s: %w", index, err)
+		}
+	}
+	return nil
+}
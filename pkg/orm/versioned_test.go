@@ -0,0 +1,215 @@
+package orm
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+type account struct {
+	ID   int64
+	Name string
+	VersionedModel
+}
+
+// fakeConn 记录执行的 SQL，exec 返回影响行数，query 返回查询结果；delay 模拟慢查询，期间 context 结束时返回 ctx.Err()
+type fakeConn struct {
+	mu    sync.Mutex
+	sqls  []string
+	exec  func(query string, args []driver.Value) int64
+	query func(query string, args []driver.Value) *fakeRows
+	delay time.Duration
+}
+
+func (c *fakeConn) Prepare(string) (driver.Stmt, error) { return nil, driver.ErrSkip }
+func (c *fakeConn) Close() error                        { return nil }
+func (c *fakeConn) Begin() (driver.Tx, error)           { return c, nil }
+func (c *fakeConn) Commit() error                       { return nil }
+func (c *fakeConn) Rollback() error                     { return nil }
+
+func (c *fakeConn) lastSQL() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.sqls[len(c.sqls)-1]
+}
+
+func (c *fakeConn) record(query string, args []driver.NamedValue) []driver.Value {
+	c.mu.Lock()
+	c.sqls = append(c.sqls, query)
+	c.mu.Unlock()
+	values := make([]driver.Value, len(args))
+	for i, arg := range args {
+		values[i] = arg.Value
+	}
+	return values
+}
+
+func (c *fakeConn) wait(ctx context.Context) error {
+	if c.delay <= 0 {
+		return nil
+	}
+	select {
+	case <-time.After(c.delay):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (c *fakeConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	values := c.record(query, args)
+	if err := c.wait(ctx); err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(c.exec(query, values)), nil
+}
+
+func (c *fakeConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	values := c.record(query, args)
+	if err := c.wait(ctx); err != nil {
+		return nil, err
+	}
+	return c.query(query, values), nil
+}
+
+type fakeRows struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+func countResult(n int64) *fakeRows {
+	return &fakeRows{columns: []string{"count(*)"}, rows: [][]driver.Value{{n}}}
+}
+
+func (r *fakeRows) Columns() []string { return r.columns }
+func (r *fakeRows) Close() error      { return nil }
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
+
+// argOf 返回 query 中 marker 处占位符对应的参数
+func argOf(query, marker string, args []driver.Value) driver.Value {
+	pos := strings.Index(query, marker)
+	if pos < 0 {
+		return nil
+	}
+	return args[strings.Count(query[:pos], "?")]
+}
+
+var registerOnce sync.Once
+
+func newFakeDB(t *testing.T, conn *fakeConn) *gorm.DB {
+	registerOnce.Do(func() {
+		sql.Register("orm-fake", &fakeDriver{})
+	})
+	fakeConns.Store(t.Name(), conn)
+	sqlDB, err := sql.Open("orm-fake", t.Name())
+	require.NoError(t, err)
+	db, err := gorm.Open(mysql.New(mysql.Config{Conn: sqlDB, SkipInitializeWithVersion: true}),
+		&gorm.Config{Logger: logger.Discard})
+	require.NoError(t, err)
+	require.NoError(t, db.Use(OptimisticLock{}))
+	return db
+}
+
+var fakeConns sync.Map
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(name string) (driver.Conn, error) {
+	conn, _ := fakeConns.Load(name)
+	return conn.(*fakeConn), nil
+}
+
+func TestOptimisticLock(t *testing.T) {
+	// 库中的版本号，版本号一致时更新成功并加一
+	stored := int64(1)
+	conn := &fakeConn{exec: func(query string, args []driver.Value) int64 {
+		v, ok := argOf(query, "`version` = ?", args).(int64)
+		if !ok || v != stored {
+			return 0
+		}
+		stored = argOf(query, "`version`=?", args).(int64)
+		return 1
+	}}
+	db := newFakeDB(t, conn)
+
+	// 两个请求读到同一版本，先更新的成功，后更新的冲突
+	a1 := account{ID: 1, Name: "a", VersionedModel: VersionedModel{Version: 1}}
+	a2 := a1
+	require.NoError(t, db.Model(&a1).Updates(map[string]any{"name": "b"}).Error)
+	assert.Equal(t, 2, a1.Version)
+	assert.Equal(t, int64(2), stored)
+	assert.Contains(t, conn.lastSQL(), "`accounts`.`version` = ?")
+
+	a2.Name = "c"
+	err := db.Save(&a2).Error
+	assert.ErrorIs(t, err, ErrStaleObject)
+	assert.Equal(t, 1, a2.Version)
+	assert.Equal(t, int64(2), stored)
+
+	// 重新查询后重试成功
+	a2.Version = 2
+	require.NoError(t, db.Save(&a2).Error)
+	assert.Equal(t, 3, a2.Version)
+
+	// Select 指定更新列时同样更新版本号
+	a2.Name = "d"
+	require.NoError(t, db.Select("name").Updates(&a2).Error)
+	assert.Equal(t, 4, a2.Version)
+	assert.Equal(t, int64(4), stored)
+
+	// 版本号为0的批量更新不加锁
+	conn.exec = func(string, []driver.Value) int64 { return 0 }
+	require.NoError(t, db.Model(&account{}).Where("name = ?", "d").Update("name", "e").Error)
+	assert.NotContains(t, conn.lastSQL(), "`version`")
+}
+
+func TestUniqueNotDeleted(t *testing.T) {
+	var count int64
+	conn := &fakeConn{query: func(string, []driver.Value) *fakeRows { return countResult(count) }}
+	db := newFakeDB(t, conn)
+
+	unique, err := UniqueNotDeleted(db, &account{Name: "a"}, "Name")
+	require.NoError(t, err)
+	assert.True(t, unique)
+	query := conn.lastSQL()
+	assert.Contains(t, query, "`accounts`.`name` = ?")
+	assert.Contains(t, query, "`accounts`.`deleted_at` IS NULL")
+	assert.NotContains(t, query, "`id`")
+
+	// 更新时排除自身
+	count = 1
+	unique, err = UniqueNotDeleted(db, &account{ID: 3, Name: "a"}, "name")
+	require.NoError(t, err)
+	assert.False(t, unique)
+	assert.Contains(t, conn.lastSQL(), "`accounts`.`id` <> ?")
+
+	_, err = UniqueNotDeleted(db, &account{}, "email")
+	assert.Error(t, err)
+	_, err = UniqueNotDeleted(db, &account{})
+	assert.Error(t, err)
+}
+
+func TestUniqueNotDeletedIndexSQL(t *testing.T) {
+	assert.Equal(t, []string{
+		"ALTER TABLE `accounts` ADD COLUMN `not_deleted` TINYINT GENERATED ALWAYS AS (IF(`deleted_at` IS NULL, 1, NULL)) VIRTUAL",
+		"CREATE UNIQUE INDEX `uk_name` ON `accounts` (`tenant_id`, `name`, `not_deleted`)",
+	}, UniqueNotDeletedIndexSQL("accounts", "uk_name", "tenant_id", "name"))
+}
@@ -0,0 +1,245 @@
+// Package oss -----------------------------
+// @file      : archive.go
+// @author    : xiangtao
+// @contact   : xiangtao1994@gmail.com
+// @time      : 2025/9/14 18:10
+// Description: 将多个对象流式打包为 zip / tar.gz，边下载边写入，不在内存中缓存整个文件
+// -------------------------------------------
+package oss
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/minio/minio-go/v7"
+
+	"github.com/xiangtao94/golib/pkg/zlog"
+)
+
+const (
+	ArchiveFormatZip   = "zip"
+	ArchiveFormatTarGz = "tar.gz"
+
+	// ArchiveManifestName 开启 Manifest 时写入压缩包的清单文件名
+	ArchiveManifestName = "manifest.json"
+)
+
+// ArchiveOptions 打包选项
+type ArchiveOptions struct {
+	StripPrefix string // 去掉对象名的前缀后作为压缩包内的路径，如 "jobs/123/"
+	SkipMissing bool   // 对象不存在时跳过并记录警告，默认直接返回错误
+	Manifest    bool   // 在压缩包末尾写入 manifest.json，记录每个对象的路径和大小
+}
+
+// ArchiveEntry 压缩包中的一个对象
+type ArchiveEntry struct {
+	ObjectName string `json:"objectName"`
+	Path       string `json:"path"`              // 压缩包内的路径
+	Size       int64  `json:"size"`              // 写入的字节数
+	Skipped    bool   `json:"skipped,omitempty"` // SkipMissing 时对象不存在
+}
+
+// StreamArchive 将 bucketName 下的多个对象按 format（zip、tar.gz）打包写入 w，返回每个对象的清单
+// 对象逐个下载并直接写入压缩流，请求取消时中断打包；出错时 w 中可能已写入部分数据
+func (mc *MinioClient) StreamArchive(ctx *gin.Context, bucketName string, objectNames []string, format string, w io.Writer, opts *ArchiveOptions) ([]ArchiveEntry, error) {
+	return StreamArchive(ctx, mc, bucketName, objectNames, format, w, opts)
+}
+
+// StreamArchive 同 MinioClient.StreamArchive，适用于任意 ObjectStorage 后端
+func StreamArchive(ctx *gin.Context, store ObjectStorage, bucketName string, objectNames []string, format string, w io.Writer, opts *ArchiveOptions) ([]ArchiveEntry, error) {
+	if opts == nil {
+		opts = &ArchiveOptions{}
+	}
+	aw, err := newArchiveWriter(format, w)
+	if err != nil {
+		return nil, err
+	}
+	reqCtx := context.Background()
+	if ctx != nil && ctx.Request != nil {
+		reqCtx = ctx.Request.Context()
+	}
+
+	start := time.Now()
+	entries := make([]ArchiveEntry, 0, len(objectNames))
+	for _, objectName := range objectNames {
+		if err := reqCtx.Err(); err != nil {
+			return entries, err
+		}
+		entry, err := archiveObject(ctx, reqCtx, store, aw, bucketName, objectName, opts)
+		if err != nil {
+			return entries, err
+		}
+		entries = append(entries, entry)
+	}
+	if opts.Manifest {
+		if err := writeArchiveManifest(aw, entries); err != nil {
+			return entries, err
+		}
+	}
+	if err := aw.Close(); err != nil {
+		return entries, fmt.Errorf("failed to close archive: %w", err)
+	}
+
+	zlog.Infof(ctx, "archive streamed: %s, objects: %d, format: %s, cost: %v",
+		bucketName, len(entries), format, time.Since(start))
+	return entries, nil
+}
+
+// ArchiveResponse 设置 Content-Type 和 Content-Disposition 后将压缩包写入响应，filename 为下载的文件名
+// 开始写入后无法再修改状态码，出错时响应不完整，调用方只需记录日志
+func ArchiveResponse(ctx *gin.Context, store ObjectStorage, bucketName string, objectNames []string, format, filename string, opts *ArchiveOptions) ([]ArchiveEntry, error) {
+	contentType, err := archiveContentType(format)
+	if err != nil {
+		return nil, err
+	}
+	ctx.Header("Content-Type", contentType)
+	ctx.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+	ctx.Status(http.StatusOK)
+	entries, err := StreamArchive(ctx, store, bucketName, objectNames, format, ctx.Writer, opts)
+	if err != nil {
+		zlog.Errorf(ctx, "failed to stream archive %s: %v", bucketName, err)
+		return entries, err
+	}
+	ctx.Writer.Flush()
+	return entries, nil
+}
+
+// archiveObject 下载一个对象写入压缩包
+func archiveObject(ctx *gin.Context, reqCtx context.Context, store ObjectStorage, aw archiveWriter, bucketName, objectName string, opts *ArchiveOptions) (ArchiveEntry, error) {
+	entry := ArchiveEntry{ObjectName: objectName, Path: archivePath(objectName, opts.StripPrefix)}
+	if entry.Path == "" {
+		return entry, fmt.Errorf("invalid archive path for object %s", objectName)
+	}
+	reader, info, err := store.DownloadFile(ctx, bucketName, objectName)
+	if err != nil {
+		if opts.SkipMissing && isNoSuchKey(err) {
+			zlog.Warnf(ctx, "archive skip missing object %s/%s", bucketName, objectName)
+			entry.Skipped = true
+			return entry, nil
+		}
+		return entry, fmt.Errorf("failed to archive object %s: %w", objectName, err)
+	}
+	defer reader.Close()
+
+	fw, err := aw.create(entry.Path, info.Size, info.LastModified)
+	if err != nil {
+		return entry, fmt.Errorf("failed to add %s to archive: %w", entry.Path, err)
+	}
+	entry.Size, err = io.Copy(fw, &ctxReader{ctx: reqCtx, r: reader})
+	if err != nil {
+		return entry, fmt.Errorf("failed to archive object %s: %w", objectName, err)
+	}
+	if entry.Size != info.Size {
+		return entry, fmt.Errorf("archive object %s size mismatch: expected %d, got %d", objectName, info.Size, entry.Size)
+	}
+	return entry, nil
+}
+
+func writeArchiveManifest(aw archiveWriter, entries []ArchiveEntry) error {
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	fw, err := aw.create(ArchiveManifestName, int64(len(data)), time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to add manifest to archive: %w", err)
+	}
+	_, err = fw.Write(data)
+	return err
+}
+
+// archivePath 去掉前缀并清理 ".."，避免解压时写到目标目录之外
+func archivePath(objectName, stripPrefix string) string {
+	p := strings.TrimPrefix(objectName, stripPrefix)
+	return strings.TrimPrefix(path.Clean("/"+p), "/")
+}
+
+// isNoSuchKey 兼容 MinIO/本地存储的 minio.ErrorResponse 和 S3 的 NoSuchKey 错误
+func isNoSuchKey(err error) bool {
+	var resp minio.ErrorResponse
+	return errors.As(err, &resp) && resp.Code == "NoSuchKey" || isS3NotFound(err)
+}
+
+func archiveContentType(format string) (string, error) {
+	switch format {
+	case ArchiveFormatZip:
+		return "application/zip", nil
+	case ArchiveFormatTarGz:
+		return "application/gzip", nil
+	default:
+		return "", fmt.Errorf("unsupported archive format: %s", format)
+	}
+}
+
+// archiveWriter 屏蔽 zip 和 tar.gz 的差异
+type archiveWriter interface {
+	create(name string, size int64, modTime time.Time) (io.Writer, error)
+	Close() error
+}
+
+func newArchiveWriter(format string, w io.Writer) (archiveWriter, error) {
+	switch format {
+	case ArchiveFormatZip:
+		return &zipArchive{zw: zip.NewWriter(w)}, nil
+	case ArchiveFormatTarGz:
+		gz := gzip.NewWriter(w)
+		return &tarGzArchive{gz: gz, tw: tar.NewWriter(gz)}, nil
+	default:
+		return nil, fmt.Errorf("unsupported archive format: %s", format)
+	}
+}
+
+type zipArchive struct {
+	zw *zip.Writer
+}
+
+func (a *zipArchive) create(name string, _ int64, modTime time.Time) (io.Writer, error) {
+	return a.zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: modTime})
+}
+
+func (a *zipArchive) Close() error {
+	return a.zw.Close()
+}
+
+type tarGzArchive struct {
+	gz *gzip.Writer
+	tw *tar.Writer
+}
+
+// create tar 需要预先知道文件大小，使用对象信息中的大小
+func (a *tarGzArchive) create(name string, size int64, modTime time.Time) (io.Writer, error) {
+	err := a.tw.WriteHeader(&tar.Header{Typeflag: tar.TypeReg, Name: name, Size: size, Mode: 0o644, ModTime: modTime})
+	return a.tw, err
+}
+
+func (a *tarGzArchive) Close() error {
+	if err := a.tw.Close(); err != nil {
+		return err
+	}
+	return a.gz.Close()
+}
+
+// ctxReader 每次读取前检查请求是否已取消
+type ctxReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (r *ctxReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.r.Read(p)
+}
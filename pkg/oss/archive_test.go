@@ -0,0 +1,160 @@
+package oss
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/minio/minio-go/v7"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var archiveObjects = map[string]string{
+	"jobs/1/out.log":      "job finished",
+	"jobs/1/result.json":  `{"ok":true}`,
+	"jobs/1/images/a.png": "\x89PNG\r\n\x1a\n....",
+}
+
+func newArchiveStore(t *testing.T) ObjectStorage {
+	store := newLocalStore(t)
+	for name, content := range archiveObjects {
+		upload(t, store, name, content, nil)
+	}
+	return store
+}
+
+func readZip(t *testing.T, data []byte) map[string]string {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	require.NoError(t, err)
+	files := map[string]string{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		require.NoError(t, err)
+		body, err := io.ReadAll(rc)
+		require.NoError(t, err)
+		_ = rc.Close()
+		files[f.Name] = string(body)
+	}
+	return files
+}
+
+func readTarGz(t *testing.T, data []byte) map[string]string {
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	require.NoError(t, err)
+	tr := tar.NewReader(gz)
+	files := map[string]string{}
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		body, err := io.ReadAll(tr)
+		require.NoError(t, err)
+		files[hdr.Name] = string(body)
+	}
+	return files
+}
+
+func TestStreamArchive(t *testing.T) {
+	store := newArchiveStore(t)
+	names := []string{"jobs/1/out.log", "jobs/1/result.json", "jobs/1/images/a.png"}
+	want := map[string]string{}
+	for _, name := range names {
+		want[name[len("jobs/1/"):]] = archiveObjects[name]
+	}
+
+	for format, read := range map[string]func(*testing.T, []byte) map[string]string{
+		ArchiveFormatZip:   readZip,
+		ArchiveFormatTarGz: readTarGz,
+	} {
+		var buf bytes.Buffer
+		entries, err := StreamArchive(newTestCtx(), store, "docs", names, format, &buf, &ArchiveOptions{StripPrefix: "jobs/1/", Manifest: true})
+		require.NoError(t, err, format)
+		require.Len(t, entries, 3)
+		assert.Equal(t, ArchiveEntry{ObjectName: "jobs/1/result.json", Path: "result.json", Size: 11}, entries[1])
+
+		files := read(t, buf.Bytes())
+		var manifest []ArchiveEntry
+		require.NoError(t, json.Unmarshal([]byte(files[ArchiveManifestName]), &manifest), format)
+		assert.Equal(t, entries, manifest)
+		delete(files, ArchiveManifestName)
+		assert.Equal(t, want, files, format)
+	}
+
+	// 不去前缀时保留对象名作为路径
+	var buf bytes.Buffer
+	_, err := StreamArchive(newTestCtx(), store, "docs", names[:1], ArchiveFormatZip, &buf, nil)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"jobs/1/out.log": "job finished"}, readZip(t, buf.Bytes()))
+
+	_, err = StreamArchive(newTestCtx(), store, "docs", names, "rar", &buf, nil)
+	assert.Error(t, err)
+}
+
+func TestStreamArchive_Missing(t *testing.T) {
+	store := newArchiveStore(t)
+	names := []string{"jobs/1/out.log", "jobs/1/none.txt", "jobs/1/result.json"}
+
+	var buf bytes.Buffer
+	entries, err := StreamArchive(newTestCtx(), store, "docs", names, ArchiveFormatZip, &buf, &ArchiveOptions{SkipMissing: true})
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+	assert.True(t, entries[1].Skipped)
+	files := readZip(t, buf.Bytes())
+	assert.Len(t, files, 2)
+	assert.NotContains(t, files, "jobs/1/none.txt")
+
+	// 默认遇到不存在的对象直接失败
+	entries, err = StreamArchive(newTestCtx(), store, "docs", names, ArchiveFormatZip, io.Discard, nil)
+	var resp minio.ErrorResponse
+	require.True(t, errors.As(err, &resp))
+	assert.Equal(t, "NoSuchKey", resp.Code)
+	assert.Len(t, entries, 1)
+}
+
+func TestStreamArchive_Canceled(t *testing.T) {
+	store := newArchiveStore(t)
+	ctx := newTestCtx()
+	reqCtx, cancel := context.WithCancel(context.Background())
+	ctx.Request = ctx.Request.WithContext(reqCtx)
+	cancel()
+
+	_, err := StreamArchive(ctx, store, "docs", []string{"jobs/1/out.log"}, ArchiveFormatTarGz, io.Discard, nil)
+	assert.ErrorIs(t, err, context.Canceled)
+
+	// 读取对象内容时取消
+	r := &ctxReader{ctx: reqCtx, r: bytes.NewReader([]byte("data"))}
+	_, err = r.Read(make([]byte, 4))
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestArchiveResponse(t *testing.T) {
+	store := newArchiveStore(t)
+	w := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(w)
+	ctx.Request = httptest.NewRequest(http.MethodGet, "/download", nil)
+
+	_, err := ArchiveResponse(ctx, store, "docs", []string{"jobs/1/out.log"}, ArchiveFormatZip, "job 1.zip", nil)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/zip", w.Header().Get("Content-Type"))
+	assert.Equal(t, `attachment; filename="job 1.zip"`, w.Header().Get("Content-Disposition"))
+	assert.Equal(t, map[string]string{"jobs/1/out.log": "job finished"}, readZip(t, w.Body.Bytes()))
+}
+
+func TestArchivePath(t *testing.T) {
+	assert.Equal(t, "a/b.txt", archivePath("jobs/a/b.txt", "jobs/"))
+	assert.Equal(t, "etc/passwd", archivePath("jobs/../../etc/passwd", "jobs/"))
+	assert.Equal(t, "", archivePath("jobs/", "jobs/"))
+}
@@ -0,0 +1,414 @@
+// Package oss -----------------------------
+// @file      : local.go
+// @author    : xiangtao
+// @contact   : xiangtao1994@gmail.com
+// @time      : 2025/9/14 11:30
+// Description: 本地磁盘实现的对象存储，用于单元测试和本地开发
+// -------------------------------------------
+package oss
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/minio/minio-go/v7"
+
+	"github.com/xiangtao94/golib/pkg/zlog"
+)
+
+// 对象元数据（Content-Type、ETag 等）保存在 Root 下的该目录中，与存储桶目录分开
+const localMetaDir = ".oss-meta"
+
+type LocalConf struct {
+	// Root 存储根目录，每个存储桶对应一个子目录
+	Root string `yaml:"root"`
+	// BaseURL 可选，生成预签名URL时使用 BaseURL/bucket/object，为空时返回 file:// 地址
+	BaseURL string `yaml:"baseURL"`
+}
+
+// LocalFSStore 本地磁盘实现的 ObjectStorage，对象 a/b.txt 保存为 Root/bucket/a/b.txt
+// 对象不存在时返回的错误与 S3 一致（可通过 errors.As 得到 Code 为 NoSuchKey 的 minio.ErrorResponse）
+// 预签名URL不带签名和过期时间，不能用于生产环境
+type LocalFSStore struct {
+	config LocalConf
+}
+
+type localMeta struct {
+	ContentType string            `json:"contentType"`
+	ETag        string            `json:"etag"`
+	UserMeta    map[string]string `json:"userMeta,omitempty"`
+}
+
+// NewLocalFSStore 创建本地对象存储，Root 不存在时自动创建
+func NewLocalFSStore(config LocalConf) (*LocalFSStore, error) {
+	if config.Root == "" {
+		return nil, errors.New("local oss root is empty")
+	}
+	root, err := filepath.Abs(config.Root)
+	if err != nil {
+		return nil, fmt.Errorf("invalid local oss root: %w", err)
+	}
+	if err = os.MkdirAll(root, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create local oss root: %w", err)
+	}
+	config.Root = root
+	return &LocalFSStore{config: config}, nil
+}
+
+// CreateBucket 创建存储桶目录，location 无意义
+func (ls *LocalFSStore) CreateBucket(ctx *gin.Context, bucketName string, location string) error {
+	dir, err := ls.bucketDir(bucketName)
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(dir, 0o755); err != nil {
+		zlog.Errorf(ctx, "failed to create bucket %s: %v", bucketName, err)
+		return fmt.Errorf("failed to create bucket: %w", err)
+	}
+	return nil
+}
+
+// UploadFile 上传文件，先写入临时文件再重命名，objectSize 大于等于0时校验实际写入的大小
+func (ls *LocalFSStore) UploadFile(ctx *gin.Context, bucketName, objectName string, reader io.Reader, objectSize int64, opts *UploadOptions) (UploadInfo, error) {
+	if opts == nil {
+		opts = &UploadOptions{}
+	}
+	objectPath, err := ls.objectPath(bucketName, objectName)
+	if err != nil {
+		return UploadInfo{}, err
+	}
+	contentType := opts.ContentType
+	if contentType == "" {
+		if contentType, reader, err = detectContentType(objectName, reader); err != nil {
+			return UploadInfo{}, fmt.Errorf("failed to upload file: %w", err)
+		}
+	}
+	if err = ls.checkBucket(bucketName); err != nil {
+		return UploadInfo{}, err
+	}
+	size, etag, err := writeFileAtomic(objectPath, reader, objectSize)
+	if err != nil {
+		zlog.Errorf(ctx, "failed to upload file %s/%s: %v", bucketName, objectName, err)
+		return UploadInfo{}, fmt.Errorf("failed to upload file: %w", err)
+	}
+	meta := localMeta{ContentType: contentType, ETag: etag, UserMeta: opts.UserMeta}
+	if err = ls.writeMeta(bucketName, objectName, meta); err != nil {
+		zlog.Errorf(ctx, "failed to upload file %s/%s: %v", bucketName, objectName, err)
+		return UploadInfo{}, fmt.Errorf("failed to upload file: %w", err)
+	}
+	return UploadInfo{Bucket: bucketName, Key: objectName, ETag: etag, Size: size, LastModified: time.Now()}, nil
+}
+
+// DownloadFile 下载文件，调用方负责关闭返回的 io.ReadCloser
+func (ls *LocalFSStore) DownloadFile(ctx *gin.Context, bucketName, objectName string) (io.ReadCloser, *DownloadInfo, error) {
+	info, err := ls.GetObjectInfo(ctx, bucketName, objectName)
+	if err != nil {
+		return nil, nil, err
+	}
+	objectPath, _ := ls.objectPath(bucketName, objectName)
+	f, err := os.Open(objectPath)
+	if err != nil {
+		zlog.Errorf(ctx, "failed to download file %s/%s: %v", bucketName, objectName, err)
+		return nil, nil, fmt.Errorf("failed to download file: %w", ls.notFound(bucketName, objectName, err))
+	}
+	return f, info, nil
+}
+
+// GetPresignedURL 返回对象地址，method 和 expiry 无意义
+func (ls *LocalFSStore) GetPresignedURL(ctx *gin.Context, bucketName, objectName string, expiry time.Duration, method string) (string, error) {
+	objectPath, err := ls.objectPath(bucketName, objectName)
+	if err != nil {
+		return "", err
+	}
+	if ls.config.BaseURL == "" {
+		return (&url.URL{Scheme: "file", Path: filepath.ToSlash(objectPath)}).String(), nil
+	}
+	return strings.TrimRight(ls.config.BaseURL, "/") + "/" + url.PathEscape(bucketName) + "/" + escapeObjectName(objectName), nil
+}
+
+// DeleteFile 删除文件，与 S3 一致，对象不存在时不返回错误
+func (ls *LocalFSStore) DeleteFile(ctx *gin.Context, bucketName, objectName string) error {
+	objectPath, err := ls.objectPath(bucketName, objectName)
+	if err != nil {
+		return err
+	}
+	metaPath, _ := ls.metaPath(bucketName, objectName)
+	for _, p := range []string{objectPath, metaPath} {
+		if err = os.Remove(p); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			zlog.Errorf(ctx, "failed to delete file %s/%s: %v", bucketName, objectName, err)
+			return fmt.Errorf("failed to delete file: %w", err)
+		}
+	}
+	return nil
+}
+
+// ListObjects 按对象名字典序列出对象，recursive 为 false 时下一级目录以 "dir/" 形式返回
+func (ls *LocalFSStore) ListObjects(ctx *gin.Context, bucketName, prefix string, recursive bool) ([]ObjectInfo, error) {
+	if err := ls.checkBucket(bucketName); err != nil {
+		return nil, err
+	}
+	dir, _ := ls.bucketDir(bucketName)
+	var keys []string
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || strings.HasPrefix(d.Name(), ".tmp-") {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		if key := filepath.ToSlash(rel); strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+		return nil
+	})
+	if err != nil {
+		zlog.Errorf(ctx, "error listing objects in bucket %s: %v", bucketName, err)
+		return nil, fmt.Errorf("error listing objects: %w", err)
+	}
+	sort.Strings(keys)
+
+	var objects []ObjectInfo
+	seen := make(map[string]bool)
+	for _, key := range keys {
+		if !recursive {
+			if i := strings.Index(key[len(prefix):], "/"); i >= 0 {
+				commonPrefix := key[:len(prefix)+i+1]
+				if !seen[commonPrefix] {
+					seen[commonPrefix] = true
+					objects = append(objects, ObjectInfo{Key: commonPrefix})
+				}
+				continue
+			}
+		}
+		info, err := ls.GetObjectInfo(ctx, bucketName, key)
+		if err != nil {
+			return nil, fmt.Errorf("error listing objects: %w", err)
+		}
+		objects = append(objects, ObjectInfo{
+			Key:          key,
+			Size:         info.Size,
+			LastModified: info.LastModified,
+			ETag:         info.ETag,
+		})
+	}
+	return objects, nil
+}
+
+// ObjectExists 检查对象是否存在
+func (ls *LocalFSStore) ObjectExists(ctx *gin.Context, bucketName, objectName string) (bool, error) {
+	objectPath, err := ls.objectPath(bucketName, objectName)
+	if err != nil {
+		return false, err
+	}
+	fi, err := os.Stat(objectPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		zlog.Errorf(ctx, "failed to check object existence %s/%s: %v", bucketName, objectName, err)
+		return false, fmt.Errorf("failed to check object existence: %w", err)
+	}
+	return !fi.IsDir(), nil
+}
+
+// GetObjectInfo 获取对象信息
+func (ls *LocalFSStore) GetObjectInfo(ctx *gin.Context, bucketName, objectName string) (*DownloadInfo, error) {
+	objectPath, err := ls.objectPath(bucketName, objectName)
+	if err != nil {
+		return nil, err
+	}
+	fi, err := os.Stat(objectPath)
+	if err == nil && fi.IsDir() {
+		err = fs.ErrNotExist
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get object info: %w", ls.notFound(bucketName, objectName, err))
+	}
+	meta, err := ls.readMeta(bucketName, objectName)
+	if err != nil {
+		zlog.Errorf(ctx, "failed to get object info %s/%s: %v", bucketName, objectName, err)
+		return nil, fmt.Errorf("failed to get object info: %w", err)
+	}
+	return &DownloadInfo{
+		ObjectName:   objectName,
+		Size:         fi.Size(),
+		LastModified: fi.ModTime(),
+		ContentType:  meta.ContentType,
+		ETag:         meta.ETag,
+	}, nil
+}
+
+// CopyObject 复制对象，包括 Content-Type 和用户元数据
+func (ls *LocalFSStore) CopyObject(ctx *gin.Context, srcBucket, srcObject, destBucket, destObject string) error {
+	reader, _, err := ls.DownloadFile(ctx, srcBucket, srcObject)
+	if err != nil {
+		return fmt.Errorf("failed to copy object: %w", err)
+	}
+	defer reader.Close()
+	meta, err := ls.readMeta(srcBucket, srcObject)
+	if err != nil {
+		return fmt.Errorf("failed to copy object: %w", err)
+	}
+	_, err = ls.UploadFile(ctx, destBucket, destObject, reader, -1, &UploadOptions{ContentType: meta.ContentType, UserMeta: meta.UserMeta})
+	if err != nil {
+		return fmt.Errorf("failed to copy object: %w", err)
+	}
+	return nil
+}
+
+// Close 本地存储没有需要释放的资源
+func (ls *LocalFSStore) Close() {}
+
+func (ls *LocalFSStore) bucketDir(bucketName string) (string, error) {
+	if bucketName == "" || strings.HasPrefix(bucketName, ".") || strings.ContainsAny(bucketName, `/\`) {
+		return "", fmt.Errorf("invalid bucket name: %q", bucketName)
+	}
+	return filepath.Join(ls.config.Root, bucketName), nil
+}
+
+// checkBucket 存储桶不存在时返回 NoSuchBucket
+func (ls *LocalFSStore) checkBucket(bucketName string) error {
+	dir, err := ls.bucketDir(bucketName)
+	if err != nil {
+		return err
+	}
+	if fi, err := os.Stat(dir); err != nil || !fi.IsDir() {
+		return minio.ErrorResponse{
+			StatusCode: http.StatusNotFound,
+			Code:       "NoSuchBucket",
+			Message:    "The specified bucket does not exist",
+			BucketName: bucketName,
+		}
+	}
+	return nil
+}
+
+// objectPath 对象名按 "/" 分隔，拒绝逃出存储桶目录的名称（如 ../a）
+func (ls *LocalFSStore) objectPath(bucketName, objectName string) (string, error) {
+	dir, err := ls.bucketDir(bucketName)
+	if err != nil {
+		return "", err
+	}
+	if !validObjectName(objectName) {
+		return "", fmt.Errorf("invalid object name: %q", objectName)
+	}
+	return filepath.Join(dir, filepath.FromSlash(objectName)), nil
+}
+
+func (ls *LocalFSStore) metaPath(bucketName, objectName string) (string, error) {
+	if _, err := ls.objectPath(bucketName, objectName); err != nil {
+		return "", err
+	}
+	return filepath.Join(ls.config.Root, localMetaDir, bucketName, filepath.FromSlash(objectName)+".json"), nil
+}
+
+func (ls *LocalFSStore) writeMeta(bucketName, objectName string, meta localMeta) error {
+	metaPath, err := ls.metaPath(bucketName, objectName)
+	if err != nil {
+		return err
+	}
+	b, err := json.Marshal(meta)
+	if err != nil {
+		return err
+	}
+	_, _, err = writeFileAtomic(metaPath, bytes.NewReader(b), -1)
+	return err
+}
+
+// readMeta 元数据缺失时（如直接拷贝到目录中的文件）按扩展名推断 Content-Type
+func (ls *LocalFSStore) readMeta(bucketName, objectName string) (localMeta, error) {
+	metaPath, err := ls.metaPath(bucketName, objectName)
+	if err != nil {
+		return localMeta{}, err
+	}
+	var meta localMeta
+	b, err := os.ReadFile(metaPath)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		meta.ContentType = getContentType(objectName)
+		return meta, nil
+	case err != nil:
+		return meta, err
+	}
+	return meta, json.Unmarshal(b, &meta)
+}
+
+// notFound 文件不存在时转换为 S3 的 NoSuchKey/NoSuchBucket 错误
+func (ls *LocalFSStore) notFound(bucketName, objectName string, err error) error {
+	if !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	if bucketErr := ls.checkBucket(bucketName); bucketErr != nil {
+		return bucketErr
+	}
+	return minio.ErrorResponse{
+		StatusCode: http.StatusNotFound,
+		Code:       "NoSuchKey",
+		Message:    "The specified key does not exist.",
+		BucketName: bucketName,
+		Key:        objectName,
+	}
+}
+
+func validObjectName(objectName string) bool {
+	if objectName == "" || strings.HasSuffix(objectName, "/") || strings.Contains(objectName, `\`) {
+		return false
+	}
+	cleaned := path.Clean("/" + objectName)
+	return cleaned == "/"+objectName
+}
+
+func escapeObjectName(objectName string) string {
+	parts := strings.Split(objectName, "/")
+	for i, part := range parts {
+		parts[i] = url.PathEscape(part)
+	}
+	return strings.Join(parts, "/")
+}
+
+// writeFileAtomic 写入同目录的临时文件后重命名，返回写入的字节数和内容的 MD5
+func writeFileAtomic(filePath string, reader io.Reader, expectSize int64) (int64, string, error) {
+	dir := filepath.Dir(filePath)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return 0, "", err
+	}
+	tmp, err := os.CreateTemp(dir, ".tmp-")
+	if err != nil {
+		return 0, "", err
+	}
+	defer os.Remove(tmp.Name())
+	hash := md5.New()
+	size, err := io.Copy(io.MultiWriter(tmp, hash), reader)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return 0, "", err
+	}
+	if expectSize >= 0 && size != expectSize {
+		return 0, "", fmt.Errorf("size mismatch: expected %d, got %d", expectSize, size)
+	}
+	if err = os.Rename(tmp.Name(), filePath); err != nil {
+		return 0, "", err
+	}
+	return size, hex.EncodeToString(hash.Sum(nil)), nil
+}
@@ -0,0 +1,127 @@
+package oss
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newLocalStore(t *testing.T) ObjectStorage {
+	store, err := New(OssConf{Provider: ProviderLocal, Local: LocalConf{Root: t.TempDir()}})
+	require.NoError(t, err)
+	require.NoError(t, store.CreateBucket(newTestCtx(), "docs", ""))
+	return store
+}
+
+func upload(t *testing.T, store ObjectStorage, objectName, content string, opts *UploadOptions) UploadInfo {
+	info, err := store.UploadFile(newTestCtx(), "docs", objectName, strings.NewReader(content), int64(len(content)), opts)
+	require.NoError(t, err)
+	return info
+}
+
+func TestLocalFSStore_UploadDownload(t *testing.T) {
+	store := newLocalStore(t)
+	ctx := newTestCtx()
+
+	info := upload(t, store, "a/hello.txt", "hello world", nil)
+	assert.Equal(t, int64(11), info.Size)
+	assert.Equal(t, "5eb63bbbe01eeed093cb22bb8f5acdc3", info.ETag)
+
+	reader, dl, err := store.DownloadFile(ctx, "docs", "a/hello.txt")
+	require.NoError(t, err)
+	body, _ := io.ReadAll(reader)
+	_ = reader.Close()
+	assert.Equal(t, "hello world", string(body))
+	assert.Equal(t, "text/plain", dl.ContentType)
+	assert.Equal(t, info.ETag, dl.ETag)
+
+	// 指定 Content-Type 并复制
+	upload(t, store, "raw", "{}", &UploadOptions{ContentType: "application/json"})
+	require.NoError(t, store.CopyObject(ctx, "docs", "raw", "docs", "copy/raw"))
+	got, err := store.GetObjectInfo(ctx, "docs", "copy/raw")
+	require.NoError(t, err)
+	assert.Equal(t, "application/json", got.ContentType)
+
+	// 无扩展名时按内容判断
+	upload(t, store, "avatar", "\x89PNG\r\n\x1a\n....", nil)
+	got, err = store.GetObjectInfo(ctx, "docs", "avatar")
+	require.NoError(t, err)
+	assert.Equal(t, "image/png", got.ContentType)
+
+	// 大小不一致时不写入
+	_, err = store.UploadFile(ctx, "docs", "short", strings.NewReader("abc"), 10, nil)
+	assert.Error(t, err)
+	exists, err := store.ObjectExists(ctx, "docs", "short")
+	require.NoError(t, err)
+	assert.False(t, exists)
+}
+
+func TestLocalFSStore_ListDelete(t *testing.T) {
+	store := newLocalStore(t)
+	ctx := newTestCtx()
+	for _, name := range []string{"b.txt", "a/1.txt", "a/2.txt", "a/sub/3.txt", "c/4.txt"} {
+		upload(t, store, name, name, nil)
+	}
+
+	keys := func(objects []ObjectInfo) []string {
+		var ks []string
+		for _, o := range objects {
+			ks = append(ks, o.Key)
+		}
+		return ks
+	}
+	objects, err := store.ListObjects(ctx, "docs", "", false)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a/", "b.txt", "c/"}, keys(objects))
+	objects, err = store.ListObjects(ctx, "docs", "a/", false)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a/1.txt", "a/2.txt", "a/sub/"}, keys(objects))
+	objects, err = store.ListObjects(ctx, "docs", "a/", true)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a/1.txt", "a/2.txt", "a/sub/3.txt"}, keys(objects))
+	assert.Equal(t, int64(7), objects[0].Size)
+
+	require.NoError(t, store.DeleteFile(ctx, "docs", "a/1.txt"))
+	require.NoError(t, store.DeleteFile(ctx, "docs", "a/1.txt"))
+	exists, err := store.ObjectExists(ctx, "docs", "a/1.txt")
+	require.NoError(t, err)
+	assert.False(t, exists)
+}
+
+func TestLocalFSStore_Errors(t *testing.T) {
+	store := newLocalStore(t)
+	ctx := newTestCtx()
+
+	var resp minio.ErrorResponse
+	_, _, err := store.DownloadFile(ctx, "docs", "missing.txt")
+	require.True(t, errors.As(err, &resp))
+	assert.Equal(t, "NoSuchKey", resp.Code)
+	_, err = store.ListObjects(ctx, "missing", "", true)
+	require.True(t, errors.As(err, &resp))
+	assert.Equal(t, "NoSuchBucket", resp.Code)
+
+	for _, name := range []string{"../escape.txt", "a/../../b", "/abs", "dir/", ""} {
+		_, err = store.UploadFile(ctx, "docs", name, strings.NewReader("x"), 1, nil)
+		assert.Error(t, err, name)
+	}
+	_, err = store.UploadFile(ctx, "../docs", "a.txt", strings.NewReader("x"), 1, nil)
+	assert.Error(t, err)
+}
+
+func TestLocalFSStore_PresignedURL(t *testing.T) {
+	store, err := NewLocalFSStore(LocalConf{Root: t.TempDir(), BaseURL: "http://localhost:8080/files/"})
+	require.NoError(t, err)
+	url, err := store.GetPresignedURL(newTestCtx(), "docs", "a/hello world.txt", 0, "GET")
+	require.NoError(t, err)
+	assert.Equal(t, "http://localhost:8080/files/docs/a/hello%20world.txt", url)
+
+	store.config.BaseURL = ""
+	url, err = store.GetPresignedURL(newTestCtx(), "docs", "a.txt", 0, "GET")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(url, "file:///"), url)
+}
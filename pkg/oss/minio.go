@@ -8,8 +8,10 @@
 package oss
 
 import (
+	"bytes"
 	"fmt"
 	"io"
+	"net/http"
 	"net/url"
 	"path/filepath"
 	"strings"
@@ -18,10 +20,13 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/minio/minio-go/v7"
 	"github.com/minio/minio-go/v7/pkg/credentials"
+	"github.com/minio/minio-go/v7/pkg/encrypt"
 	"github.com/xiangtao94/golib/pkg/errors"
 	"github.com/xiangtao94/golib/pkg/zlog"
 )
 
+const defaultContentType = "application/octet-stream"
+
 type MinioConf struct {
 	AK       string `yaml:"ak"`
 	SK       string `yaml:"sk"`
@@ -40,8 +45,9 @@ type MinioClient struct {
 
 // UploadOptions 上传选项
 type UploadOptions struct {
-	ContentType string            // 文件类型
+	ContentType string            // 文件类型，为空时按扩展名判断，无法识别时按内容判断
 	UserMeta    map[string]string // 用户元数据
+	Tags        map[string]string // 对象标签
 	ServerSide  bool              // 服务端加密
 }
 
@@ -122,37 +128,45 @@ func (mc *MinioClient) CreateBucket(ctx *gin.Context, bucketName string, locatio
 }
 
 // UploadFile 上传文件
-func (mc *MinioClient) UploadFile(ctx *gin.Context, bucketName, objectName string, reader io.Reader, objectSize int64, opts *UploadOptions) (minio.UploadInfo, error) {
+func (mc *MinioClient) UploadFile(ctx *gin.Context, bucketName, objectName string, reader io.Reader, objectSize int64, opts *UploadOptions) (UploadInfo, error) {
 	start := time.Now()
 
 	if opts == nil {
 		opts = &UploadOptions{}
 	}
 
-	// 设置默认Content-Type
-	if opts.ContentType == "" {
-		opts.ContentType = getContentType(objectName)
+	// 设置默认Content-Type，扩展名无法识别时按内容判断
+	contentType := opts.ContentType
+	if contentType == "" {
+		var err error
+		contentType, reader, err = detectContentType(objectName, reader)
+		if err != nil {
+			zlog.Errorf(ctx, "failed to upload file %s/%s: %v", bucketName, objectName, err)
+			return UploadInfo{}, fmt.Errorf("failed to upload file: %w", err)
+		}
 	}
 
 	putOptions := minio.PutObjectOptions{
-		ContentType:  opts.ContentType,
-		UserMetadata: opts.UserMeta,
+		ContentType:          contentType,
+		UserMetadata:         opts.UserMeta,
+		UserTags:             opts.Tags,
+		ServerSideEncryption: mc.serverSide(opts),
 	}
 
 	uploadInfo, err := mc.client.PutObject(ctx, bucketName, objectName, reader, objectSize, putOptions)
 	if err != nil {
 		zlog.Errorf(ctx, "failed to upload file %s/%s: %v", bucketName, objectName, err)
-		return minio.UploadInfo{}, fmt.Errorf("failed to upload file: %w", err)
+		return UploadInfo{}, fmt.Errorf("failed to upload file: %w", err)
 	}
 
 	zlog.Infof(ctx, "file uploaded successfully: %s/%s, size: %d, etag: %s, cost: %v",
 		bucketName, objectName, uploadInfo.Size, uploadInfo.ETag, time.Since(start))
 
-	return uploadInfo, nil
+	return toUploadInfo(uploadInfo), nil
 }
 
 // UploadFileFromPath 从本地路径上传文件
-func (mc *MinioClient) UploadFileFromPath(ctx *gin.Context, bucketName, objectName, filePath string, opts *UploadOptions) (minio.UploadInfo, error) {
+func (mc *MinioClient) UploadFileFromPath(ctx *gin.Context, bucketName, objectName, filePath string, opts *UploadOptions) (UploadInfo, error) {
 	start := time.Now()
 
 	if opts == nil {
@@ -165,20 +179,22 @@ func (mc *MinioClient) UploadFileFromPath(ctx *gin.Context, bucketName, objectNa
 	}
 
 	putOptions := minio.PutObjectOptions{
-		ContentType:  opts.ContentType,
-		UserMetadata: opts.UserMeta,
+		ContentType:          opts.ContentType,
+		UserMetadata:         opts.UserMeta,
+		UserTags:             opts.Tags,
+		ServerSideEncryption: mc.serverSide(opts),
 	}
 
 	uploadInfo, err := mc.client.FPutObject(ctx, bucketName, objectName, filePath, putOptions)
 	if err != nil {
 		zlog.Errorf(ctx, "failed to upload file from path %s to %s/%s: %v", filePath, bucketName, objectName, err)
-		return minio.UploadInfo{}, fmt.Errorf("failed to upload file from path: %w", err)
+		return UploadInfo{}, fmt.Errorf("failed to upload file from path: %w", err)
 	}
 
 	zlog.Infof(ctx, "file uploaded successfully from path: %s -> %s/%s, size: %d, etag: %s, cost: %v",
 		filePath, bucketName, objectName, uploadInfo.Size, uploadInfo.ETag, time.Since(start))
 
-	return uploadInfo, nil
+	return toUploadInfo(uploadInfo), nil
 }
 
 // DownloadFile 下载文件
@@ -287,10 +303,10 @@ func (mc *MinioClient) DeleteFile(ctx *gin.Context, bucketName, objectName strin
 }
 
 // ListObjects 列出对象
-func (mc *MinioClient) ListObjects(ctx *gin.Context, bucketName, prefix string, recursive bool) ([]minio.ObjectInfo, error) {
+func (mc *MinioClient) ListObjects(ctx *gin.Context, bucketName, prefix string, recursive bool) ([]ObjectInfo, error) {
 	start := time.Now()
 
-	var objects []minio.ObjectInfo
+	var objects []ObjectInfo
 	objectCh := mc.client.ListObjects(ctx, bucketName, minio.ListObjectsOptions{
 		Prefix:    prefix,
 		Recursive: recursive,
@@ -301,7 +317,13 @@ func (mc *MinioClient) ListObjects(ctx *gin.Context, bucketName, prefix string,
 			zlog.Errorf(ctx, "error listing objects in bucket %s: %v", bucketName, object.Err)
 			return nil, fmt.Errorf("error listing objects: %w", object.Err)
 		}
-		objects = append(objects, object)
+		objects = append(objects, ObjectInfo{
+			Key:          object.Key,
+			Size:         object.Size,
+			ETag:         object.ETag,
+			LastModified: object.LastModified,
+			StorageClass: object.StorageClass,
+		})
 	}
 
 	zlog.Infof(ctx, "listed %d objects in bucket %s with prefix %s, cost: %v",
@@ -384,6 +406,64 @@ func (mc *MinioClient) Close() {
 	// 这里主要是为了保持接口一致性
 }
 
+func toUploadInfo(info minio.UploadInfo) UploadInfo {
+	return UploadInfo{
+		Bucket:       info.Bucket,
+		Key:          info.Key,
+		ETag:         info.ETag,
+		Size:         info.Size,
+		LastModified: info.LastModified,
+		VersionID:    info.VersionID,
+	}
+}
+
+// serverSide 获取写入时使用的服务端加密方式
+func (mc *MinioClient) serverSide(opts *UploadOptions) encrypt.ServerSide {
+	if opts != nil && opts.ServerSide {
+		return encrypt.NewSSE()
+	}
+	return nil
+}
+
+// sniffLen http.DetectContentType 最多使用的字节数
+const sniffLen = 512
+
+// detectContentType 根据扩展名获取 Content-Type，无法识别时读取前512字节按内容判断
+// reader 可 Seek 时读取后回到原位置，否则返回拼接了已读内容的新 reader
+func detectContentType(objectName string, reader io.Reader) (string, io.Reader, error) {
+	contentType := getContentType(objectName)
+	if contentType != defaultContentType {
+		return contentType, reader, nil
+	}
+	buf := make([]byte, sniffLen)
+	if seeker, ok := reader.(io.ReadSeeker); ok {
+		offset, err := seeker.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return "", nil, err
+		}
+		n, err := io.ReadFull(seeker, buf)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return "", nil, err
+		}
+		if _, err = seeker.Seek(offset, io.SeekStart); err != nil {
+			return "", nil, err
+		}
+		return sniffContentType(buf[:n]), reader, nil
+	}
+	n, err := io.ReadFull(reader, buf)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return "", nil, err
+	}
+	return sniffContentType(buf[:n]), io.MultiReader(bytes.NewReader(buf[:n]), reader), nil
+}
+
+func sniffContentType(data []byte) string {
+	if len(data) == 0 {
+		return defaultContentType
+	}
+	return http.DetectContentType(data)
+}
+
 // getContentType 根据文件扩展名获取Content-Type
 func getContentType(filename string) string {
 	ext := strings.ToLower(filepath.Ext(filename))
@@ -411,6 +491,6 @@ func getContentType(filename string) string {
 	case ".mp3":
 		return "audio/mpeg"
 	default:
-		return "application/octet-stream"
+		return defaultContentType
 	}
 }
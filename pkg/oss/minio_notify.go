@@ -0,0 +1,205 @@
+// Package oss -----------------------------
+// @file      : minio_notify.go
+// @author    : xiangtao
+// @contact   : xiangtao1994@gmail.com
+// @time      : 2025/9/14 16:30
+// Description: 存储桶事件订阅（断线自动重连）与通知目标配置
+// -------------------------------------------
+package oss
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"net/url"
+	"runtime/debug"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/notification"
+	"github.com/xiangtao94/golib/pkg/zlog"
+)
+
+// NotificationEvent 简化的存储桶事件
+type NotificationEvent struct {
+	Bucket    string
+	Key       string // 对象名，已做 URL 解码
+	Size      int64
+	ETag      string
+	EventType string // 事件类型，如 s3:ObjectCreated:Put
+	Time      time.Time
+}
+
+// NotificationTarget 存储桶通知目标，需先在 MinIO 服务端配置对应的 webhook、AMQP 等目标
+type NotificationTarget struct {
+	ARN    string   // 目标ARN，如 arn:minio:sqs::primary:webhook、arn:minio:sqs::1:amqp
+	Events []string // 事件类型，为空时为 s3:ObjectCreated:*
+	Prefix string   // 对象名前缀过滤
+	Suffix string   // 对象名后缀过滤，如 .pdf
+}
+
+// 断线重连的退避时间，测试中替换
+var (
+	notifyBackoffMin = time.Second
+	notifyBackoffMax = 30 * time.Second
+)
+
+// listenBucketNotification 订阅事件流，测试中替换为假的事件流
+var listenBucketNotification = func(ctx context.Context, c *minio.Client, bucketName, prefix, suffix string, events []string) <-chan notification.Info {
+	return c.ListenBucketNotification(ctx, bucketName, prefix, suffix, events)
+}
+
+// ListenBucketNotifications 订阅存储桶事件并逐个交给 handler 处理，阻塞直到 ctx 取消（gin 需开启 ContextWithFallback）
+// 事件流断开时按指数退避（带抖动）自动重连；handler 返回错误或 panic 只记录日志，不影响后续事件
+// events 为空时订阅 s3:ObjectCreated:*，仅 MinIO 服务端支持，AWS S3 需使用 SetBucketNotification 配置外部目标
+func (mc *MinioClient) ListenBucketNotifications(ctx *gin.Context, bucketName, prefix, suffix string, events []string, handler func(event NotificationEvent) error) error {
+	if handler == nil {
+		return fmt.Errorf("listen bucket %s notifications: handler is nil", bucketName)
+	}
+	if len(events) == 0 {
+		events = []string{string(notification.ObjectCreatedAll)}
+	}
+
+	zlog.Infof(ctx, "listening bucket %s notifications, prefix: %q, suffix: %q, events: %v", bucketName, prefix, suffix, events)
+	backoff := notifyBackoffMin
+	for {
+		ch := listenBucketNotification(ctx, mc.client, bucketName, prefix, suffix, events)
+		if received := consumeNotifications(ctx, bucketName, ch, handler); received {
+			// 连接期间正常收到过事件，重新从最小退避开始
+			backoff = notifyBackoffMin
+		}
+		if ctx.Err() != nil {
+			zlog.Infof(ctx, "stopped listening bucket %s notifications", bucketName)
+			return nil
+		}
+
+		wait := backoff/2 + rand.N(backoff/2+1)
+		zlog.Warnf(ctx, "bucket %s notification stream closed, reconnecting in %v", bucketName, wait)
+		select {
+		case <-ctx.Done():
+			zlog.Infof(ctx, "stopped listening bucket %s notifications", bucketName)
+			return nil
+		case <-time.After(wait):
+		}
+		backoff = min(backoff*2, notifyBackoffMax)
+	}
+}
+
+// consumeNotifications 读取事件流直到关闭，返回是否收到过事件
+func consumeNotifications(ctx *gin.Context, bucketName string, ch <-chan notification.Info, handler func(NotificationEvent) error) (received bool) {
+	for info := range ch {
+		if info.Err != nil {
+			zlog.Warnf(ctx, "bucket %s notification stream error: %v", bucketName, info.Err)
+			continue
+		}
+		for _, record := range info.Records {
+			received = true
+			handleNotification(ctx, handler, toNotificationEvent(record))
+		}
+	}
+	return received
+}
+
+func handleNotification(ctx *gin.Context, handler func(NotificationEvent) error, event NotificationEvent) {
+	start := time.Now()
+	defer func() {
+		if r := recover(); r != nil {
+			zlog.Errorf(ctx, "bucket notification handler panic, object: %s/%s, event: %s, panic: %v\n%s",
+				event.Bucket, event.Key, event.EventType, r, debug.Stack())
+		}
+	}()
+
+	err := handler(event)
+	var delay time.Duration
+	if !event.Time.IsZero() {
+		delay = start.Sub(event.Time)
+	}
+	if err != nil {
+		zlog.Errorf(ctx, "bucket notification handler failed, object: %s/%s, event: %s, delay: %v, cost: %v, err: %v",
+			event.Bucket, event.Key, event.EventType, delay, time.Since(start), err)
+		return
+	}
+	zlog.Infof(ctx, "bucket notification handled, object: %s/%s, event: %s, delay: %v, cost: %v",
+		event.Bucket, event.Key, event.EventType, delay, time.Since(start))
+}
+
+func toNotificationEvent(record notification.Event) NotificationEvent {
+	key := record.S3.Object.Key
+	// 事件中的对象名按 URL 编码
+	if decoded, err := url.QueryUnescape(key); err == nil {
+		key = decoded
+	}
+	eventTime, _ := time.Parse(time.RFC3339Nano, record.EventTime)
+	return NotificationEvent{
+		Bucket:    record.S3.Bucket.Name,
+		Key:       key,
+		Size:      record.S3.Object.Size,
+		ETag:      record.S3.Object.ETag,
+		EventType: record.EventName,
+		Time:      eventTime,
+	}
+}
+
+// SetBucketNotification 覆盖存储桶的通知配置，事件由服务端推送到 webhook、AMQP 等目标，targets 为空时清除通知配置
+func (mc *MinioClient) SetBucketNotification(ctx *gin.Context, bucketName string, targets []NotificationTarget) error {
+	start := time.Now()
+
+	if len(targets) == 0 {
+		if err := mc.client.RemoveAllBucketNotification(ctx, bucketName); err != nil {
+			zlog.Errorf(ctx, "failed to remove bucket notification %s: %v", bucketName, err)
+			return fmt.Errorf("failed to remove bucket notification: %w", err)
+		}
+		zlog.Infof(ctx, "bucket notification removed: %s, cost: %v", bucketName, time.Since(start))
+		return nil
+	}
+
+	config, err := buildNotificationConfig(targets)
+	if err != nil {
+		return err
+	}
+	if err = mc.client.SetBucketNotification(ctx, bucketName, config); err != nil {
+		zlog.Errorf(ctx, "failed to set bucket notification %s: %v", bucketName, err)
+		return fmt.Errorf("failed to set bucket notification: %w", err)
+	}
+
+	zlog.Infof(ctx, "bucket notification set: %s, targets: %d, cost: %v", bucketName, len(targets), time.Since(start))
+	return nil
+}
+
+// buildNotificationConfig 按 ARN 的服务类型添加为 queue（sqs，MinIO 的 webhook/AMQP/Kafka 等）、topic（sns）或 lambda
+func buildNotificationConfig(targets []NotificationTarget) (notification.Configuration, error) {
+	var config notification.Configuration
+	for i, target := range targets {
+		arn, err := notification.NewArnFromString(target.ARN)
+		if err != nil {
+			return config, fmt.Errorf("invalid notification target %d: %w", i+1, err)
+		}
+		c := notification.NewConfig(arn)
+		events := target.Events
+		if len(events) == 0 {
+			events = []string{string(notification.ObjectCreatedAll)}
+		}
+		for _, e := range events {
+			c.AddEvents(notification.EventType(e))
+		}
+		if target.Prefix != "" {
+			c.AddFilterPrefix(target.Prefix)
+		}
+		if target.Suffix != "" {
+			c.AddFilterSuffix(target.Suffix)
+		}
+
+		switch arn.Service {
+		case "sqs":
+			config.AddQueue(c)
+		case "sns":
+			config.AddTopic(c)
+		case "lambda":
+			config.AddLambda(c)
+		default:
+			return config, fmt.Errorf("invalid notification target %d: unsupported service %q in arn %s", i+1, arn.Service, target.ARN)
+		}
+	}
+	return config, nil
+}
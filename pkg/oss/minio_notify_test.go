@@ -0,0 +1,200 @@
+package oss
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/notification"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func record(key, eventName string) notification.Event {
+	var e notification.Event
+	e.EventName = eventName
+	e.EventTime = "2025-09-14T08:30:00.000Z"
+	e.S3.Bucket.Name = "docs"
+	e.S3.Object.Key = key
+	e.S3.Object.Size = 42
+	return e
+}
+
+// fakeStreams 每次连接返回一个预设的事件流，发送完后关闭模拟连接断开
+func fakeStreams(t *testing.T, streams ...[]notification.Info) *int {
+	var calls int
+	old := listenBucketNotification
+	oldMin := notifyBackoffMin
+	listenBucketNotification = func(ctx context.Context, _ *minio.Client, bucketName, prefix, suffix string, events []string) <-chan notification.Info {
+		assert.Equal(t, "docs", bucketName)
+		assert.Equal(t, "uploads/", prefix)
+		assert.Equal(t, []string{"s3:ObjectCreated:*"}, events)
+		ch := make(chan notification.Info, 8)
+		if calls < len(streams) {
+			for _, info := range streams[calls] {
+				ch <- info
+			}
+		}
+		calls++
+		close(ch)
+		return ch
+	}
+	notifyBackoffMin = time.Millisecond
+	t.Cleanup(func() {
+		listenBucketNotification = old
+		notifyBackoffMin = oldMin
+	})
+	return &calls
+}
+
+func newCancelCtx() (*gin.Context, context.CancelFunc) {
+	ctx, engine := gin.CreateTestContext(httptest.NewRecorder())
+	engine.ContextWithFallback = true
+	reqCtx, cancel := context.WithCancel(context.Background())
+	ctx.Request = httptest.NewRequest(http.MethodGet, "/", nil).WithContext(reqCtx)
+	return ctx, cancel
+}
+
+func TestListenBucketNotifications_Reconnect(t *testing.T) {
+	calls := fakeStreams(t,
+		[]notification.Info{
+			{Records: []notification.Event{record("uploads/a%20b.pdf", "s3:ObjectCreated:Put")}},
+			{Err: errors.New("connection reset by peer")},
+		},
+		[]notification.Info{
+			{Records: []notification.Event{
+				record("uploads/panic.pdf", "s3:ObjectCreated:Put"),
+				record("uploads/fail.pdf", "s3:ObjectCreated:Put"),
+				record("uploads/c.pdf", "s3:ObjectCreated:CompleteMultipartUpload"),
+			}},
+		},
+	)
+
+	ctx, cancel := newCancelCtx()
+	var (
+		mu   sync.Mutex
+		seen []NotificationEvent
+	)
+	handler := func(event NotificationEvent) error {
+		mu.Lock()
+		seen = append(seen, event)
+		n := len(seen)
+		mu.Unlock()
+		switch event.Key {
+		case "uploads/panic.pdf":
+			panic("boom")
+		case "uploads/fail.pdf":
+			return errors.New("ingest failed")
+		}
+		if n == 4 {
+			cancel()
+		}
+		return nil
+	}
+
+	mc := &MinioClient{}
+	done := make(chan error, 1)
+	go func() { done <- mc.ListenBucketNotifications(ctx, "docs", "uploads/", "", nil, handler) }()
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("listener did not stop after ctx was cancelled")
+	}
+
+	// 第一个连接断开后重连，handler 的 panic 和错误不影响后续事件
+	assert.Equal(t, 2, *calls)
+	require.Len(t, seen, 4)
+	assert.Equal(t, NotificationEvent{
+		Bucket:    "docs",
+		Key:       "uploads/a b.pdf",
+		Size:      42,
+		EventType: "s3:ObjectCreated:Put",
+		Time:      time.Date(2025, 9, 14, 8, 30, 0, 0, time.UTC),
+	}, seen[0])
+	assert.Equal(t, "uploads/c.pdf", seen[3].Key)
+	assert.Equal(t, "s3:ObjectCreated:CompleteMultipartUpload", seen[3].EventType)
+}
+
+func TestListenBucketNotifications_Backoff(t *testing.T) {
+	calls := fakeStreams(t)
+	notifyBackoffMin = 20 * time.Millisecond
+	ctx, cancel := newCancelCtx()
+	time.AfterFunc(100*time.Millisecond, cancel)
+
+	mc := &MinioClient{}
+	err := mc.ListenBucketNotifications(ctx, "docs", "uploads/", "", nil, func(NotificationEvent) error { return nil })
+	require.NoError(t, err)
+	// 连接一直失败时退避时间翻倍（10-20ms、20-40ms、40-80ms ...），100ms 内最多重连4次
+	assert.GreaterOrEqual(t, *calls, 2)
+	assert.LessOrEqual(t, *calls, 5)
+}
+
+func TestBuildNotificationConfig(t *testing.T) {
+	config, err := buildNotificationConfig([]NotificationTarget{
+		{ARN: "arn:minio:sqs::primary:webhook", Prefix: "uploads/", Suffix: ".pdf"},
+		{ARN: "arn:minio:sqs::1:amqp", Events: []string{"s3:ObjectRemoved:*"}},
+	})
+	require.NoError(t, err)
+	require.Len(t, config.QueueConfigs, 2)
+	assert.Equal(t, "arn:minio:sqs::primary:webhook", config.QueueConfigs[0].Queue)
+	assert.Equal(t, []notification.EventType{notification.ObjectCreatedAll}, config.QueueConfigs[0].Events)
+	assert.Equal(t, []notification.FilterRule{{Name: "prefix", Value: "uploads/"}, {Name: "suffix", Value: ".pdf"}},
+		config.QueueConfigs[0].Filter.S3Key.FilterRules)
+	assert.Equal(t, []notification.EventType{notification.ObjectRemovedAll}, config.QueueConfigs[1].Events)
+
+	cases := map[string][]NotificationTarget{
+		"invalid arn": {{ARN: "webhook"}},
+		"unsupported": {{ARN: "arn:minio:s3::1:webhook"}},
+	}
+	for name, targets := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := buildNotificationConfig(targets)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestMinioClient_SetBucketNotification(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		methods []string
+		body    string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := r.URL.Query()["notification"]; !ok {
+			w.WriteHeader(http.StatusNotImplemented)
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		methods = append(methods, r.Method)
+		b, _ := io.ReadAll(r.Body)
+		body = string(b)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client, err := NewMinioClient(MinioConf{AK: "ak", SK: "sk", Endpoint: server.URL, Region: "us-east-1"})
+	require.NoError(t, err)
+	ctx := newTestCtx()
+
+	require.NoError(t, client.SetBucketNotification(ctx, "docs", []NotificationTarget{
+		{ARN: "arn:minio:sqs::primary:webhook", Suffix: ".pdf"},
+	}))
+	assert.Contains(t, body, "<Queue>arn:minio:sqs::primary:webhook</Queue>")
+	assert.Contains(t, body, "<Event>s3:ObjectCreated:*</Event>")
+	assert.Contains(t, body, "<Name>suffix</Name><Value>.pdf</Value>")
+
+	// targets 为空时清除通知配置
+	require.NoError(t, client.SetBucketNotification(ctx, "docs", nil))
+	assert.Equal(t, []string{http.MethodPut, http.MethodPut}, methods)
+	assert.NotContains(t, body, "<Queue>")
+}
@@ -0,0 +1,177 @@
+// Package oss -----------------------------
+// @file      : minio_policy.go
+// @author    : xiangtao
+// @contact   : xiangtao1994@gmail.com
+// @time      : 2025/9/4 15:12
+// Description: 对象标签、生命周期、保留策略与法律保留
+// -------------------------------------------
+package oss
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/lifecycle"
+	"github.com/minio/minio-go/v7/pkg/tags"
+	"github.com/xiangtao94/golib/pkg/zlog"
+)
+
+// LifecycleRule 简化的生命周期规则
+type LifecycleRule struct {
+	ID             string // 规则ID，为空时按序号生成
+	Prefix         string // 生效的对象前缀，如 tmp/
+	ExpiryDays     int    // 创建后多少天过期删除，0表示不过期
+	TransitionDays int    // 创建后多少天转换存储类型，0表示不转换
+	StorageClass   string // 转换的目标存储类型，TransitionDays>0时必填
+}
+
+// SetObjectTags 设置对象标签，tags为空时清除对象所有标签
+func (mc *MinioClient) SetObjectTags(ctx *gin.Context, bucketName, objectName string, objTags map[string]string) error {
+	start := time.Now()
+
+	if len(objTags) == 0 {
+		err := mc.client.RemoveObjectTagging(ctx, bucketName, objectName, minio.RemoveObjectTaggingOptions{})
+		if err != nil {
+			zlog.Errorf(ctx, "failed to remove object tags %s/%s: %v", bucketName, objectName, err)
+			return fmt.Errorf("failed to remove object tags: %w", err)
+		}
+		zlog.Infof(ctx, "object tags removed: %s/%s, cost: %v", bucketName, objectName, time.Since(start))
+		return nil
+	}
+
+	t, err := tags.MapToObjectTags(objTags)
+	if err != nil {
+		return fmt.Errorf("invalid object tags: %w", err)
+	}
+	err = mc.client.PutObjectTagging(ctx, bucketName, objectName, t, minio.PutObjectTaggingOptions{})
+	if err != nil {
+		zlog.Errorf(ctx, "failed to set object tags %s/%s: %v", bucketName, objectName, err)
+		return fmt.Errorf("failed to set object tags: %w", err)
+	}
+
+	zlog.Infof(ctx, "object tags set: %s/%s, tags: %v, cost: %v", bucketName, objectName, objTags, time.Since(start))
+	return nil
+}
+
+// GetObjectTags 获取对象标签
+func (mc *MinioClient) GetObjectTags(ctx *gin.Context, bucketName, objectName string) (map[string]string, error) {
+	start := time.Now()
+
+	t, err := mc.client.GetObjectTagging(ctx, bucketName, objectName, minio.GetObjectTaggingOptions{})
+	if err != nil {
+		zlog.Errorf(ctx, "failed to get object tags %s/%s: %v", bucketName, objectName, err)
+		return nil, fmt.Errorf("failed to get object tags: %w", err)
+	}
+
+	zlog.Infof(ctx, "got object tags: %s/%s, cost: %v", bucketName, objectName, time.Since(start))
+	return t.ToMap(), nil
+}
+
+// SetBucketLifecycle 设置存储桶生命周期规则，rules为空时清除生命周期配置
+func (mc *MinioClient) SetBucketLifecycle(ctx *gin.Context, bucketName string, rules []LifecycleRule) error {
+	start := time.Now()
+
+	config, err := buildLifecycleConfig(rules)
+	if err != nil {
+		return err
+	}
+
+	err = mc.client.SetBucketLifecycle(ctx, bucketName, config)
+	if err != nil {
+		zlog.Errorf(ctx, "failed to set bucket lifecycle %s: %v", bucketName, err)
+		return fmt.Errorf("failed to set bucket lifecycle: %w", err)
+	}
+
+	zlog.Infof(ctx, "bucket lifecycle set: %s, rules: %d, cost: %v", bucketName, len(rules), time.Since(start))
+	return nil
+}
+
+// SetObjectRetention 设置对象保留策略，mode 为 GOVERNANCE 或 COMPLIANCE，存储桶需开启对象锁定
+func (mc *MinioClient) SetObjectRetention(ctx *gin.Context, bucketName, objectName string, mode string, retainUntil time.Time) error {
+	start := time.Now()
+
+	retentionMode := minio.RetentionMode(strings.ToUpper(mode))
+	if !retentionMode.IsValid() {
+		return fmt.Errorf("invalid retention mode: %s", mode)
+	}
+	if !retainUntil.After(time.Now()) {
+		return fmt.Errorf("retain until date must be in the future: %v", retainUntil)
+	}
+
+	err := mc.client.PutObjectRetention(ctx, bucketName, objectName, minio.PutObjectRetentionOptions{
+		Mode:            &retentionMode,
+		RetainUntilDate: &retainUntil,
+	})
+	if err != nil {
+		zlog.Errorf(ctx, "failed to set object retention %s/%s: %v", bucketName, objectName, err)
+		return fmt.Errorf("failed to set object retention: %w", err)
+	}
+
+	zlog.Infof(ctx, "object retention set: %s/%s, mode: %s, until: %v, cost: %v",
+		bucketName, objectName, retentionMode, retainUntil, time.Since(start))
+	return nil
+}
+
+// SetObjectLegalHold 开启或关闭对象的法律保留，存储桶需开启对象锁定
+func (mc *MinioClient) SetObjectLegalHold(ctx *gin.Context, bucketName, objectName string, enabled bool) error {
+	start := time.Now()
+
+	status := minio.LegalHoldDisabled
+	if enabled {
+		status = minio.LegalHoldEnabled
+	}
+	err := mc.client.PutObjectLegalHold(ctx, bucketName, objectName, minio.PutObjectLegalHoldOptions{
+		Status: &status,
+	})
+	if err != nil {
+		zlog.Errorf(ctx, "failed to set object legal hold %s/%s: %v", bucketName, objectName, err)
+		return fmt.Errorf("failed to set object legal hold: %w", err)
+	}
+
+	zlog.Infof(ctx, "object legal hold set: %s/%s, status: %s, cost: %v",
+		bucketName, objectName, status, time.Since(start))
+	return nil
+}
+
+// buildLifecycleConfig 校验简化规则并转换为 minio 生命周期配置
+func buildLifecycleConfig(rules []LifecycleRule) (*lifecycle.Configuration, error) {
+	config := lifecycle.NewConfiguration()
+	for i, r := range rules {
+		if r.ExpiryDays < 0 || r.TransitionDays < 0 {
+			return nil, fmt.Errorf("lifecycle rule %d: days must not be negative", i)
+		}
+		if r.ExpiryDays == 0 && r.TransitionDays == 0 {
+			return nil, fmt.Errorf("lifecycle rule %d: expiryDays or transitionDays is required", i)
+		}
+		if r.TransitionDays > 0 && r.StorageClass == "" {
+			return nil, fmt.Errorf("lifecycle rule %d: storageClass is required when transitionDays is set", i)
+		}
+		if r.ExpiryDays > 0 && r.TransitionDays > 0 && r.ExpiryDays <= r.TransitionDays {
+			return nil, fmt.Errorf("lifecycle rule %d: expiryDays must be greater than transitionDays", i)
+		}
+
+		id := r.ID
+		if id == "" {
+			id = fmt.Sprintf("rule-%d", i+1)
+		}
+		rule := lifecycle.Rule{
+			ID:         id,
+			Status:     "Enabled",
+			RuleFilter: lifecycle.Filter{Prefix: r.Prefix},
+		}
+		if r.ExpiryDays > 0 {
+			rule.Expiration = lifecycle.Expiration{Days: lifecycle.ExpirationDays(r.ExpiryDays)}
+		}
+		if r.TransitionDays > 0 {
+			rule.Transition = lifecycle.Transition{
+				Days:         lifecycle.ExpirationDays(r.TransitionDays),
+				StorageClass: r.StorageClass,
+			}
+		}
+		config.Rules = append(config.Rules, rule)
+	}
+	return config, nil
+}
@@ -0,0 +1,90 @@
+package oss
+
+import (
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildLifecycleConfig(t *testing.T) {
+	config, err := buildLifecycleConfig([]LifecycleRule{
+		{Prefix: "tmp/", ExpiryDays: 7},
+		{ID: "archive", Prefix: "logs/", TransitionDays: 30, StorageClass: "GLACIER", ExpiryDays: 365},
+	})
+	assert.NoError(t, err)
+
+	b, err := xml.Marshal(config)
+	assert.NoError(t, err)
+	body := string(b)
+	assert.Contains(t, body, "<ID>rule-1</ID>")
+	assert.Contains(t, body, "<Filter><Prefix>tmp/</Prefix></Filter>")
+	assert.Contains(t, body, "<Expiration><Days>7</Days></Expiration>")
+	assert.Contains(t, body, "<ID>archive</ID>")
+	assert.Contains(t, body, "<Transition><StorageClass>GLACIER</StorageClass><Days>30</Days></Transition>")
+	assert.Contains(t, body, "<Status>Enabled</Status>")
+}
+
+func TestBuildLifecycleConfig_Invalid(t *testing.T) {
+	cases := map[string]LifecycleRule{
+		"negative":           {Prefix: "tmp/", ExpiryDays: -1},
+		"empty":              {Prefix: "tmp/"},
+		"no storage class":   {Prefix: "tmp/", TransitionDays: 10},
+		"expiry before move": {Prefix: "tmp/", TransitionDays: 10, StorageClass: "GLACIER", ExpiryDays: 5},
+	}
+	for name, rule := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := buildLifecycleConfig([]LifecycleRule{rule})
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestMinioClient_ObjectTags(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		tagging []byte
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := r.URL.Query()["tagging"]; !ok {
+			w.WriteHeader(http.StatusNotImplemented)
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		switch r.Method {
+		case http.MethodPut:
+			tagging, _ = io.ReadAll(r.Body)
+			w.WriteHeader(http.StatusOK)
+		case http.MethodGet:
+			if tagging == nil {
+				tagging = []byte(`<Tagging><TagSet></TagSet></Tagging>`)
+			}
+			w.Header().Set("Content-Type", "application/xml")
+			_, _ = w.Write(tagging)
+		case http.MethodDelete:
+			tagging = nil
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer server.Close()
+
+	client, err := NewMinioClient(MinioConf{AK: "ak", SK: "sk", Endpoint: server.URL, Region: "us-east-1"})
+	assert.NoError(t, err)
+
+	ctx := newTestCtx()
+	want := map[string]string{"project": "golib", "owner": "xiangtao"}
+	assert.NoError(t, client.SetObjectTags(ctx, "bucket", "a.txt", want))
+	got, err := client.GetObjectTags(ctx, "bucket", "a.txt")
+	assert.NoError(t, err)
+	assert.Equal(t, want, got)
+
+	assert.NoError(t, client.SetObjectTags(ctx, "bucket", "a.txt", nil))
+	got, err = client.GetObjectTags(ctx, "bucket", "a.txt")
+	assert.NoError(t, err)
+	assert.Empty(t, got)
+}
@@ -0,0 +1,481 @@
+// Package oss -----------------------------
+// @file      : s3.go
+// @author    : xiangtao
+// @contact   : xiangtao1994@gmail.com
+// @time      : 2025/9/2 10:48
+// Description: AWS S3对象存储客户端封装，基于 aws-sdk-go-v2
+// -------------------------------------------
+package oss
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/aws/smithy-go"
+	"github.com/gin-gonic/gin"
+
+	"github.com/xiangtao94/golib/pkg/zlog"
+)
+
+const (
+	defaultS3Region = "us-east-1"
+
+	SSETypeS3  = "AES256"
+	SSETypeKMS = "aws:kms"
+)
+
+type S3Conf struct {
+	Region       string `yaml:"region"`
+	AK           string `yaml:"ak"`
+	SK           string `yaml:"sk"`
+	SessionToken string `yaml:"sessionToken"`
+	// RoleARN 配置后通过STS AssumeRole获取临时凭证，AK/SK作为源凭证
+	RoleARN         string `yaml:"roleARN"`
+	RoleSessionName string `yaml:"roleSessionName"`
+	// Endpoint 可选，覆盖默认的AWS S3地址（如S3兼容存储、VPC Endpoint）
+	Endpoint     string `yaml:"endpoint"`
+	UsePathStyle bool   `yaml:"usePathStyle"`
+	// SSE 服务端加密方式：空表示不加密，AES256（SSE-S3）或 aws:kms（SSE-KMS）
+	SSE      string `yaml:"sse"`
+	KMSKeyID string `yaml:"kmsKeyId"`
+}
+
+// S3Client AWS S3客户端封装
+type S3Client struct {
+	client  *s3.Client
+	presign *s3.PresignClient
+	config  S3Conf
+	// sse 客户端级服务端加密方式，非空时所有写入操作都会带上
+	sse types.ServerSideEncryption
+}
+
+// NewS3Client 创建S3客户端封装
+// 凭证优先级：RoleARN(AssumeRole) > AK/SK > 运行环境默认凭证链（环境变量、IAM角色等）
+func NewS3Client(config S3Conf) (*S3Client, error) {
+	if config.Endpoint != "" {
+		endpointUrl, err := url.Parse(config.Endpoint)
+		if err != nil || endpointUrl.Host == "" {
+			return nil, fmt.Errorf("invalid endpoint: %s", config.Endpoint)
+		}
+	}
+	sse, err := newS3ServerSide(config)
+	if err != nil {
+		return nil, err
+	}
+	if config.Region == "" {
+		config.Region = defaultS3Region
+	}
+
+	loadOpts := []func(*awsconfig.LoadOptions) error{awsconfig.WithRegion(config.Region)}
+	if config.AK != "" {
+		loadOpts = append(loadOpts, awsconfig.WithCredentialsProvider(
+			credentials.NewStaticCredentialsProvider(config.AK, config.SK, config.SessionToken)))
+	}
+	cfg, err := awsconfig.LoadDefaultConfig(context.Background(), loadOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load aws config: %w", err)
+	}
+	if config.RoleARN != "" {
+		sessionName := config.RoleSessionName
+		if sessionName == "" {
+			sessionName = "golib-oss"
+		}
+		provider := stscreds.NewAssumeRoleProvider(sts.NewFromConfig(cfg), config.RoleARN, func(o *stscreds.AssumeRoleOptions) {
+			o.RoleSessionName = sessionName
+		})
+		cfg.Credentials = aws.NewCredentialsCache(provider)
+	}
+
+	client := s3.NewFromConfig(cfg, func(o *s3.Options) {
+		if config.Endpoint != "" {
+			o.BaseEndpoint = aws.String(config.Endpoint)
+		}
+		o.UsePathStyle = config.UsePathStyle
+		// 只在接口要求时计算校验和，S3兼容存储大多不支持 aws-chunked 尾部校验和
+		o.RequestChecksumCalculation = aws.RequestChecksumCalculationWhenRequired
+		o.ResponseChecksumValidation = aws.ResponseChecksumValidationWhenRequired
+	})
+	return &S3Client{
+		client:  client,
+		presign: s3.NewPresignClient(client),
+		config:  config,
+		sse:     sse,
+	}, nil
+}
+
+func newS3ServerSide(config S3Conf) (types.ServerSideEncryption, error) {
+	switch strings.ToLower(config.SSE) {
+	case "":
+		return "", nil
+	case strings.ToLower(SSETypeS3):
+		return types.ServerSideEncryptionAes256, nil
+	case SSETypeKMS:
+		return types.ServerSideEncryptionAwsKms, nil
+	default:
+		return "", fmt.Errorf("unsupported sse type: %s", config.SSE)
+	}
+}
+
+// CreateBucket 创建存储桶，已存在时直接返回
+func (sc *S3Client) CreateBucket(ctx *gin.Context, bucketName string, location string) error {
+	start := time.Now()
+
+	_, err := sc.client.HeadBucket(ctx, &s3.HeadBucketInput{Bucket: aws.String(bucketName)})
+	if err == nil {
+		zlog.Infof(ctx, "bucket %s already exists", bucketName)
+		return nil
+	}
+	if !isS3NotFound(err) {
+		zlog.Errorf(ctx, "failed to check bucket exists: %v", err)
+		return fmt.Errorf("failed to check bucket exists: %w", err)
+	}
+
+	if location == "" {
+		location = sc.config.Region
+	}
+	input := &s3.CreateBucketInput{Bucket: aws.String(bucketName)}
+	// us-east-1 不能指定 LocationConstraint
+	if location != defaultS3Region {
+		input.CreateBucketConfiguration = &types.CreateBucketConfiguration{
+			LocationConstraint: types.BucketLocationConstraint(location),
+		}
+	}
+	if _, err = sc.client.CreateBucket(ctx, input); err != nil {
+		zlog.Errorf(ctx, "failed to create bucket %s: %v", bucketName, err)
+		return fmt.Errorf("failed to create bucket: %w", err)
+	}
+
+	zlog.Infof(ctx, "bucket %s created successfully, cost: %v", bucketName, time.Since(start))
+	return nil
+}
+
+// UploadFile 上传文件，objectSize 小于0且 reader 不可 Seek 时会先读入内存
+func (sc *S3Client) UploadFile(ctx *gin.Context, bucketName, objectName string, reader io.Reader, objectSize int64, opts *UploadOptions) (UploadInfo, error) {
+	start := time.Now()
+
+	if opts == nil {
+		opts = &UploadOptions{}
+	}
+
+	contentType := opts.ContentType
+	if contentType == "" {
+		var err error
+		contentType, reader, err = detectContentType(objectName, reader)
+		if err != nil {
+			zlog.Errorf(ctx, "failed to upload file %s/%s: %v", bucketName, objectName, err)
+			return UploadInfo{}, fmt.Errorf("failed to upload file: %w", err)
+		}
+	}
+	reader, objectSize, err := s3Body(reader, objectSize)
+	if err != nil {
+		zlog.Errorf(ctx, "failed to upload file %s/%s: %v", bucketName, objectName, err)
+		return UploadInfo{}, fmt.Errorf("failed to upload file: %w", err)
+	}
+
+	input := &s3.PutObjectInput{
+		Bucket:        aws.String(bucketName),
+		Key:           aws.String(objectName),
+		Body:          reader,
+		ContentLength: aws.Int64(objectSize),
+		ContentType:   aws.String(contentType),
+		Metadata:      opts.UserMeta,
+	}
+	if len(opts.Tags) > 0 {
+		tags := url.Values{}
+		for k, v := range opts.Tags {
+			tags.Set(k, v)
+		}
+		input.Tagging = aws.String(tags.Encode())
+	}
+	input.ServerSideEncryption, input.SSEKMSKeyId = sc.serverSide(opts)
+
+	var optFns []func(*s3.Options)
+	if _, ok := reader.(io.Seeker); !ok {
+		// 不可 Seek 的 body 无法预先计算 sha256，改为不签名 payload
+		optFns = append(optFns, s3.WithAPIOptions(v4.SwapComputePayloadSHA256ForUnsignedPayloadMiddleware))
+	}
+	output, err := sc.client.PutObject(ctx, input, optFns...)
+	if err != nil {
+		zlog.Errorf(ctx, "failed to upload file %s/%s: %v", bucketName, objectName, err)
+		return UploadInfo{}, fmt.Errorf("failed to upload file: %w", err)
+	}
+
+	uploadInfo := UploadInfo{
+		Bucket:    bucketName,
+		Key:       objectName,
+		ETag:      trimETag(output.ETag),
+		Size:      objectSize,
+		VersionID: aws.ToString(output.VersionId),
+	}
+	zlog.Infof(ctx, "file uploaded successfully: %s/%s, size: %d, etag: %s, cost: %v",
+		bucketName, objectName, uploadInfo.Size, uploadInfo.ETag, time.Since(start))
+
+	return uploadInfo, nil
+}
+
+// s3Body PutObject 需要 Content-Length，未知大小时通过 Seek 计算，不可 Seek 时读入内存
+func s3Body(reader io.Reader, size int64) (io.Reader, int64, error) {
+	if size >= 0 {
+		return reader, size, nil
+	}
+	if seeker, ok := reader.(io.ReadSeeker); ok {
+		offset, err := seeker.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return nil, 0, err
+		}
+		end, err := seeker.Seek(0, io.SeekEnd)
+		if err != nil {
+			return nil, 0, err
+		}
+		if _, err = seeker.Seek(offset, io.SeekStart); err != nil {
+			return nil, 0, err
+		}
+		return reader, end - offset, nil
+	}
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, 0, err
+	}
+	return bytes.NewReader(data), int64(len(data)), nil
+}
+
+// DownloadFile 下载文件
+func (sc *S3Client) DownloadFile(ctx *gin.Context, bucketName, objectName string) (io.ReadCloser, *DownloadInfo, error) {
+	start := time.Now()
+
+	output, err := sc.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(bucketName),
+		Key:    aws.String(objectName),
+	})
+	if err != nil {
+		zlog.Errorf(ctx, "failed to download file %s/%s: %v", bucketName, objectName, err)
+		return nil, nil, fmt.Errorf("failed to download file: %w", err)
+	}
+
+	downloadInfo := &DownloadInfo{
+		ObjectName:   objectName,
+		Size:         aws.ToInt64(output.ContentLength),
+		LastModified: aws.ToTime(output.LastModified),
+		ContentType:  aws.ToString(output.ContentType),
+		ETag:         trimETag(output.ETag),
+	}
+
+	zlog.Infof(ctx, "file download started: %s/%s, size: %d, cost: %v",
+		bucketName, objectName, downloadInfo.Size, time.Since(start))
+
+	return output.Body, downloadInfo, nil
+}
+
+// GetPresignedURL 获取预签名URL，method 为 PUT 时生成上传URL，其余生成下载URL
+func (sc *S3Client) GetPresignedURL(ctx *gin.Context, bucketName, objectName string, expiry time.Duration, method string) (string, error) {
+	start := time.Now()
+
+	if expiry <= 0 {
+		expiry = 24 * time.Hour // 默认24小时
+	}
+
+	var presigned *v4.PresignedHTTPRequest
+	var err error
+	if strings.ToUpper(method) == "PUT" {
+		presigned, err = sc.presign.PresignPutObject(ctx, &s3.PutObjectInput{
+			Bucket: aws.String(bucketName),
+			Key:    aws.String(objectName),
+		}, s3.WithPresignExpires(expiry))
+	} else {
+		presigned, err = sc.presign.PresignGetObject(ctx, &s3.GetObjectInput{
+			Bucket: aws.String(bucketName),
+			Key:    aws.String(objectName),
+		}, s3.WithPresignExpires(expiry))
+	}
+	if err != nil {
+		zlog.Errorf(ctx, "failed to get presigned URL for %s/%s: %v", bucketName, objectName, err)
+		return "", fmt.Errorf("failed to get presigned URL: %w", err)
+	}
+
+	zlog.Infof(ctx, "presigned URL generated: %s/%s, method: %s, expiry: %v, cost: %v",
+		bucketName, objectName, method, expiry, time.Since(start))
+
+	return presigned.URL, nil
+}
+
+// DeleteFile 删除文件
+func (sc *S3Client) DeleteFile(ctx *gin.Context, bucketName, objectName string) error {
+	start := time.Now()
+
+	_, err := sc.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(bucketName),
+		Key:    aws.String(objectName),
+	})
+	if err != nil {
+		zlog.Errorf(ctx, "failed to delete file %s/%s: %v", bucketName, objectName, err)
+		return fmt.Errorf("failed to delete file: %w", err)
+	}
+
+	zlog.Infof(ctx, "file deleted successfully: %s/%s, cost: %v",
+		bucketName, objectName, time.Since(start))
+
+	return nil
+}
+
+// ListObjects 列出对象，recursive 为 false 时下一级目录以 "dir/" 形式返回
+func (sc *S3Client) ListObjects(ctx *gin.Context, bucketName, prefix string, recursive bool) ([]ObjectInfo, error) {
+	start := time.Now()
+
+	input := &s3.ListObjectsV2Input{
+		Bucket: aws.String(bucketName),
+		Prefix: aws.String(prefix),
+	}
+	if !recursive {
+		input.Delimiter = aws.String("/")
+	}
+
+	var objects []ObjectInfo
+	paginator := s3.NewListObjectsV2Paginator(sc.client, input)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			zlog.Errorf(ctx, "error listing objects in bucket %s: %v", bucketName, err)
+			return nil, fmt.Errorf("error listing objects: %w", err)
+		}
+		for _, object := range page.Contents {
+			objects = append(objects, ObjectInfo{
+				Key:          aws.ToString(object.Key),
+				Size:         aws.ToInt64(object.Size),
+				ETag:         trimETag(object.ETag),
+				LastModified: aws.ToTime(object.LastModified),
+				StorageClass: string(object.StorageClass),
+			})
+		}
+		for _, commonPrefix := range page.CommonPrefixes {
+			objects = append(objects, ObjectInfo{Key: aws.ToString(commonPrefix.Prefix)})
+		}
+	}
+
+	zlog.Infof(ctx, "listed %d objects in bucket %s with prefix %s, cost: %v",
+		len(objects), bucketName, prefix, time.Since(start))
+
+	return objects, nil
+}
+
+// ObjectExists 检查对象是否存在
+func (sc *S3Client) ObjectExists(ctx *gin.Context, bucketName, objectName string) (bool, error) {
+	start := time.Now()
+
+	_, err := sc.headObject(ctx, bucketName, objectName)
+	if err != nil {
+		if isS3NotFound(err) {
+			zlog.Infof(ctx, "object %s/%s does not exist, cost: %v", bucketName, objectName, time.Since(start))
+			return false, nil
+		}
+		zlog.Errorf(ctx, "failed to check object existence %s/%s: %v", bucketName, objectName, err)
+		return false, fmt.Errorf("failed to check object existence: %w", err)
+	}
+
+	zlog.Infof(ctx, "object %s/%s exists, cost: %v", bucketName, objectName, time.Since(start))
+	return true, nil
+}
+
+// GetObjectInfo 获取对象信息
+func (sc *S3Client) GetObjectInfo(ctx *gin.Context, bucketName, objectName string) (*DownloadInfo, error) {
+	start := time.Now()
+
+	output, err := sc.headObject(ctx, bucketName, objectName)
+	if err != nil {
+		zlog.Errorf(ctx, "failed to get object info %s/%s: %v", bucketName, objectName, err)
+		return nil, fmt.Errorf("failed to get object info: %w", err)
+	}
+
+	downloadInfo := &DownloadInfo{
+		ObjectName:   objectName,
+		Size:         aws.ToInt64(output.ContentLength),
+		LastModified: aws.ToTime(output.LastModified),
+		ContentType:  aws.ToString(output.ContentType),
+		ETag:         trimETag(output.ETag),
+	}
+
+	zlog.Infof(ctx, "got object info: %s/%s, size: %d, cost: %v",
+		bucketName, objectName, downloadInfo.Size, time.Since(start))
+
+	return downloadInfo, nil
+}
+
+func (sc *S3Client) headObject(ctx *gin.Context, bucketName, objectName string) (*s3.HeadObjectOutput, error) {
+	return sc.client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(bucketName),
+		Key:    aws.String(objectName),
+	})
+}
+
+// CopyObject 复制对象，客户端配置了 SSE 时目标对象同样加密
+func (sc *S3Client) CopyObject(ctx *gin.Context, srcBucket, srcObject, destBucket, destObject string) error {
+	start := time.Now()
+
+	input := &s3.CopyObjectInput{
+		Bucket:     aws.String(destBucket),
+		Key:        aws.String(destObject),
+		CopySource: aws.String(url.PathEscape(srcBucket + "/" + srcObject)),
+	}
+	input.ServerSideEncryption, input.SSEKMSKeyId = sc.serverSide(nil)
+	if _, err := sc.client.CopyObject(ctx, input); err != nil {
+		zlog.Errorf(ctx, "failed to copy object %s/%s to %s/%s: %v", srcBucket, srcObject, destBucket, destObject, err)
+		return fmt.Errorf("failed to copy object: %w", err)
+	}
+
+	zlog.Infof(ctx, "object copied successfully: %s/%s -> %s/%s, cost: %v",
+		srcBucket, srcObject, destBucket, destObject, time.Since(start))
+
+	return nil
+}
+
+// Close S3客户端不需要显式关闭连接，保持接口一致
+func (sc *S3Client) Close() {}
+
+// serverSide 获取写入时使用的服务端加密方式，客户端级配置优先于单次上传选项
+func (sc *S3Client) serverSide(opts *UploadOptions) (types.ServerSideEncryption, *string) {
+	switch {
+	case sc.sse == types.ServerSideEncryptionAwsKms:
+		var keyID *string
+		if sc.config.KMSKeyID != "" {
+			keyID = aws.String(sc.config.KMSKeyID)
+		}
+		return sc.sse, keyID
+	case sc.sse != "":
+		return sc.sse, nil
+	case opts != nil && opts.ServerSide:
+		return types.ServerSideEncryptionAes256, nil
+	default:
+		return "", nil
+	}
+}
+
+// trimETag S3 返回的 ETag 带引号，与 MinioClient 保持一致去掉引号
+func trimETag(etag *string) string {
+	return strings.Trim(aws.ToString(etag), `"`)
+}
+
+// isS3NotFound HeadObject/HeadBucket 返回 NotFound，GetObject 返回 NoSuchKey
+func isS3NotFound(err error) bool {
+	var apiErr smithy.APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	switch apiErr.ErrorCode() {
+	case "NotFound", "NoSuchKey":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,228 @@
+package oss
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/xiangtao94/golib/pkg/zlog"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+	zlog.InitLog(zlog.LogConfig{})
+}
+
+// stubS3Server 模拟S3协议的最小服务端，记录收到的请求头
+type stubS3Server struct {
+	*httptest.Server
+	mu      sync.Mutex
+	headers []http.Header
+	body    []byte // 最后一次 PUT 的请求体
+}
+
+func newStubS3Server() *stubS3Server {
+	s := &stubS3Server{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.headers = append(s.headers, r.Header.Clone())
+		s.mu.Unlock()
+		switch r.Method {
+		case http.MethodHead:
+			w.Header().Set("Content-Type", "text/plain")
+			w.Header().Set("Content-Length", "11")
+			w.Header().Set("ETag", `"d41d8cd98f00b204e9800998ecf8427e"`)
+			w.Header().Set("Last-Modified", "Mon, 01 Sep 2025 10:00:00 GMT")
+			w.WriteHeader(http.StatusOK)
+		case http.MethodGet:
+			if r.URL.Query().Get("list-type") == "2" {
+				w.Header().Set("Content-Type", "application/xml")
+				_, _ = io.WriteString(w, stubListResult)
+				return
+			}
+			w.Header().Set("Content-Type", "text/plain")
+			w.Header().Set("ETag", `"d41d8cd98f00b204e9800998ecf8427e"`)
+			w.Header().Set("Last-Modified", "Mon, 01 Sep 2025 10:00:00 GMT")
+			_, _ = io.WriteString(w, "hello world")
+		case http.MethodPut:
+			body, _ := io.ReadAll(r.Body)
+			s.mu.Lock()
+			s.body = body
+			s.mu.Unlock()
+			w.Header().Set("ETag", `"d41d8cd98f00b204e9800998ecf8427e"`)
+			w.WriteHeader(http.StatusOK)
+		default:
+			w.WriteHeader(http.StatusNotImplemented)
+		}
+	}))
+	return s
+}
+
+// stubListResult 非递归列举 dir/ 的结果：一个对象和一个子目录
+const stubListResult = `<?xml version="1.0" encoding="UTF-8"?>
+<ListBucketResult xmlns="http://s3.amazonaws.com/doc/2006-03-01/">
+<Name>bucket</Name><Prefix>dir/</Prefix><Delimiter>/</Delimiter><KeyCount>2</KeyCount><MaxKeys>1000</MaxKeys><IsTruncated>false</IsTruncated>
+<Contents><Key>dir/a.txt</Key><LastModified>2025-09-01T10:00:00.000Z</LastModified><ETag>"d41d8cd98f00b204e9800998ecf8427e"</ETag><Size>11</Size><StorageClass>STANDARD</StorageClass></Contents>
+<CommonPrefixes><Prefix>dir/sub/</Prefix></CommonPrefixes>
+</ListBucketResult>`
+
+func (s *stubS3Server) lastHeader() http.Header {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.headers) == 0 {
+		return nil
+	}
+	return s.headers[len(s.headers)-1]
+}
+
+func newTestStorages(t *testing.T, endpoint string, sse string) map[string]ObjectStorage {
+	m, err := New(OssConf{Provider: ProviderMinio, Minio: MinioConf{AK: "ak", SK: "sk", Endpoint: endpoint, Region: "us-east-1"}})
+	assert.NoError(t, err)
+	s, err := New(OssConf{Provider: ProviderS3, S3: S3Conf{AK: "ak", SK: "sk", Endpoint: endpoint, Region: "us-east-1", UsePathStyle: true, SSE: sse, KMSKeyID: "key-id"}})
+	assert.NoError(t, err)
+	return map[string]ObjectStorage{ProviderMinio: m, ProviderS3: s}
+}
+
+func newTestCtx() *gin.Context {
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	ctx.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+	return ctx
+}
+
+func TestNew_UnsupportedProvider(t *testing.T) {
+	_, err := New(OssConf{Provider: "ftp"})
+	assert.Error(t, err)
+}
+
+func TestObjectStorage_PresignedURL(t *testing.T) {
+	server := newStubS3Server()
+	defer server.Close()
+
+	for name, storage := range newTestStorages(t, server.URL, "") {
+		t.Run(name, func(t *testing.T) {
+			u, err := storage.GetPresignedURL(newTestCtx(), "bucket", "dir/a.txt", time.Hour, "GET")
+			assert.NoError(t, err)
+			assert.True(t, strings.HasPrefix(u, server.URL+"/bucket/dir/a.txt?"), u)
+			assert.Contains(t, u, "X-Amz-Signature=")
+			assert.Contains(t, u, "X-Amz-Expires=3600")
+		})
+	}
+}
+
+func TestObjectStorage_GetObjectInfo(t *testing.T) {
+	server := newStubS3Server()
+	defer server.Close()
+
+	for name, storage := range newTestStorages(t, server.URL, "") {
+		t.Run(name, func(t *testing.T) {
+			info, err := storage.GetObjectInfo(newTestCtx(), "bucket", "a.txt")
+			assert.NoError(t, err)
+			assert.Equal(t, "a.txt", info.ObjectName)
+			assert.Equal(t, int64(11), info.Size)
+			assert.Equal(t, "text/plain", info.ContentType)
+			assert.Equal(t, "d41d8cd98f00b204e9800998ecf8427e", info.ETag)
+		})
+	}
+}
+
+func TestObjectStorage_DownloadAndList(t *testing.T) {
+	server := newStubS3Server()
+	defer server.Close()
+
+	lastModified := time.Date(2025, 9, 1, 10, 0, 0, 0, time.UTC)
+	for name, storage := range newTestStorages(t, server.URL, "") {
+		t.Run(name, func(t *testing.T) {
+			reader, info, err := storage.DownloadFile(newTestCtx(), "bucket", "a.txt")
+			assert.NoError(t, err)
+			body, _ := io.ReadAll(reader)
+			_ = reader.Close()
+			assert.Equal(t, "hello world", string(body))
+			assert.Equal(t, int64(11), info.Size)
+			assert.Equal(t, "d41d8cd98f00b204e9800998ecf8427e", info.ETag)
+			assert.True(t, lastModified.Equal(info.LastModified))
+
+			objects, err := storage.ListObjects(newTestCtx(), "bucket", "dir/", false)
+			assert.NoError(t, err)
+			assert.Equal(t, []ObjectInfo{
+				{Key: "dir/a.txt", Size: 11, ETag: "d41d8cd98f00b204e9800998ecf8427e", LastModified: lastModified, StorageClass: "STANDARD"},
+				{Key: "dir/sub/"},
+			}, objects)
+
+			uploaded, err := storage.UploadFile(newTestCtx(), "bucket", "b.txt", strings.NewReader("hello world"), 11, nil)
+			assert.NoError(t, err)
+			assert.Equal(t, UploadInfo{Bucket: "bucket", Key: "b.txt", ETag: "d41d8cd98f00b204e9800998ecf8427e", Size: 11}, uploaded)
+		})
+	}
+}
+
+func TestS3Client_UploadWithSSEKMS(t *testing.T) {
+	server := newStubS3Server()
+	defer server.Close()
+
+	storage := newTestStorages(t, server.URL, SSETypeKMS)[ProviderS3]
+	content := []byte("hello world")
+	_, err := storage.UploadFile(newTestCtx(), "bucket", "a.txt", bytes.NewReader(content), int64(len(content)), nil)
+	assert.NoError(t, err)
+	h := server.lastHeader()
+	assert.Equal(t, SSETypeKMS, h.Get("X-Amz-Server-Side-Encryption"))
+	assert.Equal(t, "key-id", h.Get("X-Amz-Server-Side-Encryption-Aws-Kms-Key-Id"))
+}
+
+func TestObjectStorage_UploadSniffContentType(t *testing.T) {
+	server := newStubS3Server()
+	defer server.Close()
+
+	png := append([]byte("\x89PNG\r\n\x1a\n"), bytes.Repeat([]byte{0}, 600)...)
+	readers := map[string]func() io.Reader{
+		"seekable":     func() io.Reader { return bytes.NewReader(png) },
+		"non-seekable": func() io.Reader { return io.MultiReader(bytes.NewReader(png)) },
+	}
+	for backend, storage := range newTestStorages(t, server.URL, "") {
+		for name, newReader := range readers {
+			t.Run(backend+"/"+name, func(t *testing.T) {
+				// 无扩展名和扩展名错误时按内容判断
+				for _, objectName := range []string{"avatar", "avatar.bin"} {
+					_, err := storage.UploadFile(newTestCtx(), "bucket", objectName, newReader(), int64(len(png)), nil)
+					assert.NoError(t, err)
+					assert.Equal(t, "image/png", server.lastHeader().Get("Content-Type"))
+					server.mu.Lock()
+					assert.True(t, bytes.Contains(server.body, png[:520]), "body should contain the sniffed bytes")
+					server.mu.Unlock()
+				}
+			})
+		}
+
+		// 扩展名可识别或显式指定时不读取内容
+		_, err := storage.UploadFile(newTestCtx(), "bucket", "a.json", bytes.NewReader(png), int64(len(png)), nil)
+		assert.NoError(t, err)
+		assert.Equal(t, "application/json", server.lastHeader().Get("Content-Type"))
+		_, err = storage.UploadFile(newTestCtx(), "bucket", "avatar", bytes.NewReader(png), int64(len(png)), &UploadOptions{ContentType: "image/webp"})
+		assert.NoError(t, err)
+		assert.Equal(t, "image/webp", server.lastHeader().Get("Content-Type"))
+	}
+}
+
+func TestDetectContentType(t *testing.T) {
+	reader := strings.NewReader("prefix<html><body>hi</body></html>")
+	_, _ = reader.Seek(6, io.SeekStart)
+	contentType, got, err := detectContentType("page", reader)
+	assert.NoError(t, err)
+	assert.Equal(t, "text/html; charset=utf-8", contentType)
+	// 可 Seek 时回到读取前的位置
+	body, _ := io.ReadAll(got)
+	assert.Equal(t, "<html><body>hi</body></html>", string(body))
+
+	contentType, got, err = detectContentType("empty", io.MultiReader())
+	assert.NoError(t, err)
+	assert.Equal(t, defaultContentType, contentType)
+	body, _ = io.ReadAll(got)
+	assert.Empty(t, body)
+}
@@ -0,0 +1,49 @@
+// Package redis -----------------------------
+// @file      : json.go
+// @author    : xiangtao
+// @contact   : xiangtao1994@gmail.com
+// @time      : 2025/9/9 10:12
+// Description: JSON 序列化读写缓存
+// -------------------------------------------
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// SetJSON 将 v 序列化为 JSON 后写入 key
+// expire 为过期秒数，可使用 EXPIRE_TIME_* 常量，不传表示永不过期
+func (r *Redis) SetJSON(ctx context.Context, key string, v any, expire ...int64) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("redis set json marshal error: %w", err)
+	}
+	var ttl time.Duration
+	if len(expire) > 0 && expire[0] > 0 {
+		ttl = time.Duration(expire[0]) * time.Second
+	}
+	return r.Set(ctx, key, data, ttl).Err()
+}
+
+// GetJSON 读取 key 并反序列化为 T，key 不存在时返回 found=false 且 err 为 nil
+// Go 方法不支持类型参数，因此以包函数提供
+func GetJSON[T any](ctx context.Context, r *Redis, key string) (*T, bool, error) {
+	data, err := r.Get(ctx, key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	v := new(T)
+	if err := json.Unmarshal(data, v); err != nil {
+		return nil, false, fmt.Errorf("redis get json unmarshal error: %w", err)
+	}
+	return v, true, nil
+}
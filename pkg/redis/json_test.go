@@ -0,0 +1,59 @@
+package redis
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type jsonUser struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+func TestSetGetJSON(t *testing.T) {
+	r, mr := newTestRedis(t)
+	ctx := context.Background()
+
+	require.NoError(t, r.SetJSON(ctx, "user:1", jsonUser{ID: 1, Name: "tom"}, EXPIRE_TIME_1_MINUTE))
+	v, err := mr.Get("user:1")
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"id":1,"name":"tom"}`, v)
+	assert.Equal(t, time.Minute, mr.TTL("user:1"))
+
+	user, found, err := GetJSON[jsonUser](ctx, r, "user:1")
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, &jsonUser{ID: 1, Name: "tom"}, user)
+
+	// 不传过期时间时永不过期
+	require.NoError(t, r.SetJSON(ctx, "user:2", jsonUser{ID: 2}))
+	assert.Zero(t, mr.TTL("user:2"))
+
+	// 过期后按不存在处理
+	mr.FastForward(time.Minute)
+	_, found, err = GetJSON[jsonUser](ctx, r, "user:1")
+	require.NoError(t, err)
+	assert.False(t, found)
+}
+
+func TestGetJSONErrors(t *testing.T) {
+	r, _ := newTestRedis(t)
+	ctx := context.Background()
+
+	// key 不存在不视为错误
+	user, found, err := GetJSON[jsonUser](ctx, r, "missing")
+	require.NoError(t, err)
+	assert.False(t, found)
+	assert.Nil(t, user)
+
+	require.NoError(t, r.Set(ctx, "broken", "{", 0).Err())
+	_, found, err = GetJSON[jsonUser](ctx, r, "broken")
+	assert.ErrorContains(t, err, "unmarshal")
+	assert.False(t, found)
+
+	assert.ErrorContains(t, r.SetJSON(ctx, "chan", make(chan int)), "marshal")
+}
@@ -0,0 +1,172 @@
+// Package redis -----------------------------
+// @file      : lock.go
+// @author    : xiangtao
+// @contact   : xiangtao1994@gmail.com
+// @time      : 2025/9/8 14:26
+// Description: 基于 SET NX PX 的分布式锁，支持看门狗自动续期
+// -------------------------------------------
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/xiangtao94/golib/pkg/zlog"
+)
+
+var (
+	ErrLockNotAcquired = errors.New("redis lock not acquired")
+	ErrLockNotHeld     = errors.New("redis lock not held")
+)
+
+// 仅当token一致时删除，避免误删他人持有的锁
+var releaseScript = redis.NewScript(`
+if redis.call("get", KEYS[1]) == ARGV[1] then
+	return redis.call("del", KEYS[1])
+else
+	return 0
+end`)
+
+// 仅当token一致时续期
+var refreshScript = redis.NewScript(`
+if redis.call("get", KEYS[1]) == ARGV[1] then
+	return redis.call("pexpire", KEYS[1], ARGV[2])
+else
+	return 0
+end`)
+
+type Lock struct {
+	r     *Redis
+	key   string
+	token string
+	ttl   time.Duration
+
+	watchdog bool
+	stop     chan struct{}
+	stopOnce sync.Once
+	wg       sync.WaitGroup
+	lost     chan struct{}
+	lostOnce sync.Once
+}
+
+type LockOption func(*Lock)
+
+// WithWatchdog 持有锁期间每 ttl/3 自动续期一次，直到 Release
+func WithWatchdog() LockOption {
+	return func(l *Lock) {
+		l.watchdog = true
+	}
+}
+
+// AcquireLock 尝试获取分布式锁，key 会自动带上 GetKeyPrefix() 前缀
+// 锁已被其他实例持有时返回 ErrLockNotAcquired
+func (r *Redis) AcquireLock(ctx context.Context, key string, ttl time.Duration, opts ...LockOption) (*Lock, error) {
+	if ttl <= 0 {
+		return nil, fmt.Errorf("redis lock ttl must be positive")
+	}
+	l := &Lock{
+		r:     r,
+		key:   GetKeyPrefix() + "lock:" + key,
+		token: uuid.NewString(),
+		ttl:   ttl,
+		stop:  make(chan struct{}),
+		lost:  make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+
+	ok, err := r.SetNX(ctx, l.key, l.token, ttl).Result()
+	if err != nil {
+		return nil, fmt.Errorf("redis lock acquire error: %w", err)
+	}
+	if !ok {
+		return nil, ErrLockNotAcquired
+	}
+
+	if l.watchdog {
+		l.wg.Add(1)
+		go l.renew()
+	}
+	return l, nil
+}
+
+// Key 返回带前缀的锁key
+func (l *Lock) Key() string {
+	return l.key
+}
+
+// Lost 在看门狗或 Refresh 发现锁已过期、被他人持有时关闭，持有者应据此停止临界区内的操作
+// 未开启看门狗时只有 Refresh 会关闭该 channel；Release 不会关闭
+func (l *Lock) Lost() <-chan struct{} {
+	return l.lost
+}
+
+// Refresh 手动续期锁，锁已过期或被他人持有时返回 ErrLockNotHeld
+func (l *Lock) Refresh(ctx context.Context) error {
+	res, err := refreshScript.Run(ctx, l.r, []string{l.key}, l.token, l.ttl.Milliseconds()).Int64()
+	if err != nil {
+		return fmt.Errorf("redis lock refresh error: %w", err)
+	}
+	if res == 0 {
+		l.lostOnce.Do(func() {
+			close(l.lost)
+		})
+		return ErrLockNotHeld
+	}
+	return nil
+}
+
+// Release 释放锁并停止看门狗，锁已过期或被他人持有时返回 ErrLockNotHeld
+func (l *Lock) Release(ctx context.Context) error {
+	l.stopOnce.Do(func() {
+		close(l.stop)
+	})
+	l.wg.Wait()
+
+	res, err := releaseScript.Run(ctx, l.r, []string{l.key}, l.token).Int64()
+	if err != nil {
+		return fmt.Errorf("redis lock release error: %w", err)
+	}
+	if res == 0 {
+		return ErrLockNotHeld
+	}
+	return nil
+}
+
+func (l *Lock) renew() {
+	defer l.wg.Done()
+
+	interval := l.ttl / 3
+	if interval <= 0 {
+		interval = l.ttl
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-ticker.C:
+			// 看门狗独立于请求上下文运行，请求结束后仍需续期
+			ctx, cancel := context.WithTimeout(context.Background(), interval)
+			err := l.Refresh(ctx)
+			cancel()
+			if errors.Is(err, ErrLockNotHeld) {
+				// Refresh 已关闭 Lost()
+				zlog.Warnf(nil, "redis lock %s lost, watchdog stopped", l.key)
+				return
+			}
+			if err != nil {
+				zlog.Warnf(nil, "redis lock %s refresh failed: %v", l.key, err)
+			}
+		}
+	}
+}
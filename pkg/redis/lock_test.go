@@ -0,0 +1,143 @@
+package redis
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestRedis(t *testing.T) (*Redis, *miniredis.Miniredis) {
+	mr := miniredis.RunT(t)
+	client := redis.NewUniversalClient(&redis.UniversalOptions{Addrs: []string{mr.Addr()}})
+	t.Cleanup(func() { _ = client.Close() })
+	return &Redis{UniversalClient: client}, mr
+}
+
+func TestAcquireLock(t *testing.T) {
+	r, mr := newTestRedis(t)
+	ctx := context.Background()
+
+	lock, err := r.AcquireLock(ctx, "order:1", time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, GetKeyPrefix()+"lock:order:1", lock.Key())
+	assert.Equal(t, time.Minute, mr.TTL(lock.Key()))
+
+	// 锁被持有时其他实例获取失败，不影响其他key
+	_, err = r.AcquireLock(ctx, "order:1", time.Minute)
+	assert.ErrorIs(t, err, ErrLockNotAcquired)
+	other, err := r.AcquireLock(ctx, "order:2", time.Minute)
+	require.NoError(t, err)
+	require.NoError(t, other.Release(ctx))
+
+	require.NoError(t, lock.Release(ctx))
+	assert.False(t, mr.Exists(lock.Key()))
+	assert.ErrorIs(t, lock.Release(ctx), ErrLockNotHeld)
+
+	// 释放后可以再次获取
+	lock, err = r.AcquireLock(ctx, "order:1", time.Minute)
+	require.NoError(t, err)
+	require.NoError(t, lock.Release(ctx))
+
+	_, err = r.AcquireLock(ctx, "order:1", 0)
+	assert.Error(t, err)
+}
+
+func TestLockRefresh(t *testing.T) {
+	r, mr := newTestRedis(t)
+	ctx := context.Background()
+
+	lock, err := r.AcquireLock(ctx, "job", time.Second)
+	require.NoError(t, err)
+	mr.FastForward(800 * time.Millisecond)
+	require.NoError(t, lock.Refresh(ctx))
+	assert.Equal(t, time.Second, mr.TTL(lock.Key()))
+
+	select {
+	case <-lock.Lost():
+		t.Fatal("lock lost before expiry")
+	default:
+	}
+
+	mr.FastForward(time.Second)
+	assert.ErrorIs(t, lock.Refresh(ctx), ErrLockNotHeld)
+	select {
+	case <-lock.Lost():
+	default:
+		t.Fatal("Lost not closed after refresh failed")
+	}
+}
+
+func TestLockReleaseNotHeld(t *testing.T) {
+	r, mr := newTestRedis(t)
+	ctx := context.Background()
+
+	expired, err := r.AcquireLock(ctx, "job", 50*time.Millisecond)
+	require.NoError(t, err)
+	mr.FastForward(time.Second)
+
+	// 过期后被其他实例获取，旧锁的 token 不一致，Release/Refresh 不影响新锁
+	holder, err := r.AcquireLock(ctx, "job", time.Minute)
+	require.NoError(t, err)
+	assert.ErrorIs(t, expired.Refresh(ctx), ErrLockNotHeld)
+	assert.ErrorIs(t, expired.Release(ctx), ErrLockNotHeld)
+	v, err := mr.Get(holder.Key())
+	require.NoError(t, err)
+	assert.Equal(t, holder.token, v)
+	assert.Equal(t, time.Minute, mr.TTL(holder.Key()))
+	require.NoError(t, holder.Release(ctx))
+}
+
+func TestLockWatchdog(t *testing.T) {
+	r, mr := newTestRedis(t)
+	ctx := context.Background()
+
+	lock, err := r.AcquireLock(ctx, "job", 90*time.Millisecond, WithWatchdog())
+	require.NoError(t, err)
+	lost := lock.Lost()
+
+	// 看门狗每 ttl/3 把过期时间续回 ttl
+	mr.SetTTL(lock.Key(), time.Millisecond)
+	assert.Eventually(t, func() bool {
+		return mr.TTL(lock.Key()) == 90*time.Millisecond
+	}, time.Second, 5*time.Millisecond)
+
+	// Release 后看门狗停止续期
+	require.NoError(t, lock.Release(ctx))
+	assert.False(t, mr.Exists(lock.Key()))
+	require.NoError(t, mr.Set(lock.Key(), lock.token))
+	mr.SetTTL(lock.Key(), time.Millisecond)
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, time.Millisecond, mr.TTL(lock.Key()))
+	// 正常释放不视为丢失
+	select {
+	case <-lost:
+		t.Fatal("Lost closed by Release")
+	default:
+	}
+}
+
+func TestLockWatchdogStopsWhenLost(t *testing.T) {
+	r, mr := newTestRedis(t)
+	ctx := context.Background()
+
+	lock, err := r.AcquireLock(ctx, "job", 60*time.Millisecond, WithWatchdog())
+	require.NoError(t, err)
+	mr.Del(lock.Key())
+
+	// 续期发现锁丢失后关闭 Lost() 并退出，之后不再续期
+	select {
+	case <-lock.Lost():
+	case <-time.After(time.Second):
+		t.Fatal("Lost not closed after the lock was deleted")
+	}
+	require.NoError(t, mr.Set(lock.Key(), lock.token))
+	mr.SetTTL(lock.Key(), time.Millisecond)
+	time.Sleep(60 * time.Millisecond)
+	assert.Equal(t, time.Millisecond, mr.TTL(lock.Key()))
+	require.NoError(t, lock.Release(ctx))
+}
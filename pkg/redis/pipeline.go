@@ -0,0 +1,42 @@
+// Package redis -----------------------------
+// @file      : pipeline.go
+// @author    : xiangtao
+// @contact   : xiangtao1994@gmail.com
+// @time      : 2025/9/8 17:03
+// Description: 管道与事务封装，命令经过带日志hook的客户端执行
+// -------------------------------------------
+package redis
+
+import (
+	"context"
+	"errors"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Pipelined 在管道中批量执行 fn 中的命令，命令与耗时会通过 ProcessPipelineHook 记录日志
+// ctx 建议传入 *gin.Context，日志中才会带上 requestId
+// 与 go-redis 原生实现不同，key 不存在（redis.Nil）不视为错误，调用方通过各 cmd 自行判断
+func (r *Redis) Pipelined(ctx context.Context, fn func(p redis.Pipeliner) error) ([]redis.Cmder, error) {
+	cmds, err := r.UniversalClient.Pipelined(ctx, fn)
+	return cmds, pipelineError(cmds, err)
+}
+
+// TxPipelined 与 Pipelined 相同，但命令包裹在 MULTI/EXEC 中原子执行
+func (r *Redis) TxPipelined(ctx context.Context, fn func(p redis.Pipeliner) error) ([]redis.Cmder, error) {
+	cmds, err := r.UniversalClient.TxPipelined(ctx, fn)
+	return cmds, pipelineError(cmds, err)
+}
+
+// pipelineError 过滤掉 redis.Nil，返回第一个真实错误
+func pipelineError(cmds []redis.Cmder, err error) error {
+	if err == nil || !errors.Is(err, redis.Nil) {
+		return err
+	}
+	for _, cmd := range cmds {
+		if e := cmd.Err(); e != nil && !errors.Is(e, redis.Nil) {
+			return e
+		}
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package redis
+
+import (
+	"context"
+	"testing"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPipelined(t *testing.T) {
+	r, mr := newTestRedis(t)
+	ctx := context.Background()
+
+	var set *redis.StatusCmd
+	var missing *redis.StringCmd
+	cmds, err := r.Pipelined(ctx, func(p redis.Pipeliner) error {
+		set = p.Set(ctx, "a", "1", 0)
+		missing = p.Get(ctx, "missing")
+		return nil
+	})
+	// key 不存在不视为错误，由调用方通过 cmd 判断
+	require.NoError(t, err)
+	assert.Len(t, cmds, 2)
+	assert.Equal(t, "OK", set.Val())
+	assert.ErrorIs(t, missing.Err(), redis.Nil)
+	mr.CheckGet(t, "a", "1")
+
+	// redis.Nil 之后的真实错误仍然返回
+	_, err = r.Pipelined(ctx, func(p redis.Pipeliner) error {
+		p.Get(ctx, "missing")
+		p.Incr(ctx, "b")
+		p.Do(ctx, "bogus")
+		return nil
+	})
+	assert.ErrorContains(t, err, "unknown command")
+	mr.CheckGet(t, "b", "1")
+}
+
+func TestTxPipelined(t *testing.T) {
+	r, _ := newTestRedis(t)
+	ctx := context.Background()
+
+	var incr *redis.IntCmd
+	var missing *redis.StringCmd
+	_, err := r.TxPipelined(ctx, func(p redis.Pipeliner) error {
+		p.Incr(ctx, "counter")
+		incr = p.Incr(ctx, "counter")
+		missing = p.Get(ctx, "missing")
+		return nil
+	})
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, incr.Val())
+	assert.ErrorIs(t, missing.Err(), redis.Nil)
+
+	_, err = r.TxPipelined(ctx, func(p redis.Pipeliner) error {
+		p.Set(ctx, "name", "tom", 0)
+		p.Incr(ctx, "name")
+		return nil
+	})
+	assert.ErrorContains(t, err, "not an integer")
+}
@@ -161,12 +161,20 @@ func (r *redisLogger) ProcessPipelineHook(hook redis.ProcessPipelineHook) redis.
 
 func (r *redisLogger) commonFields(ctx context.Context) []zlog.Field {
 	var requestID string
+	var ctxFields []zlog.Field
 	if c, ok := ctx.(*gin.Context); ok && c != nil {
 		requestID, _ = ctx.Value(zlog.ContextKeyRequestID).(string)
+		ctxFields = zlog.GetContextFields(c)
 	}
-	return []zlog.Field{
+	fields := []zlog.Field{
 		zlog.String("requestId", requestID),
 	}
+	return append(fields, ctxFields...)
+}
+
+// HealthCheck 通过 PING 检查 redis 连接，可用于就绪探针（golib.RegisterHealthChecker）
+func (r *Redis) HealthCheck(ctx context.Context) error {
+	return r.Ping(ctx).Err()
 }
 
 func (r *Redis) Clear() error {
@@ -0,0 +1,16 @@
+package redis
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHealthCheck(t *testing.T) {
+	r, mr := newTestRedis(t)
+	assert.NoError(t, r.HealthCheck(context.Background()))
+
+	mr.Close()
+	assert.Error(t, r.HealthCheck(context.Background()))
+}
@@ -0,0 +1,89 @@
+// Package redis -----------------------------
+// @file      : scan.go
+// @author    : xiangtao
+// @contact   : xiangtao1994@gmail.com
+// @time      : 2025/9/9 17:40
+// Description: 基于 SCAN 的 key 遍历，替代会阻塞 redis 的 KEYS
+// -------------------------------------------
+package redis
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const defaultScanCount = 100
+
+type scanOptions struct {
+	withPrefix bool
+}
+
+type ScanOption func(*scanOptions)
+
+// WithScanKeyPrefix 在 pattern 前自动拼接 GetKeyPrefix()，只遍历本应用的key
+func WithScanKeyPrefix() ScanOption {
+	return func(o *scanOptions) {
+		o.withPrefix = true
+	}
+}
+
+// ScanKeys 使用 SCAN 游标遍历匹配 pattern 的 key，每批调用一次 fn，fn 返回错误时终止遍历
+// count 为每次 SCAN 的建议数量，<=0 时默认100；集群模式下逐个遍历所有 master 节点，
+// fn 不会被并发调用，返回错误后不再遍历剩余节点
+// SCAN 可能返回重复的 key，fn 需要保证幂等
+func (r *Redis) ScanKeys(ctx context.Context, pattern string, count int64, fn func(keys []string) error, opts ...ScanOption) error {
+	o := &scanOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.withPrefix {
+		pattern = GetKeyPrefix() + pattern
+	}
+	if count <= 0 {
+		count = defaultScanCount
+	}
+
+	if cluster, ok := r.UniversalClient.(*redis.ClusterClient); ok {
+		// ForEachMaster 并发回调，只用来收集节点，再按顺序遍历
+		var mu sync.Mutex
+		var masters []*redis.Client
+		err := cluster.ForEachMaster(ctx, func(ctx context.Context, node *redis.Client) error {
+			mu.Lock()
+			masters = append(masters, node)
+			mu.Unlock()
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("redis scan error: %w", err)
+		}
+		for _, node := range masters {
+			if err := scanNode(ctx, node, pattern, count, fn); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return scanNode(ctx, r.UniversalClient, pattern, count, fn)
+}
+
+func scanNode(ctx context.Context, c redis.Cmdable, pattern string, count int64, fn func(keys []string) error) error {
+	var cursor uint64
+	for {
+		keys, next, err := c.Scan(ctx, cursor, pattern, count).Result()
+		if err != nil {
+			return fmt.Errorf("redis scan error: %w", err)
+		}
+		if len(keys) > 0 {
+			if err := fn(keys); err != nil {
+				return err
+			}
+		}
+		if next == 0 {
+			return nil
+		}
+		cursor = next
+	}
+}
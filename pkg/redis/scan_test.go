@@ -0,0 +1,121 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScanKeys(t *testing.T) {
+	r, _ := newTestRedis(t)
+	ctx := context.Background()
+	for i := 0; i < 5; i++ {
+		require.NoError(t, r.Set(ctx, fmt.Sprintf("%suser:%d", GetKeyPrefix(), i), i, 0).Err())
+	}
+	require.NoError(t, r.Set(ctx, GetKeyPrefix()+"order:1", 1, 0).Err())
+	require.NoError(t, r.Set(ctx, "other:user:9", 9, 0).Err())
+
+	// 按游标分批回调，直到游标为0
+	var batches [][]string
+	err := r.ScanKeys(ctx, "user:*", 2, func(keys []string) error {
+		batches = append(batches, keys)
+		return nil
+	}, WithScanKeyPrefix())
+	require.NoError(t, err)
+	var keys []string
+	for _, b := range batches {
+		assert.LessOrEqual(t, len(b), 2)
+		keys = append(keys, b...)
+	}
+	assert.Greater(t, len(batches), 1)
+	assert.ElementsMatch(t, []string{
+		GetKeyPrefix() + "user:0", GetKeyPrefix() + "user:1", GetKeyPrefix() + "user:2",
+		GetKeyPrefix() + "user:3", GetKeyPrefix() + "user:4",
+	}, keys)
+
+	// 不带前缀时 pattern 原样使用，count<=0 使用默认值
+	batches = nil
+	err = r.ScanKeys(ctx, "*user:*", 0, func(keys []string) error {
+		batches = append(batches, keys)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Len(t, batches, 1)
+	assert.Len(t, batches[0], 6)
+}
+
+func TestScanKeysStop(t *testing.T) {
+	r, mr := newTestRedis(t)
+	ctx := context.Background()
+	for i := 0; i < 4; i++ {
+		require.NoError(t, r.Set(ctx, fmt.Sprintf("k%d", i), i, 0).Err())
+	}
+
+	// fn 返回错误时终止遍历并原样返回
+	stop := errors.New("stop")
+	calls := 0
+	err := r.ScanKeys(ctx, "k*", 1, func(keys []string) error {
+		calls++
+		return stop
+	})
+	assert.ErrorIs(t, err, stop)
+	assert.Equal(t, 1, calls)
+
+	// SCAN 失败时包装错误
+	mr.SetError("LOADING Redis is loading the dataset in memory")
+	err = r.ScanKeys(ctx, "k*", 1, func(keys []string) error { return nil })
+	assert.ErrorContains(t, err, "redis scan error")
+}
+
+func TestScanKeysCluster(t *testing.T) {
+	nodes := []*miniredis.Miniredis{miniredis.RunT(t), miniredis.RunT(t)}
+	client := redis.NewClusterClient(&redis.ClusterOptions{
+		ClusterSlots: func(ctx context.Context) ([]redis.ClusterSlot, error) {
+			return []redis.ClusterSlot{
+				{Start: 0, End: 8191, Nodes: []redis.ClusterNode{{Addr: nodes[0].Addr()}}},
+				{Start: 8192, End: 16383, Nodes: []redis.ClusterNode{{Addr: nodes[1].Addr()}}},
+			}, nil
+		},
+	})
+	t.Cleanup(func() { _ = client.Close() })
+	r := &Redis{UniversalClient: client}
+	ctx := context.Background()
+	for i, node := range nodes {
+		for j := 0; j < 3; j++ {
+			require.NoError(t, node.Set(fmt.Sprintf("k%d-%d", i, j), "v"))
+		}
+	}
+
+	// 逐个节点遍历，fn 不会被并发调用
+	var running, calls atomic.Int32
+	var keys []string
+	err := r.ScanKeys(ctx, "k*", 1, func(batch []string) error {
+		assert.Equal(t, int32(1), running.Add(1))
+		defer running.Add(-1)
+		calls.Add(1)
+		time.Sleep(5 * time.Millisecond)
+		keys = append(keys, batch...)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Len(t, keys, 6)
+	assert.EqualValues(t, 6, calls.Load())
+
+	// fn 返回错误后不再遍历其他节点
+	stop := errors.New("stop")
+	calls.Store(0)
+	err = r.ScanKeys(ctx, "k*", 10, func(batch []string) error {
+		calls.Add(1)
+		return stop
+	})
+	assert.ErrorIs(t, err, stop)
+	assert.EqualValues(t, 1, calls.Load())
+}
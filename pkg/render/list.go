@@ -0,0 +1,85 @@
+// Package render -----------------------------
+// @file      : list.go
+// @author    : xiangtao
+// @contact   : xiangtao1994@gmail.com
+// @time      : 2025/9/11 10:20
+// Description: 统一的分页列表响应
+// -------------------------------------------
+package render
+
+import (
+	"reflect"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListData 分页列表响应的 data 部分
+type ListData[T any] struct {
+	List  []T   `json:"list"`
+	Total int64 `json:"total"`
+	Page  int   `json:"page"`
+	Size  int   `json:"size"`
+}
+
+// NewListData 构造分页数据，items 为 nil 时序列化为 [] 而不是 null
+func NewListData[T any](items []T, total int64, page, size int) ListData[T] {
+	if items == nil {
+		items = []T{}
+	}
+	return ListData[T]{List: items, Total: total, Page: page, Size: size}
+}
+
+// RenderJsonList 输出分页列表 {code, message, data: {list, total, page, size}}
+// items 需为切片或数组，仍通过 RegisterRender 注册的渲染器输出
+func RenderJsonList(ctx *gin.Context, items any, total int64, page, size int) {
+	RenderJsonSucc(ctx, NewListData(toAnySlice(items), total, page, size))
+}
+
+// PageData 标准分页响应的 data 部分，新接口统一使用该结构，便于各服务字段一致，也可用于 swagger 文档
+type PageData[T any] struct {
+	Items   []T   `json:"items"`
+	Total   int64 `json:"total"`
+	Page    int   `json:"page"`
+	Size    int   `json:"size"`
+	HasMore bool  `json:"hasMore"`
+}
+
+// NewPageData 构造标准分页数据，items 为 nil 时序列化为 [] 而不是 null
+// page 从 1 开始，hasMore 表示 page 之后是否还有数据，size <= 0 时为 false
+func NewPageData[T any](items []T, total int64, page, size int) PageData[T] {
+	if items == nil {
+		items = []T{}
+	}
+	return PageData[T]{Items: items, Total: total, Page: page, Size: size, HasMore: hasMore(total, page, size)}
+}
+
+// RenderPageSucc 输出标准分页列表 {code, message, data: {items, total, page, size, hasMore}}
+// items 需为切片或数组，仍通过 RegisterRender 注册的渲染器输出
+func RenderPageSucc(ctx *gin.Context, items any, total int64, page, size int) {
+	RenderJsonSucc(ctx, NewPageData(toAnySlice(items), total, page, size))
+}
+
+func hasMore(total int64, page, size int) bool {
+	if size <= 0 {
+		return false
+	}
+	if page < 1 {
+		page = 1
+	}
+	return int64(page)*int64(size) < total
+}
+
+func toAnySlice(items any) []any {
+	if items == nil {
+		return nil
+	}
+	v := reflect.ValueOf(items)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return []any{items}
+	}
+	list := make([]any, v.Len())
+	for i := range list {
+		list[i] = v.Index(i).Interface()
+	}
+	return list
+}
@@ -0,0 +1,126 @@
+package render
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func renderBody(t *testing.T, render func(ctx *gin.Context)) map[string]any {
+	w := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(w)
+	render(ctx)
+	var body map[string]any
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	return body
+}
+
+func renderPage(t *testing.T, items any, total int64, page, size int) map[string]any {
+	return renderBody(t, func(ctx *gin.Context) { RenderPageSucc(ctx, items, total, page, size) })
+}
+
+func renderList(t *testing.T, items any, total int64, page, size int) map[string]any {
+	return renderBody(t, func(ctx *gin.Context) { RenderJsonList(ctx, items, total, page, size) })
+}
+
+func TestNewListData(t *testing.T) {
+	data := NewListData[string](nil, 0, 1, 10)
+	assert.NotNil(t, data.List)
+	out, err := json.Marshal(data)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"list":[],"total":0,"page":1,"size":10}`, string(out))
+
+	data = NewListData([]string{"a"}, 1, 1, 10)
+	assert.Equal(t, []string{"a"}, data.List)
+}
+
+func TestRenderJsonList(t *testing.T) {
+	body := renderList(t, []string{"a", "b"}, 5, 1, 2)
+	assert.EqualValues(t, 200, body["code"])
+	assert.Equal(t, map[string]any{"list": []any{"a", "b"}, "total": 5.0, "page": 1.0, "size": 2.0}, body["data"])
+
+	// nil 与空切片都输出 []
+	var users []string
+	for _, items := range []any{nil, users} {
+		data := renderList(t, items, 0, 1, 10)["data"].(map[string]any)
+		assert.Equal(t, []any{}, data["list"])
+	}
+
+	// 数组按元素展开，非切片的单个值作为唯一元素
+	data := renderList(t, [2]int{1, 2}, 2, 1, 10)["data"].(map[string]any)
+	assert.Equal(t, []any{1.0, 2.0}, data["list"])
+	data = renderList(t, map[string]int{"a": 1}, 1, 1, 10)["data"].(map[string]any)
+	assert.Equal(t, []any{map[string]any{"a": 1.0}}, data["list"])
+}
+
+func TestRenderPageSucc(t *testing.T) {
+	// 空页：nil 输出 []
+	body := renderPage(t, nil, 0, 1, 10)
+	assert.EqualValues(t, 200, body["code"])
+	data := body["data"].(map[string]any)
+	assert.Equal(t, []any{}, data["items"])
+	assert.EqualValues(t, 0, data["total"])
+	assert.Equal(t, false, data["hasMore"])
+
+	var users []string
+	data = renderPage(t, users, 0, 1, 10)["data"].(map[string]any)
+	assert.Equal(t, []any{}, data["items"])
+
+	// 恰好在最后一页的边界上
+	data = renderPage(t, []string{"c", "d"}, 4, 2, 2)["data"].(map[string]any)
+	assert.Equal(t, []any{"c", "d"}, data["items"])
+	assert.EqualValues(t, 2, data["page"])
+	assert.EqualValues(t, 2, data["size"])
+	assert.Equal(t, false, data["hasMore"])
+
+	data = renderPage(t, []string{"c", "d"}, 5, 2, 2)["data"].(map[string]any)
+	assert.Equal(t, true, data["hasMore"])
+}
+
+func TestNewPageData_HasMore(t *testing.T) {
+	assert.True(t, NewPageData([]int{1}, 11, 1, 10).HasMore)
+	assert.False(t, NewPageData([]int{1}, 10, 1, 10).HasMore)
+	assert.False(t, NewPageData([]int{1}, 10, 1, 0).HasMore)
+	// page 未传按第一页计算
+	assert.True(t, NewPageData([]int{1}, 11, 0, 10).HasMore)
+	assert.NotNil(t, NewPageData[int](nil, 0, 1, 10).Items)
+}
+
+type pageRender struct {
+	Status int    `json:"status"`
+	Msg    string `json:"msg"`
+	Result any    `json:"result"`
+}
+
+func (r *pageRender) SetReturnCode(code int)    { r.Status = code }
+func (r *pageRender) SetReturnMsg(msg string)   { r.Msg = msg }
+func (r *pageRender) SetReturnData(data any)    { r.Result = data }
+func (r *pageRender) SetReturnRequestId(string) {}
+func (r *pageRender) GetReturnCode() int        { return r.Status }
+func (r *pageRender) GetReturnMsg() string      { return r.Msg }
+
+func TestRenderPageSucc_CustomRender(t *testing.T) {
+	RegisterRender(func() Render { return &pageRender{} })
+	t.Cleanup(func() { RegisterRender(nil) })
+
+	body := renderPage(t, []int{1, 2}, 3, 1, 2)
+	assert.EqualValues(t, 200, body["status"])
+	assert.NotContains(t, body, "data")
+	result := body["result"].(map[string]any)
+	assert.Equal(t, []any{1.0, 2.0}, result["items"])
+	assert.Equal(t, true, result["hasMore"])
+}
+
+func TestRenderJsonList_CustomRender(t *testing.T) {
+	RegisterRender(func() Render { return &pageRender{} })
+	t.Cleanup(func() { RegisterRender(nil) })
+
+	body := renderList(t, nil, 0, 1, 10)
+	assert.EqualValues(t, 200, body["status"])
+	assert.NotContains(t, body, "data")
+	assert.Equal(t, map[string]any{"list": []any{}, "total": 0.0, "page": 1.0, "size": 10.0}, body["result"])
+}
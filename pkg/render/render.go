@@ -44,6 +44,25 @@ func newJsonRender() Render {
 	return newRender()
 }
 
+// 业务码到HTTP状态码的映射，默认全部返回200
+var statusMapper func(code int) int
+
+// RegisterStatusMapper 注册业务码到HTTP状态码的映射，作用于 RenderJson 与 RenderJsonFail
+// 返回值 <= 0 时按200处理
+func RegisterStatusMapper(fn func(code int) int) {
+	statusMapper = fn
+}
+
+func httpStatus(code int) int {
+	if statusMapper == nil {
+		return http.StatusOK
+	}
+	if status := statusMapper(code); status > 0 {
+		return status
+	}
+	return http.StatusOK
+}
+
 // default render
 
 var defaultNew = func() Render {
@@ -88,8 +107,16 @@ func setCommonHeader(ctx *gin.Context, code int, msg string) {
 }
 
 // 打印错误栈
+// err 被 fmt.Errorf 等再次包装时，%+v 只有外层信息，此时追加错误链中 errors.Error 的附加信息、底层错误和调用栈
 func StackLogger(ctx *gin.Context, err error) {
-	if !strings.Contains(fmt.Sprintf("%+v", err), "\n") {
+	detail := fmt.Sprintf("%+v", err)
+	var e errors2.Error
+	if !strings.Contains(detail, "\n") && errors.As(err, &e) {
+		if wrapped := fmt.Sprintf("%+v", e); strings.Contains(wrapped, "\n") {
+			detail = detail + "\nwrapped: " + wrapped
+		}
+	}
+	if !strings.Contains(detail, "\n") {
 		return
 	}
 
@@ -100,7 +127,7 @@ func StackLogger(ctx *gin.Context, err error) {
 		info, _ = json.Marshal(map[string]interface{}{"time": time.Now().Format("2006-01-02 15:04:05"), "level": "error", "module": "errorstack"})
 	}
 
-	fmt.Printf("%s\n-------------------stack-start-------------------\n%+v\n-------------------stack-end-------------------\n", string(info), err)
+	fmt.Printf("%s\n-------------------stack-start-------------------\n%s\n-------------------stack-end-------------------\n", string(info), detail)
 }
 
 func RenderJson(ctx *gin.Context, code int, msg string, data interface{}) {
@@ -110,22 +137,37 @@ func RenderJson(ctx *gin.Context, code int, msg string, data interface{}) {
 	r.SetReturnData(data)
 	r.SetReturnRequestId(zlog.GetRequestID(ctx))
 	setCommonHeader(ctx, code, msg)
-	ctx.JSON(http.StatusOK, r)
+	ctx.JSON(httpStatus(code), r)
 	return
 }
 
 func RenderJsonSucc(ctx *gin.Context, data interface{}) {
+	RenderJsonSuccWithStatus(ctx, http.StatusOK, data)
+}
+
+// RenderJsonSuccWithStatus 与 RenderJsonSucc 相同，但使用指定的HTTP状态码，如创建资源时的 201
+func RenderJsonSuccWithStatus(ctx *gin.Context, status int, data interface{}) {
 	r := newJsonRender()
 	r.SetReturnCode(200)
 	r.SetReturnMsg("success")
 	r.SetReturnData(data)
 	r.SetReturnRequestId(zlog.GetRequestID(ctx))
 	setCommonHeader(ctx, 200, "success")
-	ctx.JSON(http.StatusOK, r)
+	ctx.JSON(status, r)
 	return
 }
 
 func RenderJsonFail(ctx *gin.Context, err error) {
+	renderJsonFail(ctx, 0, err)
+}
+
+// RenderJsonFailWithStatus 与 RenderJsonFail 相同，但使用指定的HTTP状态码，不经过 RegisterStatusMapper
+func RenderJsonFailWithStatus(ctx *gin.Context, status int, err error) {
+	renderJsonFail(ctx, status, err)
+}
+
+// status <= 0 时按业务码映射HTTP状态码
+func renderJsonFail(ctx *gin.Context, status int, err error) {
 	r := newJsonRender()
 
 	code := 500
@@ -135,6 +177,7 @@ func RenderJsonFail(ctx *gin.Context, err error) {
 	if errors.As(err, &e2) {
 		code = e2.Code
 		msg = e2.GetMessage(ctx)
+		errors2.IncBusinessError(code)
 	} else {
 		code = errors2.ErrorSystemError.Code
 		msg = errors2.ErrorSystemError.GetMessage(ctx)
@@ -145,7 +188,10 @@ func RenderJsonFail(ctx *gin.Context, err error) {
 	r.SetReturnData(gin.H{})
 
 	setCommonHeader(ctx, code, msg)
-	ctx.JSON(http.StatusOK, r)
+	if status <= 0 {
+		status = httpStatus(code)
+	}
+	ctx.JSON(status, r)
 
 	// 打印错误栈（标准库没有自动栈，需要你在生成错误时自己加）
 	StackLogger(ctx, err)
@@ -163,9 +209,11 @@ func RenderStream(ctx *gin.Context, id, event string, data interface{}) {
 
 func RenderStreamFail(ctx *gin.Context, err error) {
 	rander := DefaultRender{}
-	if e, ok := err.(errors2.Error); ok {
+	var e errors2.Error
+	if errors.As(err, &e) {
 		rander.Code = e.Code
 		rander.Message = e.GetMessage(ctx)
+		errors2.IncBusinessError(e.Code)
 	} else {
 		rander.Code = errors2.ErrorSystemError.Code
 		rander.Message = errors2.ErrorSystemError.GetMessage(ctx)
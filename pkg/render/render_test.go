@@ -0,0 +1,38 @@
+package render
+
+import (
+	stderrors "errors"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+
+	errors2 "github.com/xiangtao94/golib/pkg/errors"
+)
+
+func TestRenderJsonFailCountsBusinessError(t *testing.T) {
+	errors2.RegisterCodeRange("render_test", 90000, 90999)
+	errUserNotFound := errors2.Register(90001, map[string]string{"zh": "用户不存在", "en": "User not found"})
+
+	counter := func(module string, code int) float64 {
+		return testutil.ToFloat64(errors2.BusinessErrorTotal.WithLabelValues(module, fmt.Sprint(code)))
+	}
+	before := counter("render_test", 90001)
+	beforeUnregistered := counter(errors2.ModuleUnregistered, 90500)
+	beforeSystem := counter(errors2.ModuleCommon, errors2.SYSTEM_ERROR)
+
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	RenderJsonFail(ctx, fmt.Errorf("get user: %w", errUserNotFound))
+	ctx, _ = gin.CreateTestContext(httptest.NewRecorder())
+	RenderJsonFail(ctx, errors2.NewError(90500, map[string]string{"zh": "未注册"}))
+	// 非 errors2.Error 不计数
+	ctx, _ = gin.CreateTestContext(httptest.NewRecorder())
+	RenderJsonFail(ctx, stderrors.New("plain error"))
+
+	assert.Equal(t, before+1, counter("render_test", 90001))
+	assert.Equal(t, beforeUnregistered+1, counter(errors2.ModuleUnregistered, 90500))
+	assert.Equal(t, beforeSystem, counter(errors2.ModuleCommon, errors2.SYSTEM_ERROR))
+}
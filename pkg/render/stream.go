@@ -0,0 +1,117 @@
+// Package render -----------------------------
+// @file      : stream.go
+// @author    : xiangtao
+// @contact   : xiangtao1994@gmail.com
+// @time      : 2025/9/11 14:36
+// Description: 带心跳和断连检测的SSE会话
+// -------------------------------------------
+package render
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+
+	"github.com/xiangtao94/golib/pkg/zlog"
+)
+
+const defaultHeartbeatInterval = 15 * time.Second
+
+// ErrClientDisconnected 客户端已断开，send 返回该错误后 producer 应尽快退出
+var ErrClientDisconnected = errors.New("stream client disconnected")
+
+type streamOptions struct {
+	heartbeat time.Duration
+}
+
+type StreamOption func(*streamOptions)
+
+// WithHeartbeat 设置心跳间隔，默认15s，小于等于0时不发送心跳
+func WithHeartbeat(interval time.Duration) StreamOption {
+	return func(o *streamOptions) {
+		o.heartbeat = interval
+	}
+}
+
+// StreamSession 建立SSE会话：定时发送注释行心跳保持连接，客户端断开后 send 返回 ErrClientDisconnected
+// producer 通过 send 推送事件，返回后会话结束；producer 返回错误且客户端未断开时会推送 error 事件
+func StreamSession(ctx *gin.Context, producer func(send func(event, data string) error) error, opts ...StreamOption) error {
+	o := &streamOptions{heartbeat: defaultHeartbeatInterval}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	ctx.Header("Content-Type", "text/event-stream")
+	ctx.Header("Cache-Control", "no-cache")
+	ctx.Header("Connection", "keep-alive")
+	// 关闭nginx缓冲，保证事件实时下发
+	ctx.Header("X-Accel-Buffering", "no")
+	ctx.Status(http.StatusOK)
+
+	reqCtx := ctx.Request.Context()
+	flusher, _ := ctx.Writer.(http.Flusher)
+	var mu sync.Mutex
+	// write 串行化 producer 与心跳的写入
+	write := func(fn func(w io.Writer) error) error {
+		mu.Lock()
+		defer mu.Unlock()
+		if reqCtx.Err() != nil {
+			return ErrClientDisconnected
+		}
+		if err := fn(ctx.Writer); err != nil {
+			return err
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		return nil
+	}
+	_ = write(func(w io.Writer) error { return nil })
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	if o.heartbeat > 0 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ticker := time.NewTicker(o.heartbeat)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-done:
+					return
+				case <-reqCtx.Done():
+					return
+				case <-ticker.C:
+					_ = write(func(w io.Writer) error {
+						_, err := io.WriteString(w, ": heartbeat\n\n")
+						return err
+					})
+				}
+			}
+		}()
+	}
+
+	send := func(event, data string) error {
+		return write(func(w io.Writer) error {
+			return sse.Encode(w, sse.Event{Event: event, Data: data})
+		})
+	}
+	err := producer(send)
+	close(done)
+	wg.Wait()
+
+	if reqCtx.Err() != nil {
+		zlog.Infof(ctx, "stream session closed by client")
+		return ErrClientDisconnected
+	}
+	if err != nil {
+		RenderStreamFail(ctx, err)
+	}
+	return err
+}
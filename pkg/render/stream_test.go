@@ -0,0 +1,92 @@
+package render
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	errors2 "github.com/xiangtao94/golib/pkg/errors"
+)
+
+func newStreamContext() (*gin.Context, *httptest.ResponseRecorder, context.CancelFunc) {
+	w := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(w)
+	reqCtx, cancel := context.WithCancel(context.Background())
+	ctx.Request = httptest.NewRequest(http.MethodGet, "/stream", nil).WithContext(reqCtx)
+	return ctx, w, cancel
+}
+
+func TestStreamSession(t *testing.T) {
+	ctx, w, cancel := newStreamContext()
+	defer cancel()
+
+	err := StreamSession(ctx, func(send func(event, data string) error) error {
+		require.NoError(t, send("message", "hello"))
+		// 等待心跳
+		time.Sleep(60 * time.Millisecond)
+		return send("done", "[DONE]")
+	}, WithHeartbeat(10*time.Millisecond))
+	require.NoError(t, err)
+
+	body := w.Body.String()
+	assert.Equal(t, "text/event-stream", w.Header().Get("Content-Type"))
+	assert.Contains(t, body, ": heartbeat\n\n")
+	assert.True(t, strings.HasPrefix(body, "event:message\ndata:hello\n\n"), body)
+	assert.True(t, strings.HasSuffix(body, "event:done\ndata:[DONE]\n\n"), body)
+}
+
+func TestStreamSession_NoHeartbeat(t *testing.T) {
+	for _, interval := range []time.Duration{0, -time.Second} {
+		ctx, w, cancel := newStreamContext()
+		err := StreamSession(ctx, func(send func(event, data string) error) error {
+			time.Sleep(20 * time.Millisecond)
+			return send("message", "hello")
+		}, WithHeartbeat(interval))
+		cancel()
+		require.NoError(t, err)
+		assert.NotContains(t, w.Body.String(), "heartbeat")
+	}
+}
+
+func TestStreamSession_ClientDisconnected(t *testing.T) {
+	ctx, w, cancel := newStreamContext()
+
+	var sendErr error
+	err := StreamSession(ctx, func(send func(event, data string) error) error {
+		require.NoError(t, send("message", "first"))
+		cancel()
+		sendErr = send("message", "second")
+		return sendErr
+	}, WithHeartbeat(time.Hour))
+
+	assert.ErrorIs(t, sendErr, ErrClientDisconnected)
+	assert.ErrorIs(t, err, ErrClientDisconnected)
+	// 断开后不再写入，也不推送 error 事件
+	assert.Equal(t, "event:message\ndata:first\n\n", w.Body.String())
+}
+
+func TestStreamSession_ProducerError(t *testing.T) {
+	ctx, w, cancel := newStreamContext()
+	defer cancel()
+
+	producerErr := stderrors.New("llm unavailable")
+	err := StreamSession(ctx, func(send func(event, data string) error) error {
+		require.NoError(t, send("message", "partial"))
+		return producerErr
+	})
+
+	assert.ErrorIs(t, err, producerErr)
+	body := w.Body.String()
+	assert.Contains(t, body, "event:message\ndata:partial\n\n")
+	assert.Contains(t, body, "event:error\n")
+	assert.Contains(t, body, fmt.Sprintf(`"code":%d`, errors2.ErrorSystemError.Code))
+}
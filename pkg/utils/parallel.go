@@ -0,0 +1,155 @@
+// Package utils -----------------------------
+// @file      : parallel.go
+// @author    : xiangtao
+// @contact   : xiangtao1994@gmail.com
+// @time      : 2025/9/13 20:40
+// Description: 限制并发数的并行处理和分批处理
+// -------------------------------------------
+package utils
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/xiangtao94/golib/pkg/zlog"
+)
+
+// MultiError 汇总多个错误，支持 errors.Is/As 匹配其中任一错误
+type MultiError struct {
+	Errors []error
+}
+
+func (m *MultiError) Error() string {
+	msgs := make([]string, 0, len(m.Errors))
+	for _, err := range m.Errors {
+		msgs = append(msgs, err.Error())
+	}
+	return fmt.Sprintf("%d errors occurred: %s", len(m.Errors), strings.Join(msgs, "; "))
+}
+
+func (m *MultiError) Unwrap() []error {
+	return m.Errors
+}
+
+type parallelOptions struct {
+	collectAll bool
+}
+
+// ParallelOption ParallelMap 的可选项
+type ParallelOption func(*parallelOptions)
+
+// WithCollectAllErrors 出错时不取消其他任务，全部执行完后以 *MultiError 返回所有错误，结果中失败的位置为零值
+func WithCollectAllErrors() ParallelOption {
+	return func(o *parallelOptions) {
+		o.collectAll = true
+	}
+}
+
+// ParallelMap 以最多 concurrency 个协程并行执行 fn，结果与 items 顺序一致
+// 默认任一任务出错即取消 ctx 并不再开始新任务，返回第一个错误；ctx 取消后不再开始新任务并返回 ctx.Err()
+// fn 中的 panic 会被恢复并转为错误，堆栈通过 zlog 记录；concurrency <= 0 时每个元素一个协程
+func ParallelMap[T, R any](ctx context.Context, items []T, concurrency int, fn func(ctx context.Context, item T) (R, error), opts ...ParallelOption) ([]R, error) {
+	var o parallelOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if len(items) == 0 {
+		return []R{}, nil
+	}
+	if concurrency <= 0 || concurrency > len(items) {
+		concurrency = len(items)
+	}
+
+	parent := ctx
+	logCtx, _ := parent.(*gin.Context)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		next     atomic.Int64
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+		results  = make([]R, len(items))
+		errs     = make([]error, len(items))
+	)
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				i := int(next.Add(1) - 1)
+				if i >= len(items) {
+					return
+				}
+				if err := ctx.Err(); err != nil {
+					errs[i] = fmt.Errorf("item %d: %w", i, err)
+					continue
+				}
+				res, err := safeCall(ctx, logCtx, i, items[i], fn)
+				if err != nil {
+					errs[i] = fmt.Errorf("item %d: %w", i, err)
+					if !o.collectAll {
+						once.Do(func() {
+							firstErr = errs[i]
+							cancel()
+						})
+					}
+					continue
+				}
+				results[i] = res
+			}
+		}()
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if err := parent.Err(); err != nil && !o.collectAll {
+		return nil, err
+	}
+	if o.collectAll {
+		var failed []error
+		for _, err := range errs {
+			if err != nil {
+				failed = append(failed, err)
+			}
+		}
+		if len(failed) > 0 {
+			return results, &MultiError{Errors: failed}
+		}
+	}
+	return results, nil
+}
+
+// safeCall 恢复 fn 中的 panic，传入的 ctx 为 *gin.Context 时日志带上 requestId
+func safeCall[T, R any](ctx context.Context, logCtx *gin.Context, index int, item T, fn func(ctx context.Context, item T) (R, error)) (res R, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			zlog.Errorf(logCtx, "parallel task %d panic: %v\n%s", index, r, debug.Stack())
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return fn(ctx, item)
+}
+
+// ForEachBatch 按 batchSize 切分 items 依次调用 fn，fn 出错时停止并返回错误；batchSize <= 0 时整体作为一批
+func ForEachBatch[T any](items []T, batchSize int, fn func(batch []T) error) error {
+	if batchSize <= 0 {
+		batchSize = len(items)
+	}
+	for start := 0; start < len(items); start += batchSize {
+		end := min(start+batchSize, len(items))
+		if err := fn(items[start:end:end]); err != nil {
+			return fmt.Errorf("batch [%d, %d): %w", start, end, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParallelMapOrder(t *testing.T) {
+	items := []int{5, 1, 4, 2, 3}
+	var running, maxRunning atomic.Int32
+	res, err := ParallelMap(context.Background(), items, 2, func(ctx context.Context, item int) (int, error) {
+		n := running.Add(1)
+		defer running.Add(-1)
+		for {
+			m := maxRunning.Load()
+			if n <= m || maxRunning.CompareAndSwap(m, n) {
+				break
+			}
+		}
+		time.Sleep(time.Duration(item) * time.Millisecond)
+		return item * 10, nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []int{50, 10, 40, 20, 30}, res)
+	assert.LessOrEqual(t, maxRunning.Load(), int32(2))
+}
+
+func TestParallelMapEdgeCases(t *testing.T) {
+	res, err := ParallelMap(context.Background(), []int(nil), 4, func(ctx context.Context, item int) (int, error) {
+		t.Fatal("should not be called")
+		return 0, nil
+	})
+	require.NoError(t, err)
+	assert.Empty(t, res)
+
+	// 并发数大于元素数
+	res, err = ParallelMap(context.Background(), []int{1, 2}, 100, func(ctx context.Context, item int) (int, error) {
+		return item + 1, nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []int{2, 3}, res)
+}
+
+func TestParallelMapCancelOnError(t *testing.T) {
+	boom := errors.New("boom")
+	var started atomic.Int32
+	items := make([]int, 100)
+	for i := range items {
+		items[i] = i
+	}
+	_, err := ParallelMap(context.Background(), items, 2, func(ctx context.Context, item int) (int, error) {
+		started.Add(1)
+		if item == 1 {
+			return 0, boom
+		}
+		select {
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		case <-time.After(5 * time.Millisecond):
+			return item, nil
+		}
+	})
+	assert.ErrorIs(t, err, boom)
+	assert.Equal(t, "item 1: boom", err.Error())
+	// 出错后不再开始新任务
+	assert.Less(t, started.Load(), int32(10))
+}
+
+func TestParallelMapContextCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var started atomic.Int32
+	_, err := ParallelMap(ctx, make([]int, 50), 1, func(ctx context.Context, item int) (int, error) {
+		if started.Add(1) == 3 {
+			cancel()
+		}
+		return item, nil
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, int32(3), started.Load())
+}
+
+func TestParallelMapCollectAllErrors(t *testing.T) {
+	errOdd := errors.New("odd")
+	res, err := ParallelMap(context.Background(), []int{1, 2, 3, 4}, 2, func(ctx context.Context, item int) (int, error) {
+		if item%2 == 1 {
+			return 0, errOdd
+		}
+		return item, nil
+	}, WithCollectAllErrors())
+	var multi *MultiError
+	require.ErrorAs(t, err, &multi)
+	assert.Len(t, multi.Errors, 2)
+	assert.ErrorIs(t, err, errOdd)
+	assert.Equal(t, []int{0, 2, 0, 4}, res)
+}
+
+func TestParallelMapPanic(t *testing.T) {
+	res, err := ParallelMap(context.Background(), []string{"a", "b"}, 2, func(ctx context.Context, item string) (string, error) {
+		if item == "b" {
+			panic("bad item")
+		}
+		return item, nil
+	}, WithCollectAllErrors())
+	require.Error(t, err)
+	assert.Equal(t, "1 errors occurred: item 1: panic: bad item", err.Error())
+	assert.Equal(t, []string{"a", ""}, res)
+}
+
+func TestForEachBatch(t *testing.T) {
+	var batches [][]int
+	err := ForEachBatch([]int{1, 2, 3, 4, 5}, 2, func(batch []int) error {
+		batches = append(batches, batch)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, [][]int{{1, 2}, {3, 4}, {5}}, batches)
+
+	boom := errors.New("boom")
+	calls := 0
+	err = ForEachBatch([]int{1, 2, 3, 4, 5}, 2, func(batch []int) error {
+		calls++
+		if batch[0] == 3 {
+			return boom
+		}
+		return nil
+	})
+	assert.ErrorIs(t, err, boom)
+	assert.Equal(t, "batch [2, 4): boom", err.Error())
+	assert.Equal(t, 2, calls)
+
+	assert.NoError(t, ForEachBatch([]int{}, 2, func(batch []int) error {
+		t.Fatal("should not be called")
+		return nil
+	}))
+}
@@ -0,0 +1,95 @@
+// Package ws -----------------------------
+// @file      : hub.go
+// @author    : xiangtao
+// @contact   : xiangtao1994@gmail.com
+// @time      : 2025/9/14 16:50
+// Description: 按房间广播，连接关闭时自动离开所有房间
+// -------------------------------------------
+package ws
+
+import (
+	"encoding/json"
+	"sync"
+
+	"github.com/gorilla/websocket"
+)
+
+// Hub 按房间（如任务ID、用户ID）管理连接并广播消息，可在多个 goroutine 中并发使用
+type Hub struct {
+	mu    sync.RWMutex
+	rooms map[string]map[*Conn]struct{}
+}
+
+func NewHub() *Hub {
+	return &Hub{rooms: map[string]map[*Conn]struct{}{}}
+}
+
+// Join 把连接加入房间，连接关闭时自动离开
+func (h *Hub) Join(room string, conn *Conn) {
+	h.mu.Lock()
+	conns, ok := h.rooms[room]
+	if !ok {
+		conns = map[*Conn]struct{}{}
+		h.rooms[room] = conns
+	}
+	_, joined := conns[conn]
+	conns[conn] = struct{}{}
+	h.mu.Unlock()
+
+	if !joined {
+		conn.onClose(func() { h.Leave(room, conn) })
+	}
+}
+
+// Leave 把连接移出房间，房间为空时删除
+func (h *Hub) Leave(room string, conn *Conn) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	conns, ok := h.rooms[room]
+	if !ok {
+		return
+	}
+	delete(conns, conn)
+	if len(conns) == 0 {
+		delete(h.rooms, room)
+	}
+}
+
+// Count 房间内的连接数
+func (h *Hub) Count(room string) int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.rooms[room])
+}
+
+// Broadcast 向房间内所有连接发送消息，返回成功放入发送队列的连接数，已关闭或发送队列满的连接被跳过
+func (h *Hub) Broadcast(room string, msgType int, data []byte) int {
+	h.mu.RLock()
+	conns := make([]*Conn, 0, len(h.rooms[room]))
+	for conn := range h.rooms[room] {
+		conns = append(conns, conn)
+	}
+	h.mu.RUnlock()
+
+	sent := 0
+	for _, conn := range conns {
+		if conn.Send(msgType, data) == nil {
+			sent++
+		}
+	}
+	return sent
+}
+
+// BroadcastJSON 序列化一次后向房间内所有连接发送 JSON 文本消息
+func (h *Hub) BroadcastJSON(room string, v any) (int, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return 0, err
+	}
+	return h.Broadcast(room, websocket.TextMessage, data), nil
+}
+
+// BroadcastText 向房间内所有连接发送文本消息
+func (h *Hub) BroadcastText(room string, text string) int {
+	return h.Broadcast(room, websocket.TextMessage, []byte(text))
+}
@@ -0,0 +1,340 @@
+// Package ws -----------------------------
+// @file      : ws.go
+// @author    : xiangtao
+// @contact   : xiangtao1994@gmail.com
+// @time      : 2025/9/14 16:40
+// Description: websocket 服务端封装，处理升级、源校验、心跳与并发安全的发送
+// -------------------------------------------
+package ws
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+
+	"github.com/xiangtao94/golib/pkg/zlog"
+)
+
+var (
+	// ErrClosed 连接已关闭
+	ErrClosed = errors.New("websocket connection closed")
+	// ErrSendQueueFull 发送队列已满，通常是客户端读取过慢
+	ErrSendQueueFull = errors.New("websocket send queue full")
+)
+
+// WSConf websocket 配置
+type WSConf struct {
+	// AllowOrigins 允许的源，支持精确匹配、"*" 以及子域名通配（https://*.example.com），为空时只允许同源或不带 Origin 的请求
+	AllowOrigins []string `yaml:"allowOrigins"`
+	// MaxMessageSize 单条消息的最大字节数，超过时以 1009 关闭连接，默认64KB
+	MaxMessageSize int64 `yaml:"maxMessageSize"`
+	// PingInterval 发送 ping 的间隔，默认30s
+	PingInterval time.Duration `yaml:"pingInterval"`
+	// PongWait 超过该时间没有收到客户端的消息（包括 pong）时断开连接，默认60s，需大于 PingInterval
+	PongWait time.Duration `yaml:"pongWait"`
+	// WriteWait 单次写入的超时时间，默认10s
+	WriteWait time.Duration `yaml:"writeWait"`
+	// SendQueueSize 每个连接的发送队列长度，队列满时发送返回 ErrSendQueueFull，默认256
+	SendQueueSize   int `yaml:"sendQueueSize"`
+	ReadBufferSize  int `yaml:"readBufferSize"`
+	WriteBufferSize int `yaml:"writeBufferSize"`
+}
+
+func (conf *WSConf) checkConf() {
+	if conf.MaxMessageSize <= 0 {
+		conf.MaxMessageSize = 64 * 1024
+	}
+	if conf.PingInterval <= 0 {
+		conf.PingInterval = 30 * time.Second
+	}
+	if conf.PongWait <= conf.PingInterval {
+		conf.PongWait = conf.PingInterval * 2
+	}
+	if conf.WriteWait <= 0 {
+		conf.WriteWait = 10 * time.Second
+	}
+	if conf.SendQueueSize <= 0 {
+		conf.SendQueueSize = 256
+	}
+}
+
+// UpgradeHandler 返回升级 websocket 的 gin.HandlerFunc，升级成功后调用 onConnect
+// onConnect 返回后连接关闭，通常在其中调用 conn.ReadLoop 阻塞到连接断开；只推送不读取时使用 conn.ReadLoop(nil)
+func UpgradeHandler(conf WSConf, onConnect func(ctx *gin.Context, conn *Conn) error) gin.HandlerFunc {
+	conf.checkConf()
+	origins := newOriginMatcher(conf.AllowOrigins)
+	upgrader := websocket.Upgrader{
+		ReadBufferSize:  conf.ReadBufferSize,
+		WriteBufferSize: conf.WriteBufferSize,
+		CheckOrigin: func(r *http.Request) bool {
+			if origins == nil {
+				return sameOrigin(r)
+			}
+			origin := r.Header.Get("Origin")
+			return origin == "" || origins.match(origin)
+		},
+	}
+
+	return func(ctx *gin.Context) {
+		// 升级前生成请求ID，连接内的日志都带上
+		_ = zlog.GetRequestID(ctx)
+		ws, err := upgrader.Upgrade(ctx.Writer, ctx.Request, nil)
+		if err != nil {
+			// Upgrade 已返回 HTTP 错误响应
+			zlog.Warnf(ctx, "websocket upgrade failed, origin: %s, err: %v", ctx.GetHeader("Origin"), err)
+			return
+		}
+
+		conn := newConn(ctx.Copy(), ws, conf)
+		zlog.Infof(ctx, "websocket connected, remote: %s", ws.RemoteAddr())
+		var handleErr error
+		if onConnect != nil {
+			handleErr = onConnect(ctx, conn)
+		}
+		conn.Close()
+		if handleErr != nil {
+			zlog.Warnf(ctx, "websocket closed with error, remote: %s, duration: %v, err: %v", ws.RemoteAddr(), time.Since(conn.connectedAt), handleErr)
+			return
+		}
+		zlog.Infof(ctx, "websocket closed, remote: %s, duration: %v", ws.RemoteAddr(), time.Since(conn.connectedAt))
+	}
+}
+
+type outbound struct {
+	msgType int
+	data    []byte
+}
+
+// Conn websocket 连接，发送方法可在多个 goroutine 中并发调用，由每个连接的写协程串行写出
+type Conn struct {
+	ws   *websocket.Conn
+	ctx  *gin.Context
+	conf WSConf
+
+	send        chan outbound
+	done        chan struct{}
+	pumpDone    chan struct{}
+	closeOnce   sync.Once
+	connectedAt time.Time
+
+	mu         sync.Mutex
+	closeHooks []func()
+}
+
+func newConn(ctx *gin.Context, ws *websocket.Conn, conf WSConf) *Conn {
+	c := &Conn{
+		ws:          ws,
+		ctx:         ctx,
+		conf:        conf,
+		send:        make(chan outbound, conf.SendQueueSize),
+		done:        make(chan struct{}),
+		pumpDone:    make(chan struct{}),
+		connectedAt: time.Now(),
+	}
+	ws.SetReadLimit(conf.MaxMessageSize)
+	_ = ws.SetReadDeadline(time.Now().Add(conf.PongWait))
+	ws.SetPongHandler(func(string) error {
+		return ws.SetReadDeadline(time.Now().Add(conf.PongWait))
+	})
+	go c.writePump()
+	return c
+}
+
+// Context 升级时的请求上下文副本，可在其他 goroutine 中使用
+func (c *Conn) Context() *gin.Context {
+	return c.ctx
+}
+
+// Done 连接关闭时关闭
+func (c *Conn) Done() <-chan struct{} {
+	return c.done
+}
+
+// SendJSON 发送 JSON 文本消息
+func (c *Conn) SendJSON(v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return c.Send(websocket.TextMessage, data)
+}
+
+// SendText 发送文本消息
+func (c *Conn) SendText(text string) error {
+	return c.Send(websocket.TextMessage, []byte(text))
+}
+
+// Send 把消息放入发送队列，不等待写出；连接关闭返回 ErrClosed，队列满返回 ErrSendQueueFull
+func (c *Conn) Send(msgType int, data []byte) error {
+	select {
+	case <-c.done:
+		return ErrClosed
+	default:
+	}
+	select {
+	case c.send <- outbound{msgType: msgType, data: data}:
+		return nil
+	case <-c.done:
+		return ErrClosed
+	default:
+		zlog.Warnf(c.ctx, "websocket send queue full, remote: %s", c.ws.RemoteAddr())
+		return ErrSendQueueFull
+	}
+}
+
+// ReadLoop 循环读取消息交给 handler，handler 为 nil 时丢弃消息
+// 客户端正常关闭时返回 nil；读超时、消息超过 MaxMessageSize 或 handler 返回错误时返回该错误
+func (c *Conn) ReadLoop(handler func(msgType int, data []byte) error) error {
+	for {
+		msgType, data, err := c.ws.ReadMessage()
+		if err != nil {
+			select {
+			case <-c.done:
+				return nil
+			default:
+			}
+			if websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway, websocket.CloseNoStatusReceived) {
+				return nil
+			}
+			return err
+		}
+		_ = c.ws.SetReadDeadline(time.Now().Add(c.conf.PongWait))
+		if handler == nil {
+			continue
+		}
+		if err = handler(msgType, data); err != nil {
+			return err
+		}
+	}
+}
+
+// Close 发送关闭帧并关闭连接，等待写协程退出，可重复调用
+func (c *Conn) Close() {
+	c.closeOnce.Do(func() {
+		close(c.done)
+		<-c.pumpDone
+		_ = c.ws.Close()
+
+		c.mu.Lock()
+		hooks := c.closeHooks
+		c.closeHooks = nil
+		c.mu.Unlock()
+		for _, hook := range hooks {
+			hook()
+		}
+	})
+}
+
+// onClose 注册关闭时的回调，连接已关闭时立即执行
+func (c *Conn) onClose(hook func()) {
+	c.mu.Lock()
+	select {
+	case <-c.done:
+		c.mu.Unlock()
+		hook()
+		return
+	default:
+	}
+	c.closeHooks = append(c.closeHooks, hook)
+	c.mu.Unlock()
+}
+
+// writePump 串行写出队列中的消息并定时发送 ping，连接关闭时发送关闭帧
+func (c *Conn) writePump() {
+	defer close(c.pumpDone)
+	ticker := time.NewTicker(c.conf.PingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case msg := <-c.send:
+			if !c.write(msg) {
+				return
+			}
+		case <-ticker.C:
+			if err := c.ws.WriteControl(websocket.PingMessage, nil, time.Now().Add(c.conf.WriteWait)); err != nil {
+				zlog.Warnf(c.ctx, "websocket ping failed, remote: %s, err: %v", c.ws.RemoteAddr(), err)
+				_ = c.ws.Close()
+				return
+			}
+		case <-c.done:
+			// 写出关闭前已入队的消息
+			for len(c.send) > 0 {
+				if !c.write(<-c.send) {
+					return
+				}
+			}
+			msg := websocket.FormatCloseMessage(websocket.CloseNormalClosure, "")
+			_ = c.ws.WriteControl(websocket.CloseMessage, msg, time.Now().Add(c.conf.WriteWait))
+			return
+		}
+	}
+}
+
+func (c *Conn) write(msg outbound) bool {
+	_ = c.ws.SetWriteDeadline(time.Now().Add(c.conf.WriteWait))
+	if err := c.ws.WriteMessage(msg.msgType, msg.data); err != nil {
+		zlog.Warnf(c.ctx, "websocket write failed, remote: %s, err: %v", c.ws.RemoteAddr(), err)
+		// 关闭底层连接使 ReadLoop 返回
+		_ = c.ws.Close()
+		return false
+	}
+	return true
+}
+
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	i := strings.Index(origin, "://")
+	return i >= 0 && strings.EqualFold(origin[i+3:], r.Host)
+}
+
+// originMatcher 与 CORS 中间件的匹配规则一致
+type originMatcher struct {
+	any      bool
+	exact    map[string]struct{}
+	wildcard [][2]string
+}
+
+func newOriginMatcher(origins []string) *originMatcher {
+	if len(origins) == 0 {
+		return nil
+	}
+	m := &originMatcher{exact: map[string]struct{}{}}
+	for _, origin := range origins {
+		origin = strings.ToLower(strings.TrimSpace(origin))
+		switch {
+		case origin == "*":
+			m.any = true
+		case strings.Contains(origin, "*"):
+			i := strings.Index(origin, "*")
+			m.wildcard = append(m.wildcard, [2]string{origin[:i], origin[i+1:]})
+		default:
+			m.exact[origin] = struct{}{}
+		}
+	}
+	return m
+}
+
+func (m *originMatcher) match(origin string) bool {
+	if m.any {
+		return true
+	}
+	origin = strings.ToLower(origin)
+	if _, ok := m.exact[origin]; ok {
+		return true
+	}
+	for _, w := range m.wildcard {
+		if len(origin) > len(w[0])+len(w[1]) && strings.HasPrefix(origin, w[0]) && strings.HasSuffix(origin, w[1]) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,174 @@
+package ws
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// newServer 注册 /ws 路由，返回 ws:// 地址
+func newServer(t *testing.T, conf WSConf, onConnect func(ctx *gin.Context, conn *Conn) error) string {
+	engine := gin.New()
+	engine.GET("/ws", UpgradeHandler(conf, onConnect))
+	server := httptest.NewServer(engine)
+	t.Cleanup(server.Close)
+	return "ws" + strings.TrimPrefix(server.URL, "http") + "/ws"
+}
+
+func dial(t *testing.T, url string, header http.Header) *websocket.Conn {
+	conn, _, err := websocket.DefaultDialer.Dial(url, header)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	return conn
+}
+
+func readText(t *testing.T, conn *websocket.Conn) string {
+	_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	msgType, data, err := conn.ReadMessage()
+	require.NoError(t, err)
+	assert.Equal(t, websocket.TextMessage, msgType)
+	return string(data)
+}
+
+func TestUpgradeHandler_Echo(t *testing.T) {
+	url := newServer(t, WSConf{}, func(ctx *gin.Context, conn *Conn) error {
+		return conn.ReadLoop(func(msgType int, data []byte) error {
+			return conn.SendText("echo: " + string(data))
+		})
+	})
+	client := dial(t, url, nil)
+
+	for _, msg := range []string{"hello", "world"} {
+		require.NoError(t, client.WriteMessage(websocket.TextMessage, []byte(msg)))
+		assert.Equal(t, "echo: "+msg, readText(t, client))
+	}
+}
+
+func TestHub_Broadcast(t *testing.T) {
+	hub := NewHub()
+	url := newServer(t, WSConf{}, func(ctx *gin.Context, conn *Conn) error {
+		hub.Join(ctx.Query("job"), conn)
+		return conn.ReadLoop(nil)
+	})
+	a := dial(t, url+"?job=1", nil)
+	b := dial(t, url+"?job=1", nil)
+	other := dial(t, url+"?job=2", nil)
+	require.Eventually(t, func() bool { return hub.Count("1") == 2 && hub.Count("2") == 1 }, 2*time.Second, 5*time.Millisecond)
+
+	sent, err := hub.BroadcastJSON("1", map[string]int{"progress": 50})
+	require.NoError(t, err)
+	assert.Equal(t, 2, sent)
+	assert.Equal(t, `{"progress":50}`, readText(t, a))
+	assert.Equal(t, `{"progress":50}`, readText(t, b))
+
+	// 其他房间收不到
+	_ = other.SetReadDeadline(time.Now().Add(50 * time.Millisecond))
+	_, _, err = other.ReadMessage()
+	var netErr net.Error
+	assert.True(t, errors.As(err, &netErr) && netErr.Timeout())
+
+	// 连接关闭后自动离开房间
+	require.NoError(t, a.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, "")))
+	require.Eventually(t, func() bool { return hub.Count("1") == 1 }, 2*time.Second, 5*time.Millisecond)
+	assert.Equal(t, 1, hub.BroadcastText("1", "done"))
+	assert.Equal(t, "done", readText(t, b))
+}
+
+func TestUpgradeHandler_MessageTooBig(t *testing.T) {
+	result := make(chan error, 1)
+	url := newServer(t, WSConf{MaxMessageSize: 16}, func(ctx *gin.Context, conn *Conn) error {
+		err := conn.ReadLoop(nil)
+		result <- err
+		return err
+	})
+	client := dial(t, url, nil)
+
+	require.NoError(t, client.WriteMessage(websocket.TextMessage, []byte(strings.Repeat("x", 32))))
+	_ = client.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, _, err := client.ReadMessage()
+	assert.True(t, websocket.IsCloseError(err, websocket.CloseMessageTooBig), "got %v", err)
+	assert.ErrorIs(t, <-result, websocket.ErrReadLimit)
+}
+
+func TestUpgradeHandler_IdleTimeout(t *testing.T) {
+	result := make(chan error, 1)
+	url := newServer(t, WSConf{PingInterval: 20 * time.Millisecond, PongWait: 60 * time.Millisecond},
+		func(ctx *gin.Context, conn *Conn) error {
+			err := conn.ReadLoop(nil)
+			result <- err
+			return err
+		})
+	// 客户端不读取消息，不会回复 pong
+	_ = dial(t, url, nil)
+
+	select {
+	case err := <-result:
+		var netErr net.Error
+		assert.True(t, errors.As(err, &netErr) && netErr.Timeout(), "got %v", err)
+	case <-time.After(2 * time.Second):
+		t.Fatal("idle connection was not closed")
+	}
+}
+
+func TestUpgradeHandler_KeepAlive(t *testing.T) {
+	result := make(chan error, 1)
+	url := newServer(t, WSConf{PingInterval: 20 * time.Millisecond, PongWait: 60 * time.Millisecond},
+		func(ctx *gin.Context, conn *Conn) error {
+			err := conn.ReadLoop(nil)
+			result <- err
+			return err
+		})
+	client := dial(t, url, nil)
+	// 客户端读取时自动回复 pong，超过 PongWait 后连接仍然存活
+	go func() {
+		for {
+			if _, _, err := client.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	select {
+	case err := <-result:
+		t.Fatalf("connection closed while client answered pings: %v", err)
+	case <-time.After(200 * time.Millisecond):
+	}
+}
+
+func TestUpgradeHandler_Origin(t *testing.T) {
+	url := newServer(t, WSConf{AllowOrigins: []string{"https://*.example.com"}}, func(ctx *gin.Context, conn *Conn) error {
+		return conn.ReadLoop(nil)
+	})
+
+	_, resp, err := websocket.DefaultDialer.Dial(url, http.Header{"Origin": {"https://evil.com"}})
+	require.Error(t, err)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	dial(t, url, http.Header{"Origin": {"https://app.example.com"}})
+}
+
+func TestConn_SendAfterClose(t *testing.T) {
+	conns := make(chan *Conn, 1)
+	url := newServer(t, WSConf{}, func(ctx *gin.Context, conn *Conn) error {
+		conns <- conn
+		return nil
+	})
+	dial(t, url, nil)
+
+	conn := <-conns
+	<-conn.Done()
+	assert.ErrorIs(t, conn.SendText("late"), ErrClosed)
+}
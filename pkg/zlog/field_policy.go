@@ -0,0 +1,133 @@
+package zlog
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const redactedValue = "***"
+
+// fieldPolicy 全局字段策略，在 defaultEncoder 中对所有 core（包括 access 日志）生效
+// 字符串类字段超过 maxBytes 时截断，redacted 中的字段替换为 ***，数值等其他类型的字段不截断
+type fieldPolicy struct {
+	maxBytes int
+	redacted map[string]struct{}
+}
+
+func newFieldPolicy(maxBytes int, redactedFields []string) fieldPolicy {
+	p := fieldPolicy{maxBytes: max(maxBytes, 0)}
+	for _, name := range redactedFields {
+		if name = strings.TrimSpace(name); name != "" {
+			if p.redacted == nil {
+				p.redacted = make(map[string]struct{}, len(redactedFields))
+			}
+			p.redacted[strings.ToLower(name)] = struct{}{}
+		}
+	}
+	return p
+}
+
+func (p fieldPolicy) enabled() bool {
+	return p.maxBytes > 0 || len(p.redacted) > 0
+}
+
+func (p fieldPolicy) isRedacted(key string) bool {
+	if len(p.redacted) == 0 {
+		return false
+	}
+	_, ok := p.redacted[strings.ToLower(key)]
+	return ok
+}
+
+// truncate 按字节截断，不截断半个 UTF-8 字符
+func (p fieldPolicy) truncate(s string) string {
+	if p.maxBytes <= 0 || len(s) <= p.maxBytes {
+		return s
+	}
+	cut := p.maxBytes
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut] + "...(truncated, originalLen=" + strconv.Itoa(len(s)) + ")"
+}
+
+// applyFields 返回处理后的字段，没有需要处理的字段时返回原切片
+func (p fieldPolicy) applyFields(fields []zapcore.Field) []zapcore.Field {
+	if !p.enabled() {
+		return fields
+	}
+	var out []zapcore.Field
+	for i, f := range fields {
+		nf, changed := p.apply(f)
+		if !changed {
+			continue
+		}
+		if out == nil {
+			out = make([]zapcore.Field, len(fields))
+			copy(out, fields)
+		}
+		out[i] = nf
+	}
+	if out == nil {
+		return fields
+	}
+	return out
+}
+
+func (p fieldPolicy) apply(f zapcore.Field) (zapcore.Field, bool) {
+	if f.Type == zapcore.SkipType || f.Type == zapcore.NamespaceType {
+		return f, false
+	}
+	if p.isRedacted(f.Key) {
+		return zap.String(f.Key, redactedValue), true
+	}
+	if p.maxBytes <= 0 {
+		return f, false
+	}
+	var s string
+	switch f.Type {
+	case zapcore.StringType:
+		s = f.String
+	case zapcore.ByteStringType, zapcore.BinaryType:
+		b, _ := f.Interface.([]byte)
+		if len(b) <= p.maxBytes {
+			return f, false
+		}
+		s = string(b)
+	case zapcore.StringerType:
+		s = stringerValue(f.Interface)
+	case zapcore.ReflectType:
+		v := reflect.ValueOf(f.Interface)
+		if v.Kind() != reflect.String {
+			return f, false
+		}
+		s = v.String()
+	default:
+		return f, false
+	}
+	if len(s) <= p.maxBytes {
+		return f, false
+	}
+	if f.Type == zapcore.BinaryType {
+		return zap.Binary(f.Key, []byte(p.truncate(s))), true
+	}
+	return zap.String(f.Key, p.truncate(s)), true
+}
+
+func stringerValue(v any) (s string) {
+	defer func() {
+		if r := recover(); r != nil {
+			s = fmt.Sprintf("PANIC=%v", r)
+		}
+	}()
+	if stringer, ok := v.(fmt.Stringer); ok {
+		return stringer.String()
+	}
+	return fmt.Sprint(v)
+}
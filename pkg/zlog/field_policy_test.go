@@ -0,0 +1,75 @@
+package zlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+type secretString string
+
+func withFieldPolicy(t *testing.T, maxBytes int, redacted ...string) *bytes.Buffer {
+	oldPolicy, oldFormat := logConfig.FieldPolicy, logConfig.LogFormat
+	logConfig.FieldPolicy = newFieldPolicy(maxBytes, redacted)
+	logConfig.LogFormat = "json"
+	t.Cleanup(func() { logConfig.FieldPolicy, logConfig.LogFormat = oldPolicy, oldFormat })
+	return new(bytes.Buffer)
+}
+
+func decodeLine(t *testing.T, line string) map[string]any {
+	require.True(t, json.Valid([]byte(line)), line)
+	var m map[string]any
+	require.NoError(t, json.Unmarshal([]byte(line), &m))
+	return m
+}
+
+func TestFieldPolicy(t *testing.T) {
+	out := withFieldPolicy(t, 16, "password", "Authorization")
+	logger := zap.New(zapcore.NewCore(getEncoder(false), zapcore.AddSync(out), zapcore.DebugLevel))
+
+	respBody := `{"data":"` + strings.Repeat("x", 100) + `"}`
+	logger.Info("http invoke",
+		ByteString("respBody", []byte(respBody)),
+		String("request", strings.Repeat("中", 10)),
+		String("short", "ok"),
+		String("PASSWORD", "s3cret"),
+		Any("authorization", secretString("Bearer token")),
+		Any("note", secretString(strings.Repeat("n", 20))),
+		Int64("big", 12345678901234567),
+	)
+	m := decodeLine(t, out.String())
+	assert.Equal(t, `{"data":"xxxxxxx...(truncated, originalLen=111)`, m["respBody"])
+	// 不截断半个字符
+	assert.Equal(t, "中中中中中...(truncated, originalLen=30)", m["request"])
+	assert.Equal(t, "ok", m["short"])
+	assert.Equal(t, "***", m["PASSWORD"])
+	assert.Equal(t, "***", m["authorization"])
+	assert.Equal(t, "nnnnnnnnnnnnnnnn...(truncated, originalLen=20)", m["note"])
+	assert.Contains(t, out.String(), `"big":12345678901234567`)
+
+	// logger.With 添加的字段同样处理
+	out.Reset()
+	logger.With(String("password", "s3cret"), ByteString("body", []byte(strings.Repeat("y", 20)))).Info("with")
+	m = decodeLine(t, out.String())
+	assert.Equal(t, "***", m["password"])
+	assert.Equal(t, "yyyyyyyyyyyyyyyy...(truncated, originalLen=20)", m["body"])
+}
+
+func TestFieldPolicyDisabled(t *testing.T) {
+	out := withFieldPolicy(t, 0)
+	logger := zap.New(zapcore.NewCore(getEncoder(false), zapcore.AddSync(out), zapcore.DebugLevel))
+	long := strings.Repeat("x", 1000)
+	fields := []zapcore.Field{String("password", "s3cret"), String("body", long)}
+	assert.Equal(t, fields, logConfig.FieldPolicy.applyFields(fields))
+
+	logger.Info("disabled", fields...)
+	m := decodeLine(t, out.String())
+	assert.Equal(t, "s3cret", m["password"])
+	assert.Equal(t, long, m["body"])
+}
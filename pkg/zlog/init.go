@@ -19,13 +19,54 @@ type Buffer struct {
 	FlushInterval time.Duration `yaml:"flushInterval"`
 }
 
+// 日志文件切割配置
+type RotateConfig struct {
+	MaxAge       time.Duration `yaml:"maxAge"`       // 日志保留时长，默认14天
+	RotationTime time.Duration `yaml:"rotationTime"` // 切割间隔，默认24小时
+	MaxSize      int64         `yaml:"maxSize"`      // 单个文件最大字节数，超过后切割，0表示不限制
+}
+
 type LogConfig struct {
 	Level     string `yaml:"level"` // 显示的日志等级
 	Stdout    bool   `yaml:"stdout"`
 	Buffer    Buffer `yaml:"buffer"`
 	LogToFile bool   `yaml:"logToFile"`
 	Format    string `yaml:"format"`
-	LogDir    string `yaml:"logDir"`
+	// console 格式下控制台输出的日志级别是否着色，不设置时非容器环境开启，容器环境关闭；写入文件的日志不着色
+	Color *bool `yaml:"color"`
+	// 控制台输出时 WARN 及以上输出到 stderr，其余输出到 stdout，与文件的 .log/.log.wf 拆分一致，便于日志采集区分错误
+	ErrorToStderr bool   `yaml:"errorToStderr"`
+	LogDir        string `yaml:"logDir"`
+	// 单个字符串字段的最大字节数，超过时截断并追加 ...(truncated, originalLen=N)，0表示不限制，避免超长字段导致整条日志被采集丢弃
+	MaxFieldBytes int `yaml:"maxFieldBytes"`
+	// 需要脱敏的字段名（不区分大小写），所有日志中该字段的值都替换为 ***，如 password、authorization
+	RedactedFields []string `yaml:"redactedFields"`
+	// 各类型日志文件的切割配置，未设置的字段使用默认值
+	NormalLog RotateConfig `yaml:"normalLog"` // .log
+	ErrorLog  RotateConfig `yaml:"errorLog"`  // .log.wf
+	AccessLog RotateConfig `yaml:"accessLog"` // .log.access
+}
+
+// defaultRotateConfig 默认保留14天，每24小时切割一次
+func defaultRotateConfig() RotateConfig {
+	return RotateConfig{
+		MaxAge:       14 * 24 * time.Hour,
+		RotationTime: 24 * time.Hour,
+	}
+}
+
+func (rc RotateConfig) mergeWithDefault() RotateConfig {
+	defaultConf := defaultRotateConfig()
+	if rc.MaxAge <= 0 {
+		rc.MaxAge = defaultConf.MaxAge
+	}
+	if rc.RotationTime <= 0 {
+		rc.RotationTime = defaultConf.RotationTime
+	}
+	if rc.MaxSize < 0 {
+		rc.MaxSize = 0
+	}
+	return rc
 }
 
 // DefaultLogConfig 返回默认的日志配置
@@ -40,6 +81,9 @@ func DefaultLogConfig() LogConfig {
 			Size:          256 * 1024,      // 256KB
 			FlushInterval: 5 * time.Second, // 5秒
 		},
+		NormalLog: defaultRotateConfig(),
+		ErrorLog:  defaultRotateConfig(),
+		AccessLog: defaultRotateConfig(),
 	}
 }
 
@@ -66,6 +110,11 @@ func mergeWithDefault(userConf LogConfig) LogConfig {
 		userConf.Buffer.FlushInterval = defaultConf.Buffer.FlushInterval
 	}
 
+	// 文件切割配置合并
+	userConf.NormalLog = userConf.NormalLog.mergeWithDefault()
+	userConf.ErrorLog = userConf.ErrorLog.mergeWithDefault()
+	userConf.AccessLog = userConf.AccessLog.mergeWithDefault()
+
 	return userConf
 }
 
@@ -118,6 +167,14 @@ func (conf LogConfig) SetBuffer() {
 	}
 }
 
+func (conf LogConfig) SetRotate() {
+	logConfig.Rotate = map[string]RotateConfig{
+		txtLogNormal:    conf.NormalLog.mergeWithDefault(),
+		txtLogWarnFatal: conf.ErrorLog.mergeWithDefault(),
+		txtLogAccess:    conf.AccessLog.mergeWithDefault(),
+	}
+}
+
 func (conf LogConfig) SetLogOutput() {
 	// 使用用户配置的 LogDir
 	if conf.LogDir != "" {
@@ -130,6 +187,15 @@ func (conf LogConfig) SetLogOutput() {
 	if conf.Format != "" {
 		logConfig.LogFormat = conf.Format
 	}
+	// 级别着色只对 console 格式生效
+	if conf.Color != nil {
+		logConfig.Color = *conf.Color
+	} else {
+		logConfig.Color = !env.IsDockerPlatform()
+	}
+
+	logConfig.ErrorToStderr = conf.ErrorToStderr
+	logConfig.FieldPolicy = newFieldPolicy(conf.MaxFieldBytes, conf.RedactedFields)
 
 	// 判断是否输出到文件
 	if env.IsDockerPlatform() && !conf.LogToFile {
@@ -173,6 +239,11 @@ var logConfig = struct {
 	BufferSize          int
 	BufferFlushInterval time.Duration
 	LogFormat           string
+	Color               bool
+	ErrorToStderr       bool
+	FieldPolicy         fieldPolicy
+	// 文件切割配置，key为日志文件类型
+	Rotate map[string]RotateConfig
 }{
 	ZapLevel: zapcore.InfoLevel,
 
@@ -203,11 +274,13 @@ func InitLog(conf ...LogConfig) *zap.SugaredLogger {
 	logConf.SetLogLevel()
 	// 日志缓冲区设置
 	logConf.SetBuffer()
+	// 日志文件切割
+	logConf.SetRotate()
 	// 日志输出方式
 	logConf.SetLogOutput()
 	// 初始化全局logger
 	globalLogger = GetGlobalLogger()
-	Info(nil, "Logger initialized")
+	Infof(nil, "Logger initialized, version: %s, gitSha: %s, buildTime: %s", env.GetVersion(), env.GetGitSHA(), env.GetBuildTime())
 	return globalLogger
 }
 
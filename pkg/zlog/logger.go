@@ -63,7 +63,8 @@ var (
 
 // buildZapCore 构造 zapcore.Core，支持普通日志和 Access 日志类型
 func buildZapCore(isAccess bool) zapcore.Core {
-	encoder := getEncoder()
+	encoder := getEncoder(false)
+	stdEncoder := getEncoder(logConfig.Color)
 	name := logConfig.ModuleName
 	if name == "" {
 		name = "server"
@@ -81,9 +82,8 @@ func buildZapCore(isAccess bool) zapcore.Core {
 				return lvl >= logConfig.ZapLevel && lvl >= zapcore.DebugLevel
 			})
 
-			var cores []zapcore.Core
 			// 控制台输出
-			cores = append(cores, zapcore.NewCore(encoder, zapcore.AddSync(os.Stdout), stdLevel))
+			cores := consoleCores(stdEncoder, stdLevel)
 			if logConfig.Log2File {
 				cores = append(cores, zapcore.NewCore(encoder, getLogFileWriter(name, txtLogNormal), infoLevel))
 				cores = append(cores, zapcore.NewCore(encoder, getLogFileWriter(name, txtLogWarnFatal), errorLevel))
@@ -102,16 +102,39 @@ func buildZapCore(isAccess bool) zapcore.Core {
 			return lvl >= logConfig.ZapLevel && lvl >= zapcore.DebugLevel
 		})
 
-		var cores []zapcore.Core
 		// 控制台输出
-		cores = append(cores, zapcore.NewCore(encoder, zapcore.AddSync(os.Stdout), stdLevel))
+		cores := consoleCores(stdEncoder, stdLevel)
 		cores = append(cores, zapcore.NewCore(encoder, getLogFileWriter(name, txtLogAccess), infoLevel))
 		baseAccessCore = zapcore.NewTee(cores...)
 	})
 	return baseAccessCore
 }
 
-func getEncoder() zapcore.Encoder {
+// 控制台输出的目标
+var (
+	consoleStdout zapcore.WriteSyncer = os.Stdout
+	consoleStderr zapcore.WriteSyncer = os.Stderr
+)
+
+// consoleCores 控制台输出的 core，开启 ErrorToStderr 时 WARN 及以上输出到 stderr，其余输出到 stdout
+func consoleCores(encoder zapcore.Encoder, enabler zapcore.LevelEnabler) []zapcore.Core {
+	if !logConfig.ErrorToStderr {
+		return []zapcore.Core{zapcore.NewCore(encoder, consoleStdout, enabler)}
+	}
+	outLevel := zap.LevelEnablerFunc(func(lvl zapcore.Level) bool {
+		return enabler.Enabled(lvl) && lvl < zapcore.WarnLevel
+	})
+	errLevel := zap.LevelEnablerFunc(func(lvl zapcore.Level) bool {
+		return enabler.Enabled(lvl) && lvl >= zapcore.WarnLevel
+	})
+	return []zapcore.Core{
+		zapcore.NewCore(encoder, consoleStdout, outLevel),
+		zapcore.NewCore(encoder.Clone(), consoleStderr, errLevel),
+	}
+}
+
+// getEncoder color 为 true 且为 console 格式时日志级别带 ANSI 颜色，JSON 格式不受影响
+func getEncoder(color bool) zapcore.Encoder {
 	// time字段编码器
 	timeEncoder := zapcore.TimeEncoderOfLayout("2006-01-02 15:04:05.999")
 	encoderCfg := zapcore.EncoderConfig{
@@ -128,6 +151,9 @@ func getEncoder() zapcore.Encoder {
 	}
 	var encoder zapcore.Encoder
 	if logConfig.LogFormat == "console" {
+		if color {
+			encoderCfg.EncodeLevel = zapcore.CapitalColorLevelEncoder
+		}
 		encoder = zapcore.NewConsoleEncoder(encoderCfg)
 	} else {
 		encoder = zapcore.NewJSONEncoder(encoderCfg)
@@ -148,20 +174,61 @@ func (enc *defaultEncoder) Clone() zapcore.Encoder {
 
 func (enc *defaultEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
 	ent.Time = time.Now()
-	return enc.Encoder.EncodeEntry(ent, fields)
+	return enc.Encoder.EncodeEntry(ent, logConfig.FieldPolicy.applyFields(fields))
+}
+
+// 以下方法处理 logger.With 添加的字段，这些字段在 With 时直接写入 encoder，不经过 EncodeEntry
+func (enc *defaultEncoder) AddString(key, value string) {
+	policy := logConfig.FieldPolicy
+	if policy.isRedacted(key) {
+		value = redactedValue
+	}
+	enc.Encoder.AddString(key, policy.truncate(value))
+}
+
+func (enc *defaultEncoder) AddByteString(key string, value []byte) {
+	policy := logConfig.FieldPolicy
+	if policy.isRedacted(key) {
+		enc.Encoder.AddString(key, redactedValue)
+		return
+	}
+	if policy.maxBytes > 0 && len(value) > policy.maxBytes {
+		enc.Encoder.AddString(key, policy.truncate(string(value)))
+		return
+	}
+	enc.Encoder.AddByteString(key, value)
+}
+
+func (enc *defaultEncoder) AddReflected(key string, value interface{}) error {
+	if f, changed := logConfig.FieldPolicy.apply(zap.Reflect(key, value)); changed {
+		enc.Encoder.AddString(key, f.String)
+		return nil
+	}
+	return enc.Encoder.AddReflected(key, value)
 }
 
 func getLogFileWriter(name, loggerType string) (ws zapcore.WriteSyncer) {
 	logDir := strings.TrimSuffix(logConfig.Path, "/")
+	rotate, ok := logConfig.Rotate[loggerType]
+	if !ok {
+		rotate = defaultRotateConfig()
+	}
 	filenamePattern := filepath.Join(logDir, appendLogFileTail(name, loggerType, true))
+	if rotate.RotationTime < 24*time.Hour {
+		// 切割间隔小于一天时文件名精确到小时，避免同一天的文件名冲突
+		filenamePattern = strings.Replace(filenamePattern, "%Y-%m-%d", "%Y-%m-%d-%H", 1)
+	}
 	filename := filepath.Join(logDir, appendLogFileTail(name, loggerType, false))
-	// Info按日期切割日志，每天一个新文件
-	fileWriter, _ := rotatelogs.New(
-		filenamePattern,                           // 生成的日志文件格式
-		rotatelogs.WithLinkName(filename),         // 软链接，指向最新日志
-		rotatelogs.WithMaxAge(14*24*time.Hour),    // 只保留 14 天的日志
-		rotatelogs.WithRotationTime(24*time.Hour), // 每 24 小时切割一次
-	)
+	// 默认按日期切割日志，每天一个新文件
+	options := []rotatelogs.Option{
+		rotatelogs.WithLinkName(filename),                // 软链接，指向最新日志
+		rotatelogs.WithMaxAge(rotate.MaxAge),             // 日志保留时长
+		rotatelogs.WithRotationTime(rotate.RotationTime), // 切割间隔
+	}
+	if rotate.MaxSize > 0 {
+		options = append(options, rotatelogs.WithRotationSize(rotate.MaxSize)) // 按大小切割
+	}
+	fileWriter, _ := rotatelogs.New(filenamePattern, options...)
 	if !logConfig.BufferSwitch {
 		return zapcore.AddSync(fileWriter)
 	}
@@ -42,7 +42,7 @@ func zapAccessLogger(ctx *gin.Context) *zap.Logger {
 			return l
 		}
 	}
-	l := LoggerWithContext(m, ctx)
+	l := LoggerWithRequestID(m, ctx)
 	l.With(
 		String("uri", GetRequestUri(ctx)),
 		String("localIp", env.LocalIP),
@@ -52,5 +52,8 @@ func zapAccessLogger(ctx *gin.Context) *zap.Logger {
 }
 
 func AccessInfo(ctx *gin.Context, fields ...zap.Field) {
+	if noLog(ctx) {
+		return
+	}
 	zapAccessLogger(ctx).Info("accesslog", fields...)
 }
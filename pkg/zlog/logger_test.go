@@ -0,0 +1,72 @@
+package zlog
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func encodeWarn(t *testing.T, format string, color bool) string {
+	old := logConfig.LogFormat
+	logConfig.LogFormat = format
+	defer func() { logConfig.LogFormat = old }()
+
+	buf, err := getEncoder(color).EncodeEntry(zapcore.Entry{Level: zapcore.WarnLevel, Time: time.Now(), Message: "hello"}, nil)
+	require.NoError(t, err)
+	defer buf.Free()
+	return buf.String()
+}
+
+func TestGetEncoderColor(t *testing.T) {
+	// console 格式着色时级别带 ANSI 颜色（WARN 为黄色）
+	assert.Contains(t, encodeWarn(t, "console", true), "\x1b[33mWARN\x1b[0m")
+
+	plain := encodeWarn(t, "console", false)
+	assert.Contains(t, plain, "WARN")
+	assert.NotContains(t, plain, "\x1b[")
+
+	// JSON 格式不受影响
+	json := encodeWarn(t, "json", true)
+	assert.Contains(t, json, `"level":"WARN"`)
+	assert.NotContains(t, json, "\x1b[")
+}
+
+func consoleLogger(t *testing.T, errorToStderr bool) (*zap.Logger, *bytes.Buffer, *bytes.Buffer) {
+	stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+	oldOut, oldErr, oldFlag := consoleStdout, consoleStderr, logConfig.ErrorToStderr
+	consoleStdout, consoleStderr, logConfig.ErrorToStderr = zapcore.AddSync(stdout), zapcore.AddSync(stderr), errorToStderr
+	t.Cleanup(func() {
+		consoleStdout, consoleStderr, logConfig.ErrorToStderr = oldOut, oldErr, oldFlag
+	})
+	cores := consoleCores(getEncoder(false), zapcore.DebugLevel)
+	return zap.New(zapcore.NewTee(cores...)), stdout, stderr
+}
+
+func TestConsoleCoresErrorToStderr(t *testing.T) {
+	logger, stdout, stderr := consoleLogger(t, true)
+	logger.Debug("debug msg")
+	logger.Info("info msg")
+	logger.Warn("warn msg")
+	logger.Error("error msg")
+
+	assert.Contains(t, stdout.String(), "debug msg")
+	assert.Contains(t, stdout.String(), "info msg")
+	assert.NotContains(t, stdout.String(), "warn msg")
+	assert.NotContains(t, stdout.String(), "error msg")
+	assert.Contains(t, stderr.String(), "warn msg")
+	assert.Contains(t, stderr.String(), "error msg")
+	assert.NotContains(t, stderr.String(), "info msg")
+
+	// 默认全部输出到 stdout
+	logger, stdout, stderr = consoleLogger(t, false)
+	logger.Info("info msg")
+	logger.Error("error msg")
+	assert.Contains(t, stdout.String(), "info msg")
+	assert.Contains(t, stdout.String(), "error msg")
+	assert.Empty(t, stderr.String())
+}
@@ -20,34 +20,74 @@ import (
 
 const (
 	ContextKeyRequestID = "request_id"
+	ContextKeySpanID    = "span_id"
+
+	// 上游传入的请求ID超过该长度时忽略，避免日志被超长header污染
+	maxInboundRequestIDLen = 128
 )
 
+// 上游传递请求ID的请求头，按顺序取第一个非空值，request_id 兼容旧版本的请求头
+var requestIDHeaders = []string{"Request-Id", "X-Request-Id", ContextKeyRequestID}
+
+// SetRequestIDHeaders 设置读取上游请求ID的请求头，需在服务启动时调用
+func SetRequestIDHeaders(headers ...string) {
+	if len(headers) == 0 {
+		return
+	}
+	requestIDHeaders = headers
+}
+
+// GetRequestID 获取请求ID：优先使用上下文，其次上游请求头，都没有时生成新的ID，结果写入上下文
 func GetRequestID(ctx *gin.Context) string {
 	if ctx == nil {
 		return genRequestID()
 	}
 
-	// 从ctx中获取
+	// 从ctx中获取，业务显式设置的值优先
 	if r := ctx.GetString(ContextKeyRequestID); r != "" {
 		return r
 	}
 	// 请求头是上层传下来的
-	var requestID string
-	if ctx.Request != nil && ctx.Request.Header != nil {
-		requestID = ctx.Request.Header.Get(ContextKeyRequestID)
-	}
+	requestID := inboundRequestID(ctx)
 	if len(requestID) > 0 {
 		if strings.Contains(requestID, ":") {
 			tt := strings.Split(requestID, ":")
 			requestID = fmt.Sprintf("%s:%016x", tt[0], uint64(generator.Int63()))
 		}
-		return requestID
+	} else {
+		requestID = genRequestID()
 	}
-	requestID = genRequestID()
 	ctx.Set(ContextKeyRequestID, requestID)
 	return requestID
 }
 
+func inboundRequestID(ctx *gin.Context) string {
+	if ctx.Request == nil || ctx.Request.Header == nil {
+		return ""
+	}
+	for _, header := range requestIDHeaders {
+		requestID := strings.TrimSpace(ctx.Request.Header.Get(header))
+		if requestID != "" && len(requestID) <= maxInboundRequestIDLen {
+			return requestID
+		}
+	}
+	return ""
+}
+
+// GetOrNewSpanID 获取当前请求的spanID，不存在时生成并写入上下文
+// 使用同一个ctx的协程共享spanID，便于日志关联
+func GetOrNewSpanID(ctx *gin.Context) string {
+	if ctx == nil {
+		return genSpanID()
+	}
+	if spanID := ctx.GetString(ContextKeySpanID); spanID != "" {
+		return spanID
+	}
+	spanID := genSpanID()
+	ctx.Set(ContextKeySpanID, spanID)
+	return spanID
+}
+
 var generator = newRand(time.Now().UnixNano())
 
 func genRequestID() string {
@@ -57,6 +97,10 @@ func genRequestID() string {
 	return buffer.String()
 }
 
+func genSpanID() string {
+	return fmt.Sprintf("%016x", uint64(generator.Int63()))
+}
+
 type LockedSource struct {
 	mut sync.Mutex
 	src rand.Source
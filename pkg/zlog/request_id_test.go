@@ -0,0 +1,101 @@
+package zlog
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+func newRequestIDTestContext(headers map[string]string) *gin.Context {
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	ctx.Request = httptest.NewRequest(http.MethodGet, "/api", nil)
+	for k, v := range headers {
+		ctx.Request.Header.Set(k, v)
+	}
+	return ctx
+}
+
+func TestGetRequestIDFromHeader(t *testing.T) {
+	ctx := newRequestIDTestContext(map[string]string{"Request-Id": "upstream-id"})
+	assert.Equal(t, "upstream-id", GetRequestID(ctx))
+	assert.Equal(t, "upstream-id", GetRequestID(ctx))
+	// 写入上下文，供 ctx.Value 的使用方读取
+	assert.Equal(t, "upstream-id", ctx.Value(ContextKeyRequestID))
+
+	ctx = newRequestIDTestContext(map[string]string{"X-Request-Id": "x-upstream"})
+	assert.Equal(t, "x-upstream", GetRequestID(ctx))
+
+	// 兼容旧版本的 request_id 请求头
+	ctx = newRequestIDTestContext(map[string]string{"request_id": "legacy"})
+	assert.Equal(t, "legacy", GetRequestID(ctx))
+}
+
+func TestGetRequestIDSuffix(t *testing.T) {
+	// prefix:xxx 保留前缀并重新生成后缀，同一个请求内保持不变
+	ctx := newRequestIDTestContext(map[string]string{"Request-Id": "upstream-id:1"})
+	requestID := GetRequestID(ctx)
+	assert.Regexp(t, `^upstream-id:[0-9a-f]{16}$`, requestID)
+	assert.Equal(t, requestID, GetRequestID(ctx))
+}
+
+func TestGetRequestIDContextWins(t *testing.T) {
+	ctx := newRequestIDTestContext(map[string]string{"Request-Id": "upstream-id"})
+	ctx.Set(ContextKeyRequestID, "manual")
+	assert.Equal(t, "manual", GetRequestID(ctx))
+}
+
+func TestGetRequestIDGenerate(t *testing.T) {
+	ctx := newRequestIDTestContext(nil)
+	requestID := GetRequestID(ctx)
+	assert.NotEmpty(t, requestID)
+	assert.Equal(t, requestID, GetRequestID(ctx))
+
+	ctx = newRequestIDTestContext(nil)
+	ctx.Set(ContextKeyRequestID, "manual")
+	assert.Equal(t, "manual", GetRequestID(ctx))
+}
+
+func TestSetRequestIDHeaders(t *testing.T) {
+	origin := requestIDHeaders
+	t.Cleanup(func() { requestIDHeaders = origin })
+
+	SetRequestIDHeaders("X-Trace-Id")
+	ctx := newRequestIDTestContext(map[string]string{"Request-Id": "ignored", "X-Trace-Id": "trace"})
+	assert.Equal(t, "trace", GetRequestID(ctx))
+}
+
+func TestGetOrNewSpanID(t *testing.T) {
+	ctx := newRequestIDTestContext(nil)
+	spanID := GetOrNewSpanID(ctx)
+	assert.Len(t, spanID, 16)
+
+	// 同一个ctx的协程共享spanID
+	done := make(chan string)
+	go func() { done <- GetOrNewSpanID(ctx) }()
+	assert.Equal(t, spanID, <-done)
+}
+
+func TestAccessLogRequestID(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	origin := accessLogger
+	accessLogger = zap.New(core)
+	t.Cleanup(func() { accessLogger = origin })
+
+	ctx := newRequestIDTestContext(map[string]string{"Request-Id": "upstream-id"})
+	AccessInfo(ctx, String("method", http.MethodGet))
+
+	entries := logs.All()
+	assert.Len(t, entries, 1)
+	fields := entries[0].ContextMap()
+	assert.Equal(t, "upstream-id", fields["requestId"])
+	assert.Equal(t, GetOrNewSpanID(ctx), fields["spanId"])
+}
@@ -1,6 +1,7 @@
 package zlog
 
 import (
+	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -12,6 +13,7 @@ const (
 	ContextKeyNoLog  = "_no_log"
 	ContextKeyUri    = "_uri"
 	customerFieldKey = "__customerFields"
+	accessLogOptsKey = "__accessLogOptions"
 )
 
 func GetRequestUri(ctx *gin.Context) string {
@@ -23,7 +25,7 @@ func GetRequestUri(ctx *gin.Context) string {
 
 // a new method for customer notice
 func AddField(c *gin.Context, field ...Field) {
-	customerFields := GetCustomerFields(c)
+	customerFields := addedFields(c)
 	if customerFields == nil {
 		customerFields = field
 	} else {
@@ -33,8 +35,32 @@ func AddField(c *gin.Context, field ...Field) {
 	c.Set(customerFieldKey, customerFields)
 }
 
-// 获得所有用户自定义的Field
+// 获得所有用户自定义的Field，并合并上下文字段提取器的输出（trace_id/span_id 等）
+// 与 AddField 添加的字段同名时以 AddField 为准
 func GetCustomerFields(c *gin.Context) (customerFields []Field) {
+	customerFields = addedFields(c)
+	ctxFields := GetContextFields(c)
+	if len(ctxFields) == 0 {
+		return customerFields
+	}
+	added := make(map[string]struct{}, len(customerFields))
+	for _, f := range customerFields {
+		added[f.Key] = struct{}{}
+	}
+	customerFields = append([]Field(nil), customerFields...)
+	for _, f := range ctxFields {
+		if _, ok := added[f.Key]; !ok {
+			customerFields = append(customerFields, f)
+		}
+	}
+	return customerFields
+}
+
+// addedFields 通过 AddField 添加的字段
+func addedFields(c *gin.Context) (customerFields []Field) {
+	if c == nil {
+		return nil
+	}
 	if v, exist := c.Get(customerFieldKey); exist {
 		customerFields, _ = v.([]Field)
 	}
@@ -49,6 +75,33 @@ func SetLogFlag(ctx *gin.Context) {
 	ctx.Set(ContextKeyNoLog, false)
 }
 
+// AccessLogOptions 单个请求的 access 日志配置，覆盖 middleware.AccessLog 的全局配置
+type AccessLogOptions struct {
+	// Skip 不打印该请求的 access 日志，也不再缓存响应体
+	Skip bool
+	// MaxReqBodyLen 0表示沿用全局配置，-1表示不打印。请求体在业务处理前已读取，只能比全局配置更短
+	MaxReqBodyLen int
+	// MaxRespBodyLen 0表示沿用全局配置，-1表示不打印也不缓存响应体（如文件下载），大于0时只缓存前 N 字节
+	MaxRespBodyLen int
+}
+
+// SetAccessLogOptions 在 handler 或路由中间件中调用，覆盖当前请求的 access 日志配置
+// 需在写响应之前调用，之前写入的响应体已被缓存
+func SetAccessLogOptions(ctx *gin.Context, opts AccessLogOptions) {
+	ctx.Set(accessLogOptsKey, opts)
+}
+
+// GetAccessLogOptions 获取当前请求的 access 日志配置，未设置时 ok 为 false
+func GetAccessLogOptions(ctx *gin.Context) (opts AccessLogOptions, ok bool) {
+	if ctx == nil {
+		return opts, false
+	}
+	if v, exist := ctx.Get(accessLogOptsKey); exist {
+		opts, ok = v.(AccessLogOptions)
+	}
+	return opts, ok
+}
+
 func noLog(ctx *gin.Context) bool {
 	if ctx == nil {
 		return false
@@ -68,12 +121,55 @@ func GetRequestCost(start, end time.Time) float64 {
 	return float64(end.Sub(start).Nanoseconds()/1e4) / 100.0
 }
 
+// ContextFieldExtractor 从请求上下文中提取额外的日志字段，例如 OpenTelemetry 的 trace_id/span_id
+type ContextFieldExtractor func(ctx *gin.Context) []Field
+
+var (
+	contextFieldExtractors []ContextFieldExtractor
+	extractorLock          sync.RWMutex
+)
+
+// RegisterContextFieldExtractor 注册上下文字段提取器，提取的字段会附加到所有带上下文的日志中
+// （业务日志、access日志以及 mysql/redis/http 组件日志），需在服务启动时注册
+func RegisterContextFieldExtractor(fn ContextFieldExtractor) {
+	if fn == nil {
+		return
+	}
+	extractorLock.Lock()
+	contextFieldExtractors = append(contextFieldExtractors, fn)
+	extractorLock.Unlock()
+}
+
+// GetContextFields 执行所有已注册的提取器，返回上下文相关的日志字段
+func GetContextFields(ctx *gin.Context) []Field {
+	if ctx == nil {
+		return nil
+	}
+	extractorLock.RLock()
+	defer extractorLock.RUnlock()
+	var fields []Field
+	for _, fn := range contextFieldExtractors {
+		fields = append(fields, fn(ctx)...)
+	}
+	return fields
+}
+
 // 返回带上下文信息的 zap.Logger
 func LoggerWithContext(baseLogger *zap.Logger, ctx *gin.Context) *zap.Logger {
+	if ctx == nil || baseLogger == nil {
+		return baseLogger
+	}
+	return LoggerWithRequestID(baseLogger, ctx).With(GetContextFields(ctx)...)
+}
+
+// LoggerWithRequestID 只附加 requestId/spanId，用于 access 日志
+// access 日志通过 GetCustomerFields 附加上下文字段，避免与 LoggerWithContext 重复
+func LoggerWithRequestID(baseLogger *zap.Logger, ctx *gin.Context) *zap.Logger {
 	if ctx == nil || baseLogger == nil {
 		return baseLogger
 	}
 	return baseLogger.With(
 		String("requestId", GetRequestID(ctx)),
+		String("spanId", GetOrNewSpanID(ctx)),
 	)
 }
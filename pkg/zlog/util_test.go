@@ -0,0 +1,69 @@
+package zlog
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+type traceKey struct{}
+
+func registerTraceExtractor(t *testing.T) {
+	origin := contextFieldExtractors
+	t.Cleanup(func() { contextFieldExtractors = origin })
+	RegisterContextFieldExtractor(func(ctx *gin.Context) []Field {
+		if ctx.Request == nil {
+			return nil
+		}
+		if id, ok := ctx.Request.Context().Value(traceKey{}).(string); ok {
+			return []Field{String("trace_id", id)}
+		}
+		return nil
+	})
+}
+
+func TestGetCustomerFieldsMergesContextFields(t *testing.T) {
+	registerTraceExtractor(t)
+	ctx := newRequestIDTestContext(nil)
+	assert.Empty(t, GetCustomerFields(ctx))
+
+	ctx.Request = ctx.Request.WithContext(context.WithValue(ctx.Request.Context(), traceKey{}, "t1"))
+	AddField(ctx, String("uid", "42"))
+	assert.Equal(t, []Field{String("uid", "42"), String("trace_id", "t1")}, GetCustomerFields(ctx))
+
+	// AddField 不会把提取器的输出保存下来，同名字段以 AddField 为准
+	AddField(ctx, String("trace_id", "t0"))
+	assert.Equal(t, []Field{String("uid", "42"), String("trace_id", "t0")}, GetCustomerFields(ctx))
+	assert.Nil(t, GetCustomerFields(nil))
+}
+
+func TestAccessLogContextFieldsOnce(t *testing.T) {
+	registerTraceExtractor(t)
+	core, logs := observer.New(zap.InfoLevel)
+	origin := accessLogger
+	accessLogger = zap.New(core)
+	t.Cleanup(func() { accessLogger = origin })
+
+	ctx := newRequestIDTestContext(nil)
+	ctx.Request = ctx.Request.WithContext(context.WithValue(ctx.Request.Context(), traceKey{}, "t1"))
+	AccessInfo(ctx, GetCustomerFields(ctx)...)
+
+	entries := logs.All()
+	assert.Len(t, entries, 1)
+	n := 0
+	for _, f := range entries[0].Context {
+		if f.Key == "trace_id" {
+			n++
+		}
+	}
+	assert.Equal(t, 1, n)
+
+	// 业务日志通过 LoggerWithContext 附加上下文字段
+	l := LoggerWithContext(zap.New(core), ctx)
+	l.Info("biz")
+	assert.Equal(t, "t1", logs.All()[1].ContextMap()["trace_id"])
+}
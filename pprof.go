@@ -0,0 +1,138 @@
+// Package golib -----------------------------
+// @file      : pprof.go
+// @author    : xiangtao
+// @contact   : xiangtao1994@gmail.com
+// @time      : 2025/9/10 16:32
+// Description: 可选的 pprof 端点，支持鉴权和独立监听端口
+// -------------------------------------------
+package golib
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/xiangtao94/golib/pkg/env"
+	"github.com/xiangtao94/golib/pkg/zlog"
+)
+
+const pprofPrefix = "/debug/pprof"
+
+// pprofWriteTimeout profile/trace 默认采样30s，seconds 参数需小于该值
+const pprofWriteTimeout = 2 * time.Minute
+
+type PprofConf struct {
+	// EnableInRelease 生产环境（GIN_MODE=release）默认不开启，需显式打开
+	EnableInRelease bool `yaml:"enableInRelease"`
+	// Token 不为空时要求请求头 Authorization: Bearer {Token}
+	Token string `yaml:"token"`
+	// Username/Password 不为空时要求 Basic Auth
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+	// Addr 不为空时在独立端口监听，如 127.0.0.1:6060，不挂载到业务engine
+	Addr string `yaml:"addr"`
+	// Legacy 兼容旧行为：直接挂载 http.DefaultServeMux 且不做鉴权
+	Legacy bool `yaml:"legacy"`
+}
+
+// WithPprof 注册 pprof 端点，未传入该选项时不暴露 pprof
+func WithPprof(conf ...PprofConf) BootstrapOption {
+	return func(engine *gin.Engine) {
+		var c PprofConf
+		if len(conf) > 0 {
+			c = conf[0]
+		}
+		if c.Legacy {
+			engine.GET(pprofPrefix+"/*any", gin.WrapH(http.DefaultServeMux))
+			return
+		}
+		if env.IsDockerPlatform() && !c.EnableInRelease {
+			return
+		}
+		if c.Addr == "" {
+			registerPprof(engine, c)
+			return
+		}
+		startPprofServer(c)
+	}
+}
+
+// startPprofServer 在独立端口监听，随应用退出关闭
+func startPprofServer(c PprofConf) {
+	pprofEngine := gin.New()
+	registerPprof(pprofEngine, c)
+	srv := &http.Server{
+		Addr:              c.Addr,
+		Handler:           pprofEngine,
+		ReadTimeout:       10 * time.Second,
+		ReadHeaderTimeout: 10 * time.Second,
+		WriteTimeout:      pprofWriteTimeout,
+		IdleTimeout:       120 * time.Second,
+	}
+	lis, err := net.Listen("tcp", c.Addr)
+	if err != nil {
+		zlog.Errorf(nil, "pprof listen on %s error: %v", c.Addr, err)
+		return
+	}
+	go func() {
+		if err := srv.Serve(lis); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			zlog.Errorf(nil, "pprof serve on %s error: %v", c.Addr, err)
+		}
+	}()
+	OnShutdown("pprof", srv.Shutdown)
+}
+
+func registerPprof(engine *gin.Engine, conf PprofConf) {
+	engine.GET(pprofPrefix+"/*any", pprofAuth(conf), pprofHandler)
+	engine.POST(pprofPrefix+"/symbol", pprofAuth(conf), gin.WrapF(pprof.Symbol))
+}
+
+// pprofHandler 只分发 pprof 自身的handler，不经过 DefaultServeMux
+func pprofHandler(ctx *gin.Context) {
+	zlog.SetNoLogFlag(ctx)
+	switch strings.TrimPrefix(ctx.Param("any"), "/") {
+	case "cmdline":
+		pprof.Cmdline(ctx.Writer, ctx.Request)
+	case "profile":
+		pprof.Profile(ctx.Writer, ctx.Request)
+	case "symbol":
+		pprof.Symbol(ctx.Writer, ctx.Request)
+	case "trace":
+		pprof.Trace(ctx.Writer, ctx.Request)
+	default:
+		// 首页以及 heap、goroutine 等命名profile
+		pprof.Index(ctx.Writer, ctx.Request)
+	}
+}
+
+func pprofAuth(conf PprofConf) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if conf.Token != "" {
+			token, ok := strings.CutPrefix(ctx.GetHeader("Authorization"), "Bearer ")
+			if ok && subtle.ConstantTimeCompare([]byte(token), []byte(conf.Token)) == 1 {
+				ctx.Next()
+				return
+			}
+		}
+		if conf.Username != "" {
+			user, pass, ok := ctx.Request.BasicAuth()
+			if ok && subtle.ConstantTimeCompare([]byte(user), []byte(conf.Username)) == 1 &&
+				subtle.ConstantTimeCompare([]byte(pass), []byte(conf.Password)) == 1 {
+				ctx.Next()
+				return
+			}
+			ctx.Header("WWW-Authenticate", `Basic realm="pprof"`)
+		}
+		if conf.Token == "" && conf.Username == "" {
+			ctx.Next()
+			return
+		}
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+	}
+}
@@ -0,0 +1,91 @@
+package golib
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func doPprofRequest(engine *gin.Engine, path string, setAuth func(r *http.Request)) int {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, path, nil)
+	if setAuth != nil {
+		setAuth(req)
+	}
+	engine.ServeHTTP(w, req)
+	return w.Code
+}
+
+func TestPprofDisabledByDefault(t *testing.T) {
+	engine := gin.New()
+	Bootstraps(engine)
+
+	assert.Equal(t, http.StatusNotFound, doPprofRequest(engine, "/debug/pprof/", nil))
+	assert.Equal(t, http.StatusNotFound, doPprofRequest(engine, "/debug/pprof/heap", nil))
+}
+
+func TestPprofTokenAuth(t *testing.T) {
+	engine := gin.New()
+	Bootstraps(engine, WithPprof(PprofConf{Token: "secret"}))
+
+	assert.Equal(t, http.StatusUnauthorized, doPprofRequest(engine, "/debug/pprof/heap", nil))
+	assert.Equal(t, http.StatusUnauthorized, doPprofRequest(engine, "/debug/pprof/heap", func(r *http.Request) {
+		r.Header.Set("Authorization", "Bearer wrong")
+	}))
+	// 缺少 Bearer 前缀时拒绝
+	assert.Equal(t, http.StatusUnauthorized, doPprofRequest(engine, "/debug/pprof/heap", func(r *http.Request) {
+		r.Header.Set("Authorization", "secret")
+	}))
+	assert.Equal(t, http.StatusOK, doPprofRequest(engine, "/debug/pprof/heap?debug=1", func(r *http.Request) {
+		r.Header.Set("Authorization", "Bearer secret")
+	}))
+}
+
+func TestPprofSeparateAddr(t *testing.T) {
+	resetShutdownHooks(t)
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := lis.Addr().String()
+	require.NoError(t, lis.Close())
+
+	engine := gin.New()
+	Bootstraps(engine, WithPprof(PprofConf{Addr: addr, Token: "secret"}))
+	// 不挂载到业务engine
+	assert.Equal(t, http.StatusNotFound, doPprofRequest(engine, "/debug/pprof/heap", nil))
+
+	req, _ := http.NewRequest(http.MethodGet, "http://"+addr+"/debug/pprof/cmdline", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// 退出钩子关闭独立监听
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	runShutdownHooks(ctx)
+	_, err = http.Get("http://" + addr + "/debug/pprof/cmdline")
+	assert.Error(t, err)
+}
+
+func TestPprofBasicAuth(t *testing.T) {
+	engine := gin.New()
+	Bootstraps(engine, WithPprof(PprofConf{Username: "ops", Password: "pass"}))
+
+	assert.Equal(t, http.StatusUnauthorized, doPprofRequest(engine, "/debug/pprof/cmdline", func(r *http.Request) {
+		r.SetBasicAuth("ops", "wrong")
+	}))
+	assert.Equal(t, http.StatusOK, doPprofRequest(engine, "/debug/pprof/cmdline", func(r *http.Request) {
+		r.SetBasicAuth("ops", "pass")
+	}))
+	assert.Equal(t, http.StatusOK, doPprofRequest(engine, "/debug/pprof/goroutine?debug=1", func(r *http.Request) {
+		r.SetBasicAuth("ops", "pass")
+	}))
+}
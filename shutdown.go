@@ -0,0 +1,133 @@
+// Package golib -----------------------------
+// @file      : shutdown.go
+// @author    : xiangtao
+// @contact   : xiangtao1994@gmail.com
+// @time      : 2025/9/9 15:20
+// Description: 优雅退出钩子，HTTP服务关闭后按注册的逆序释放资源
+// -------------------------------------------
+package golib
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/xiangtao94/golib/pkg/redis"
+	"github.com/xiangtao94/golib/pkg/zlog"
+)
+
+const defaultShutdownTimeout = 5 * time.Second
+
+// ShutdownConfig 优雅退出配置
+type ShutdownConfig struct {
+	// Timeout 整体退出时限（HTTP Shutdown + 后台任务 + 所有钩子），默认5s
+	Timeout time.Duration
+	// DrainDelay 收到信号后、关闭监听前的等待时间，用于k8s摘除endpoint期间继续处理请求
+	DrainDelay time.Duration
+}
+
+// hook 生命周期钩子，退出钩子和 App 的初始化、就绪钩子共用
+type hook struct {
+	name    string
+	fn      func(ctx context.Context) error
+	timeout time.Duration
+}
+
+type ShutdownOption func(*hook)
+
+// WithHookTimeout 设置单个钩子的超时时间，不设置时以整体时限为准
+func WithHookTimeout(timeout time.Duration) ShutdownOption {
+	return func(h *hook) {
+		h.timeout = timeout
+	}
+}
+
+var (
+	shutdownMu    sync.Mutex
+	shutdownHooks []hook
+)
+
+// OnShutdown 注册退出钩子，在 HTTP 服务关闭后按注册的逆序执行
+// 先初始化的资源（如数据库）后关闭，依赖它的资源先关闭
+func OnShutdown(name string, fn func(ctx context.Context) error, opts ...ShutdownOption) {
+	h := hook{name: name, fn: fn}
+	for _, opt := range opts {
+		opt(&h)
+	}
+	shutdownMu.Lock()
+	shutdownHooks = append(shutdownHooks, h)
+	shutdownMu.Unlock()
+}
+
+// OnShutdownRedis 注册 redis 客户端关闭
+func OnShutdownRedis(client *redis.Redis, opts ...ShutdownOption) {
+	OnShutdown("redis", func(ctx context.Context) error {
+		return client.Close()
+	}, opts...)
+}
+
+// OnShutdownMysql 注册 mysql 连接池关闭
+func OnShutdownMysql(client *gorm.DB, opts ...ShutdownOption) {
+	OnShutdown("mysql", func(ctx context.Context) error {
+		sqlDB, err := client.DB()
+		if err != nil {
+			return err
+		}
+		return sqlDB.Close()
+	}, opts...)
+}
+
+// runShutdownHooks 逆序执行所有钩子，单个钩子超时或失败只记录日志，不影响后续钩子
+func runShutdownHooks(ctx context.Context) {
+	shutdownMu.Lock()
+	hooks := make([]hook, len(shutdownHooks))
+	copy(hooks, shutdownHooks)
+	shutdownMu.Unlock()
+
+	for i := len(hooks) - 1; i >= 0; i-- {
+		h := hooks[i]
+		start := time.Now()
+		if err := runHook(ctx, h); err != nil {
+			zlog.Errorf(LogContext(ctx), "shutdown hook %s error: %v, cost: %v", h.name, err, time.Since(start))
+			continue
+		}
+		zlog.Infof(LogContext(ctx), "shutdown hook %s done, cost: %v", h.name, time.Since(start))
+	}
+}
+
+// runHook 执行单个钩子，panic 转为错误，超过 timeout 或 ctx 结束时不再等待
+func runHook(ctx context.Context, h hook) (err error) {
+	if h.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, h.timeout)
+		defer cancel()
+	}
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- fmt.Errorf("panic: %v", r)
+			}
+		}()
+		done <- h.fn(ctx)
+	}()
+	// 钩子未响应ctx时也不阻塞退出
+	select {
+	case err = <-done:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("timeout: %w", ctx.Err())
+	}
+}
+
+func (conf *ShutdownConfig) checkConf() {
+	if conf.Timeout <= 0 {
+		conf.Timeout = defaultShutdownTimeout
+	}
+	if conf.DrainDelay < 0 {
+		conf.DrainDelay = 0
+	}
+}
@@ -0,0 +1,104 @@
+package golib
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/xiangtao94/golib/pkg/zlog"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+	zlog.InitLog(zlog.LogConfig{})
+}
+
+func resetShutdownHooks(t *testing.T) {
+	shutdownMu.Lock()
+	shutdownHooks = nil
+	shutdownMu.Unlock()
+	t.Cleanup(func() {
+		shutdownMu.Lock()
+		shutdownHooks = nil
+		shutdownMu.Unlock()
+	})
+}
+
+func TestShutdownHooksReverseOrder(t *testing.T) {
+	resetShutdownHooks(t)
+
+	var mu sync.Mutex
+	var order []string
+	record := func(name string, err error) func(ctx context.Context) error {
+		return func(ctx context.Context) error {
+			mu.Lock()
+			order = append(order, name)
+			mu.Unlock()
+			return err
+		}
+	}
+	OnShutdown("mysql", record("mysql", nil))
+	OnShutdown("redis", record("redis", errors.New("close failed")))
+	OnShutdown("consumer", record("consumer", nil))
+
+	runShutdownHooks(context.Background())
+
+	// 失败的钩子不影响后续钩子执行
+	assert.Equal(t, []string{"consumer", "redis", "mysql"}, order)
+}
+
+func TestShutdownHookTimeout(t *testing.T) {
+	resetShutdownHooks(t)
+
+	var finished []string
+	OnShutdown("fast", func(ctx context.Context) error {
+		finished = append(finished, "fast")
+		return nil
+	})
+	// 不响应ctx的钩子也会在超时后被跳过
+	OnShutdown("stuck", func(ctx context.Context) error {
+		time.Sleep(time.Second)
+		return nil
+	}, WithHookTimeout(50*time.Millisecond))
+	// runHook 超时返回时钩子协程可能还未写入，通过 channel 等待
+	hookErr := make(chan error, 1)
+	OnShutdown("ctx-aware", func(ctx context.Context) error {
+		<-ctx.Done()
+		hookErr <- ctx.Err()
+		return ctx.Err()
+	}, WithHookTimeout(50*time.Millisecond))
+
+	start := time.Now()
+	runShutdownHooks(context.Background())
+	cost := time.Since(start)
+
+	select {
+	case err := <-hookErr:
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	case <-time.After(time.Second):
+		t.Fatal("ctx-aware hook not cancelled")
+	}
+	assert.Equal(t, []string{"fast"}, finished)
+	assert.Less(t, cost, 500*time.Millisecond)
+}
+
+func TestShutdownHookOverallDeadline(t *testing.T) {
+	resetShutdownHooks(t)
+
+	OnShutdown("slow", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}, WithHookTimeout(time.Minute))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	runShutdownHooks(ctx)
+	// 单个钩子超时不能超过整体时限
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+}
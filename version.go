@@ -0,0 +1,59 @@
+// Package golib -----------------------------
+// @file      : version.go
+// @author    : xiangtao
+// @contact   : xiangtao1994@gmail.com
+// @time      : 2025/9/14 17:10
+// Description: 版本信息端点，返回构建版本、git 提交、启动时间等运行信息
+// -------------------------------------------
+package golib
+
+import (
+	"net/http"
+	"runtime"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/xiangtao94/golib/pkg/env"
+	"github.com/xiangtao94/golib/pkg/zlog"
+)
+
+const defaultVersionPath = "/version"
+
+// versionInfo 版本端点的返回内容
+type versionInfo struct {
+	AppName   string `json:"appName"`
+	Version   string `json:"version"`
+	GitSHA    string `json:"gitSha"`
+	BuildTime string `json:"buildTime"`
+	StartTime string `json:"startTime"`
+	Uptime    string `json:"uptime"`
+	GoVersion string `json:"goVersion"`
+	RunMode   string `json:"runMode"`
+}
+
+// WithVersionEndpoint 注册版本信息端点，path 为空时为 /version，请求不打印access日志
+// 构建信息通过 env.SetBuildInfo 设置，未设置的字段为 unknown
+func WithVersionEndpoint(path string) BootstrapOption {
+	return func(engine *gin.Engine) {
+		if path == "" {
+			path = defaultVersionPath
+		}
+		engine.GET(path, versionHandler)
+	}
+}
+
+func versionHandler(ctx *gin.Context) {
+	zlog.SetNoLogFlag(ctx)
+	startTime := env.GetStartTime()
+	ctx.JSON(http.StatusOK, versionInfo{
+		AppName:   env.GetAppName(),
+		Version:   env.GetVersion(),
+		GitSHA:    env.GetGitSHA(),
+		BuildTime: env.GetBuildTime(),
+		StartTime: startTime.Format(time.RFC3339),
+		Uptime:    time.Since(startTime).Truncate(time.Second).String(),
+		GoVersion: runtime.Version(),
+		RunMode:   env.GetRunMode(),
+	})
+}
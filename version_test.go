@@ -0,0 +1,61 @@
+package golib
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xiangtao94/golib/pkg/env"
+	"github.com/xiangtao94/golib/pkg/zlog"
+)
+
+func getJSON(t *testing.T, engine *gin.Engine, path string) (int, map[string]string) {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, path, nil)
+	engine.ServeHTTP(w, req)
+	var body map[string]string
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	return w.Code, body
+}
+
+func TestVersionEndpoint(t *testing.T) {
+	t.Cleanup(func() { env.SetBuildInfo("", "", "") })
+	t.Setenv(env.APP_ENV, "")
+	engine := gin.New()
+	Bootstraps(engine, WithVersionEndpoint(""), WithVersionEndpoint("/internal/version"))
+
+	// 未设置时为 unknown
+	code, body := getJSON(t, engine, "/version")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "unknown", body["version"])
+	assert.Equal(t, "unknown", body["gitSha"])
+	assert.Equal(t, "unknown", body["buildTime"])
+	assert.Equal(t, "unknown", body["runMode"])
+
+	env.SetBuildInfo("v1.2.3", "abc1234", "2025-09-14T09:00:00Z")
+	t.Setenv(env.APP_ENV, "prod")
+	_, body = getJSON(t, engine, "/internal/version")
+	assert.Equal(t, env.GetAppName(), body["appName"])
+	assert.Equal(t, "v1.2.3", body["version"])
+	assert.Equal(t, "abc1234", body["gitSha"])
+	assert.Equal(t, "2025-09-14T09:00:00Z", body["buildTime"])
+	assert.Equal(t, runtime.Version(), body["goVersion"])
+	assert.Equal(t, "prod", body["runMode"])
+	assert.NotEmpty(t, body["startTime"])
+	assert.NotEmpty(t, body["uptime"])
+}
+
+func TestVersionEndpointNoAccessLog(t *testing.T) {
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	ctx.Request = httptest.NewRequest(http.MethodGet, "/version", nil)
+	versionHandler(ctx)
+	assert.Equal(t, true, ctx.Value(zlog.ContextKeyNoLog))
+	assert.True(t, strings.HasPrefix(ctx.Writer.Header().Get("Content-Type"), "application/json"))
+}